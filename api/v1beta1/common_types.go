@@ -19,6 +19,7 @@ package v1beta1
 import (
 	"strings"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -42,6 +43,11 @@ type DeploymentOptions struct {
 	// Labels to be added for the Deployment.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// The deployment strategy to use to replace existing pods with new ones.
+	// Defaults to the Kubernetes default, RollingUpdate.
+	// +optional
+	Strategy *appsv1.DeploymentStrategy `json:"strategy,omitempty"`
 }
 
 // PodOptions defines the common pod configuration for Pods, including when used
@@ -64,10 +70,18 @@ type PodOptions struct {
 	// +optional
 	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
 
+	// ContainerSecurityContext is the security context for the default container.
+	// +optional
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+
 	// Additional environment variables to pass to the default container.
 	// +optional
 	EnvVariables []corev1.EnvVar `json:"envVars,omitempty"`
 
+	// Secrets and ConfigMaps to populate the environment variables of the default container from.
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
 	// Annotations to be added for pods.
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
@@ -95,6 +109,56 @@ type PodOptions struct {
 	// Startup probe parameters
 	// +optional
 	StartupProbe *corev1.Probe `json:"startupProbe,omitempty"`
+
+	// Lifecycle hooks for the default container.
+	// Exec handlers run along with the hooks that the operator generates for the default container, other handlers replace them.
+	// +optional
+	Lifecycle *corev1.Lifecycle `json:"lifecycle,omitempty"`
+
+	// Optional Service Account to run the pod under.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Optional PriorityClassName for the pod.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Optional scheduler to schedule the pod with, instead of the default scheduler.
+	// +optional
+	SchedulerName string `json:"schedulerName,omitempty"`
+
+	// Optional RuntimeClass to run the pod with, such as a sandboxed container runtime.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
+
+	// Additional pull secrets for the images of the pod, e.g. for sidecar images from other private registries.
+	// These are used along with the imagePullSecret of the default container's image.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Sidecar containers to run in the pod. These are in addition to the default container.
+	// +optional
+	SidecarContainers []corev1.Container `json:"sidecarContainers,omitempty"`
+
+	// Additional init containers to run in the pod.
+	// These will run along with the init container that sets up the default container, if one exists.
+	// +optional
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+
+	// Set DNS policy for the pod.
+	// Defaults to "ClusterFirst".
+	// +optional
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// Specifies the DNS parameters of a pod.
+	// Parameters specified here will be merged to the generated DNS configuration based on DNSPolicy.
+	// +optional
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+
+	// Additional entries to add to the pod's /etc/hosts file.
+	// Host aliases generated by the operator take precedence over these for the same hostname.
+	// +optional
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
 }
 
 // ServiceOptions defines custom options for services
@@ -106,6 +170,59 @@ type ServiceOptions struct {
 	// Labels to be added for the Service.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// The port to expose the Service on.
+	// This is only used for the SolrPrometheusExporter metrics Service, SolrCloud Service ports are set via spec.solrAddressability.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// The type of the Service, which determines how it is exposed.
+	// This is not used for headless Services, and overrides the type that the operator would set otherwise, e.g. for the NodePort addressability method.
+	// Defaults to ClusterIP.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// The nodePort to expose the port of the Service on, for Services of type NodePort or LoadBalancer.
+	// If not provided, Kubernetes assigns a nodePort.
+	// This is not used for the Solr node Services, which are exposed on the ports given by the NodePort addressability method.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+
+	// The IP address to request for a Service of type LoadBalancer, if the cloud provider supports it.
+	// +optional
+	LoadBalancerIP string `json:"loadBalancerIP,omitempty"`
+
+	// The client IP ranges that can access a Service of type LoadBalancer, if the cloud provider supports it.
+	// +optional
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+
+	// Denotes if this Service desires to route external traffic to node-local or cluster-wide endpoints.
+	// This is only used for Services of type NodePort or LoadBalancer.
+	// +kubebuilder:validation:Enum=Local;Cluster
+	// +optional
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+
+	// Supports "ClientIP" and "None". Used to maintain session affinity.
+	// This is not used for headless Services.
+	// +kubebuilder:validation:Enum=ClientIP;None
+	// +optional
+	SessionAffinity corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+
+	// The configurations of session affinity, used when sessionAffinity is "ClientIP".
+	// +optional
+	SessionAffinityConfig *corev1.SessionAffinityConfig `json:"sessionAffinityConfig,omitempty"`
+
+	// The IP family of the Service, for Kubernetes clusters with the IPv6DualStack feature enabled.
+	// The IP family of a Service cannot be changed, so it is only used when the Service is created.
+	// If not provided, the Service uses the primary IP family of the cluster.
+	// +kubebuilder:validation:Enum=IPv4;IPv6
+	// +optional
+	IPFamily *corev1.IPFamily `json:"ipFamily,omitempty"`
 }
 
 // IngressOptions defines custom options for ingresses
@@ -117,6 +234,27 @@ type IngressOptions struct {
 	// Labels to be added for the Ingress.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// The name of the IngressClass of the Ingress, which selects the ingress controller that implements it.
+	// If not provided, the default IngressClass of the Kubernetes cluster is used.
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+}
+
+// ServiceAccountOptions defines custom options for ServiceAccounts
+type ServiceAccountOptions struct {
+	// Create a ServiceAccount for the pods, which is deleted along with the resource that owns it.
+	// This cannot be combined with a serviceAccountName in the podOptions.
+	// +optional
+	Create bool `json:"create,omitempty"`
+
+	// Annotations to be added for the ServiceAccount, e.g. to bind it to a cloud IAM role.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Labels to be added for the ServiceAccount.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // ConfigMapOptions defines custom options for configMaps
@@ -128,6 +266,26 @@ type ConfigMapOptions struct {
 	// Labels to be added for the ConfigMap.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// Additional files to add to the ConfigMap, keyed by file name.
+	// The keys that the operator generates, such as solr.xml, cannot be used.
+	// This is only used for the SolrCloud ConfigMap.
+	// +optional
+	AdditionalData map[string]string `json:"additionalData,omitempty"`
+
+	// The directory to mount the additionalData files into the Solr container.
+	// Defaults to "/var/solr/config".
+	// This is only used for the SolrCloud ConfigMap.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	AdditionalDataMountPath string `json:"additionalDataMountPath,omitempty"`
+
+	// The name of a ConfigMap, in the same namespace, that provides a custom solr.xml under the "solr.xml" key.
+	// It is used instead of the solr.xml generated by the operator, and changes to it restart the Solr pods.
+	// The solr.xml must set the hostPort of the solrcloud section to "${hostPort:...}", which the operator sets for each Solr node.
+	// This is only used for the SolrCloud ConfigMap.
+	// +optional
+	ProvidedConfigMap string `json:"providedConfigMap,omitempty"`
 }
 
 // AdditionalVolume provides information on additional volumes that should be loaded into pods
@@ -142,6 +300,26 @@ type AdditionalVolume struct {
 	DefaultContainerMount corev1.VolumeMount `json:"defaultContainerMount"`
 }
 
+// SolrTLSOptions defines the keystores and truststores needed to connect to Solr over TLS
+type SolrTLSOptions struct {
+	// TrustStoreSecret is a reference to the key in a Secret that contains the truststore (JKS or PKCS12)
+	// used to validate the certificates presented by Solr.
+	TrustStoreSecret *corev1.SecretKeySelector `json:"trustStoreSecret"`
+
+	// TrustStorePasswordSecret is a reference to the key in a Secret that contains the password for the truststore.
+	// +optional
+	TrustStorePasswordSecret *corev1.SecretKeySelector `json:"trustStorePasswordSecret,omitempty"`
+
+	// KeyStoreSecret is a reference to the key in a Secret that contains a keystore (JKS or PKCS12)
+	// with a client certificate, for when Solr requires clients to authenticate via TLS.
+	// +optional
+	KeyStoreSecret *corev1.SecretKeySelector `json:"keyStoreSecret,omitempty"`
+
+	// KeyStorePasswordSecret is a reference to the key in a Secret that contains the password for the keystore.
+	// +optional
+	KeyStorePasswordSecret *corev1.SecretKeySelector `json:"keyStorePasswordSecret,omitempty"`
+}
+
 // ContainerImage defines the fields needed for a Docker repository image. The
 // format here matches the predominant format used in Helm charts.
 type ContainerImage struct {
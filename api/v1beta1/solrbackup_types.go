@@ -21,6 +21,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"strings"
+	"time"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -30,6 +31,8 @@ const (
 	DefaultAWSCliImageRepo    = "infrastructureascode/aws-cli"
 	DefaultAWSCliImageVersion = "1.16.204"
 	DefaultS3Retries          = 5
+
+	DefaultBackupRetryBackoff = 30 * time.Second
 )
 
 // SolrBackupSpec defines the desired state of SolrBackup
@@ -42,17 +45,50 @@ type SolrBackupSpec struct {
 	Collections []string `json:"collections,omitempty"`
 
 	// Persistence is the specification on how to persist the backup data.
+	// If no persistence method is specified, the backup data is kept in the backup volume of the SolrCloud.
 	Persistence PersistenceSource `json:"persistence"`
+
+	// How to retry the backups of collections that fail.
+	// If not provided, a collection backup that fails is not retried.
+	// +optional
+	RetryPolicy *BackupRetryPolicy `json:"retryPolicy,omitempty"`
 }
 
 func (spec *SolrBackupSpec) withDefaults(backupName string) (changed bool) {
 	changed = spec.Persistence.withDefaults(backupName) || changed
 
+	if spec.RetryPolicy != nil {
+		changed = spec.RetryPolicy.withDefaults() || changed
+	}
+
+	return changed
+}
+
+// BackupRetryPolicy defines how the backups of collections that fail are retried
+type BackupRetryPolicy struct {
+	// The number of times the backup of a collection is retried after it fails.
+	// Once all retries have failed, the backup gets a Failed condition.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRetries int32 `json:"maxRetries,omitempty"`
+
+	// How long to wait after a collection backup fails, before retrying it.
+	// Defaults to 30s
+	// +optional
+	RetryBackoff *metav1.Duration `json:"retryBackoff,omitempty"`
+}
+
+func (policy *BackupRetryPolicy) withDefaults() (changed bool) {
+	if policy.RetryBackoff == nil {
+		policy.RetryBackoff = &metav1.Duration{Duration: DefaultBackupRetryBackoff}
+		changed = true
+	}
+
 	return changed
 }
 
 // PersistenceSource defines the location and method of persisting the backup data.
-// Exactly one member must be specified.
+// At most one member must be specified.
 type PersistenceSource struct {
 	// Persist to an s3 compatible endpoint
 	// +optional
@@ -63,6 +99,11 @@ type PersistenceSource struct {
 	Volume *VolumePersistenceSource `json:"volume,omitempty"`
 }
 
+// IsEmpty returns whether no persistence method is specified, in which case the backup data is kept in the backup volume of the SolrCloud
+func (spec *PersistenceSource) IsEmpty() bool {
+	return spec.S3 == nil && spec.Volume == nil
+}
+
 func (spec *PersistenceSource) withDefaults(backupName string) (changed bool) {
 	if spec.Volume != nil {
 		changed = spec.Volume.withDefaults(backupName) || changed
@@ -205,6 +246,70 @@ type SolrBackupStatus struct {
 
 	// Whether the backup has finished
 	Finished bool `json:"finished,omitempty"`
+
+	// Conditions describe the state of the backup
+	// +optional
+	Conditions []SolrBackupCondition `json:"conditions,omitempty"`
+}
+
+// SolrBackupConditionType is a type of condition that a SolrBackup can be in
+type SolrBackupConditionType string
+
+const (
+	// BackupFailed is True when the backup of a collection has failed, and will not be retried
+	BackupFailed SolrBackupConditionType = "Failed"
+)
+
+// SolrBackupCondition describes the state of a SolrBackup at a certain point
+type SolrBackupCondition struct {
+	// Type of the condition
+	Type SolrBackupConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The last time the condition transitioned from one status to another
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// The reason for the condition's last transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating details about the transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// GetCondition returns the condition of the given type, or nil if the status does not have one
+func (sbs *SolrBackupStatus) GetCondition(conditionType SolrBackupConditionType) *SolrBackupCondition {
+	for i := range sbs.Conditions {
+		if sbs.Conditions[i].Type == conditionType {
+			return &sbs.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition sets the condition of the given type, only changing the lastTransitionTime if the status of the condition changes
+func (sbs *SolrBackupStatus) SetCondition(conditionType SolrBackupConditionType, status corev1.ConditionStatus, reason string, message string) {
+	condition := sbs.GetCondition(conditionType)
+	if condition == nil {
+		sbs.Conditions = append(sbs.Conditions, SolrBackupCondition{Type: conditionType})
+		condition = &sbs.Conditions[len(sbs.Conditions)-1]
+	}
+	if condition.Status != status {
+		condition.Status = status
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+}
+
+// IsConditionTrue returns whether the status has a condition of the given type with a status of True
+func (sbs *SolrBackupStatus) IsConditionTrue(conditionType SolrBackupConditionType) bool {
+	condition := sbs.GetCondition(conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
 }
 
 // CollectionBackupStatus defines the progress of a Solr Collection's backup
@@ -224,6 +329,18 @@ type CollectionBackupStatus struct {
 	// +optional
 	AsyncBackupStatus string `json:"asyncBackupStatus,omitempty"`
 
+	// The number of times the backup of the collection has been started
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// The error returned by Solr for the last failed attempt to backup the collection
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Time after which the failed backup of the collection will be retried
+	// +optional
+	RetryTime *metav1.Time `json:"retryTimestamp,omitempty"`
+
 	// Whether the backup has finished
 	Finished bool `json:"finished,omitempty"`
 
@@ -18,6 +18,9 @@ package v1beta1
 
 import (
 	"fmt"
+	"net"
+	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -25,19 +28,34 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
 	DefaultPullPolicy = "" // This will use the default pullPolicy of Always when the tag is "latest" and IfNotPresent for all other tags.
 
-	DefaultSolrReplicas = int32(3)
-	DefaultSolrRepo     = "library/solr"
-	DefaultSolrVersion  = "7.7.0"
-	DefaultSolrStorage  = "5Gi"
-	DefaultSolrJavaMem  = "-Xms1g -Xmx2g"
-	DefaultSolrOpts     = ""
-	DefaultSolrLogLevel = "INFO"
-	DefaultSolrGCTune   = ""
+	DefaultSolrReplicas      = int32(3)
+	DefaultSolrRepo          = "library/solr"
+	DefaultSolrVersion       = "7.7.0"
+	DefaultSolrStorage       = "5Gi"
+	DefaultSolrLogStorage    = "2Gi"
+	DefaultSolrBackupStorage = "5Gi"
+	DefaultSolrJavaMem       = "-Xms1g -Xmx2g"
+	DefaultSolrOpts          = ""
+	DefaultSolrLogLevel      = "INFO"
+	DefaultSolrGCTune        = ""
+
+	DefaultSolrGCLogFileCount = int32(9)
+	DefaultSolrGCLogFileSize  = "20M"
+
+	DefaultSolrDataMountPath = "/var/solr/data"
+
+	DefaultSolrAdditionalConfigMountPath = "/var/solr/config"
+
+	DefaultNodeHostPattern = "{namespace}-{node}.{domain}"
+
+	DefaultLiveNodesCheckIntervalSeconds = int32(30)
 
 	DefaultBusyBoxImageRepo    = "library/busybox"
 	DefaultBusyBoxImageVersion = "1.28.0-glibc"
@@ -57,10 +75,19 @@ const (
 
 // SolrCloudSpec defines the desired state of SolrCloud
 type SolrCloudSpec struct {
-	// The number of solr nodes to run
+	// The number of solr nodes to run.
+	// A Standalone Solr runs at most one node.
+	// Scaling to zero stops the cloud, while keeping the data of the nodes.
+	// +kubebuilder:validation:Minimum=0
 	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// The mode to run Solr in. Defaults to Cloud.
+	// In Standalone mode a single Solr node is run without Zookeeper, and the zookeeperRef is ignored.
+	// +kubebuilder:validation:Enum=Cloud;Standalone
+	// +optional
+	SolrMode SolrMode `json:"solrMode,omitempty"`
+
 	// The information for the Zookeeper this SolrCloud should connect to
 	// Can be a zookeeper that is running, or one that is created by the solr operator
 	// +optional
@@ -90,10 +117,25 @@ type SolrCloudSpec struct {
 	// +optional
 	BackupRestoreVolume *corev1.VolumeSource `json:"backupRestoreVolume,omitempty"`
 
+	// Enables backups & restores on clusters without ReadWriteMany storage.
+	// A separate volume is created for each solrNode from this PersistentVolumeClaim spec, and mounted where the backupRestoreVolume would be.
+	// Solr's local backup support then writes the data of each shard to the volume of the node that hosts its leader.
+	// Backups stored this way cannot be persisted by a SolrBackup, they remain on the volumes of the solrNodes.
+	// This cannot be used together with the backupRestoreVolume, and must be set when the cloud is created.
+	// +optional
+	BackupRestorePerNodePvcSpec *corev1.PersistentVolumeClaimSpec `json:"backupRestorePerNodePvcSpec,omitempty"`
+
 	// Provide custom options for kubernetes objects created for the Solr Cloud.
 	// +optional
 	CustomSolrKubeOptions CustomSolrKubeOptions `json:"customSolrKubeOptions,omitempty"`
 
+	// A strategic merge patch that is applied to the pod template of the Solr StatefulSet after all other options,
+	// in the same way as "kubectl patch". Lists such as containers and volumes are merged by name.
+	// This is meant for pod settings that the other options do not support, and is not validated beyond being a valid patch.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	PodTemplateOverrides *runtime.RawExtension `json:"podTemplateOverrides,omitempty"`
+
 	// Customize how Solr is addressed both internally and externally in Kubernetes.
 	// +optional
 	SolrAddressability SolrAddressabilityOptions `json:"solrAddressability,omitempty"`
@@ -104,11 +146,37 @@ type SolrCloudSpec struct {
 	// +optional
 	SolrJavaMem string `json:"solrJavaMem,omitempty"`
 
+	// Size the heap of the Solr JVM as a percentage of the memory limit of the Solr container, instead of using the solrJavaMem.
+	// Both the initial and the maximum heap size are set to this amount, and a memory limit must be set for the Solr container.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	SolrJavaMemPercentage *int32 `json:"solrJavaMemPercentage,omitempty"`
+
 	// You can add common system properties to the SOLR_OPTS environment variable
 	// SolrOpts is the string interface for these optional settings
 	// +optional
 	SolrOpts string `json:"solrOpts,omitempty"`
 
+	// The Solr modules to enable on every Solr node, such as "ltr", "analytics" or "extraction".
+	// They are passed to Solr through the SOLR_MODULES environment variable, which requires Solr 9 or later.
+	// The cloud is rejected if the tag of the Solr image is an earlier version.
+	// +optional
+	SolrModules []string `json:"solrModules,omitempty"`
+
+	// Plugin jars to provide to every Solr node, which are placed in the lib directory of the SOLR_HOME by an init container.
+	// +optional
+	PluginLibs *SolrPluginLibsOptions `json:"pluginLibs,omitempty"`
+
+	// Tune the Jetty server of the Solr nodes. The options are passed to Solr as system properties, before the solrOpts.
+	// +optional
+	Jetty *SolrJettyOptions `json:"jetty,omitempty"`
+
+	// Pass metadata of each pod, such as the name of its node, to Solr as system properties.
+	// This lets replica placement rules use per-pod values, without configuring each pod separately.
+	// +optional
+	PodSystemProperties []SolrPodSystemProperty `json:"podSystemProperties,omitempty"`
+
 	// Set the Solr Log level, defaults to INFO
 	// +optional
 	SolrLogLevel string `json:"solrLogLevel,omitempty"`
@@ -116,6 +184,239 @@ type SolrCloudSpec struct {
 	// Set GC Tuning configuration through GC_TUNE environment variable
 	// +optional
 	SolrGCTune string `json:"solrGCTune,omitempty"`
+
+	// Set the timezone of the Solr JVM through the SOLR_TIMEZONE environment variable, e.g. "America/New_York".
+	// If not provided, Solr runs in UTC.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_+\-]+(/[A-Za-z0-9_+\-]+)*$`
+	// +optional
+	SolrTimezone string `json:"solrTimezone,omitempty"`
+
+	// Also set the timezone of the operating system in the Solr container, by mounting the zoneinfo file of the solrTimezone
+	// from the Kubernetes node at /etc/localtime. The file must exist under /usr/share/zoneinfo on every node.
+	// This has no effect if no solrTimezone is provided.
+	// +optional
+	MountLocaltime bool `json:"mountLocaltime,omitempty"`
+
+	// Set the session timeout, in milliseconds, of Solr's Zookeeper client through the ZK_CLIENT_TIMEOUT environment variable.
+	// If not provided, the default of the Solr image is used.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ZkClientTimeout *int32 `json:"zkClientTimeout,omitempty"`
+
+	// You can add Zookeeper related system properties, such as -DdistribUpdateSoTimeout=120000, through the SOLR_ZK_OPTS environment variable.
+	// These are passed to Solr before the solrOpts, and are ignored for a Standalone Solr.
+	// +optional
+	SolrZkOpts string `json:"solrZkOpts,omitempty"`
+
+	// Customize the locations of Solr's home and data directories within the Solr pods.
+	// +optional
+	DataStorage *SolrDataStorageOptions `json:"dataStorage,omitempty"`
+
+	// Customize where Solr writes its logs and GC logs.
+	// If not provided, Solr will log to the default locations of the Solr image.
+	// +optional
+	SolrLogs *SolrLogsOptions `json:"solrLogs,omitempty"`
+
+	// Define how the Solr pods are restarted when the StatefulSet changes.
+	// +optional
+	UpdateStrategy *SolrUpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// Report whether each Solr node is live in the cluster state, by periodically querying the CLUSTERSTATUS of the cloud.
+	// If not provided, the live state of the nodes will not be reported in the status.
+	// +optional
+	LiveNodesCheck *LiveNodesCheckOptions `json:"liveNodesCheck,omitempty"`
+
+	// Maintain a ConfigMap named "<cloud>-solrcloud-endpoints" with the ZooKeeper connection string and the addresses of the cloud and its nodes,
+	// so that clients can discover the cloud without reading the SolrCloud resource.
+	// +optional
+	PublishEndpointsConfigMap bool `json:"publishEndpointsConfigMap,omitempty"`
+
+	// Cluster properties that the operator sets through the CLUSTERPROP API of Solr, e.g. "urlScheme" or "defaults.collection.numShards".
+	// The properties are checked on every reconcile, and set again if their values in Solr differ.
+	// Properties removed from this map are not unset in Solr.
+	// +optional
+	ClusterProperties map[string]string `json:"clusterProperties,omitempty"`
+
+	// The autoscaling policy and preferences that the operator sets through the autoscaling API of Solr.
+	// If not provided, the autoscaling configuration of Solr is not managed by the operator.
+	// +optional
+	Autoscaling *SolrAutoscalingOptions `json:"autoscaling,omitempty"`
+
+	// Enable authentication and authorization in Solr.
+	// Unless a basicAuthSecret is provided, the operator generates the admin credentials and bootstraps a security.json into Zookeeper.
+	// +optional
+	SolrSecurity *SolrSecurityOptions `json:"solrSecurity,omitempty"`
+
+	// Enable TLS for the Solr nodes, with a keystore provided in a Secret.
+	// The nodes are then addressed over https, and the urlScheme cluster property is set to https before they start.
+	// +optional
+	SolrTLS *SolrServerTLSOptions `json:"solrTLS,omitempty"`
+}
+
+// SolrServerTLSOptions defines the keystore and truststore that the Solr nodes use to serve and make requests over TLS
+type SolrServerTLSOptions struct {
+	// A reference to the key in a Secret that contains the PKCS12 keystore with the certificate and private key of the Solr nodes.
+	// The certificate must be valid for the hostnames that the nodes and the common service are addressed with.
+	// Required unless the certificate is issued through cert-manager.
+	// +optional
+	PKCS12Secret *corev1.SecretKeySelector `json:"pkcs12Secret,omitempty"`
+
+	// A reference to the key in a Secret that contains the password of the keystore.
+	// Required unless the certificate is issued through cert-manager.
+	// +optional
+	KeyStorePasswordSecret *corev1.SecretKeySelector `json:"keyStorePasswordSecret,omitempty"`
+
+	// A reference to the key in a Secret that contains the PKCS12 truststore, used to validate the certificates of other Solr nodes.
+	// If not provided, the keystore is used as the truststore.
+	// +optional
+	TrustStoreSecret *corev1.SecretKeySelector `json:"trustStoreSecret,omitempty"`
+
+	// A reference to the key in a Secret that contains the password of the truststore.
+	// If not provided, the password of the keystore is used.
+	// +optional
+	TrustStorePasswordSecret *corev1.SecretKeySelector `json:"trustStorePasswordSecret,omitempty"`
+
+	// Have cert-manager issue the certificate of the Solr nodes, instead of providing a keystore.
+	// The operator creates a Certificate for the hostnames of the cloud, and the keystore is built from the issued Secret when a pod starts.
+	// +optional
+	CertManager *SolrCertManagerOptions `json:"certManager,omitempty"`
+
+	// Require clients, including the other Solr nodes and the operator, to present a certificate that is trusted by the truststore.
+	// +optional
+	NeedClientAuth bool `json:"needClientAuth,omitempty"`
+
+	// Request clients to present a certificate, without rejecting the clients that do not.
+	// Cannot be set together with needClientAuth.
+	// +optional
+	WantClientAuth bool `json:"wantClientAuth,omitempty"`
+
+	// Verify that the certificate presented by a client is valid for its hostname.
+	// +optional
+	VerifyClientHostname bool `json:"verifyClientHostname,omitempty"`
+
+	// Verify that the certificate of a Solr node is valid for the hostname it is called with, when the Solr nodes call each other.
+	// Defaults to true.
+	// +optional
+	CheckPeerName *bool `json:"checkPeerName,omitempty"`
+
+	// The name of a Secret of type kubernetes.io/tls with the PEM encoded client certificate and key that the operator,
+	// and the probes when needClientAuth is set, present to Solr.
+	// Defaults to the Secret issued by cert-manager, and is otherwise required when needClientAuth is set.
+	// +optional
+	ClientCertSecret string `json:"clientCertSecret,omitempty"`
+}
+
+func (opts *SolrServerTLSOptions) withDefaults() (changed bool) {
+	if opts.CheckPeerName == nil {
+		changed = true
+		checkPeerName := true
+		opts.CheckPeerName = &checkPeerName
+	}
+	if opts.CertManager != nil {
+		changed = opts.CertManager.IssuerRef.withDefaults() || changed
+	}
+	return changed
+}
+
+// SolrCertManagerOptions defines the cert-manager Certificate that the operator creates for the Solr nodes
+type SolrCertManagerOptions struct {
+	// The cert-manager Issuer or ClusterIssuer that issues the certificate.
+	IssuerRef SolrCertManagerIssuerRef `json:"issuerRef"`
+
+	// The requested lifetime of the certificate, e.g. "2160h". Defaults to the duration of cert-manager.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// How long before it expires the certificate is renewed, e.g. "360h". Defaults to the renewBefore of cert-manager.
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+
+	// Additional DNS names that the certificate must be valid for, besides the hostnames of the cloud and its nodes.
+	// +optional
+	AdditionalDNSNames []string `json:"additionalDNSNames,omitempty"`
+
+	// The image of the init container that builds the PKCS12 keystore and truststore from the issued certificate.
+	// It must provide "openssl" and "keytool". Defaults to the solrImage.
+	// +optional
+	Image *ContainerImage `json:"image,omitempty"`
+}
+
+// SolrCertManagerIssuerRef references the cert-manager issuer of a Certificate
+type SolrCertManagerIssuerRef struct {
+	// The name of the issuer.
+	Name string `json:"name"`
+
+	// The kind of the issuer. Defaults to Issuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// The API group of the issuer. Defaults to cert-manager.io.
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+func (ref *SolrCertManagerIssuerRef) withDefaults() (changed bool) {
+	if ref.Kind == "" {
+		changed = true
+		ref.Kind = "Issuer"
+	}
+	if ref.Group == "" {
+		changed = true
+		ref.Group = "cert-manager.io"
+	}
+	return changed
+}
+
+// SolrAuthenticationType is the type of authentication used by Solr
+// +kubebuilder:validation:Enum=Basic
+type SolrAuthenticationType string
+
+const (
+	// Basic authentication, through Solr's BasicAuthPlugin
+	Basic SolrAuthenticationType = "Basic"
+)
+
+// SolrSecurityOptions defines how Solr authenticates and authorizes requests, and how the operator authenticates to Solr
+type SolrSecurityOptions struct {
+	// The type of authentication. Only Basic is supported.
+	// +optional
+	AuthenticationType SolrAuthenticationType `json:"authenticationType,omitempty"`
+
+	// The name of a Secret of type kubernetes.io/basic-auth, with the "username" and "password" the operator uses to call Solr.
+	// When provided, the user is responsible for the security.json in Zookeeper, and no credentials or security.json are generated.
+	// If not provided, the operator creates the "<cloud>-solrcloud-basic-auth" Secret, and bootstraps a security.json
+	// that grants these credentials the admin role, before the Solr pods start.
+	// +optional
+	BasicAuthSecret string `json:"basicAuthSecret,omitempty"`
+
+	// Authenticate the liveness and readiness probes of the Solr pods, using the credentials of the operator.
+	// The bootstrapped security.json then blocks all unauthenticated requests.
+	// Otherwise the probe endpoints are left open, and the security.json does not block unknown users.
+	// +optional
+	ProbesRequireAuth bool `json:"probesRequireAuth,omitempty"`
+}
+
+func (opts *SolrSecurityOptions) withDefaults() (changed bool) {
+	if opts.AuthenticationType == "" {
+		changed = true
+		opts.AuthenticationType = Basic
+	}
+	return changed
+}
+
+// SolrAutoscalingOptions defines the autoscaling configuration of a SolrCloud.
+// Each list is only managed when it is provided, and replaces the list stored in Solr as a whole.
+type SolrAutoscalingOptions struct {
+	// The rules of the cluster policy, e.g. {"replica": "<2", "shard": "#EACH", "node": "#ANY"}.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	ClusterPolicy []runtime.RawExtension `json:"clusterPolicy,omitempty"`
+
+	// The cluster preferences, in order of importance, e.g. {"minimize": "cores", "precision": 1}.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	ClusterPreferences []runtime.RawExtension `json:"clusterPreferences,omitempty"`
 }
 
 func (spec *SolrCloudSpec) withDefaults(ingressBaseDomain string) (changed bool) {
@@ -147,10 +448,26 @@ func (spec *SolrCloudSpec) withDefaults(ingressBaseDomain string) (changed bool)
 
 	changed = spec.SolrAddressability.withDefaults(ingressBaseDomain) || changed
 
-	if spec.ZookeeperRef == nil {
-		spec.ZookeeperRef = &ZookeeperRef{}
+	if spec.SolrSecurity != nil {
+		changed = spec.SolrSecurity.withDefaults() || changed
+	}
+
+	if spec.SolrTLS != nil {
+		changed = spec.SolrTLS.withDefaults() || changed
+	}
+
+	if spec.SolrMode == StandaloneMode {
+		if *spec.Replicas > 1 {
+			changed = true
+			r := int32(1)
+			spec.Replicas = &r
+		}
+	} else {
+		if spec.ZookeeperRef == nil {
+			spec.ZookeeperRef = &ZookeeperRef{}
+		}
+		changed = spec.ZookeeperRef.withDefaults() || changed
 	}
-	changed = spec.ZookeeperRef.withDefaults() || changed
 
 	if spec.SolrImage == nil {
 		spec.SolrImage = &ContainerImage{}
@@ -173,12 +490,484 @@ func (spec *SolrCloudSpec) withDefaults(ingressBaseDomain string) (changed bool)
 		}
 	}
 
+	if spec.BackupRestorePerNodePvcSpec != nil {
+		if len(spec.BackupRestorePerNodePvcSpec.AccessModes) == 0 {
+			spec.BackupRestorePerNodePvcSpec.AccessModes = []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			}
+			changed = true
+		}
+		if len(spec.BackupRestorePerNodePvcSpec.Resources.Requests) == 0 {
+			spec.BackupRestorePerNodePvcSpec.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse(DefaultSolrBackupStorage),
+			}
+			changed = true
+		}
+	}
+
+	if spec.SolrLogs != nil {
+		changed = spec.SolrLogs.withDefaults() || changed
+	}
+
+	if configMapOptions := spec.CustomSolrKubeOptions.ConfigMapOptions; configMapOptions != nil && len(configMapOptions.AdditionalData) > 0 && configMapOptions.AdditionalDataMountPath == "" {
+		changed = true
+		configMapOptions.AdditionalDataMountPath = DefaultSolrAdditionalConfigMountPath
+	}
+
+	if spec.LiveNodesCheck != nil {
+		changed = spec.LiveNodesCheck.withDefaults() || changed
+	}
+
+	if spec.UpdateStrategy != nil {
+		changed = spec.UpdateStrategy.withDefaults() || changed
+	}
+
 	if spec.BusyBoxImage == nil {
 		c := ContainerImage{}
 		spec.BusyBoxImage = &c
 	}
 	changed = spec.BusyBoxImage.withDefaults(DefaultBusyBoxImageRepo, DefaultBusyBoxImageVersion, DefaultPullPolicy) || changed
 
+	if spec.DataStorage != nil && spec.DataStorage.Persistent != nil {
+		changed = spec.DataStorage.Persistent.withDefaults() || changed
+	}
+
+	if spec.DataStorage != nil && spec.DataStorage.Tlog != nil && spec.DataStorage.Tlog.Persistent != nil {
+		changed = spec.DataStorage.Tlog.Persistent.withDefaults() || changed
+	}
+
+	if spec.DataStorage != nil && spec.DataStorage.ReclaimPolicy == "" {
+		changed = true
+		spec.DataStorage.ReclaimPolicy = DataReclaimRetain
+	}
+
+	if spec.DataStorage != nil && spec.DataStorage.InitContainer != nil && spec.DataStorage.InitContainer.Image != nil {
+		changed = spec.DataStorage.InitContainer.Image.withDefaults(DefaultBusyBoxImageRepo, DefaultBusyBoxImageVersion, DefaultPullPolicy) || changed
+	}
+
+	if spec.PluginLibs != nil && spec.PluginLibs.Image != nil {
+		changed = spec.PluginLibs.Image.withDefaults(DefaultBusyBoxImageRepo, DefaultBusyBoxImageVersion, DefaultPullPolicy) || changed
+	}
+
+	return changed
+}
+
+// SolrMode is the mode that Solr runs in
+type SolrMode string
+
+const (
+	// Run Solr in SolrCloud mode, connected to Zookeeper
+	CloudMode SolrMode = "Cloud"
+
+	// Run a single Solr node without Zookeeper
+	StandaloneMode SolrMode = "Standalone"
+)
+
+// SolrDataStorageOptions defines where Solr stores its data within the Solr pods.
+// The paths cannot be changed once the cloud is created, since that would orphan the existing data.
+type SolrDataStorageOptions struct {
+	// The path to mount the data volume at. Defaults to "/var/solr/data".
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	DataMountPath string `json:"dataMountPath,omitempty"`
+
+	// The SOLR_HOME for the Solr nodes, in which the solr.xml will be placed.
+	// This must be the dataMountPath or a directory within it. Defaults to the dataMountPath.
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	SolrHome string `json:"solrHome,omitempty"`
+
+	// Customize the init container that copies the solr.xml into the SOLR_HOME.
+	// +optional
+	InitContainer *SolrDataInitContainerOptions `json:"initContainer,omitempty"`
+
+	// Store the data of each Solr node in a PersistentVolumeClaim, so that the indexes are kept when the pod is rescheduled.
+	// If neither this nor the dataPvcSpec is provided, each Solr node will use an emptyDir as the data volume.
+	// This cannot be used together with the dataPvcSpec, and cannot be changed once the cloud is created.
+	// +optional
+	Persistent *SolrPersistentDataStorageOptions `json:"persistent,omitempty"`
+
+	// EmptyDir defines the options for the emptyDir data volume, used when the data is not stored in PVCs.
+	// Set a sizeLimit to keep a runaway index from filling the root disk of the node, or the medium to store the data in memory.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// Store the transaction logs of the Solr cores on a separate volume, instead of alongside the index data.
+	// The updateLog of the configsets must read its directory from the "solr.ulog.dir" system property.
+	// This cannot be changed once the cloud is created.
+	// +optional
+	Tlog *SolrTlogStorageOptions `json:"tlog,omitempty"`
+
+	// What happens to the data PVCs when the SolrCloud is deleted. Defaults to Retain.
+	// Retained PVCs are reused if a SolrCloud with the same name is created again, which allows the data to be recovered.
+	// Only used when the data is stored in PVCs, through the persistent options or the dataPvcSpec.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	ReclaimPolicy DataReclaimPolicy `json:"reclaimPolicy,omitempty"`
+}
+
+// DataReclaimPolicy defines what happens to the data PVCs of a SolrCloud when it is deleted
+type DataReclaimPolicy string
+
+const (
+	// The data PVCs are kept when the SolrCloud is deleted
+	DataReclaimRetain DataReclaimPolicy = "Retain"
+
+	// The data PVCs are deleted along with the SolrCloud
+	DataReclaimDelete DataReclaimPolicy = "Delete"
+)
+
+// SolrTlogStorageOptions defines the volume that stores the transaction logs of the Solr nodes
+type SolrTlogStorageOptions struct {
+	// Store the transaction logs of each Solr node in a PersistentVolumeClaim.
+	// If not provided, each Solr node will use an emptyDir as the transaction log volume.
+	// +optional
+	Persistent *SolrPersistentDataStorageOptions `json:"persistent,omitempty"`
+
+	// EmptyDir defines the options for the emptyDir transaction log volume, used when no persistent options are provided.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+}
+
+// SolrPersistentDataStorageOptions defines the PersistentVolumeClaims that store the data of the Solr nodes
+type SolrPersistentDataStorageOptions struct {
+	// The StorageClass of the data PVCs. Defaults to the default StorageClass of the Kubernetes cluster.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// The size of the data PVC of each Solr node. Defaults to "5Gi".
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// The access modes of the data PVCs. Defaults to ReadWriteOnce.
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+
+	// Labels to add to the PVCs, for example to apply backup policies by label.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to add to the PVCs.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// A label query over the pre-provisioned PersistentVolumes that the PVCs may be bound to.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Bind the PVCs to local PersistentVolumes, on the disks of the Kubernetes nodes.
+	// The storageClassName must be given, and the StorageClass should use the WaitForFirstConsumer volumeBindingMode.
+	// +optional
+	Local *SolrLocalStorageOptions `json:"local,omitempty"`
+}
+
+// SolrLocalStorageOptions defines the nodes that hold the local PersistentVolumes of the Solr nodes
+type SolrLocalStorageOptions struct {
+	// The labels of the nodes with local disks for Solr.
+	// The Solr pods are only scheduled onto these nodes, through a required node affinity that is added to the affinity of the pods.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+func (opts *SolrPersistentDataStorageOptions) withDefaults() (changed bool) {
+	if opts.Size == nil {
+		changed = true
+		size := resource.MustParse(DefaultSolrStorage)
+		opts.Size = &size
+	}
+	if len(opts.AccessModes) == 0 {
+		changed = true
+		opts.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+	return changed
+}
+
+// pvcSpec returns the spec of the PVCs described by the persistent storage options
+func (opts *SolrPersistentDataStorageOptions) pvcSpec() *corev1.PersistentVolumeClaimSpec {
+	volumeMode := corev1.PersistentVolumeFilesystem
+	pvcSpec := &corev1.PersistentVolumeClaimSpec{
+		AccessModes:      opts.AccessModes,
+		StorageClassName: opts.StorageClassName,
+		VolumeMode:       &volumeMode,
+		Selector:         opts.Selector,
+	}
+	if opts.Size != nil {
+		pvcSpec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: *opts.Size}
+	}
+	return pvcSpec
+}
+
+// SolrDataInitContainerOptions defines the init container that prepares the SOLR_HOME of each Solr pod
+type SolrDataInitContainerOptions struct {
+	// Do not run the init container.
+	// The solr.xml is instead mounted into the SOLR_HOME directly from the ConfigMap, and the pod's fsGroup must give Solr access to the data volume.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// The image of the init container. Defaults to the busyBoxImage of the SolrCloud.
+	// +optional
+	Image *ContainerImage `json:"image,omitempty"`
+
+	// The resources of the init container.
+	// They are also used for the init containers that build the keystore from a cert-manager Certificate and set up Zookeeper.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// The security context of the init container.
+	// It is also used for the init containers that build the keystore from a cert-manager Certificate and set up Zookeeper.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// SolrJettyOptions defines the options of the Jetty server of the Solr nodes.
+// Options that are not provided use the defaults of the Solr image.
+type SolrJettyOptions struct {
+	// The maximum size of the request headers, in bytes, set through the solr.jetty.request.header.size system property.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RequestHeaderSize *int32 `json:"requestHeaderSize,omitempty"`
+
+	// The minimum number of threads of the request thread pool, set through the solr.jetty.threads.min system property.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinThreads *int32 `json:"minThreads,omitempty"`
+
+	// The maximum number of threads of the request thread pool, set through the solr.jetty.threads.max system property.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxThreads *int32 `json:"maxThreads,omitempty"`
+
+	// The idle timeout of HTTP connections, in milliseconds, set through the solr.jetty.http.idleTimeout system property.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IdleTimeoutMillis *int32 `json:"idleTimeoutMillis,omitempty"`
+}
+
+// SolrPodSystemProperty defines a Solr system property whose value is read from a field of the pod through the downward API
+type SolrPodSystemProperty struct {
+	// The name of the system property, e.g. "rack"
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_.\-]+$`
+	Name string `json:"name"`
+
+	// The field of the pod to set the system property to, e.g. "spec.nodeName" or "metadata.labels['topology.kubernetes.io/zone']".
+	// Any field supported by the downward API for environment variables can be used.
+	FieldPath string `json:"fieldPath"`
+}
+
+// SolrPluginLibsOptions defines the plugin jars to provide to the Solr nodes, and the init container that provides them
+type SolrPluginLibsOptions struct {
+	// The URLs of jars to download before Solr starts.
+	// Each jar is stored under the last segment of the path of its URL.
+	// +optional
+	URLs []string `json:"urls,omitempty"`
+
+	// A volume with jars to copy before Solr starts, such as a ConfigMap with binaryData or a PersistentVolumeClaim.
+	// Only the jars at the root of the volume are copied.
+	// +optional
+	VolumeSource *corev1.VolumeSource `json:"volumeSource,omitempty"`
+
+	// The image of the init container, which must provide "wget" to download the URLs. Defaults to the busyBoxImage of the SolrCloud.
+	// +optional
+	Image *ContainerImage `json:"image,omitempty"`
+
+	// The resources of the init container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// The security context of the init container.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// SolrLogsOptions defines where Solr should write its logs
+type SolrLogsOptions struct {
+	// Only log to the console (stdout), which is then handled by Kubernetes.
+	// When enabled, no log volume is created and Solr does not write any log or GC log files.
+	// +optional
+	LogToStdout bool `json:"logToStdout,omitempty"`
+
+	// PersistentVolumeClaimSpec is the spec to describe a PVC for each Solr node to store its logs and GC logs.
+	// If not provided, and logToStdout is not enabled, each Solr node will use an emptyDir as the log volume.
+	// This field cannot be updated once the cluster is created.
+	// +optional
+	PersistentVolumeClaimSpec *corev1.PersistentVolumeClaimSpec `json:"pvcSpec,omitempty"`
+
+	// EmptyDir defines the options for the emptyDir log volume, used when no pvcSpec is provided.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// A custom log4j2 configuration for the Solr nodes, for example to log in JSON or to set the levels of individual loggers.
+	// It is used instead of the configuration of the Solr image, or the console-only configuration of logToStdout.
+	// +optional
+	Log4j2Config *SolrLog4j2ConfigOptions `json:"log4j2Config,omitempty"`
+
+	// Write the GC logs, with rotation, to a dedicated volume instead of the log volume.
+	// GC logs are written even when logToStdout is enabled.
+	// +optional
+	GCLogs *SolrGCLogsOptions `json:"gcLogs,omitempty"`
+}
+
+// SolrGCLogsOptions defines the GC log files of the Solr nodes and the volume that they are written to
+type SolrGCLogsOptions struct {
+	// The number of GC log files to keep, defaults to 9
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FileCount *int32 `json:"fileCount,omitempty"`
+
+	// The size at which a GC log file is rotated, e.g. "20M", defaults to "20M"
+	// +kubebuilder:validation:Pattern=`^[0-9]+[KMG]?$`
+	// +optional
+	FileSize string `json:"fileSize,omitempty"`
+
+	// PersistentVolumeClaimSpec is the spec to describe a PVC for each Solr node to store its GC logs.
+	// If not provided, each Solr node will use an emptyDir as the GC log volume.
+	// This field cannot be updated once the cluster is created.
+	// +optional
+	PersistentVolumeClaimSpec *corev1.PersistentVolumeClaimSpec `json:"pvcSpec,omitempty"`
+
+	// EmptyDir defines the options for the emptyDir GC log volume, used when no pvcSpec is provided.
+	// +optional
+	EmptyDir *corev1.EmptyDirVolumeSource `json:"emptyDir,omitempty"`
+
+	// The major version of Java that the Solr image runs on, which determines the format of the GC log options.
+	// If not provided, Java 8 is assumed for Solr versions before 8.0, and Java 11 otherwise.
+	// +kubebuilder:validation:Minimum=8
+	// +optional
+	JavaVersion *int32 `json:"javaVersion,omitempty"`
+}
+
+// SolrLog4j2ConfigOptions defines a custom log4j2 configuration. Only one of the options can be provided.
+type SolrLog4j2ConfigOptions struct {
+	// The log4j2 XML configuration, which is stored in the ConfigMap of the SolrCloud.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+
+	// The key of a ConfigMap, in the same namespace, that contains the log4j2 XML configuration.
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+// Validate returns an error if not exactly one of the log4j2 configuration options is provided
+func (opts *SolrLog4j2ConfigOptions) Validate() error {
+	if opts.Inline != "" && opts.ConfigMapRef != nil {
+		return fmt.Errorf("only one of the inline log4j2 configuration and the configMapRef can be provided")
+	}
+	if opts.Inline == "" && opts.ConfigMapRef == nil {
+		return fmt.Errorf("either the inline log4j2 configuration or the configMapRef must be provided")
+	}
+	return nil
+}
+
+func (opts *SolrLogsOptions) withDefaults() (changed bool) {
+	if opts.PersistentVolumeClaimSpec != nil {
+		changed = logPvcSpecWithDefaults(opts.PersistentVolumeClaimSpec)
+	}
+	if opts.GCLogs != nil {
+		changed = opts.GCLogs.withDefaults() || changed
+	}
+	return changed
+}
+
+func (opts *SolrGCLogsOptions) withDefaults() (changed bool) {
+	if opts.FileCount == nil {
+		changed = true
+		count := DefaultSolrGCLogFileCount
+		opts.FileCount = &count
+	}
+	if opts.FileSize == "" {
+		changed = true
+		opts.FileSize = DefaultSolrGCLogFileSize
+	}
+	if opts.PersistentVolumeClaimSpec != nil {
+		changed = logPvcSpecWithDefaults(opts.PersistentVolumeClaimSpec) || changed
+	}
+	return changed
+}
+
+// logPvcSpecWithDefaults sets the defaults of the spec of a PVC that Solr writes logs to
+func logPvcSpecWithDefaults(pvcSpec *corev1.PersistentVolumeClaimSpec) (changed bool) {
+	if len(pvcSpec.AccessModes) == 0 {
+		changed = true
+		pvcSpec.AccessModes = []corev1.PersistentVolumeAccessMode{
+			corev1.ReadWriteOnce,
+		}
+	}
+	if len(pvcSpec.Resources.Requests) == 0 {
+		changed = true
+		pvcSpec.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceStorage: resource.MustParse(DefaultSolrLogStorage),
+		}
+	}
+	if pvcSpec.VolumeMode == nil {
+		changed = true
+		temp := corev1.PersistentVolumeFilesystem
+		pvcSpec.VolumeMode = &temp
+	}
+	return changed
+}
+
+// SolrUpdateMethod is a method of restarting the Solr pods when the StatefulSet changes
+type SolrUpdateMethod string
+
+const (
+	// The Solr pods are restarted one at a time by a rolling update
+	ManagedUpdate SolrUpdateMethod = "Managed"
+
+	// Only the Solr pods with an ordinal at or above the partition given by the user are restarted
+	ManualUpdate SolrUpdateMethod = "Manual"
+
+	// The Solr pods are only updated once they are deleted by the user
+	OnDeleteUpdate SolrUpdateMethod = "OnDelete"
+)
+
+// SolrUpdateStrategy defines how the Solr pods are restarted when the StatefulSet changes
+type SolrUpdateStrategy struct {
+	// The method of restarting the Solr pods. Defaults to Managed.
+	// +kubebuilder:validation:Enum=Managed;Manual;OnDelete
+	// +optional
+	Method SolrUpdateMethod `json:"method,omitempty"`
+
+	// Options for the Manual update method.
+	// Cleared when the method is not Manual.
+	// +optional
+	Manual *ManualUpdateOptions `json:"manual,omitempty"`
+}
+
+func (opts *SolrUpdateStrategy) withDefaults() (changed bool) {
+	if opts.Method == "" {
+		changed = true
+		opts.Method = ManagedUpdate
+	}
+	if opts.Method != ManualUpdate && opts.Manual != nil {
+		changed = true
+		opts.Manual = nil
+	}
+	return changed
+}
+
+// ManualUpdateOptions defines which Solr pods can be restarted when using the Manual update method
+type ManualUpdateOptions struct {
+	// Only the Solr pods with an ordinal greater than or equal to the partition are restarted when the StatefulSet changes.
+	// The operator never lowers the partition itself, so pods can be restarted one at a time by lowering it step by step.
+	// Must be between 0 and the number of replicas. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Partition int32 `json:"partition,omitempty"`
+}
+
+// LiveNodesCheckOptions defines how often the live nodes of the cloud are fetched from Solr
+type LiveNodesCheckOptions struct {
+	// The minimum number of seconds between two queries of the cluster state. Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IntervalSeconds int32 `json:"intervalSeconds,omitempty"`
+}
+
+func (opts *LiveNodesCheckOptions) withDefaults() (changed bool) {
+	if opts.IntervalSeconds == 0 {
+		changed = true
+		opts.IntervalSeconds = DefaultLiveNodesCheckIntervalSeconds
+	}
 	return changed
 }
 
@@ -204,13 +993,17 @@ type CustomSolrKubeOptions struct {
 	// +optional
 	NodeServiceOptions *ServiceOptions `json:"nodeServiceOptions,omitempty"`
 
-	// ServiceOptions defines the custom options for the solrCloud ConfigMap.
+	// ConfigMapOptions defines the custom options for the solrCloud ConfigMap.
 	// +optional
 	ConfigMapOptions *ConfigMapOptions `json:"configMapOptions,omitempty"`
 
 	// IngressOptions defines the custom options for the solrCloud Ingress.
 	// +optional
 	IngressOptions *IngressOptions `json:"ingressOptions,omitempty"`
+
+	// ServiceAccountOptions defines whether the operator creates a dedicated ServiceAccount for the solrCloud pods, and its custom options.
+	// +optional
+	ServiceAccountOptions *ServiceAccountOptions `json:"serviceAccountOptions,omitempty"`
 }
 
 type SolrAddressabilityOptions struct {
@@ -233,6 +1026,15 @@ type SolrAddressabilityOptions struct {
 	// Only use this option if the Kubernetes cluster has been setup with a custom domain.
 	// +optional
 	KubeDomain string `json:"kubeDomain,omitempty"`
+
+	// HostNetwork runs the Solr pods in the network of their Kubernetes nodes, bypassing the overlay network of the cluster.
+	// The Solr nodes advertise themselves with the IP address of their Kubernetes node and the podPort,
+	// so the podPort must be available on every Kubernetes node, and at most one Solr node of the cloud runs on each Kubernetes node.
+	//
+	// The Solr nodes cannot advertise an external address when using the host network, so external.useExternalAddress is set to false.
+	// Defaults to false.
+	// +optional
+	HostNetwork bool `json:"hostNetwork,omitempty"`
 }
 
 func (opts *SolrAddressabilityOptions) withDefaults(ingressBaseDomain string) (changed bool) {
@@ -256,6 +1058,11 @@ func (opts *SolrAddressabilityOptions) withDefaults(ingressBaseDomain string) (c
 		changed = true
 		opts.CommonServicePort = 80
 	}
+	// The Solr nodes advertise the IP address of their Kubernetes node when using the host network
+	if opts.HostNetwork && opts.External != nil && opts.External.UseExternalAddress {
+		changed = true
+		opts.External.UseExternalAddress = false
+	}
 	return changed
 }
 
@@ -299,7 +1106,7 @@ type ExternalAddressability struct {
 	DomainName string `json:"domainName,omitempty"`
 
 	// Provide additional domainNames that the Ingress or ExternalDNS should listen on.
-	// This option is ignored with the LoadBalancer method.
+	// This option is ignored with the LoadBalancer and Route methods, since a Route only has a single host.
 	// +optional
 	AdditionalDomainNames []string `json:"additionalDomains,omitempty"`
 
@@ -315,11 +1122,84 @@ type ExternalAddressability struct {
 	// Defaults to 80 if HideNodes=false and method=Ingress, otherwise this is optional.
 	// +optional
 	NodePortOverride int `json:"nodePortOverride,omitempty"`
+
+	// AdvertisedPort defines the port that the Solr Nodes advertise themselves with when useExternalAddress=true,
+	// and that is used in the external addresses of the Solr Nodes.
+	// This allows Solr to advertise the port that the ingress controller listens on, e.g. 80, while the node service(s) keep listening on a different port, e.g. the podPort.
+	//
+	// This option is not used with the NodePort method, since each Solr Node advertises the nodePort of its own service.
+	//
+	// Defaults to the port of the node service(s), the nodePortOverride if provided and the podPort otherwise.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	AdvertisedPort int `json:"advertisedPort,omitempty"`
+
+	// NodeHostPattern is the template for the hostnames of the individual Solr Nodes when using the Ingress, Istio or Route method.
+	// The placeholders {namespace}, {cloud}, {node} (the name of the Solr pod), {ordinal} and {domain} are replaced for each node and domain.
+	// The hostnames must be valid DNS names, and unique for each node, so either {node} or {ordinal} must be used.
+	// The {ordinal} placeholder cannot be used with useExternalAddress, since the Solr pods only know their own pod name.
+	//
+	// Defaults to "{namespace}-{node}.{domain}" for the Ingress, Istio and Route methods.
+	// +optional
+	NodeHostPattern string `json:"nodeHostPattern,omitempty"`
+
+	// ExternalDnsTTL is the TTL, in seconds, of the DNS records that ExternalDNS creates for the services, only used by the ExternalDNS method.
+	// If not provided, the default TTL of the DNS provider is used.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ExternalDnsTTL int `json:"externalDnsTTL,omitempty"`
+
+	// NodePortBase is the first port used by the NodePort method, which exposes the Services on this port of every Kubernetes node.
+	// The common Service is exposed on the nodePortBase, and the Solr node with ordinal N on nodePortBase + 1 + N.
+	// All of these ports must be within the service node port range of the Kubernetes cluster, 30000-32767 by default,
+	// and cannot be used by any other Service.
+	//
+	// If useExternalAddress is true, each Solr node advertises itself with the domainName and the nodePort of its own Service.
+	//
+	// Required for the NodePort method.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	NodePortBase int `json:"nodePortBase,omitempty"`
+
+	// IngressTLS terminates TLS for the hosts of the Ingress, only used by the Ingress and Istio methods.
+	// Solr still advertises its nodes over http, since the TLS is terminated by the ingress controller.
+	// +optional
+	IngressTLS *SolrIngressTLSOptions `json:"ingressTLS,omitempty"`
+
+	// Istio defines the Gateway that exposes the Solr services, only used by the Istio method.
+	// +optional
+	Istio *SolrIstioOptions `json:"istio,omitempty"`
+}
+
+// SolrIngressTLSOptions defines how the Ingress of a SolrCloud terminates TLS
+type SolrIngressTLSOptions struct {
+	// The name of the Secret containing the TLS certificate for the hosts.
+	// It does not need to exist yet if it is created by cert-manager from the annotations of the Ingress.
+	SecretName string `json:"secretName"`
+
+	// The hosts that the certificate is for. Defaults to all hosts of the Ingress.
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// SolrIstioOptions defines the Istio Gateway that routes to the VirtualService of a SolrCloud
+type SolrIstioOptions struct {
+	// The name of an existing Gateway to bind the VirtualService to, in the form [namespace/]name.
+	// If provided, the operator does not generate a Gateway for the SolrCloud.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// The labels of the Istio ingress gateway pods that the generated Gateway is applied to.
+	// Defaults to "istio: ingressgateway", the labels of the default Istio ingress gateway.
+	// +optional
+	GatewaySelector map[string]string `json:"gatewaySelector,omitempty"`
 }
 
 // ExternalAddressability is a string enumeration type that enumerates
 // all possible ways that a SolrCloud can be made addressable external to the kubernetes cluster.
-// +kubebuilder:validation:Enum=Ingress;ExternalDNS
+// +kubebuilder:validation:Enum=Ingress;ExternalDNS;NodePort;Istio;Route
 type ExternalAddressabilityMethod string
 
 const (
@@ -332,6 +1212,15 @@ const (
 	// Make Solr service(s) type:LoadBalancer to make them externally addressable
 	// NOTE: This option is not currently supported.
 	LoadBalancer ExternalAddressabilityMethod = "LoadBalancer"
+
+	// Make Solr service(s) type:NodePort, so that they are addressable through a port of every Kubernetes node
+	NodePort ExternalAddressabilityMethod = "NodePort"
+
+	// Use an Istio Gateway and VirtualService to make the Solr service(s) externally addressable, instead of an Ingress
+	Istio ExternalAddressabilityMethod = "Istio"
+
+	// Use OpenShift Routes to make the Solr service(s) externally addressable, instead of an Ingress
+	Route ExternalAddressabilityMethod = "Route"
 )
 
 func (opts *ExternalAddressability) withDefaults() (changed bool) {
@@ -340,11 +1229,30 @@ func (opts *ExternalAddressability) withDefaults() (changed bool) {
 		changed = true
 		opts.UseExternalAddress = false
 	}
-	// If the Ingress method is used, default the nodePortOverride to 80, since that is the port that most ingress controllers listen on.
-	if !opts.HideNodes && opts.Method == Ingress && opts.NodePortOverride == 0 {
+	// If the Ingress, Istio or Route method is used, default the nodePortOverride to 80, since that is the port that most ingress controllers listen on.
+	if !opts.HideNodes && opts.UsesIngressHosts() && opts.NodePortOverride == 0 {
 		changed = true
 		opts.NodePortOverride = 80
 	}
+	if opts.UsesIngressHosts() && opts.NodeHostPattern == "" {
+		changed = true
+		opts.NodeHostPattern = DefaultNodeHostPattern
+	}
+	if opts.Method == Istio {
+		if opts.Istio == nil {
+			changed = true
+			opts.Istio = &SolrIstioOptions{}
+		}
+		if opts.Istio.Gateway == "" && len(opts.Istio.GatewaySelector) == 0 {
+			changed = true
+			opts.Istio.GatewaySelector = map[string]string{"istio": "ingressgateway"}
+		}
+	}
+	// With the NodePort method, each node advertises the nodePort of its own service instead.
+	if opts.Method == NodePort && opts.AdvertisedPort > 0 {
+		changed = true
+		opts.AdvertisedPort = 0
+	}
 	// If a headless service is used, aka not using individual node services, then a nodePortOverride is not allowed.
 	if !opts.UsesIndividualNodeServices() && opts.NodePortOverride > 0 {
 		changed = true
@@ -384,10 +1292,47 @@ type ZookeeperRef struct {
 	//   - A zookeeper operator to be running
 	// +optional
 	ProvidedZookeeper *ZookeeperSpec `json:"provided,omitempty"`
+
+	// An existing ZookeeperCluster to connect to, which is managed outside of the Solr operator.
+	// The Solr operator builds the connection string from the ZookeeperCluster, but never creates, updates or owns it.
+	// Note: Requires the zookeeperOperator flag to be provided to the Solr Operator
+	// +optional
+	ZookeeperClusterRef *ZookeeperClusterRef `json:"zookeeperClusterRef,omitempty"`
+}
+
+// ZookeeperClusterRef references a ZookeeperCluster of the zookeeper operator
+type ZookeeperClusterRef struct {
+	// The name of the ZookeeperCluster
+	Name string `json:"name"`
+
+	// The namespace of the ZookeeperCluster.
+	// Defaults to the namespace of the SolrCloud.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// The ChRoot to connect solr at
+	// +optional
+	ChRoot string `json:"chroot,omitempty"`
+}
+
+// Validate returns an error if more than one way of connecting to Zookeeper is provided
+func (ref *ZookeeperRef) Validate() error {
+	options := 0
+	for _, provided := range []bool{ref.ConnectionInfo != nil, ref.ProvidedZookeeper != nil, ref.ZookeeperClusterRef != nil} {
+		if provided {
+			options++
+		}
+	}
+	if options > 1 {
+		return fmt.Errorf("only one of connectionInfo, provided and zookeeperClusterRef can be used in the zookeeperRef")
+	}
+	return nil
 }
 
 func (ref *ZookeeperRef) withDefaults() (changed bool) {
-	if ref.ProvidedZookeeper == nil && ref.ConnectionInfo == nil {
+	if ref.ZookeeperClusterRef != nil {
+		changed = ref.ZookeeperClusterRef.withDefaults() || changed
+	} else if ref.ProvidedZookeeper == nil && ref.ConnectionInfo == nil {
 		changed = true
 		ref.ProvidedZookeeper = &ZookeeperSpec{}
 	} else if ref.ConnectionInfo != nil {
@@ -403,7 +1348,24 @@ func (ref *ZookeeperRef) withDefaults() (changed bool) {
 	return changed
 }
 
+func (zcr *ZookeeperClusterRef) withDefaults() (changed bool) {
+	if zcr.ChRoot == "" {
+		changed = true
+		zcr.ChRoot = "/"
+	} else if !strings.HasPrefix(zcr.ChRoot, "/") {
+		changed = true
+		zcr.ChRoot = "/" + zcr.ChRoot
+	}
+	return changed
+}
+
 func (ci *ZookeeperConnectionInfo) withDefaults() (changed bool) {
+	if ci.AllACL != nil {
+		changed = ci.AllACL.withDefaults() || changed
+	}
+	if ci.ReadOnlyACL != nil {
+		changed = ci.ReadOnlyACL.withDefaults() || changed
+	}
 	if ci.InternalConnectionString == "" {
 		if ci.ExternalConnectionString != nil {
 			changed = true
@@ -592,42 +1554,188 @@ func (z *OldZookeeperSpec) withDefaults() (changed bool) {
 			changed = true
 		}
 	}
-	return changed
+	return changed
+}
+
+// SolrCloudStatus defines the observed state of SolrCloud
+type SolrCloudStatus struct {
+	// SolrNodes contain the statuses of each solr node running in this solr cloud.
+	SolrNodes []SolrNodeStatus `json:"solrNodes"`
+
+	// Replicas is the number of number of desired replicas in the cluster
+	Replicas int32 `json:"replicas"`
+
+	// ReadyReplicas is the number of number of ready replicas in the cluster
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// UpToDateNodes is the number of Solr nodes running the current spec of the StatefulSet
+	UpToDateNodes int32 `json:"upToDateNodes"`
+
+	// The version of solr that the cloud is running
+	Version string `json:"version"`
+
+	// The version of solr that the cloud is meant to be running.
+	// Will only be provided when the cloud is migrating between versions
+	// +optional
+	TargetVersion string `json:"targetVersion,omitempty"`
+
+	// InternalCommonAddress is the internal common http address for all solr nodes
+	InternalCommonAddress string `json:"internalCommonAddress"`
+
+	// ExternalCommonAddress is the external common http address for all solr nodes.
+	// Will only be provided when an ingressUrl is provided for the cloud
+	// +optional
+	ExternalCommonAddress *string `json:"externalCommonAddress,omitempty"`
+
+	// ZookeeperConnectionInfo is the information on how to connect to the used Zookeeper
+	ZookeeperConnectionInfo ZookeeperConnectionInfo `json:"zookeeperConnectionInfo"`
+
+	// BackupRestoreReady announces whether the solrCloud has the backupRestorePVC mounted to all pods
+	// and therefore is ready for backups and restores.
+	BackupRestoreReady bool `json:"backupRestoreReady"`
+
+	// BackupRestoreMissingPods lists the pods of the solrCloud that do not have the backup volume mounted.
+	// +optional
+	BackupRestoreMissingPods []string `json:"backupRestoreMissingPods,omitempty"`
+
+	// LiveNodes is the number of Solr nodes registered as live in the cluster state.
+	// Will only be provided when the liveNodesCheck is enabled for the cloud
+	// +optional
+	LiveNodes *int32 `json:"liveNodes,omitempty"`
+
+	// Conditions describe operations on the cloud that span multiple reconciles.
+	// +optional
+	Conditions []SolrCloudCondition `json:"conditions,omitempty"`
+
+	// ClusterOperation is the operation that currently holds the lock on the cloud.
+	// Managed updates, scale downs and backups take this lock, so that they do not run at the same time.
+	// +optional
+	ClusterOperation *SolrClusterOperation `json:"clusterOperation,omitempty"`
+}
+
+// SolrClusterOperationType is a type of operation that must not run on a SolrCloud at the same time as other operations
+type SolrClusterOperationType string
+
+const (
+	// ManagedUpdateOperation restarts the Solr pods one at a time, after the pod template has changed
+	ManagedUpdateOperation SolrClusterOperationType = "ManagedUpdate"
+
+	// ScaleDownOperation removes Solr pods, after the number of replicas has been lowered
+	ScaleDownOperation SolrClusterOperationType = "ScaleDown"
+
+	// BackupOperation backs up the collections of the cloud for a SolrBackup
+	BackupOperation SolrClusterOperationType = "Backup"
+)
+
+// SolrClusterOperation is a lock on a SolrCloud, held by the operation that is running on the cloud
+type SolrClusterOperation struct {
+	// The type of the operation
+	Operation SolrClusterOperationType `json:"operation"`
+
+	// Time that the operation took the lock
+	StartTime metav1.Time `json:"startTimestamp"`
+
+	// Information about the operation, e.g. the name of the SolrBackup that holds the lock
+	// +optional
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// IsSolrCloudOperation returns whether the operation is run by the SolrCloud reconciler itself
+func (op *SolrClusterOperation) IsSolrCloudOperation() bool {
+	return op.Operation == ManagedUpdateOperation || op.Operation == ScaleDownOperation
+}
+
+// HasSameOwner returns whether both operations are run by the same owner, which can hold the lock for either of them.
+// All operations of the SolrCloud reconciler share an owner, while a backup is owned by its SolrBackup.
+func (op *SolrClusterOperation) HasSameOwner(other *SolrClusterOperation) bool {
+	if op.IsSolrCloudOperation() {
+		return other.IsSolrCloudOperation()
+	}
+	return op.Operation == other.Operation && op.Metadata == other.Metadata
 }
 
-// SolrCloudStatus defines the observed state of SolrCloud
-type SolrCloudStatus struct {
-	// SolrNodes contain the statuses of each solr node running in this solr cloud.
-	SolrNodes []SolrNodeStatus `json:"solrNodes"`
+// SolrCloudConditionType is a type of condition that a SolrCloud can be in
+type SolrCloudConditionType string
 
-	// Replicas is the number of number of desired replicas in the cluster
-	Replicas int32 `json:"replicas"`
+const (
+	// ZkConnectionMigration is True while the Solr nodes are restarted one at a time to connect to a new ZooKeeper connection string
+	ZkConnectionMigration SolrCloudConditionType = "ZkConnectionMigration"
 
-	// ReadyReplicas is the number of number of ready replicas in the cluster
-	ReadyReplicas int32 `json:"readyReplicas"`
+	// SolrCloudStopped is True while the cloud is scaled to zero replicas
+	SolrCloudStopped SolrCloudConditionType = "Stopped"
 
-	// The version of solr that the cloud is running
-	Version string `json:"version"`
+	// ZkConnectionInfoInvalid is True when the ZooKeeper connection information provided for the cloud cannot be used
+	ZkConnectionInfoInvalid SolrCloudConditionType = "ZkConnectionInfoInvalid"
 
-	// The version of solr that the cloud is meant to be running.
-	// Will only be provided when the cloud is migrating between versions
+	// BackupRestoreNotReady is True when backups are enabled for the cloud, but its backup volume cannot be used
+	BackupRestoreNotReady SolrCloudConditionType = "BackupRestoreNotReady"
+
+	// RequiredAPIUnavailable is True when the cloud uses a feature whose API is not served by the Kubernetes cluster, such as a provided Zookeeper or an Ingress
+	RequiredAPIUnavailable SolrCloudConditionType = "RequiredAPIUnavailable"
+
+	// ReferencesResolved is False while a Secret or ConfigMap referenced by the Solr pods is missing, which would keep the pods from starting
+	ReferencesResolved SolrCloudConditionType = "ReferencesResolved"
+
+	// ClusterPropertiesNotApplied is True when the cluster properties of the spec could not be set in Solr
+	ClusterPropertiesNotApplied SolrCloudConditionType = "ClusterPropertiesNotApplied"
+
+	// AutoscalingNotApplied is True when the autoscaling policy or preferences of the spec could not be set in Solr
+	AutoscalingNotApplied SolrCloudConditionType = "AutoscalingNotApplied"
+
+	// DataVolumeExpansion is True while the existing data PVCs are expanded to a larger size of the data storage spec, or when they cannot be expanded
+	DataVolumeExpansion SolrCloudConditionType = "DataVolumeExpansion"
+)
+
+// SolrCloudCondition describes the state of a SolrCloud at a certain point
+type SolrCloudCondition struct {
+	// Type of the condition
+	Type SolrCloudConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The last time the condition transitioned from one status to another
 	// +optional
-	TargetVersion string `json:"targetVersion,omitempty"`
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 
-	// InternalCommonAddress is the internal common http address for all solr nodes
-	InternalCommonAddress string `json:"internalCommonAddress"`
+	// The reason for the condition's last transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
 
-	// ExternalCommonAddress is the external common http address for all solr nodes.
-	// Will only be provided when an ingressUrl is provided for the cloud
+	// A human readable message indicating details about the transition
 	// +optional
-	ExternalCommonAddress *string `json:"externalCommonAddress,omitempty"`
+	Message string `json:"message,omitempty"`
+}
 
-	// ZookeeperConnectionInfo is the information on how to connect to the used Zookeeper
-	ZookeeperConnectionInfo ZookeeperConnectionInfo `json:"zookeeperConnectionInfo"`
+// GetCondition returns the condition of the given type, or nil if the status does not have one
+func (scs *SolrCloudStatus) GetCondition(conditionType SolrCloudConditionType) *SolrCloudCondition {
+	for i := range scs.Conditions {
+		if scs.Conditions[i].Type == conditionType {
+			return &scs.Conditions[i]
+		}
+	}
+	return nil
+}
 
-	// BackupRestoreReady announces whether the solrCloud has the backupRestorePVC mounted to all pods
-	// and therefore is ready for backups and restores.
-	BackupRestoreReady bool `json:"backupRestoreReady"`
+// SetCondition sets the condition of the given type, only changing the lastTransitionTime if the status of the condition changes
+func (scs *SolrCloudStatus) SetCondition(conditionType SolrCloudConditionType, status corev1.ConditionStatus, reason string, message string) {
+	condition := scs.GetCondition(conditionType)
+	if condition == nil {
+		scs.Conditions = append(scs.Conditions, SolrCloudCondition{Type: conditionType})
+		condition = &scs.Conditions[len(scs.Conditions)-1]
+	}
+	if condition.Status != status {
+		condition.Status = status
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+}
+
+// IsConditionTrue returns whether the status has a condition of the given type with a status of True
+func (scs *SolrCloudStatus) IsConditionTrue(conditionType SolrCloudConditionType) bool {
+	condition := scs.GetCondition(conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
 }
 
 // SolrNodeStatus is the status of a solrNode in the cloud, with readiness status
@@ -650,8 +1758,21 @@ type SolrNodeStatus struct {
 	// Is the node up and running
 	Ready bool `json:"ready"`
 
+	// Is the pod of the node running the current spec of the StatefulSet
+	SpecUpToDate bool `json:"specUpToDate"`
+
 	// The version of solr that the node is running
 	Version string `json:"version"`
+
+	// The IP address that the node advertises itself with.
+	// Will only be provided when the cloud uses the host network
+	// +optional
+	IP string `json:"ip,omitempty"`
+
+	// Is the node registered as live in the cluster state.
+	// Will only be provided when the liveNodesCheck is enabled for the cloud
+	// +optional
+	Live *bool `json:"live,omitempty"`
 }
 
 // SolrNodeStatus is the status of a solrNode in the cloud, with readiness status
@@ -669,6 +1790,91 @@ type ZookeeperConnectionInfo struct {
 	// The ChRoot to connect solr at
 	// +optional
 	ChRoot string `json:"chroot,omitempty"`
+
+	// The digest credentials that Solr connects to ZooKeeper with, which are given all permissions on the znodes that Solr creates.
+	// Without them, the znodes of Solr are open to every client of ZooKeeper.
+	// +optional
+	AllACL *ZookeeperACL `json:"acl,omitempty"`
+
+	// The digest credentials that are given read permissions on the znodes that Solr creates, and that the Prometheus exporter reads them with.
+	// Requires the acl.
+	// +optional
+	ReadOnlyACL *ZookeeperACL `json:"readOnlyAcl,omitempty"`
+
+	// Connect to ZooKeeper over TLS, with the given keystore and truststore.
+	// Requires ZooKeeper to serve TLS on its client port, or on the secureClientPort.
+	// +optional
+	TLS *ZookeeperTLSOptions `json:"tls,omitempty"`
+}
+
+// ZookeeperACL defines the digest credentials of a ZooKeeper ACL, which are read from a Secret
+type ZookeeperACL struct {
+	// The name of the Secret with the username and password, in the namespace of the SolrCloud or exporter.
+	SecretRef string `json:"secret"`
+
+	// The key of the username in the Secret. Defaults to "username".
+	// +optional
+	UsernameKey string `json:"usernameKey,omitempty"`
+
+	// The key of the password in the Secret. Defaults to "password".
+	// +optional
+	PasswordKey string `json:"passwordKey,omitempty"`
+}
+
+// ZookeeperTLSOptions defines the keystore and truststore that Solr and the Prometheus exporter connect to ZooKeeper with over TLS.
+// The Secrets must be in the namespace of the SolrCloud or exporter.
+type ZookeeperTLSOptions struct {
+	// The secure client port of the ensemble.
+	// If provided, every host of the connection string is connected to on this port, instead of the port given in the connection string.
+	// +optional
+	SecureClientPort int32 `json:"secureClientPort,omitempty"`
+
+	// A reference to the key in a Secret that contains the keystore with the client certificate, for ensembles that require client authentication.
+	// The type of the keystore is inferred from its file extension, i.e. ".p12", ".jks" or ".pem".
+	// +optional
+	KeyStoreSecret *corev1.SecretKeySelector `json:"keyStoreSecret,omitempty"`
+
+	// A reference to the key in a Secret that contains the password of the keystore.
+	// +optional
+	KeyStorePasswordSecret *corev1.SecretKeySelector `json:"keyStorePasswordSecret,omitempty"`
+
+	// A reference to the key in a Secret that contains the truststore, used to validate the certificates of the ZooKeeper servers.
+	// The type of the truststore is inferred from its file extension, i.e. ".p12", ".jks" or ".pem".
+	// If not provided, the default truststore of the JVM is used.
+	// +optional
+	TrustStoreSecret *corev1.SecretKeySelector `json:"trustStoreSecret,omitempty"`
+
+	// A reference to the key in a Secret that contains the password of the truststore.
+	// +optional
+	TrustStorePasswordSecret *corev1.SecretKeySelector `json:"trustStorePasswordSecret,omitempty"`
+}
+
+// Validate returns an error if a password is provided without its store, or a Secret is referenced without a name or key
+func (tls *ZookeeperTLSOptions) Validate() error {
+	if tls.KeyStorePasswordSecret != nil && tls.KeyStoreSecret == nil {
+		return fmt.Errorf("the ZooKeeper TLS keyStorePasswordSecret requires a keyStoreSecret")
+	}
+	if tls.TrustStorePasswordSecret != nil && tls.TrustStoreSecret == nil {
+		return fmt.Errorf("the ZooKeeper TLS trustStorePasswordSecret requires a trustStoreSecret")
+	}
+	for _, secretKey := range []*corev1.SecretKeySelector{tls.KeyStoreSecret, tls.KeyStorePasswordSecret, tls.TrustStoreSecret, tls.TrustStorePasswordSecret} {
+		if secretKey != nil && (secretKey.Name == "" || secretKey.Key == "") {
+			return fmt.Errorf("the ZooKeeper TLS Secrets must be referenced with a name and a key")
+		}
+	}
+	return nil
+}
+
+func (acl *ZookeeperACL) withDefaults() (changed bool) {
+	if acl.UsernameKey == "" {
+		changed = true
+		acl.UsernameKey = corev1.BasicAuthUsernameKey
+	}
+	if acl.PasswordKey == "" {
+		changed = true
+		acl.PasswordKey = corev1.BasicAuthPasswordKey
+	}
+	return changed
 }
 
 // +kubebuilder:object:root=true
@@ -711,11 +1917,179 @@ func (sc *SolrCloud) GetAllSolrNodeNames() []string {
 	return nodeNames
 }
 
+// DataPvcSpec returns the spec of the PVC that stores the data of each Solr node, either from the dataPvcSpec or from the persistent data storage options.
+// Nil is returned if the Solr nodes use an emptyDir as the data volume.
+func (sc *SolrCloud) DataPvcSpec() *corev1.PersistentVolumeClaimSpec {
+	if sc.Spec.DataPvcSpec != nil {
+		return sc.Spec.DataPvcSpec
+	}
+	if sc.Spec.DataStorage == nil || sc.Spec.DataStorage.Persistent == nil {
+		return nil
+	}
+	return sc.Spec.DataStorage.Persistent.pvcSpec()
+}
+
+// UsesTlogVolume returns whether the transaction logs of the Solr nodes are stored on a separate volume
+func (sc *SolrCloud) UsesTlogVolume() bool {
+	return sc.Spec.DataStorage != nil && sc.Spec.DataStorage.Tlog != nil
+}
+
+// TlogPvcSpec returns the spec of the PVC that stores the transaction logs of each Solr node.
+// Nil is returned if the Solr nodes use an emptyDir as the transaction log volume, or do not use a separate volume at all.
+func (sc *SolrCloud) TlogPvcSpec() *corev1.PersistentVolumeClaimSpec {
+	if !sc.UsesTlogVolume() || sc.Spec.DataStorage.Tlog.Persistent == nil {
+		return nil
+	}
+	return sc.Spec.DataStorage.Tlog.Persistent.pvcSpec()
+}
+
+// LocalStorageNodeSelector returns the labels of the nodes that hold the local PersistentVolumes of the data and transaction log PVCs
+func (sc *SolrCloud) LocalStorageNodeSelector() map[string]string {
+	nodeSelector := map[string]string{}
+	if sc.Spec.DataStorage == nil {
+		return nodeSelector
+	}
+	if sc.Spec.DataPvcSpec == nil && sc.Spec.DataStorage.Persistent != nil && sc.Spec.DataStorage.Persistent.Local != nil {
+		for label, value := range sc.Spec.DataStorage.Persistent.Local.NodeSelector {
+			nodeSelector[label] = value
+		}
+	}
+	if sc.Spec.DataStorage.Tlog != nil && sc.Spec.DataStorage.Tlog.Persistent != nil && sc.Spec.DataStorage.Tlog.Persistent.Local != nil {
+		for label, value := range sc.Spec.DataStorage.Tlog.Persistent.Local.NodeSelector {
+			nodeSelector[label] = value
+		}
+	}
+	return nodeSelector
+}
+
+// DeletesDataOnDeletion returns whether the data and transaction log PVCs of the SolrCloud are deleted along with it
+func (sc *SolrCloud) DeletesDataOnDeletion() bool {
+	return (sc.DataPvcSpec() != nil || sc.TlogPvcSpec() != nil) && sc.Spec.DataStorage != nil && sc.Spec.DataStorage.ReclaimPolicy == DataReclaimDelete
+}
+
+// DataMountPath returns the path that the Solr data volume is mounted at
+func (sc *SolrCloud) DataMountPath() string {
+	if sc.Spec.DataStorage != nil && sc.Spec.DataStorage.DataMountPath != "" {
+		return path.Clean(sc.Spec.DataStorage.DataMountPath)
+	}
+	return DefaultSolrDataMountPath
+}
+
+// SolrHome returns the SOLR_HOME for the Solr nodes
+func (sc *SolrCloud) SolrHome() string {
+	if sc.Spec.DataStorage != nil && sc.Spec.DataStorage.SolrHome != "" {
+		return path.Clean(sc.Spec.DataStorage.SolrHome)
+	}
+	return sc.DataMountPath()
+}
+
+// SolrHomeDataSubPath returns the location of the SOLR_HOME relative to the data volume.
+// An error is returned if the SOLR_HOME is not within the data volume.
+func (sc *SolrCloud) SolrHomeDataSubPath() (string, error) {
+	subPath, err := filepath.Rel(sc.DataMountPath(), sc.SolrHome())
+	if err != nil || subPath == ".." || strings.HasPrefix(subPath, "../") {
+		return "", fmt.Errorf("the solrHome '%s' must be within the dataMountPath '%s'", sc.SolrHome(), sc.DataMountPath())
+	}
+	if subPath == "." {
+		subPath = ""
+	}
+	return subPath, nil
+}
+
 // ConfigMapName returns the name of the cloud config-map
 func (sc *SolrCloud) ConfigMapName() string {
 	return fmt.Sprintf("%s-solrcloud-configmap", sc.GetName())
 }
 
+// BasicAuthSecretName returns the name of the Secret with the credentials the operator uses to call Solr, if security is enabled
+func (sc *SolrCloud) BasicAuthSecretName() string {
+	if sc.Spec.SolrSecurity != nil && sc.Spec.SolrSecurity.BasicAuthSecret != "" {
+		return sc.Spec.SolrSecurity.BasicAuthSecret
+	}
+	return fmt.Sprintf("%s-solrcloud-basic-auth", sc.GetName())
+}
+
+// SecurityBootstrapSecretName returns the name of the Secret with the generated security.json and the credentials of its users
+func (sc *SolrCloud) SecurityBootstrapSecretName() string {
+	return fmt.Sprintf("%s-solrcloud-security-bootstrap", sc.GetName())
+}
+
+// CertificateName returns the name of the cert-manager Certificate of the cloud, and of the Secret it is issued to
+func (sc *SolrCloud) CertificateName() string {
+	return fmt.Sprintf("%s-solrcloud-tls", sc.GetName())
+}
+
+// PKCS12PasswordSecretName returns the name of the Secret with the generated password of the keystore built from the cert-manager Certificate
+func (sc *SolrCloud) PKCS12PasswordSecretName() string {
+	return fmt.Sprintf("%s-solrcloud-pkcs12-password", sc.GetName())
+}
+
+// ClientCertSecretName returns the name of the Secret with the client certificate that the operator presents to Solr, or an empty string if there is none
+func (sc *SolrCloud) ClientCertSecretName() string {
+	if sc.Spec.SolrTLS == nil {
+		return ""
+	} else if sc.Spec.SolrTLS.ClientCertSecret != "" {
+		return sc.Spec.SolrTLS.ClientCertSecret
+	} else if sc.UsesCertManager() {
+		return sc.CertificateName()
+	}
+	return ""
+}
+
+// RequiresClientAuth returns whether the clients of Solr must present a certificate
+func (sc *SolrCloud) RequiresClientAuth() bool {
+	return sc.Spec.SolrTLS != nil && sc.Spec.SolrTLS.NeedClientAuth
+}
+
+// UsesCertManager returns whether the certificate of the Solr nodes is issued through cert-manager
+func (sc *SolrCloud) UsesCertManager() bool {
+	return sc.Spec.SolrTLS != nil && sc.Spec.SolrTLS.CertManager != nil
+}
+
+// BootstrapsSecurity returns whether the operator generates the credentials and the security.json of the cloud
+func (sc *SolrCloud) BootstrapsSecurity() bool {
+	return sc.Spec.SolrSecurity != nil && sc.Spec.SolrSecurity.BasicAuthSecret == ""
+}
+
+// CustomLog4j2Config returns the custom log4j2 configuration of the Solr nodes, if one is given
+func (sc *SolrCloud) CustomLog4j2Config() *SolrLog4j2ConfigOptions {
+	if sc.Spec.SolrLogs != nil {
+		return sc.Spec.SolrLogs.Log4j2Config
+	}
+	return nil
+}
+
+// ProvidedSolrXmlConfigMap returns the name of the user provided config-map with the solr.xml of the cloud, if one is given
+func (sc *SolrCloud) ProvidedSolrXmlConfigMap() string {
+	if options := sc.Spec.CustomSolrKubeOptions.ConfigMapOptions; options != nil {
+		return options.ProvidedConfigMap
+	}
+	return ""
+}
+
+// SolrXmlConfigMapName returns the name of the config-map that the solr.xml of the cloud is mounted from
+func (sc *SolrCloud) SolrXmlConfigMapName() string {
+	if providedConfigMap := sc.ProvidedSolrXmlConfigMap(); providedConfigMap != "" {
+		return providedConfigMap
+	}
+	return sc.ConfigMapName()
+}
+
+// EndpointsConfigMapName returns the name of the config-map that publishes the endpoints of the cloud
+func (sc *SolrCloud) EndpointsConfigMapName() string {
+	return fmt.Sprintf("%s-solrcloud-endpoints", sc.GetName())
+}
+
+// ServiceAccountName returns the name of the service account created for the cloud
+func (sc *SolrCloud) ServiceAccountName() string {
+	return fmt.Sprintf("%s-solrcloud", sc.GetName())
+}
+
+// CreatesServiceAccount returns whether the operator creates a service account for the pods of the cloud
+func (sc *SolrCloud) CreatesServiceAccount() bool {
+	return sc.Spec.CustomSolrKubeOptions.ServiceAccountOptions != nil && sc.Spec.CustomSolrKubeOptions.ServiceAccountOptions.Create
+}
+
 // StatefulSetName returns the name of the statefulset for the cloud
 func (sc *SolrCloud) StatefulSetName() string {
 	return fmt.Sprintf("%s-solrcloud", sc.GetName())
@@ -726,9 +2100,49 @@ func (sc *SolrCloud) CommonServiceName() string {
 	return fmt.Sprintf("%s-solrcloud-common", sc.GetName())
 }
 
-// InternalURLForCloud returns the name of the common service for the cloud
-func InternalURLForCloud(cloudName string, namespace string) string {
-	return fmt.Sprintf("http://%s-solrcloud-common.%s", cloudName, namespace)
+// UrlScheme returns the scheme that the Solr nodes of the cloud are addressed with,
+// which is "https" if TLS is enabled or the urlScheme cluster property is set to https
+func (sc *SolrCloud) UrlScheme() string {
+	if sc.Spec.SolrTLS != nil || strings.EqualFold(sc.Spec.ClusterProperties["urlScheme"], "https") {
+		return "https"
+	}
+	return "http"
+}
+
+// SolrMajorVersion returns the major version of Solr, parsed from the tag of the Solr image, e.g. 8 for "8.11-slim".
+// Returns false if the tag does not start with a version, e.g. "latest".
+func (sc *SolrCloud) SolrMajorVersion() (major int, known bool) {
+	if sc.Spec.SolrImage == nil {
+		return 0, false
+	}
+	tag := sc.Spec.SolrImage.Tag
+	if versionEnd := strings.IndexFunc(tag, func(r rune) bool { return r < '0' || r > '9' }); versionEnd >= 0 {
+		tag = tag[:versionEnd]
+	}
+	major, err := strconv.Atoi(tag)
+	return major, err == nil
+}
+
+// GCLogsJavaVersion returns the major version of Java that the GC log options of the Solr nodes are generated for
+func (sc *SolrCloud) GCLogsJavaVersion() int32 {
+	if solrLogs := sc.Spec.SolrLogs; solrLogs != nil && solrLogs.GCLogs != nil && solrLogs.GCLogs.JavaVersion != nil {
+		return *solrLogs.GCLogs.JavaVersion
+	}
+	// The Solr images before 8.0 run on Java 8
+	if major, known := sc.SolrMajorVersion(); known && major < 8 {
+		return 8
+	}
+	return 11
+}
+
+// ExternalUrlScheme returns the scheme of the external addresses of the cloud.
+// This is "https" if the Ingress or Istio Gateway terminates TLS for the external hosts, and the scheme of the Solr nodes otherwise.
+func (sc *SolrCloud) ExternalUrlScheme() string {
+	external := sc.Spec.SolrAddressability.External
+	if external != nil && external.IngressTLS != nil && (external.Method == Ingress || external.Method == Istio) {
+		return "https"
+	}
+	return sc.UrlScheme()
 }
 
 // HeadlessServiceName returns the name of the headless service for the cloud
@@ -751,6 +2165,33 @@ func (sc *SolrCloud) ProvidedZookeeperAddress() string {
 	return fmt.Sprintf("%s-solrcloud-zookeeper-client:2181", sc.GetName())
 }
 
+// ManualUpdatePartition returns the partition to restart pods at or above, if the cloud uses the Manual update method
+func (sc *SolrCloud) ManualUpdatePartition() (partition int32, isManual bool) {
+	strategy := sc.Spec.UpdateStrategy
+	if strategy == nil || strategy.Method != ManualUpdate {
+		return 0, false
+	}
+	if strategy.Manual != nil {
+		partition = strategy.Manual.Partition
+	}
+	return partition, true
+}
+
+// IsManagedUpdate returns whether the operator decides when the Solr pods are restarted after the StatefulSet changes
+func (sc *SolrCloud) IsManagedUpdate() bool {
+	return sc.Spec.UpdateStrategy == nil || sc.Spec.UpdateStrategy.Method == ManagedUpdate
+}
+
+// IsStandalone returns whether the cloud runs a standalone Solr, without Zookeeper
+func (sc *SolrCloud) IsStandalone() bool {
+	return sc.Spec.SolrMode == StandaloneMode
+}
+
+// UsesBackupRestoreVolume returns whether the cloud is configured to take backups, with either a shared or a per-node backup volume
+func (sc *SolrCloud) UsesBackupRestoreVolume() bool {
+	return sc.Spec.BackupRestoreVolume != nil || sc.Spec.BackupRestorePerNodePvcSpec != nil
+}
+
 // ZkConnectionString returns the zkConnectionString for the cloud
 func (sc *SolrCloud) ZkConnectionString() string {
 	return sc.Status.ZkConnectionString()
@@ -768,7 +2209,71 @@ func (scs SolrCloudStatus) DissectZkInfo() (zkConnectionString string, zkServer
 }
 
 func (zkInfo ZookeeperConnectionInfo) ZkConnectionString() string {
-	return zkInfo.InternalConnectionString + zkInfo.ChRoot
+	return zkInfo.zkHosts() + zkInfo.ChRoot
+}
+
+// zkHosts returns the hosts of the internal connection string, on the secure client port if one is provided
+func (zkInfo ZookeeperConnectionInfo) zkHosts() string {
+	if zkInfo.TLS == nil || zkInfo.TLS.SecureClientPort == 0 {
+		return zkInfo.InternalConnectionString
+	}
+	hosts := strings.Split(zkInfo.InternalConnectionString, ",")
+	for i, host := range hosts {
+		if hostname, _, err := net.SplitHostPort(strings.TrimSpace(host)); err == nil {
+			hosts[i] = net.JoinHostPort(hostname, strconv.Itoa(int(zkInfo.TLS.SecureClientPort)))
+		}
+	}
+	return strings.Join(hosts, ",")
+}
+
+// Validate returns an error if the connection information does not contain a usable list of ZooKeeper hosts.
+// Each host must be given as host:port, since Solr will not start without a complete connection string.
+func (zkInfo ZookeeperConnectionInfo) Validate() error {
+	if err := validateZkHosts("internalConnectionString", zkInfo.InternalConnectionString); err != nil {
+		return err
+	}
+	if zkInfo.ExternalConnectionString != nil {
+		if err := validateZkHosts("externalConnectionString", *zkInfo.ExternalConnectionString); err != nil {
+			return err
+		}
+	}
+	if strings.ContainsAny(zkInfo.ChRoot, ", ") {
+		return fmt.Errorf("the ZooKeeper chroot %q must not contain commas or spaces", zkInfo.ChRoot)
+	}
+	if zkInfo.ReadOnlyACL != nil && zkInfo.AllACL == nil {
+		return fmt.Errorf("the ZooKeeper readOnlyAcl requires an acl with all permissions")
+	}
+	if (zkInfo.AllACL != nil && zkInfo.AllACL.SecretRef == "") || (zkInfo.ReadOnlyACL != nil && zkInfo.ReadOnlyACL.SecretRef == "") {
+		return fmt.Errorf("the ZooKeeper ACLs must reference a Secret")
+	}
+	if zkInfo.TLS != nil {
+		return zkInfo.TLS.Validate()
+	}
+	return nil
+}
+
+// ReaderACL returns the ACL that the znodes of Solr are read with, which is the readOnlyAcl if one is provided
+func (zkInfo ZookeeperConnectionInfo) ReaderACL() *ZookeeperACL {
+	if zkInfo.ReadOnlyACL != nil {
+		return zkInfo.ReadOnlyACL
+	}
+	return zkInfo.AllACL
+}
+
+func validateZkHosts(field string, connectionString string) error {
+	if strings.TrimSpace(connectionString) == "" {
+		return fmt.Errorf("no ZooKeeper hosts were provided in the %s", field)
+	}
+	for _, host := range strings.Split(connectionString, ",") {
+		hostname, port, err := net.SplitHostPort(strings.TrimSpace(host))
+		if err != nil || hostname == "" || port == "" {
+			return fmt.Errorf("the ZooKeeper host %q in the %s must be of the form host:port", host, field)
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			return fmt.Errorf("the ZooKeeper host %q in the %s has an invalid port", host, field)
+		}
+	}
+	return nil
 }
 
 // UsesHeadlessService returns whether the given solrCloud requires a headless service to be created for it.
@@ -783,9 +2288,36 @@ func (sc *SolrCloud) UsesIndividualNodeServices() bool {
 	return sc.Spec.SolrAddressability.External.UsesIndividualNodeServices()
 }
 
+const (
+	// HeadlessServiceAddressing means that the Solr nodes are addressed through the DNS entries of the headless service
+	HeadlessServiceAddressing = "headlessService"
+
+	// NodeServiceAddressing means that the Solr nodes are addressed through their individual node services
+	NodeServiceAddressing = "nodeServices"
+)
+
+// NodeAddressing returns how the Solr nodes are addressed within the Kubernetes cluster.
+// Switching between the two changes the name that every Solr node registers under in ZooKeeper.
+func (sc *SolrCloud) NodeAddressing() string {
+	if sc.UsesIndividualNodeServices() {
+		return NodeServiceAddressing
+	}
+	return HeadlessServiceAddressing
+}
+
 func (extOpts *ExternalAddressability) UsesIndividualNodeServices() bool {
-	// LoadBalancer and Ingress will not work with headless services if each pod needs to be exposed externally.
-	return extOpts != nil && !extOpts.HideNodes && (extOpts.Method == Ingress || extOpts.Method == LoadBalancer)
+	// LoadBalancer, NodePort and Ingress will not work with headless services if each pod needs to be exposed externally.
+	return extOpts != nil && !extOpts.HideNodes && (extOpts.UsesIngressHosts() || extOpts.Method == LoadBalancer || extOpts.Method == NodePort)
+}
+
+// UsesIngressHosts returns true if the Solr services are exposed under their own hostnames through an ingress controller, by an Ingress, an Istio Gateway or OpenShift Routes
+func (extOpts *ExternalAddressability) UsesIngressHosts() bool {
+	return extOpts != nil && (extOpts.Method == Ingress || extOpts.Method == Istio || extOpts.Method == Route)
+}
+
+// AdvertisesNodePorts returns true if each Solr node advertises itself with the nodePort of its own Service, rather than a port shared by all nodes
+func (extOpts *ExternalAddressability) AdvertisesNodePorts() bool {
+	return extOpts != nil && extOpts.UseExternalAddress && extOpts.Method == NodePort
 }
 
 func (sc *SolrCloud) CommonExternalPrefix() string {
@@ -796,8 +2328,56 @@ func (sc *SolrCloud) CommonExternalUrl(domainName string) string {
 	return fmt.Sprintf("%s.%s", sc.CommonExternalPrefix(), domainName)
 }
 
-func (sc *SolrCloud) NodeIngressPrefix(nodeName string) string {
-	return fmt.Sprintf("%s-%s", sc.Namespace, nodeName)
+// NodeIngressHost returns the hostname of the given Solr node under the given domain, rendered from the nodeHostPattern
+func (sc *SolrCloud) NodeIngressHost(nodeName string, domainName string) string {
+	pattern := DefaultNodeHostPattern
+	if external := sc.Spec.SolrAddressability.External; external != nil && external.NodeHostPattern != "" {
+		pattern = external.NodeHostPattern
+	}
+	ordinal := nodeName[strings.LastIndex(nodeName, "-")+1:]
+	return strings.NewReplacer(
+		"{namespace}", sc.Namespace,
+		"{cloud}", sc.Name,
+		"{node}", nodeName,
+		"{ordinal}", ordinal,
+		"{domain}", domainName,
+	).Replace(pattern)
+}
+
+// ValidateNodeHostPattern returns an error if the nodeHostPattern does not render valid and unique hostnames for all Solr nodes and domains
+func (sc *SolrCloud) ValidateNodeHostPattern() error {
+	external := sc.Spec.SolrAddressability.External
+	if !external.UsesIngressHosts() || external.HideNodes || external.NodeHostPattern == "" {
+		return nil
+	}
+	pattern := external.NodeHostPattern
+	if !strings.Contains(pattern, "{node}") && !strings.Contains(pattern, "{ordinal}") {
+		return fmt.Errorf("the nodeHostPattern %q must contain {node} or {ordinal}, so that each node has a unique hostname", pattern)
+	}
+	if external.UseExternalAddress && strings.Contains(pattern, "{ordinal}") {
+		return fmt.Errorf("the nodeHostPattern %q cannot contain {ordinal} when useExternalAddress is true, use {node} instead", pattern)
+	}
+
+	domainNames := append([]string{external.DomainName}, external.AdditionalDomainNames...)
+	hosts := map[string]string{}
+	for _, nodeName := range sc.GetAllSolrNodeNames() {
+		for _, domainName := range domainNames {
+			host := sc.NodeIngressHost(nodeName, domainName)
+			if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+				return fmt.Errorf("the nodeHostPattern %q renders the invalid hostname %q for node %s: %s", pattern, host, nodeName, strings.Join(errs, ", "))
+			}
+			for _, label := range strings.Split(host, ".") {
+				if errs := validation.IsDNS1123Label(label); len(errs) > 0 {
+					return fmt.Errorf("the nodeHostPattern %q renders the invalid hostname %q for node %s: %s", pattern, host, nodeName, strings.Join(errs, ", "))
+				}
+			}
+			if otherNode, duplicate := hosts[host]; duplicate && otherNode != nodeName {
+				return fmt.Errorf("the nodeHostPattern %q renders the same hostname %q for nodes %s and %s", pattern, host, otherNode, nodeName)
+			}
+			hosts[host] = nodeName
+		}
+	}
+	return nil
 }
 
 func (sc *SolrCloud) ExternalDnsDomain(domainName string) string {
@@ -812,16 +2392,27 @@ func (sc *SolrCloud) customKubeDomain() string {
 	}
 }
 
+// NodeHeadlessHost returns the hostname of the Solr node in the DNS of the headless service.
+// It only depends on the name of the node and the spec of the cloud, so the generated pods, the controller and the status always agree on it.
+func (sc *SolrCloud) NodeHeadlessHost(nodeName string) string {
+	return fmt.Sprintf("%s.%s.%s", nodeName, sc.HeadlessServiceName(), sc.Namespace) + sc.customKubeDomain()
+}
+
 func (sc *SolrCloud) NodeHeadlessUrl(nodeName string, withPort bool) (url string) {
-	url = fmt.Sprintf("%s.%s.%s", nodeName, sc.HeadlessServiceName(), sc.Namespace) + sc.customKubeDomain()
+	url = sc.NodeHeadlessHost(nodeName)
 	if withPort {
 		url += sc.NodePortSuffix()
 	}
 	return url
 }
 
+// NodeServiceHost returns the hostname of the individual service of the Solr node
+func (sc *SolrCloud) NodeServiceHost(nodeName string) string {
+	return fmt.Sprintf("%s.%s", nodeName, sc.Namespace) + sc.customKubeDomain()
+}
+
 func (sc *SolrCloud) NodeServiceUrl(nodeName string, withPort bool) (url string) {
-	url = fmt.Sprintf("%s.%s", nodeName, sc.Namespace) + sc.customKubeDomain()
+	url = sc.NodeServiceHost(nodeName)
 	if withPort {
 		url += sc.NodePortSuffix()
 	}
@@ -846,6 +2437,26 @@ func (sc *SolrCloud) NodePort() int {
 	return port
 }
 
+// ExternalNodePort returns the port of the external addresses of the Solr nodes, the advertisedPort if one is given and the port of the node services otherwise.
+// This is not used by the NodePort method, which exposes every node on a separate nodePort.
+func (sc *SolrCloud) ExternalNodePort() int {
+	if external := sc.Spec.SolrAddressability.External; external != nil && external.AdvertisedPort > 0 {
+		return external.AdvertisedPort
+	}
+	return sc.NodePort()
+}
+
+// CommonNodePort returns the nodePort of the common Service, when using the NodePort method
+func (sc *SolrCloud) CommonNodePort() int {
+	return sc.Spec.SolrAddressability.External.NodePortBase
+}
+
+// NodeServiceNodePort returns the nodePort of the Service of the given Solr node, when using the NodePort method
+func (sc *SolrCloud) NodeServiceNodePort(nodeName string) int {
+	ordinal, _ := strconv.Atoi(nodeName[strings.LastIndex(nodeName, "-")+1:])
+	return sc.Spec.SolrAddressability.External.NodePortBase + 1 + ordinal
+}
+
 // PortToSuffix returns the url suffix for a port.
 // Port 80 does not require a suffix, as it is the default port for HTTP.
 func PortToSuffix(port int) string {
@@ -855,14 +2466,20 @@ func PortToSuffix(port int) string {
 	return ":" + strconv.Itoa(port)
 }
 
-func (sc *SolrCloud) InternalNodeUrl(nodeName string, withPort bool) string {
-	if sc.UsesHeadlessService() {
-		return sc.NodeHeadlessUrl(nodeName, withPort)
-	} else if sc.UsesIndividualNodeServices() {
-		return sc.NodeServiceUrl(nodeName, withPort)
-	} else {
-		return ""
+// InternalNodeHost returns the hostname of the Solr node within the Kubernetes cluster, depending on how the nodes are addressed
+func (sc *SolrCloud) InternalNodeHost(nodeName string) string {
+	if sc.NodeAddressing() == NodeServiceAddressing {
+		return sc.NodeServiceHost(nodeName)
+	}
+	return sc.NodeHeadlessHost(nodeName)
+}
+
+func (sc *SolrCloud) InternalNodeUrl(nodeName string, withPort bool) (url string) {
+	url = sc.InternalNodeHost(nodeName)
+	if withPort {
+		url += sc.NodePortSuffix()
 	}
+	return url
 }
 
 func (sc *SolrCloud) InternalCommonUrl(withPort bool) (url string) {
@@ -874,23 +2491,36 @@ func (sc *SolrCloud) InternalCommonUrl(withPort bool) (url string) {
 }
 
 func (sc *SolrCloud) ExternalNodeUrl(nodeName string, domainName string, withPort bool) (url string) {
-	if sc.Spec.SolrAddressability.External.Method == Ingress {
-		url = fmt.Sprintf("%s.%s", sc.NodeIngressPrefix(nodeName), domainName)
+	if sc.Spec.SolrAddressability.External.UsesIngressHosts() {
+		url = sc.NodeIngressHost(nodeName, domainName)
 	} else if sc.Spec.SolrAddressability.External.Method == ExternalDNS {
 		url = fmt.Sprintf("%s.%s", nodeName, sc.ExternalDnsDomain(domainName))
+	} else if sc.Spec.SolrAddressability.External.Method == NodePort {
+		// Every Kubernetes node forwards the nodePort of the Service to the Solr node, so the domain is shared by all Solr nodes
+		url = urlHost(domainName)
+		if withPort {
+			url += PortToSuffix(sc.NodeServiceNodePort(nodeName))
+		}
+		return url
 	}
 	// TODO: Add LoadBalancer stuff here
 	if withPort {
-		url += sc.NodePortSuffix()
+		url += PortToSuffix(sc.ExternalNodePort())
 	}
 	return url
 }
 
 func (sc *SolrCloud) ExternalCommonUrl(domainName string, withPort bool) (url string) {
-	if sc.Spec.SolrAddressability.External.Method == Ingress {
+	if sc.Spec.SolrAddressability.External.UsesIngressHosts() {
 		url = fmt.Sprintf("%s.%s", sc.CommonExternalPrefix(), domainName)
 	} else if sc.Spec.SolrAddressability.External.Method == ExternalDNS {
 		url = fmt.Sprintf("%s.%s", sc.CommonServiceName(), sc.ExternalDnsDomain(domainName))
+	} else if sc.Spec.SolrAddressability.External.Method == NodePort {
+		url = urlHost(domainName)
+		if withPort {
+			url += PortToSuffix(sc.CommonNodePort())
+		}
+		return url
 	}
 	if withPort {
 		url += sc.CommonPortSuffix()
@@ -898,13 +2528,48 @@ func (sc *SolrCloud) ExternalCommonUrl(domainName string, withPort bool) (url st
 	return url
 }
 
+// urlHost returns the given host in the form used in URLs, which requires IPv6 addresses to be enclosed in brackets
+func urlHost(host string) string {
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "[" + host + "]"
+	}
+	return host
+}
+
 func (sc *SolrCloud) AdvertisedNodeHost(nodeName string) string {
 	external := sc.Spec.SolrAddressability.External
 	if external != nil && external.UseExternalAddress {
 		return sc.ExternalNodeUrl(nodeName, sc.Spec.SolrAddressability.External.DomainName, false)
 	} else {
-		return sc.InternalNodeUrl(nodeName, false)
+		return sc.InternalNodeHost(nodeName)
+	}
+}
+
+// AdvertisedNodePort returns the port that the given Solr node advertises itself with
+func (sc *SolrCloud) AdvertisedNodePort(nodeName string) int {
+	external := sc.Spec.SolrAddressability.External
+	if sc.Spec.SolrAddressability.HostNetwork {
+		return sc.Spec.SolrAddressability.PodPort
+	} else if external.AdvertisesNodePorts() {
+		return sc.NodeServiceNodePort(nodeName)
+	} else if external != nil && external.UseExternalAddress {
+		return sc.ExternalNodePort()
+	}
+	return sc.NodePort()
+}
+
+// LiveNodeName returns the name that the given Solr node registers under in the live_nodes of the cluster state
+func (sc *SolrCloud) LiveNodeName(nodeName string) string {
+	return fmt.Sprintf("%s:%d_solr", sc.AdvertisedNodeHost(nodeName), sc.AdvertisedNodePort(nodeName))
+}
+
+// PodLiveNodeName returns the name that the given Solr node registers under in the live_nodes of the cluster state, given the IP address of its pod.
+// The IP address is only used when the cloud uses the host network, since the Solr nodes advertise it instead of a hostname.
+func (sc *SolrCloud) PodLiveNodeName(nodeName string, podIP string) string {
+	if sc.Spec.SolrAddressability.HostNetwork {
+		return fmt.Sprintf("%s:%d_solr", urlHost(podIP), sc.AdvertisedNodePort(nodeName))
 	}
+	return sc.LiveNodeName(nodeName)
 }
 
 func (sc *SolrCloud) SharedLabels() map[string]string {
@@ -18,7 +18,9 @@ package v1beta1
 
 import (
 	"fmt"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"regexp"
 )
 
 const (
@@ -31,6 +33,8 @@ type SolrPrometheusExporterSpec struct {
 	SolrReference `json:"solrReference"`
 
 	// Image of Solr Prometheus Exporter to run.
+	// If not provided, and the exporter references a SolrCloud running in the kubernetes cluster,
+	// then the image of that SolrCloud will be used.
 	// +optional
 	Image *ContainerImage `json:"image,omitempty"`
 
@@ -62,15 +66,47 @@ type SolrPrometheusExporterSpec struct {
 	// The xml config for the metrics
 	// +optional
 	Config string `json:"metricsConfig,omitempty"`
+
+	// Only scrape the collection-level metrics of the given collections, while still scraping the node and JVM metrics.
+	// The operator generates the exporter config for these collections, so this cannot be used together with metricsConfig.
+	// +optional
+	MetricsCollections []string `json:"metricsCollections,omitempty"`
+
+	// Also scrape the core-level metrics of the cores that belong to the metricsCollections.
+	// This can only be used together with metricsCollections.
+	// +optional
+	ScrapeCores bool `json:"scrapeCores,omitempty"`
+
+	// The heap size to give the exporter JVM, e.g. "1g". This is passed to the exporter through the JAVA_HEAP environment variable.
+	// Defaults to the exporter's default heap size.
+	// +optional
+	JavaMemory string `json:"javaMemory,omitempty"`
+
+	// Options for exposing the metrics endpoint outside of the Kubernetes cluster through an Ingress.
+	// +optional
+	External *ExporterExternalAddressability `json:"external,omitempty"`
+
+	// Options for generating a prometheus-operator ServiceMonitor for the metrics Service.
+	// This will only take effect if the ServiceMonitor CRD is installed in the Kubernetes cluster.
+	// +optional
+	ServiceMonitor *ServiceMonitorOptions `json:"serviceMonitor,omitempty"`
+
+	// Scale the exporter Deployment to zero replicas, while keeping its Service and ConfigMap.
+	// The previous number of replicas is restored when the exporter is no longer suspended.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 func (ps *SolrPrometheusExporterSpec) withDefaults(namespace string) (changed bool) {
 	changed = ps.SolrReference.withDefaults(namespace) || changed
 
-	if ps.Image == nil {
-		ps.Image = &ContainerImage{}
+	// The image is taken from the SolrCloud if one is referenced, and no image is provided
+	if ps.Image != nil || !ps.SolrReference.referencesSolrCloud() {
+		if ps.Image == nil {
+			ps.Image = &ContainerImage{}
+		}
+		changed = ps.Image.withDefaults(DefaultSolrRepo, DefaultSolrVersion, DefaultPullPolicy) || changed
 	}
-	changed = ps.Image.withDefaults(DefaultSolrRepo, DefaultSolrVersion, DefaultPullPolicy) || changed
 
 	if ps.NumThreads == 0 {
 		ps.NumThreads = 1
@@ -90,15 +126,38 @@ type SolrReference struct {
 	// Reference of a standalone solr instance
 	// +optional
 	Standalone *StandaloneSolrReference `json:"standalone,omitempty"`
+
+	// Settings to connect to a Solr instance that is using TLS.
+	// +optional
+	SolrTLS *SolrTLSOptions `json:"solrTLS,omitempty"`
+}
+
+// referencesSolrCloud returns whether the reference is to a SolrCloud running in the kubernetes cluster
+func (sr *SolrReference) referencesSolrCloud() bool {
+	return sr.Cloud != nil && sr.Cloud.Name != ""
 }
 
 func (sr *SolrReference) withDefaults(namespace string) (changed bool) {
 	if sr.Cloud != nil {
 		changed = sr.Cloud.withDefaults(namespace) || changed
 	}
+	if sr.Standalone != nil {
+		changed = sr.Standalone.withDefaults(namespace) || changed
+	}
 	return changed
 }
 
+// ReferencedSolrCloud returns the name and namespace of the SolrCloud that is referenced by name, either as a cloud or a standalone Solr.
+// An empty name is returned if no SolrCloud is referenced by name.
+func (sr *SolrReference) ReferencedSolrCloud() (name string, namespace string) {
+	if sr.Cloud != nil && sr.Cloud.Name != "" {
+		return sr.Cloud.Name, sr.Cloud.Namespace
+	} else if sr.Standalone != nil && sr.Standalone.Name != "" {
+		return sr.Standalone.Name, sr.Standalone.Namespace
+	}
+	return "", ""
+}
+
 // SolrCloudReference defines a reference to an internal or external solrCloud.
 // Internal (to the kube cluster) clouds should be specified via the Name and Namespace options.
 // External clouds should be specified by their Zookeeper connection information.
@@ -132,8 +191,26 @@ func (scr *SolrCloudReference) withDefaults(namespace string) (changed bool) {
 
 // SolrPrometheusExporterStatus defines the observed state of SolrPrometheusExporter
 type StandaloneSolrReference struct {
-	// The address of the standalone solr
-	Address string `json:"address"`
+	// The address of the standalone solr.
+	// Defaults to the address of the referenced standalone SolrCloud, if a name is provided.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// The name of a SolrCloud running in Standalone mode within the kubernetes cluster
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// The namespace of a SolrCloud running in Standalone mode within the kubernetes cluster
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (ssr *StandaloneSolrReference) withDefaults(namespace string) (changed bool) {
+	if ssr.Name != "" && ssr.Namespace == "" {
+		ssr.Namespace = namespace
+		changed = true
+	}
+	return changed
 }
 
 type CustomExporterKubeOptions struct {
@@ -152,6 +229,43 @@ type CustomExporterKubeOptions struct {
 	// ServiceOptions defines the custom options for the solrPrometheusExporter ConfigMap.
 	// +optional
 	ConfigMapOptions *ConfigMapOptions `json:"configMapOptions,omitempty"`
+
+	// IngressOptions defines the custom options for the solrPrometheusExporter Ingress.
+	// +optional
+	IngressOptions *IngressOptions `json:"ingressOptions,omitempty"`
+}
+
+// ExporterExternalAddressability defines how to expose the metrics endpoint outside of the Kubernetes cluster
+type ExporterExternalAddressability struct {
+	// The domain name that the Ingress host will be created under.
+	// The host will take the form "<namespace>-<name>-solr-metrics.<domainName>".
+	DomainName string `json:"domainName"`
+
+	// The name of a Secret containing a TLS certificate for the Ingress host.
+	// If provided, the external address of the exporter will use https.
+	// +optional
+	IngressTLSSecret string `json:"ingressTLSSecret,omitempty"`
+}
+
+// ServiceMonitorOptions defines how to create a prometheus-operator ServiceMonitor for the metrics Service
+type ServiceMonitorOptions struct {
+	// Create a ServiceMonitor for the metrics Service.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// The interval at which prometheus should scrape the metrics endpoint, e.g. "30s".
+	// Defaults to the prometheus default.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+
+	// The timeout for prometheus scrapes of the metrics endpoint, e.g. "10s".
+	// Defaults to the prometheus default.
+	// +optional
+	ScrapeTimeout string `json:"scrapeTimeout,omitempty"`
+
+	// Labels to be added for the ServiceMonitor, generally used to match a Prometheus' serviceMonitorSelector.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // SolrPrometheusExporterStatus defines the observed state of SolrPrometheusExporter
@@ -160,12 +274,80 @@ type SolrPrometheusExporterStatus struct {
 	// InternalAddress string `json:"internalAddress"`
 
 	// An address the prometheus exporter can be connected to from outside of the Kube cluster
-	// Will only be provided when an ingressUrl is provided for the cloud
+	// Will only be provided when spec.external is provided for the exporter
 	// +optional
-	// ExternalAddress string `json:"externalAddress,omitempty"`
+	ExternalAddress string `json:"externalAddress,omitempty"`
 
 	// Is the prometheus exporter up and running
+	// +optional
 	Ready bool `json:"ready"`
+
+	// Conditions describe the state of the prometheus exporter
+	// +optional
+	Conditions []SolrPrometheusExporterCondition `json:"conditions,omitempty"`
+}
+
+// SolrPrometheusExporterConditionType is a type of condition that a SolrPrometheusExporter can be in
+type SolrPrometheusExporterConditionType string
+
+const (
+	// ExporterSuspended is True when the exporter Deployment has been scaled to zero, because spec.suspend is set
+	ExporterSuspended SolrPrometheusExporterConditionType = "Suspended"
+
+	// ExporterRequiredAPIUnavailable is True when the exporter uses a feature whose API is not served by the Kubernetes cluster, such as an Ingress or ServiceMonitor
+	ExporterRequiredAPIUnavailable SolrPrometheusExporterConditionType = "RequiredAPIUnavailable"
+)
+
+// SolrPrometheusExporterCondition describes the state of a SolrPrometheusExporter at a certain point
+type SolrPrometheusExporterCondition struct {
+	// Type of the condition
+	Type SolrPrometheusExporterConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// The last time the condition transitioned from one status to another
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// The reason for the condition's last transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating details about the transition
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// GetCondition returns the condition of the given type, or nil if the status does not have one
+func (spes *SolrPrometheusExporterStatus) GetCondition(conditionType SolrPrometheusExporterConditionType) *SolrPrometheusExporterCondition {
+	for i := range spes.Conditions {
+		if spes.Conditions[i].Type == conditionType {
+			return &spes.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition sets the condition of the given type, only changing the lastTransitionTime if the status of the condition changes
+func (spes *SolrPrometheusExporterStatus) SetCondition(conditionType SolrPrometheusExporterConditionType, status corev1.ConditionStatus, reason string, message string) {
+	condition := spes.GetCondition(conditionType)
+	if condition == nil {
+		spes.Conditions = append(spes.Conditions, SolrPrometheusExporterCondition{Type: conditionType})
+		condition = &spes.Conditions[len(spes.Conditions)-1]
+	}
+	if condition.Status != status {
+		condition.Status = status
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+}
+
+// IsConditionTrue returns whether the status has a condition of the given type with a status of True
+func (spes *SolrPrometheusExporterStatus) IsConditionTrue(conditionType SolrPrometheusExporterConditionType) bool {
+	condition := spes.GetCondition(conditionType)
+	return condition != nil && condition.Status == corev1.ConditionTrue
 }
 
 // +kubebuilder:object:root=true
@@ -190,6 +372,73 @@ func (spe *SolrPrometheusExporter) WithDefaults() bool {
 	return spe.Spec.withDefaults(spe.Namespace)
 }
 
+// collectionNameRegex matches the names that Solr allows for collections
+var collectionNameRegex = regexp.MustCompile(`^[._A-Za-z0-9][-._A-Za-z0-9]*$`)
+
+// ValidateMetricsConfig returns an error if the options for the metrics config of the exporter are ambiguous or invalid
+func (spe *SolrPrometheusExporter) ValidateMetricsConfig() error {
+	if spe.Spec.Config != "" && (len(spe.Spec.MetricsCollections) > 0 || spe.Spec.ScrapeCores) {
+		return fmt.Errorf("metricsCollections and scrapeCores cannot be used together with a custom metricsConfig")
+	}
+	if spe.Spec.ScrapeCores && len(spe.Spec.MetricsCollections) == 0 {
+		return fmt.Errorf("scrapeCores can only be used together with metricsCollections")
+	}
+	for _, collection := range spe.Spec.MetricsCollections {
+		if !collectionNameRegex.MatchString(collection) {
+			return fmt.Errorf("invalid collection name %q in metricsCollections", collection)
+		}
+	}
+	return nil
+}
+
+// UsesMetricsConfigMap returns whether the exporter reads its config from a ConfigMap, either provided by the user or generated for the metricsCollections
+func (spe *SolrPrometheusExporter) UsesMetricsConfigMap() bool {
+	return spe.Spec.Config != "" || len(spe.Spec.MetricsCollections) > 0
+}
+
+// ImageForSolrCloud returns the image that the exporter should run.
+// If no image is provided in the spec, the image of the referenced SolrCloud is used. If the SolrCloud is not available,
+// then the default Solr image is used.
+func (spe *SolrPrometheusExporter) ImageForSolrCloud(solrCloud *SolrCloud) *ContainerImage {
+	if spe.Spec.Image != nil {
+		return spe.Spec.Image
+	}
+	image := &ContainerImage{}
+	if solrCloud != nil && solrCloud.Spec.SolrImage != nil {
+		image = solrCloud.Spec.SolrImage.DeepCopy()
+	}
+	image.withDefaults(DefaultSolrRepo, DefaultSolrVersion, DefaultPullPolicy)
+	return image
+}
+
+// SolrTLSForSolrCloud returns the TLS options that the exporter connects to Solr with.
+// If none are provided in the spec, the keystore and truststore of the referenced SolrCloud are used, if they are provided in Secrets of the namespace of the exporter.
+// The keystore of the SolrCloud is then presented as the client certificate when the SolrCloud asks its clients for one.
+func (spe *SolrPrometheusExporter) SolrTLSForSolrCloud(solrCloud *SolrCloud) *SolrTLSOptions {
+	if spe.Spec.SolrReference.SolrTLS != nil {
+		return spe.Spec.SolrReference.SolrTLS
+	}
+	if solrCloud == nil || solrCloud.Spec.SolrTLS == nil || solrCloud.UsesCertManager() || solrCloud.Namespace != spe.Namespace {
+		return nil
+	}
+	serverTLS := solrCloud.Spec.SolrTLS
+	tlsOptions := &SolrTLSOptions{
+		TrustStoreSecret:         serverTLS.PKCS12Secret,
+		TrustStorePasswordSecret: serverTLS.KeyStorePasswordSecret,
+	}
+	if serverTLS.TrustStoreSecret != nil {
+		tlsOptions.TrustStoreSecret = serverTLS.TrustStoreSecret
+		if serverTLS.TrustStorePasswordSecret != nil {
+			tlsOptions.TrustStorePasswordSecret = serverTLS.TrustStorePasswordSecret
+		}
+	}
+	if serverTLS.NeedClientAuth || serverTLS.WantClientAuth {
+		tlsOptions.KeyStoreSecret = serverTLS.PKCS12Secret
+		tlsOptions.KeyStorePasswordSecret = serverTLS.KeyStorePasswordSecret
+	}
+	return tlsOptions.DeepCopy()
+}
+
 func (spe *SolrPrometheusExporter) SharedLabels() map[string]string {
 	return spe.SharedLabelsWith(map[string]string{})
 }
@@ -222,6 +471,11 @@ func (sc *SolrPrometheusExporter) MetricsServiceName() string {
 	return fmt.Sprintf("%s-solr-metrics", sc.GetName())
 }
 
+// MetricsServiceMonitorName returns the name of the metrics ServiceMonitor for the cloud
+func (sc *SolrPrometheusExporter) MetricsServiceMonitorName() string {
+	return fmt.Sprintf("%s-solr-metrics", sc.GetName())
+}
+
 func (sc *SolrPrometheusExporter) MetricsIngressPrefix() string {
 	return fmt.Sprintf("%s-%s-solr-metrics", sc.Namespace, sc.Name)
 }
@@ -230,6 +484,11 @@ func (sc *SolrPrometheusExporter) MetricsIngressUrl(ingressBaseUrl string) strin
 	return fmt.Sprintf("%s.%s", sc.MetricsIngressPrefix(), ingressBaseUrl)
 }
 
+// MetricsIngressName returns the name of the metrics ingress for the exporter
+func (sc *SolrPrometheusExporter) MetricsIngressName() string {
+	return fmt.Sprintf("%s-solr-metrics", sc.GetName())
+}
+
 // +kubebuilder:object:root=true
 
 // SolrPrometheusExporterList contains a list of SolrPrometheusExporter
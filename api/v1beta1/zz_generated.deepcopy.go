@@ -22,8 +22,10 @@ package v1beta1
 
 import (
 	zookeeperv1beta1 "github.com/pravega/zookeeper-operator/pkg/apis/zookeeper/v1beta1"
-	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -71,6 +73,26 @@ func (in *BackupPersistenceStatus) DeepCopy() *BackupPersistenceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRetryPolicy) DeepCopyInto(out *BackupRetryPolicy) {
+	*out = *in
+	if in.RetryBackoff != nil {
+		in, out := &in.RetryBackoff, &out.RetryBackoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetryPolicy.
+func (in *BackupRetryPolicy) DeepCopy() *BackupRetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CollectionBackupStatus) DeepCopyInto(out *CollectionBackupStatus) {
 	*out = *in
@@ -78,6 +100,10 @@ func (in *CollectionBackupStatus) DeepCopyInto(out *CollectionBackupStatus) {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
 	}
+	if in.RetryTime != nil {
+		in, out := &in.RetryTime, &out.RetryTime
+		*out = (*in).DeepCopy()
+	}
 	if in.FinishTime != nil {
 		in, out := &in.FinishTime, &out.FinishTime
 		*out = (*in).DeepCopy()
@@ -116,6 +142,13 @@ func (in *ConfigMapOptions) DeepCopyInto(out *ConfigMapOptions) {
 			(*out)[key] = val
 		}
 	}
+	if in.AdditionalData != nil {
+		in, out := &in.AdditionalData, &out.AdditionalData
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapOptions.
@@ -166,6 +199,11 @@ func (in *CustomExporterKubeOptions) DeepCopyInto(out *CustomExporterKubeOptions
 		*out = new(ConfigMapOptions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IngressOptions != nil {
+		in, out := &in.IngressOptions, &out.IngressOptions
+		*out = new(IngressOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomExporterKubeOptions.
@@ -216,6 +254,11 @@ func (in *CustomSolrKubeOptions) DeepCopyInto(out *CustomSolrKubeOptions) {
 		*out = new(IngressOptions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ServiceAccountOptions != nil {
+		in, out := &in.ServiceAccountOptions, &out.ServiceAccountOptions
+		*out = new(ServiceAccountOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomSolrKubeOptions.
@@ -245,6 +288,11 @@ func (in *DeploymentOptions) DeepCopyInto(out *DeploymentOptions) {
 			(*out)[key] = val
 		}
 	}
+	if in.Strategy != nil {
+		in, out := &in.Strategy, &out.Strategy
+		*out = new(v1.DeploymentStrategy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentOptions.
@@ -257,6 +305,21 @@ func (in *DeploymentOptions) DeepCopy() *DeploymentOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterExternalAddressability) DeepCopyInto(out *ExporterExternalAddressability) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterExternalAddressability.
+func (in *ExporterExternalAddressability) DeepCopy() *ExporterExternalAddressability {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterExternalAddressability)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalAddressability) DeepCopyInto(out *ExternalAddressability) {
 	*out = *in
@@ -265,6 +328,16 @@ func (in *ExternalAddressability) DeepCopyInto(out *ExternalAddressability) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IngressTLS != nil {
+		in, out := &in.IngressTLS, &out.IngressTLS
+		*out = new(SolrIngressTLSOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Istio != nil {
+		in, out := &in.Istio, &out.Istio
+		*out = new(SolrIstioOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalAddressability.
@@ -294,6 +367,11 @@ func (in *IngressOptions) DeepCopyInto(out *IngressOptions) {
 			(*out)[key] = val
 		}
 	}
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressOptions.
@@ -306,6 +384,36 @@ func (in *IngressOptions) DeepCopy() *IngressOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LiveNodesCheckOptions) DeepCopyInto(out *LiveNodesCheckOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LiveNodesCheckOptions.
+func (in *LiveNodesCheckOptions) DeepCopy() *LiveNodesCheckOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(LiveNodesCheckOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManualUpdateOptions) DeepCopyInto(out *ManualUpdateOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManualUpdateOptions.
+func (in *ManualUpdateOptions) DeepCopy() *ManualUpdateOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ManualUpdateOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OldZookeeperSpec) DeepCopyInto(out *OldZookeeperSpec) {
 	*out = *in
@@ -321,7 +429,7 @@ func (in *OldZookeeperSpec) DeepCopyInto(out *OldZookeeperSpec) {
 	}
 	if in.PersistentVolumeClaimSpec != nil {
 		in, out := &in.PersistentVolumeClaimSpec, &out.PersistentVolumeClaimSpec
-		*out = new(v1.PersistentVolumeClaimSpec)
+		*out = new(corev1.PersistentVolumeClaimSpec)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.Persistence != nil {
@@ -372,7 +480,7 @@ func (in *PodOptions) DeepCopyInto(out *PodOptions) {
 	*out = *in
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
@@ -385,12 +493,24 @@ func (in *PodOptions) DeepCopyInto(out *PodOptions) {
 	}
 	if in.PodSecurityContext != nil {
 		in, out := &in.PodSecurityContext, &out.PodSecurityContext
-		*out = new(v1.PodSecurityContext)
+		*out = new(corev1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerSecurityContext != nil {
+		in, out := &in.ContainerSecurityContext, &out.ContainerSecurityContext
+		*out = new(corev1.SecurityContext)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.EnvVariables != nil {
 		in, out := &in.EnvVariables, &out.EnvVariables
-		*out = make([]v1.EnvVar, len(*in))
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]corev1.EnvFromSource, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -411,7 +531,7 @@ func (in *PodOptions) DeepCopyInto(out *PodOptions) {
 	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
+		*out = make([]corev1.Toleration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -425,19 +545,60 @@ func (in *PodOptions) DeepCopyInto(out *PodOptions) {
 	}
 	if in.LivenessProbe != nil {
 		in, out := &in.LivenessProbe, &out.LivenessProbe
-		*out = new(v1.Probe)
+		*out = new(corev1.Probe)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.ReadinessProbe != nil {
 		in, out := &in.ReadinessProbe, &out.ReadinessProbe
-		*out = new(v1.Probe)
+		*out = new(corev1.Probe)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.StartupProbe != nil {
 		in, out := &in.StartupProbe, &out.StartupProbe
-		*out = new(v1.Probe)
+		*out = new(corev1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(corev1.Lifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.SidecarContainers != nil {
+		in, out := &in.SidecarContainers, &out.SidecarContainers
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(corev1.PodDNSConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]corev1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodOptions.
@@ -487,6 +648,57 @@ func (in *S3Secrets) DeepCopy() *S3Secrets {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceAccountOptions) DeepCopyInto(out *ServiceAccountOptions) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceAccountOptions.
+func (in *ServiceAccountOptions) DeepCopy() *ServiceAccountOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceAccountOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitorOptions) DeepCopyInto(out *ServiceMonitorOptions) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitorOptions.
+func (in *ServiceMonitorOptions) DeepCopy() *ServiceMonitorOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitorOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceOptions) DeepCopyInto(out *ServiceOptions) {
 	*out = *in
@@ -504,6 +716,21 @@ func (in *ServiceOptions) DeepCopyInto(out *ServiceOptions) {
 			(*out)[key] = val
 		}
 	}
+	if in.LoadBalancerSourceRanges != nil {
+		in, out := &in.LoadBalancerSourceRanges, &out.LoadBalancerSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SessionAffinityConfig != nil {
+		in, out := &in.SessionAffinityConfig, &out.SessionAffinityConfig
+		*out = new(corev1.SessionAffinityConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPFamily != nil {
+		in, out := &in.IPFamily, &out.IPFamily
+		*out = new(corev1.IPFamily)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceOptions.
@@ -536,6 +763,35 @@ func (in *SolrAddressabilityOptions) DeepCopy() *SolrAddressabilityOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrAutoscalingOptions) DeepCopyInto(out *SolrAutoscalingOptions) {
+	*out = *in
+	if in.ClusterPolicy != nil {
+		in, out := &in.ClusterPolicy, &out.ClusterPolicy
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterPreferences != nil {
+		in, out := &in.ClusterPreferences, &out.ClusterPreferences
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrAutoscalingOptions.
+func (in *SolrAutoscalingOptions) DeepCopy() *SolrAutoscalingOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrAutoscalingOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrBackup) DeepCopyInto(out *SolrBackup) {
 	*out = *in
@@ -563,6 +819,22 @@ func (in *SolrBackup) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrBackupCondition) DeepCopyInto(out *SolrBackupCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrBackupCondition.
+func (in *SolrBackupCondition) DeepCopy() *SolrBackupCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrBackupCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrBackupList) DeepCopyInto(out *SolrBackupList) {
 	*out = *in
@@ -604,6 +876,11 @@ func (in *SolrBackupSpec) DeepCopyInto(out *SolrBackupSpec) {
 		copy(*out, *in)
 	}
 	in.Persistence.DeepCopyInto(&out.Persistence)
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(BackupRetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrBackupSpec.
@@ -636,6 +913,13 @@ func (in *SolrBackupStatus) DeepCopyInto(out *SolrBackupStatus) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SolrBackupCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrBackupStatus.
@@ -648,6 +932,57 @@ func (in *SolrBackupStatus) DeepCopy() *SolrBackupStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrCertManagerIssuerRef) DeepCopyInto(out *SolrCertManagerIssuerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrCertManagerIssuerRef.
+func (in *SolrCertManagerIssuerRef) DeepCopy() *SolrCertManagerIssuerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrCertManagerIssuerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrCertManagerOptions) DeepCopyInto(out *SolrCertManagerOptions) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.AdditionalDNSNames != nil {
+		in, out := &in.AdditionalDNSNames, &out.AdditionalDNSNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ContainerImage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrCertManagerOptions.
+func (in *SolrCertManagerOptions) DeepCopy() *SolrCertManagerOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrCertManagerOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrCloud) DeepCopyInto(out *SolrCloud) {
 	*out = *in
@@ -675,6 +1010,22 @@ func (in *SolrCloud) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrCloudCondition) DeepCopyInto(out *SolrCloudCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrCloudCondition.
+func (in *SolrCloudCondition) DeepCopy() *SolrCloudCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrCloudCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrCloudList) DeepCopyInto(out *SolrCloudList) {
 	*out = *in
@@ -748,47 +1099,153 @@ func (in *SolrCloudSpec) DeepCopyInto(out *SolrCloudSpec) {
 	in.SolrPod.DeepCopyInto(&out.SolrPod)
 	if in.DataPvcSpec != nil {
 		in, out := &in.DataPvcSpec, &out.DataPvcSpec
-		*out = new(v1.PersistentVolumeClaimSpec)
+		*out = new(corev1.PersistentVolumeClaimSpec)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.BackupRestoreVolume != nil {
 		in, out := &in.BackupRestoreVolume, &out.BackupRestoreVolume
-		*out = new(v1.VolumeSource)
+		*out = new(corev1.VolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackupRestorePerNodePvcSpec != nil {
+		in, out := &in.BackupRestorePerNodePvcSpec, &out.BackupRestorePerNodePvcSpec
+		*out = new(corev1.PersistentVolumeClaimSpec)
 		(*in).DeepCopyInto(*out)
 	}
 	in.CustomSolrKubeOptions.DeepCopyInto(&out.CustomSolrKubeOptions)
+	if in.PodTemplateOverrides != nil {
+		in, out := &in.PodTemplateOverrides, &out.PodTemplateOverrides
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	in.SolrAddressability.DeepCopyInto(&out.SolrAddressability)
 	if in.BusyBoxImage != nil {
 		in, out := &in.BusyBoxImage, &out.BusyBoxImage
 		*out = new(ContainerImage)
 		**out = **in
 	}
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrCloudSpec.
-func (in *SolrCloudSpec) DeepCopy() *SolrCloudSpec {
-	if in == nil {
-		return nil
+	if in.SolrJavaMemPercentage != nil {
+		in, out := &in.SolrJavaMemPercentage, &out.SolrJavaMemPercentage
+		*out = new(int32)
+		**out = **in
 	}
-	out := new(SolrCloudSpec)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SolrCloudStatus) DeepCopyInto(out *SolrCloudStatus) {
-	*out = *in
-	if in.SolrNodes != nil {
-		in, out := &in.SolrNodes, &out.SolrNodes
-		*out = make([]SolrNodeStatus, len(*in))
+	if in.SolrModules != nil {
+		in, out := &in.SolrModules, &out.SolrModules
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.ExternalCommonAddress != nil {
-		in, out := &in.ExternalCommonAddress, &out.ExternalCommonAddress
-		*out = new(string)
-		**out = **in
+	if in.PluginLibs != nil {
+		in, out := &in.PluginLibs, &out.PluginLibs
+		*out = new(SolrPluginLibsOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Jetty != nil {
+		in, out := &in.Jetty, &out.Jetty
+		*out = new(SolrJettyOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodSystemProperties != nil {
+		in, out := &in.PodSystemProperties, &out.PodSystemProperties
+		*out = make([]SolrPodSystemProperty, len(*in))
+		copy(*out, *in)
+	}
+	if in.ZkClientTimeout != nil {
+		in, out := &in.ZkClientTimeout, &out.ZkClientTimeout
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DataStorage != nil {
+		in, out := &in.DataStorage, &out.DataStorage
+		*out = new(SolrDataStorageOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SolrLogs != nil {
+		in, out := &in.SolrLogs, &out.SolrLogs
+		*out = new(SolrLogsOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(SolrUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LiveNodesCheck != nil {
+		in, out := &in.LiveNodesCheck, &out.LiveNodesCheck
+		*out = new(LiveNodesCheckOptions)
+		**out = **in
+	}
+	if in.ClusterProperties != nil {
+		in, out := &in.ClusterProperties, &out.ClusterProperties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(SolrAutoscalingOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SolrSecurity != nil {
+		in, out := &in.SolrSecurity, &out.SolrSecurity
+		*out = new(SolrSecurityOptions)
+		**out = **in
+	}
+	if in.SolrTLS != nil {
+		in, out := &in.SolrTLS, &out.SolrTLS
+		*out = new(SolrServerTLSOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrCloudSpec.
+func (in *SolrCloudSpec) DeepCopy() *SolrCloudSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrCloudSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrCloudStatus) DeepCopyInto(out *SolrCloudStatus) {
+	*out = *in
+	if in.SolrNodes != nil {
+		in, out := &in.SolrNodes, &out.SolrNodes
+		*out = make([]SolrNodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExternalCommonAddress != nil {
+		in, out := &in.ExternalCommonAddress, &out.ExternalCommonAddress
+		*out = new(string)
+		**out = **in
 	}
 	in.ZookeeperConnectionInfo.DeepCopyInto(&out.ZookeeperConnectionInfo)
+	if in.BackupRestoreMissingPods != nil {
+		in, out := &in.BackupRestoreMissingPods, &out.BackupRestoreMissingPods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LiveNodes != nil {
+		in, out := &in.LiveNodes, &out.LiveNodes
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SolrCloudCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ClusterOperation != nil {
+		in, out := &in.ClusterOperation, &out.ClusterOperation
+		*out = new(SolrClusterOperation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrCloudStatus.
@@ -801,6 +1258,22 @@ func (in *SolrCloudStatus) DeepCopy() *SolrCloudStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrClusterOperation) DeepCopyInto(out *SolrClusterOperation) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrClusterOperation.
+func (in *SolrClusterOperation) DeepCopy() *SolrClusterOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrClusterOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrCollection) DeepCopyInto(out *SolrCollection) {
 	*out = *in
@@ -997,9 +1470,264 @@ func (in *SolrCollectionStatus) DeepCopy() *SolrCollectionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrDataInitContainerOptions) DeepCopyInto(out *SolrDataInitContainerOptions) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ContainerImage)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrDataInitContainerOptions.
+func (in *SolrDataInitContainerOptions) DeepCopy() *SolrDataInitContainerOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrDataInitContainerOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrDataStorageOptions) DeepCopyInto(out *SolrDataStorageOptions) {
+	*out = *in
+	if in.InitContainer != nil {
+		in, out := &in.InitContainer, &out.InitContainer
+		*out = new(SolrDataInitContainerOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Persistent != nil {
+		in, out := &in.Persistent, &out.Persistent
+		*out = new(SolrPersistentDataStorageOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(corev1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tlog != nil {
+		in, out := &in.Tlog, &out.Tlog
+		*out = new(SolrTlogStorageOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrDataStorageOptions.
+func (in *SolrDataStorageOptions) DeepCopy() *SolrDataStorageOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrDataStorageOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrGCLogsOptions) DeepCopyInto(out *SolrGCLogsOptions) {
+	*out = *in
+	if in.FileCount != nil {
+		in, out := &in.FileCount, &out.FileCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PersistentVolumeClaimSpec != nil {
+		in, out := &in.PersistentVolumeClaimSpec, &out.PersistentVolumeClaimSpec
+		*out = new(corev1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(corev1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JavaVersion != nil {
+		in, out := &in.JavaVersion, &out.JavaVersion
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrGCLogsOptions.
+func (in *SolrGCLogsOptions) DeepCopy() *SolrGCLogsOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrGCLogsOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrIngressTLSOptions) DeepCopyInto(out *SolrIngressTLSOptions) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrIngressTLSOptions.
+func (in *SolrIngressTLSOptions) DeepCopy() *SolrIngressTLSOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrIngressTLSOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrIstioOptions) DeepCopyInto(out *SolrIstioOptions) {
+	*out = *in
+	if in.GatewaySelector != nil {
+		in, out := &in.GatewaySelector, &out.GatewaySelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrIstioOptions.
+func (in *SolrIstioOptions) DeepCopy() *SolrIstioOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrIstioOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrJettyOptions) DeepCopyInto(out *SolrJettyOptions) {
+	*out = *in
+	if in.RequestHeaderSize != nil {
+		in, out := &in.RequestHeaderSize, &out.RequestHeaderSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinThreads != nil {
+		in, out := &in.MinThreads, &out.MinThreads
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxThreads != nil {
+		in, out := &in.MaxThreads, &out.MaxThreads
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IdleTimeoutMillis != nil {
+		in, out := &in.IdleTimeoutMillis, &out.IdleTimeoutMillis
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrJettyOptions.
+func (in *SolrJettyOptions) DeepCopy() *SolrJettyOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrJettyOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrLocalStorageOptions) DeepCopyInto(out *SolrLocalStorageOptions) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrLocalStorageOptions.
+func (in *SolrLocalStorageOptions) DeepCopy() *SolrLocalStorageOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrLocalStorageOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrLog4j2ConfigOptions) DeepCopyInto(out *SolrLog4j2ConfigOptions) {
+	*out = *in
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrLog4j2ConfigOptions.
+func (in *SolrLog4j2ConfigOptions) DeepCopy() *SolrLog4j2ConfigOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrLog4j2ConfigOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrLogsOptions) DeepCopyInto(out *SolrLogsOptions) {
+	*out = *in
+	if in.PersistentVolumeClaimSpec != nil {
+		in, out := &in.PersistentVolumeClaimSpec, &out.PersistentVolumeClaimSpec
+		*out = new(corev1.PersistentVolumeClaimSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(corev1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Log4j2Config != nil {
+		in, out := &in.Log4j2Config, &out.Log4j2Config
+		*out = new(SolrLog4j2ConfigOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCLogs != nil {
+		in, out := &in.GCLogs, &out.GCLogs
+		*out = new(SolrGCLogsOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrLogsOptions.
+func (in *SolrLogsOptions) DeepCopy() *SolrLogsOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrLogsOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrNodeStatus) DeepCopyInto(out *SolrNodeStatus) {
 	*out = *in
+	if in.Live != nil {
+		in, out := &in.Live, &out.Live
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrNodeStatus.
@@ -1012,12 +1740,102 @@ func (in *SolrNodeStatus) DeepCopy() *SolrNodeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrPersistentDataStorageOptions) DeepCopyInto(out *SolrPersistentDataStorageOptions) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Local != nil {
+		in, out := &in.Local, &out.Local
+		*out = new(SolrLocalStorageOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPersistentDataStorageOptions.
+func (in *SolrPersistentDataStorageOptions) DeepCopy() *SolrPersistentDataStorageOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrPersistentDataStorageOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrPluginLibsOptions) DeepCopyInto(out *SolrPluginLibsOptions) {
+	*out = *in
+	if in.URLs != nil {
+		in, out := &in.URLs, &out.URLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeSource != nil {
+		in, out := &in.VolumeSource, &out.VolumeSource
+		*out = new(corev1.VolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ContainerImage)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPluginLibsOptions.
+func (in *SolrPluginLibsOptions) DeepCopy() *SolrPluginLibsOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrPluginLibsOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrPodPolicy) DeepCopyInto(out *SolrPodPolicy) {
 	*out = *in
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
@@ -1033,13 +1851,28 @@ func (in *SolrPodPolicy) DeepCopy() *SolrPodPolicy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrPodSystemProperty) DeepCopyInto(out *SolrPodSystemProperty) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPodSystemProperty.
+func (in *SolrPodSystemProperty) DeepCopy() *SolrPodSystemProperty {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrPodSystemProperty)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrPrometheusExporter) DeepCopyInto(out *SolrPrometheusExporter) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPrometheusExporter.
@@ -1060,6 +1893,22 @@ func (in *SolrPrometheusExporter) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrPrometheusExporterCondition) DeepCopyInto(out *SolrPrometheusExporterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPrometheusExporterCondition.
+func (in *SolrPrometheusExporterCondition) DeepCopy() *SolrPrometheusExporterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrPrometheusExporterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrPrometheusExporterList) DeepCopyInto(out *SolrPrometheusExporterList) {
 	*out = *in
@@ -1103,6 +1952,21 @@ func (in *SolrPrometheusExporterSpec) DeepCopyInto(out *SolrPrometheusExporterSp
 	}
 	in.PodPolicy.DeepCopyInto(&out.PodPolicy)
 	in.CustomKubeOptions.DeepCopyInto(&out.CustomKubeOptions)
+	if in.MetricsCollections != nil {
+		in, out := &in.MetricsCollections, &out.MetricsCollections
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.External != nil {
+		in, out := &in.External, &out.External
+		*out = new(ExporterExternalAddressability)
+		**out = **in
+	}
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitorOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPrometheusExporterSpec.
@@ -1118,6 +1982,13 @@ func (in *SolrPrometheusExporterSpec) DeepCopy() *SolrPrometheusExporterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SolrPrometheusExporterStatus) DeepCopyInto(out *SolrPrometheusExporterStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]SolrPrometheusExporterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrPrometheusExporterStatus.
@@ -1143,6 +2014,11 @@ func (in *SolrReference) DeepCopyInto(out *SolrReference) {
 		*out = new(StandaloneSolrReference)
 		**out = **in
 	}
+	if in.SolrTLS != nil {
+		in, out := &in.SolrTLS, &out.SolrTLS
+		*out = new(SolrTLSOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrReference.
@@ -1155,6 +2031,146 @@ func (in *SolrReference) DeepCopy() *SolrReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrSecurityOptions) DeepCopyInto(out *SolrSecurityOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrSecurityOptions.
+func (in *SolrSecurityOptions) DeepCopy() *SolrSecurityOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrSecurityOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrServerTLSOptions) DeepCopyInto(out *SolrServerTLSOptions) {
+	*out = *in
+	if in.PKCS12Secret != nil {
+		in, out := &in.PKCS12Secret, &out.PKCS12Secret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KeyStorePasswordSecret != nil {
+		in, out := &in.KeyStorePasswordSecret, &out.KeyStorePasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustStoreSecret != nil {
+		in, out := &in.TrustStoreSecret, &out.TrustStoreSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustStorePasswordSecret != nil {
+		in, out := &in.TrustStorePasswordSecret, &out.TrustStorePasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(SolrCertManagerOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CheckPeerName != nil {
+		in, out := &in.CheckPeerName, &out.CheckPeerName
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrServerTLSOptions.
+func (in *SolrServerTLSOptions) DeepCopy() *SolrServerTLSOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrServerTLSOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrTLSOptions) DeepCopyInto(out *SolrTLSOptions) {
+	*out = *in
+	if in.TrustStoreSecret != nil {
+		in, out := &in.TrustStoreSecret, &out.TrustStoreSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustStorePasswordSecret != nil {
+		in, out := &in.TrustStorePasswordSecret, &out.TrustStorePasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KeyStoreSecret != nil {
+		in, out := &in.KeyStoreSecret, &out.KeyStoreSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KeyStorePasswordSecret != nil {
+		in, out := &in.KeyStorePasswordSecret, &out.KeyStorePasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrTLSOptions.
+func (in *SolrTLSOptions) DeepCopy() *SolrTLSOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrTLSOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrTlogStorageOptions) DeepCopyInto(out *SolrTlogStorageOptions) {
+	*out = *in
+	if in.Persistent != nil {
+		in, out := &in.Persistent, &out.Persistent
+		*out = new(SolrPersistentDataStorageOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EmptyDir != nil {
+		in, out := &in.EmptyDir, &out.EmptyDir
+		*out = new(corev1.EmptyDirVolumeSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrTlogStorageOptions.
+func (in *SolrTlogStorageOptions) DeepCopy() *SolrTlogStorageOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrTlogStorageOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SolrUpdateStrategy) DeepCopyInto(out *SolrUpdateStrategy) {
+	*out = *in
+	if in.Manual != nil {
+		in, out := &in.Manual, &out.Manual
+		*out = new(ManualUpdateOptions)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SolrUpdateStrategy.
+func (in *SolrUpdateStrategy) DeepCopy() *SolrUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(SolrUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StandaloneSolrReference) DeepCopyInto(out *StandaloneSolrReference) {
 	*out = *in
@@ -1216,6 +2232,36 @@ func (in *VolumePersistenceSource) DeepCopy() *VolumePersistenceSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperACL) DeepCopyInto(out *ZookeeperACL) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperACL.
+func (in *ZookeeperACL) DeepCopy() *ZookeeperACL {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperACL)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperClusterRef) DeepCopyInto(out *ZookeeperClusterRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperClusterRef.
+func (in *ZookeeperClusterRef) DeepCopy() *ZookeeperClusterRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperClusterRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ZookeeperConnectionInfo) DeepCopyInto(out *ZookeeperConnectionInfo) {
 	*out = *in
@@ -1224,6 +2270,21 @@ func (in *ZookeeperConnectionInfo) DeepCopyInto(out *ZookeeperConnectionInfo) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.AllACL != nil {
+		in, out := &in.AllACL, &out.AllACL
+		*out = new(ZookeeperACL)
+		**out = **in
+	}
+	if in.ReadOnlyACL != nil {
+		in, out := &in.ReadOnlyACL, &out.ReadOnlyACL
+		*out = new(ZookeeperACL)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(ZookeeperTLSOptions)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperConnectionInfo.
@@ -1241,7 +2302,7 @@ func (in *ZookeeperPodPolicy) DeepCopyInto(out *ZookeeperPodPolicy) {
 	*out = *in
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.NodeSelector != nil {
@@ -1253,7 +2314,7 @@ func (in *ZookeeperPodPolicy) DeepCopyInto(out *ZookeeperPodPolicy) {
 	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
+		*out = make([]corev1.Toleration, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -1284,6 +2345,11 @@ func (in *ZookeeperRef) DeepCopyInto(out *ZookeeperRef) {
 		*out = new(ZookeeperSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ZookeeperClusterRef != nil {
+		in, out := &in.ZookeeperClusterRef, &out.ZookeeperClusterRef
+		*out = new(ZookeeperClusterRef)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperRef.
@@ -1331,3 +2397,38 @@ func (in *ZookeeperSpec) DeepCopy() *ZookeeperSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZookeeperTLSOptions) DeepCopyInto(out *ZookeeperTLSOptions) {
+	*out = *in
+	if in.KeyStoreSecret != nil {
+		in, out := &in.KeyStoreSecret, &out.KeyStoreSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KeyStorePasswordSecret != nil {
+		in, out := &in.KeyStorePasswordSecret, &out.KeyStorePasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustStoreSecret != nil {
+		in, out := &in.TrustStoreSecret, &out.TrustStoreSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustStorePasswordSecret != nil {
+		in, out := &in.TrustStorePasswordSecret, &out.TrustStorePasswordSecret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZookeeperTLSOptions.
+func (in *ZookeeperTLSOptions) DeepCopy() *ZookeeperTLSOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ZookeeperTLSOptions)
+	in.DeepCopyInto(out)
+	return out
+}
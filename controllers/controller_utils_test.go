@@ -28,6 +28,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -96,8 +97,8 @@ func expectNoService(g *gomega.GomegaWithT, serviceKey types.NamespacedName, mes
 		Should(gomega.MatchError("Service \""+serviceKey.Name+"\" not found"), message)
 }
 
-func expectIngress(g *gomega.GomegaWithT, requests chan reconcile.Request, expectedRequest reconcile.Request, ingressKey types.NamespacedName) *extv1.Ingress {
-	ingress := &extv1.Ingress{}
+func expectIngress(g *gomega.GomegaWithT, requests chan reconcile.Request, expectedRequest reconcile.Request, ingressKey types.NamespacedName) *netv1.Ingress {
+	ingress := &netv1.Ingress{}
 	g.Eventually(func() error { return testClient.Get(context.TODO(), ingressKey, ingress) }, timeout).
 		Should(gomega.Succeed())
 
@@ -109,15 +110,15 @@ func expectIngress(g *gomega.GomegaWithT, requests chan reconcile.Request, expec
 
 	// Manually delete Ingress since GC isn't enabled in the test control plane
 	g.Eventually(func() error { return testClient.Delete(context.TODO(), ingress) }, timeout).
-		Should(gomega.MatchError("ingresses.extensions \"" + ingressKey.Name + "\" not found"))
+		Should(gomega.MatchError("ingresses.networking.k8s.io \"" + ingressKey.Name + "\" not found"))
 
 	return ingress
 }
 
 func expectNoIngress(g *gomega.GomegaWithT, ingressKey types.NamespacedName) {
-	ingress := &extv1.Ingress{}
+	ingress := &netv1.Ingress{}
 	g.Eventually(func() error { return testClient.Get(context.TODO(), ingressKey, ingress) }, timeout).
-		Should(gomega.MatchError("Ingress.extensions \"" + ingressKey.Name + "\" not found"))
+		Should(gomega.MatchError("Ingress.networking.k8s.io \"" + ingressKey.Name + "\" not found"))
 }
 
 func expectConfigMap(t *testing.T, g *gomega.GomegaWithT, requests chan reconcile.Request, expectedRequest reconcile.Request, configMapKey types.NamespacedName, configMapData map[string]string) *corev1.ConfigMap {
@@ -220,7 +221,7 @@ func cleanupTest(g *gomega.GomegaWithT, namespace string) {
 		&solr.SolrCloud{}, &solr.SolrBackup{}, &solr.SolrCollection{}, &solr.SolrCollectionAlias{}, &solr.SolrPrometheusExporter{},
 
 		// All dependent Kubernetes types, in order of dependence (deployment then replicaSet then pod)
-		&corev1.ConfigMap{}, &batchv1.Job{}, &extv1.Ingress{},
+		&corev1.ConfigMap{}, &batchv1.Job{}, &extv1.Ingress{}, &netv1.Ingress{},
 		&corev1.PersistentVolumeClaim{}, &corev1.PersistentVolume{},
 		&appsv1.StatefulSet{}, &appsv1.Deployment{}, &appsv1.ReplicaSet{}, &corev1.Pod{},
 	}
@@ -18,17 +18,20 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"time"
 
 	"github.com/bloomberg/solr-operator/controllers/util"
 	"github.com/go-logr/logr"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -40,16 +43,20 @@ import (
 // SolrBackupReconciler reconciles a SolrBackup object
 type SolrBackupReconciler struct {
 	client.Client
-	Log    logr.Logger
-	scheme *runtime.Scheme
-	config *rest.Config
+	Log      logr.Logger
+	scheme   *runtime.Scheme
+	config   *rest.Config
+	recorder record.EventRecorder
+
+	// Removes the data of a failed collection backup, before the backup is retried
+	deleteCollectionBackupData func(solrCloud *solrv1beta1.SolrCloud, backupName string, collection string) error
 }
 
 // +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds,verbs=get;list;watch
-// +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds/status,verbs=get
+// +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrbackups,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrbackups/status,verbs=get;update;patch
 
@@ -87,6 +94,9 @@ func (r *SolrBackupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 	solrCloud, allCollectionsComplete, collectionActionTaken, err := reconcileSolrCloudBackup(r, backup)
 	if err != nil {
 		r.Log.Error(err, "Error while taking SolrCloud backup")
+		if util.IsSolrApiError(err) {
+			requeueOrNot, err = solrApiErrorResult(r.recorder, backup, err)
+		}
 	}
 	if allCollectionsComplete && collectionActionTaken {
 		// Requeue immediately to start the persisting job
@@ -101,7 +111,14 @@ func (r *SolrBackupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		if allCollectionsComplete && !backup.Status.Finished {
 			// We will count on the Job updates to be notifified
 			requeueOrNot = reconcile.Result{}
-			err = persistSolrCloudBackups(r, backup, solrCloud)
+			if backup.Spec.Persistence.IsEmpty() {
+				// The backup data is kept in the backup volume of the cloud
+				tru := true
+				backup.Status.Finished = true
+				backup.Status.Successful = &tru
+			} else {
+				err = persistSolrCloudBackups(r, backup, solrCloud)
+			}
 		}
 		if err != nil {
 			r.Log.Error(err, "Error while persisting SolrCloud backup")
@@ -111,7 +128,9 @@ func (r *SolrBackupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 	if backup.Status.Finished && backup.Status.FinishTime == nil {
 		now := metav1.Now()
 		backup.Status.FinishTime = &now
-		backup.Status.Successful = backup.Status.PersistenceStatus.Successful
+		if backup.Status.PersistenceStatus.Successful != nil {
+			backup.Status.Successful = backup.Status.PersistenceStatus.Successful
+		}
 	}
 
 	if !reflect.DeepEqual(oldStatus, backup.Status) {
@@ -141,15 +160,22 @@ func reconcileSolrCloudBackup(r *SolrBackupReconciler, backup *solrv1beta1.SolrB
 	// First check if the collection backups have been completed
 	collectionBackupsFinished = util.CheckStatusOfCollectionBackups(backup)
 
-	// If the collectionBackups are complete, then nothing else has to be done here
+	// If the collectionBackups are complete, then nothing else has to be done here.
+	// The lock of the cloud is released here as well, in case the operator was stopped before it could be released.
 	if collectionBackupsFinished {
-		return solrCloud, collectionBackupsFinished, actionTaken, nil
+		err = releaseClusterOperationLock(r.Client, solrCloud, backupClusterOperation(backup))
+		return solrCloud, collectionBackupsFinished, actionTaken, err
 	}
 
 	actionTaken = true
 
 	// This should only occur before the backup processes have been started
 	if backup.Status.SolrVersion == "" {
+		// The persistence job cannot mount the volumes of every Solr node
+		if solrCloud.Spec.BackupRestorePerNodePvcSpec != nil && !backup.Spec.Persistence.IsEmpty() {
+			return solrCloud, collectionBackupsFinished, actionTaken, errors.NewBadRequest("Backups of a cloud with per-node backup volumes cannot be persisted, remove the persistence options of the backup")
+		}
+
 		// Prep the backup directory in the persistentVolume
 		err := util.EnsureDirectoryForBackup(solrCloud, backup.Name, r.config)
 		if err != nil {
@@ -167,18 +193,44 @@ func reconcileSolrCloudBackup(r *SolrBackupReconciler, backup *solrv1beta1.SolrB
 		backup.Status.SolrVersion = solrCloud.Status.Version
 	}
 
+	// Managed updates and scale downs of the cloud restart or remove the Solr nodes that the backup is taken from
+	holder, acquired, err := acquireClusterOperationLock(r.Client, solrCloud, backupClusterOperation(backup))
+	if err != nil {
+		return solrCloud, collectionBackupsFinished, actionTaken, err
+	}
+	if !acquired {
+		r.recorder.Eventf(backup, corev1.EventTypeNormal, "ClusterOperationLocked", "Waiting for the %s operation (%s) on SolrCloud %s to finish, before starting the backup", holder.Operation, holder.Metadata, solrCloud.Name)
+		return solrCloud, collectionBackupsFinished, actionTaken, nil
+	}
+
 	// Go through each collection specified and reconcile the backup.
+	apiClient, err := solrApiClientForCloud(r.Client, solrCloud)
+	if err != nil {
+		return solrCloud, collectionBackupsFinished, actionTaken, err
+	}
 	for _, collection := range backup.Spec.Collections {
-		_, err = reconcileSolrCollectionBackup(backup, solrCloud, collection)
+		_, err = reconcileSolrCollectionBackup(r, backup, solrCloud, apiClient, collection)
 	}
 
 	// First check if the collection backups have been completed
 	collectionBackupsFinished = util.CheckStatusOfCollectionBackups(backup)
+	if collectionBackupsFinished && err == nil {
+		err = releaseClusterOperationLock(r.Client, solrCloud, backupClusterOperation(backup))
+	}
 
 	return solrCloud, collectionBackupsFinished, actionTaken, err
 }
 
-func reconcileSolrCollectionBackup(backup *solrv1beta1.SolrBackup, solrCloud *solrv1beta1.SolrCloud, collection string) (finished bool, err error) {
+// backupClusterOperation returns the operation that holds the lock of the SolrCloud while the collections are backed up
+func backupClusterOperation(backup *solrv1beta1.SolrBackup) *solrv1beta1.SolrClusterOperation {
+	return &solrv1beta1.SolrClusterOperation{
+		Operation: solrv1beta1.BackupOperation,
+		StartTime: metav1.Now(),
+		Metadata:  backup.Name,
+	}
+}
+
+func reconcileSolrCollectionBackup(r *SolrBackupReconciler, backup *solrv1beta1.SolrBackup, solrCloud *solrv1beta1.SolrCloud, apiClient *util.SolrApiClient, collection string) (finished bool, err error) {
 	now := metav1.Now()
 	collectionBackupStatus := solrv1beta1.CollectionBackupStatus{}
 	collectionBackupStatus.Collection = collection
@@ -191,36 +243,62 @@ func reconcileSolrCollectionBackup(backup *solrv1beta1.SolrBackup, solrCloud *so
 		}
 	}
 
-	// If the collection backup hasn't started, start it
-	if !collectionBackupStatus.InProgress && !collectionBackupStatus.Finished {
+	// If the collection backup hasn't started, start it. A failed backup is only retried after the retry backoff.
+	if !collectionBackupStatus.InProgress && !collectionBackupStatus.Finished && (collectionBackupStatus.RetryTime == nil || !now.Before(collectionBackupStatus.RetryTime)) {
+		if collectionBackupStatus.RetryTime != nil {
+			// Solr will not backup a collection to a directory that already exists
+			if err = r.deleteCollectionBackupData(solrCloud, backup.Name, collection); err != nil {
+				return false, err
+			}
+			collectionBackupStatus.RetryTime = nil
+		}
 
 		// Start the backup by calling solr
-		started, err := util.StartBackupForCollection(solrCloud.Name, collection, backup.Name, backup.Namespace)
+		started, err := util.StartBackupForCollection(apiClient, collection, backup.Name)
 		if err != nil {
 			return true, err
 		}
 		collectionBackupStatus.InProgress = started
-		if started && collectionBackupStatus.StartTime == nil {
-			collectionBackupStatus.StartTime = &now
+		if started {
+			collectionBackupStatus.Attempts += 1
+			if collectionBackupStatus.StartTime == nil {
+				collectionBackupStatus.StartTime = &now
+			}
 		}
 	} else if collectionBackupStatus.InProgress {
 		// Check the state of the backup, when it is in progress, and update the state accordingly
-		finished, successful, asyncStatus, error := util.CheckBackupForCollection(solrCloud.Name, collection, backup.Name, backup.Namespace)
+		finished, successful, asyncStatus, message, error := util.CheckBackupForCollection(apiClient, collection, backup.Name)
 		if error != nil {
 			return false, error
 		}
-		collectionBackupStatus.Finished = finished
 		if finished {
 			collectionBackupStatus.InProgress = false
-			if collectionBackupStatus.Successful == nil {
-				collectionBackupStatus.Successful = &successful
-			}
 			collectionBackupStatus.AsyncBackupStatus = ""
-			if collectionBackupStatus.FinishTime == nil {
-				collectionBackupStatus.FinishTime = &now
+
+			if !successful {
+				collectionBackupStatus.LastError = message
+			}
+			retryPolicy := backup.Spec.RetryPolicy
+			if !successful && retryPolicy != nil && collectionBackupStatus.Attempts <= retryPolicy.MaxRetries {
+				// Retry the backup of only this collection, once the backoff has passed
+				retryTime := metav1.NewTime(now.Add(retryPolicy.RetryBackoff.Duration))
+				collectionBackupStatus.RetryTime = &retryTime
+				r.recorder.Eventf(backup, corev1.EventTypeWarning, "CollectionBackupFailed", "Backup of collection %s failed on attempt %d, retrying after %s: %s", collection, collectionBackupStatus.Attempts, retryPolicy.RetryBackoff.Duration, message)
+			} else {
+				collectionBackupStatus.Finished = true
+				if collectionBackupStatus.Successful == nil {
+					collectionBackupStatus.Successful = &successful
+				}
+				if collectionBackupStatus.FinishTime == nil {
+					collectionBackupStatus.FinishTime = &now
+				}
+				if !successful {
+					backup.Status.SetCondition(solrv1beta1.BackupFailed, corev1.ConditionTrue, "CollectionBackupFailed", fmt.Sprintf("Backup of collection %s failed after %d attempts: %s", collection, collectionBackupStatus.Attempts, message))
+				}
 			}
 
-			err = util.DeleteAsyncInfoForBackup(solrCloud.Name, collection, backup.Name, backup.Namespace)
+			// The async request id is reused when the backup is retried
+			err = util.DeleteAsyncInfoForBackup(apiClient, collection, backup.Name)
 		} else {
 			collectionBackupStatus.AsyncBackupStatus = asyncStatus
 		}
@@ -296,5 +374,9 @@ func (r *SolrBackupReconciler) SetupWithManagerAndReconciler(mgr ctrl.Manager, r
 
 	r.config = mgr.GetConfig()
 	r.scheme = mgr.GetScheme()
+	r.recorder = mgr.GetEventRecorderFor("solrbackup-controller")
+	r.deleteCollectionBackupData = func(solrCloud *solrv1beta1.SolrCloud, backupName string, collection string) error {
+		return util.DeleteCollectionBackupDirectory(solrCloud, backupName, collection, r.config)
+	}
 	return ctrlBuilder.Complete(reconciler)
 }
@@ -17,15 +17,24 @@ limitations under the License.
 package controllers
 
 import (
+	"fmt"
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
+	"github.com/bloomberg/solr-operator/controllers/util"
 	"github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"net/http"
+	"net/http/httptest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +80,106 @@ func TestBackupReconcile(t *testing.T) {
 	defer testClient.Delete(context.TODO(), instance)
 	g.Eventually(requests, timeout).Should(gomega.Receive(gomega.Equal(expectedBackupRequest)))
 }
+
+// fakeSolrBackupServer mimics the async backup APIs of Solr, failing the given number of backup attempts for each collection
+type fakeSolrBackupServer struct {
+	failures       map[string]int
+	backupRequests map[string]int
+}
+
+func (s *fakeSolrBackupServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	params := req.URL.Query()
+	switch params.Get("action") {
+	case "BACKUP":
+		s.backupRequests[params.Get("collection")] += 1
+		fmt.Fprint(w, `{"responseHeader":{"status":0}}`)
+	case "REQUESTSTATUS":
+		collection := strings.TrimPrefix(params.Get("requestid"), "foo-back-")
+		if s.backupRequests[collection] <= s.failures[collection] {
+			fmt.Fprintf(w, `{"responseHeader":{"status":0},"exception":{"msg":"Could not backup shard1 of %s","rspCode":500},"status":{"state":"failed","msg":"found [%s] in failed tasks"}}`, collection, params.Get("requestid"))
+		} else {
+			fmt.Fprint(w, `{"responseHeader":{"status":0},"status":{"state":"completed"}}`)
+		}
+	default:
+		fmt.Fprint(w, `{"responseHeader":{"status":0}}`)
+	}
+}
+
+func TestBackupRetryPolicy(t *testing.T) {
+	solrServer := &fakeSolrBackupServer{
+		failures:       map[string]int{"flaky": 1, "broken": 5},
+		backupRequests: map[string]int{},
+	}
+	server := httptest.NewServer(solrServer)
+	defer server.Close()
+
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-back-cloud", Namespace: expectedBackupRequest.Namespace},
+		Status:     solr.SolrCloudStatus{InternalCommonAddress: server.URL},
+	}
+	backup := &solr.SolrBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedBackupRequest.Name, Namespace: expectedBackupRequest.Namespace},
+		Spec: solr.SolrBackupSpec{
+			SolrCloud:   solrCloud.Name,
+			Collections: []string{"healthy", "flaky", "broken"},
+			RetryPolicy: &solr.BackupRetryPolicy{MaxRetries: 2, RetryBackoff: &metav1.Duration{}},
+		},
+		// Skip preparing the backup directory on the Solr pods
+		Status: solr.SolrBackupStatus{SolrVersion: "8.7.0"},
+	}
+	backup.WithDefaults()
+
+	var deletedCollectionData []string
+	reconciler := &SolrBackupReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, solrCloud, backup),
+		Log:      ctrl.Log.WithName("controllers").WithName("SolrBackup"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+		deleteCollectionBackupData: func(solrCloud *solr.SolrCloud, backupName string, collection string) error {
+			deletedCollectionData = append(deletedCollectionData, collection)
+			return nil
+		},
+	}
+
+	// Every reconcile either starts or checks on the backup of each collection
+	for i := 0; i < 8; i++ {
+		_, err := reconciler.Reconcile(expectedBackupRequest)
+		assert.NoError(t, err)
+	}
+	foundBackup := &solr.SolrBackup{}
+	assert.NoError(t, reconciler.Get(context.TODO(), expectedBackupRequest.NamespacedName, foundBackup))
+
+	// Only the failed collections are backed up again
+	assert.Equal(t, map[string]int{"healthy": 1, "flaky": 2, "broken": 3}, solrServer.backupRequests, "Wrong number of backup attempts per collection")
+	assert.Equal(t, []string{"flaky", "broken", "broken"}, deletedCollectionData, "The data of failed collection backups should be removed before retrying")
+
+	statuses := map[string]solr.CollectionBackupStatus{}
+	for _, status := range foundBackup.Status.CollectionBackupStatuses {
+		statuses[status.Collection] = status
+		assert.True(t, status.Finished, "The backup of collection %s should be finished", status.Collection)
+		assert.Nil(t, status.RetryTime, "The backup of collection %s should not be waiting for a retry", status.Collection)
+	}
+	assert.EqualValues(t, 1, statuses["healthy"].Attempts, "Wrong number of attempts recorded")
+	assert.EqualValues(t, 2, statuses["flaky"].Attempts, "Wrong number of attempts recorded")
+	assert.True(t, *statuses["flaky"].Successful, "A collection backup that succeeds on retry should be successful")
+	assert.Equal(t, "Could not backup shard1 of flaky", statuses["flaky"].LastError, "The error of the failed attempt should be recorded")
+
+	// A collection that keeps failing fails the backup, once all retries are used
+	assert.EqualValues(t, 3, statuses["broken"].Attempts, "Wrong number of attempts recorded")
+	assert.False(t, *statuses["broken"].Successful, "A collection backup that keeps failing should not be successful")
+	assert.True(t, foundBackup.Status.IsConditionTrue(solr.BackupFailed), "The backup should have a Failed condition")
+	assert.Equal(t, "Backup of collection broken failed after 3 attempts: Could not backup shard1 of broken", foundBackup.Status.GetCondition(solr.BackupFailed).Message, "The Failed condition should include the error from Solr")
+
+	// The lock of the cloud is released once all collections are backed up
+	foundCloud := &solr.SolrCloud{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: solrCloud.Name, Namespace: solrCloud.Namespace}, foundCloud))
+	assert.Nil(t, foundCloud.Status.ClusterOperation, "The backup should release the lock of the cloud")
+
+	// Without a retry policy, a failed collection backup is not retried
+	backup.Spec.RetryPolicy = nil
+	collectionStatus := solr.CollectionBackupStatus{Collection: "broken", InProgress: true, Attempts: 1}
+	backup.Status.CollectionBackupStatuses = []solr.CollectionBackupStatus{collectionStatus}
+	finished, err := reconcileSolrCollectionBackup(reconciler, backup, solrCloud, util.NewSolrApiClientForCloud(solrCloud), "broken")
+	assert.NoError(t, err)
+	assert.True(t, finished, "A failed collection backup should not be retried without a retry policy")
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"crypto/md5"
 	"fmt"
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
 	"github.com/bloomberg/solr-operator/controllers/util"
@@ -26,37 +27,91 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // SolrCloudReconciler reconciles a SolrCloud object
 type SolrCloudReconciler struct {
 	client.Client
-	scheme *runtime.Scheme
-	Log    logr.Logger
+	scheme   *runtime.Scheme
+	Log      logr.Logger
+	recorder record.EventRecorder
 }
 
 var useZkCRD bool
+var useIngressAPI = true
+var useLegacyIngressAPI bool
+var useIstioAPI bool
+var useRouteAPI bool
+var useCertManagerAPI bool
 var IngressBaseUrl string
+var KubeDomain string
 
 func UseZkCRD(useCRD bool) {
 	useZkCRD = useCRD
 }
 
+func UseIngressAPI(useAPI bool) {
+	useIngressAPI = useAPI
+}
+
+// UseIstioAPI manages the Istio Gateways and VirtualServices of SolrClouds using the Istio method, if Istio is installed in the Kubernetes cluster
+func UseIstioAPI(useAPI bool) {
+	useIstioAPI = useAPI
+}
+
+// UseRouteAPI manages the OpenShift Routes of SolrClouds using the Route method, if the Kubernetes cluster is an OpenShift cluster
+func UseRouteAPI(useAPI bool) {
+	useRouteAPI = useAPI
+}
+
+// UseCertManagerAPI manages the cert-manager Certificates of SolrClouds that issue their certificates through cert-manager, if cert-manager is installed in the Kubernetes cluster
+func UseCertManagerAPI(useAPI bool) {
+	useCertManagerAPI = useAPI
+}
+
+// UseLegacyIngressAPI manages Ingresses through the extensions/v1beta1 API, for Kubernetes clusters that do not serve the networking.k8s.io/v1 API
+func UseLegacyIngressAPI(useLegacyAPI bool) {
+	useLegacyIngressAPI = useLegacyAPI
+}
+
+// ingressObject is an Ingress of either the current or the legacy Ingress API
+type ingressObject interface {
+	metav1.Object
+	runtime.Object
+}
+
 func SetIngressBaseUrl(ingressBaseUrl string) {
 	IngressBaseUrl = ingressBaseUrl
 }
 
+// SetKubeDomain sets the Kubernetes cluster domain used by SolrClouds that do not override it, for clusters not using "cluster.local"
+func SetKubeDomain(kubeDomain string) {
+	KubeDomain = kubeDomain
+}
+
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods/status,verbs=get
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
@@ -65,12 +120,24 @@ func SetIngressBaseUrl(ingressBaseUrl string) {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=extensions,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=extensions,resources=ingresses/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get
+// +kubebuilder:rbac:groups=networking.istio.io,resources=gateways;virtualservices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=create;update
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrbackups,verbs=get;list;watch
 // +kubebuilder:rbac:groups=zookeeper.pravega.io,resources=zookeeperclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=zookeeper.pravega.io,resources=zookeeperclusters/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;update;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
@@ -89,7 +156,21 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return reconcile.Result{}, err
 	}
 
+	// The data PVCs are not owned by the cloud, so they are deleted by the operator if the reclaim policy requires it
+	if finalized, err := reconcileDataStorageFinalizer(r, instance); err != nil || finalized {
+		return reconcile.Result{}, err
+	}
+
 	changed := instance.WithDefaults(IngressBaseUrl)
+	// The operator's kubeDomain is only a default for new clouds, since it would give the nodes of a running cloud new names
+	if instance.Spec.SolrAddressability.KubeDomain == "" && KubeDomain != "" {
+		if err := r.Get(context.TODO(), types.NamespacedName{Name: instance.StatefulSetName(), Namespace: instance.Namespace}, &appsv1.StatefulSet{}); errors.IsNotFound(err) {
+			instance.Spec.SolrAddressability.KubeDomain = KubeDomain
+			changed = true
+		} else if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
 	if changed {
 		r.Log.Info("Setting default settings for solr-cloud", "namespace", instance.Namespace, "name", instance.Name)
 		if err := r.Update(context.TODO(), instance); err != nil {
@@ -98,16 +179,70 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return reconcile.Result{Requeue: true}, nil
 	}
 
+	// Reject a nodeHostPattern that gives the nodes invalid or conflicting hostnames, before creating any resources
+	if err := instance.ValidateNodeHostPattern(); err != nil {
+		return reconcile.Result{}, errors.NewBadRequest(err.Error())
+	}
+	if instance.Spec.SolrTLS != nil {
+		if urlScheme, hasUrlScheme := instance.Spec.ClusterProperties["urlScheme"]; hasUrlScheme && !strings.EqualFold(urlScheme, "https") {
+			return reconcile.Result{}, errors.NewBadRequest("The urlScheme cluster property must be https when TLS is enabled")
+		}
+		tlsOptions := instance.Spec.SolrTLS
+		if instance.UsesCertManager() && (tlsOptions.PKCS12Secret != nil || tlsOptions.KeyStorePasswordSecret != nil) {
+			return reconcile.Result{}, errors.NewBadRequest("The pkcs12Secret and keyStorePasswordSecret cannot be provided when the certificate is issued through cert-manager")
+		} else if !instance.UsesCertManager() && (tlsOptions.PKCS12Secret == nil || tlsOptions.KeyStorePasswordSecret == nil) {
+			return reconcile.Result{}, errors.NewBadRequest("The pkcs12Secret and keyStorePasswordSecret must be provided for TLS, unless the certificate is issued through cert-manager")
+		}
+		if tlsOptions.NeedClientAuth && tlsOptions.WantClientAuth {
+			return reconcile.Result{}, errors.NewBadRequest("Only one of needClientAuth and wantClientAuth can be set")
+		} else if tlsOptions.NeedClientAuth && instance.ClientCertSecretName() == "" {
+			return reconcile.Result{}, errors.NewBadRequest("The clientCertSecret must be provided with needClientAuth, unless the certificate is issued through cert-manager")
+		}
+	}
+	// Solr only reads the SOLR_MODULES from version 9 on, so the modules would silently not be loaded by earlier versions
+	if major, known := instance.SolrMajorVersion(); known && major < 9 && len(instance.Spec.SolrModules) > 0 {
+		return reconcile.Result{}, errors.NewBadRequest(fmt.Sprintf("The solrModules require Solr 9 or later, but the Solr image has version %s", instance.Spec.SolrImage.Tag))
+	}
+	if external := instance.Spec.SolrAddressability.External; external != nil && external.Method == solr.NodePort {
+		if external.NodePortBase == 0 {
+			return reconcile.Result{}, errors.NewBadRequest("The nodePortBase must be provided for the NodePort external method")
+		}
+		if external.DomainName == "" {
+			return reconcile.Result{}, errors.NewBadRequest("The domainName must be provided for the NodePort external method")
+		}
+	}
+
+	// Switching how the nodes of a running cloud are addressed would change the identity of every Solr node in ZooKeeper
+	if err := validateNodeAddressingChange(r, instance); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// When working with the clouds, some actions outside of kube may need to be retried after a few seconds
 	requeueOrNot := reconcile.Result{}
 
-	newStatus := solr.SolrCloudStatus{}
+	// Conditions track operations that span multiple reconciles, so they are carried over to the new status
+	newStatus := solr.SolrCloudStatus{Conditions: instance.Status.DeepCopy().Conditions}
+
+	// The cluster operation lock is also held by other reconcilers, so it is carried over as well
+	newStatus.ClusterOperation = instance.Status.DeepCopy().ClusterOperation
 
 	busyBoxImage := *instance.Spec.BusyBoxImage
 
 	blockReconciliationOfStatefulSet := false
 
-	if err := reconcileZk(r, req, instance, busyBoxImage, &newStatus); err != nil {
+	// Features that require an API which the Kubernetes cluster does not serve are skipped, instead of failing the reconcile
+	apiReason, apiMessage := unavailableAPIProblem(instance)
+	setProblemCondition(r, instance, &newStatus, solr.RequiredAPIUnavailable, apiReason, apiMessage, "APIsAvailable", "All APIs required by the cloud are available")
+
+	// A standalone Solr does not use Zookeeper
+	if !instance.IsStandalone() {
+		if err := reconcileZk(r, req, instance, busyBoxImage, &newStatus, &requeueOrNot); err != nil {
+			return requeueOrNot, err
+		}
+	}
+
+	// The credentials must exist before the Solr pods that read them are created
+	if err := reconcileSolrSecurity(r, instance); err != nil {
 		return requeueOrNot, err
 	}
 
@@ -135,6 +270,13 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	solrNodeNames := instance.GetAllSolrNodeNames()
 
+	// The certificate must be valid for the hostnames of all nodes, so it is reissued whenever the cloud is scaled up
+	if instance.UsesCertManager() && useCertManagerAPI {
+		if err := reconcileSolrTLS(r, instance, solrNodeNames); err != nil {
+			return requeueOrNot, err
+		}
+	}
+
 	hostNameIpMap := make(map[string]string)
 	// Generate a service for every Node
 	if instance.UsesIndividualNodeServices() {
@@ -144,7 +286,8 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				return requeueOrNot, err
 			}
 			// This IP Address only needs to be used in the hostname map if the SolrCloud is advertising the external address.
-			if instance.Spec.SolrAddressability.External.UseExternalAddress {
+			// Nodes advertising their nodePorts share a hostname, which must resolve to the Kubernetes nodes instead.
+			if instance.Spec.SolrAddressability.External.UseExternalAddress && !instance.Spec.SolrAddressability.External.AdvertisesNodePorts() {
 				if ip == "" {
 					// If we are using this IP in the hostAliases of the statefulSet, it needs to be set for every service before trying to update the statefulSet
 					blockReconciliationOfStatefulSet = true
@@ -155,6 +298,11 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	// Remove the services of Solr nodes that no longer exist, e.g. after the cloud is scaled down
+	if err := deleteUnusedNodeServices(r, instance, solrNodeNames); err != nil {
+		return requeueOrNot, err
+	}
+
 	// Generate HeadlessService
 	if instance.UsesHeadlessService() {
 		headless := util.GenerateHeadlessService(instance)
@@ -179,6 +327,18 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	}
 
 	// Generate ConfigMap
+	if configMapOptions := instance.Spec.CustomSolrKubeOptions.ConfigMapOptions; configMapOptions != nil {
+		for key := range configMapOptions.AdditionalData {
+			if util.IsReservedConfigMapKey(key) {
+				return requeueOrNot, errors.NewBadRequest(fmt.Sprintf("The ConfigMap key %s is generated by the operator and cannot be provided in the additionalData", key))
+			}
+		}
+	}
+	if log4j2Config := instance.CustomLog4j2Config(); log4j2Config != nil {
+		if err = log4j2Config.Validate(); err != nil {
+			return requeueOrNot, errors.NewBadRequest(err.Error())
+		}
+	}
 	configMap := util.GenerateConfigMap(instance)
 	if err := controllerutil.SetControllerReference(instance, configMap, r.scheme); err != nil {
 		return requeueOrNot, err
@@ -199,60 +359,225 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return requeueOrNot, err
 	}
 
-	// Only create stateful set if zkConnectionString can be found (must contain host and port)
-	if !strings.Contains(newStatus.ZkConnectionString(), ":") {
+	// The solr.xml is hashed, so that changes to it will restart the Solr pods
+	solrXml := configMap.Data[util.SolrXmlFile]
+	if providedConfigMap := instance.ProvidedSolrXmlConfigMap(); providedConfigMap != "" {
+		foundProvidedConfigMap := &corev1.ConfigMap{}
+		err = r.Get(context.TODO(), types.NamespacedName{Name: providedConfigMap, Namespace: instance.Namespace}, foundProvidedConfigMap)
+		if err == nil {
+			if err = util.ValidateProvidedSolrXml(foundProvidedConfigMap); err != nil {
+				return requeueOrNot, errors.NewBadRequest(err.Error())
+			}
+			solrXml = foundProvidedConfigMap.Data[util.SolrXmlFile]
+		} else if !errors.IsNotFound(err) {
+			return requeueOrNot, err
+		}
+		// A missing ConfigMap is reported along with the other missing references of the Solr pods
+	}
+
+	// A custom log4j2 configuration restarts the Solr pods in the same way
+	log4j2Xml := ""
+	if log4j2Config := instance.CustomLog4j2Config(); log4j2Config != nil {
+		log4j2Xml = configMap.Data[util.LogXmlFile]
+		if configMapRef := log4j2Config.ConfigMapRef; configMapRef != nil {
+			foundLog4j2ConfigMap := &corev1.ConfigMap{}
+			err = r.Get(context.TODO(), types.NamespacedName{Name: configMapRef.Name, Namespace: instance.Namespace}, foundLog4j2ConfigMap)
+			if err == nil {
+				var hasKey bool
+				if log4j2Xml, hasKey = foundLog4j2ConfigMap.Data[configMapRef.Key]; !hasKey && (configMapRef.Optional == nil || !*configMapRef.Optional) {
+					return requeueOrNot, errors.NewBadRequest(fmt.Sprintf("The ConfigMap %s does not contain the log4j2 configuration key %s", configMapRef.Name, configMapRef.Key))
+				}
+			} else if !errors.IsNotFound(err) {
+				return requeueOrNot, err
+			}
+		}
+	}
+
+	// Only create stateful set if zkConnectionString can be found (must contain host and port).
+	// Provided Zookeeper clusters will not have a connection string until they have been created.
+	if !instance.IsStandalone() && (newStatus.IsConditionTrue(solr.ZkConnectionInfoInvalid) || !strings.Contains(newStatus.ZkConnectionString(), ":")) {
 		blockReconciliationOfStatefulSet = true
 	}
 
+	// The solr.xml must be placed within the data volume
+	if _, err := instance.SolrHomeDataSubPath(); err != nil {
+		return requeueOrNot, err
+	}
+
+	if instance.Spec.DataPvcSpec != nil && instance.Spec.DataStorage != nil && instance.Spec.DataStorage.Persistent != nil {
+		return requeueOrNot, errors.NewBadRequest("Only one of the dataPvcSpec and the dataStorage.persistent options can be provided")
+	}
+
+	if instance.Spec.DataStorage != nil {
+		if persistent := instance.Spec.DataStorage.Persistent; persistent != nil && persistent.Local != nil && persistent.StorageClassName == nil {
+			return requeueOrNot, errors.NewBadRequest("The dataStorage.persistent.storageClassName must be provided for local PersistentVolumes")
+		}
+		if tlog := instance.Spec.DataStorage.Tlog; tlog != nil && tlog.Persistent != nil && tlog.Persistent.Local != nil && tlog.Persistent.StorageClassName == nil {
+			return requeueOrNot, errors.NewBadRequest("The dataStorage.tlog.persistent.storageClassName must be provided for local PersistentVolumes")
+		}
+	}
+
+	if instance.Spec.BackupRestoreVolume != nil && instance.Spec.BackupRestorePerNodePvcSpec != nil {
+		return requeueOrNot, errors.NewBadRequest("Only one of the backupRestoreVolume and the backupRestorePerNodePvcSpec can be provided")
+	}
+
+	if partition, isManual := instance.ManualUpdatePartition(); isManual && partition > *instance.Spec.Replicas {
+		return requeueOrNot, errors.NewBadRequest(fmt.Sprintf("The manual update partition %d must be between 0 and the number of replicas %d", partition, *instance.Spec.Replicas))
+	}
+
+	if err = util.ApplyPodTemplateOverrides(&corev1.PodTemplateSpec{}, instance.Spec.PodTemplateOverrides); err != nil {
+		return requeueOrNot, errors.NewBadRequest(fmt.Sprintf("The podTemplateOverrides cannot be applied: %s", err))
+	}
+
+	// The ServiceAccount must exist before the Solr pods that use it can be created
+	if err = reconcileServiceAccount(r, instance); err != nil {
+		return requeueOrNot, err
+	}
+
+	statefulSetUpdateRevision := ""
 	if !blockReconciliationOfStatefulSet {
 		// Generate StatefulSet
-		statefulSet := util.GenerateStatefulSet(instance, &newStatus, hostNameIpMap)
+		solrXmlMd5 := fmt.Sprintf("%x", md5.Sum([]byte(solrXml)))
+		statefulSet := util.GenerateStatefulSet(instance, &newStatus, hostNameIpMap, solrXmlMd5)
+		util.SetLog4j2ConfigMd5(statefulSet, log4j2Xml)
+		// Rotated certificates are only loaded when Solr restarts
+		tlsCertMd5, err := tlsCertificateMd5(r, instance)
+		if err != nil {
+			return requeueOrNot, err
+		}
+		util.SetTLSCertMd5(statefulSet, tlsCertMd5)
+		if instance.Spec.SolrJavaMemPercentage != nil && statefulSet.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().IsZero() {
+			return requeueOrNot, errors.NewBadRequest("The solrJavaMemPercentage requires a memory limit for the Solr container")
+		}
 		if err := controllerutil.SetControllerReference(instance, statefulSet, r.scheme); err != nil {
 			return requeueOrNot, err
 		}
 
-		// Check if the StatefulSet already exists
-		foundStatefulSet := &appsv1.StatefulSet{}
-		err = r.Get(context.TODO(), types.NamespacedName{Name: statefulSet.Name, Namespace: statefulSet.Namespace}, foundStatefulSet)
-		if err != nil && errors.IsNotFound(err) {
-			r.Log.Info("Creating StatefulSet", "namespace", statefulSet.Namespace, "name", statefulSet.Name)
-			err = r.Create(context.TODO(), statefulSet)
-		} else if err == nil {
-			if util.CopyStatefulSetFields(statefulSet, foundStatefulSet) {
-				// Update the found StatefulSet and write the result back if there are any changes
-				r.Log.Info("Updating StatefulSet", "namespace", statefulSet.Namespace, "name", statefulSet.Name)
-				err = r.Update(context.TODO(), foundStatefulSet)
-			}
-			newStatus.Replicas = foundStatefulSet.Status.Replicas
-			newStatus.ReadyReplicas = foundStatefulSet.Status.ReadyReplicas
-		}
+		// The Solr pods cannot start while a Secret or ConfigMap that they reference is missing
+		referencesResolved, err := reconcilePodReferences(r, instance, &statefulSet.Spec.Template.Spec, &newStatus)
 		if err != nil {
 			return requeueOrNot, err
 		}
+		if !referencesResolved {
+			requeueAfter(&requeueOrNot, MissingReferencesCheckInterval)
+		} else {
+			// Check if the StatefulSet already exists
+			foundStatefulSet := &appsv1.StatefulSet{}
+			err = r.Get(context.TODO(), types.NamespacedName{Name: statefulSet.Name, Namespace: statefulSet.Namespace}, foundStatefulSet)
+			if err != nil && errors.IsNotFound(err) {
+				r.Log.Info("Creating StatefulSet", "namespace", statefulSet.Namespace, "name", statefulSet.Name)
+				err = r.Create(context.TODO(), statefulSet)
+			} else if err == nil {
+				// Changing where Solr stores its data would orphan the existing data
+				if err = util.ValidateStatefulSetDataPaths(statefulSet, foundStatefulSet); err != nil {
+					return requeueOrNot, err
+				}
+				// Pods connecting to a new ZooKeeper must be restarted one at a time
+				if migrating, err := reconcileZkConnectionMigration(r, instance, statefulSet, foundStatefulSet, &newStatus); err != nil {
+					return requeueOrNot, err
+				} else if migrating {
+					requeueAfter(&requeueOrNot, ZkConnectionMigrationCheckInterval)
+				}
+				observedStatefulSet := foundStatefulSet.DeepCopy()
+				updateRequired := util.CopyStatefulSetFields(statefulSet, foundStatefulSet)
+
+				// Updates that restart or remove Solr pods must wait for other operations on the cloud, such as backups, to finish
+				var operation *solr.SolrClusterOperation
+				if updateRequired {
+					operation = clusterOperationForUpdate(instance, observedStatefulSet, foundStatefulSet)
+				}
+				proceed := false
+				proceed, err = reconcileClusterOperationLock(r, instance, operation, observedStatefulSet)
+				newStatus.ClusterOperation = instance.Status.ClusterOperation
+				if err != nil {
+					return requeueOrNot, err
+				}
+				if !proceed {
+					requeueAfter(&requeueOrNot, ClusterOperationLockCheckInterval)
+				} else if updateRequired {
+					// Update the found StatefulSet and write the result back if there are any changes
+					r.Log.Info("Updating StatefulSet", "namespace", statefulSet.Namespace, "name", statefulSet.Name)
+					err = r.Update(context.TODO(), foundStatefulSet)
+				}
+				newStatus.Replicas = foundStatefulSet.Status.Replicas
+				newStatus.ReadyReplicas = foundStatefulSet.Status.ReadyReplicas
+				statefulSetUpdateRevision = foundStatefulSet.Status.UpdateRevision
+			}
+			if err != nil {
+				return requeueOrNot, err
+			}
+		}
 	}
 
-	err = reconcileCloudStatus(r, instance, &newStatus)
+	err = reconcileCloudStatus(r, instance, &newStatus, statefulSetUpdateRevision)
 	if err != nil {
 		return requeueOrNot, err
 	}
+	if err = reconcileEndpointsConfigMap(r, instance, &newStatus); err != nil {
+		return requeueOrNot, err
+	}
+	if instance.Spec.LiveNodesCheck != nil && !instance.IsStandalone() {
+		// The live nodes are not tied to any watched resource, so they must be checked periodically
+		requeueAfter(&requeueOrNot, time.Duration(instance.Spec.LiveNodesCheck.IntervalSeconds)*time.Second)
+	}
+
+	if len(instance.Spec.ClusterProperties) > 0 && !instance.IsStandalone() && newStatus.ReadyReplicas > 0 {
+		if retryAfter, applied := reconcileClusterProperties(r, instance, &newStatus); !applied {
+			// The failure is reported through the status, so the properties are retried later instead of failing the reconcile
+			requeueAfter(&requeueOrNot, retryAfter)
+		}
+	} else if len(instance.Spec.ClusterProperties) == 0 && newStatus.GetCondition(solr.ClusterPropertiesNotApplied) != nil {
+		newStatus.SetCondition(solr.ClusterPropertiesNotApplied, corev1.ConditionFalse, "NoClusterProperties", "The cloud does not define any cluster properties")
+	}
+	if expanding, err := reconcileDataVolumeExpansion(r, instance, &newStatus); err != nil {
+		return requeueOrNot, err
+	} else if expanding {
+		requeueAfter(&requeueOrNot, DataVolumeExpansionCheckInterval)
+	}
+
+	if instance.Spec.Autoscaling != nil && !instance.IsStandalone() && newStatus.ReadyReplicas > 0 {
+		if retryAfter, applied := reconcileAutoscaling(r, instance, &newStatus); !applied {
+			requeueAfter(&requeueOrNot, retryAfter)
+		}
+	} else if instance.Spec.Autoscaling == nil && newStatus.GetCondition(solr.AutoscalingNotApplied) != nil {
+		newStatus.SetCondition(solr.AutoscalingNotApplied, corev1.ConditionFalse, "NoAutoscaling", "The autoscaling configuration of the cloud is not managed")
+	}
 
 	extAddressabilityOpts := instance.Spec.SolrAddressability.External
-	if extAddressabilityOpts != nil && extAddressabilityOpts.Method == solr.Ingress {
-		// Generate Ingress
-		ingress := util.GenerateIngress(instance, solrNodeNames, IngressBaseUrl)
+	if extAddressabilityOpts != nil && extAddressabilityOpts.Method == solr.Ingress && useIngressAPI {
+		// Generate Ingress, in the legacy API version if the cluster does not serve the current one
+		generatedIngress := util.GenerateIngress(instance, solrNodeNames, IngressBaseUrl)
+		var ingress, foundIngress ingressObject
+		var copyIngressFields func() bool
+		if useLegacyIngressAPI {
+			legacyIngress, foundLegacyIngress := util.LegacyIngress(generatedIngress), &extv1.Ingress{}
+			ingress, foundIngress = legacyIngress, foundLegacyIngress
+			copyIngressFields = func() bool { return util.CopyLegacyIngressFields(legacyIngress, foundLegacyIngress) }
+		} else {
+			foundNetIngress := &netv1.Ingress{}
+			ingress, foundIngress = generatedIngress, foundNetIngress
+			copyIngressFields = func() bool { return util.CopyIngressFields(generatedIngress, foundNetIngress) }
+		}
 		if err := controllerutil.SetControllerReference(instance, ingress, r.scheme); err != nil {
 			return requeueOrNot, err
 		}
 
 		// Check if the Ingress already exists
-		foundIngress := &extv1.Ingress{}
-		err = r.Get(context.TODO(), types.NamespacedName{Name: ingress.Name, Namespace: ingress.Namespace}, foundIngress)
-		if err != nil && errors.IsNotFound(err) {
-			r.Log.Info("Creating Common Ingress", "namespace", ingress.Namespace, "name", ingress.Name)
+		err = r.Get(context.TODO(), types.NamespacedName{Name: ingress.GetName(), Namespace: ingress.GetNamespace()}, foundIngress)
+		if len(generatedIngress.Spec.Rules) == 0 {
+			// An Ingress without rules is invalid, which happens when the common endpoint is hidden and the cloud has no nodes
+			if err == nil && metav1.IsControlledBy(foundIngress, instance) {
+				r.Log.Info("Deleting Common Ingress", "namespace", foundIngress.GetNamespace(), "name", foundIngress.GetName())
+				err = r.Delete(context.TODO(), foundIngress)
+			} else if errors.IsNotFound(err) {
+				err = nil
+			}
+		} else if err != nil && errors.IsNotFound(err) {
+			r.Log.Info("Creating Common Ingress", "namespace", ingress.GetNamespace(), "name", ingress.GetName())
 			err = r.Create(context.TODO(), ingress)
-		} else if err == nil && util.CopyIngressFields(ingress, foundIngress) {
+		} else if err == nil && copyIngressFields() {
 			// Update the found Ingress and write the result back if there are any changes
-			r.Log.Info("Updating Common Ingress", "namespace", ingress.Namespace, "name", ingress.Name)
+			r.Log.Info("Updating Common Ingress", "namespace", ingress.GetNamespace(), "name", ingress.GetName())
 			err = r.Update(context.TODO(), foundIngress)
 		}
 		if err != nil {
@@ -260,6 +585,25 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		}
 	}
 
+	if extAddressabilityOpts != nil && extAddressabilityOpts.Method == solr.Istio && useIstioAPI {
+		if err := reconcileIstioResources(r, instance, solrNodeNames); err != nil {
+			return requeueOrNot, err
+		}
+	}
+
+	if extAddressabilityOpts != nil && extAddressabilityOpts.Method == solr.Route && useRouteAPI {
+		if err := reconcileRoutes(r, instance, solrNodeNames); err != nil {
+			return requeueOrNot, err
+		}
+	}
+
+	// A cloud scaled to zero is stopped, but keeps its PVCs so that it can be started again
+	if *instance.Spec.Replicas == 0 {
+		newStatus.SetCondition(solr.SolrCloudStopped, corev1.ConditionTrue, "ScaledToZero", "The cloud has been scaled to zero replicas")
+	} else if newStatus.GetCondition(solr.SolrCloudStopped) != nil {
+		newStatus.SetCondition(solr.SolrCloudStopped, corev1.ConditionFalse, "Started", fmt.Sprintf("The cloud has been scaled to %d replicas", *instance.Spec.Replicas))
+	}
+
 	if !reflect.DeepEqual(instance.Status, newStatus) {
 		instance.Status = newStatus
 		r.Log.Info("Updating SolrCloud Status: ", "namespace", instance.Namespace, "name", instance.Name)
@@ -272,7 +616,7 @@ func (r *SolrCloudReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return requeueOrNot, nil
 }
 
-func reconcileCloudStatus(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus) (err error) {
+func reconcileCloudStatus(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus, statefulSetUpdateRevision string) (err error) {
 	foundPods := &corev1.PodList{}
 	selectorLabels := solrCloud.SharedLabels()
 	selectorLabels["technology"] = solr.SolrTechnologyLabel
@@ -292,14 +636,18 @@ func reconcileCloudStatus(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, new
 	nodeNames := make([]string, len(foundPods.Items))
 	nodeStatusMap := map[string]solr.SolrNodeStatus{}
 	backupRestoreReadyPods := 0
+	readyPods := 0
 	for idx, p := range foundPods.Items {
 		nodeNames[idx] = p.Name
 		nodeStatus := solr.SolrNodeStatus{}
 		nodeStatus.Name = p.Name
 		nodeStatus.NodeName = p.Spec.NodeName
-		nodeStatus.InternalAddress = "http://" + solrCloud.InternalNodeUrl(nodeStatus.Name, true)
+		nodeStatus.InternalAddress = solrCloud.UrlScheme() + "://" + solrCloud.InternalNodeUrl(nodeStatus.Name, true)
+		if solrCloud.Spec.SolrAddressability.HostNetwork {
+			nodeStatus.IP = p.Status.PodIP
+		}
 		if solrCloud.Spec.SolrAddressability.External != nil && !solrCloud.Spec.SolrAddressability.External.HideNodes {
-			nodeStatus.ExternalAddress = "http://" + solrCloud.ExternalNodeUrl(nodeStatus.Name, solrCloud.Spec.SolrAddressability.External.DomainName, true)
+			nodeStatus.ExternalAddress = solrCloud.ExternalUrlScheme() + "://" + solrCloud.ExternalNodeUrl(nodeStatus.Name, solrCloud.Spec.SolrAddressability.External.DomainName, true)
 		}
 		ready := false
 		if len(p.Status.ContainerStatuses) > 0 {
@@ -315,16 +663,31 @@ func reconcileCloudStatus(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, new
 			}
 		}
 		nodeStatus.Ready = ready
+		if ready {
+			readyPods += 1
+		}
+
+		// The StatefulSet labels each pod with the revision of the spec that it was created with
+		nodeStatus.SpecUpToDate = statefulSetUpdateRevision != "" && p.Labels[appsv1.StatefulSetRevisionLabel] == statefulSetUpdateRevision
+		if nodeStatus.SpecUpToDate {
+			newStatus.UpToDateNodes += 1
+		}
 
 		nodeStatusMap[nodeStatus.Name] = nodeStatus
 
 		// Get Volumes for backup/restore
-		if solrCloud.Spec.BackupRestoreVolume != nil {
+		if solrCloud.UsesBackupRestoreVolume() {
+			hasBackupRestoreVolume := false
 			for _, volume := range p.Spec.Volumes {
 				if volume.Name == util.BackupRestoreVolume {
-					backupRestoreReadyPods += 1
+					hasBackupRestoreVolume = true
 				}
 			}
+			if hasBackupRestoreVolume {
+				backupRestoreReadyPods += 1
+			} else {
+				newStatus.BackupRestoreMissingPods = append(newStatus.BackupRestoreMissingPods, p.Name)
+			}
 		}
 	}
 	sort.Strings(nodeNames)
@@ -334,8 +697,23 @@ func reconcileCloudStatus(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, new
 		newStatus.SolrNodes[idx] = nodeStatusMap[nodeName]
 	}
 
-	if backupRestoreReadyPods == int(*solrCloud.Spec.Replicas) && backupRestoreReadyPods > 0 {
-		newStatus.BackupRestoreReady = true
+	// A standalone Solr has no cluster state to check
+	if solrCloud.Spec.LiveNodesCheck != nil && !solrCloud.IsStandalone() {
+		reconcileLiveNodes(r, solrCloud, newStatus, readyPods > 0)
+	}
+
+	if solrCloud.UsesBackupRestoreVolume() {
+		sort.Strings(newStatus.BackupRestoreMissingPods)
+		reason, message, err := backupRestoreVolumeProblem(r, solrCloud, newStatus.BackupRestoreMissingPods)
+		if err != nil {
+			return err
+		}
+		if reason == "" && backupRestoreReadyPods == int(*solrCloud.Spec.Replicas) && backupRestoreReadyPods > 0 {
+			newStatus.BackupRestoreReady = true
+		}
+		setProblemCondition(r, solrCloud, newStatus, solr.BackupRestoreNotReady, reason, message, "VolumeReady", "The backup volume is mounted on all Solr pods")
+	} else if newStatus.GetCondition(solr.BackupRestoreNotReady) != nil {
+		newStatus.SetCondition(solr.BackupRestoreNotReady, corev1.ConditionFalse, "Disabled", "Backups are not enabled for the cloud")
 	}
 
 	// If there are multiple versions of solr running, use the first otherVersion as the current running solr version of the cloud
@@ -347,109 +725,1228 @@ func reconcileCloudStatus(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, new
 		newStatus.Version = solrCloud.Spec.SolrImage.Tag
 	}
 
-	newStatus.InternalCommonAddress = "http://" + solrCloud.InternalCommonUrl(true)
+	newStatus.InternalCommonAddress = solrCloud.UrlScheme() + "://" + solrCloud.InternalCommonUrl(true)
 	if solrCloud.Spec.SolrAddressability.External != nil && !solrCloud.Spec.SolrAddressability.External.HideCommon {
-		extAddress := "http://" + solrCloud.ExternalCommonUrl(solrCloud.Spec.SolrAddressability.External.DomainName, true)
+		extAddress := solrCloud.ExternalUrlScheme() + "://" + solrCloud.ExternalCommonUrl(solrCloud.Spec.SolrAddressability.External.DomainName, true)
 		newStatus.ExternalCommonAddress = &extAddress
 	}
 
 	return nil
 }
 
-func reconcileNodeService(r *SolrCloudReconciler, instance *solr.SolrCloud, nodeName string) (err error, ip string) {
-	// Generate Node Service
-	service := util.GenerateNodeService(instance, nodeName)
-	if err := controllerutil.SetControllerReference(instance, service, r.scheme); err != nil {
-		return err, ip
+// reconcileEndpointsConfigMap publishes the endpoints of the cloud found in the new status in a ConfigMap, if enabled, or deletes the ConfigMap otherwise
+func reconcileEndpointsConfigMap(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus) (err error) {
+	foundConfigMap := &corev1.ConfigMap{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.EndpointsConfigMapName(), Namespace: solrCloud.Namespace}, foundConfigMap)
+	if !solrCloud.Spec.PublishEndpointsConfigMap {
+		if err == nil && metav1.IsControlledBy(foundConfigMap, solrCloud) {
+			r.Log.Info("Deleting Endpoints ConfigMap", "namespace", foundConfigMap.Namespace, "name", foundConfigMap.Name)
+			err = r.Delete(context.TODO(), foundConfigMap)
+		}
+		if errors.IsNotFound(err) {
+			err = nil
+		}
+		return err
 	}
 
-	// Check if the Ingress already exists
-	foundService := &corev1.Service{}
-	err = r.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, foundService)
+	configMap := util.GenerateEndpointsConfigMap(solrCloud, newStatus)
+	if err := controllerutil.SetControllerReference(solrCloud, configMap, r.scheme); err != nil {
+		return err
+	}
 	if err != nil && errors.IsNotFound(err) {
-		r.Log.Info("Creating Node Service", "namespace", service.Namespace, "name", service.Name)
-		err = r.Create(context.TODO(), service)
-	} else if err == nil {
-		if util.CopyServiceFields(service, foundService) {
-			// Update the found Ingress and write the result back if there are any changes
-			r.Log.Info("Updating Node Service", "namespace", service.Namespace, "name", service.Name)
-			err = r.Update(context.TODO(), foundService)
+		r.Log.Info("Creating Endpoints ConfigMap", "namespace", configMap.Namespace, "name", configMap.Name)
+		err = r.Create(context.TODO(), configMap)
+	} else if err == nil && util.CopyEndpointsConfigMapFields(configMap, foundConfigMap) {
+		// Update the found ConfigMap and write the result back if there are any changes
+		r.Log.Info("Updating Endpoints ConfigMap", "namespace", configMap.Namespace, "name", configMap.Name)
+		err = r.Update(context.TODO(), foundConfigMap)
+	}
+	return err
+}
+
+// reconcileServiceAccount creates the ServiceAccount for the Solr pods, if enabled, or deletes the ServiceAccount otherwise
+func reconcileServiceAccount(r *SolrCloudReconciler, solrCloud *solr.SolrCloud) (err error) {
+	if solrCloud.CreatesServiceAccount() {
+		if podOptions := solrCloud.Spec.CustomSolrKubeOptions.PodOptions; podOptions != nil && podOptions.ServiceAccountName != "" {
+			return errors.NewBadRequest("A ServiceAccount cannot be created when the podOptions provide a serviceAccountName")
 		}
-		ip = foundService.Spec.ClusterIP
 	}
-	if err != nil {
-		return err, ip
+
+	foundServiceAccount := &corev1.ServiceAccount{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.ServiceAccountName(), Namespace: solrCloud.Namespace}, foundServiceAccount)
+	if !solrCloud.CreatesServiceAccount() {
+		if err == nil && metav1.IsControlledBy(foundServiceAccount, solrCloud) {
+			r.Log.Info("Deleting ServiceAccount", "namespace", foundServiceAccount.Namespace, "name", foundServiceAccount.Name)
+			err = r.Delete(context.TODO(), foundServiceAccount)
+		}
+		if errors.IsNotFound(err) {
+			err = nil
+		}
+		return err
 	}
 
-	return nil, ip
+	serviceAccount := util.GenerateServiceAccount(solrCloud)
+	if err := controllerutil.SetControllerReference(solrCloud, serviceAccount, r.scheme); err != nil {
+		return err
+	}
+	if err != nil && errors.IsNotFound(err) {
+		r.Log.Info("Creating ServiceAccount", "namespace", serviceAccount.Namespace, "name", serviceAccount.Name)
+		err = r.Create(context.TODO(), serviceAccount)
+	} else if err == nil && util.CopyServiceAccountFields(serviceAccount, foundServiceAccount) {
+		// Update the found ServiceAccount and write the result back if there are any changes
+		r.Log.Info("Updating ServiceAccount", "namespace", serviceAccount.Namespace, "name", serviceAccount.Name)
+		err = r.Update(context.TODO(), foundServiceAccount)
+	}
+	return err
 }
 
-func reconcileZk(r *SolrCloudReconciler, request reconcile.Request, instance *solr.SolrCloud, busyBoxImage solr.ContainerImage, newStatus *solr.SolrCloudStatus) error {
-	zkRef := instance.Spec.ZookeeperRef
+// reconcileSolrSecurity creates the generated credentials and security.json of the cloud, or checks the provided basic auth Secret.
+// The generated Secrets are never updated, since the security.json is only bootstrapped into Zookeeper once.
+func reconcileSolrSecurity(r *SolrCloudReconciler, solrCloud *solr.SolrCloud) (err error) {
+	if solrCloud.Spec.SolrSecurity == nil {
+		return nil
+	}
 
-	if zkRef.ConnectionInfo != nil {
-		newStatus.ZookeeperConnectionInfo = *zkRef.ConnectionInfo
-	} else if zkRef.ProvidedZookeeper != nil {
-		pzk := zkRef.ProvidedZookeeper
-		// Generate ZookeeperCluster
-		if !useZkCRD {
-			return errors.NewBadRequest("Cannot create a Zookeeper Cluster, as the Solr Operator is not configured to use the Zookeeper CRD")
+	basicAuthSecret := &corev1.Secret{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.BasicAuthSecretName(), Namespace: solrCloud.Namespace}, basicAuthSecret)
+	if !solrCloud.BootstrapsSecurity() {
+		if err == nil {
+			if _, err = util.SolrBasicAuthFromSecret(basicAuthSecret); err != nil {
+				err = errors.NewBadRequest(fmt.Sprintf("Invalid basicAuthSecret: %v", err))
+			}
 		}
-		zkCluster := util.GenerateZookeeperCluster(instance, pzk)
-		if err := controllerutil.SetControllerReference(instance, zkCluster, r.scheme); err != nil {
+		return err
+	}
+
+	if err != nil && errors.IsNotFound(err) {
+		if basicAuthSecret, err = util.GenerateBasicAuthSecret(solrCloud); err != nil {
+			return err
+		}
+		if err = controllerutil.SetControllerReference(solrCloud, basicAuthSecret, r.scheme); err != nil {
 			return err
 		}
+		r.Log.Info("Creating basic auth Secret", "namespace", basicAuthSecret.Namespace, "name", basicAuthSecret.Name)
+		err = r.Create(context.TODO(), basicAuthSecret)
+	}
+	if err != nil {
+		return err
+	}
 
-		// Check if the ZookeeperCluster already exists
-		foundZkCluster := &zk.ZookeeperCluster{}
-		err := r.Get(context.TODO(), types.NamespacedName{Name: zkCluster.Name, Namespace: zkCluster.Namespace}, foundZkCluster)
-		if err != nil && errors.IsNotFound(err) {
-			r.Log.Info("Creating Zookeeer Cluster", "namespace", zkCluster.Namespace, "name", zkCluster.Name)
-			err = r.Create(context.TODO(), zkCluster)
-		} else if err == nil {
-			if util.CopyZookeeperClusterFields(zkCluster, foundZkCluster) {
-				// Update the found ZookeeperCluster and write the result back if there are any changes
-				r.Log.Info("Updating Zookeeer Cluster", "namespace", zkCluster.Namespace, "name", zkCluster.Name)
-				err = r.Update(context.TODO(), foundZkCluster)
-			}
-			external := &foundZkCluster.Status.ExternalClientEndpoint
-			if "" == *external {
-				external = nil
-			}
-			internal := make([]string, zkCluster.Spec.Replicas)
-			for i, _ := range internal {
-				internal[i] = fmt.Sprintf("%s-%d.%s-headless.%s:%d", foundZkCluster.Name, i, foundZkCluster.Name, foundZkCluster.Namespace, foundZkCluster.ZookeeperPorts().Client)
-			}
-			newStatus.ZookeeperConnectionInfo = solr.ZookeeperConnectionInfo{
-				InternalConnectionString: strings.Join(internal, ","),
-				ExternalConnectionString: external,
-				ChRoot:                   pzk.ChRoot,
-			}
+	bootstrapSecret := &corev1.Secret{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.SecurityBootstrapSecretName(), Namespace: solrCloud.Namespace}, bootstrapSecret)
+	if err != nil && errors.IsNotFound(err) {
+		basicAuth, err := util.SolrBasicAuthFromSecret(basicAuthSecret)
+		if err != nil {
+			return err
+		}
+		if bootstrapSecret, err = util.GenerateSecurityBootstrapSecret(solrCloud, basicAuth); err != nil {
+			return err
+		}
+		if err = controllerutil.SetControllerReference(solrCloud, bootstrapSecret, r.scheme); err != nil {
+			return err
+		}
+		r.Log.Info("Creating security bootstrap Secret", "namespace", bootstrapSecret.Namespace, "name", bootstrapSecret.Name)
+		return r.Create(context.TODO(), bootstrapSecret)
+	}
+	return err
+}
+
+// reconcileSolrTLS creates the generated password of the keystore, and the cert-manager Certificate for the hostnames of the cloud and the given nodes.
+// The password Secret is never updated, since the running pods build their keystores with it.
+func reconcileSolrTLS(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, nodeNames []string) (err error) {
+	passwordSecret := &corev1.Secret{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.PKCS12PasswordSecretName(), Namespace: solrCloud.Namespace}, passwordSecret)
+	if err != nil && errors.IsNotFound(err) {
+		if passwordSecret, err = util.GeneratePKCS12PasswordSecret(solrCloud); err != nil {
+			return err
 		}
+		if err = controllerutil.SetControllerReference(solrCloud, passwordSecret, r.scheme); err != nil {
+			return err
+		}
+		r.Log.Info("Creating PKCS12 password Secret", "namespace", passwordSecret.Namespace, "name", passwordSecret.Name)
+		err = r.Create(context.TODO(), passwordSecret)
+	}
+	if err != nil {
 		return err
-	} else {
-		return errors.NewBadRequest("No Zookeeper reference information provided.")
 	}
-	return nil
+
+	return reconcileUnstructuredResource(r, solrCloud, util.GenerateCertificate(solrCloud, nodeNames), true)
 }
 
-func (r *SolrCloudReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return r.SetupWithManagerAndReconciler(mgr, r)
+// solrBasicAuthForCloud returns the credentials that the operator authenticates to the SolrCloud with, or nil if the cloud does not require authentication
+func solrBasicAuthForCloud(c client.Client, solrCloud *solr.SolrCloud) (*util.SolrBasicAuth, error) {
+	if solrCloud.Spec.SolrSecurity == nil {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: solrCloud.BasicAuthSecretName(), Namespace: solrCloud.Namespace}, secret); err != nil {
+		return nil, err
+	}
+	return util.SolrBasicAuthFromSecret(secret)
 }
 
-func (r *SolrCloudReconciler) SetupWithManagerAndReconciler(mgr ctrl.Manager, reconciler reconcile.Reconciler) error {
-	ctrlBuilder := ctrl.NewControllerManagedBy(mgr).
-		For(&solr.SolrCloud{}).
-		Owns(&corev1.ConfigMap{}).
-		Owns(&appsv1.StatefulSet{}).
-		Owns(&corev1.Service{}).
-		Owns(&extv1.Ingress{}).
-		Owns(&appsv1.Deployment{})
+// solrApiClientForCloud returns a client for the common Service of the SolrCloud, which authenticates as the operator if the cloud requires it
+func solrApiClientForCloud(c client.Client, solrCloud *solr.SolrCloud) (*util.SolrApiClient, error) {
+	return solrApiClientForUrl(c, solrCloud, util.NewSolrApiClientForCloud(solrCloud).BaseUrl)
+}
 
-	if useZkCRD {
-		ctrlBuilder = ctrlBuilder.Owns(&zk.ZookeeperCluster{})
+// solrApiClientForUrl returns a client for the given base url of the SolrCloud, which authenticates as the operator if the cloud requires it,
+// and trusts the CA of the certificates of the cloud if it uses TLS. The client certificate of the operator is presented when Solr asks for one.
+func solrApiClientForUrl(c client.Client, solrCloud *solr.SolrCloud, baseUrl string) (apiClient *util.SolrApiClient, err error) {
+	apiClient = util.NewSolrApiClient(baseUrl)
+	if apiClient.BasicAuth, err = solrBasicAuthForCloud(c, solrCloud); err != nil {
+		return nil, err
+	}
+	if tlsOptions := solrCloud.Spec.SolrTLS; tlsOptions != nil {
+		var trustSecretName string
+		if solrCloud.UsesCertManager() {
+			trustSecretName = solrCloud.CertificateName()
+		} else {
+			trustSecretName = tlsOptions.PKCS12Secret.Name
+		}
+		if tlsOptions.TrustStoreSecret != nil {
+			trustSecretName = tlsOptions.TrustStoreSecret.Name
+		}
+		trustSecret := &corev1.Secret{}
+		if err = c.Get(context.TODO(), types.NamespacedName{Name: trustSecretName, Namespace: solrCloud.Namespace}, trustSecret); err != nil {
+			return nil, err
+		}
+		var clientCertSecret *corev1.Secret
+		if clientCertSecretName := solrCloud.ClientCertSecretName(); clientCertSecretName != "" && (tlsOptions.NeedClientAuth || tlsOptions.WantClientAuth) {
+			clientCertSecret = &corev1.Secret{}
+			if err = c.Get(context.TODO(), types.NamespacedName{Name: clientCertSecretName, Namespace: solrCloud.Namespace}, clientCertSecret); err != nil {
+				return nil, err
+			}
+		}
+		if apiClient.TLSConfig, err = util.SolrTLSClientConfig(trustSecret, clientCertSecret); err != nil {
+			return nil, err
+		}
+	}
+	return apiClient, nil
+}
+
+// getLiveNodesOfCloud fetches the live nodes in the cluster state of the SolrCloud through its common Service
+func getLiveNodesOfCloud(c client.Client, solrCloud *solr.SolrCloud) ([]string, error) {
+	apiClient, err := solrApiClientForUrl(c, solrCloud, solrCloud.UrlScheme()+"://"+solrCloud.InternalCommonUrl(true))
+	if err != nil {
+		return nil, err
+	}
+	return util.GetLiveNodesFromApi(apiClient)
+}
+
+// backupRestoreVolumeProblem returns the reason and message explaining why the backup volume of the cloud cannot be used, if there is a problem
+func backupRestoreVolumeProblem(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, missingPods []string) (reason string, message string, err error) {
+	if len(missingPods) > 0 {
+		return "VolumeMissing", fmt.Sprintf("The backup volume is not mounted on %d pods: %s", len(missingPods), strings.Join(missingPods, ", ")), nil
+	}
+
+	// A shared volume must be mountable by all Solr pods, which could be running on different Kubernetes nodes
+	if solrCloud.Spec.BackupRestoreVolume != nil && solrCloud.Spec.BackupRestoreVolume.PersistentVolumeClaim != nil && *solrCloud.Spec.Replicas > 1 {
+		pvcName := solrCloud.Spec.BackupRestoreVolume.PersistentVolumeClaim.ClaimName
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err = r.Get(context.TODO(), types.NamespacedName{Name: pvcName, Namespace: solrCloud.Namespace}, pvc); err != nil {
+			if errors.IsNotFound(err) {
+				return "VolumeMissing", fmt.Sprintf("The PVC %s used as the backupRestoreVolume does not exist", pvcName), nil
+			}
+			return "", "", err
+		}
+		for _, accessMode := range pvc.Spec.AccessModes {
+			if accessMode == corev1.ReadWriteMany {
+				return "", "", nil
+			}
+		}
+		return "AccessModeMismatch", fmt.Sprintf("The PVC %s used as the backupRestoreVolume must have the ReadWriteMany access mode to be mounted by all Solr pods, use the backupRestorePerNodePvcSpec for storage that cannot be shared", pvcName), nil
+	}
+	return "", "", nil
+}
+
+// setProblemCondition sets a condition that describes a problem with the cloud, recording a warning event whenever the problem changes.
+// An empty reason means that there is no problem, which is only recorded if the condition had been set before.
+func setProblemCondition(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus, conditionType solr.SolrCloudConditionType, reason string, message string, resolvedReason string, resolvedMessage string) {
+	condition := newStatus.GetCondition(conditionType)
+	if reason == "" {
+		if condition != nil {
+			newStatus.SetCondition(conditionType, corev1.ConditionFalse, resolvedReason, resolvedMessage)
+		}
+		return
+	}
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Message != message {
+		r.recorder.Event(solrCloud, corev1.EventTypeWarning, string(conditionType), message)
+	}
+	newStatus.SetCondition(conditionType, corev1.ConditionTrue, reason, message)
+}
+
+// solrApiErrorResult records a warning event with the error of a failed Solr API call, and returns the result of the reconcile.
+// Calls that may succeed later are requeued after a fixed delay instead of returning the error, so that an unavailable Solr does not put the controller into a hot loop.
+// Errors that do not come from the Solr API are returned as is.
+func solrApiErrorResult(recorder record.EventRecorder, object runtime.Object, err error) (reconcile.Result, error) {
+	if !util.IsSolrApiError(err) {
+		return reconcile.Result{}, err
+	}
+	if retryAfter, retryLater := util.IsSolrApiRetryLater(err); retryLater {
+		recorder.Event(object, corev1.EventTypeWarning, "SolrApiUnavailable", err.Error())
+		return reconcile.Result{RequeueAfter: retryAfter}, nil
+	}
+	recorder.Event(object, corev1.EventTypeWarning, "SolrApiError", err.Error())
+	return reconcile.Result{}, err
+}
+
+// unavailableAPIProblem returns the reason and message explaining which features of the cloud require an API that the Kubernetes cluster does not serve, if there are any
+func unavailableAPIProblem(solrCloud *solr.SolrCloud) (reason string, message string) {
+	var reasons, messages []string
+	if zkRef := solrCloud.Spec.ZookeeperRef; !solrCloud.IsStandalone() && zkRef != nil && !useZkCRD {
+		if zkRef.ProvidedZookeeper != nil {
+			reasons = append(reasons, "ZookeeperClusterUnavailable")
+			messages = append(messages, fmt.Sprintf("A provided Zookeeper requires the %s %s CRD, which is not installed or the Solr Operator is not configured to use", util.ZookeeperClusterGroupVersion, util.ZookeeperClusterKind))
+		} else if zkRef.ZookeeperClusterRef != nil {
+			reasons = append(reasons, "ZookeeperClusterUnavailable")
+			messages = append(messages, fmt.Sprintf("A referenced ZookeeperCluster requires the %s %s CRD, which is not installed or the Solr Operator is not configured to use", util.ZookeeperClusterGroupVersion, util.ZookeeperClusterKind))
+		}
+	}
+	if external := solrCloud.Spec.SolrAddressability.External; external != nil && external.Method == solr.Ingress && !useIngressAPI {
+		reasons = append(reasons, "IngressUnavailable")
+		messages = append(messages, fmt.Sprintf("Addressing Solr through an Ingress requires the %s or %s %s API, which the Kubernetes cluster does not serve", util.IngressGroupVersion, util.LegacyIngressGroupVersion, util.IngressKind))
+	}
+	if external := solrCloud.Spec.SolrAddressability.External; external != nil && external.Method == solr.Istio && !useIstioAPI {
+		reasons = append(reasons, "IstioUnavailable")
+		messages = append(messages, fmt.Sprintf("Addressing Solr through Istio requires the %s %s and %s APIs, which the Kubernetes cluster does not serve", util.IstioGroupVersion, util.IstioGatewayKind, util.IstioVirtualServiceKind))
+	}
+	if external := solrCloud.Spec.SolrAddressability.External; external != nil && external.Method == solr.Route && !useRouteAPI {
+		reasons = append(reasons, "RouteUnavailable")
+		messages = append(messages, fmt.Sprintf("Addressing Solr through OpenShift Routes requires the %s %s API, which the Kubernetes cluster does not serve", util.RouteGroupVersion, util.RouteKind))
+	}
+	if solrCloud.UsesCertManager() && !useCertManagerAPI {
+		reasons = append(reasons, "CertManagerUnavailable")
+		messages = append(messages, fmt.Sprintf("Issuing the certificate of Solr through cert-manager requires the %s %s API, which the Kubernetes cluster does not serve", util.CertManagerGroupVersion, util.CertificateKind))
+	}
+	return strings.Join(reasons, ","), strings.Join(messages, ". ")
+}
+
+// reconcileRoutes generates the OpenShift Routes of the cloud, and deletes the Routes of nodes that no longer exist or are hidden
+func reconcileRoutes(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, nodeNames []string) error {
+	routes := util.GenerateRoutes(solrCloud, nodeNames)
+	routeNames := make([]string, len(routes))
+	for i, route := range routes {
+		routeNames[i] = route.GetName()
+		if err := reconcileUnstructuredResource(r, solrCloud, route, true); err != nil {
+			return err
+		}
+	}
+
+	foundRoutes := &unstructured.UnstructuredList{}
+	foundRoutes.SetGroupVersionKind(util.RouteGVK.GroupVersion().WithKind(util.RouteKind + "List"))
+	listOps := &client.ListOptions{
+		Namespace:     solrCloud.Namespace,
+		LabelSelector: labels.SelectorFromSet(solrCloud.SharedLabels()),
+	}
+	if err := r.List(context.TODO(), foundRoutes, listOps); err != nil {
+		return err
+	}
+	for idx := range foundRoutes.Items {
+		route := &foundRoutes.Items[idx]
+		if metav1.IsControlledBy(route, solrCloud) && !util.ContainsString(routeNames, route.GetName()) {
+			r.Log.Info("Deleting Route", "namespace", route.GetNamespace(), "name", route.GetName())
+			if err := r.Delete(context.TODO(), route); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileIstioResources generates the Istio VirtualService of the cloud, and the Gateway it is bound to unless an existing Gateway is used
+func reconcileIstioResources(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, nodeNames []string) error {
+	// Resources without hosts are invalid, which happens when the common endpoint is hidden and the cloud has no nodes
+	hasHosts := len(util.IngressHosts(solrCloud, nodeNames)) > 0
+	if err := reconcileUnstructuredResource(r, solrCloud, util.GenerateIstioGateway(solrCloud, nodeNames), hasHosts && solrCloud.Spec.SolrAddressability.External.Istio.Gateway == ""); err != nil {
+		return err
+	}
+	return reconcileUnstructuredResource(r, solrCloud, util.GenerateIstioVirtualService(solrCloud, nodeNames), hasHosts)
+}
+
+// reconcileUnstructuredResource creates or updates the given Istio resource, OpenShift Route or cert-manager Certificate, or deletes the one controlled by the cloud if it is not required
+func reconcileUnstructuredResource(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, resource *unstructured.Unstructured, required bool) error {
+	kind := resource.GetKind()
+	foundResource := &unstructured.Unstructured{}
+	foundResource.SetGroupVersionKind(resource.GroupVersionKind())
+	err := r.Get(context.TODO(), types.NamespacedName{Name: resource.GetName(), Namespace: resource.GetNamespace()}, foundResource)
+	if !required {
+		if err == nil && metav1.IsControlledBy(foundResource, solrCloud) {
+			r.Log.Info("Deleting "+kind, "namespace", foundResource.GetNamespace(), "name", foundResource.GetName())
+			err = r.Delete(context.TODO(), foundResource)
+		} else if errors.IsNotFound(err) {
+			err = nil
+		}
+		return err
+	}
+
+	if err := controllerutil.SetControllerReference(solrCloud, resource, r.scheme); err != nil {
+		return err
+	}
+	if err != nil && errors.IsNotFound(err) {
+		r.Log.Info("Creating "+kind, "namespace", resource.GetNamespace(), "name", resource.GetName())
+		err = r.Create(context.TODO(), resource)
+	} else if err == nil && util.CopyUnstructuredResourceFields(resource, foundResource) {
+		// Update the found resource and write the result back if there are any changes
+		r.Log.Info("Updating "+kind, "namespace", resource.GetNamespace(), "name", resource.GetName())
+		err = r.Update(context.TODO(), foundResource)
+	}
+	return err
+}
+
+// validateNodeAddressingChange returns an error if the nodes of a running cloud would switch between being addressed through the headless service and through individual node services,
+// or would be addressed in another kubeDomain.
+// The addressing of the existing nodes is read from the annotations of the StatefulSet, so clouds created before they were added are not checked until their StatefulSet is updated.
+func validateNodeAddressingChange(r *SolrCloudReconciler, solrCloud *solr.SolrCloud) error {
+	foundStatefulSet := &appsv1.StatefulSet{}
+	err := r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.StatefulSetName(), Namespace: solrCloud.Namespace}, foundStatefulSet)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if foundStatefulSet.Status.Replicas == 0 {
+		return nil
+	}
+	if currentAddressing, found := foundStatefulSet.Annotations[util.SolrNodeAddressingAnnotation]; found && currentAddressing != solrCloud.NodeAddressing() {
+		return errors.NewBadRequest(fmt.Sprintf("Cannot switch the Solr nodes from %s to %s addressing while the cloud is running, as every Solr node would register in ZooKeeper under a new name. Scale the cloud down to 0 replicas before changing the addressability options", currentAddressing, solrCloud.NodeAddressing()))
+	}
+	if currentKubeDomain, found := foundStatefulSet.Annotations[util.SolrKubeDomainAnnotation]; found && currentKubeDomain != solrCloud.Spec.SolrAddressability.KubeDomain {
+		return errors.NewBadRequest(fmt.Sprintf("Cannot change the kubeDomain of the Solr nodes from '%s' to '%s' while the cloud is running, as every Solr node would register in ZooKeeper under a new name. Scale the cloud down to 0 replicas before changing the kubeDomain", currentKubeDomain, solrCloud.Spec.SolrAddressability.KubeDomain))
+	}
+	return nil
+}
+
+// MissingReferencesCheckInterval is how often the Secrets and ConfigMaps referenced by a cloud are checked while some are missing
+const MissingReferencesCheckInterval = 30 * time.Second
+
+// reconcilePodReferences checks that the Secrets and ConfigMaps referenced by the Solr pods exist.
+// The missing references are listed in the ReferencesResolved condition, with an event whenever they change.
+func reconcilePodReferences(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, podSpec *corev1.PodSpec, newStatus *solr.SolrCloudStatus) (resolved bool, err error) {
+	var missing []string
+	secrets, configMaps := util.PodSpecReferences(podSpec)
+	for _, name := range secrets {
+		if err = r.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: solrCloud.Namespace}, &corev1.Secret{}); errors.IsNotFound(err) {
+			missing = append(missing, "Secret "+name)
+		} else if err != nil {
+			return false, err
+		}
+	}
+	for _, name := range configMaps {
+		if err = r.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: solrCloud.Namespace}, &corev1.ConfigMap{}); errors.IsNotFound(err) {
+			missing = append(missing, "ConfigMap "+name)
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	condition := newStatus.GetCondition(solr.ReferencesResolved)
+	if len(missing) == 0 {
+		if condition != nil {
+			newStatus.SetCondition(solr.ReferencesResolved, corev1.ConditionTrue, "AllReferencesFound", "All Secrets and ConfigMaps referenced by the Solr pods exist")
+		}
+		return true, nil
+	}
+	message := fmt.Sprintf("The Solr pods reference missing objects: %s", strings.Join(missing, ", "))
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Message != message {
+		r.recorder.Event(solrCloud, corev1.EventTypeWarning, "MissingReferences", message)
+	}
+	newStatus.SetCondition(solr.ReferencesResolved, corev1.ConditionFalse, "MissingReferences", message)
+	return false, nil
+}
+
+// referenceToCloudRequests maps a Secret or ConfigMap to reconcile requests for the SolrClouds in its namespace that are waiting for missing references,
+// and for the SolrClouds that restart their pods when the content of the Secret or ConfigMap changes
+func (r *SolrCloudReconciler) referenceToCloudRequests(obj handler.MapObject) []reconcile.Request {
+	_, isConfigMap := obj.Object.(*corev1.ConfigMap)
+	_, isSecret := obj.Object.(*corev1.Secret)
+	cloudList := &solr.SolrCloudList{}
+	if err := r.List(context.TODO(), cloudList, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "Unable to list SolrClouds for referenced object", "namespace", obj.Meta.GetNamespace(), "name", obj.Meta.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, cloud := range cloudList.Items {
+		condition := cloud.Status.GetCondition(solr.ReferencesResolved)
+		waitingForReferences := condition != nil && condition.Status == corev1.ConditionFalse
+		if waitingForReferences || (isConfigMap && cloudUsesConfigMapContent(&cloud, obj.Meta.GetName())) || (isSecret && cloudUsesSecretContent(&cloud, obj.Meta.GetName())) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cloud.Name, Namespace: cloud.Namespace}})
+		}
+	}
+	return requests
+}
+
+// cloudUsesConfigMapContent returns whether the Solr pods of the cloud are restarted when the content of the named ConfigMap changes
+func cloudUsesConfigMapContent(cloud *solr.SolrCloud, name string) bool {
+	if cloud.ProvidedSolrXmlConfigMap() == name {
+		return true
+	}
+	log4j2Config := cloud.CustomLog4j2Config()
+	return log4j2Config != nil && log4j2Config.ConfigMapRef != nil && log4j2Config.ConfigMapRef.Name == name
+}
+
+// cloudUsesSecretContent returns whether the Solr pods of the cloud are restarted when the content of the named Secret changes
+func cloudUsesSecretContent(cloud *solr.SolrCloud, name string) bool {
+	for _, secretKey := range util.SolrTLSCertSecretKeys(cloud) {
+		if secretKey.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsCertificateMd5 returns the hash of the certificates that the Solr nodes load when they start, or an empty string if the cloud does not use TLS.
+// Missing Secrets are not hashed, since the Solr pods cannot start until they exist.
+func tlsCertificateMd5(r *SolrCloudReconciler, solrCloud *solr.SolrCloud) (string, error) {
+	secretKeys := util.SolrTLSCertSecretKeys(solrCloud)
+	if len(secretKeys) == 0 {
+		return "", nil
+	}
+	hash := md5.New()
+	for _, secretKey := range secretKeys {
+		secret := &corev1.Secret{}
+		if err := r.Get(context.TODO(), types.NamespacedName{Name: secretKey.Name, Namespace: solrCloud.Namespace}, secret); errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		hash.Write(secret.Data[secretKey.Key])
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// zookeeperClusterToCloudRequests maps a ZookeeperCluster to reconcile requests for all SolrClouds that reference it through a zookeeperClusterRef
+func (r *SolrCloudReconciler) zookeeperClusterToCloudRequests(obj handler.MapObject) []reconcile.Request {
+	cloudList := &solr.SolrCloudList{}
+	if err := r.List(context.TODO(), cloudList); err != nil {
+		r.Log.Error(err, "Unable to list SolrClouds for ZookeeperCluster", "namespace", obj.Meta.GetNamespace(), "name", obj.Meta.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, cloud := range cloudList.Items {
+		if cloud.Spec.ZookeeperRef == nil || cloud.Spec.ZookeeperRef.ZookeeperClusterRef == nil {
+			continue
+		}
+		zkClusterRef := cloud.Spec.ZookeeperRef.ZookeeperClusterRef
+		// The namespace of the reference defaults to the namespace of the cloud
+		namespace := zkClusterRef.Namespace
+		if namespace == "" {
+			namespace = cloud.Namespace
+		}
+		if zkClusterRef.Name == obj.Meta.GetName() && namespace == obj.Meta.GetNamespace() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cloud.Name, Namespace: cloud.Namespace}})
+		}
+	}
+	return requests
+}
+
+// ZkConnectionMigrationCheckInterval is how often the progress of a ZooKeeper connection migration is checked
+const ZkConnectionMigrationCheckInterval = 5 * time.Second
+
+// requeueAfter requeues the reconcile after the given duration, unless it is already requeued sooner
+func requeueAfter(result *reconcile.Result, after time.Duration) {
+	if result.RequeueAfter == 0 || after < result.RequeueAfter {
+		result.RequeueAfter = after
+	}
+}
+
+// ClusterOperationLockCheckInterval is how often an operation that waits for the cluster operation lock of a cloud checks the lock again
+const ClusterOperationLockCheckInterval = 10 * time.Second
+
+// clusterOperationForUpdate returns the operation that applying the update of the StatefulSet would start, if the update restarts or removes Solr pods
+func clusterOperationForUpdate(solrCloud *solr.SolrCloud, before *appsv1.StatefulSet, after *appsv1.StatefulSet) *solr.SolrClusterOperation {
+	if before.Spec.Replicas != nil && after.Spec.Replicas != nil && *after.Spec.Replicas < *before.Spec.Replicas {
+		return &solr.SolrClusterOperation{
+			Operation: solr.ScaleDownOperation,
+			StartTime: metav1.Now(),
+			Metadata:  fmt.Sprintf("Scaling down from %d to %d replicas", *before.Spec.Replicas, *after.Spec.Replicas),
+		}
+	}
+	// With the Manual and OnDelete update methods, the user decides when pods are restarted
+	if solrCloud.IsManagedUpdate() && !reflect.DeepEqual(before.Spec.Template, after.Spec.Template) {
+		return &solr.SolrClusterOperation{
+			Operation: solr.ManagedUpdateOperation,
+			StartTime: metav1.Now(),
+			Metadata:  "Restarting the Solr pods with an updated pod template",
+		}
+	}
+	return nil
+}
+
+// reconcileClusterOperationLock takes the cluster operation lock of the cloud for the given operation of the SolrCloud reconciler, if there is one.
+// Locks of operations that have finished are released, which includes locks left behind when the operator was stopped during an operation.
+// Returns false if the operation must wait for another operation on the cloud to finish.
+func reconcileClusterOperationLock(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, operation *solr.SolrClusterOperation, statefulSet *appsv1.StatefulSet) (proceed bool, err error) {
+	if lock := solrCloud.Status.ClusterOperation; lock != nil && (operation == nil || !lock.HasSameOwner(operation)) {
+		finished := false
+		if finished, err = isClusterOperationFinished(r, solrCloud, lock, statefulSet); err != nil {
+			return false, err
+		}
+		if finished {
+			r.Log.Info("Releasing the cluster operation lock of a finished operation", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "operation", lock.Operation, "metadata", lock.Metadata)
+			if err = releaseClusterOperationLock(r.Client, solrCloud, lock); err != nil {
+				return false, err
+			}
+		}
+	}
+	if operation == nil {
+		return true, nil
+	}
+
+	holder, acquired, err := acquireClusterOperationLock(r.Client, solrCloud, operation)
+	if err == nil && !acquired {
+		r.recorder.Eventf(solrCloud, corev1.EventTypeNormal, "ClusterOperationLocked", "Waiting for the %s operation (%s) to finish, before starting the %s operation", holder.Operation, holder.Metadata, operation.Operation)
+	}
+	return acquired, err
+}
+
+// isClusterOperationFinished returns whether the operation holding the lock of the cloud has finished, based on the observed state of the cloud or the SolrBackup
+func isClusterOperationFinished(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, lock *solr.SolrClusterOperation, statefulSet *appsv1.StatefulSet) (bool, error) {
+	if lock.IsSolrCloudOperation() {
+		// With the Manual and OnDelete update methods, pods are not updated until the user lowers the partition or deletes them
+		if !solrCloud.IsManagedUpdate() {
+			return util.IsStatefulSetScaleComplete(statefulSet), nil
+		}
+		return util.IsStatefulSetRolloutComplete(statefulSet), nil
+	}
+	if lock.Operation == solr.BackupOperation {
+		backup := &solr.SolrBackup{}
+		if err := r.Get(context.TODO(), types.NamespacedName{Name: lock.Metadata, Namespace: solrCloud.Namespace}, backup); err != nil {
+			if errors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		}
+		return backup.Status.Finished || util.CheckStatusOfCollectionBackups(backup), nil
+	}
+	return false, nil
+}
+
+// acquireClusterOperationLock records the given operation as the lock of the SolrCloud, unless an operation of another owner holds the lock.
+// Returns the operation that holds the lock, which is the given operation if the lock was acquired.
+// The SolrCloud and SolrBackup reconcilers both write the lock, so it is written with a retry on conflict, against the latest version of the cloud.
+// The resourceVersion and lock of the given cloud are updated, so that later status updates of the cloud do not conflict.
+func acquireClusterOperationLock(c client.Client, solrCloud *solr.SolrCloud, operation *solr.SolrClusterOperation) (holder *solr.SolrClusterOperation, acquired bool, err error) {
+	// Avoid writing the lock if it is already held
+	if holder = solrCloud.Status.ClusterOperation; holder != nil && holder.HasSameOwner(operation) {
+		return holder, true, nil
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &solr.SolrCloud{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: solrCloud.Name, Namespace: solrCloud.Namespace}, latest); err != nil {
+			return err
+		}
+		holder = latest.Status.ClusterOperation
+		acquired = holder == nil || holder.HasSameOwner(operation)
+		if holder == nil {
+			latest.Status.ClusterOperation = operation
+			if err := c.Status().Update(context.TODO(), latest); err != nil {
+				return err
+			}
+			holder = operation
+		}
+		solrCloud.ResourceVersion = latest.ResourceVersion
+		solrCloud.Status.ClusterOperation = latest.Status.ClusterOperation
+		return nil
+	})
+	return holder, acquired, err
+}
+
+// releaseClusterOperationLock removes the lock of the SolrCloud, if it is held by the owner of the given operation
+func releaseClusterOperationLock(c client.Client, solrCloud *solr.SolrCloud, operation *solr.SolrClusterOperation) error {
+	if holder := solrCloud.Status.ClusterOperation; holder == nil || !holder.HasSameOwner(operation) {
+		return nil
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &solr.SolrCloud{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: solrCloud.Name, Namespace: solrCloud.Namespace}, latest); err != nil {
+			return err
+		}
+		if holder := latest.Status.ClusterOperation; holder != nil && holder.HasSameOwner(operation) {
+			latest.Status.ClusterOperation = nil
+			if err := c.Status().Update(context.TODO(), latest); err != nil {
+				return err
+			}
+		}
+		solrCloud.ResourceVersion = latest.ResourceVersion
+		solrCloud.Status.ClusterOperation = latest.Status.ClusterOperation
+		return nil
+	})
+}
+
+// reconcileZkConnectionMigration restarts the Solr pods one at a time when the ZooKeeper connection of the cloud changes.
+// Each restarted node must be live in the new ZooKeeper before the next pod is restarted, so that the collections never lose all of their replicas.
+// The returned migrating is true while pods are still waiting to be restarted.
+func reconcileZkConnectionMigration(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, desired *appsv1.StatefulSet, found *appsv1.StatefulSet, newStatus *solr.SolrCloudStatus) (migrating bool, err error) {
+	replicas := *desired.Spec.Replicas
+	newZk := util.StatefulSetZkConnectionString(desired)
+
+	if util.IsZkConnectionChange(desired, found) {
+		// No pods are restarted until the partition is lowered, starting from the highest ordinal
+		r.Log.Info("Starting ZooKeeper connection migration", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "from", util.StatefulSetZkConnectionString(found), "to", newZk)
+		util.SetStatefulSetPartition(desired, replicas)
+		newStatus.SetCondition(solr.ZkConnectionMigration, corev1.ConditionTrue, "Migrating", fmt.Sprintf("Restarting the Solr nodes one at a time to connect to %s", newZk))
+		return true, nil
+	}
+
+	if !newStatus.IsConditionTrue(solr.ZkConnectionMigration) {
+		return false, nil
+	}
+
+	partition := util.StatefulSetPartition(found)
+	if partition > replicas {
+		partition = replicas
+	}
+	if partition < replicas {
+		// The most recently restarted node must be live in the new ZooKeeper before moving on
+		nodeName := fmt.Sprintf("%s-%d", solrCloud.StatefulSetName(), partition)
+		if live, err := isNodeMigrated(r, solrCloud, found, nodeName); err != nil || !live {
+			util.SetStatefulSetPartition(desired, partition)
+			return true, err
+		}
+	}
+
+	if partition == 0 {
+		r.Log.Info("Finished ZooKeeper connection migration", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "to", newZk)
+		newStatus.SetCondition(solr.ZkConnectionMigration, corev1.ConditionFalse, "Completed", fmt.Sprintf("All Solr nodes are connected to %s", newZk))
+		return false, nil
+	}
+
+	r.Log.Info("Restarting the next Solr node for the ZooKeeper connection migration", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "partition", partition-1)
+	util.SetStatefulSetPartition(desired, partition-1)
+	return true, nil
+}
+
+// isNodeMigrated returns whether the pod of the Solr node has been restarted with the current StatefulSet revision, and is live in the cluster state it now reads from
+func isNodeMigrated(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, statefulSet *appsv1.StatefulSet, nodeName string) (bool, error) {
+	pod := &corev1.Pod{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: nodeName, Namespace: solrCloud.Namespace}, pod); err != nil {
+		if errors.IsNotFound(err) {
+			err = nil
+		}
+		return false, err
+	}
+	if !util.IsPodUpdatedAndReady(pod, statefulSet) {
+		return false, nil
+	}
+	apiClient, err := solrApiClientForUrl(r.Client, solrCloud, solrCloud.UrlScheme()+"://"+solrCloud.InternalNodeUrl(nodeName, true))
+	if err != nil {
+		return false, err
+	}
+	liveNodes, err := util.GetLiveNodesFromApi(apiClient)
+	if err != nil {
+		r.Log.Info("Could not fetch the live nodes from the restarted Solr node, will retry", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "node", nodeName, "error", err.Error())
+		return false, nil
+	}
+	return util.ContainsString(liveNodes, solrCloud.PodLiveNodeName(nodeName, pod.Status.PodIP)), nil
+}
+
+// liveNodesCache stores the result of the last live nodes check of each SolrCloud,
+// so that the cluster state is not fetched from Solr on every reconcile.
+type liveNodesCache struct {
+	sync.Mutex
+	checks map[types.NamespacedName]liveNodesCheck
+}
+
+type liveNodesCheck struct {
+	checkedAt time.Time
+	liveNodes []string
+	failed    bool
+}
+
+var cloudLiveNodes = &liveNodesCache{checks: map[types.NamespacedName]liveNodesCheck{}}
+
+// get returns the last live nodes check of the cloud, if one has been done within the given interval
+func (c *liveNodesCache) get(cloud types.NamespacedName, interval time.Duration, now time.Time) (check liveNodesCheck, found bool) {
+	c.Lock()
+	defer c.Unlock()
+	check, found = c.checks[cloud]
+	if found && now.Sub(check.checkedAt) >= interval {
+		found = false
+	}
+	return check, found
+}
+
+func (c *liveNodesCache) set(cloud types.NamespacedName, check liveNodesCheck) {
+	c.Lock()
+	defer c.Unlock()
+	c.checks[cloud] = check
+}
+
+// reconcileLiveNodes sets the live state of the Solr nodes in the new status, fetching the live nodes from Solr at most once per interval.
+// If the live nodes cannot be fetched, the live states of the previous status are kept.
+func reconcileLiveNodes(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus, solrAvailable bool) {
+	cloud := types.NamespacedName{Name: solrCloud.Name, Namespace: solrCloud.Namespace}
+	interval := time.Duration(solrCloud.Spec.LiveNodesCheck.IntervalSeconds) * time.Second
+	now := time.Now()
+
+	check, found := cloudLiveNodes.get(cloud, interval, now)
+	if !found {
+		if !solrAvailable {
+			// No Solr node can answer the request, so every node is down
+			check = liveNodesCheck{checkedAt: now, liveNodes: []string{}}
+		} else if liveNodes, err := getLiveNodesOfCloud(r.Client, solrCloud); err != nil {
+			r.Log.Error(err, "Could not fetch the live nodes of the SolrCloud, keeping the previous live states", "namespace", solrCloud.Namespace, "name", solrCloud.Name)
+			check = liveNodesCheck{checkedAt: now, failed: true}
+		} else {
+			check = liveNodesCheck{checkedAt: now, liveNodes: liveNodes}
+		}
+		cloudLiveNodes.set(cloud, check)
+	}
+
+	if check.failed {
+		keepLiveNodeStates(&solrCloud.Status, newStatus)
+	} else {
+		setLiveNodeStates(solrCloud, newStatus, check.liveNodes)
+	}
+}
+
+// DataStorageFinalizer is added to the SolrClouds whose data PVCs must be deleted along with them
+const DataStorageFinalizer = "storage.finalizers.bloomberg.com"
+
+// reconcileDataStorageFinalizer keeps the data storage finalizer on the clouds that delete their data PVCs, and deletes the PVCs once the cloud is deleted.
+// It returns whether the cloud is being deleted, in which case nothing else should be reconciled.
+func reconcileDataStorageFinalizer(r *SolrCloudReconciler, solrCloud *solr.SolrCloud) (deleted bool, err error) {
+	hasFinalizer := util.ContainsString(solrCloud.ObjectMeta.Finalizers, DataStorageFinalizer)
+	if !solrCloud.ObjectMeta.DeletionTimestamp.IsZero() {
+		if hasFinalizer {
+			if err = deleteDataPvcs(r, solrCloud); err != nil {
+				return true, err
+			}
+			solrCloud.ObjectMeta.Finalizers = util.RemoveString(solrCloud.ObjectMeta.Finalizers, DataStorageFinalizer)
+			err = r.Update(context.TODO(), solrCloud)
+		}
+		return true, err
+	}
+
+	if solrCloud.DeletesDataOnDeletion() && !hasFinalizer {
+		solrCloud.ObjectMeta.Finalizers = append(solrCloud.ObjectMeta.Finalizers, DataStorageFinalizer)
+		err = r.Update(context.TODO(), solrCloud)
+	} else if !solrCloud.DeletesDataOnDeletion() && hasFinalizer {
+		solrCloud.ObjectMeta.Finalizers = util.RemoveString(solrCloud.ObjectMeta.Finalizers, DataStorageFinalizer)
+		err = r.Update(context.TODO(), solrCloud)
+	}
+	return false, err
+}
+
+// deleteDataPvcs deletes the PVCs created for the data and transaction log volumes of the Solr pods
+func deleteDataPvcs(r *SolrCloudReconciler, solrCloud *solr.SolrCloud) error {
+	dataPvcs, err := listVolumePvcs(r, solrCloud, util.SolrDataVolume)
+	if err != nil {
+		return err
+	}
+	tlogPvcs, err := listVolumePvcs(r, solrCloud, util.SolrTlogVolume)
+	if err != nil {
+		return err
+	}
+	for _, pvc := range append(dataPvcs, tlogPvcs...) {
+		r.Log.Info("Deleting data PVC of deleted SolrCloud", "namespace", pvc.Namespace, "name", pvc.Name, "cloud", solrCloud.Name)
+		if err := r.Delete(context.TODO(), &pvc); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// listVolumePvcs returns the PVCs that the StatefulSet of the cloud created from the given volumeClaimTemplate for the Solr pods
+func listVolumePvcs(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, volumeName string) (volumePvcs []corev1.PersistentVolumeClaim, err error) {
+	selectorLabels := solrCloud.SharedLabels()
+	selectorLabels["technology"] = solr.SolrTechnologyLabel
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err = r.List(context.TODO(), pvcList, client.InNamespace(solrCloud.Namespace), client.MatchingLabels(selectorLabels)); err != nil {
+		return nil, err
+	}
+	// The StatefulSet names the PVCs after the volumeClaimTemplate and the pod, e.g. "data-foo-solrcloud-0"
+	pvcPrefix := volumeName + "-" + solrCloud.StatefulSetName() + "-"
+	for _, pvc := range pvcList.Items {
+		if strings.HasPrefix(pvc.Name, pvcPrefix) {
+			volumePvcs = append(volumePvcs, pvc)
+		}
+	}
+	sort.Slice(volumePvcs, func(i, j int) bool { return volumePvcs[i].Name < volumePvcs[j].Name })
+	return volumePvcs, nil
+}
+
+// DataVolumeExpansionCheckInterval is how often the progress of expanding the data PVCs is checked, since the PVCs are not watched
+const DataVolumeExpansionCheckInterval = 30 * time.Second
+
+// reconcileDataVolumeExpansion expands the existing data PVCs of the cloud to the size of its data storage spec,
+// since the volumeClaimTemplates of the StatefulSet cannot be changed. The progress is reported by the DataVolumeExpansion condition.
+// It returns whether the expansion is still in progress.
+func reconcileDataVolumeExpansion(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus) (expanding bool, err error) {
+	var size resource.Quantity
+	if pvcSpec := solrCloud.DataPvcSpec(); pvcSpec != nil {
+		size = pvcSpec.Resources.Requests[corev1.ResourceStorage]
+	}
+	if size.IsZero() {
+		if condition := newStatus.GetCondition(solr.DataVolumeExpansion); condition != nil && condition.Status == corev1.ConditionTrue {
+			newStatus.SetCondition(solr.DataVolumeExpansion, corev1.ConditionFalse, "NoDataVolumes", "The data of the cloud is not stored in PVCs")
+		}
+		return false, nil
+	}
+
+	dataPvcs, err := listVolumePvcs(r, solrCloud, util.SolrDataVolume)
+	if err != nil {
+		return false, err
+	}
+	expandedPvcs, fileSystemResizePending := 0, 0
+	storageClassesAllowExpansion := map[string]bool{}
+	for _, pvc := range dataPvcs {
+		if capacity, hasCapacity := pvc.Status.Capacity[corev1.ResourceStorage]; hasCapacity && capacity.Cmp(size) >= 0 {
+			expandedPvcs += 1
+			continue
+		}
+		for _, pvcCondition := range pvc.Status.Conditions {
+			if pvcCondition.Type == corev1.PersistentVolumeClaimFileSystemResizePending && pvcCondition.Status == corev1.ConditionTrue {
+				fileSystemResizePending += 1
+			}
+		}
+		requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if requested.Cmp(size) >= 0 {
+			// The PVC is already being expanded
+			continue
+		}
+
+		storageClassName := ""
+		if pvc.Spec.StorageClassName != nil {
+			storageClassName = *pvc.Spec.StorageClassName
+		}
+		allowExpansion, checked := storageClassesAllowExpansion[storageClassName]
+		if !checked {
+			if allowExpansion, err = storageClassAllowsExpansion(r, storageClassName); err != nil {
+				return false, err
+			}
+			storageClassesAllowExpansion[storageClassName] = allowExpansion
+		}
+		if !allowExpansion {
+			message := fmt.Sprintf("The data PVC %s cannot be expanded to %s, because its StorageClass %q does not allow volume expansion", pvc.Name, size.String(), storageClassName)
+			setProblemCondition(r, solrCloud, newStatus, solr.DataVolumeExpansion, "ExpansionNotAllowed", message, "", "")
+			return false, nil
+		}
+
+		r.Log.Info("Expanding data PVC", "namespace", pvc.Namespace, "name", pvc.Name, "from", requested.String(), "to", size.String())
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+		if err = r.Update(context.TODO(), &pvc); err != nil {
+			return false, err
+		}
+	}
+
+	condition := newStatus.GetCondition(solr.DataVolumeExpansion)
+	if expandedPvcs < len(dataPvcs) {
+		message := fmt.Sprintf("%d of %d data PVCs have been expanded to %s", expandedPvcs, len(dataPvcs), size.String())
+		if fileSystemResizePending > 0 {
+			message += fmt.Sprintf(", %d are waiting for their pod to be restarted to resize the file system", fileSystemResizePending)
+		}
+		if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != "Expanding" {
+			r.recorder.Eventf(solrCloud, corev1.EventTypeNormal, "ExpandingDataVolumes", "Expanding the data PVCs to %s", size.String())
+		}
+		newStatus.SetCondition(solr.DataVolumeExpansion, corev1.ConditionTrue, "Expanding", message)
+		return true, nil
+	}
+	if condition != nil && condition.Status == corev1.ConditionTrue {
+		newStatus.SetCondition(solr.DataVolumeExpansion, corev1.ConditionFalse, "Expanded", fmt.Sprintf("All data PVCs have been expanded to %s", size.String()))
+	}
+	return false, nil
+}
+
+// storageClassAllowsExpansion returns whether the PVCs of the given StorageClass can be expanded.
+// The default StorageClass is used for PVCs without a StorageClass, which cannot be checked, so expansion is attempted.
+func storageClassAllowsExpansion(r *SolrCloudReconciler, storageClassName string) (bool, error) {
+	if storageClassName == "" {
+		return true, nil
+	}
+	storageClass := &storagev1.StorageClass{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: storageClassName}, storageClass); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return storageClass.AllowVolumeExpansion != nil && *storageClass.AllowVolumeExpansion, nil
+}
+
+// reconcileClusterProperties sets the cluster properties of the spec whose values in Solr are missing or different.
+// If they cannot be set, the ClusterPropertiesNotApplied condition is set, and the wait before trying again is returned.
+func reconcileClusterProperties(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus) (retryAfter time.Duration, applied bool) {
+	apiClient, err := solrApiClientForCloud(r.Client, solrCloud)
+	var currentProperties map[string]string
+	if err == nil {
+		currentProperties, err = util.GetClusterProperties(apiClient)
+	}
+	if err == nil {
+		names := make([]string, 0, len(solrCloud.Spec.ClusterProperties))
+		for name := range solrCloud.Spec.ClusterProperties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := solrCloud.Spec.ClusterProperties[name]
+			if currentValue, found := currentProperties[name]; found && currentValue == value {
+				continue
+			}
+			r.Log.Info("Setting cluster property", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "property", name, "value", value)
+			if err = util.SetClusterProperty(apiClient, name, value); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		r.Log.Error(err, "Could not set the cluster properties of the SolrCloud", "namespace", solrCloud.Namespace, "name", solrCloud.Name)
+	}
+	reason, message, retryAfter := solrApiProblem("Could not set the cluster properties", err)
+	setProblemCondition(r, solrCloud, newStatus, solr.ClusterPropertiesNotApplied, reason, message, "Applied", "The cluster properties are set in Solr")
+	return retryAfter, err == nil
+}
+
+// reconcileAutoscaling sets the autoscaling policy and preferences of the spec, if they differ from the ones stored in Solr.
+// If they cannot be set, the AutoscalingNotApplied condition is set, and the wait before trying again is returned.
+func reconcileAutoscaling(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus) (retryAfter time.Duration, applied bool) {
+	apiClient, err := solrApiClientForCloud(r.Client, solrCloud)
+	var commands map[string]interface{}
+	if err == nil {
+		commands, err = util.AutoscalingCommandsForCloud(apiClient, solrCloud.Spec.Autoscaling)
+	}
+	if err == nil && len(commands) > 0 {
+		r.Log.Info("Setting autoscaling configuration", "namespace", solrCloud.Namespace, "name", solrCloud.Name, "commands", commands)
+		err = util.SetAutoscalingConfig(apiClient, commands)
+	}
+	if err != nil {
+		r.Log.Error(err, "Could not set the autoscaling configuration of the SolrCloud", "namespace", solrCloud.Namespace, "name", solrCloud.Name)
+	}
+	reason, message, retryAfter := solrApiProblem("Could not set the autoscaling configuration", err)
+	setProblemCondition(r, solrCloud, newStatus, solr.AutoscalingNotApplied, reason, message, "Applied", "The autoscaling policy and preferences are set in Solr")
+	return retryAfter, err == nil
+}
+
+// solrApiProblem returns the reason and message of a problem condition for a failed Solr API call, and the wait before trying the call again.
+// No problem is returned if there is no error.
+func solrApiProblem(action string, err error) (reason string, message string, retryAfter time.Duration) {
+	if err == nil {
+		return "", "", 0
+	}
+	if retryLaterAfter, retryLater := util.IsSolrApiRetryLater(err); retryLater {
+		return "SolrApiUnavailable", fmt.Sprintf("%s: %v", action, err), retryLaterAfter
+	}
+	return "SolrApiError", fmt.Sprintf("%s: %v", action, err), util.DefaultSolrApiRetryAfter
+}
+
+// setLiveNodeStates marks each Solr node in the status as live if it is found in the given live nodes
+func setLiveNodeStates(solrCloud *solr.SolrCloud, newStatus *solr.SolrCloudStatus, liveNodes []string) {
+	liveNodeSet := make(map[string]bool, len(liveNodes))
+	for _, liveNode := range liveNodes {
+		liveNodeSet[liveNode] = true
+	}
+	liveCount := int32(0)
+	for idx := range newStatus.SolrNodes {
+		live := liveNodeSet[solrCloud.PodLiveNodeName(newStatus.SolrNodes[idx].Name, newStatus.SolrNodes[idx].IP)]
+		newStatus.SolrNodes[idx].Live = &live
+		if live {
+			liveCount += 1
+		}
+	}
+	newStatus.LiveNodes = &liveCount
+}
+
+// keepLiveNodeStates copies the live states of the Solr nodes from the previous status to the new status
+func keepLiveNodeStates(oldStatus *solr.SolrCloudStatus, newStatus *solr.SolrCloudStatus) {
+	oldLiveStates := make(map[string]*bool, len(oldStatus.SolrNodes))
+	for _, nodeStatus := range oldStatus.SolrNodes {
+		oldLiveStates[nodeStatus.Name] = nodeStatus.Live
+	}
+	for idx := range newStatus.SolrNodes {
+		newStatus.SolrNodes[idx].Live = oldLiveStates[newStatus.SolrNodes[idx].Name]
+	}
+	newStatus.LiveNodes = oldStatus.LiveNodes
+}
+
+// deleteUnusedNodeServices deletes the individual node services of the cloud that do not belong to any of the given Solr nodes
+func deleteUnusedNodeServices(r *SolrCloudReconciler, solrCloud *solr.SolrCloud, solrNodeNames []string) error {
+	if !solrCloud.UsesIndividualNodeServices() {
+		solrNodeNames = []string{}
+	}
+
+	foundServices := &corev1.ServiceList{}
+	selectorLabels := solrCloud.SharedLabels()
+	selectorLabels["service-type"] = "external"
+	listOps := &client.ListOptions{
+		Namespace:     solrCloud.Namespace,
+		LabelSelector: labels.SelectorFromSet(selectorLabels),
+	}
+	if err := r.List(context.TODO(), foundServices, listOps); err != nil {
+		return err
+	}
+
+	for idx := range foundServices.Items {
+		service := &foundServices.Items[idx]
+		if metav1.IsControlledBy(service, solrCloud) && !util.ContainsString(solrNodeNames, service.Name) {
+			r.Log.Info("Deleting Node Service", "namespace", service.Namespace, "name", service.Name)
+			if err := r.Delete(context.TODO(), service); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func reconcileNodeService(r *SolrCloudReconciler, instance *solr.SolrCloud, nodeName string) (err error, ip string) {
+	// Generate Node Service
+	service := util.GenerateNodeService(instance, nodeName)
+	if err := controllerutil.SetControllerReference(instance, service, r.scheme); err != nil {
+		return err, ip
+	}
+
+	// Check if the Ingress already exists
+	foundService := &corev1.Service{}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, foundService)
+	if err != nil && errors.IsNotFound(err) {
+		r.Log.Info("Creating Node Service", "namespace", service.Namespace, "name", service.Name)
+		err = r.Create(context.TODO(), service)
+	} else if err == nil {
+		if util.CopyServiceFields(service, foundService) {
+			// Update the found Ingress and write the result back if there are any changes
+			r.Log.Info("Updating Node Service", "namespace", service.Namespace, "name", service.Name)
+			err = r.Update(context.TODO(), foundService)
+		}
+		ip = foundService.Spec.ClusterIP
+	}
+	if err != nil {
+		return err, ip
+	}
+
+	return nil, ip
+}
+
+// ZookeeperClusterRefCheckInterval is how often a referenced ZookeeperCluster that does not exist is looked up again
+const ZookeeperClusterRefCheckInterval = 30 * time.Second
+
+func reconcileZk(r *SolrCloudReconciler, request reconcile.Request, instance *solr.SolrCloud, busyBoxImage solr.ContainerImage, newStatus *solr.SolrCloudStatus, result *reconcile.Result) error {
+	zkRef := instance.Spec.ZookeeperRef
+
+	if err := zkRef.Validate(); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+
+	if zkRef.ConnectionInfo != nil {
+		// Reject unusable connection information up front, instead of letting the Solr pods crash-loop
+		if err := zkRef.ConnectionInfo.Validate(); err != nil {
+			r.recorder.Event(instance, corev1.EventTypeWarning, "InvalidZookeeperConnectionInfo", err.Error())
+			newStatus.SetCondition(solr.ZkConnectionInfoInvalid, corev1.ConditionTrue, "InvalidConnectionInfo", err.Error())
+			// Keep reporting the last connection information that was in use
+			newStatus.ZookeeperConnectionInfo = instance.Status.ZookeeperConnectionInfo
+			return nil
+		} else if newStatus.GetCondition(solr.ZkConnectionInfoInvalid) != nil {
+			newStatus.SetCondition(solr.ZkConnectionInfoInvalid, corev1.ConditionFalse, "ValidConnectionInfo", "The ZooKeeper connection information is valid")
+		}
+		newStatus.ZookeeperConnectionInfo = *zkRef.ConnectionInfo
+	} else if zkRef.ProvidedZookeeper != nil {
+		pzk := zkRef.ProvidedZookeeper
+		// Generate ZookeeperCluster
+		// The RequiredAPIUnavailable condition explains why the StatefulSet is not created
+		if !useZkCRD {
+			return nil
+		}
+		zkCluster := util.GenerateZookeeperCluster(instance, pzk)
+		if err := controllerutil.SetControllerReference(instance, zkCluster, r.scheme); err != nil {
+			return err
+		}
+
+		// Check if the ZookeeperCluster already exists
+		foundZkCluster := &zk.ZookeeperCluster{}
+		err := r.Get(context.TODO(), types.NamespacedName{Name: zkCluster.Name, Namespace: zkCluster.Namespace}, foundZkCluster)
+		if err != nil && errors.IsNotFound(err) {
+			r.Log.Info("Creating Zookeeer Cluster", "namespace", zkCluster.Namespace, "name", zkCluster.Name)
+			err = r.Create(context.TODO(), zkCluster)
+		} else if err == nil {
+			if util.CopyZookeeperClusterFields(zkCluster, foundZkCluster) {
+				// Update the found ZookeeperCluster and write the result back if there are any changes
+				r.Log.Info("Updating Zookeeer Cluster", "namespace", zkCluster.Namespace, "name", zkCluster.Name)
+				err = r.Update(context.TODO(), foundZkCluster)
+			}
+			newStatus.ZookeeperConnectionInfo = util.ZookeeperClusterConnectionInfo(foundZkCluster, zkCluster.Spec.Replicas, pzk.ChRoot, instance.Spec.SolrAddressability.KubeDomain)
+		}
+		return err
+	} else if zkRef.ZookeeperClusterRef != nil {
+		zkClusterRef := zkRef.ZookeeperClusterRef
+		// The RequiredAPIUnavailable condition explains why the StatefulSet is not created
+		if !useZkCRD {
+			return nil
+		}
+		namespace := zkClusterRef.Namespace
+		if namespace == "" {
+			namespace = instance.Namespace
+		}
+
+		// The referenced ZookeeperCluster is managed outside of the Solr operator, so it is only read
+		foundZkCluster := &zk.ZookeeperCluster{}
+		err := r.Get(context.TODO(), types.NamespacedName{Name: zkClusterRef.Name, Namespace: namespace}, foundZkCluster)
+		if err != nil && errors.IsNotFound(err) {
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, "ZookeeperClusterNotFound", "The referenced ZookeeperCluster %s/%s does not exist", namespace, zkClusterRef.Name)
+			// Keep reporting the last connection information that was in use
+			newStatus.ZookeeperConnectionInfo = instance.Status.ZookeeperConnectionInfo
+			requeueAfter(result, ZookeeperClusterRefCheckInterval)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		newStatus.ZookeeperConnectionInfo = util.ZookeeperClusterConnectionInfo(foundZkCluster, foundZkCluster.Spec.Replicas, zkClusterRef.ChRoot, instance.Spec.SolrAddressability.KubeDomain)
+	} else {
+		return errors.NewBadRequest("No Zookeeper reference information provided.")
+	}
+	return nil
+}
+
+func (r *SolrCloudReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return r.SetupWithManagerAndReconciler(mgr, r)
+}
+
+func (r *SolrCloudReconciler) SetupWithManagerAndReconciler(mgr ctrl.Manager, reconciler reconcile.Reconciler) error {
+	ctrlBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&solr.SolrCloud{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&corev1.Secret{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.referenceToCloudRequests),
+		}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.referenceToCloudRequests),
+		})
+
+	if useZkCRD {
+		ctrlBuilder = ctrlBuilder.Owns(&zk.ZookeeperCluster{}).
+			Watches(&source.Kind{Type: &zk.ZookeeperCluster{}}, &handler.EnqueueRequestsFromMapFunc{
+				ToRequests: handler.ToRequestsFunc(r.zookeeperClusterToCloudRequests),
+			})
+	}
+
+	var ownedUnstructuredGVKs []schema.GroupVersionKind
+	if useIstioAPI {
+		ownedUnstructuredGVKs = append(ownedUnstructuredGVKs, util.IstioGatewayGVK, util.IstioVirtualServiceGVK)
+	}
+	if useRouteAPI {
+		ownedUnstructuredGVKs = append(ownedUnstructuredGVKs, util.RouteGVK)
+	}
+	if useCertManagerAPI {
+		ownedUnstructuredGVKs = append(ownedUnstructuredGVKs, util.CertificateGVK)
+	}
+	for _, gvk := range ownedUnstructuredGVKs {
+		ownedResource := &unstructured.Unstructured{}
+		ownedResource.SetGroupVersionKind(gvk)
+		ctrlBuilder = ctrlBuilder.Owns(ownedResource)
+	}
+
+	if useIngressAPI && useLegacyIngressAPI {
+		ctrlBuilder = ctrlBuilder.Owns(&extv1.Ingress{})
+	} else if useIngressAPI {
+		ctrlBuilder = ctrlBuilder.Owns(&netv1.Ingress{})
 	}
 
 	r.scheme = mgr.GetScheme()
+	r.recorder = mgr.GetEventRecorderFor("solrcloud-controller")
 	return ctrlBuilder.Complete(reconciler)
 }
@@ -17,7 +17,7 @@ limitations under the License.
 package controllers
 
 import (
-	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"strconv"
 	"testing"
@@ -819,7 +819,7 @@ func TestIngressKubeDomainCloudReconcile(t *testing.T) {
 	assert.EqualValues(t, "http://"+instance.Namespace+"-"+instance.Name+"-solrcloud"+"."+testDomain, *instance.Status.ExternalCommonAddress, "Wrong external common address in status")
 }
 
-func testIngressRules(t *testing.T, ingress *extv1.Ingress, withCommon bool, withNodes int, domainNames []string, commonPort int, nodePort int) {
+func testIngressRules(t *testing.T, ingress *netv1.Ingress, withCommon bool, withNodes int, domainNames []string, commonPort int, nodePort int) {
 	expected := 0
 	if withCommon {
 		expected += 1
@@ -856,9 +856,9 @@ func testIngressRules(t *testing.T, ingress *extv1.Ingress, withCommon bool, wit
 			assert.EqualValues(t, expectedHost, rule.Host, "Wrong host for ingress rule: "+ruleName)
 			assert.EqualValues(t, 1, len(rule.HTTP.Paths), "Wrong number of path rules in ingress host: "+ruleName)
 			path := rule.HTTP.Paths[0]
-			assert.EqualValues(t, "", path.Path, "There should be no path value for ingress rule: "+ruleName)
-			assert.EqualValues(t, expectedCloudRequest.Name+"-solrcloud-"+serviceSuffix, path.Backend.ServiceName, "Wrong service name for ingress rule: "+ruleName)
-			assert.EqualValues(t, port, path.Backend.ServicePort.IntVal, "Wrong port name for ingress rule: "+ruleName)
+			assert.EqualValues(t, "/", path.Path, "Wrong path value for ingress rule: "+ruleName)
+			assert.EqualValues(t, expectedCloudRequest.Name+"-solrcloud-"+serviceSuffix, path.Backend.Service.Name, "Wrong service name for ingress rule: "+ruleName)
+			assert.EqualValues(t, port, path.Backend.Service.Port.Number, "Wrong port name for ingress rule: "+ruleName)
 		}
 	}
 }
@@ -17,9 +17,30 @@ limitations under the License.
 package controllers
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	zookeeperv1beta1 "github.com/pravega/zookeeper-operator/pkg/apis/zookeeper/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bloomberg/solr-operator/controllers/util"
 	"github.com/stretchr/testify/assert"
@@ -28,8 +49,17 @@ import (
 	"github.com/onsi/gomega"
 	"golang.org/x/net/context"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -246,12 +276,13 @@ func TestCustomKubeOptionsCloudReconcile(t *testing.T) {
 		"GC_TUNE":   "gc Options",
 	}
 	expectedStatefulSetLabels := util.MergeLabelsOrAnnotations(instance.SharedLabelsWith(instance.Labels), map[string]string{"technology": "solr-cloud"})
-	expectedStatefulSetAnnotations := map[string]string{util.SolrZKConnectionStringAnnotation: "host:7271/"}
+	expectedStatefulSetAnnotations := map[string]string{util.SolrZKConnectionStringAnnotation: "host:7271/", util.SolrNodeAddressingAnnotation: solr.NodeServiceAddressing}
 	testPodEnvVariables(t, expectedEnvVars, statefulSet.Spec.Template.Spec.Containers[0].Env)
 	testMapsEqual(t, "statefulSet labels", util.MergeLabelsOrAnnotations(expectedStatefulSetLabels, testSSLabels), statefulSet.Labels)
 	testMapsEqual(t, "statefulSet annotations", util.MergeLabelsOrAnnotations(expectedStatefulSetAnnotations, testSSAnnotations), statefulSet.Annotations)
 	testMapsEqual(t, "pod labels", util.MergeLabelsOrAnnotations(expectedStatefulSetLabels, testPodLabels), statefulSet.Spec.Template.ObjectMeta.Labels)
-	testMapsEqual(t, "pod annotations", testPodAnnotations, statefulSet.Spec.Template.Annotations)
+	expectedSolrXmlMd5 := fmt.Sprintf("%x", md5.Sum([]byte(util.GenerateConfigMap(instance).Data[util.SolrXmlFile])))
+	testMapsEqual(t, "pod annotations", util.MergeLabelsOrAnnotations(testPodAnnotations, map[string]string{util.SolrXmlMd5Annotation: expectedSolrXmlMd5}), statefulSet.Spec.Template.Annotations)
 	testMapsEqual(t, "pod node selectors", testNodeSelectors, statefulSet.Spec.Template.Spec.NodeSelector)
 	testPodProbe(t, testProbeLivenessNonDefaults, statefulSet.Spec.Template.Spec.Containers[0].LivenessProbe)
 	testPodProbe(t, testProbeReadinessNonDefaults, statefulSet.Spec.Template.Spec.Containers[0].ReadinessProbe)
@@ -359,7 +390,7 @@ func TestCloudWithProvidedZookeeperReconcile(t *testing.T) {
 		"SOLR_PORT": "8983",
 		"GC_TUNE":   "",
 	}
-	expectedStatefulSetAnnotations := map[string]string{util.SolrZKConnectionStringAnnotation: expectedZKHost}
+	expectedStatefulSetAnnotations := map[string]string{util.SolrZKConnectionStringAnnotation: expectedZKHost, util.SolrNodeAddressingAnnotation: solr.HeadlessServiceAddressing}
 	testPodEnvVariables(t, expectedEnvVars, statefulSet.Spec.Template.Spec.Containers[0].Env)
 	testMapsEqual(t, "statefulSet annotations", expectedStatefulSetAnnotations, statefulSet.Annotations)
 	assert.EqualValues(t, []string{"sh", "-c", "solr zk ls ${ZK_CHROOT} -z ${ZK_SERVER} || solr zk mkroot ${ZK_CHROOT} -z ${ZK_SERVER}"}, statefulSet.Spec.Template.Spec.Containers[0].Lifecycle.PostStart.Exec.Command, "Incorrect post-start command")
@@ -435,12 +466,4043 @@ func TestCloudWithExternalZookeeperChroot(t *testing.T) {
 		"SOLR_PORT": "8983",
 		"GC_TUNE":   "",
 	}
-	expectedStatefulSetAnnotations := map[string]string{util.SolrZKConnectionStringAnnotation: expectedZKHost}
+	expectedStatefulSetAnnotations := map[string]string{util.SolrZKConnectionStringAnnotation: expectedZKHost, util.SolrNodeAddressingAnnotation: solr.HeadlessServiceAddressing}
 	testPodEnvVariables(t, expectedEnvVars, statefulSet.Spec.Template.Spec.Containers[0].Env)
 	testMapsEqual(t, "statefulSet annotations", expectedStatefulSetAnnotations, statefulSet.Annotations)
 	assert.EqualValues(t, []string{"sh", "-c", "solr zk ls ${ZK_CHROOT} -z ${ZK_SERVER} || solr zk mkroot ${ZK_CHROOT} -z ${ZK_SERVER}"}, statefulSet.Spec.Template.Spec.Containers[0].Lifecycle.PostStart.Exec.Command, "Incorrect post-start command")
 }
 
+func TestCloudSolrXmlChangeRollsPods(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					Annotations: testPodAnnotations,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	solrXmlMd5 := fmt.Sprintf("%x", md5.Sum([]byte(util.GenerateConfigMap(instance).Data[util.SolrXmlFile])))
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, solrXmlMd5)
+	assert.Equal(t, solrXmlMd5, statefulSet.Spec.Template.Annotations[util.SolrXmlMd5Annotation], "Wrong solr.xml hash on the Solr pod template")
+	for k, v := range testPodAnnotations {
+		assert.Equal(t, v, statefulSet.Spec.Template.Annotations[k], "Pod annotation '%s' not added to the Solr pod template", k)
+	}
+
+	// Regenerating the same solr.xml must not restart the pods
+	foundStatefulSet := statefulSet.DeepCopy()
+	sameSolrXmlMd5 := fmt.Sprintf("%x", md5.Sum([]byte(util.GenerateConfigMap(instance).Data[util.SolrXmlFile])))
+	assert.False(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, sameSolrXmlMd5), foundStatefulSet), "An unchanged solr.xml should not require an update")
+
+	// A changed solr.xml must roll the pods
+	changedSolrXmlMd5 := fmt.Sprintf("%x", md5.Sum([]byte("<solr></solr>")))
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, changedSolrXmlMd5), foundStatefulSet), "A changed solr.xml should require an update")
+	assert.Equal(t, changedSolrXmlMd5, foundStatefulSet.Spec.Template.Annotations[util.SolrXmlMd5Annotation], "The solr.xml hash was not updated on the Solr pod template")
+}
+
+func TestCloudDNSOptions(t *testing.T) {
+	ndots := "1"
+	dnsConfig := &corev1.PodDNSConfig{
+		Searches: []string{"zk.other-cluster.example.com"},
+		Options:  []corev1.PodDNSConfigOption{{Name: "ndots", Value: &ndots}},
+	}
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Empty(t, statefulSet.Spec.Template.Spec.DNSPolicy, "No dnsPolicy should be set by default")
+	assert.Nil(t, statefulSet.Spec.Template.Spec.DNSConfig, "No dnsConfig should be set by default")
+
+	instance.Spec.CustomSolrKubeOptions.PodOptions.DNSPolicy = corev1.DNSNone
+	instance.Spec.CustomSolrKubeOptions.PodOptions.DNSConfig = dnsConfig
+	foundStatefulSet := statefulSet.DeepCopy()
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, corev1.DNSNone, statefulSet.Spec.Template.Spec.DNSPolicy, "Wrong dnsPolicy for the Solr pod")
+	assert.Equal(t, dnsConfig, statefulSet.Spec.Template.Spec.DNSConfig, "Wrong dnsConfig for the Solr pod")
+
+	// Changing the DNS options must be picked up by the copy logic
+	assert.True(t, util.CopyStatefulSetFields(statefulSet, foundStatefulSet), "Changed DNS options should require an update")
+	assert.Equal(t, corev1.DNSNone, foundStatefulSet.Spec.Template.Spec.DNSPolicy, "DNSPolicy not updated")
+	assert.Equal(t, dnsConfig, foundStatefulSet.Spec.Template.Spec.DNSConfig, "DNSConfig not updated")
+}
+
+func TestCloudJvmOptions(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrOpts: "-Dsolr.autoSoftCommit.maxTime=10000",
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	testPodEnvVariables(t, map[string]string{"SOLR_OPTS": "-Dsolr.autoSoftCommit.maxTime=10000"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	// Changing the options must update the StatefulSet, which restarts the Solr pods
+	instance.Spec.SolrOpts = "-Dsolr.autoSoftCommit.maxTime=5000"
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changed solrOpts should require an update")
+	testPodEnvVariables(t, map[string]string{"SOLR_OPTS": "-Dsolr.autoSoftCommit.maxTime=5000"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.False(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Unchanged solrOpts should not require an update")
+
+	instance.Spec.SolrGCTune = "-XX:+UseZGC"
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changed solrGCTune should require an update")
+	testPodEnvVariables(t, map[string]string{"GC_TUNE": "-XX:+UseZGC"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	// The heap can be sized from the memory limit of the Solr container instead
+	percentage := int32(75)
+	instance.Spec.SolrJavaMemPercentage = &percentage
+	instance.Spec.CustomSolrKubeOptions.PodOptions = &solr.PodOptions{
+		Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")}},
+	}
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "A changed heap size should require an update")
+	testPodEnvVariables(t, map[string]string{"SOLR_JAVA_MEM": "-Xms6144m -Xmx6144m"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	// Without a memory limit the heap cannot be sized, which the controller rejects
+	instance.Spec.CustomSolrKubeOptions.PodOptions.Resources.Limits = nil
+	testPodEnvVariables(t, map[string]string{"SOLR_JAVA_MEM": instance.Spec.SolrJavaMem}, util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Env)
+	instance.Spec.SolrJavaMemPercentage = nil
+
+	// The Jetty options are passed before the solrOpts, which take precedence
+	requestHeaderSize, idleTimeout := int32(65536), int32(300000)
+	instance.Spec.Jetty = &solr.SolrJettyOptions{RequestHeaderSize: &requestHeaderSize, IdleTimeoutMillis: &idleTimeout}
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changed Jetty options should require an update")
+	testPodEnvVariables(t, map[string]string{"SOLR_OPTS": "-Dsolr.jetty.request.header.size=65536 -Dsolr.jetty.http.idleTimeout=300000 -Dsolr.autoSoftCommit.maxTime=5000"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	assert.Equal(t, "INFO", instance.Spec.SolrLogLevel, "Wrong default solrLogLevel")
+	instance.Spec.SolrLogLevel = "WARN"
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changed solrLogLevel should require an update")
+	testPodEnvVariables(t, map[string]string{"SOLR_LOG_LEVEL": "WARN"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+}
+
+func TestCloudPodSystemProperties(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrOpts: "-Dsolr.autoSoftCommit.maxTime=10000",
+			PodSystemProperties: []solr.SolrPodSystemProperty{
+				{Name: "host_node", FieldPath: "spec.nodeName"},
+				{Name: "rack", FieldPath: "metadata.labels['topology.kubernetes.io/zone']"},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	env := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Env
+	testPodEnvVariables(t, map[string]string{"SOLR_OPTS": "-Dhost_node=$(SOLR_POD_PROPERTY_0) -Drack=$(SOLR_POD_PROPERTY_1) -Dsolr.autoSoftCommit.maxTime=10000"}, env)
+
+	envIndexes := map[string]int{}
+	for i, envVar := range env {
+		envIndexes[envVar.Name] = i
+	}
+	for i, fieldPath := range []string{"spec.nodeName", "metadata.labels['topology.kubernetes.io/zone']"} {
+		name := fmt.Sprintf("SOLR_POD_PROPERTY_%d", i)
+		if assert.Contains(t, envIndexes, name, "The pod property env var should be defined") {
+			envVar := env[envIndexes[name]]
+			if assert.NotNil(t, envVar.ValueFrom, "The pod property should be read from the downward API") {
+				assert.Equal(t, fieldPath, envVar.ValueFrom.FieldRef.FieldPath, "Wrong pod field")
+			}
+			assert.Less(t, envIndexes[name], envIndexes["SOLR_OPTS"], "The pod property env vars must be defined before the SOLR_OPTS that reference them")
+		}
+	}
+}
+
+func TestCloudTimezone(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			MountLocaltime: true,
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	// Without a timezone, Solr runs in UTC and nothing is mounted
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	for _, envVar := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+		assert.NotEqual(t, "SOLR_TIMEZONE", envVar.Name, "No timezone should be set by default")
+	}
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		assert.NotEqual(t, util.SolrLocaltimeVolume, volume.Name, "The localtime should not be mounted without a timezone")
+	}
+
+	instance.Spec.SolrTimezone = "America/New_York"
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "A changed timezone should require an update")
+	testPodEnvVariables(t, map[string]string{"SOLR_TIMEZONE": "America/New_York"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	var localtimeVolume *corev1.Volume
+	for i, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name == util.SolrLocaltimeVolume {
+			localtimeVolume = &statefulSet.Spec.Template.Spec.Volumes[i]
+		}
+	}
+	if assert.NotNil(t, localtimeVolume, "The localtime volume should be added") && assert.NotNil(t, localtimeVolume.HostPath, "The localtime should be mounted from the node") {
+		assert.Equal(t, "/usr/share/zoneinfo/America/New_York", localtimeVolume.HostPath.Path, "Wrong zoneinfo file")
+	}
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrLocaltimeVolume, MountPath: "/etc/localtime", ReadOnly: true}, "The localtime should be mounted")
+}
+
+func TestCloudSolrModules(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	for _, envVar := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+		assert.NotEqual(t, "SOLR_MODULES", envVar.Name, "No modules should be enabled by default")
+	}
+
+	instance.Spec.SolrModules = []string{"ltr", "analytics", "extraction"}
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Enabling modules should require an update")
+	testPodEnvVariables(t, map[string]string{"SOLR_MODULES": "ltr,analytics,extraction"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	// Solr versions before 9 do not load the modules, so they are rejected instead of being silently ignored
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: record.NewFakeRecorder(10),
+		scheme:   scheme.Scheme,
+	}
+	_, err := r.Reconcile(expectedCloudRequest)
+	if assert.True(t, errors.IsBadRequest(err), "Modules should be rejected for the default Solr 7 image") {
+		assert.Contains(t, err.Error(), "solrModules", "Wrong validation error")
+	}
+	for _, tag := range []string{"9.1.0", "latest"} {
+		instance.Spec.SolrImage.Tag = tag
+		assert.NoError(t, r.Update(context.TODO(), instance))
+		_, err = r.Reconcile(expectedCloudRequest)
+		assert.False(t, errors.IsBadRequest(err), "Modules should be accepted for the Solr image %s", tag)
+		assert.NoError(t, r.Get(context.TODO(), expectedCloudRequest.NamespacedName, instance))
+	}
+}
+
+func TestCloudPluginLibs(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			PluginLibs: &solr.SolrPluginLibsOptions{
+				URLs: []string{"https://repo.example.com/plugins/custom-query-parser-1.0.jar?token=a'b"},
+				VolumeSource: &corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "plugin-jars"}},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	if assert.Len(t, podSpec.InitContainers, 2, "The plugin init container should run after the solr.xml init container") {
+		pluginContainer := podSpec.InitContainers[1]
+		assert.Equal(t, instance.Spec.BusyBoxImage.ToImageName(), pluginContainer.Image, "The busyBoxImage should be used by default")
+		assert.Equal(t, []string{"sh", "-c", "cp /plugin-libs-source/*.jar /plugin-libs/ && 'wget' '-q' '-O' '/plugin-libs/custom-query-parser-1.0.jar' 'https://repo.example.com/plugins/custom-query-parser-1.0.jar?token=a'\\''b'"}, pluginContainer.Command, "Wrong command to provide the plugin jars")
+	}
+	assert.Contains(t, podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrPluginLibsVolume, MountPath: "/var/solr/data/lib", ReadOnly: true}, "The plugin jars should be mounted in the lib directory of the SOLR_HOME")
+	_, configMaps := util.PodSpecReferences(&podSpec)
+	assert.Contains(t, configMaps, "plugin-jars", "The ConfigMap with the plugin jars must exist for the Solr pods to start")
+
+	// The plugin init container is used even if the solr.xml init container is disabled
+	instance.Spec.DataStorage = &solr.SolrDataStorageOptions{InitContainer: &solr.SolrDataInitContainerOptions{Disabled: true}}
+	instance.Spec.PluginLibs.VolumeSource = nil
+	podSpec = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	if assert.Len(t, podSpec.InitContainers, 1, "Only the plugin init container should be used") {
+		assert.Equal(t, "solr-plugin-libs", podSpec.InitContainers[0].Name, "Wrong init container")
+	}
+	assert.Contains(t, podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: "solr-xml", MountPath: "/var/solr/data/solr.xml", SubPath: util.SolrXmlFile}, "The solr.xml should be mounted directly")
+}
+
+func TestCloudPodTemplateOverrides(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			PodTemplateOverrides: &runtime.RawExtension{Raw: []byte(`{
+				"metadata": {"labels": {"team": "search"}},
+				"spec": {
+					"shareProcessNamespace": true,
+					"containers": [{"name": "solrcloud-node", "env": [{"name": "SOLR_HEAP", "value": "4g"}], "terminationMessagePolicy": "FallbackToLogsOnError"}]
+				}
+			}`)},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	podTemplate := statefulSet.Spec.Template
+	assert.Equal(t, "search", podTemplate.Labels["team"], "The label from the overrides was not added")
+	assert.Equal(t, instance.Name, podTemplate.Labels["solr-cloud"], "The generated labels should be kept")
+	assert.True(t, *podTemplate.Spec.ShareProcessNamespace, "The pod spec from the overrides was not applied")
+	assert.Len(t, podTemplate.Spec.Containers, 1, "The Solr container should be patched, not added")
+	solrContainer := podTemplate.Spec.Containers[0]
+	assert.Equal(t, corev1.TerminationMessageFallbackToLogsOnError, solrContainer.TerminationMessagePolicy, "The Solr container was not patched")
+	assert.Contains(t, solrContainer.Env, corev1.EnvVar{Name: "SOLR_HEAP", Value: "4g"}, "The env var from the overrides was not added")
+	assert.Contains(t, solrContainer.Env, corev1.EnvVar{Name: "SOLR_JAVA_MEM", Value: instance.Spec.SolrJavaMem}, "The generated env vars should be kept")
+	assert.NotNil(t, solrContainer.ReadinessProbe, "The generated probes should be kept")
+
+	// Changes to the overrides must be picked up by the copy logic
+	instance.Spec.PodTemplateOverrides = &runtime.RawExtension{Raw: []byte(`{"spec": {"shareProcessNamespace": false}}`)}
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changed overrides should require an update")
+
+	assert.Error(t, util.ApplyPodTemplateOverrides(&corev1.PodTemplateSpec{}, &runtime.RawExtension{Raw: []byte(`{"spec": {"containers": "solr"}}`)}), "Invalid overrides should not be applied")
+}
+
+func TestCloudLifecycleOptions(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					Lifecycle: &corev1.Lifecycle{
+						PostStart: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"/opt/register.sh", "--catalog", "it's-me"}}},
+						PreStop:   &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"sh", "-c", "curl -s localhost:8983/flush"}}},
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/solr"}}
+
+	// Custom exec hooks run along with the generated ones
+	lifecycle := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Lifecycle
+	assert.Equal(t, []string{"sh", "-c", "(solr zk ls ${ZK_CHROOT} -z ${ZK_SERVER} || solr zk mkroot ${ZK_CHROOT} -z ${ZK_SERVER}) && '/opt/register.sh' '--catalog' 'it'\\''s-me'"}, lifecycle.PostStart.Exec.Command, "The custom postStart should run after the chRoot is created")
+	assert.Equal(t, []string{"sh", "-c", "(curl -s localhost:8983/flush) ; 'solr' 'stop' '-p' '8983'"}, lifecycle.PreStop.Exec.Command, "The custom preStop should run before Solr is stopped")
+
+	// Without a generated postStart, the custom one is used on its own
+	status.ZookeeperConnectionInfo.ChRoot = "/"
+	lifecycle = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Lifecycle
+	assert.Equal(t, []string{"/opt/register.sh", "--catalog", "it's-me"}, lifecycle.PostStart.Exec.Command, "The custom postStart should be used on its own")
+
+	// Other handlers replace the generated ones
+	httpPreStop := &corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/flush", Port: intstr.FromInt(8983)}}
+	instance.Spec.CustomSolrKubeOptions.PodOptions.Lifecycle.PreStop = httpPreStop
+	lifecycle = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Lifecycle
+	assert.Equal(t, httpPreStop, lifecycle.PreStop, "A custom http preStop should replace the generated one")
+
+	// Without custom hooks, only the generated ones are used
+	instance.Spec.CustomSolrKubeOptions.PodOptions.Lifecycle = nil
+	lifecycle = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Lifecycle
+	assert.Nil(t, lifecycle.PostStart, "No postStart is needed without a chRoot")
+	assert.Equal(t, []string{"solr", "stop", "-p", "8983"}, lifecycle.PreStop.Exec.Command, "Wrong default preStop")
+}
+
+func TestCloudProbeOptions(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					LivenessProbe: &corev1.Probe{
+						InitialDelaySeconds: 300,
+						FailureThreshold:    10,
+						Handler:             corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/solr/admin/info/health"}},
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	livenessProbe := statefulSet.Spec.Template.Spec.Containers[0].LivenessProbe
+	assert.EqualValues(t, 300, livenessProbe.InitialDelaySeconds, "Wrong initialDelaySeconds for the liveness probe")
+	assert.EqualValues(t, 10, livenessProbe.FailureThreshold, "Wrong failureThreshold for the liveness probe")
+	assert.EqualValues(t, util.DefaultLivenessProbePeriodSeconds, livenessProbe.PeriodSeconds, "Options that are not given should use the defaults")
+	assert.Equal(t, "/solr/admin/info/health", livenessProbe.HTTPGet.Path, "Wrong path for the liveness probe")
+	assert.Equal(t, intstr.FromInt(instance.Spec.SolrAddressability.PodPort), livenessProbe.HTTPGet.Port, "The port of the default check should be used")
+	assert.Equal(t, corev1.URISchemeHTTP, livenessProbe.HTTPGet.Scheme, "The scheme of the default check should be used")
+	assert.Empty(t, instance.Spec.CustomSolrKubeOptions.PodOptions.LivenessProbe.HTTPGet.Port, "The probe options of the SolrCloud should not be changed")
+
+	readinessProbe := statefulSet.Spec.Template.Spec.Containers[0].ReadinessProbe
+	assert.EqualValues(t, util.DefaultReadinessProbeInitialDelaySeconds, readinessProbe.InitialDelaySeconds, "The default readiness probe should be used")
+	assert.Equal(t, "/solr/admin/info/system", readinessProbe.HTTPGet.Path, "The default readiness probe should be used")
+
+	// Changing the probe options must be picked up by the copy logic
+	foundStatefulSet := statefulSet.DeepCopy()
+	instance.Spec.CustomSolrKubeOptions.PodOptions.ReadinessProbe = &corev1.Probe{PeriodSeconds: 30}
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), foundStatefulSet), "Changed probe options should require an update")
+	assert.EqualValues(t, 30, foundStatefulSet.Spec.Template.Spec.Containers[0].ReadinessProbe.PeriodSeconds, "The readiness probe was not updated")
+
+	// A startup probe is only added when it is requested
+	assert.Nil(t, statefulSet.Spec.Template.Spec.Containers[0].StartupProbe, "No startup probe should be added by default")
+	instance.Spec.CustomSolrKubeOptions.PodOptions.StartupProbe = &corev1.Probe{FailureThreshold: 60}
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), foundStatefulSet), "An added startup probe should require an update")
+	startupProbe := foundStatefulSet.Spec.Template.Spec.Containers[0].StartupProbe
+	assert.NotNil(t, startupProbe, "The startup probe was not added")
+	assert.EqualValues(t, 60, startupProbe.FailureThreshold, "Wrong failureThreshold for the startup probe")
+	assert.EqualValues(t, util.DefaultStartupProbePeriodSeconds, startupProbe.PeriodSeconds, "Options that are not given should use the defaults")
+	assert.Equal(t, "/solr/admin/info/system", startupProbe.HTTPGet.Path, "The startup probe should use the default check")
+}
+
+func TestCloudPodLabelsAndAnnotations(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					Labels:      map[string]string{"cost-center": "search", "technology": "other"},
+					Annotations: map[string]string{"sidecar.istio.io/inject": "true"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, "search", statefulSet.Spec.Template.Labels["cost-center"], "The custom pod label was not added")
+	assert.Equal(t, solr.SolrTechnologyLabel, statefulSet.Spec.Template.Labels["technology"], "The selector labels of the operator should not be overridden")
+	assert.Equal(t, "true", statefulSet.Spec.Template.Annotations["sidecar.istio.io/inject"], "The custom pod annotation was not added")
+
+	// Labels and annotations added outside of the operator are kept
+	foundStatefulSet := statefulSet.DeepCopy()
+	foundStatefulSet.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = "2020-10-01T00:00:00Z"
+	foundStatefulSet.Spec.Template.Labels["team"] = "search"
+	assert.False(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), foundStatefulSet), "Additional pod labels and annotations should not require an update")
+	assert.Equal(t, "2020-10-01T00:00:00Z", foundStatefulSet.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"], "The additional pod annotation should be kept")
+	assert.Equal(t, "search", foundStatefulSet.Spec.Template.Labels["team"], "The additional pod label should be kept")
+
+	// Changing the custom labels and annotations updates the pod template
+	instance.Spec.CustomSolrKubeOptions.PodOptions.Annotations["sidecar.istio.io/inject"] = "false"
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), foundStatefulSet), "Changed pod annotations should require an update")
+	assert.Equal(t, "false", foundStatefulSet.Spec.Template.Annotations["sidecar.istio.io/inject"], "The pod annotation was not updated")
+	assert.Equal(t, "2020-10-01T00:00:00Z", foundStatefulSet.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"], "The additional pod annotation should be kept")
+}
+
+func TestCloudEnvOptions(t *testing.T) {
+	envFrom := []corev1.EnvFromSource{
+		{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "solr-credentials"}}},
+		{Prefix: "PROXY_", ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "proxy-settings"}}},
+	}
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					EnvVariables: []corev1.EnvVar{{Name: "SOLR_HEAP", Value: "4g"}},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	solrContainer := statefulSet.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, solrContainer.Env, corev1.EnvVar{Name: "SOLR_HEAP", Value: "4g"}, "The custom environment variable was not added to the Solr container")
+	assert.Empty(t, solrContainer.EnvFrom, "No envFrom should be set by default")
+
+	// Adding envFrom sources to an existing cloud must update the StatefulSet
+	instance.Spec.CustomSolrKubeOptions.PodOptions.EnvFrom = envFrom
+	foundStatefulSet := statefulSet.DeepCopy()
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, envFrom, statefulSet.Spec.Template.Spec.Containers[0].EnvFrom, "Wrong envFrom for the Solr container")
+	assert.True(t, util.CopyStatefulSetFields(statefulSet, foundStatefulSet), "Changed envFrom should require an update")
+	assert.Equal(t, envFrom, foundStatefulSet.Spec.Template.Spec.Containers[0].EnvFrom, "EnvFrom not updated")
+}
+
+func TestCloudImagePullSecrets(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrImage: &solr.ContainerImage{ImagePullSecret: "solr-registry"},
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "solr-registry"}}, statefulSet.Spec.Template.Spec.ImagePullSecrets, "Wrong pull secrets for the Solr image")
+
+	// Additional pull secrets are added after the one of the Solr image, without duplicates
+	instance.Spec.CustomSolrKubeOptions.PodOptions.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "sidecar-registry"}, {Name: "solr-registry"}}
+	foundStatefulSet := statefulSet.DeepCopy()
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), foundStatefulSet), "Changed pull secrets should require an update")
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "solr-registry"}, {Name: "sidecar-registry"}}, foundStatefulSet.Spec.Template.Spec.ImagePullSecrets, "Wrong merged pull secrets")
+
+	// The additional pull secrets can be used without a pull secret for the Solr image
+	instance.Spec.SolrImage.ImagePullSecret = ""
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "sidecar-registry"}, {Name: "solr-registry"}}, util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.ImagePullSecrets, "Wrong additional pull secrets")
+
+	instance.Spec.CustomSolrKubeOptions.PodOptions.ImagePullSecrets = nil
+	assert.Nil(t, util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.ImagePullSecrets, "No pull secrets should be set by default")
+}
+
+func TestCloudPodScheduling(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Empty(t, statefulSet.Spec.Template.Spec.Tolerations, "No tolerations should be set by default")
+	assert.Empty(t, statefulSet.Spec.Template.Spec.NodeSelector, "No nodeSelector should be set by default")
+	assert.Empty(t, statefulSet.Spec.Template.Spec.PriorityClassName, "No priorityClassName should be set by default")
+	assert.Empty(t, statefulSet.Spec.Template.Spec.SchedulerName, "No schedulerName should be set by default")
+	assert.Nil(t, statefulSet.Spec.Template.Spec.RuntimeClassName, "No runtimeClassName should be set by default")
+
+	instance.Spec.CustomSolrKubeOptions.PodOptions.Tolerations = testTolerations
+	instance.Spec.CustomSolrKubeOptions.PodOptions.NodeSelector = testNodeSelectors
+	instance.Spec.CustomSolrKubeOptions.PodOptions.PriorityClassName = "solr-high-priority"
+	instance.Spec.CustomSolrKubeOptions.PodOptions.SchedulerName = "bin-packing-scheduler"
+	runtimeClassName := "gvisor"
+	instance.Spec.CustomSolrKubeOptions.PodOptions.RuntimeClassName = &runtimeClassName
+	foundStatefulSet := statefulSet.DeepCopy()
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	testPodTolerations(t, testTolerations, statefulSet.Spec.Template.Spec.Tolerations)
+	testMapsEqual(t, "pod node selectors", testNodeSelectors, statefulSet.Spec.Template.Spec.NodeSelector)
+	assert.Equal(t, "solr-high-priority", statefulSet.Spec.Template.Spec.PriorityClassName, "Wrong priorityClassName for the Solr pod")
+	assert.Equal(t, "bin-packing-scheduler", statefulSet.Spec.Template.Spec.SchedulerName, "Wrong schedulerName for the Solr pod")
+	assert.Equal(t, &runtimeClassName, statefulSet.Spec.Template.Spec.RuntimeClassName, "Wrong runtimeClassName for the Solr pod")
+
+	// Adding the scheduling options to an existing cloud must update the StatefulSet
+	assert.True(t, util.CopyStatefulSetFields(statefulSet, foundStatefulSet), "Changed scheduling options should require an update")
+	testPodTolerations(t, testTolerations, foundStatefulSet.Spec.Template.Spec.Tolerations)
+	testMapsEqual(t, "pod node selectors", testNodeSelectors, foundStatefulSet.Spec.Template.Spec.NodeSelector)
+	assert.Equal(t, "solr-high-priority", foundStatefulSet.Spec.Template.Spec.PriorityClassName, "PriorityClassName not updated")
+	assert.Equal(t, "bin-packing-scheduler", foundStatefulSet.Spec.Template.Spec.SchedulerName, "SchedulerName not updated")
+	assert.Equal(t, &runtimeClassName, foundStatefulSet.Spec.Template.Spec.RuntimeClassName, "RuntimeClassName not updated")
+	assert.False(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), foundStatefulSet), "Unchanged scheduling options should not require an update")
+}
+
+func TestCloudLogOptions(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	findVolume := func(volumes []corev1.Volume, name string) *corev1.Volume {
+		for _, volume := range volumes {
+			if volume.Name == name {
+				return &volume
+			}
+		}
+		return nil
+	}
+	findEnvVar := func(envVars []corev1.EnvVar, name string) *corev1.EnvVar {
+		for _, envVar := range envVars {
+			if envVar.Name == name {
+				return &envVar
+			}
+		}
+		return nil
+	}
+
+	// No log options, nothing changes
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Nil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume), "No log volume should be created by default")
+	assert.Nil(t, findEnvVar(statefulSet.Spec.Template.Spec.Containers[0].Env, "SOLR_LOGS_DIR"), "SOLR_LOGS_DIR should not be set by default")
+	assert.NotContains(t, util.GenerateConfigMap(instance).Data, util.LogXmlFile, "No log4j2.xml should be generated by default")
+
+	// Default emptyDir log volume
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{}
+	instance.WithDefaults("")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	logVolume := findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume)
+	if assert.NotNil(t, logVolume, "Log volume not created") {
+		assert.NotNil(t, logVolume.EmptyDir, "Log volume should be an emptyDir by default")
+	}
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrLogsVolume, MountPath: util.SolrLogsPath}, "Log volume not mounted")
+	testPodEnvVariables(t, map[string]string{"SOLR_LOGS_DIR": util.SolrLogsPath}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	// A size limit on the log volume, separate from the data volume
+	sizeLimit := resource.MustParse("2Gi")
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &sizeLimit}}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	if logVolume = findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume); assert.NotNil(t, logVolume, "Log volume not created") {
+		assert.Equal(t, &sizeLimit, logVolume.EmptyDir.SizeLimit, "The size limit was not set on the log volume")
+	}
+	if dataVolume := findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrDataVolume); assert.NotNil(t, dataVolume, "Data volume not created") {
+		assert.Nil(t, dataVolume.EmptyDir.SizeLimit, "The log size limit should not apply to the data volume")
+	}
+
+	// PVC log volume
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{PersistentVolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{}}
+	instance.WithDefaults("")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Nil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume), "Log emptyDir should not be created when a pvcSpec is provided")
+	if assert.Equal(t, 1, len(statefulSet.Spec.VolumeClaimTemplates), "Wrong number of volumeClaimTemplates") {
+		assert.Equal(t, util.SolrLogsVolume, statefulSet.Spec.VolumeClaimTemplates[0].Name, "Wrong name for the log volumeClaimTemplate")
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, statefulSet.Spec.VolumeClaimTemplates[0].Spec.AccessModes, "Wrong default accessModes for the log PVC")
+	}
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrLogsVolume, MountPath: util.SolrLogsPath}, "Log volume not mounted")
+
+	// Log to stdout only
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{LogToStdout: true}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Nil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume), "No log volume should be created when logging to stdout")
+	assert.Empty(t, statefulSet.Spec.VolumeClaimTemplates, "No log PVC should be created when logging to stdout")
+	assert.Nil(t, findEnvVar(statefulSet.Spec.Template.Spec.Containers[0].Env, "SOLR_LOGS_DIR"), "SOLR_LOGS_DIR should not be set when logging to stdout")
+	testPodEnvVariables(t, map[string]string{"LOG4J_PROPS": util.SolrLog4j2ConfigPath + "/" + util.LogXmlFile, "GC_LOG_OPTS": ""}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.NotNil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLog4j2ConfigVolume), "The log4j2.xml volume was not created")
+	assert.Equal(t, util.StdoutLog4j2Xml, util.GenerateConfigMap(instance).Data[util.LogXmlFile], "The stdout log4j2.xml was not added to the ConfigMap")
+
+	// An inline log4j2 configuration replaces the stdout configuration
+	jsonLog4j2Xml := "<Configuration><Appenders><Console name=\"STDOUT\"><JsonTemplateLayout/></Console></Appenders></Configuration>"
+	instance.Spec.SolrLogs.Log4j2Config = &solr.SolrLog4j2ConfigOptions{Inline: jsonLog4j2Xml}
+	assert.NoError(t, instance.Spec.SolrLogs.Log4j2Config.Validate())
+	assert.Equal(t, jsonLog4j2Xml, util.GenerateConfigMap(instance).Data[util.LogXmlFile], "The inline log4j2.xml was not added to the ConfigMap")
+
+	// A log4j2 configuration from another ConfigMap can be used along with a log volume
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{
+		Log4j2Config: &solr.SolrLog4j2ConfigOptions{
+			ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "logging"}, Key: "solr-log4j2.xml"},
+		},
+	}
+	assert.NoError(t, instance.Spec.SolrLogs.Log4j2Config.Validate())
+	assert.NotContains(t, util.GenerateConfigMap(instance).Data, util.LogXmlFile, "No log4j2.xml should be generated when a ConfigMap is referenced")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.NotNil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume), "The log volume should still be created")
+	if log4j2Volume := findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLog4j2ConfigVolume); assert.NotNil(t, log4j2Volume, "The log4j2.xml volume was not created") {
+		assert.Equal(t, "logging", log4j2Volume.ConfigMap.Name, "The log4j2.xml should be mounted from the referenced ConfigMap")
+		assert.Equal(t, []corev1.KeyToPath{{Key: "solr-log4j2.xml", Path: util.LogXmlFile}}, log4j2Volume.ConfigMap.Items, "The referenced key should be mounted as the log4j2.xml")
+	}
+	testPodEnvVariables(t, map[string]string{"LOG4J_PROPS": util.SolrLog4j2ConfigPath + "/" + util.LogXmlFile, "SOLR_LOGS_DIR": util.SolrLogsPath}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.Nil(t, findEnvVar(statefulSet.Spec.Template.Spec.Containers[0].Env, "GC_LOG_OPTS"), "GC logs should still be written")
+
+	// Only one of the options can be given
+	instance.Spec.SolrLogs.Log4j2Config.Inline = jsonLog4j2Xml
+	assert.Error(t, instance.Spec.SolrLogs.Log4j2Config.Validate(), "The inline configuration and the configMapRef cannot both be provided")
+	assert.Error(t, (&solr.SolrLog4j2ConfigOptions{}).Validate(), "One of the options must be provided")
+
+	// Changes to the configuration restart the pods, unless log4j2 reloads it by itself
+	util.SetLog4j2ConfigMd5(statefulSet, jsonLog4j2Xml)
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum([]byte(jsonLog4j2Xml))), statefulSet.Spec.Template.Annotations[util.Log4j2XmlMd5Annotation], "Wrong log4j2.xml hash")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	util.SetLog4j2ConfigMd5(statefulSet, "<Configuration monitorInterval=\"30\"/>")
+	assert.NotContains(t, statefulSet.Spec.Template.Annotations, util.Log4j2XmlMd5Annotation, "A reloaded configuration should not be hashed")
+
+	// GC logs are rotated in their own volume, even when logging to stdout
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{LogToStdout: true, GCLogs: &solr.SolrGCLogsOptions{}}
+	instance.WithDefaults("")
+	assert.Equal(t, solr.DefaultSolrGCLogFileCount, *instance.Spec.SolrLogs.GCLogs.FileCount, "Wrong default GC log file count")
+	assert.Equal(t, solr.DefaultSolrGCLogFileSize, instance.Spec.SolrLogs.GCLogs.FileSize, "Wrong default GC log file size")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	// The default Solr image runs on Java 8, which does not support unified logging
+	testPodEnvVariables(t, map[string]string{"GC_LOG_OPTS": "-Xloggc:/var/solr/gc-logs/solr_gc.log -XX:+UseGCLogFileRotation -XX:NumberOfGCLogFiles=9 -XX:GCLogFileSize=20M"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	if gcLogVolume := findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrGCLogsVolume); assert.NotNil(t, gcLogVolume, "The GC log volume was not created") {
+		assert.NotNil(t, gcLogVolume.EmptyDir, "The GC log volume should be an emptyDir by default")
+	}
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrGCLogsVolume, MountPath: util.SolrGCLogsPath}, "GC log volume not mounted")
+	assert.Nil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrLogsVolume), "No log volume should be created when logging to stdout")
+
+	// Solr 8 images run on Java 11, which uses unified logging
+	instance.Spec.SolrImage.Tag = "8.11.1"
+	assert.Equal(t, int32(11), instance.GCLogsJavaVersion(), "Solr 8 images run on Java 11")
+	instance.Spec.SolrImage.Tag = "latest"
+	assert.Equal(t, int32(11), instance.GCLogsJavaVersion(), "Images without a version should be assumed to run on Java 11")
+	instance.Spec.SolrImage.Tag = "8.11.1"
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	testPodEnvVariables(t, map[string]string{"GC_LOG_OPTS": "-Xlog:gc*:file=/var/solr/gc-logs/solr_gc.log:time,uptime:filecount=9,filesize=20M"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	// The Java version can be provided for custom images
+	fileCount := int32(3)
+	javaVersion := int32(8)
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{GCLogs: &solr.SolrGCLogsOptions{FileCount: &fileCount, FileSize: "50M", JavaVersion: &javaVersion}}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	testPodEnvVariables(t, map[string]string{"GC_LOG_OPTS": "-Xloggc:/var/solr/gc-logs/solr_gc.log -XX:+UseGCLogFileRotation -XX:NumberOfGCLogFiles=3 -XX:GCLogFileSize=50M"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+
+	instance.Spec.SolrLogs = &solr.SolrLogsOptions{GCLogs: &solr.SolrGCLogsOptions{FileCount: &fileCount, FileSize: "50M", PersistentVolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{}}}
+	instance.WithDefaults("")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	testPodEnvVariables(t, map[string]string{"GC_LOG_OPTS": "-Xlog:gc*:file=/var/solr/gc-logs/solr_gc.log:time,uptime:filecount=3,filesize=50M", "SOLR_LOGS_DIR": util.SolrLogsPath}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.Nil(t, findVolume(statefulSet.Spec.Template.Spec.Volumes, util.SolrGCLogsVolume), "The GC log emptyDir should not be created when a pvcSpec is provided")
+	if assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1, "Wrong number of volumeClaimTemplates") {
+		assert.Equal(t, util.SolrGCLogsVolume, statefulSet.Spec.VolumeClaimTemplates[0].Name, "Wrong name for the GC log volumeClaimTemplate")
+		assert.Equal(t, resource.MustParse(solr.DefaultSolrLogStorage), statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage], "Wrong default size for the GC log PVC")
+	}
+}
+
+func TestCloudDataStorageOptions(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	// The default paths
+	defaultStatefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, corev1.VolumeMount{Name: util.SolrDataVolume, MountPath: "/var/solr/data"}, defaultStatefulSet.Spec.Template.Spec.Containers[0].VolumeMounts[0], "Wrong default data mount")
+	testPodEnvVariables(t, map[string]string{"SOLR_HOME": "/var/solr/data"}, defaultStatefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.Equal(t, []string{"sh", "-c", "cp /tmp/solr.xml /tmp-config/solr.xml"}, defaultStatefulSet.Spec.Template.Spec.InitContainers[0].Command, "Wrong default command to copy the solr.xml")
+
+	// Custom paths
+	instance.Spec.DataStorage = &solr.SolrDataStorageOptions{
+		DataMountPath: "/data/solr/",
+		SolrHome:      "/data/solr/home",
+	}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, corev1.VolumeMount{Name: util.SolrDataVolume, MountPath: "/data/solr"}, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts[0], "Wrong custom data mount")
+	testPodEnvVariables(t, map[string]string{"SOLR_HOME": "/data/solr/home"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.Equal(t, []string{"sh", "-c", "mkdir -p /tmp-config/home && cp /tmp/solr.xml /tmp-config/home/solr.xml"}, statefulSet.Spec.Template.Spec.InitContainers[0].Command, "The solr.xml is not copied to the custom SOLR_HOME")
+	instance.Spec.PluginLibs = &solr.SolrPluginLibsOptions{}
+	assert.Contains(t, util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrPluginLibsVolume, MountPath: "/data/solr/home/lib", ReadOnly: true}, "The plugin jars are not mounted in the custom SOLR_HOME")
+	instance.Spec.PluginLibs = nil
+
+	// Changing the paths of an existing cloud is rejected
+	assert.NoError(t, util.ValidateStatefulSetDataPaths(statefulSet, statefulSet.DeepCopy()), "Unchanged data paths should be valid")
+	assert.Error(t, util.ValidateStatefulSetDataPaths(statefulSet, defaultStatefulSet), "Changing the data paths should be rejected")
+	instance.Spec.DataStorage.SolrHome = ""
+	assert.Error(t, util.ValidateStatefulSetDataPaths(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changing the SOLR_HOME should be rejected")
+
+	// The SOLR_HOME must be within the data volume
+	instance.Spec.DataStorage.SolrHome = "/data/other"
+	_, err := instance.SolrHomeDataSubPath()
+	assert.Error(t, err, "A SOLR_HOME outside of the data volume should be rejected")
+	instance.Spec.DataStorage.SolrHome = "/data/solr-home"
+	_, err = instance.SolrHomeDataSubPath()
+	assert.Error(t, err, "A SOLR_HOME outside of the data volume should be rejected")
+}
+
+func TestCloudEmptyDirDataStorage(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	dataVolume := func(statefulSet *appsv1.StatefulSet) *corev1.Volume {
+		for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+			if volume.Name == util.SolrDataVolume {
+				return &volume
+			}
+		}
+		return nil
+	}
+
+	// Without options, the data is stored in an unbounded emptyDir
+	if volume := dataVolume(util.GenerateStatefulSet(instance, status, nil, "")); assert.NotNil(t, volume, "The emptyDir data volume was not created") {
+		assert.Equal(t, &corev1.EmptyDirVolumeSource{}, volume.EmptyDir, "Wrong default emptyDir for the data volume")
+	}
+
+	sizeLimit := resource.MustParse("10Gi")
+	instance.Spec.DataStorage = &solr.SolrDataStorageOptions{
+		EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: &sizeLimit},
+	}
+	instance.WithDefaults("")
+	if volume := dataVolume(util.GenerateStatefulSet(instance, status, nil, "")); assert.NotNil(t, volume, "The emptyDir data volume was not created") {
+		assert.Equal(t, instance.Spec.DataStorage.EmptyDir, volume.EmptyDir, "The emptyDir options were not used for the data volume")
+	}
+
+	// The emptyDir options are ignored when the data is stored in PVCs
+	instance.Spec.DataStorage.Persistent = &solr.SolrPersistentDataStorageOptions{}
+	instance.WithDefaults("")
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Nil(t, dataVolume(statefulSet), "No emptyDir data volume should be created for persistent storage")
+	assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1, "Wrong number of volumeClaimTemplates")
+}
+
+func TestCloudTlogStorage(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			DataStorage: &solr.SolrDataStorageOptions{
+				Tlog: &solr.SolrTlogStorageOptions{},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	// Without options, the transaction logs are stored in an emptyDir
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	foundVolume := false
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name == util.SolrTlogVolume {
+			foundVolume = true
+			assert.Equal(t, &corev1.EmptyDirVolumeSource{}, volume.EmptyDir, "Wrong emptyDir for the transaction log volume")
+		}
+	}
+	assert.True(t, foundVolume, "The transaction log volume was not created")
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrTlogVolume, MountPath: util.SolrTlogPath}, "The transaction log volume is not mounted")
+	for _, envVar := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+		if envVar.Name == "SOLR_OPTS" {
+			assert.Contains(t, envVar.Value, "-Dsolr.ulog.dir="+util.SolrTlogPath, "Solr is not pointed to the transaction log volume")
+		}
+	}
+
+	// Persistent transaction logs get their own volumeClaimTemplate, next to the data volume
+	storageClass := "fast-ssd"
+	size := resource.MustParse("20Gi")
+	instance.Spec.DataStorage.Persistent = &solr.SolrPersistentDataStorageOptions{}
+	instance.Spec.DataStorage.Tlog.Persistent = &solr.SolrPersistentDataStorageOptions{StorageClassName: &storageClass, Size: &size}
+	instance.WithDefaults("")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		assert.NotEqual(t, util.SolrTlogVolume, volume.Name, "No emptyDir transaction log volume should be created for persistent storage")
+	}
+	if assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 2, "Wrong number of volumeClaimTemplates") {
+		pvc := statefulSet.Spec.VolumeClaimTemplates[1]
+		assert.Equal(t, util.SolrTlogVolume, pvc.Name, "Wrong name for the transaction log volumeClaimTemplate")
+		assert.Equal(t, &storageClass, pvc.Spec.StorageClassName, "Wrong StorageClass")
+		assert.Equal(t, size, pvc.Spec.Resources.Requests[corev1.ResourceStorage], "Wrong size")
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, pvc.Spec.AccessModes, "Wrong default accessModes")
+	}
+}
+
+func TestCloudLocalDataStorage(t *testing.T) {
+	storageClass := "local-nvme"
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			DataStorage: &solr.SolrDataStorageOptions{
+				Persistent: &solr.SolrPersistentDataStorageOptions{
+					StorageClassName: &storageClass,
+					Local:            &solr.SolrLocalStorageOptions{NodeSelector: map[string]string{"disk": "nvme", "pool": "solr"}},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	localRequirements := []corev1.NodeSelectorRequirement{
+		{Key: "disk", Operator: corev1.NodeSelectorOpIn, Values: []string{"nvme"}},
+		{Key: "pool", Operator: corev1.NodeSelectorOpIn, Values: []string{"solr"}},
+	}
+
+	// Without a custom affinity, the pods are required to run on the nodes with local disks
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	if affinity := statefulSet.Spec.Template.Spec.Affinity; assert.NotNil(t, affinity, "No affinity was generated for local storage") {
+		assert.Equal(t, []corev1.NodeSelectorTerm{{MatchExpressions: localRequirements}}, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, "Wrong node affinity for local storage")
+	}
+
+	// The local storage requirements are added to every term of a custom node affinity
+	customAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}}},
+				},
+			},
+		},
+	}
+	instance.Spec.CustomSolrKubeOptions.PodOptions = &solr.PodOptions{Affinity: customAffinity}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	terms := statefulSet.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if assert.Len(t, terms, 2, "Wrong number of node selector terms") {
+		for i, zone := range []string{"a", "b"} {
+			expected := append([]corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{zone}}}, localRequirements...)
+			assert.Equal(t, expected, terms[i].MatchExpressions, "Wrong node selector term for local storage")
+		}
+	}
+	assert.Len(t, customAffinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions, 1, "The custom affinity of the spec should not be changed")
+
+	// Local PersistentVolumes need a StorageClass
+	instance.Spec.DataStorage.Persistent.StorageClassName = nil
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: record.NewFakeRecorder(10),
+		scheme:   scheme.Scheme,
+	}
+	_, err := r.Reconcile(expectedCloudRequest)
+	if assert.True(t, errors.IsBadRequest(err), "Local storage without a StorageClass should be rejected") {
+		assert.Contains(t, err.Error(), "storageClassName", "Wrong validation error")
+	}
+}
+
+func TestCloudPersistentDataStorage(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			DataStorage: &solr.SolrDataStorageOptions{
+				Persistent: &solr.SolrPersistentDataStorageOptions{},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	// The defaults give a ReadWriteOnce PVC of the default size
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		assert.NotEqual(t, util.SolrDataVolume, volume.Name, "No emptyDir data volume should be created for persistent storage")
+	}
+	if assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1, "Wrong number of volumeClaimTemplates") {
+		pvcSpec := statefulSet.Spec.VolumeClaimTemplates[0].Spec
+		assert.Equal(t, util.SolrDataVolume, statefulSet.Spec.VolumeClaimTemplates[0].Name, "Wrong name for the data volumeClaimTemplate")
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, pvcSpec.AccessModes, "Wrong default accessModes")
+		assert.Equal(t, resource.MustParse(solr.DefaultSolrStorage), pvcSpec.Resources.Requests[corev1.ResourceStorage], "Wrong default size")
+		assert.Nil(t, pvcSpec.StorageClassName, "The default StorageClass should be used")
+	}
+
+	storageClass := "fast-ssd"
+	size := resource.MustParse("100Gi")
+	instance.Spec.DataStorage.Persistent = &solr.SolrPersistentDataStorageOptions{
+		StorageClassName: &storageClass,
+		Size:             &size,
+		AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+	}
+	instance.WithDefaults("")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	if assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1, "Wrong number of volumeClaimTemplates") {
+		pvcSpec := statefulSet.Spec.VolumeClaimTemplates[0].Spec
+		assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, pvcSpec.AccessModes, "Wrong accessModes")
+		assert.Equal(t, size, pvcSpec.Resources.Requests[corev1.ResourceStorage], "Wrong size")
+		assert.Equal(t, &storageClass, pvcSpec.StorageClassName, "Wrong StorageClass")
+	}
+	assert.Equal(t, corev1.VolumeMount{Name: util.SolrDataVolume, MountPath: "/var/solr/data"}, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts[0], "The data PVC is not mounted")
+
+	// Labels, annotations and a selector for pre-provisioned volumes
+	instance.Spec.DataStorage.Persistent.Labels = map[string]string{"backup-policy": "daily"}
+	instance.Spec.DataStorage.Persistent.Annotations = map[string]string{"storage.example.com/team": "search"}
+	instance.Spec.DataStorage.Persistent.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "solr"}}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	if assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1, "Wrong number of volumeClaimTemplates") {
+		pvc := statefulSet.Spec.VolumeClaimTemplates[0]
+		assert.Equal(t, map[string]string{"backup-policy": "daily"}, pvc.Labels, "Wrong labels for the data PVCs")
+		assert.Equal(t, map[string]string{"storage.example.com/team": "search"}, pvc.Annotations, "Wrong annotations for the data PVCs")
+		assert.Equal(t, instance.Spec.DataStorage.Persistent.Selector, pvc.Spec.Selector, "Wrong selector for the data PVCs")
+	}
+
+	// The dataPvcSpec takes precedence, but both cannot be provided
+	instance.Spec.DataPvcSpec = &corev1.PersistentVolumeClaimSpec{}
+	instance.WithDefaults("")
+	assert.Equal(t, instance.Spec.DataPvcSpec, instance.DataPvcSpec(), "The dataPvcSpec should be used")
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: record.NewFakeRecorder(10),
+		scheme:   scheme.Scheme,
+	}
+	_, err := r.Reconcile(expectedCloudRequest)
+	if assert.True(t, errors.IsBadRequest(err), "Providing both data storage options should be rejected") {
+		assert.Contains(t, err.Error(), "dataStorage.persistent", "Wrong validation error")
+	}
+}
+
+func TestCloudHostAliases(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					HostAliases: []corev1.HostAlias{
+						{IP: "10.0.0.3", Hostnames: []string{"zk-3.onprem", "zk-1.onprem"}},
+						{IP: "10.0.0.2", Hostnames: []string{"zk-2.onprem"}},
+						{IP: "10.0.0.99", Hostnames: []string{"foo-clo-solrcloud-0.ing.base.domain"}},
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	hostNameIPs := map[string]string{
+		"foo-clo-solrcloud-1.ing.base.domain": "10.1.0.1",
+		"foo-clo-solrcloud-0.ing.base.domain": "10.1.0.0",
+	}
+
+	expectedHostAliases := []corev1.HostAlias{
+		{IP: "10.1.0.0", Hostnames: []string{"foo-clo-solrcloud-0.ing.base.domain"}},
+		{IP: "10.1.0.1", Hostnames: []string{"foo-clo-solrcloud-1.ing.base.domain"}},
+		{IP: "10.0.0.3", Hostnames: []string{"zk-1.onprem"}},
+		{IP: "10.0.0.2", Hostnames: []string{"zk-2.onprem"}},
+		{IP: "10.0.0.3", Hostnames: []string{"zk-3.onprem"}},
+	}
+	statefulSet := util.GenerateStatefulSet(instance, status, hostNameIPs, "")
+	assert.Equal(t, expectedHostAliases, statefulSet.Spec.Template.Spec.HostAliases, "Host aliases were not merged correctly")
+
+	// Repeated generations must produce the same output, so that no update is required
+	for i := 0; i < 10; i++ {
+		foundStatefulSet := statefulSet.DeepCopy()
+		assert.False(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, hostNameIPs, ""), foundStatefulSet), "Host aliases should be generated deterministically")
+	}
+
+	// Without operator generated aliases, only the user provided aliases are used
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, 4, len(statefulSet.Spec.Template.Spec.HostAliases), "Wrong number of host aliases")
+	assert.Equal(t, corev1.HostAlias{IP: "10.0.0.99", Hostnames: []string{"foo-clo-solrcloud-0.ing.base.domain"}}, statefulSet.Spec.Template.Spec.HostAliases[0], "User provided host alias not used")
+}
+
+func TestCloudHostAliasesCollideWithNodeServices(t *testing.T) {
+	replicas := int32(2)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-alias", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			Replicas: &replicas,
+			ZookeeperRef: &solr.ZookeeperRef{
+				ConnectionInfo: &solr.ZookeeperConnectionInfo{InternalConnectionString: "host:2181", ChRoot: "/"},
+			},
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:             solr.Ingress,
+					UseExternalAddress: true,
+					DomainName:         testDomain,
+				},
+			},
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					HostAliases: []corev1.HostAlias{
+						{IP: "10.0.0.99", Hostnames: []string{"zk-1.onprem"}},
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	nodeHost := instance.AdvertisedNodeHost("foo-alias-solrcloud-0")
+	instance.Spec.CustomSolrKubeOptions.PodOptions.HostAliases[0].Hostnames = append(instance.Spec.CustomSolrKubeOptions.PodOptions.HostAliases[0].Hostnames, nodeHost)
+
+	// The node services already have their cluster IPs, which are used for the external addresses of the nodes
+	objects := []runtime.Object{instance}
+	for i, nodeName := range instance.GetAllSolrNodeNames() {
+		service := util.GenerateNodeService(instance, nodeName)
+		service.Spec.ClusterIP = fmt.Sprintf("10.1.0.%d", i)
+		assert.NoError(t, ctrl.SetControllerReference(instance, service, scheme.Scheme))
+		objects = append(objects, service)
+	}
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, objects...),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}})
+	assert.NoError(t, err)
+
+	// The operator's IP wins over the user provided alias of the same hostname
+	statefulSet := &appsv1.StatefulSet{}
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: instance.StatefulSetName(), Namespace: instance.Namespace}, statefulSet))
+	assert.Equal(t, []corev1.HostAlias{
+		{IP: "10.1.0.0", Hostnames: []string{nodeHost}},
+		{IP: "10.1.0.1", Hostnames: []string{instance.AdvertisedNodeHost("foo-alias-solrcloud-1")}},
+		{IP: "10.0.0.99", Hostnames: []string{"zk-1.onprem"}},
+	}, statefulSet.Spec.Template.Spec.HostAliases, "The generated host aliases should take precedence over the user provided ones")
+}
+
+func TestCloudServiceLabelsAndAnnotations(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				CommonServiceOptions: &solr.ServiceOptions{
+					Annotations: map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+					Labels:      map[string]string{"exposure": "internal", "service-type": "other"},
+				},
+				HeadlessServiceOptions: &solr.ServiceOptions{
+					Annotations: map[string]string{"service.kubernetes.io/topology-aware-hints": "auto"},
+				},
+				NodeServiceOptions: &solr.ServiceOptions{
+					Labels: map[string]string{"exposure": "node"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// Each type of Service only gets its own options, and the labels of the operator cannot be overridden
+	commonService := util.GenerateCommonService(instance)
+	assert.Equal(t, "true", commonService.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"], "The annotation was not added to the common service")
+	assert.Equal(t, "internal", commonService.Labels["exposure"], "The label was not added to the common service")
+	assert.Equal(t, "common", commonService.Labels["service-type"], "The service-type label of the operator should not be overridden")
+
+	headlessService := util.GenerateHeadlessService(instance)
+	assert.Equal(t, map[string]string{"service.kubernetes.io/topology-aware-hints": "auto"}, headlessService.Annotations, "Wrong annotations for the headless service")
+	assert.Empty(t, headlessService.Labels["exposure"], "The labels of the common service should not be added to the headless service")
+
+	nodeService := util.GenerateNodeService(instance, "foo-clo-solrcloud-0")
+	assert.Equal(t, "node", nodeService.Labels["exposure"], "The label was not added to the node service")
+	assert.Empty(t, nodeService.Annotations, "The annotations of other services should not be added to the node service")
+
+	// Custom annotations are kept next to the ones generated by the operator
+	instance.Spec.SolrAddressability.External = &solr.ExternalAddressability{Method: solr.ExternalDNS, DomainName: "test.domain.com"}
+	commonService = util.GenerateCommonService(instance)
+	assert.Equal(t, "true", commonService.Annotations["service.beta.kubernetes.io/aws-load-balancer-internal"], "The custom annotation should be kept")
+	assert.NotEmpty(t, commonService.Annotations["external-dns.alpha.kubernetes.io/hostname"], "The external DNS annotation should be added")
+}
+
+func TestCloudServiceTrafficOptions(t *testing.T) {
+	timeout := int32(600)
+	affinityConfig := &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &timeout}}
+	serviceOptions := &solr.ServiceOptions{
+		ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+		SessionAffinity:       corev1.ServiceAffinityClientIP,
+		SessionAffinityConfig: affinityConfig,
+	}
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				CommonServiceOptions:   serviceOptions,
+				HeadlessServiceOptions: serviceOptions,
+				NodeServiceOptions:     serviceOptions,
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// Common Service
+	commonService := util.GenerateCommonService(instance)
+	assert.Equal(t, corev1.ServiceAffinityClientIP, commonService.Spec.SessionAffinity, "Wrong sessionAffinity for the common service")
+	assert.Equal(t, affinityConfig, commonService.Spec.SessionAffinityConfig, "Wrong sessionAffinityConfig for the common service")
+	assert.Empty(t, commonService.Spec.ExternalTrafficPolicy, "The externalTrafficPolicy cannot be used for ClusterIP services")
+
+	// Headless Service
+	headlessService := util.GenerateHeadlessService(instance)
+	assert.Empty(t, headlessService.Spec.SessionAffinity, "The sessionAffinity cannot be used for headless services")
+	assert.Nil(t, headlessService.Spec.SessionAffinityConfig, "The sessionAffinityConfig cannot be used for headless services")
+	assert.Empty(t, headlessService.Spec.ExternalTrafficPolicy, "The externalTrafficPolicy cannot be used for headless services")
+
+	// Node Service
+	nodeService := util.GenerateNodeService(instance, "foo-clo-solrcloud-0")
+	assert.Equal(t, corev1.ServiceAffinityClientIP, nodeService.Spec.SessionAffinity, "Wrong sessionAffinity for the node service")
+	assert.Equal(t, affinityConfig, nodeService.Spec.SessionAffinityConfig, "Wrong sessionAffinityConfig for the node service")
+
+	// Default options are left to Kubernetes
+	instance.Spec.CustomSolrKubeOptions.CommonServiceOptions = nil
+	defaultService := util.GenerateCommonService(instance)
+	assert.Empty(t, defaultService.Spec.SessionAffinity, "No sessionAffinity should be set by default")
+	assert.Empty(t, defaultService.Spec.ExternalTrafficPolicy, "No externalTrafficPolicy should be set by default")
+}
+
+func TestCloudCommonServiceType(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				CommonServiceOptions: &solr.ServiceOptions{
+					Type:                     corev1.ServiceTypeLoadBalancer,
+					NodePort:                 30080,
+					LoadBalancerIP:           "10.1.2.3",
+					LoadBalancerSourceRanges: []string{"10.0.0.0/8"},
+					Annotations:              map[string]string{"service.beta.kubernetes.io/aws-load-balancer-internal": "true"},
+				},
+				HeadlessServiceOptions: &solr.ServiceOptions{Type: corev1.ServiceTypeLoadBalancer},
+				NodeServiceOptions:     &solr.ServiceOptions{Type: corev1.ServiceTypeLoadBalancer, NodePort: 30081},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// The common service is exposed through an internal load balancer
+	commonService := util.GenerateCommonService(instance)
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, commonService.Spec.Type, "Wrong type for the common service")
+	assert.Equal(t, int32(30080), commonService.Spec.Ports[0].NodePort, "Wrong nodePort for the common service")
+	assert.Equal(t, "10.1.2.3", commonService.Spec.LoadBalancerIP, "Wrong loadBalancerIP for the common service")
+	assert.Equal(t, []string{"10.0.0.0/8"}, commonService.Spec.LoadBalancerSourceRanges, "Wrong loadBalancerSourceRanges for the common service")
+
+	// The type of the headless service cannot be changed, and the node services cannot share a nodePort
+	assert.Empty(t, util.GenerateHeadlessService(instance).Spec.Type, "The type of the headless service should not be changed")
+	instance.Spec.SolrAddressability.External = &solr.ExternalAddressability{Method: solr.Ingress, DomainName: testDomain}
+	instance.WithDefaults("")
+	nodeService := util.GenerateNodeService(instance, "foo-clo-solrcloud-0")
+	assert.Equal(t, corev1.ServiceTypeLoadBalancer, nodeService.Spec.Type, "Wrong type for the node service")
+	assert.Equal(t, int32(0), nodeService.Spec.Ports[0].NodePort, "The nodePort option should not be used for the node services")
+
+	// The load balancer options are only used for LoadBalancer services
+	instance.Spec.CustomSolrKubeOptions.CommonServiceOptions.Type = corev1.ServiceTypeNodePort
+	commonService = util.GenerateCommonService(instance)
+	assert.Equal(t, int32(30080), commonService.Spec.Ports[0].NodePort, "Wrong nodePort for the common service")
+	assert.Empty(t, commonService.Spec.LoadBalancerIP, "The loadBalancerIP should only be set for LoadBalancer services")
+
+	// Changing the common service back to ClusterIP removes the fields of the exposed service
+	existing := util.GenerateCommonService(instance)
+	existing.Spec.Type = corev1.ServiceTypeLoadBalancer
+	existing.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
+	existing.Spec.LoadBalancerIP = "10.1.2.3"
+	instance.Spec.CustomSolrKubeOptions.CommonServiceOptions.Type = corev1.ServiceTypeClusterIP
+	assert.True(t, util.CopyServiceFields(util.GenerateCommonService(instance), existing), "The changed type should require an update")
+	assert.Equal(t, corev1.ServiceTypeClusterIP, existing.Spec.Type, "The type was not copied")
+	assert.Equal(t, int32(0), existing.Spec.Ports[0].NodePort, "The nodePort should be removed from the ClusterIP service")
+	assert.Empty(t, existing.Spec.ExternalTrafficPolicy, "The externalTrafficPolicy should be removed from the ClusterIP service")
+	assert.Empty(t, existing.Spec.LoadBalancerIP, "The loadBalancerIP should be removed from the ClusterIP service")
+	assert.False(t, util.CopyServiceFields(util.GenerateCommonService(instance), existing), "An unchanged service should not require an update")
+}
+
+func TestCloudIPv6Services(t *testing.T) {
+	ipv6 := corev1.IPv6Protocol
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				CommonServiceOptions:   &solr.ServiceOptions{IPFamily: &ipv6},
+				HeadlessServiceOptions: &solr.ServiceOptions{IPFamily: &ipv6},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// The IP family is set on the created services, including the headless service
+	commonService := util.GenerateCommonService(instance)
+	assert.Equal(t, &ipv6, commonService.Spec.IPFamily, "Wrong IP family for the common service")
+	assert.Equal(t, &ipv6, util.GenerateHeadlessService(instance).Spec.IPFamily, "Wrong IP family for the headless service")
+
+	// The IP family cannot be changed, so it is never copied to an existing service
+	existing := commonService.DeepCopy()
+	existing.Spec.IPFamily = nil
+	assert.False(t, util.CopyServiceFields(commonService, existing), "The IP family should not require an update")
+	assert.Nil(t, existing.Spec.IPFamily, "The IP family should not be copied")
+
+	// IPv6 addresses of the node services can be used in the hostAliases
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, map[string]string{"foo-solrcloud-0.test.domain.com": "fd00::10"}, "")
+	assert.Equal(t, []corev1.HostAlias{{IP: "fd00::10", Hostnames: []string{"foo-solrcloud-0.test.domain.com"}}}, statefulSet.Spec.Template.Spec.HostAliases, "Wrong hostAliases")
+
+	// An IPv6 address used as the domain of the NodePort method is advertised in brackets
+	instance.Spec.SolrAddressability.External = &solr.ExternalAddressability{
+		Method:             solr.NodePort,
+		DomainName:         "fd00::1",
+		UseExternalAddress: true,
+		NodePortBase:       30100,
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, "[fd00::1]:30101", instance.ExternalNodeUrl("foo-solrcloud-0", "fd00::1", true), "Wrong external address for the node")
+	assert.Equal(t, "[fd00::1]:30100", instance.ExternalCommonUrl("fd00::1", true), "Wrong external address for the common service")
+	assert.Equal(t, "[fd00::1]:30101_solr", instance.LiveNodeName("foo-solrcloud-0"), "Wrong live node name")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	testPodEnvVariables(t, map[string]string{"SOLR_HOST": "[fd00::1]"}, statefulSet.Spec.Template.Spec.Containers[0].Env)
+	assert.Equal(t, "10.0.0.1:30100", instance.ExternalCommonUrl("10.0.0.1", true), "IPv4 addresses should not be enclosed in brackets")
+}
+
+func TestCopyServiceTrafficOptions(t *testing.T) {
+	timeout := int32(600)
+	defaultTimeout := int32(10800)
+	desired := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-clo-solrcloud-common", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:                  corev1.ServiceTypeLoadBalancer,
+			ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+		},
+	}
+
+	// The fields defaulted by Kubernetes, including the assigned healthCheckNodePort, must be preserved
+	existing := desired.DeepCopy()
+	existing.Spec.HealthCheckNodePort = 32000
+	existing.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	assert.False(t, util.CopyServiceFields(desired, existing), "No update should be required for fields defaulted by Kubernetes")
+	assert.Equal(t, int32(32000), existing.Spec.HealthCheckNodePort, "The healthCheckNodePort should not be removed")
+	assert.Equal(t, corev1.ServiceAffinityNone, existing.Spec.SessionAffinity, "The defaulted sessionAffinity should not be removed")
+
+	// Adding session affinity must not clobber the healthCheckNodePort
+	desired.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+	desired.Spec.SessionAffinityConfig = &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &timeout}}
+	assert.True(t, util.CopyServiceFields(desired, existing), "Changed sessionAffinity should require an update")
+	assert.Equal(t, corev1.ServiceAffinityClientIP, existing.Spec.SessionAffinity, "SessionAffinity not updated")
+	assert.Equal(t, desired.Spec.SessionAffinityConfig, existing.Spec.SessionAffinityConfig, "SessionAffinityConfig not updated")
+	assert.Equal(t, int32(32000), existing.Spec.HealthCheckNodePort, "The healthCheckNodePort should not be removed")
+
+	// A defaulted sessionAffinityConfig should not require an update
+	desired.Spec.SessionAffinityConfig = nil
+	existing.Spec.SessionAffinityConfig = &corev1.SessionAffinityConfig{ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &defaultTimeout}}
+	assert.False(t, util.CopyServiceFields(desired, existing), "A defaulted sessionAffinityConfig should not require an update")
+
+	// Removing session affinity removes the config as well
+	desired.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	assert.True(t, util.CopyServiceFields(desired, existing), "Changed sessionAffinity should require an update")
+	assert.Nil(t, existing.Spec.SessionAffinityConfig, "SessionAffinityConfig should be removed")
+
+	// The healthCheckNodePort can only be kept for the Local externalTrafficPolicy
+	desired.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
+	assert.True(t, util.CopyServiceFields(desired, existing), "Changed externalTrafficPolicy should require an update")
+	assert.Equal(t, corev1.ServiceExternalTrafficPolicyTypeCluster, existing.Spec.ExternalTrafficPolicy, "ExternalTrafficPolicy not updated")
+	assert.Equal(t, int32(0), existing.Spec.HealthCheckNodePort, "The healthCheckNodePort must be removed for the Cluster externalTrafficPolicy")
+}
+
+func TestCopyServiceFieldsExternallyMutated(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				CommonServiceOptions: &solr.ServiceOptions{
+					Annotations: map[string]string{"operator": "owned"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// Simulate a Service that Kubernetes, a cloud provider and metallb have modified
+	existing := util.GenerateCommonService(instance)
+	existing.Finalizers = []string{"service.kubernetes.io/load-balancer-cleanup"}
+	existing.Annotations["metallb.universe.tf/ip-allocated-from-pool"] = "default"
+	existing.Labels["cloud-provider"] = "label"
+	existing.Spec.ClusterIP = "10.0.0.10"
+	existing.Spec.HealthCheckNodePort = 32001
+	existing.Spec.LoadBalancerIP = "192.168.1.10"
+	existing.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	existing.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeCluster
+	for i := range existing.Spec.Ports {
+		existing.Spec.Ports[i].NodePort = int32(31000 + i)
+	}
+	mutated := existing.DeepCopy()
+
+	// A steady state must not produce any updates
+	for i := 0; i < 3; i++ {
+		assert.False(t, util.CopyServiceFields(util.GenerateCommonService(instance), existing), "Externally assigned fields should not require an update")
+	}
+	assert.Equal(t, mutated, existing, "Externally assigned fields should not be modified")
+
+	// Changing an operator owned field only updates that field
+	instance.Spec.CustomSolrKubeOptions.CommonServiceOptions.Annotations["operator"] = "changed"
+	assert.True(t, util.CopyServiceFields(util.GenerateCommonService(instance), existing), "Changed operator annotation should require an update")
+	assert.Equal(t, "changed", existing.Annotations["operator"], "Operator annotation not updated")
+	assert.Equal(t, "default", existing.Annotations["metallb.universe.tf/ip-allocated-from-pool"], "External annotation should be kept")
+	assert.Equal(t, "label", existing.Labels["cloud-provider"], "External label should be kept")
+	assert.Equal(t, mutated.Finalizers, existing.Finalizers, "External finalizers should be kept")
+	assert.Equal(t, mutated.Spec.Ports, existing.Spec.Ports, "Assigned nodePorts should be kept")
+	assert.Equal(t, "192.168.1.10", existing.Spec.LoadBalancerIP, "Assigned loadBalancerIP should be kept")
+
+	// A specific nodePort is applied when requested
+	desired := util.GenerateCommonService(instance)
+	desired.Spec.Ports[0].NodePort = 30080
+	assert.True(t, util.CopyServiceFields(desired, existing), "Requested nodePort should require an update")
+	assert.Equal(t, int32(30080), existing.Spec.Ports[0].NodePort, "Requested nodePort not applied")
+}
+
+func TestCloudLiveNodeStates(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			LiveNodesCheck: &solr.LiveNodesCheckOptions{},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, solr.DefaultLiveNodesCheckIntervalSeconds, instance.Spec.LiveNodesCheck.IntervalSeconds, "Wrong default live nodes check interval")
+	assert.Equal(t, "foo-clo-solrcloud-1.foo-clo-solrcloud-headless.default:8983_solr", instance.LiveNodeName("foo-clo-solrcloud-1"), "Wrong live node name")
+
+	newStatus := func() *solr.SolrCloudStatus {
+		return &solr.SolrCloudStatus{
+			SolrNodes: []solr.SolrNodeStatus{
+				{Name: "foo-clo-solrcloud-0"},
+				{Name: "foo-clo-solrcloud-1"},
+				{Name: "foo-clo-solrcloud-2"},
+			},
+		}
+	}
+
+	status := newStatus()
+	setLiveNodeStates(instance, status, []string{instance.LiveNodeName("foo-clo-solrcloud-0"), instance.LiveNodeName("foo-clo-solrcloud-2"), "other-host:8983_solr"})
+	assert.Equal(t, int32(2), *status.LiveNodes, "Wrong number of live nodes")
+	assert.True(t, *status.SolrNodes[0].Live, "Node 0 should be live")
+	assert.False(t, *status.SolrNodes[1].Live, "Node 1 should not be live")
+	assert.True(t, *status.SolrNodes[2].Live, "Node 2 should be live")
+
+	// A failed check keeps the previously reported live states
+	instance.Status = *status
+	status = newStatus()
+	status.SolrNodes = append(status.SolrNodes, solr.SolrNodeStatus{Name: "foo-clo-solrcloud-3"})
+	keepLiveNodeStates(&instance.Status, status)
+	assert.Equal(t, int32(2), *status.LiveNodes, "Previous number of live nodes not kept")
+	assert.True(t, *status.SolrNodes[0].Live, "Previous live state of node 0 not kept")
+	assert.False(t, *status.SolrNodes[1].Live, "Previous live state of node 1 not kept")
+	assert.Nil(t, status.SolrNodes[3].Live, "New nodes should have no live state until they are checked")
+
+	// Checks are only reused within the check interval
+	cache := &liveNodesCache{checks: map[types.NamespacedName]liveNodesCheck{}}
+	now := time.Now()
+	_, found := cache.get(expectedCloudRequest.NamespacedName, 30*time.Second, now)
+	assert.False(t, found, "No check should be cached yet")
+	cache.set(expectedCloudRequest.NamespacedName, liveNodesCheck{checkedAt: now, liveNodes: []string{"a"}})
+	check, found := cache.get(expectedCloudRequest.NamespacedName, 30*time.Second, now.Add(10*time.Second))
+	assert.True(t, found, "Check should be reused within the interval")
+	assert.Equal(t, []string{"a"}, check.liveNodes, "Wrong cached live nodes")
+	_, found = cache.get(expectedCloudRequest.NamespacedName, 30*time.Second, now.Add(30*time.Second))
+	assert.False(t, found, "Check should expire after the interval")
+}
+
+func TestCloudClusterProperties(t *testing.T) {
+	var setProperties []string
+	rejectSet := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params := req.URL.Query()
+		switch params.Get("action") {
+		case "CLUSTERSTATUS":
+			fmt.Fprint(w, `{"responseHeader":{"status":0},"cluster":{"live_nodes":[],"properties":{"urlScheme":"http","defaults":{"collection":{"numShards":2}}}}}`)
+		case "CLUSTERPROP":
+			if rejectSet {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":{"msg":"Not a known cluster property"}}`)
+				return
+			}
+			setProperties = append(setProperties, params.Get("name")+"="+params.Get("val"))
+			fmt.Fprint(w, `{"responseHeader":{"status":0}}`)
+		}
+	}))
+	defer server.Close()
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			ClusterProperties: map[string]string{
+				"urlScheme":                     "https",
+				"defaults.collection.numShards": "2",
+				"maxCoresPerNode":               "10",
+			},
+		},
+		Status: solr.SolrCloudStatus{InternalCommonAddress: server.URL},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{Log: ctrl.Log.WithName("test"), recorder: recorder}
+
+	// Only the properties that differ from the cluster state are set
+	status := &solr.SolrCloudStatus{}
+	_, applied := reconcileClusterProperties(r, instance, status)
+	assert.True(t, applied, "The cluster properties should be applied")
+	assert.Equal(t, []string{"maxCoresPerNode=10", "urlScheme=https"}, setProperties, "Wrong cluster properties set")
+	assert.Nil(t, status.GetCondition(solr.ClusterPropertiesNotApplied), "No condition should be set while the properties can be applied")
+
+	// Rejected properties are reported in the status, and retried later
+	rejectSet = true
+	retryAfter, applied := reconcileClusterProperties(r, instance, status)
+	assert.False(t, applied, "The cluster properties should not be applied")
+	assert.Equal(t, util.DefaultSolrApiRetryAfter, retryAfter, "Wrong retry delay")
+	if condition := status.GetCondition(solr.ClusterPropertiesNotApplied); assert.NotNil(t, condition, "The condition should be set") {
+		assert.Equal(t, corev1.ConditionTrue, condition.Status, "Wrong condition status")
+		assert.Contains(t, condition.Message, "Not a known cluster property", "The condition should contain the response of Solr")
+	}
+	assert.Len(t, recorder.Events, 1, "An event should be recorded")
+
+	rejectSet = false
+	_, applied = reconcileClusterProperties(r, instance, status)
+	assert.True(t, applied, "The cluster properties should be applied")
+	assert.Equal(t, corev1.ConditionFalse, status.GetCondition(solr.ClusterPropertiesNotApplied).Status, "The condition should be resolved")
+}
+
+func TestCloudAutoscaling(t *testing.T) {
+	var postedCommands []map[string]interface{}
+	rejectPost := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, util.SolrAutoscalingApiPath, req.URL.Path, "Wrong Solr API path")
+		if req.Method == http.MethodPost && rejectPost {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"msg":"Error in preference"}}`)
+			return
+		} else if req.Method == http.MethodPost {
+			commands := map[string]interface{}{}
+			assert.NoError(t, json.NewDecoder(req.Body).Decode(&commands), "The commands should be sent as JSON")
+			postedCommands = append(postedCommands, commands)
+			fmt.Fprint(w, `{"responseHeader":{"status":0},"result":"success"}`)
+			return
+		}
+		fmt.Fprint(w, `{"responseHeader":{"status":0},"cluster-preferences":[{"minimize":"cores","precision":1},{"maximize":"freedisk"}],"cluster-policy":[]}`)
+	}))
+	defer server.Close()
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			Autoscaling: &solr.SolrAutoscalingOptions{
+				ClusterPolicy: []runtime.RawExtension{
+					{Raw: []byte(`{"replica": "<2", "shard": "#EACH", "node": "#ANY"}`)},
+				},
+				ClusterPreferences: []runtime.RawExtension{
+					{Raw: []byte(`{"minimize": "cores", "precision": 1}`)},
+					{Raw: []byte(`{"maximize": "freedisk"}`)},
+				},
+			},
+		},
+		Status: solr.SolrCloudStatus{InternalCommonAddress: server.URL},
+	}
+	r := &SolrCloudReconciler{Log: ctrl.Log.WithName("test"), recorder: record.NewFakeRecorder(10)}
+
+	// Only the lists that differ from the configuration in Solr are set
+	status := &solr.SolrCloudStatus{}
+	_, applied := reconcileAutoscaling(r, instance, status)
+	assert.True(t, applied, "The autoscaling configuration should be applied")
+	if assert.Len(t, postedCommands, 1, "The autoscaling configuration should be set once") {
+		assert.Equal(t, map[string]interface{}{
+			"set-cluster-policy": []interface{}{map[string]interface{}{"replica": "<2", "shard": "#EACH", "node": "#ANY"}},
+		}, postedCommands[0], "Wrong autoscaling commands")
+	}
+
+	// Nothing is set when Solr already has the configuration of the spec
+	postedCommands = nil
+	instance.Spec.Autoscaling.ClusterPolicy = nil
+	_, applied = reconcileAutoscaling(r, instance, status)
+	assert.True(t, applied, "The autoscaling configuration should be applied")
+	assert.Empty(t, postedCommands, "No autoscaling commands should be sent")
+	assert.Nil(t, status.GetCondition(solr.AutoscalingNotApplied), "No condition should be set while the configuration can be applied")
+
+	// Rules rejected by Solr are reported in the status, and retried later
+	rejectPost = true
+	instance.Spec.Autoscaling.ClusterPreferences = []runtime.RawExtension{{Raw: []byte(`{"minimize": "unknown"}`)}}
+	retryAfter, applied := reconcileAutoscaling(r, instance, status)
+	assert.False(t, applied, "Rejected autoscaling rules should not be applied")
+	assert.Equal(t, util.DefaultSolrApiRetryAfter, retryAfter, "Wrong retry delay")
+	if condition := status.GetCondition(solr.AutoscalingNotApplied); assert.NotNil(t, condition, "The condition should be set") {
+		assert.Equal(t, "SolrApiError", condition.Reason, "Wrong condition reason")
+		assert.Contains(t, condition.Message, "Error in preference", "The condition should contain the response of Solr")
+	}
+}
+
+func TestCloudDataReclaimPolicy(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			DataStorage: &solr.SolrDataStorageOptions{
+				Persistent: &solr.SolrPersistentDataStorageOptions{},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, solr.DataReclaimRetain, instance.Spec.DataStorage.ReclaimPolicy, "Wrong default reclaim policy")
+
+	pvcLabels := instance.SharedLabelsWith(map[string]string{"technology": solr.SolrTechnologyLabel})
+	newPvc := func(name string, labels map[string]string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Namespace, Labels: labels}}
+	}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, instance,
+			newPvc("data-foo-clo-solrcloud-0", pvcLabels),
+			newPvc("data-foo-clo-solrcloud-1", pvcLabels),
+			newPvc("tlog-foo-clo-solrcloud-0", pvcLabels),
+			newPvc("solr-logs-foo-clo-solrcloud-0", pvcLabels),
+			newPvc("data-other-solrcloud-0", map[string]string{"technology": solr.SolrTechnologyLabel, "solr-cloud": "other"}),
+		),
+		Log: ctrl.Log.WithName("test"),
+	}
+
+	// Retained data needs no finalizer
+	deleted, err := reconcileDataStorageFinalizer(r, instance)
+	assert.NoError(t, err)
+	assert.False(t, deleted, "The cloud is not being deleted")
+	assert.NotContains(t, instance.Finalizers, DataStorageFinalizer, "No finalizer should be added when the data is retained")
+
+	instance.Spec.DataStorage.ReclaimPolicy = solr.DataReclaimDelete
+	_, err = reconcileDataStorageFinalizer(r, instance)
+	assert.NoError(t, err)
+	assert.Contains(t, instance.Finalizers, DataStorageFinalizer, "The finalizer should be added when the data is deleted with the cloud")
+
+	// Only the data and transaction log PVCs of the deleted cloud are deleted
+	now := metav1.Now()
+	instance.DeletionTimestamp = &now
+	deleted, err = reconcileDataStorageFinalizer(r, instance)
+	assert.NoError(t, err)
+	assert.True(t, deleted, "The cloud is being deleted")
+	assert.NotContains(t, instance.Finalizers, DataStorageFinalizer, "The finalizer should be removed once the PVCs are deleted")
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	assert.NoError(t, r.List(context.TODO(), pvcList))
+	var remaining []string
+	for _, pvc := range pvcList.Items {
+		remaining = append(remaining, pvc.Name)
+	}
+	assert.ElementsMatch(t, []string{"solr-logs-foo-clo-solrcloud-0", "data-other-solrcloud-0"}, remaining, "Wrong PVCs deleted")
+
+	// Without a data PVC there is nothing to delete
+	instance.DeletionTimestamp = nil
+	instance.Spec.DataStorage.Persistent = nil
+	assert.False(t, instance.DeletesDataOnDeletion(), "An emptyDir data volume cannot be deleted")
+}
+
+func TestCloudDataVolumeExpansion(t *testing.T) {
+	size := resource.MustParse("20Gi")
+	storageClass := "expandable"
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			DataStorage: &solr.SolrDataStorageOptions{
+				Persistent: &solr.SolrPersistentDataStorageOptions{Size: &size, StorageClassName: &storageClass},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	pvcLabels := instance.SharedLabelsWith(map[string]string{"technology": solr.SolrTechnologyLabel})
+	newPvc := func(name string, requested string, capacity string, storageClassName string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Namespace, Labels: pvcLabels},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClassName,
+				Resources:        corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(requested)}},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)}},
+		}
+	}
+	allowExpansion := true
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, instance,
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: storageClass}, AllowVolumeExpansion: &allowExpansion},
+			&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}},
+			newPvc("data-foo-clo-solrcloud-0", "10Gi", "10Gi", storageClass),
+			newPvc("data-foo-clo-solrcloud-1", "20Gi", "20Gi", storageClass),
+		),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+
+	// The smaller PVCs are expanded, and the progress is reported until their capacity has grown
+	status := &solr.SolrCloudStatus{}
+	expanding, err := reconcileDataVolumeExpansion(r, instance, status)
+	assert.NoError(t, err)
+	assert.True(t, expanding, "The data PVCs should be expanding")
+	pvc := &corev1.PersistentVolumeClaim{}
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: "data-foo-clo-solrcloud-0", Namespace: instance.Namespace}, pvc))
+	assert.Equal(t, size, pvc.Spec.Resources.Requests[corev1.ResourceStorage], "The data PVC was not expanded")
+	if condition := status.GetCondition(solr.DataVolumeExpansion); assert.NotNil(t, condition, "The expansion should be reported") {
+		assert.Equal(t, corev1.ConditionTrue, condition.Status, "Wrong condition status")
+		assert.Equal(t, "1 of 2 data PVCs have been expanded to 20Gi", condition.Message, "Wrong expansion progress")
+	}
+	assert.Len(t, recorder.Events, 1, "An event should be recorded when the expansion starts")
+
+	pvc.Status.Capacity[corev1.ResourceStorage] = size
+	assert.NoError(t, r.Update(context.TODO(), pvc))
+	expanding, err = reconcileDataVolumeExpansion(r, instance, status)
+	assert.NoError(t, err)
+	assert.False(t, expanding, "The expansion should be finished")
+	assert.Equal(t, corev1.ConditionFalse, status.GetCondition(solr.DataVolumeExpansion).Status, "The expansion should be reported as finished")
+
+	// PVCs of a StorageClass that does not allow expansion are left alone
+	assert.NoError(t, r.Create(context.TODO(), newPvc("data-foo-clo-solrcloud-2", "10Gi", "10Gi", "fixed")))
+	expanding, err = reconcileDataVolumeExpansion(r, instance, status)
+	assert.NoError(t, err)
+	assert.False(t, expanding, "The data PVCs cannot be expanded")
+	if condition := status.GetCondition(solr.DataVolumeExpansion); assert.NotNil(t, condition) {
+		assert.Equal(t, "ExpansionNotAllowed", condition.Reason, "The StorageClass should not allow expansion")
+	}
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: "data-foo-clo-solrcloud-2", Namespace: instance.Namespace}, pvc))
+	assert.Equal(t, resource.MustParse("10Gi"), pvc.Spec.Resources.Requests[corev1.ResourceStorage], "The data PVC should not be changed")
+}
+
+func TestCloudZkConnectionMigration(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+	}
+	instance.WithDefaults("")
+	oldStatus := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "old-zk:2181", ChRoot: "/"}}
+	newStatus := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "new-zk:2181", ChRoot: "/solr"}}
+
+	found := util.GenerateStatefulSet(instance, oldStatus, nil, "")
+	found.Status.UpdateRevision = "new-revision"
+	desired := util.GenerateStatefulSet(instance, newStatus, nil, "")
+	assert.Equal(t, "new-zk:2181/solr", util.StatefulSetZkConnectionString(desired), "Wrong ZK connection string found in the StatefulSet")
+	assert.True(t, util.IsZkConnectionChange(desired, found), "ZK connection change not detected")
+	assert.False(t, util.IsZkConnectionChange(desired, desired.DeepCopy()), "ZK connection change detected for the same connection string")
+
+	// The last pod is restarted, but not yet ready
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-clo-solrcloud-2",
+			Namespace: expectedCloudRequest.Namespace,
+			Labels:    map[string]string{"controller-revision-hash": "new-revision"},
+		},
+	}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, pod),
+		Log:    ctrl.Log.WithName("test"),
+	}
+
+	// Starting the migration does not allow any pods to be restarted
+	status := &solr.SolrCloudStatus{}
+	migrating, err := reconcileZkConnectionMigration(r, instance, desired, found, status)
+	assert.NoError(t, err)
+	assert.True(t, migrating, "The migration should have started")
+	assert.True(t, status.IsConditionTrue(solr.ZkConnectionMigration), "The migration condition should be set")
+	assert.Equal(t, int32(3), util.StatefulSetPartition(desired), "No pods should be restarted when the migration starts")
+	assert.True(t, util.CopyStatefulSetFields(desired, found), "The partition must be copied to the existing StatefulSet")
+	assert.Equal(t, int32(3), util.StatefulSetPartition(found), "The partition must be copied to the existing StatefulSet")
+
+	// The highest ordinal is restarted first
+	desired = util.GenerateStatefulSet(instance, newStatus, nil, "")
+	migrating, err = reconcileZkConnectionMigration(r, instance, desired, found, status)
+	assert.NoError(t, err)
+	assert.True(t, migrating, "The migration should be in progress")
+	assert.Equal(t, int32(2), util.StatefulSetPartition(desired), "The pod with the highest ordinal should be restarted first")
+	util.CopyStatefulSetFields(desired, found)
+
+	// The next pod is not restarted until the restarted pod is ready and live
+	desired = util.GenerateStatefulSet(instance, newStatus, nil, "")
+	migrating, err = reconcileZkConnectionMigration(r, instance, desired, found, status)
+	assert.NoError(t, err)
+	assert.True(t, migrating, "The migration should be in progress")
+	assert.Equal(t, int32(2), util.StatefulSetPartition(desired), "The next pod should not be restarted before the restarted pod is ready")
+	assert.False(t, util.CopyStatefulSetFields(desired, found), "No update is needed while waiting for the restarted pod")
+
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	assert.True(t, util.IsPodUpdatedAndReady(pod, found), "The pod should be updated and ready")
+	pod.Labels["controller-revision-hash"] = "old-revision"
+	assert.False(t, util.IsPodUpdatedAndReady(pod, found), "The pod should not be updated")
+
+	// Without a migration, the partition is reset
+	desired = util.GenerateStatefulSet(instance, newStatus, nil, "")
+	migrating, err = reconcileZkConnectionMigration(r, instance, desired, found, &solr.SolrCloudStatus{})
+	assert.NoError(t, err)
+	assert.False(t, migrating, "No migration should be in progress")
+	assert.True(t, util.CopyStatefulSetFields(desired, found), "The partition must be reset")
+	assert.Equal(t, int32(0), util.StatefulSetPartition(found), "The partition must be reset")
+}
+
+func TestCloudStandaloneMode(t *testing.T) {
+	replicas := int32(3)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrMode: solr.StandaloneMode,
+			Replicas: &replicas,
+		},
+	}
+	instance.WithDefaults("")
+	assert.True(t, instance.IsStandalone(), "The cloud should be standalone")
+	assert.Equal(t, int32(1), *instance.Spec.Replicas, "A standalone Solr must run a single node")
+	assert.Nil(t, instance.Spec.ZookeeperRef, "A standalone Solr should not default a Zookeeper")
+
+	statefulSet := util.GenerateStatefulSet(instance, &solr.SolrCloudStatus{}, nil, "")
+	assert.Equal(t, int32(1), *statefulSet.Spec.Replicas, "Wrong number of replicas for a standalone Solr")
+	for _, envVar := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+		assert.NotContains(t, []string{"ZK_HOST", "ZK_SERVER", "ZK_CHROOT"}, envVar.Name, "A standalone Solr must not be given a Zookeeper connection")
+	}
+	assert.Nil(t, statefulSet.Spec.Template.Spec.Containers[0].Lifecycle.PostStart, "A standalone Solr must not create a Zookeeper chroot")
+	assert.Equal(t, "", util.StatefulSetZkConnectionString(statefulSet), "A standalone Solr must not have a Zookeeper connection string")
+
+	// Switching from cloud to standalone mode is not a Zookeeper connection migration
+	cloud := instance.DeepCopy()
+	cloud.Spec.SolrMode = solr.CloudMode
+	cloud.WithDefaults("")
+	cloudStatefulSet := util.GenerateStatefulSet(cloud, &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}, nil, "")
+	assert.False(t, util.IsZkConnectionChange(statefulSet, cloudStatefulSet), "Switching to standalone mode should not migrate the Zookeeper connection")
+}
+
+func TestCloudManualUpdateStrategy(t *testing.T) {
+	replicas := int32(3)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			Replicas: &replicas,
+			UpdateStrategy: &solr.SolrUpdateStrategy{
+				Method: solr.ManualUpdate,
+				Manual: &solr.ManualUpdateOptions{Partition: 2},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, int32(2), util.StatefulSetPartition(statefulSet), "The StatefulSet partition should be set from the manual update options")
+
+	// Lowering the partition is applied to the existing StatefulSet
+	instance.Spec.UpdateStrategy.Manual.Partition = 1
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Lowering the partition should require an update")
+	assert.Equal(t, int32(1), util.StatefulSetPartition(statefulSet), "The lowered partition was not copied")
+
+	// Switching back to the Managed method clears the partition
+	instance.Spec.UpdateStrategy.Method = solr.ManagedUpdate
+	assert.True(t, instance.WithDefaults(""), "The manual options should be cleared")
+	assert.Nil(t, instance.Spec.UpdateStrategy.Manual, "The manual options should be cleared")
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Clearing the partition should require an update")
+	assert.Equal(t, int32(0), util.StatefulSetPartition(statefulSet), "The partition was not cleared")
+
+	// The status reports which nodes run the current spec
+	var pods []runtime.Object
+	for i, revision := range []string{"old-revision", "new-revision", "new-revision"} {
+		pods = append(pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("foo-clo-solrcloud-%d", i),
+				Namespace: expectedCloudRequest.Namespace,
+				Labels:    instance.SharedLabelsWith(map[string]string{"technology": solr.SolrTechnologyLabel, "controller-revision-hash": revision}),
+			},
+		})
+	}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, pods...),
+		Log:    ctrl.Log.WithName("test"),
+	}
+	newStatus := &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileCloudStatus(r, instance, newStatus, "new-revision"))
+	assert.Equal(t, int32(2), newStatus.UpToDateNodes, "Wrong number of up to date nodes")
+	assert.False(t, newStatus.SolrNodes[0].SpecUpToDate, "Node 0 should not be up to date")
+	assert.True(t, newStatus.SolrNodes[1].SpecUpToDate, "Node 1 should be up to date")
+	assert.True(t, newStatus.SolrNodes[2].SpecUpToDate, "Node 2 should be up to date")
+}
+
+func TestCloudStatusUrlScheme(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ClusterProperties: map[string]string{"urlScheme": "https"},
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:     solr.Ingress,
+					DomainName: testDomain,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-solrcloud-0",
+			Namespace: "default",
+			Labels:    instance.SharedLabelsWith(map[string]string{"technology": solr.SolrTechnologyLabel}),
+		},
+	}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, pod),
+		Log:    ctrl.Log.WithName("test"),
+	}
+
+	// The addresses use the urlScheme of the cloud
+	newStatus := &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileCloudStatus(r, instance, newStatus, ""))
+	assert.Equal(t, "https://foo-solrcloud-common.default", newStatus.InternalCommonAddress, "Wrong internal common address")
+	assert.Equal(t, "https://default-foo-solrcloud."+testDomain, *newStatus.ExternalCommonAddress, "Wrong external common address")
+	assert.Equal(t, "https://foo-solrcloud-0.default", newStatus.SolrNodes[0].InternalAddress, "Wrong internal node address")
+	assert.Equal(t, "https://default-foo-solrcloud-0."+testDomain, newStatus.SolrNodes[0].ExternalAddress, "Wrong external node address")
+
+	// The external addresses use https when the ingress terminates TLS, even if Solr is addressed over http
+	delete(instance.Spec.ClusterProperties, "urlScheme")
+	instance.Spec.SolrAddressability.External.IngressTLS = &solr.SolrIngressTLSOptions{SecretName: "foo-tls"}
+	newStatus = &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileCloudStatus(r, instance, newStatus, ""))
+	assert.Equal(t, "http://foo-solrcloud-common.default", newStatus.InternalCommonAddress, "Wrong internal common address")
+	assert.Equal(t, "https://default-foo-solrcloud."+testDomain, *newStatus.ExternalCommonAddress, "Wrong external common address")
+	assert.Equal(t, "http://foo-solrcloud-0.default", newStatus.SolrNodes[0].InternalAddress, "Wrong internal node address")
+	assert.Equal(t, "https://default-foo-solrcloud-0."+testDomain, newStatus.SolrNodes[0].ExternalAddress, "Wrong external node address")
+}
+
+func TestCloudOnDeleteUpdateStrategy(t *testing.T) {
+	replicas := int32(3)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			Replicas:       &replicas,
+			UpdateStrategy: &solr.SolrUpdateStrategy{Method: solr.OnDeleteUpdate},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, appsv1.OnDeleteStatefulSetStrategyType, statefulSet.Spec.UpdateStrategy.Type, "The StatefulSet should only update deleted pods")
+	assert.Nil(t, statefulSet.Spec.UpdateStrategy.RollingUpdate, "No rolling update options can be used with the OnDelete strategy")
+	assert.False(t, instance.IsManagedUpdate(), "The OnDelete method is not a managed update")
+
+	// Changing the pod template is not a managed update, so it does not wait for other cluster operations
+	updated := statefulSet.DeepCopy()
+	updated.Spec.Template.Annotations = map[string]string{util.SolrXmlMd5Annotation: "changed"}
+	assert.Nil(t, clusterOperationForUpdate(instance, statefulSet, updated), "Pods are only restarted by the user with the OnDelete method")
+
+	// Switching to the Managed method rolls the pods again
+	instance.Spec.UpdateStrategy.Method = solr.ManagedUpdate
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Changing the update method should require an update")
+	assert.Equal(t, appsv1.RollingUpdateStatefulSetStrategyType, statefulSet.Spec.UpdateStrategy.Type, "The StatefulSet should use a rolling update")
+}
+
+func TestCloudScaledToZero(t *testing.T) {
+	replicas := int32(0)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace, UID: "foo-clo-uid"},
+		Spec: solr.SolrCloudSpec{
+			Replicas: &replicas,
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:             solr.Ingress,
+					UseExternalAddress: true,
+					DomainName:         testDomain,
+					HideCommon:         true,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, int32(0), *instance.Spec.Replicas, "Zero replicas should not be defaulted")
+	assert.Empty(t, instance.GetAllSolrNodeNames(), "A cloud scaled to zero has no nodes")
+
+	ingress := util.GenerateIngress(instance, instance.GetAllSolrNodeNames(), "")
+	assert.Empty(t, ingress.Spec.Rules, "A cloud scaled to zero with a hidden common endpoint should have no ingress rules")
+
+	// The node services of nodes that no longer exist are removed
+	var services []runtime.Object
+	for i := 0; i < 2; i++ {
+		service := util.GenerateNodeService(instance, fmt.Sprintf("foo-clo-solrcloud-%d", i))
+		assert.NoError(t, ctrl.SetControllerReference(instance, service, scheme.Scheme))
+		services = append(services, service)
+	}
+	commonService := util.GenerateCommonService(instance)
+	assert.NoError(t, ctrl.SetControllerReference(instance, commonService, scheme.Scheme))
+	services = append(services, commonService)
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, services...),
+		Log:    ctrl.Log.WithName("test"),
+	}
+
+	assert.NoError(t, deleteUnusedNodeServices(r, instance, []string{"foo-clo-solrcloud-0"}))
+	foundServices := &corev1.ServiceList{}
+	assert.NoError(t, r.List(context.TODO(), foundServices))
+	var foundNames []string
+	for _, service := range foundServices.Items {
+		foundNames = append(foundNames, service.Name)
+	}
+	assert.ElementsMatch(t, []string{"foo-clo-solrcloud-0", commonService.Name}, foundNames, "Only the unused node services should be deleted")
+
+	assert.NoError(t, deleteUnusedNodeServices(r, instance, instance.GetAllSolrNodeNames()))
+	assert.NoError(t, r.List(context.TODO(), foundServices))
+	assert.Equal(t, 1, len(foundServices.Items), "All node services should be deleted when the cloud is scaled to zero")
+	assert.Equal(t, commonService.Name, foundServices.Items[0].Name, "The common service should not be deleted")
+}
+
+func TestCloudDataInitContainerOptions(t *testing.T) {
+	newInstance := func(dataStorage *solr.SolrDataStorageOptions) *solr.SolrCloud {
+		instance := &solr.SolrCloud{
+			ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+			Spec:       solr.SolrCloudSpec{DataStorage: dataStorage},
+		}
+		instance.WithDefaults("")
+		return instance
+	}
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	solrXmlMountPath := func(statefulSet *appsv1.StatefulSet) string {
+		for _, mount := range statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts {
+			if mount.Name == "solr-xml" {
+				return mount.MountPath
+			}
+		}
+		return ""
+	}
+
+	// By default the solr.xml is copied by a busybox init container
+	statefulSet := util.GenerateStatefulSet(newInstance(nil), status, nil, "")
+	assert.Equal(t, 1, len(statefulSet.Spec.Template.Spec.InitContainers), "Wrong number of init containers")
+	assert.Equal(t, solr.DefaultBusyBoxImageRepo+":"+solr.DefaultBusyBoxImageVersion, statefulSet.Spec.Template.Spec.InitContainers[0].Image, "The default init container should use the busybox image")
+	assert.Equal(t, "", solrXmlMountPath(statefulSet), "The solr.xml should not be mounted into the Solr container when it is copied")
+
+	// The image, resources and security context of the init container can be customized
+	runAsNonRoot := true
+	resources := corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}}
+	statefulSet = util.GenerateStatefulSet(newInstance(&solr.SolrDataStorageOptions{
+		InitContainer: &solr.SolrDataInitContainerOptions{
+			Image:           &solr.ContainerImage{Repository: "my-repo/busybox"},
+			Resources:       resources,
+			SecurityContext: &corev1.SecurityContext{RunAsNonRoot: &runAsNonRoot},
+		},
+	}), status, nil, "")
+	assert.Equal(t, 1, len(statefulSet.Spec.Template.Spec.InitContainers), "Wrong number of init containers")
+	initContainer := statefulSet.Spec.Template.Spec.InitContainers[0]
+	assert.Equal(t, "my-repo/busybox:"+solr.DefaultBusyBoxImageVersion, initContainer.Image, "The init container image was not customized")
+	assert.Equal(t, resources, initContainer.Resources, "The init container resources were not set")
+	assert.Equal(t, &runAsNonRoot, initContainer.SecurityContext.RunAsNonRoot, "The init container security context was not set")
+
+	// Without the init container, the solr.xml is mounted into the SOLR_HOME
+	statefulSet = util.GenerateStatefulSet(newInstance(&solr.SolrDataStorageOptions{
+		SolrHome:      "/var/solr/data/home",
+		InitContainer: &solr.SolrDataInitContainerOptions{Disabled: true},
+	}), status, nil, "")
+	assert.Empty(t, statefulSet.Spec.Template.Spec.InitContainers, "The init container should be disabled")
+	assert.Equal(t, "/var/solr/data/home/solr.xml", solrXmlMountPath(statefulSet), "The solr.xml should be mounted into the SOLR_HOME")
+
+	// Custom init containers are still used when the operator's init container is disabled
+	instance := newInstance(&solr.SolrDataStorageOptions{InitContainer: &solr.SolrDataInitContainerOptions{Disabled: true}})
+	instance.Spec.CustomSolrKubeOptions.PodOptions = &solr.PodOptions{
+		InitContainers: []corev1.Container{{Name: "custom-init", Image: "custom:1.0"}},
+	}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, 1, len(statefulSet.Spec.Template.Spec.InitContainers), "Wrong number of init containers")
+	assert.Equal(t, "custom-init", statefulSet.Spec.Template.Spec.InitContainers[0].Name, "The custom init container should be used")
+	assert.Equal(t, "/var/solr/data/solr.xml", solrXmlMountPath(statefulSet), "The solr.xml should be mounted into the SOLR_HOME")
+
+	// Custom init containers run after the operator's init container
+	instance = newInstance(nil)
+	instance.Spec.CustomSolrKubeOptions.PodOptions = &solr.PodOptions{
+		InitContainers: []corev1.Container{{Name: "install-plugins", Image: "plugins:1.0"}, {Name: "warm-cache", Image: "warmer:1.0"}},
+	}
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, 3, len(statefulSet.Spec.Template.Spec.InitContainers), "Wrong number of init containers")
+	assert.Equal(t, "cp-solr-xml", statefulSet.Spec.Template.Spec.InitContainers[0].Name, "The operator's init container should run first")
+	assert.Equal(t, "install-plugins", statefulSet.Spec.Template.Spec.InitContainers[1].Name, "The custom init containers should keep their order")
+	assert.Equal(t, "warm-cache", statefulSet.Spec.Template.Spec.InitContainers[2].Name, "The custom init containers should keep their order")
+}
+
+func TestCloudZkOptions(t *testing.T) {
+	zkClientTimeout := int32(30000)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrOpts:        "-Dextra=opts",
+			ZkClientTimeout: &zkClientTimeout,
+			SolrZkOpts:      "-DdistribUpdateSoTimeout=120000",
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					EnvVariables: []corev1.EnvVar{{Name: "CUSTOM", Value: "$(SOLR_OPTS)"}},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	envIndex := func(envVars []corev1.EnvVar, name string) int {
+		for i, envVar := range envVars {
+			if envVar.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	envVars := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Env
+	assert.Equal(t, "30000", envVars[envIndex(envVars, "ZK_CLIENT_TIMEOUT")].Value, "Wrong ZK client timeout")
+	assert.Equal(t, "-DdistribUpdateSoTimeout=120000", envVars[envIndex(envVars, "SOLR_ZK_OPTS")].Value, "Wrong SOLR_ZK_OPTS")
+	assert.Equal(t, "$(SOLR_ZK_OPTS) -Dextra=opts", envVars[envIndex(envVars, "SOLR_OPTS")].Value, "The SOLR_OPTS should include the SOLR_ZK_OPTS")
+	assert.Less(t, envIndex(envVars, "SOLR_ZK_OPTS"), envIndex(envVars, "SOLR_OPTS"), "The SOLR_ZK_OPTS must be defined before the SOLR_OPTS that reference them")
+	assert.Less(t, envIndex(envVars, "SOLR_OPTS"), envIndex(envVars, "CUSTOM"), "Custom environment variables must be defined after the operator's")
+
+	// Without the options, the environment is unchanged
+	instance.Spec.ZkClientTimeout = nil
+	instance.Spec.SolrZkOpts = ""
+	envVars = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.Containers[0].Env
+	assert.Equal(t, -1, envIndex(envVars, "ZK_CLIENT_TIMEOUT"), "No ZK client timeout should be set by default")
+	assert.Equal(t, -1, envIndex(envVars, "SOLR_ZK_OPTS"), "No SOLR_ZK_OPTS should be set by default")
+	assert.Equal(t, "-Dextra=opts", envVars[envIndex(envVars, "SOLR_OPTS")].Value, "The SOLR_OPTS should not be changed by default")
+}
+
+func TestCloudZkConnectionInfoDefaultsAndValidation(t *testing.T) {
+	external := "ext-host:2181"
+	emptyExternal := ""
+	testCases := []struct {
+		name             string
+		connectionInfo   solr.ZookeeperConnectionInfo
+		expectedInternal string
+		expectedChRoot   string
+		valid            bool
+	}{
+		{name: "internal only", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:2181"}, expectedInternal: "host:2181", expectedChRoot: "/", valid: true},
+		{name: "internal and chroot", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181,host-2:2181", ChRoot: "/solr"}, expectedInternal: "host-1:2181,host-2:2181", expectedChRoot: "/solr", valid: true},
+		{name: "chroot without leading slash", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:2181", ChRoot: "a/b"}, expectedInternal: "host:2181", expectedChRoot: "/a/b", valid: true},
+		{name: "external only", connectionInfo: solr.ZookeeperConnectionInfo{ExternalConnectionString: &external}, expectedInternal: external, expectedChRoot: "/", valid: true},
+		{name: "external only with chroot", connectionInfo: solr.ZookeeperConnectionInfo{ExternalConnectionString: &external, ChRoot: "solr"}, expectedInternal: external, expectedChRoot: "/solr", valid: true},
+		{name: "internal and external", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:2181", ExternalConnectionString: &external}, expectedInternal: "host:2181", expectedChRoot: "/", valid: true},
+		{name: "no hosts", connectionInfo: solr.ZookeeperConnectionInfo{ChRoot: "/solr"}, expectedInternal: "", expectedChRoot: "/solr", valid: false},
+		{name: "empty external", connectionInfo: solr.ZookeeperConnectionInfo{ExternalConnectionString: &emptyExternal}, expectedInternal: "", expectedChRoot: "/", valid: false},
+		{name: "empty host in list", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181,,host-2:2181"}, expectedInternal: "host-1:2181,,host-2:2181", expectedChRoot: "/", valid: false},
+		{name: "host without port", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181,host-2"}, expectedInternal: "host-1:2181,host-2", expectedChRoot: "/", valid: false},
+		{name: "chroot with a host list", connectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181", ChRoot: "/solr,host-2:2181"}, expectedInternal: "host-1:2181", expectedChRoot: "/solr,host-2:2181", valid: false},
+	}
+
+	for _, testCase := range testCases {
+		connectionInfo := testCase.connectionInfo
+		instance := &solr.SolrCloud{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: solr.SolrCloudSpec{
+				ZookeeperRef: &solr.ZookeeperRef{ConnectionInfo: &connectionInfo},
+			},
+		}
+		instance.WithDefaults("")
+		assert.Equal(t, testCase.expectedInternal, instance.Spec.ZookeeperRef.ConnectionInfo.InternalConnectionString, "Wrong defaulted internalConnectionString for case: %s", testCase.name)
+		assert.Equal(t, testCase.expectedChRoot, instance.Spec.ZookeeperRef.ConnectionInfo.ChRoot, "Wrong defaulted chroot for case: %s", testCase.name)
+		if testCase.valid {
+			assert.NoError(t, instance.Spec.ZookeeperRef.ConnectionInfo.Validate(), "Connection info should be valid for case: %s", testCase.name)
+		} else {
+			assert.Error(t, instance.Spec.ZookeeperRef.ConnectionInfo.Validate(), "Connection info should be invalid for case: %s", testCase.name)
+		}
+	}
+}
+
+func TestCloudInvalidZkConnectionInfo(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{
+				ConnectionInfo: &solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181,host-2"},
+			},
+		},
+		Status: solr.SolrCloudStatus{
+			ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181", ChRoot: "/"},
+		},
+	}
+	instance.WithDefaults("")
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+
+	// Invalid connection information is surfaced as an event and a condition, and the last connection info is kept
+	newStatus := &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &reconcile.Result{}))
+	assert.True(t, newStatus.IsConditionTrue(solr.ZkConnectionInfoInvalid), "The invalid connection info condition should be set")
+	assert.Equal(t, "host-1:2181/", newStatus.ZkConnectionString(), "The last used connection string should be kept")
+	assert.Len(t, recorder.Events, 1, "An event should be recorded for the invalid connection info")
+	assert.Contains(t, <-recorder.Events, "InvalidZookeeperConnectionInfo", "Wrong event reason")
+
+	// Fixing the connection information clears the condition
+	instance.Spec.ZookeeperRef.ConnectionInfo.InternalConnectionString = "host-1:2181,host-2:2181"
+	assert.NoError(t, reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &reconcile.Result{}))
+	assert.False(t, newStatus.IsConditionTrue(solr.ZkConnectionInfoInvalid), "The invalid connection info condition should be cleared")
+	assert.Equal(t, "host-1:2181,host-2:2181/", newStatus.ZkConnectionString(), "Wrong connection string")
+	assert.Len(t, recorder.Events, 0, "No event should be recorded for valid connection info")
+}
+
+func TestCloudConfigMapAdditionalData(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				ConfigMapOptions: &solr.ConfigMapOptions{
+					Annotations: map[string]string{"reloader": "enabled"},
+					AdditionalData: map[string]string{
+						"jetty-ssl.xml":  "<Configure/>",
+						"extra.txt":      "extra",
+						util.SolrXmlFile: "<solr/>",
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, solr.DefaultSolrAdditionalConfigMountPath, instance.Spec.CustomSolrKubeOptions.ConfigMapOptions.AdditionalDataMountPath, "Wrong default mount path for the additional data")
+
+	// The additional data is added to the ConfigMap, but cannot override the solr.xml
+	configMap := util.GenerateConfigMap(instance)
+	assert.Equal(t, "<Configure/>", configMap.Data["jetty-ssl.xml"], "The additional data was not added to the ConfigMap")
+	assert.Equal(t, "extra", configMap.Data["extra.txt"], "The additional data was not added to the ConfigMap")
+	assert.NotEqual(t, "<solr/>", configMap.Data[util.SolrXmlFile], "The solr.xml must not be overridden by the additional data")
+	assert.Equal(t, "enabled", configMap.Annotations["reloader"], "The custom annotation was not added to the ConfigMap")
+
+	// Only the additional files are mounted into the Solr container
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	var additionalVolume *corev1.Volume
+	for i, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name == util.SolrAdditionalConfigVolume {
+			additionalVolume = &statefulSet.Spec.Template.Spec.Volumes[i]
+		}
+	}
+	if assert.NotNil(t, additionalVolume, "The additional config volume was not added") {
+		assert.Equal(t, []corev1.KeyToPath{{Key: "extra.txt", Path: "extra.txt"}, {Key: "jetty-ssl.xml", Path: "jetty-ssl.xml"}}, additionalVolume.ConfigMap.Items, "Wrong files mounted from the ConfigMap")
+	}
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.SolrAdditionalConfigVolume, MountPath: solr.DefaultSolrAdditionalConfigMountPath, ReadOnly: true}, "The additional config volume was not mounted")
+
+	// Data added to the ConfigMap by other tools is kept when updating it
+	found := configMap.DeepCopy()
+	found.Data["external.txt"] = "external"
+	found.Data["extra.txt"] = "changed"
+	assert.True(t, util.CopyConfigMapFields(configMap, found), "Changed additional data requires an update")
+	assert.Equal(t, "extra", found.Data["extra.txt"], "The additional data was not updated")
+	assert.Equal(t, "external", found.Data["external.txt"], "Externally added data should be kept")
+	assert.False(t, util.CopyConfigMapFields(configMap, found), "No update should be required")
+}
+
+func TestCloudBackupRestorePerNodeVolume(t *testing.T) {
+	replicas := int32(3)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			Replicas:                    &replicas,
+			BackupRestorePerNodePvcSpec: &corev1.PersistentVolumeClaimSpec{},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, instance.Spec.BackupRestorePerNodePvcSpec.AccessModes, "Wrong default access modes for the per-node backup volumes")
+
+	// Each pod gets its own backup volume from a claim template
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	if assert.Len(t, statefulSet.Spec.VolumeClaimTemplates, 1, "The per-node backup volume claim template is missing") {
+		assert.Equal(t, util.BackupRestoreVolume, statefulSet.Spec.VolumeClaimTemplates[0].Name, "Wrong claim template name")
+		assert.Equal(t, *instance.Spec.BackupRestorePerNodePvcSpec, statefulSet.Spec.VolumeClaimTemplates[0].Spec, "Wrong claim template spec")
+	}
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		assert.NotEqual(t, util.BackupRestoreVolume, volume.Name, "The per-node backup volume must not be a pod volume")
+	}
+	assert.Contains(t, statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.BackupRestoreVolume, MountPath: util.BaseBackupRestorePath, SubPath: util.BackupRestoreSubPathForCloud(instance.Name)}, "The backup volume was not mounted")
+
+	// The status lists the pods that are missing the backup volume
+	var pods []runtime.Object
+	for i := 0; i < 3; i++ {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("foo-clo-solrcloud-%d", i),
+				Namespace: expectedCloudRequest.Namespace,
+				Labels:    instance.SharedLabelsWith(map[string]string{"technology": solr.SolrTechnologyLabel}),
+			},
+		}
+		if i != 1 {
+			pod.Spec.Volumes = []corev1.Volume{{Name: util.BackupRestoreVolume}}
+		}
+		pods = append(pods, pod)
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, pods...),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+	newStatus := &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileCloudStatus(r, instance, newStatus, ""))
+	assert.False(t, newStatus.BackupRestoreReady, "The cloud should not be ready for backups")
+	assert.Equal(t, []string{"foo-clo-solrcloud-1"}, newStatus.BackupRestoreMissingPods, "Wrong pods missing the backup volume")
+	assert.True(t, newStatus.IsConditionTrue(solr.BackupRestoreNotReady), "The backup restore condition should be set")
+	assert.Len(t, recorder.Events, 1, "An event should explain why the cloud is not ready for backups")
+	assert.Contains(t, <-recorder.Events, "foo-clo-solrcloud-1", "The event should list the pods missing the backup volume")
+
+	// The event is not repeated while the problem stays the same
+	newStatus = &solr.SolrCloudStatus{Conditions: newStatus.Conditions}
+	assert.NoError(t, reconcileCloudStatus(r, instance, newStatus, ""))
+	assert.Len(t, recorder.Events, 0, "The event should not be repeated")
+
+	// Once all pods have the volume, the cloud is ready for backups
+	pods[1].(*corev1.Pod).Spec.Volumes = []corev1.Volume{{Name: util.BackupRestoreVolume}}
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, pods...)
+	newStatus = &solr.SolrCloudStatus{Conditions: newStatus.Conditions}
+	assert.NoError(t, reconcileCloudStatus(r, instance, newStatus, ""))
+	assert.True(t, newStatus.BackupRestoreReady, "The cloud should be ready for backups")
+	assert.Empty(t, newStatus.BackupRestoreMissingPods, "No pods should be missing the backup volume")
+	assert.False(t, newStatus.IsConditionTrue(solr.BackupRestoreNotReady), "The backup restore condition should be cleared")
+}
+
+func TestCloudBackupRestoreVolumeAccessMode(t *testing.T) {
+	replicas := int32(2)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			Replicas: &replicas,
+			BackupRestoreVolume: &corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "backups"},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "backups", Namespace: expectedCloudRequest.Namespace},
+		Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, pvc),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+
+	reason, message, err := backupRestoreVolumeProblem(r, instance, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "AccessModeMismatch", reason, "A ReadWriteOnce PVC cannot be shared by the Solr pods")
+	assert.Contains(t, message, "ReadWriteMany", "The message should explain the required access mode")
+
+	pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, pvc)
+	reason, _, err = backupRestoreVolumeProblem(r, instance, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, reason, "A ReadWriteMany PVC can be shared by the Solr pods")
+
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme)
+	reason, _, err = backupRestoreVolumeProblem(r, instance, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "VolumeMissing", reason, "A missing PVC should be reported")
+}
+
+func TestCloudIngressAPIVersions(t *testing.T) {
+	ingressClass := "internal-nginx"
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{Method: solr.Ingress, DomainName: testDomain},
+			},
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				IngressOptions: &solr.IngressOptions{IngressClassName: &ingressClass},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// The networking.k8s.io/v1 Ingress is generated with the IngressClass
+	ingress := util.GenerateIngress(instance, []string{"foo-solrcloud-0"}, "")
+	assert.Equal(t, &ingressClass, ingress.Spec.IngressClassName, "Wrong ingressClassName")
+	if assert.Len(t, ingress.Spec.Rules, 2, "Wrong number of ingress rules") {
+		path := ingress.Spec.Rules[1].HTTP.Paths[0]
+		assert.Equal(t, "foo-solrcloud-0", path.Backend.Service.Name, "Wrong service for the node ingress rule")
+		assert.EqualValues(t, instance.NodePort(), path.Backend.Service.Port.Number, "Wrong port for the node ingress rule")
+	}
+
+	// A changed IngressClass updates the found Ingress
+	foundIngress := ingress.DeepCopy()
+	foundIngress.Spec.IngressClassName = nil
+	assert.True(t, util.CopyIngressFields(ingress, foundIngress), "A changed ingressClassName should require an update")
+	assert.Equal(t, &ingressClass, foundIngress.Spec.IngressClassName, "The ingressClassName was not copied")
+
+	// Clusters that only serve the legacy API get an equivalent extensions/v1beta1 Ingress
+	legacyIngress := util.LegacyIngress(ingress)
+	assert.Equal(t, ingress.Name, legacyIngress.Name, "Wrong name for the legacy Ingress")
+	assert.Equal(t, ingressClass, legacyIngress.Annotations[util.LegacyIngressClassAnnotation], "The IngressClass should be given as an annotation for older clusters")
+	assert.NotContains(t, ingress.Annotations, util.LegacyIngressClassAnnotation, "The annotations of the generated Ingress should not be changed")
+	if assert.Len(t, legacyIngress.Spec.Rules, 2, "Wrong number of legacy ingress rules") {
+		assert.Equal(t, ingress.Spec.Rules[1].Host, legacyIngress.Spec.Rules[1].Host, "Wrong host for the legacy ingress rule")
+		path := legacyIngress.Spec.Rules[1].HTTP.Paths[0]
+		assert.Equal(t, "/", path.Path, "Wrong path for the legacy ingress rule")
+		assert.Equal(t, "foo-solrcloud-0", path.Backend.ServiceName, "Wrong service for the legacy ingress rule")
+		assert.Equal(t, intstr.FromInt(instance.NodePort()), path.Backend.ServicePort, "Wrong port for the legacy ingress rule")
+	}
+
+	// An explicit ingress class annotation is kept
+	instance.Spec.CustomSolrKubeOptions.IngressOptions.Annotations = map[string]string{util.LegacyIngressClassAnnotation: "other"}
+	legacyIngress = util.LegacyIngress(util.GenerateIngress(instance, nil, ""))
+	assert.Equal(t, "other", legacyIngress.Annotations[util.LegacyIngressClassAnnotation], "The ingress class annotation should not be overridden")
+
+	// The legacy API is only used when the current one is not served
+	legacyResources := &metav1.APIResourceList{GroupVersion: util.LegacyIngressGroupVersion, APIResources: []metav1.APIResource{{Name: "ingresses", Kind: util.IngressKind}}}
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: []*metav1.APIResourceList{legacyResources}}}
+	assert.False(t, util.IsAPIResourceAvailable(discoveryClient, util.IngressGroupVersion, util.IngressKind), "The current Ingress API should not be available")
+	assert.True(t, util.IsAPIResourceAvailable(discoveryClient, util.LegacyIngressGroupVersion, util.IngressKind), "The legacy Ingress API should be available")
+}
+
+func TestCloudIngressTLS(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:                solr.Ingress,
+					DomainName:            testDomain,
+					AdditionalDomainNames: []string{"other." + testDomain},
+					IngressTLS:            &solr.SolrIngressTLSOptions{SecretName: "solr-tls"},
+				},
+			},
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				IngressOptions: &solr.IngressOptions{
+					Annotations: map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt", "nginx.ingress.kubernetes.io/proxy-body-size": "50m"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// By default the certificate covers every host of the Ingress
+	ingress := util.GenerateIngress(instance, []string{"foo-solrcloud-0"}, "")
+	assert.Equal(t, instance.Spec.CustomSolrKubeOptions.IngressOptions.Annotations, ingress.Annotations, "Wrong annotations for the Ingress")
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	assert.Len(t, hosts, 4, "Wrong number of ingress hosts")
+	assert.Equal(t, []netv1.IngressTLS{{Hosts: hosts, SecretName: "solr-tls"}}, ingress.Spec.TLS, "Wrong TLS for the Ingress")
+
+	// Only the given hosts are covered, also in the legacy Ingress
+	instance.Spec.SolrAddressability.External.IngressTLS.Hosts = []string{"*." + testDomain}
+	ingress = util.GenerateIngress(instance, []string{"foo-solrcloud-0"}, "")
+	assert.Equal(t, []netv1.IngressTLS{{Hosts: []string{"*." + testDomain}, SecretName: "solr-tls"}}, ingress.Spec.TLS, "Wrong TLS for the Ingress")
+	assert.Equal(t, []extv1.IngressTLS{{Hosts: []string{"*." + testDomain}, SecretName: "solr-tls"}}, util.LegacyIngress(ingress).Spec.TLS, "Wrong TLS for the legacy Ingress")
+
+	// Changes to the TLS are applied to the found Ingress
+	foundIngress := ingress.DeepCopy()
+	foundIngress.Spec.TLS = nil
+	assert.True(t, util.CopyIngressFields(ingress, foundIngress), "A changed TLS should require an update")
+	assert.Equal(t, ingress.Spec.TLS, foundIngress.Spec.TLS, "The TLS was not copied")
+}
+
+func TestCloudAdvertisedPort(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:             solr.Ingress,
+					DomainName:         testDomain,
+					UseExternalAddress: true,
+					NodePortOverride:   8983,
+					AdvertisedPort:     80,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	// The node services listen on the podPort, while Solr advertises the port of the ingress controller
+	assert.Equal(t, int32(8983), util.GenerateNodeService(instance, "foo-solrcloud-0").Spec.Ports[0].Port, "Wrong port for the node service")
+	assert.Equal(t, "default-foo-solrcloud-0."+testDomain, instance.ExternalNodeUrl("foo-solrcloud-0", testDomain, true), "The external address should use the advertised port")
+	assert.Equal(t, "default-foo-solrcloud-0."+testDomain+":80_solr", instance.LiveNodeName("foo-solrcloud-0"), "Wrong live node name")
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, []string{"-DhostPort=80"}, statefulSet.Spec.Template.Spec.Containers[0].Args, "Solr should advertise the advertisedPort")
+	assert.Equal(t, int32(8983), statefulSet.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort, "Solr should listen on the podPort")
+
+	// Without the external address, the nodes advertise the port of their internal node services
+	instance.Spec.SolrAddressability.External.UseExternalAddress = false
+	assert.Equal(t, "foo-solrcloud-0.default:8983_solr", instance.LiveNodeName("foo-solrcloud-0"), "Wrong live node name")
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	assert.Equal(t, []string{"-DhostPort=8983"}, statefulSet.Spec.Template.Spec.Containers[0].Args, "Solr should advertise the port of the node service")
+
+	// The NodePort method advertises the nodePorts of the node services instead
+	instance.Spec.SolrAddressability.External = &solr.ExternalAddressability{Method: solr.NodePort, DomainName: testDomain, NodePortBase: 30100, AdvertisedPort: 80}
+	assert.True(t, instance.WithDefaults(""), "The advertisedPort should be removed for the NodePort method")
+	assert.Zero(t, instance.Spec.SolrAddressability.External.AdvertisedPort, "The advertisedPort should be removed for the NodePort method")
+}
+
+func TestCloudHostNetwork(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				HostNetwork: true,
+				External: &solr.ExternalAddressability{
+					Method:             solr.Ingress,
+					DomainName:         testDomain,
+					UseExternalAddress: true,
+				},
+			},
+		},
+	}
+	assert.True(t, instance.WithDefaults(""))
+	assert.False(t, instance.Spec.SolrAddressability.External.UseExternalAddress, "The external address cannot be advertised when using the host network")
+
+	// The Solr nodes run in the host network, and advertise the IP address and the podPort
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	podSpec := statefulSet.Spec.Template.Spec
+	assert.True(t, podSpec.HostNetwork, "The pods should use the host network")
+	assert.Equal(t, corev1.DNSClusterFirstWithHostNet, podSpec.DNSPolicy, "Wrong DNS policy for the host network")
+	assert.Equal(t, int32(8983), podSpec.Containers[0].Ports[0].HostPort, "The podPort should be used as the hostPort")
+	assert.Equal(t, []string{"-DhostPort=8983"}, podSpec.Containers[0].Args, "Solr should advertise the podPort instead of the nodePortOverride")
+	testPodEnvVariables(t, map[string]string{"SOLR_HOST": "$(POD_IP)"}, podSpec.Containers[0].Env)
+	envIndex := map[string]int{}
+	for i, envVar := range podSpec.Containers[0].Env {
+		envIndex[envVar.Name] = i
+	}
+	assert.Contains(t, envIndex, "POD_IP", "The POD_IP should be provided to the pods")
+	assert.Less(t, envIndex["POD_IP"], envIndex["SOLR_HOST"], "The POD_IP must be defined before the SOLR_HOST")
+
+	// A custom DNS policy is still used
+	instance.Spec.CustomSolrKubeOptions.PodOptions = &solr.PodOptions{DNSPolicy: corev1.DNSDefault}
+	assert.Equal(t, corev1.DNSDefault, util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.DNSPolicy, "The custom DNS policy should be used")
+
+	// The live nodes are matched by the IP address of the pods
+	assert.Equal(t, "10.0.0.5:8983_solr", instance.PodLiveNodeName("foo-solrcloud-0", "10.0.0.5"), "Wrong live node name")
+	newStatus := &solr.SolrCloudStatus{SolrNodes: []solr.SolrNodeStatus{{Name: "foo-solrcloud-0", IP: "10.0.0.5"}, {Name: "foo-solrcloud-1", IP: "10.0.0.6"}}}
+	setLiveNodeStates(instance, newStatus, []string{"10.0.0.5:8983_solr", instance.LiveNodeName("foo-solrcloud-1")})
+	assert.True(t, *newStatus.SolrNodes[0].Live, "Node 0 should be live")
+	assert.False(t, *newStatus.SolrNodes[1].Live, "Node 1 is only live under its IP address")
+
+	// Leaving the host network is an update of the StatefulSet
+	instance.Spec.SolrAddressability.HostNetwork = false
+	instance.Spec.CustomSolrKubeOptions.PodOptions = nil
+	assert.True(t, util.CopyStatefulSetFields(util.GenerateStatefulSet(instance, status, nil, ""), statefulSet), "Leaving the host network should require an update")
+	assert.False(t, statefulSet.Spec.Template.Spec.HostNetwork, "The host network was not removed")
+	assert.Equal(t, "foo-solrcloud-0.default:80_solr", instance.PodLiveNodeName("foo-solrcloud-0", "10.0.0.5"), "The IP address should only be used with the host network")
+}
+
+func TestCloudNodePortAddressability(t *testing.T) {
+	replicas := int32(2)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			Replicas: &replicas,
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:             solr.NodePort,
+					DomainName:         testDomain,
+					UseExternalAddress: true,
+					NodePortBase:       30100,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.True(t, instance.UsesIndividualNodeServices(), "The NodePort method should use individual node services")
+	assert.Equal(t, 8983, instance.NodePort(), "No nodePortOverride should be defaulted for the NodePort method")
+
+	// The common service is exposed on the nodePortBase, and each node on the following ports
+	commonService := util.GenerateCommonService(instance)
+	assert.Equal(t, corev1.ServiceTypeNodePort, commonService.Spec.Type, "Wrong type for the common service")
+	assert.Equal(t, int32(30100), commonService.Spec.Ports[0].NodePort, "Wrong nodePort for the common service")
+	for i, nodeName := range []string{"foo-solrcloud-0", "foo-solrcloud-1"} {
+		nodeService := util.GenerateNodeService(instance, nodeName)
+		assert.Equal(t, corev1.ServiceTypeNodePort, nodeService.Spec.Type, "Wrong type for the node service")
+		assert.Equal(t, int32(8983), nodeService.Spec.Ports[0].Port, "Wrong port for the node service")
+		assert.Equal(t, int32(30101+i), nodeService.Spec.Ports[0].NodePort, "Wrong nodePort for the node service")
+		assert.Equal(t, fmt.Sprintf("%s:%d", testDomain, 30101+i), instance.ExternalNodeUrl(nodeName, testDomain, true), "Wrong external address for the node")
+		assert.Equal(t, fmt.Sprintf("%s:%d_solr", testDomain, 30101+i), instance.LiveNodeName(nodeName), "Wrong live node name")
+	}
+	assert.Equal(t, testDomain+":30100", instance.ExternalCommonUrl(testDomain, true), "Wrong external address for the common service")
+
+	// The Solr nodes advertise the domain and the nodePort of their own service, computed from the ordinal of the pod
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	solrContainer := statefulSet.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, []string{"sh", "-c", "exec docker-entrypoint.sh -DhostPort=$((30101 + ${POD_HOSTNAME##*-}))"}, solrContainer.Command, "Wrong command for the Solr container")
+	assert.Empty(t, solrContainer.Args, "The hostPort should only be passed through the command")
+	testPodEnvVariables(t, map[string]string{"SOLR_HOST": testDomain}, solrContainer.Env)
+
+	// Without the external address, the nodes advertise their internal address and the services are still exposed
+	instance.Spec.SolrAddressability.External.UseExternalAddress = false
+	statefulSet = util.GenerateStatefulSet(instance, status, nil, "")
+	solrContainer = statefulSet.Spec.Template.Spec.Containers[0]
+	assert.Empty(t, solrContainer.Command, "No command should be set for the Solr container")
+	assert.Equal(t, []string{"-DhostPort=8983"}, solrContainer.Args, "Wrong args for the Solr container")
+	assert.Equal(t, "foo-solrcloud-1.default:8983_solr", instance.LiveNodeName("foo-solrcloud-1"), "Wrong live node name")
+	assert.Equal(t, corev1.ServiceTypeNodePort, util.GenerateNodeService(instance, "foo-solrcloud-1").Spec.Type, "Wrong type for the node service")
+
+	// A hidden common service is not exposed on a nodePort
+	instance.Spec.SolrAddressability.External.HideCommon = true
+	commonService = util.GenerateCommonService(instance)
+	assert.Empty(t, commonService.Spec.Type, "The hidden common service should not be a NodePort service")
+	assert.Equal(t, int32(0), commonService.Spec.Ports[0].NodePort, "The hidden common service should not have a nodePort")
+
+	// An existing ClusterIP service is changed to a NodePort service
+	nodeService := util.GenerateNodeService(instance, "foo-solrcloud-0")
+	foundService := nodeService.DeepCopy()
+	foundService.Spec.Type = corev1.ServiceTypeClusterIP
+	foundService.Spec.Ports[0].NodePort = 0
+	assert.True(t, util.CopyServiceFields(nodeService, foundService), "The changed service type should require an update")
+	assert.Equal(t, corev1.ServiceTypeNodePort, foundService.Spec.Type, "The service type was not copied")
+	assert.Equal(t, int32(30101), foundService.Spec.Ports[0].NodePort, "The nodePort was not copied")
+	assert.False(t, util.CopyServiceFields(nodeService, foundService), "An unchanged NodePort service should not require an update")
+}
+
+func TestCloudExternalDnsAnnotations(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:                solr.ExternalDNS,
+					DomainName:            testDomain,
+					AdditionalDomainNames: []string{"other." + testDomain},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// Without a TTL, only the hostnames are annotated
+	expectedAnnotations := map[string]string{
+		util.ExternalDnsHostnameAnnotation: "default." + testDomain + ",default.other." + testDomain,
+	}
+	assert.Equal(t, expectedAnnotations, util.GenerateCommonService(instance).Annotations, "Wrong annotations for the common service")
+	assert.Equal(t, expectedAnnotations, util.GenerateHeadlessService(instance).Annotations, "Wrong annotations for the headless service")
+
+	// The TTL is added to both services
+	instance.Spec.SolrAddressability.External.ExternalDnsTTL = 60
+	expectedAnnotations[util.ExternalDnsTTLAnnotation] = "60"
+	assert.Equal(t, expectedAnnotations, util.GenerateCommonService(instance).Annotations, "Wrong annotations for the common service")
+	assert.Equal(t, expectedAnnotations, util.GenerateHeadlessService(instance).Annotations, "Wrong annotations for the headless service")
+
+	// Hidden services are not given any records
+	instance.Spec.SolrAddressability.External.HideCommon = true
+	instance.Spec.SolrAddressability.External.HideNodes = true
+	assert.Empty(t, util.GenerateCommonService(instance).Annotations, "The hidden common service should not be annotated")
+	assert.Empty(t, util.GenerateHeadlessService(instance).Annotations, "The hidden headless service should not be annotated")
+}
+
+func TestCloudIstioAddressability(t *testing.T) {
+	defer UseIstioAPI(false)
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:             solr.Istio,
+					DomainName:         testDomain,
+					UseExternalAddress: true,
+					IngressTLS:         &solr.SolrIngressTLSOptions{SecretName: "solr-tls"},
+				},
+			},
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				IngressOptions: &solr.IngressOptions{Annotations: map[string]string{"team": "search"}},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, map[string]string{"istio": "ingressgateway"}, instance.Spec.SolrAddressability.External.Istio.GatewaySelector, "Wrong default gateway selector")
+	assert.True(t, instance.UsesIndividualNodeServices(), "The Istio method should use individual node services")
+	assert.Equal(t, 80, instance.NodePort(), "The nodePortOverride should default to 80 for the Istio method")
+	assert.Equal(t, "default-foo-solrcloud-0."+testDomain, instance.AdvertisedNodeHost("foo-solrcloud-0"), "Wrong advertised host")
+
+	nodeNames := []string{"foo-solrcloud-0"}
+	hosts := []interface{}{"default-foo-solrcloud." + testDomain, "default-foo-solrcloud-0." + testDomain}
+
+	// The Gateway exposes every host over http, and over https with the TLS secret
+	gateway := util.GenerateIstioGateway(instance, nodeNames)
+	assert.Equal(t, util.IstioGatewayGVK, gateway.GroupVersionKind(), "Wrong GroupVersionKind for the Gateway")
+	assert.Equal(t, instance.CommonIngressName(), gateway.GetName(), "Wrong name for the Gateway")
+	assert.Equal(t, "search", gateway.GetAnnotations()["team"], "The ingress annotations should be added to the Gateway")
+	selector, _, _ := unstructured.NestedStringMap(gateway.Object, "spec", "selector")
+	assert.Equal(t, map[string]string{"istio": "ingressgateway"}, selector, "Wrong selector for the Gateway")
+	servers, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "servers")
+	assert.Len(t, servers, 2, "Wrong number of Gateway servers")
+	assert.Equal(t, hosts, servers[0].(map[string]interface{})["hosts"], "Wrong hosts for the http server")
+	assert.Equal(t, hosts, servers[1].(map[string]interface{})["hosts"], "Wrong hosts for the https server")
+	credentialName, _, _ := unstructured.NestedString(servers[1].(map[string]interface{}), "tls", "credentialName")
+	assert.Equal(t, "solr-tls", credentialName, "Wrong credential for the https server")
+
+	// The VirtualService routes every host to its service
+	virtualService := util.GenerateIstioVirtualService(instance, nodeNames)
+	assert.Equal(t, util.IstioVirtualServiceGVK, virtualService.GroupVersionKind(), "Wrong GroupVersionKind for the VirtualService")
+	vsHosts, _, _ := unstructured.NestedSlice(virtualService.Object, "spec", "hosts")
+	assert.Equal(t, hosts, vsHosts, "Wrong hosts for the VirtualService")
+	gateways, _, _ := unstructured.NestedStringSlice(virtualService.Object, "spec", "gateways")
+	assert.Equal(t, []string{instance.CommonIngressName()}, gateways, "The VirtualService should be bound to the generated Gateway")
+	routes, _, _ := unstructured.NestedSlice(virtualService.Object, "spec", "http")
+	assert.Len(t, routes, 2, "Wrong number of VirtualService routes")
+	destinations := map[string]int64{}
+	for _, route := range routes {
+		host, _, _ := unstructured.NestedString(route.(map[string]interface{})["match"].([]interface{})[0].(map[string]interface{}), "authority", "exact")
+		destination := route.(map[string]interface{})["route"].([]interface{})[0].(map[string]interface{})["destination"].(map[string]interface{})
+		port, _, _ := unstructured.NestedInt64(destination, "port", "number")
+		destinations[host+"->"+destination["host"].(string)] = port
+	}
+	assert.Equal(t, map[string]int64{
+		"default-foo-solrcloud." + testDomain + "->foo-solrcloud-common": 80,
+		"default-foo-solrcloud-0." + testDomain + "->foo-solrcloud-0":    80,
+	}, destinations, "Wrong routes for the VirtualService")
+
+	// The resources are created, and regenerating them does not require an update
+	UseIstioAPI(true)
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:    ctrl.Log.WithName("test"),
+		scheme: scheme.Scheme,
+	}
+	assert.NoError(t, reconcileIstioResources(r, instance, nodeNames), "The Istio resources should be reconciled")
+	foundGateway := &unstructured.Unstructured{}
+	foundGateway.SetGroupVersionKind(util.IstioGatewayGVK)
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: instance.CommonIngressName(), Namespace: "default"}, foundGateway), "The Gateway should be created")
+	assert.True(t, metav1.IsControlledBy(foundGateway, instance), "The Gateway should be controlled by the cloud")
+	assert.False(t, util.CopyUnstructuredResourceFields(util.GenerateIstioGateway(instance, nodeNames), foundGateway), "An unchanged Gateway should not require an update")
+
+	// An existing Gateway replaces the generated one
+	instance.Spec.SolrAddressability.External.Istio.Gateway = "istio-system/shared-gateway"
+	assert.NoError(t, reconcileIstioResources(r, instance, nodeNames), "The Istio resources should be reconciled")
+	err := r.Get(context.TODO(), types.NamespacedName{Name: instance.CommonIngressName(), Namespace: "default"}, foundGateway)
+	assert.True(t, errors.IsNotFound(err), "The generated Gateway should be deleted when an existing Gateway is used")
+	foundVirtualService := &unstructured.Unstructured{}
+	foundVirtualService.SetGroupVersionKind(util.IstioVirtualServiceGVK)
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: instance.CommonIngressName(), Namespace: "default"}, foundVirtualService), "The VirtualService should exist")
+	gateways, _, _ = unstructured.NestedStringSlice(foundVirtualService.Object, "spec", "gateways")
+	assert.Equal(t, []string{"istio-system/shared-gateway"}, gateways, "The VirtualService should be bound to the existing Gateway")
+
+	// Without the Istio CRDs, the cloud reports the missing API
+	UseIstioAPI(false)
+	reason, _ := unavailableAPIProblem(instance)
+	assert.Contains(t, reason, "IstioUnavailable", "Wrong unavailable API reason")
+}
+
+func TestCloudRouteAddressability(t *testing.T) {
+	defer UseRouteAPI(false)
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:                solr.Route,
+					DomainName:            testDomain,
+					AdditionalDomainNames: []string{"other." + testDomain},
+					UseExternalAddress:    true,
+				},
+			},
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				IngressOptions: &solr.IngressOptions{Labels: map[string]string{"router": "public"}},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.True(t, instance.UsesIndividualNodeServices(), "The Route method should use individual node services")
+	assert.Equal(t, 80, instance.NodePort(), "The nodePortOverride should default to 80 for the Route method")
+
+	// A Route is generated for the common endpoint and every node, only under the primary domain
+	routes := util.GenerateRoutes(instance, []string{"foo-solrcloud-0", "foo-solrcloud-1"})
+	assert.Len(t, routes, 3, "Wrong number of Routes")
+	expectedRoutes := map[string][]string{
+		"foo-solrcloud-common": {"default-foo-solrcloud." + testDomain, "foo-solrcloud-common"},
+		"foo-solrcloud-0":      {"default-foo-solrcloud-0." + testDomain, "foo-solrcloud-0"},
+		"foo-solrcloud-1":      {"default-foo-solrcloud-1." + testDomain, "foo-solrcloud-1"},
+	}
+	for _, route := range routes {
+		assert.Equal(t, util.RouteGVK, route.GroupVersionKind(), "Wrong GroupVersionKind for the Route")
+		assert.Equal(t, "public", route.GetLabels()["router"], "The ingress labels should be added to the Route")
+		host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+		service, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+		targetPort, _, _ := unstructured.NestedString(route.Object, "spec", "port", "targetPort")
+		assert.Equal(t, expectedRoutes[route.GetName()], []string{host, service}, "Wrong host or service for the Route %s", route.GetName())
+		assert.Equal(t, util.SolrClientPortName, targetPort, "Wrong target port for the Route %s", route.GetName())
+	}
+
+	// The Routes are created, and the Route of a removed node is deleted.
+	// The fake client can only list the Routes if their kinds are registered.
+	UseRouteAPI(true)
+	routeScheme := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(routeScheme), "The Kubernetes types should be registered")
+	assert.NoError(t, solr.AddToScheme(routeScheme), "The Solr types should be registered")
+	routeScheme.AddKnownTypeWithName(util.RouteGVK, &unstructured.Unstructured{})
+	routeScheme.AddKnownTypeWithName(util.RouteGVK.GroupVersion().WithKind(util.RouteKind+"List"), &unstructured.UnstructuredList{})
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(routeScheme, instance),
+		Log:    ctrl.Log.WithName("test"),
+		scheme: routeScheme,
+	}
+	assert.NoError(t, reconcileRoutes(r, instance, []string{"foo-solrcloud-0", "foo-solrcloud-1"}), "The Routes should be reconciled")
+	assert.NoError(t, reconcileRoutes(r, instance, []string{"foo-solrcloud-0"}), "The Routes should be reconciled")
+	foundRoutes := &unstructured.UnstructuredList{}
+	foundRoutes.SetGroupVersionKind(util.RouteGVK.GroupVersion().WithKind(util.RouteKind + "List"))
+	assert.NoError(t, r.List(context.TODO(), foundRoutes), "The Routes should be listed")
+	var routeNames []string
+	for _, route := range foundRoutes.Items {
+		routeNames = append(routeNames, route.GetName())
+	}
+	for _, route := range util.GenerateRoutes(instance, []string{"foo-solrcloud-0"}) {
+		foundRoute := &unstructured.Unstructured{}
+		foundRoute.SetGroupVersionKind(util.RouteGVK)
+		assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: route.GetName(), Namespace: "default"}, foundRoute), "The Route should exist")
+		assert.False(t, util.CopyUnstructuredResourceFields(route, foundRoute), "An unchanged Route should not require an update")
+	}
+	assert.ElementsMatch(t, []string{"foo-solrcloud-common", "foo-solrcloud-0"}, routeNames, "The Route of the removed node should be deleted")
+
+	// Hidden nodes do not get Routes
+	instance.Spec.SolrAddressability.External.HideNodes = true
+	assert.Len(t, util.GenerateRoutes(instance, []string{"foo-solrcloud-0"}), 1, "Only the common endpoint should get a Route")
+
+	// Without the Route API, the cloud reports the missing API
+	UseRouteAPI(false)
+	reason, _ := unavailableAPIProblem(instance)
+	assert.Contains(t, reason, "RouteUnavailable", "Wrong unavailable API reason")
+}
+
+func TestCloudRequiredAPIDiscovery(t *testing.T) {
+	defer UseZkCRD(false)
+	defer UseIngressAPI(true)
+
+	ingressResources := &metav1.APIResourceList{GroupVersion: util.IngressGroupVersion, APIResources: []metav1.APIResource{{Name: "ingresses", Kind: util.IngressKind}}}
+	zkResources := &metav1.APIResourceList{GroupVersion: util.ZookeeperClusterGroupVersion, APIResources: []metav1.APIResource{{Name: "zookeeperclusters", Kind: util.ZookeeperClusterKind}}}
+	otherResources := &metav1.APIResourceList{GroupVersion: util.ZookeeperClusterGroupVersion, APIResources: []metav1.APIResource{{Name: "others", Kind: "Other"}}}
+
+	testCases := []struct {
+		name           string
+		resources      []*metav1.APIResourceList
+		expectIngress  bool
+		expectZk       bool
+		expectedReason string
+	}{
+		{name: "all APIs", resources: []*metav1.APIResourceList{ingressResources, zkResources}, expectIngress: true, expectZk: true, expectedReason: ""},
+		{name: "no ZookeeperCluster CRD", resources: []*metav1.APIResourceList{ingressResources}, expectIngress: true, expectZk: false, expectedReason: "ZookeeperClusterUnavailable"},
+		{name: "no Ingress API", resources: []*metav1.APIResourceList{zkResources}, expectIngress: false, expectZk: true, expectedReason: "IngressUnavailable"},
+		{name: "no APIs", resources: nil, expectIngress: false, expectZk: false, expectedReason: "ZookeeperClusterUnavailable,IngressUnavailable"},
+		{name: "other kinds in the group version", resources: []*metav1.APIResourceList{otherResources}, expectIngress: false, expectZk: false, expectedReason: "ZookeeperClusterUnavailable,IngressUnavailable"},
+	}
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{ProvidedZookeeper: &solr.ZookeeperSpec{}},
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{Method: solr.Ingress, DomainName: testDomain},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	for _, testCase := range testCases {
+		discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: testCase.resources}}
+		ingressAvailable := util.IsAPIResourceAvailable(discoveryClient, util.IngressGroupVersion, util.IngressKind)
+		zkAvailable := util.IsAPIResourceAvailable(discoveryClient, util.ZookeeperClusterGroupVersion, util.ZookeeperClusterKind)
+		assert.Equal(t, testCase.expectIngress, ingressAvailable, "Wrong Ingress API availability for case: %s", testCase.name)
+		assert.Equal(t, testCase.expectZk, zkAvailable, "Wrong ZookeeperCluster CRD availability for case: %s", testCase.name)
+
+		UseIngressAPI(ingressAvailable)
+		UseZkCRD(zkAvailable)
+		reason, message := unavailableAPIProblem(instance)
+		assert.Equal(t, testCase.expectedReason, reason, "Wrong unavailable API reason for case: %s", testCase.name)
+		assert.Equal(t, reason == "", message == "", "A message must be given with the reason for case: %s", testCase.name)
+	}
+
+	// Clouds that do not use a feature do not require its API
+	UseIngressAPI(false)
+	UseZkCRD(false)
+	standalone := instance.DeepCopy()
+	standalone.Spec.SolrMode = solr.StandaloneMode
+	standalone.Spec.SolrAddressability.External = nil
+	reason, _ := unavailableAPIProblem(standalone)
+	assert.Empty(t, reason, "A standalone cloud without an Ingress does not require any optional API")
+
+	// The unavailable APIs are surfaced as a condition and an event, and the provided Zookeeper is skipped without an error
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+	newStatus := &solr.SolrCloudStatus{}
+	reason, message := unavailableAPIProblem(instance)
+	setProblemCondition(r, instance, newStatus, solr.RequiredAPIUnavailable, reason, message, "APIsAvailable", "")
+	assert.True(t, newStatus.IsConditionTrue(solr.RequiredAPIUnavailable), "The unavailable API condition should be set")
+	assert.Len(t, recorder.Events, 1, "An event should be recorded for the unavailable APIs")
+	assert.NoError(t, reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &reconcile.Result{}), "An unavailable ZookeeperCluster CRD should not fail the reconcile")
+	assert.Empty(t, newStatus.ZkConnectionString(), "No Zookeeper connection is available without the ZookeeperCluster CRD")
+}
+
+func TestCloudNodeHostPattern(t *testing.T) {
+	replicas := int32(2)
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "a-very-long-namespace-name-for-testing"},
+		Spec: solr.SolrCloudSpec{
+			Replicas: &replicas,
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:                solr.Ingress,
+					DomainName:            testDomain,
+					AdditionalDomainNames: []string{"other.domain.com"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+
+	// The default pattern keeps the existing hostnames
+	assert.Equal(t, solr.DefaultNodeHostPattern, instance.Spec.SolrAddressability.External.NodeHostPattern, "Wrong default nodeHostPattern")
+	assert.Equal(t, "a-very-long-namespace-name-for-testing-foo-solrcloud-1."+testDomain, instance.ExternalNodeUrl("foo-solrcloud-1", testDomain, false), "The default pattern should not change the node hostnames")
+	assert.NoError(t, instance.ValidateNodeHostPattern())
+
+	// A custom pattern is used for the ingress rules, the status and the advertised host
+	instance.Spec.SolrAddressability.External.NodeHostPattern = "solr-{cloud}-{ordinal}.{domain}"
+	assert.NoError(t, instance.ValidateNodeHostPattern())
+	ingress := util.GenerateIngress(instance, instance.GetAllSolrNodeNames(), "")
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		hosts = append(hosts, rule.Host)
+	}
+	assert.Contains(t, hosts, "solr-foo-0."+testDomain, "The ingress rule for node 0 should use the nodeHostPattern")
+	assert.Contains(t, hosts, "solr-foo-1.other.domain.com", "The ingress rule for node 1 should use the nodeHostPattern for additional domains")
+	assert.Equal(t, "solr-foo-1."+testDomain, instance.ExternalNodeUrl("foo-solrcloud-1", testDomain, true), "Wrong external node url")
+
+	instance.Spec.SolrAddressability.External.NodeHostPattern = "{node}.{domain}"
+	instance.Spec.SolrAddressability.External.UseExternalAddress = true
+	assert.NoError(t, instance.ValidateNodeHostPattern())
+	assert.Equal(t, "$(POD_HOSTNAME)."+testDomain, instance.AdvertisedNodeHost("$(POD_HOSTNAME)"), "The advertised host should use the nodeHostPattern")
+
+	// A pattern without the domain gives each node a single hostname, e.g. to match an existing wildcard certificate
+	instance.Spec.SolrAddressability.External.NodeHostPattern = "{node}.{cloud}.search.example.com"
+	assert.NoError(t, instance.ValidateNodeHostPattern())
+	hosts = util.IngressHosts(instance, instance.GetAllSolrNodeNames())
+	for _, nodeName := range instance.GetAllSolrNodeNames() {
+		host := nodeName + ".foo.search.example.com"
+		assert.Equal(t, host, instance.AdvertisedNodeHost(nodeName), "The advertised host should use the nodeHostPattern")
+		count := 0
+		for _, found := range hosts {
+			if found == host {
+				count++
+			}
+		}
+		assert.Equal(t, 1, count, "The hostname of node %s should only have a single rule for all domains", nodeName)
+	}
+
+	// Invalid patterns are rejected
+	invalidPatterns := map[string]string{
+		"no node identifier":                 "solr-{cloud}.{domain}",
+		"ordinal with useExternalAddress":    "solr-{ordinal}.{domain}",
+		"label longer than 63 characters":    "{namespace}-{namespace}-{node}.{domain}",
+		"invalid characters in the hostname": "solr_{node}.{domain}",
+	}
+	for name, pattern := range invalidPatterns {
+		instance.Spec.SolrAddressability.External.NodeHostPattern = pattern
+		assert.Error(t, instance.ValidateNodeHostPattern(), "The nodeHostPattern should be invalid: %s", name)
+	}
+
+	// Hidden nodes do not have hostnames
+	instance.Spec.SolrAddressability.External.HideNodes = true
+	assert.NoError(t, instance.ValidateNodeHostPattern(), "The nodeHostPattern is not used when the nodes are hidden")
+}
+
+func TestCloudProvidedSolrXml(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedCloudRequest.Name, Namespace: expectedCloudRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				ConfigMapOptions: &solr.ConfigMapOptions{
+					ProvidedConfigMap: "custom-solr-xml",
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+
+	// The solr.xml is mounted from the provided ConfigMap instead of the generated one
+	assert.NotContains(t, util.GenerateConfigMap(instance).Data, util.SolrXmlFile, "The operator should not generate a solr.xml when one is provided")
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.Name == "solr-xml" {
+			assert.Equal(t, "custom-solr-xml", volume.ConfigMap.Name, "The solr.xml should be mounted from the provided ConfigMap")
+		}
+	}
+	_, configMaps := util.PodSpecReferences(&statefulSet.Spec.Template.Spec)
+	assert.Contains(t, configMaps, "custom-solr-xml", "The provided ConfigMap must exist for the Solr pods to start")
+
+	// The provided solr.xml must use the hostPort that the operator sets
+	providedConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "custom-solr-xml", Namespace: instance.Namespace}}
+	assert.Error(t, util.ValidateProvidedSolrXml(providedConfigMap), "A ConfigMap without a solr.xml cannot be used")
+	providedConfigMap.Data = map[string]string{util.SolrXmlFile: "<solr><solrcloud><int name=\"hostPort\">8983</int></solrcloud></solr>"}
+	assert.Error(t, util.ValidateProvidedSolrXml(providedConfigMap), "A solr.xml with a fixed hostPort cannot be used")
+	providedConfigMap.Data[util.SolrXmlFile] = "<solr><solrcloud><int name='hostPort'>${hostPort}</int></solrcloud></solr>"
+	assert.NoError(t, util.ValidateProvidedSolrXml(providedConfigMap), "A solr.xml using the hostPort property can be used")
+	providedConfigMap.Data[util.SolrXmlFile] = util.DefaultSolrXml
+	assert.NoError(t, util.ValidateProvidedSolrXml(providedConfigMap), "The default solr.xml can be used")
+
+	// Clouds are reconciled when their provided ConfigMap changes
+	otherCloud := &solr.SolrCloud{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"}}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, instance, otherCloud, providedConfigMap),
+		Log:    ctrl.Log.WithName("test"),
+	}
+	requests := r.referenceToCloudRequests(handler.MapObject{Meta: providedConfigMap, Object: providedConfigMap})
+	assert.Equal(t, []reconcile.Request{expectedCloudRequest}, requests, "Only the cloud using the ConfigMap should be reconciled")
+	sameNameSecret := &corev1.Secret{ObjectMeta: providedConfigMap.ObjectMeta}
+	assert.Empty(t, r.referenceToCloudRequests(handler.MapObject{Meta: sameNameSecret, Object: sameNameSecret}), "A Secret with the same name is not used as the solr.xml")
+
+	// The same goes for a ConfigMap with the log4j2 configuration of a cloud
+	otherCloud.Spec.SolrLogs = &solr.SolrLogsOptions{
+		Log4j2Config: &solr.SolrLog4j2ConfigOptions{
+			ConfigMapRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "logging"}, Key: util.LogXmlFile},
+		},
+	}
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, instance, otherCloud)
+	loggingConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "logging", Namespace: "default"}}
+	requests = r.referenceToCloudRequests(handler.MapObject{Meta: loggingConfigMap, Object: loggingConfigMap})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "bar", Namespace: "default"}}}, requests, "Only the cloud using the log4j2 configuration should be reconciled")
+}
+
+func TestCloudMissingReferences(t *testing.T) {
+	optional := true
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				PodOptions: &solr.PodOptions{
+					Volumes: []solr.AdditionalVolume{
+						{
+							Name:                  "tls",
+							Source:                corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "tls-secret"}},
+							DefaultContainerMount: corev1.VolumeMount{Name: "tls", MountPath: "/tls"},
+						},
+						{
+							Name:                  "optional",
+							Source:                corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "optional-config"}, Optional: &optional}},
+							DefaultContainerMount: corev1.VolumeMount{Name: "optional", MountPath: "/optional"},
+						},
+					},
+					EnvVariables: []corev1.EnvVar{
+						{
+							Name: "AUTH_PASSWORD",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "auth-secret"}, Key: "password"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+
+	// Optional references are not required for the pods to start
+	secrets, configMaps := util.PodSpecReferences(&statefulSet.Spec.Template.Spec)
+	assert.Equal(t, []string{"auth-secret", "tls-secret"}, secrets, "Wrong referenced Secrets")
+	assert.Equal(t, []string{instance.ConfigMapName()}, configMaps, "Wrong referenced ConfigMaps")
+
+	generatedConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: instance.ConfigMapName(), Namespace: instance.Namespace}}
+	tlsSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tls-secret", Namespace: instance.Namespace}}
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, generatedConfigMap, tlsSecret),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+
+	// The missing Secret is listed in the condition and an event
+	newStatus := &solr.SolrCloudStatus{}
+	resolved, err := reconcilePodReferences(r, instance, &statefulSet.Spec.Template.Spec, newStatus)
+	assert.NoError(t, err)
+	assert.False(t, resolved, "The auth Secret is missing")
+	if condition := newStatus.GetCondition(solr.ReferencesResolved); assert.NotNil(t, condition, "The ReferencesResolved condition should be set") {
+		assert.Equal(t, corev1.ConditionFalse, condition.Status, "The references should not be resolved")
+		assert.Contains(t, condition.Message, "Secret auth-secret", "The missing Secret should be listed")
+		assert.NotContains(t, condition.Message, "tls-secret", "Existing Secrets should not be listed")
+	}
+	assert.Len(t, recorder.Events, 1, "An event should list the missing references")
+	<-recorder.Events
+
+	// Clouds waiting for missing references are reconciled when a Secret or ConfigMap in their namespace changes
+	instance.Status = *newStatus
+	otherCloud := &solr.SolrCloud{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"}}
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, instance, otherCloud, generatedConfigMap, tlsSecret)
+	authSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "auth-secret", Namespace: instance.Namespace}}
+	requests := r.referenceToCloudRequests(handler.MapObject{Meta: authSecret, Object: authSecret})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}}, requests, "Only clouds with missing references should be reconciled")
+
+	// Once the Secret exists, the references are resolved
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, generatedConfigMap, tlsSecret, authSecret)
+	newStatus = &solr.SolrCloudStatus{Conditions: newStatus.Conditions}
+	resolved, err = reconcilePodReferences(r, instance, &statefulSet.Spec.Template.Spec, newStatus)
+	assert.NoError(t, err)
+	assert.True(t, resolved, "All references exist")
+	assert.True(t, newStatus.IsConditionTrue(solr.ReferencesResolved), "The references should be resolved")
+	assert.Len(t, recorder.Events, 0, "No event should be recorded once the references are resolved")
+}
+
+func TestCloudNodeAddressing(t *testing.T) {
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	solrHost := func(statefulSet *appsv1.StatefulSet, nodeName string) string {
+		for _, envVar := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+			if envVar.Name == "SOLR_HOST" {
+				return strings.ReplaceAll(envVar.Value, "$(POD_HOSTNAME)", nodeName)
+			}
+		}
+		return ""
+	}
+
+	// Nodes addressed through the headless service
+	headless := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrAddressability: solr.SolrAddressabilityOptions{KubeDomain: "custom.local", PodPort: 8000},
+		},
+	}
+	headless.WithDefaults("")
+	assert.Equal(t, solr.HeadlessServiceAddressing, headless.NodeAddressing())
+	assert.Equal(t, "foo-solrcloud-0.foo-solrcloud-headless.default.svc.custom.local", headless.InternalNodeHost("foo-solrcloud-0"), "Wrong headless host")
+	assert.Equal(t, "foo-solrcloud-0.foo-solrcloud-headless.default.svc.custom.local:8000", headless.InternalNodeUrl("foo-solrcloud-0", true), "Wrong headless url")
+	statefulSet := util.GenerateStatefulSet(headless, status, nil, "")
+	assert.Equal(t, headless.InternalNodeHost("foo-solrcloud-0"), solrHost(statefulSet, "foo-solrcloud-0"), "The SOLR_HOST of the pods should match the internal host of the node")
+	assert.Equal(t, solr.HeadlessServiceAddressing, statefulSet.Annotations[util.SolrNodeAddressingAnnotation], "The StatefulSet should record how the nodes are addressed")
+	assert.Equal(t, "foo-solrcloud-0.foo-solrcloud-headless.default.svc.custom.local:8000_solr", headless.LiveNodeName("foo-solrcloud-0"), "Wrong live node name")
+
+	// Nodes addressed through individual node services
+	nodeServices := headless.DeepCopy()
+	nodeServices.Spec.SolrAddressability.External = &solr.ExternalAddressability{Method: solr.Ingress, DomainName: "test.domain"}
+	nodeServices.WithDefaults("")
+	assert.Equal(t, solr.NodeServiceAddressing, nodeServices.NodeAddressing())
+	assert.Equal(t, "foo-solrcloud-0.default.svc.custom.local", nodeServices.InternalNodeHost("foo-solrcloud-0"), "Wrong node service host")
+	// The node services of an Ingress listen on port 80 by default
+	assert.Equal(t, "foo-solrcloud-0.default.svc.custom.local", nodeServices.InternalNodeUrl("foo-solrcloud-0", true), "Wrong node service url")
+	nodeServicesStatefulSet := util.GenerateStatefulSet(nodeServices, status, nil, "")
+	assert.Equal(t, nodeServices.InternalNodeHost("foo-solrcloud-0"), solrHost(nodeServicesStatefulSet, "foo-solrcloud-0"), "The SOLR_HOST of the pods should match the internal host of the node")
+	assert.Equal(t, solr.NodeServiceAddressing, nodeServicesStatefulSet.Annotations[util.SolrNodeAddressingAnnotation], "The StatefulSet should record how the nodes are addressed")
+
+	// Switching the addressing of a running cloud is blocked
+	statefulSet.Status.Replicas = 3
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, statefulSet),
+		Log:    ctrl.Log.WithName("test"),
+	}
+	assert.NoError(t, validateNodeAddressingChange(r, headless), "Keeping the addressing should be allowed")
+	err := validateNodeAddressingChange(r, nodeServices)
+	assert.True(t, errors.IsBadRequest(err), "Switching the addressing of a running cloud should be rejected")
+
+	// Switching is allowed once the cloud is scaled down
+	statefulSet.Status.Replicas = 0
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, statefulSet)
+	assert.NoError(t, validateNodeAddressingChange(r, nodeServices), "Switching the addressing of a cloud without pods should be allowed")
+
+	// Clouds without a StatefulSet, or with one that does not record the addressing, are not blocked
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme)
+	assert.NoError(t, validateNodeAddressingChange(r, nodeServices), "A new cloud should be allowed")
+	delete(statefulSet.Annotations, util.SolrNodeAddressingAnnotation)
+	statefulSet.Status.Replicas = 3
+	r.Client = fake.NewFakeClientWithScheme(scheme.Scheme, statefulSet)
+	assert.NoError(t, validateNodeAddressingChange(r, nodeServices), "A StatefulSet without the addressing annotation should not be blocked")
+}
+
+func TestCloudEndpointsConfigMap(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"},
+		Spec: solr.SolrCloudSpec{
+			PublishEndpointsConfigMap: true,
+		},
+	}
+	instance.WithDefaults("")
+	externalCommon := "http://default-foo-solrcloud.test.domain"
+	newStatus := &solr.SolrCloudStatus{
+		ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/solr"},
+		InternalCommonAddress:   "http://foo-solrcloud-common.default",
+		ExternalCommonAddress:   &externalCommon,
+		SolrNodes: []solr.SolrNodeStatus{
+			{Name: "foo-solrcloud-0", InternalAddress: "http://foo-solrcloud-0.default", ExternalAddress: "http://default-foo-solrcloud-0.test.domain"},
+			{Name: "foo-solrcloud-1", InternalAddress: "http://foo-solrcloud-1.default", ExternalAddress: "http://default-foo-solrcloud-1.test.domain"},
+		},
+	}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme),
+		Log:    ctrl.Log.WithName("test"),
+		scheme: scheme.Scheme,
+	}
+	configMapKey := types.NamespacedName{Name: "foo-solrcloud-endpoints", Namespace: "default"}
+
+	// The ConfigMap is created with the endpoints of the status, and owned by the cloud
+	assert.NoError(t, reconcileEndpointsConfigMap(r, instance, newStatus))
+	configMap := &corev1.ConfigMap{}
+	assert.NoError(t, r.Get(context.TODO(), configMapKey, configMap), "The endpoints ConfigMap should be created")
+	testMapsEqual(t, "endpoints", map[string]string{
+		util.EndpointsZkConnectionStringKey: "host:7271/solr",
+		util.EndpointsCommonUrlKey:          "http://foo-solrcloud-common.default",
+		util.EndpointsExternalCommonUrlKey:  "http://default-foo-solrcloud.test.domain",
+		util.EndpointsNodeUrlsKey:           "http://foo-solrcloud-0.default\nhttp://foo-solrcloud-1.default",
+		util.EndpointsExternalNodeUrlsKey:   "http://default-foo-solrcloud-0.test.domain\nhttp://default-foo-solrcloud-1.test.domain",
+	}, configMap.Data)
+	assert.True(t, metav1.IsControlledBy(configMap, instance), "The endpoints ConfigMap should be owned by the cloud")
+
+	// Unchanged endpoints do not update the ConfigMap
+	assert.NoError(t, reconcileEndpointsConfigMap(r, instance, newStatus))
+	unchangedConfigMap := &corev1.ConfigMap{}
+	assert.NoError(t, r.Get(context.TODO(), configMapKey, unchangedConfigMap))
+	assert.Equal(t, configMap.ResourceVersion, unchangedConfigMap.ResourceVersion, "The ConfigMap should not be updated when its content is unchanged")
+
+	// Endpoints that are no longer in the status are removed
+	newStatus.ExternalCommonAddress = nil
+	newStatus.SolrNodes = []solr.SolrNodeStatus{{Name: "foo-solrcloud-0", InternalAddress: "http://foo-solrcloud-0.default"}}
+	assert.NoError(t, reconcileEndpointsConfigMap(r, instance, newStatus))
+	configMap = &corev1.ConfigMap{}
+	assert.NoError(t, r.Get(context.TODO(), configMapKey, configMap))
+	testMapsEqual(t, "endpoints", map[string]string{
+		util.EndpointsZkConnectionStringKey: "host:7271/solr",
+		util.EndpointsCommonUrlKey:          "http://foo-solrcloud-common.default",
+		util.EndpointsNodeUrlsKey:           "http://foo-solrcloud-0.default",
+	}, configMap.Data)
+
+	// The ConfigMap is deleted once the option is turned off
+	instance.Spec.PublishEndpointsConfigMap = false
+	assert.NoError(t, reconcileEndpointsConfigMap(r, instance, newStatus))
+	err := r.Get(context.TODO(), configMapKey, configMap)
+	assert.True(t, errors.IsNotFound(err), "The endpoints ConfigMap should be deleted")
+	assert.NoError(t, reconcileEndpointsConfigMap(r, instance, newStatus), "A missing ConfigMap should not be an error")
+}
+
+func TestCloudServiceAccount(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"},
+		Spec: solr.SolrCloudSpec{
+			CustomSolrKubeOptions: solr.CustomSolrKubeOptions{
+				ServiceAccountOptions: &solr.ServiceAccountOptions{
+					Create:      true,
+					Annotations: map[string]string{"eks.amazonaws.com/role-arn": "arn:aws:iam::111122223333:role/solr-backups"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	r := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme),
+		Log:    ctrl.Log.WithName("test"),
+		scheme: scheme.Scheme,
+	}
+	serviceAccountKey := types.NamespacedName{Name: "foo-solrcloud", Namespace: "default"}
+
+	// The ServiceAccount is created, owned by the cloud, and used by the Solr pods
+	assert.NoError(t, reconcileServiceAccount(r, instance))
+	serviceAccount := &corev1.ServiceAccount{}
+	assert.NoError(t, r.Get(context.TODO(), serviceAccountKey, serviceAccount), "The ServiceAccount should be created")
+	assert.Equal(t, "arn:aws:iam::111122223333:role/solr-backups", serviceAccount.Annotations["eks.amazonaws.com/role-arn"], "The annotation was not added to the ServiceAccount")
+	assert.True(t, metav1.IsControlledBy(serviceAccount, instance), "The ServiceAccount should be owned by the cloud")
+	assert.Equal(t, "foo-solrcloud", util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.ServiceAccountName, "The Solr pods should use the created ServiceAccount")
+
+	// The secrets of the ServiceAccount are left alone when its options change
+	serviceAccount.Secrets = []corev1.ObjectReference{{Name: "foo-solrcloud-token-abcde"}}
+	assert.NoError(t, r.Update(context.TODO(), serviceAccount))
+	instance.Spec.CustomSolrKubeOptions.ServiceAccountOptions.Labels = map[string]string{"team": "search"}
+	assert.NoError(t, reconcileServiceAccount(r, instance))
+	assert.NoError(t, r.Get(context.TODO(), serviceAccountKey, serviceAccount))
+	assert.Equal(t, "search", serviceAccount.Labels["team"], "The label was not added to the ServiceAccount")
+	assert.Len(t, serviceAccount.Secrets, 1, "The secrets of the ServiceAccount should be kept")
+
+	// The ServiceAccount cannot be combined with a serviceAccountName
+	instance.Spec.CustomSolrKubeOptions.PodOptions = &solr.PodOptions{ServiceAccountName: "other"}
+	assert.True(t, errors.IsBadRequest(reconcileServiceAccount(r, instance)), "A serviceAccountName should not be allowed with a created ServiceAccount")
+
+	// The ServiceAccount is deleted once it is no longer requested
+	instance.Spec.CustomSolrKubeOptions.ServiceAccountOptions.Create = false
+	assert.NoError(t, reconcileServiceAccount(r, instance))
+	assert.True(t, errors.IsNotFound(r.Get(context.TODO(), serviceAccountKey, &corev1.ServiceAccount{})), "The ServiceAccount should be deleted")
+	assert.Equal(t, "other", util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec.ServiceAccountName, "The Solr pods should use the given serviceAccountName")
+}
+
+func TestClusterOperationLock(t *testing.T) {
+	replicas := int32(3)
+	solrCloud := &solr.SolrCloud{ObjectMeta: metav1.ObjectMeta{Name: "foo-lock", Namespace: "default"}}
+	backup := &solr.SolrBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-lock-backup", Namespace: "default"},
+		Spec:       solr.SolrBackupSpec{SolrCloud: solrCloud.Name, Collections: []string{"products"}},
+		Status: solr.SolrBackupStatus{
+			CollectionBackupStatuses: []solr.CollectionBackupStatus{{Collection: "products", InProgress: true}},
+		},
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: solrCloud.StatefulSetName(), Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, CurrentRevision: "rev-1", UpdateRevision: "rev-1"},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, solrCloud, backup),
+		Log:      ctrl.Log.WithName("controllers").WithName("SolrCloud"),
+		recorder: recorder,
+	}
+	getCloud := func() *solr.SolrCloud {
+		found := &solr.SolrCloud{}
+		assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: solrCloud.Name, Namespace: solrCloud.Namespace}, found))
+		return found
+	}
+
+	// The backup takes the lock, which is written to the cloud
+	_, acquired, err := acquireClusterOperationLock(r.Client, getCloud(), backupClusterOperation(backup))
+	assert.NoError(t, err)
+	assert.True(t, acquired, "The backup should acquire the free lock")
+	assert.Equal(t, solr.BackupOperation, getCloud().Status.ClusterOperation.Operation, "The lock was not recorded in the status of the cloud")
+
+	// Restarting pods or scaling down is an operation that needs the lock
+	updated := statefulSet.DeepCopy()
+	updated.Spec.Template.Annotations = map[string]string{"solr.apache.org/solrXmlMd5": "changed"}
+	operation := clusterOperationForUpdate(solrCloud, statefulSet, updated)
+	assert.Equal(t, solr.ManagedUpdateOperation, operation.Operation, "A pod template change should be a managed update")
+	scaledDown := statefulSet.DeepCopy()
+	scaledDown.Spec.Replicas = new(int32)
+	assert.Equal(t, solr.ScaleDownOperation, clusterOperationForUpdate(solrCloud, statefulSet, scaledDown).Operation, "Lowering the replicas should be a scale down")
+	assert.Nil(t, clusterOperationForUpdate(solrCloud, statefulSet, statefulSet.DeepCopy()), "An unchanged StatefulSet should not need the lock")
+
+	// The managed update waits for the backup, even if the cloud it read does not know about the lock yet
+	proceed, err := reconcileClusterOperationLock(r, solrCloud, operation, statefulSet)
+	assert.NoError(t, err)
+	assert.False(t, proceed, "The managed update should wait for the backup")
+	assert.Contains(t, <-recorder.Events, "ClusterOperationLocked", "An event should explain why the update is waiting")
+
+	// Once the backup has finished, its lock is released and the managed update takes it
+	backup.Status.CollectionBackupStatuses[0] = solr.CollectionBackupStatus{Collection: "products", Finished: true}
+	assert.NoError(t, r.Status().Update(context.TODO(), backup))
+	cloud := getCloud()
+	proceed, err = reconcileClusterOperationLock(r, cloud, operation, statefulSet)
+	assert.NoError(t, err)
+	assert.True(t, proceed, "The managed update should start once the backup has finished")
+	assert.Equal(t, solr.ManagedUpdateOperation, getCloud().Status.ClusterOperation.Operation, "The managed update should hold the lock")
+	assert.Equal(t, getCloud().ResourceVersion, cloud.ResourceVersion, "The cloud should be updated to the version with the new lock")
+
+	// A backup must wait while the cloud is being updated
+	otherBackup := backupClusterOperation(&solr.SolrBackup{ObjectMeta: metav1.ObjectMeta{Name: "other-backup"}})
+	holder, acquired, err := acquireClusterOperationLock(r.Client, getCloud(), otherBackup)
+	assert.NoError(t, err)
+	assert.False(t, acquired, "A backup should not start during a managed update")
+	assert.Equal(t, solr.ManagedUpdateOperation, holder.Operation, "Wrong holder of the lock returned")
+
+	// After a restart of the operator, the managed update resumes with the lock it already holds
+	startTime := getCloud().Status.ClusterOperation.StartTime
+	proceed, err = reconcileClusterOperationLock(r, getCloud(), clusterOperationForUpdate(solrCloud, statefulSet, updated), statefulSet)
+	assert.NoError(t, err)
+	assert.True(t, proceed, "The managed update should resume with its own lock")
+	assert.Equal(t, startTime, getCloud().Status.ClusterOperation.StartTime, "The lock should not be taken again")
+
+	// The lock is kept until all pods run the new revision
+	rollingOut := statefulSet.DeepCopy()
+	rollingOut.Status.UpdatedReplicas = 1
+	rollingOut.Status.UpdateRevision = "rev-2"
+	_, err = reconcileClusterOperationLock(r, getCloud(), nil, rollingOut)
+	assert.NoError(t, err)
+	assert.NotNil(t, getCloud().Status.ClusterOperation, "The lock should be kept while the update rolls out")
+	_, err = reconcileClusterOperationLock(r, getCloud(), nil, statefulSet)
+	assert.NoError(t, err)
+	assert.Nil(t, getCloud().Status.ClusterOperation, "The lock should be released once the update has rolled out")
+
+	// The lock of a backup that no longer exists is released
+	_, _, err = acquireClusterOperationLock(r.Client, getCloud(), otherBackup)
+	assert.NoError(t, err)
+	_, err = reconcileClusterOperationLock(r, getCloud(), nil, statefulSet)
+	assert.NoError(t, err)
+	assert.Nil(t, getCloud().Status.ClusterOperation, "The lock of a deleted backup should be released")
+}
+
+func TestCloudZookeeperClusterRef(t *testing.T) {
+	UseZkCRD(true)
+	defer UseZkCRD(false)
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-ref", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{
+				ZookeeperClusterRef: &solr.ZookeeperClusterRef{Name: "shared-zk", Namespace: "zookeeper", ChRoot: "foo"},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Nil(t, instance.Spec.ZookeeperRef.ProvidedZookeeper, "A provided Zookeeper should not be defaulted when a ZookeeperCluster is referenced")
+	assert.Equal(t, "/foo", instance.Spec.ZookeeperRef.ZookeeperClusterRef.ChRoot, "The chRoot should start with a slash")
+
+	recorder := record.NewFakeRecorder(10)
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:      ctrl.Log.WithName("test"),
+		recorder: recorder,
+	}
+
+	// A missing ZookeeperCluster is surfaced as an event and looked up again later
+	result := reconcile.Result{}
+	newStatus := &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &result), "A missing ZookeeperCluster should not fail the reconcile")
+	assert.Equal(t, ZookeeperClusterRefCheckInterval, result.RequeueAfter, "The missing ZookeeperCluster should be looked up again")
+	assert.Empty(t, newStatus.ZkConnectionString(), "No Zookeeper connection is available without the ZookeeperCluster")
+	assert.Contains(t, <-recorder.Events, "ZookeeperClusterNotFound", "Wrong event reason")
+
+	// The connection string is built from the referenced ZookeeperCluster, which is never changed
+	zkCluster := &zookeeperv1beta1.ZookeeperCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-zk", Namespace: "zookeeper"},
+		Spec:       zookeeperv1beta1.ZookeeperClusterSpec{Replicas: 2},
+	}
+	zkCluster.WithDefaults()
+	assert.NoError(t, r.Create(context.TODO(), zkCluster))
+	result = reconcile.Result{}
+	assert.NoError(t, reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &result))
+	assert.Zero(t, result.RequeueAfter, "An existing ZookeeperCluster should not be looked up again")
+	assert.Equal(t, "shared-zk-0.shared-zk-headless.zookeeper:2181,shared-zk-1.shared-zk-headless.zookeeper:2181/foo", newStatus.ZkConnectionString(), "Wrong connection string")
+	found := &zookeeperv1beta1.ZookeeperCluster{}
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: "shared-zk", Namespace: "zookeeper"}, found))
+	assert.Empty(t, found.OwnerReferences, "The referenced ZookeeperCluster should not be owned by the cloud")
+	assert.Equal(t, zkCluster.ResourceVersion, found.ResourceVersion, "The referenced ZookeeperCluster should not be updated")
+
+	// Changes to the ZookeeperCluster reconcile the clouds that reference it
+	requests := r.zookeeperClusterToCloudRequests(handler.MapObject{Meta: found, Object: found})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "foo-ref", Namespace: "default"}}}, requests, "The referencing cloud should be reconciled")
+	other := &zookeeperv1beta1.ZookeeperCluster{ObjectMeta: metav1.ObjectMeta{Name: "shared-zk", Namespace: "default"}}
+	assert.Empty(t, r.zookeeperClusterToCloudRequests(handler.MapObject{Meta: other, Object: other}), "A ZookeeperCluster in another namespace is not referenced")
+
+	// The ZookeeperCluster reference cannot be combined with other Zookeeper information
+	instance.Spec.ZookeeperRef.ConnectionInfo = &solr.ZookeeperConnectionInfo{InternalConnectionString: "host-1:2181"}
+	err := reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &reconcile.Result{})
+	assert.True(t, errors.IsBadRequest(err), "Combining a ZookeeperCluster reference with connection info should be rejected")
+}
+
+func TestCloudSolrSecurity(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrSecurity: &solr.SolrSecurityOptions{ProbesRequireAuth: true},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, solr.Basic, instance.Spec.SolrSecurity.AuthenticationType, "Basic authentication should be the default")
+
+	reconciler := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	assert.NoError(t, reconcileSolrSecurity(reconciler, instance))
+
+	// The credentials of the operator are generated, and given the admin role in the bootstrapped security.json
+	basicAuthSecret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "foo-solrcloud-basic-auth", Namespace: "default"}, basicAuthSecret))
+	assert.Equal(t, corev1.SecretTypeBasicAuth, basicAuthSecret.Type, "Wrong type for the basic auth Secret")
+	basicAuth, err := util.SolrBasicAuthFromSecret(basicAuthSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, util.OperatorBasicAuthUser, basicAuth.Username, "Wrong operator user")
+
+	bootstrapSecret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "foo-solrcloud-security-bootstrap", Namespace: "default"}, bootstrapSecret))
+	securityJson := map[string]map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(bootstrapSecret.Data[util.SecurityJsonFile], &securityJson))
+	assert.Equal(t, true, securityJson["authentication"]["blockUnknown"], "Unknown users should be blocked when the probes authenticate")
+	credentials := securityJson["authentication"]["credentials"].(map[string]interface{})
+	for user, password := range map[string]string{basicAuth.Username: basicAuth.Password, util.AdminBasicAuthUser: string(bootstrapSecret.Data[util.AdminBasicAuthUser])} {
+		hashAndSalt := strings.Split(credentials[user].(string), " ")
+		salt, err := base64.StdEncoding.DecodeString(hashAndSalt[1])
+		assert.NoError(t, err)
+		assert.Equal(t, util.SolrPasswordHash(password, salt), credentials[user], "The password hash of %s does not match its password", user)
+	}
+	userRoles := securityJson["authorization"]["user-role"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"admin"}, userRoles[basicAuth.Username], "The operator should be an admin")
+
+	// The generated Secrets are kept as they are
+	assert.NoError(t, reconcileSolrSecurity(reconciler, instance))
+	foundSecret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "foo-solrcloud-basic-auth", Namespace: "default"}, foundSecret))
+	assert.Equal(t, basicAuthSecret.Data, foundSecret.Data, "The generated credentials should not change")
+
+	// The security.json is uploaded before Solr starts, and the probes authenticate as the operator
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/test"}}
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	initContainer := podSpec.InitContainers[len(podSpec.InitContainers)-1]
+	assert.Equal(t, "setup-zk", initContainer.Name, "The security.json should be bootstrapped by the last init container")
+	assert.Contains(t, initContainer.Command[2], "solr zk mkroot ${ZK_CHROOT}", "The chroot should be created before uploading the security.json")
+	assert.Contains(t, initContainer.Command[2], "zk:/security.json", "The security.json should be uploaded to Zookeeper")
+	assert.Equal(t, "foo-solrcloud-security-bootstrap", initContainer.Env[len(initContainer.Env)-1].ValueFrom.SecretKeyRef.Name, "The security.json should be read from the bootstrap Secret")
+	assert.Nil(t, initContainer.SecurityContext, "No security context should be set by default")
+
+	// The init container is admitted in restricted namespaces along with the other init containers of the operator
+	initOptions := &solr.SolrDataInitContainerOptions{
+		Resources:       corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}},
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &[]int64{8983}[0]},
+	}
+	securedInstance := instance.DeepCopy()
+	securedInstance.Spec.DataStorage = &solr.SolrDataStorageOptions{InitContainer: initOptions}
+	securedInstance.WithDefaults("")
+	securedPodSpec := util.GenerateStatefulSet(securedInstance, status, nil, "").Spec.Template.Spec
+	initContainer = securedPodSpec.InitContainers[len(securedPodSpec.InitContainers)-1]
+	assert.Equal(t, "setup-zk", initContainer.Name, "The security.json should be bootstrapped by the last init container")
+	assert.Equal(t, initOptions.Resources, initContainer.Resources, "Wrong resources for the setup-zk init container")
+	assert.Equal(t, initOptions.SecurityContext, initContainer.SecurityContext, "Wrong security context for the setup-zk init container")
+	assert.Nil(t, podSpec.Containers[0].LivenessProbe.HTTPGet, "An HTTP probe cannot authenticate")
+	assert.Contains(t, podSpec.Containers[0].ReadinessProbe.Exec.Command[2], "--user=\"${SOLR_BASIC_AUTH_USER}\"", "The probe should authenticate")
+	envIndex := map[string]int{}
+	for i, envVar := range podSpec.Containers[0].Env {
+		envIndex[envVar.Name] = i
+	}
+	assert.Equal(t, "foo-solrcloud-basic-auth", podSpec.Containers[0].Env[envIndex[util.BasicAuthPasswordEnvVar]].ValueFrom.SecretKeyRef.Name, "The probe credentials should be read from the basic auth Secret")
+
+	// The operator authenticates its calls to Solr
+	var requestUser, requestPassword string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestUser, requestPassword, _ = r.BasicAuth()
+		w.Write([]byte(`{"responseHeader": {"status": 0}, "cluster": {"live_nodes": ["foo-solrcloud-0.default:80_solr"]}}`))
+	}))
+	defer server.Close()
+	instance.Status.InternalCommonAddress = server.URL
+	apiClient, err := solrApiClientForCloud(reconciler.Client, instance)
+	assert.NoError(t, err)
+	_, err = util.GetLiveNodesFromApi(apiClient)
+	assert.NoError(t, err)
+	assert.Equal(t, basicAuth.Username, requestUser, "Wrong user sent to Solr")
+	assert.Equal(t, basicAuth.Password, requestPassword, "Wrong password sent to Solr")
+
+	// With a provided Secret, nothing is bootstrapped and the probes do not authenticate unless required
+	provided := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrSecurity: &solr.SolrSecurityOptions{BasicAuthSecret: "bar-creds"},
+		},
+	}
+	provided.WithDefaults("")
+	assert.True(t, errors.IsNotFound(reconcileSolrSecurity(reconciler, provided)), "A missing basicAuthSecret should be reported")
+	assert.NoError(t, reconciler.Create(context.TODO(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar-creds", Namespace: "default"},
+		Data:       map[string][]byte{corev1.BasicAuthUsernameKey: []byte("ops")},
+	}))
+	assert.True(t, errors.IsBadRequest(reconcileSolrSecurity(reconciler, provided)), "A basicAuthSecret without a password should be rejected")
+	assert.True(t, errors.IsNotFound(reconciler.Get(context.TODO(), types.NamespacedName{Name: "bar-solrcloud-security-bootstrap", Namespace: "default"}, &corev1.Secret{})), "No security.json should be generated")
+	podSpec = util.GenerateStatefulSet(provided, status, nil, "").Spec.Template.Spec
+	for _, container := range podSpec.InitContainers {
+		assert.NotEqual(t, "setup-zk", container.Name, "No security.json should be bootstrapped")
+	}
+	assert.NotNil(t, podSpec.Containers[0].LivenessProbe.HTTPGet, "The probes should not authenticate")
+}
+
+func TestCloudSolrTLS(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrTLS: &solr.SolrServerTLSOptions{
+				PKCS12Secret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls"},
+					Key:                  "keystore.p12",
+				},
+				KeyStorePasswordSecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls-password"},
+					Key:                  "password",
+				},
+			},
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:     solr.Ingress,
+					DomainName: testDomain,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, "https", instance.UrlScheme(), "The nodes should be addressed over https")
+	assert.Equal(t, "https", instance.ExternalUrlScheme(), "The external addresses should use https")
+
+	// Solr serves TLS with the mounted keystore, which is also the truststore by default
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	testPodEnvVariables(t, map[string]string{
+		"SOLR_SSL_ENABLED":          "true",
+		"SOLR_SSL_KEY_STORE":        "/var/solr/tls/keystore/keystore.p12",
+		"SOLR_SSL_KEY_STORE_TYPE":   "PKCS12",
+		"SOLR_SSL_TRUST_STORE":      "/var/solr/tls/keystore/keystore.p12",
+		"SOLR_SSL_TRUST_STORE_TYPE": "PKCS12",
+	}, podSpec.Containers[0].Env)
+	for _, envVar := range podSpec.Containers[0].Env {
+		if envVar.Name == "SOLR_SSL_KEY_STORE_PASSWORD" || envVar.Name == "SOLR_SSL_TRUST_STORE_PASSWORD" {
+			assert.Equal(t, "foo-tls-password", envVar.ValueFrom.SecretKeyRef.Name, "The password of %s should be read from the Secret", envVar.Name)
+		}
+	}
+	assert.Equal(t, "foo-tls", podSpec.Volumes[len(podSpec.Volumes)-1].Secret.SecretName, "The keystore Secret should be mounted")
+	assert.Equal(t, corev1.URISchemeHTTPS, podSpec.Containers[0].LivenessProbe.HTTPGet.Scheme, "The probes should use https")
+	initContainer := podSpec.InitContainers[len(podSpec.InitContainers)-1]
+	assert.Equal(t, "setup-zk", initContainer.Name, "The urlScheme should be set before Solr starts")
+	assert.Contains(t, initContainer.Command[2], "-cmd clusterprop -name urlScheme -val https", "The urlScheme should be set to https in Zookeeper")
+	assert.NotContains(t, initContainer.Command[2], "security.json", "No security.json should be bootstrapped without solrSecurity")
+
+	// A separate truststore and its password can be provided
+	instance.Spec.SolrTLS.TrustStoreSecret = &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo-trust"}, Key: "truststore.p12"}
+	instance.Spec.SolrTLS.TrustStorePasswordSecret = &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo-trust"}, Key: "password"}
+	podSpec = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	testPodEnvVariables(t, map[string]string{"SOLR_SSL_TRUST_STORE": "/var/solr/tls/truststore/truststore.p12"}, podSpec.Containers[0].Env)
+	for _, envVar := range podSpec.Containers[0].Env {
+		if envVar.Name == "SOLR_SSL_TRUST_STORE_PASSWORD" {
+			assert.Equal(t, "foo-trust", envVar.ValueFrom.SecretKeyRef.Name, "The truststore password should be read from its own Secret")
+		}
+	}
+
+	// The ingress controller calls Solr over https, unless the custom annotations say otherwise
+	ingress := util.GenerateIngress(instance, []string{"foo-solrcloud-0"}, "")
+	assert.Equal(t, "HTTPS", ingress.Annotations[util.NginxBackendProtocolAnnotation], "The ingress should use https for the backend")
+	instance.Spec.CustomSolrKubeOptions.IngressOptions = &solr.IngressOptions{Annotations: map[string]string{util.NginxBackendProtocolAnnotation: "GRPCS"}}
+	ingress = util.GenerateIngress(instance, []string{"foo-solrcloud-0"}, "")
+	assert.Equal(t, "GRPCS", ingress.Annotations[util.NginxBackendProtocolAnnotation], "The custom annotation should be kept")
+
+	// The operator trusts the CA of the truststore Secret when calling Solr
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"responseHeader": {"status": 0}, "cluster": {"live_nodes": ["foo-solrcloud-0.default:80_solr"]}}`))
+	}))
+	defer server.Close()
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	trustSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo-trust", Namespace: "default"}, Data: map[string][]byte{util.TLSCACertKey: caCert}}
+	reconciler := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance, trustSecret),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	apiClient, err := solrApiClientForUrl(reconciler.Client, instance, server.URL)
+	assert.NoError(t, err)
+	_, err = util.GetLiveNodesFromApi(apiClient)
+	assert.NoError(t, err, "The certificate of Solr should be trusted")
+	untrustedClient := util.NewSolrApiClient(server.URL)
+	untrustedClient.Retries = 0
+	_, err = util.GetLiveNodesFromApi(untrustedClient)
+	assert.Error(t, err, "The certificate of Solr should not be trusted without the CA")
+}
+
+func TestCloudCertManager(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrTLS: &solr.SolrServerTLSOptions{
+				CertManager: &solr.SolrCertManagerOptions{
+					IssuerRef:          solr.SolrCertManagerIssuerRef{Name: "ca-issuer"},
+					RenewBefore:        &metav1.Duration{Duration: 360 * time.Hour},
+					AdditionalDNSNames: []string{"solr.example.com"},
+				},
+			},
+			SolrAddressability: solr.SolrAddressabilityOptions{
+				External: &solr.ExternalAddressability{
+					Method:     solr.Ingress,
+					DomainName: testDomain,
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.Equal(t, "Issuer", instance.Spec.SolrTLS.CertManager.IssuerRef.Kind, "The issuer kind should default to Issuer")
+	assert.Equal(t, "cert-manager.io", instance.Spec.SolrTLS.CertManager.IssuerRef.Group, "The issuer group should default to cert-manager.io")
+
+	// The keystore is built from the issued Secret by an init container, with a generated password
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	testPodEnvVariables(t, map[string]string{
+		"SOLR_SSL_ENABLED":     "true",
+		"SOLR_SSL_KEY_STORE":   "/var/solr/tls/pkcs12/keystore.p12",
+		"SOLR_SSL_TRUST_STORE": "/var/solr/tls/pkcs12/truststore.p12",
+	}, podSpec.Containers[0].Env)
+	for _, envVar := range podSpec.Containers[0].Env {
+		if envVar.Name == "SOLR_SSL_KEY_STORE_PASSWORD" || envVar.Name == "SOLR_SSL_TRUST_STORE_PASSWORD" {
+			assert.Equal(t, "foo-solrcloud-pkcs12-password", envVar.ValueFrom.SecretKeyRef.Name, "The password of %s should be the generated one", envVar.Name)
+		}
+	}
+	var pkcs12Container *corev1.Container
+	for idx := range podSpec.InitContainers {
+		if podSpec.InitContainers[idx].Name == "solr-tls-pkcs12" {
+			pkcs12Container = &podSpec.InitContainers[idx]
+		}
+	}
+	if assert.NotNil(t, pkcs12Container, "The keystore should be built by an init container") {
+		assert.Equal(t, instance.Spec.SolrImage.ToImageName(), pkcs12Container.Image, "The init container should use the Solr image by default")
+		assert.Contains(t, pkcs12Container.Command[2], "openssl pkcs12 -export -in /var/solr/tls/pem/tls.crt -inkey /var/solr/tls/pem/tls.key", "The keystore should be built from the issued certificate")
+		assert.Contains(t, pkcs12Container.Command[2], "keytool -importcert", "The CA of the issuer should be imported into the truststore")
+		assert.Nil(t, pkcs12Container.SecurityContext, "No security context should be set by default")
+	}
+
+	// The init container is admitted in restricted namespaces along with the other init containers of the operator
+	initOptions := &solr.SolrDataInitContainerOptions{
+		Resources:       corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &[]int64{8983}[0]},
+	}
+	instance.Spec.DataStorage = &solr.SolrDataStorageOptions{InitContainer: initOptions}
+	instance.WithDefaults("")
+	podSpec = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	for _, initContainer := range podSpec.InitContainers {
+		if initContainer.Name == "solr-tls-pkcs12" {
+			assert.Equal(t, initOptions.Resources, initContainer.Resources, "Wrong resources for the keystore init container")
+			assert.Equal(t, initOptions.SecurityContext, initContainer.SecurityContext, "Wrong security context for the keystore init container")
+		}
+	}
+	secretNames := map[string]string{}
+	for _, volume := range podSpec.Volumes {
+		if volume.Secret != nil {
+			secretNames[volume.Name] = volume.Secret.SecretName
+		}
+	}
+	assert.Equal(t, "foo-solrcloud-tls", secretNames["solr-tls-pem"], "The issued Secret should be mounted")
+
+	// The certificate is valid for the internal and external hostnames of the cloud and its nodes
+	nodeNames := []string{"foo-solrcloud-0", "foo-solrcloud-1"}
+	certificate := util.GenerateCertificate(instance, nodeNames)
+	dnsNames, _, _ := unstructured.NestedStringSlice(certificate.Object, "spec", "dnsNames")
+	assert.ElementsMatch(t, []string{
+		instance.InternalCommonUrl(false),
+		instance.InternalNodeHost("foo-solrcloud-0"),
+		instance.InternalNodeHost("foo-solrcloud-1"),
+		"default-foo-solrcloud." + testDomain,
+		"default-foo-solrcloud-0." + testDomain,
+		"default-foo-solrcloud-1." + testDomain,
+		"solr.example.com",
+	}, dnsNames, "Wrong dnsNames for the Certificate")
+	secretName, _, _ := unstructured.NestedString(certificate.Object, "spec", "secretName")
+	assert.Equal(t, "foo-solrcloud-tls", secretName, "Wrong secretName for the Certificate")
+	issuerKind, _, _ := unstructured.NestedString(certificate.Object, "spec", "issuerRef", "kind")
+	assert.Equal(t, "Issuer", issuerKind, "Wrong issuer kind for the Certificate")
+	renewBefore, _, _ := unstructured.NestedString(certificate.Object, "spec", "renewBefore")
+	assert.Equal(t, "360h0m0s", renewBefore, "Wrong renewBefore for the Certificate")
+	_, hasDuration, _ := unstructured.NestedString(certificate.Object, "spec", "duration")
+	assert.False(t, hasDuration, "The duration of the issuer should be used by default")
+
+	// The password Secret is created once, and the Certificate is updated when nodes are added
+	UseCertManagerAPI(true)
+	defer UseCertManagerAPI(false)
+	reconciler := &SolrCloudReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, instance),
+		Log:    ctrl.Log.WithName("test"),
+		scheme: scheme.Scheme,
+	}
+	assert.NoError(t, reconcileSolrTLS(reconciler, instance, nodeNames[:1]))
+	passwordSecret := &corev1.Secret{}
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "foo-solrcloud-pkcs12-password", Namespace: "default"}, passwordSecret))
+	password := string(passwordSecret.Data["password"])
+	assert.NotEmpty(t, password, "A password should be generated for the keystore")
+	assert.NoError(t, reconcileSolrTLS(reconciler, instance, nodeNames))
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "foo-solrcloud-pkcs12-password", Namespace: "default"}, passwordSecret))
+	assert.Equal(t, password, string(passwordSecret.Data["password"]), "The generated password should not change")
+	foundCertificate := &unstructured.Unstructured{}
+	foundCertificate.SetGroupVersionKind(util.CertificateGVK)
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: "foo-solrcloud-tls", Namespace: "default"}, foundCertificate), "The Certificate should be created")
+	assert.True(t, metav1.IsControlledBy(foundCertificate, instance), "The Certificate should be controlled by the cloud")
+	dnsNames, _, _ = unstructured.NestedStringSlice(foundCertificate.Object, "spec", "dnsNames")
+	assert.Contains(t, dnsNames, "default-foo-solrcloud-1."+testDomain, "The Certificate should be updated for the new node")
+
+	// Without the cert-manager CRDs, the cloud reports the missing API
+	UseCertManagerAPI(false)
+	reason, _ := unavailableAPIProblem(instance)
+	assert.Contains(t, reason, "CertManagerUnavailable", "Wrong unavailable API reason")
+}
+
+func TestCloudSolrClientAuth(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrTLS: &solr.SolrServerTLSOptions{
+				PKCS12Secret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls"},
+					Key:                  "keystore.p12",
+				},
+				KeyStorePasswordSecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls-password"},
+					Key:                  "password",
+				},
+				NeedClientAuth:       true,
+				VerifyClientHostname: true,
+				ClientCertSecret:     "foo-client-tls",
+			},
+		},
+	}
+	instance.WithDefaults("")
+	assert.True(t, *instance.Spec.SolrTLS.CheckPeerName, "The peer name should be checked by default")
+
+	// Solr requires client certificates, which the probes present from within the container
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	testPodEnvVariables(t, map[string]string{
+		"SOLR_SSL_NEED_CLIENT_AUTH":             "true",
+		"SOLR_SSL_WANT_CLIENT_AUTH":             "false",
+		"SOLR_SSL_CLIENT_HOSTNAME_VERIFICATION": "true",
+		"SOLR_SSL_CHECK_PEER_NAME":              "true",
+	}, podSpec.Containers[0].Env)
+	assert.Equal(t, "foo-client-tls", podSpec.Volumes[len(podSpec.Volumes)-1].Secret.SecretName, "The client certificate should be mounted for the probes")
+	if assert.NotNil(t, podSpec.Containers[0].LivenessProbe.Exec, "The probes should run in the Solr container") {
+		assert.Contains(t, podSpec.Containers[0].LivenessProbe.Exec.Command[2], "--certificate=/var/solr/tls/client/tls.crt --private-key=/var/solr/tls/client/tls.key", "The probes should present the client certificate")
+		assert.NotContains(t, podSpec.Containers[0].LivenessProbe.Exec.Command[2], "--user", "The probes should not authenticate without solrSecurity")
+	}
+
+	// Only requesting client certificates does not affect the probes
+	instance.Spec.SolrTLS.NeedClientAuth = false
+	instance.Spec.SolrTLS.WantClientAuth = true
+	podSpec = util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	testPodEnvVariables(t, map[string]string{"SOLR_SSL_NEED_CLIENT_AUTH": "false", "SOLR_SSL_WANT_CLIENT_AUTH": "true"}, podSpec.Containers[0].Env)
+	assert.NotNil(t, podSpec.Containers[0].LivenessProbe.HTTPGet, "The probes should use https without requiring client certificates")
+	instance.Spec.SolrTLS.NeedClientAuth = true
+	instance.Spec.SolrTLS.WantClientAuth = false
+
+	// The operator presents its client certificate when calling Solr
+	clientCertPEM, clientKeyPEM, clientCert := generateTestClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"responseHeader": {"status": 0}, "cluster": {"live_nodes": ["foo-solrcloud-0.default:80_solr"]}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	trustSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo-tls", Namespace: "default"}, Data: map[string][]byte{util.TLSCACertKey: caCert}}
+	clientCertSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-client-tls", Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: clientCertPEM, corev1.TLSPrivateKeyKey: clientKeyPEM},
+	}
+	reconciler := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance, trustSecret, clientCertSecret),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	apiClient, err := solrApiClientForUrl(reconciler.Client, instance, server.URL)
+	assert.NoError(t, err)
+	_, err = util.GetLiveNodesFromApi(apiClient)
+	assert.NoError(t, err, "The client certificate of the operator should be accepted")
+	apiClient.TLSConfig.Certificates = nil
+	apiClient.Retries = 0
+	_, err = util.GetLiveNodesFromApi(apiClient)
+	assert.Error(t, err, "Solr should reject the operator without a client certificate")
+
+	// A client certificate is required with needClientAuth, unless it is issued through cert-manager
+	instance.Spec.SolrTLS.ClientCertSecret = ""
+	assert.Equal(t, "", instance.ClientCertSecretName(), "There should be no client certificate without cert-manager")
+	instance.Spec.SolrTLS = &solr.SolrServerTLSOptions{CertManager: &solr.SolrCertManagerOptions{IssuerRef: solr.SolrCertManagerIssuerRef{Name: "ca-issuer"}}, NeedClientAuth: true}
+	assert.Equal(t, "foo-solrcloud-tls", instance.ClientCertSecretName(), "The Secret issued by cert-manager should be the client certificate")
+}
+
+// generateTestClientCertificate returns a self-signed certificate for client authentication, and its PEM encoded certificate and key
+func generateTestClientCertificate(t *testing.T) (certPEM []byte, keyPEM []byte, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), cert
+}
+
+func TestCloudTLSCertRotation(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrTLS: &solr.SolrServerTLSOptions{
+				CertManager: &solr.SolrCertManagerOptions{IssuerRef: solr.SolrCertManagerIssuerRef{Name: "ca-issuer"}},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	issuedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-solrcloud-tls", Namespace: "default"},
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert-1"), corev1.TLSPrivateKeyKey: []byte("key-1"), util.TLSCACertKey: []byte("ca")},
+	}
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance, issuedSecret),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+
+	// The issued certificate is hashed into the pod template
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}
+	statefulSet := util.GenerateStatefulSet(instance, status, nil, "")
+	tlsCertMd5, err := tlsCertificateMd5(r, instance)
+	assert.NoError(t, err)
+	util.SetTLSCertMd5(statefulSet, tlsCertMd5)
+	assert.Equal(t, tlsCertMd5, statefulSet.Spec.Template.Annotations[util.TLSCertMd5Annotation], "The certificate hash should be set on the pod template")
+
+	// A renewed certificate changes the pod template, which restarts the Solr pods
+	issuedSecret.Data[corev1.TLSCertKey] = []byte("cert-2")
+	issuedSecret.Data[corev1.TLSPrivateKeyKey] = []byte("key-2")
+	assert.NoError(t, r.Update(context.TODO(), issuedSecret))
+	renewedMd5, err := tlsCertificateMd5(r, instance)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tlsCertMd5, renewedMd5, "The hash should change when the certificate is renewed")
+
+	// The cloud is reconciled when its certificate Secret changes, even if it is not waiting for references
+	requests := r.referenceToCloudRequests(handler.MapObject{Meta: issuedSecret, Object: issuedSecret})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "foo", Namespace: "default"}}}, requests, "The cloud should be reconciled when its certificate changes")
+	otherSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}
+	assert.Empty(t, r.referenceToCloudRequests(handler.MapObject{Meta: otherSecret, Object: otherSecret}), "Unrelated Secrets should not reconcile the cloud")
+
+	// With a provided keystore, its Secret and the truststore Secret are hashed
+	instance.Spec.SolrTLS = &solr.SolrServerTLSOptions{
+		PKCS12Secret:     &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls"}, Key: "keystore.p12"},
+		TrustStoreSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "foo-trust"}, Key: "truststore.p12"},
+	}
+	assert.True(t, cloudUsesSecretContent(instance, "foo-tls"), "The keystore Secret should restart the pods")
+	assert.True(t, cloudUsesSecretContent(instance, "foo-trust"), "The truststore Secret should restart the pods")
+	assert.False(t, cloudUsesSecretContent(instance, "foo-solrcloud-tls"), "The cert-manager Secret is not used with a provided keystore")
+
+	// Clouds without TLS are not hashed
+	instance.Spec.SolrTLS = nil
+	tlsCertMd5, err = tlsCertificateMd5(r, instance)
+	assert.NoError(t, err)
+	assert.Empty(t, tlsCertMd5, "No certificate hash should be computed without TLS")
+}
+
+func TestCloudZkACL(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{
+				ConnectionInfo: &solr.ZookeeperConnectionInfo{
+					InternalConnectionString: "host:7271",
+					ChRoot:                   "/foo",
+					AllACL:                   &solr.ZookeeperACL{SecretRef: "zk-acl"},
+					ReadOnlyACL:              &solr.ZookeeperACL{SecretRef: "zk-read-acl", UsernameKey: "user", PasswordKey: "pass"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults("")
+	zkInfo := instance.Spec.ZookeeperRef.ConnectionInfo
+	assert.Equal(t, "username", zkInfo.AllACL.UsernameKey, "The username key should default to username")
+	assert.Equal(t, "password", zkInfo.AllACL.PasswordKey, "The password key should default to password")
+	assert.Equal(t, "user", zkInfo.ReadOnlyACL.UsernameKey, "The provided username key should be kept")
+	assert.NoError(t, zkInfo.Validate())
+
+	// Solr connects with the credentials of the acl, and protects its znodes with both ACLs
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: *zkInfo}
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	expectedCredsAndACLs := "-DzkACLProvider=" + util.ZkACLProvider + " -DzkCredentialsProvider=" + util.ZkCredentialsProvider +
+		" -DzkDigestUsername=$(ZK_ALL_ACL_USERNAME) -DzkDigestPassword=$(ZK_ALL_ACL_PASSWORD)" +
+		" -DzkDigestReadonlyUsername=$(ZK_READ_ACL_USERNAME) -DzkDigestReadonlyPassword=$(ZK_READ_ACL_PASSWORD)"
+	testPodEnvVariables(t, map[string]string{"SOLR_ZK_CREDS_AND_ACLS": expectedCredsAndACLs}, podSpec.Containers[0].Env)
+	envIndexes := map[string]int{}
+	for idx, envVar := range podSpec.Containers[0].Env {
+		envIndexes[envVar.Name] = idx
+		switch envVar.Name {
+		case "ZK_ALL_ACL_USERNAME":
+			assert.Equal(t, corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-acl"}, Key: "username"}, *envVar.ValueFrom.SecretKeyRef, "Wrong username of the acl")
+		case "ZK_READ_ACL_PASSWORD":
+			assert.Equal(t, corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-read-acl"}, Key: "pass"}, *envVar.ValueFrom.SecretKeyRef, "Wrong password of the readOnlyAcl")
+		case "SOLR_OPTS":
+			assert.True(t, strings.HasPrefix(envVar.Value, "$(SOLR_ZK_CREDS_AND_ACLS)"), "The credentials should be passed to Solr through the SOLR_OPTS")
+		}
+	}
+	for _, name := range []string{"ZK_ALL_ACL_USERNAME", "ZK_ALL_ACL_PASSWORD", "ZK_READ_ACL_USERNAME", "ZK_READ_ACL_PASSWORD"} {
+		assert.Less(t, envIndexes[name], envIndexes["SOLR_ZK_CREDS_AND_ACLS"], "%s must be defined before it is referenced", name)
+	}
+	assert.Less(t, envIndexes["SOLR_ZK_CREDS_AND_ACLS"], envIndexes["SOLR_OPTS"], "SOLR_ZK_CREDS_AND_ACLS must be defined before it is referenced")
+
+	// Without ACLs, nothing is added
+	podSpec = util.GenerateStatefulSet(instance, &solr.SolrCloudStatus{ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{InternalConnectionString: "host:7271", ChRoot: "/"}}, nil, "").Spec.Template.Spec
+	for _, envVar := range podSpec.Containers[0].Env {
+		assert.NotEqual(t, "SOLR_ZK_CREDS_AND_ACLS", envVar.Name, "No ZooKeeper credentials should be set without ACLs")
+	}
+
+	// The readOnlyAcl cannot be used on its own
+	assert.Equal(t, "zk-read-acl", zkInfo.ReaderACL().SecretRef, "The znodes should be read with the readOnlyAcl")
+	zkInfo.AllACL = nil
+	assert.Error(t, zkInfo.Validate(), "The readOnlyAcl should require an acl")
+	zkInfo.ReadOnlyACL = nil
+	assert.Nil(t, zkInfo.ReaderACL(), "There should be no ACL to read with")
+}
+
+func TestCloudZkTLS(t *testing.T) {
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{
+				ConnectionInfo: &solr.ZookeeperConnectionInfo{
+					InternalConnectionString: "zk-0:2181,zk-1:2181",
+					ChRoot:                   "/foo",
+					AllACL:                   &solr.ZookeeperACL{SecretRef: "zk-acl"},
+					TLS: &solr.ZookeeperTLSOptions{
+						SecureClientPort:         2281,
+						KeyStoreSecret:           &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-client-tls"}, Key: "keystore.p12"},
+						KeyStorePasswordSecret:   &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-client-tls"}, Key: "keystore-password"},
+						TrustStoreSecret:         &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-tls"}, Key: "truststore.jks"},
+						TrustStorePasswordSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-tls"}, Key: "truststore-password"},
+					},
+				},
+			},
+			SolrSecurity: &solr.SolrSecurityOptions{},
+		},
+	}
+	instance.WithDefaults("")
+	zkInfo := instance.Spec.ZookeeperRef.ConnectionInfo
+	assert.NoError(t, zkInfo.Validate())
+	assert.Equal(t, "zk-0:2281,zk-1:2281/foo", zkInfo.ZkConnectionString(), "Solr should connect to the secure client port")
+
+	status := &solr.SolrCloudStatus{ZookeeperConnectionInfo: *zkInfo}
+	podSpec := util.GenerateStatefulSet(instance, status, nil, "").Spec.Template.Spec
+	expectedZkOpts := "-DzkACLProvider=" + util.ZkACLProvider + " -DzkCredentialsProvider=" + util.ZkCredentialsProvider +
+		" -DzkDigestUsername=$(ZK_ALL_ACL_USERNAME) -DzkDigestPassword=$(ZK_ALL_ACL_PASSWORD)" +
+		" -Dzookeeper.client.secure=true -Dzookeeper.clientCnxnSocket=" + util.ZkClientCnxnSocket +
+		" -Dzookeeper.ssl.keyStore.location=" + util.ZkTLSKeyStorePath + "/keystore.p12 -Dzookeeper.ssl.keyStore.password=$(ZK_TLS_KEY_STORE_PASSWORD)" +
+		" -Dzookeeper.ssl.trustStore.location=" + util.ZkTLSTrustStorePath + "/truststore.jks -Dzookeeper.ssl.trustStore.password=$(ZK_TLS_TRUST_STORE_PASSWORD)"
+	testPodEnvVariables(t, map[string]string{
+		"ZK_HOST":                "zk-0:2281,zk-1:2281/foo",
+		"SOLR_ZK_CREDS_AND_ACLS": expectedZkOpts,
+	}, podSpec.Containers[0].Env)
+	envIndexes := map[string]int{}
+	for idx, envVar := range podSpec.Containers[0].Env {
+		envIndexes[envVar.Name] = idx
+	}
+	for _, name := range []string{"ZK_TLS_KEY_STORE_PASSWORD", "ZK_TLS_TRUST_STORE_PASSWORD"} {
+		assert.Contains(t, envIndexes, name)
+		assert.Less(t, envIndexes[name], envIndexes["SOLR_ZK_CREDS_AND_ACLS"], "%s must be defined before it is referenced", name)
+	}
+	assert.Equal(t, "zk-tls", podSpec.Containers[0].Env[envIndexes["ZK_TLS_TRUST_STORE_PASSWORD"]].ValueFrom.SecretKeyRef.Name, "Wrong Secret of the truststore password")
+
+	// The stores are mounted in Solr and in the init container that sets up ZooKeeper
+	expectedMounts := []corev1.VolumeMount{
+		{Name: util.ZkTLSKeyStoreVolume, MountPath: util.ZkTLSKeyStorePath, ReadOnly: true},
+		{Name: util.ZkTLSTrustStoreVolume, MountPath: util.ZkTLSTrustStorePath, ReadOnly: true},
+	}
+	for _, mount := range expectedMounts {
+		assert.Contains(t, podSpec.Containers[0].VolumeMounts, mount, "The ZooKeeper TLS stores should be mounted in Solr")
+	}
+	var setupZk *corev1.Container
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Name == "setup-zk" {
+			setupZk = &podSpec.InitContainers[i]
+		}
+	}
+	assert.NotNil(t, setupZk, "The ZooKeeper setup init container should exist")
+	assert.Equal(t, expectedMounts, setupZk.VolumeMounts, "The ZooKeeper TLS stores should be mounted in the setup init container")
+	volumeSecrets := map[string]string{}
+	for _, volume := range podSpec.Volumes {
+		if volume.Secret != nil {
+			volumeSecrets[volume.Name] = volume.Secret.SecretName
+		}
+	}
+	assert.Equal(t, "zk-client-tls", volumeSecrets[util.ZkTLSKeyStoreVolume], "Wrong Secret of the keystore volume")
+	assert.Equal(t, "zk-tls", volumeSecrets[util.ZkTLSTrustStoreVolume], "Wrong Secret of the truststore volume")
+
+	// Rotating the stores restarts Solr
+	assert.Equal(t, []corev1.SecretKeySelector{*zkInfo.TLS.KeyStoreSecret, *zkInfo.TLS.TrustStoreSecret}, util.SolrTLSCertSecretKeys(instance), "The ZooKeeper TLS stores should be hashed")
+
+	// A password requires its store
+	zkInfo.TLS.TrustStoreSecret = nil
+	assert.Error(t, zkInfo.Validate(), "The trustStorePasswordSecret should require a trustStoreSecret")
+}
+
+func TestCloudKubeDomain(t *testing.T) {
+	UseZkCRD(true)
+	defer UseZkCRD(false)
+	SetKubeDomain(testKubeDomain)
+	defer SetKubeDomain("")
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-domain", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{
+				ZookeeperClusterRef: &solr.ZookeeperClusterRef{Name: "shared-zk", Namespace: "zookeeper"},
+			},
+		},
+	}
+	zkCluster := &zookeeperv1beta1.ZookeeperCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-zk", Namespace: "zookeeper"},
+		Spec:       zookeeperv1beta1.ZookeeperClusterSpec{Replicas: 2},
+	}
+	zkCluster.WithDefaults()
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance, zkCluster),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	cloudKey := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	// The operator's kubeDomain is the default for clouds that do not specify one
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: cloudKey})
+	assert.NoError(t, err)
+	assert.True(t, result.Requeue, "The defaulted cloud should be reconciled again")
+	assert.NoError(t, r.Get(context.TODO(), cloudKey, instance))
+	assert.Equal(t, testKubeDomain, instance.Spec.SolrAddressability.KubeDomain, "The operator's kubeDomain should be the default")
+	assert.Equal(t, "foo-domain-solrcloud-0.foo-domain-solrcloud-headless.default.svc."+testKubeDomain, instance.NodeHeadlessHost("foo-domain-solrcloud-0"), "Wrong headless host of a node")
+
+	// The ZooKeeper members are addressed with fully qualified names in the kubeDomain
+	newStatus := &solr.SolrCloudStatus{}
+	assert.NoError(t, reconcileZk(r, reconcile.Request{}, instance, *instance.Spec.BusyBoxImage, newStatus, &reconcile.Result{}))
+	assert.Equal(t, "shared-zk-0.shared-zk-headless.zookeeper.svc."+testKubeDomain+":2181,shared-zk-1.shared-zk-headless.zookeeper.svc."+testKubeDomain+":2181/", newStatus.ZkConnectionString(), "Wrong connection string")
+
+	// A kubeDomain specified by the cloud is not overridden
+	instance.Spec.SolrAddressability.KubeDomain = "custom.local"
+	assert.NoError(t, r.Update(context.TODO(), instance))
+	_, err = r.Reconcile(reconcile.Request{NamespacedName: cloudKey})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Get(context.TODO(), cloudKey, instance))
+	assert.Equal(t, "custom.local", instance.Spec.SolrAddressability.KubeDomain, "The cloud's kubeDomain should not be overridden")
+
+	// The kubeDomain of a running cloud cannot be changed, since its nodes would register under new names
+	statefulSet := &appsv1.StatefulSet{}
+	assert.NoError(t, r.Get(context.TODO(), types.NamespacedName{Name: instance.StatefulSetName(), Namespace: instance.Namespace}, statefulSet))
+	assert.Equal(t, "custom.local", statefulSet.Annotations[util.SolrKubeDomainAnnotation], "The kubeDomain of the nodes should be annotated")
+	statefulSet.Status.Replicas = 2
+	assert.NoError(t, r.Status().Update(context.TODO(), statefulSet))
+	instance.Spec.SolrAddressability.KubeDomain = testKubeDomain
+	assert.NoError(t, r.Update(context.TODO(), instance))
+	_, err = r.Reconcile(reconcile.Request{NamespacedName: cloudKey})
+	assert.True(t, errors.IsBadRequest(err), "Changing the kubeDomain of a running cloud should be rejected")
+}
+
+func TestCloudKubeDomainExistingCloud(t *testing.T) {
+	SetKubeDomain(testKubeDomain)
+	defer SetKubeDomain("")
+
+	instance := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-existing", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			ZookeeperRef: &solr.ZookeeperRef{
+				ConnectionInfo: &solr.ZookeeperConnectionInfo{InternalConnectionString: "host:2181"},
+			},
+		},
+	}
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: instance.StatefulSetName(), Namespace: instance.Namespace}}
+	r := &SolrCloudReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, instance, statefulSet),
+		Log:      ctrl.Log.WithName("test"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	cloudKey := types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}
+
+	// A cloud that already has a StatefulSet keeps the names its nodes are registered under
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: cloudKey})
+	assert.NoError(t, err)
+	assert.True(t, result.Requeue, "The defaulted cloud should be reconciled again")
+	assert.NoError(t, r.Get(context.TODO(), cloudKey, instance))
+	assert.Empty(t, instance.Spec.SolrAddressability.KubeDomain, "The operator's kubeDomain should not be set on an existing cloud")
+	assert.Equal(t, "foo-existing-solrcloud-0.foo-existing-solrcloud-headless.default", instance.NodeHeadlessHost("foo-existing-solrcloud-0"), "The nodes of an existing cloud should not be renamed")
+}
+
 func TestDefaults(t *testing.T) {
 	SetIngressBaseUrl("")
 	UseZkCRD(true)
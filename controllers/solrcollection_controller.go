@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -37,8 +38,9 @@ import (
 // SolrCollectionReconciler reconciles a SolrCollection object
 type SolrCollectionReconciler struct {
 	client.Client
-	scheme *runtime.Scheme
-	Log    logr.Logger
+	scheme   *runtime.Scheme
+	Log      logr.Logger
+	recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrcollections,verbs=get;list;watch;create;update;patch;delete
@@ -66,11 +68,22 @@ func (r *SolrCollectionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	oldStatus := collection.Status.DeepCopy()
 	requeueOrNot := reconcile.Result{Requeue: true, RequeueAfter: time.Second * 5}
 
-	solrCloud, collectionCreationStatus, err := reconcileSolrCollection(r, collection, collection.Spec.NumShards, collection.Spec.ReplicationFactor, collection.Spec.AutoAddReplicas, collection.Spec.MaxShardsPerNode, collection.Spec.RouterName, collection.Spec.RouterField, collection.Spec.Shards, collection.Spec.CollectionConfigName, collection.Namespace)
+	// Get the solrCloud that this collection is for, and a client for its API
+	solrCloud := &solrv1beta1.SolrCloud{}
+	if err = r.Get(context.TODO(), types.NamespacedName{Namespace: collection.Namespace, Name: collection.Spec.SolrCloud}, solrCloud); err != nil {
+		r.Log.Error(err, "Error while fetching the SolrCloud of the collection")
+		return reconcile.Result{}, err
+	}
+	apiClient, err := solrApiClientForCloud(r.Client, solrCloud)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	collectionCreationStatus, err := reconcileSolrCollection(r, collection, apiClient, collection.Spec.NumShards, collection.Spec.ReplicationFactor, collection.Spec.AutoAddReplicas, collection.Spec.MaxShardsPerNode, collection.Spec.RouterName, collection.Spec.RouterField, collection.Spec.Shards, collection.Spec.CollectionConfigName)
 
 	if err != nil {
 		r.Log.Error(err, "Error while creating SolrCloud collection")
-		return reconcile.Result{}, err
+		return solrApiErrorResult(r.recorder, collection, err)
 	}
 
 	if collection.Status.CreatedTime == nil {
@@ -78,7 +91,7 @@ func (r *SolrCollectionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		collection.Status.CreatedTime = &now
 	}
 
-	if solrCloud != nil && collectionCreationStatus == false {
+	if collectionCreationStatus == false {
 		r.Log.Info("Collections update failed")
 	}
 
@@ -96,10 +109,10 @@ func (r *SolrCollectionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		if util.ContainsString(collection.ObjectMeta.Finalizers, collectionFinalizer) {
 			r.Log.Info("Deleting Solr collection", "cloud", collection.Spec.SolrCloud, "namespace", collection.Namespace, "Collection Name", collection.Name)
 			// our finalizer is present, so lets handle our external dependency
-			delete, err := util.DeleteCollection(collection.Spec.SolrCloud, collection.Name, collection.Namespace)
+			delete, err := util.DeleteCollection(apiClient, collection.Name)
 			if err != nil {
 				r.Log.Error(err, "Failed to delete Solr collection")
-				return reconcile.Result{}, err
+				return solrApiErrorResult(r.recorder, collection, err)
 			}
 
 			r.Log.Info("Deleted Solr collection", "cloud", collection.Spec.SolrCloud, "namespace", collection.Namespace, "Collection Name", collection.Name, "Deleted", delete)
@@ -119,7 +132,7 @@ func (r *SolrCollectionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	}
 
 	if collection.Status.InProgressCreation {
-		if util.CheckIfCollectionExists(collection.Spec.SolrCloud, collection.Spec.Collection, collection.Namespace) {
+		if util.CheckIfCollectionExists(apiClient, collection.Spec.Collection) {
 			r.Log.Info("Collection exists, creation complete", "collection", collection, "namespace", collection.Namespace, "name", collection.Name)
 			collection.Status.InProgressCreation = false
 			requeueOrNot = reconcile.Result{}
@@ -135,26 +148,20 @@ func (r *SolrCollectionReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 	return requeueOrNot, nil
 }
 
-func reconcileSolrCollection(r *SolrCollectionReconciler, collection *solrv1beta1.SolrCollection, numShards int64, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, routerName solrv1beta1.CollectionRouterName, routerField string, shards string, collectionConfigName string, namespace string) (solrCloud *solrv1beta1.SolrCloud, collectionCreationStatus bool, err error) {
-	// Get the solrCloud that this collection is for.
-	solrCloud = &solrv1beta1.SolrCloud{}
-	if err = r.Get(context.TODO(), types.NamespacedName{Namespace: collection.Namespace, Name: collection.Spec.SolrCloud}, solrCloud); err != nil {
-		return nil, false, err
-	}
-
+func reconcileSolrCollection(r *SolrCollectionReconciler, collection *solrv1beta1.SolrCollection, apiClient *util.SolrApiClient, numShards int64, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, routerName solrv1beta1.CollectionRouterName, routerField string, shards string, collectionConfigName string) (collectionCreationStatus bool, err error) {
 	// If the collection has already been created already and requires modification
 	if collection.Status.Created {
-		modificationRequired, err := util.CheckIfCollectionModificationRequired(solrCloud.Name, collection.Name, replicationFactor, autoAddReplicas, maxShardsPerNode, collectionConfigName, namespace)
+		modificationRequired, err := util.CheckIfCollectionModificationRequired(apiClient, collection.Name, replicationFactor, autoAddReplicas, maxShardsPerNode, collectionConfigName)
 
 		if err != nil {
-			return nil, false, err
+			return false, err
 		}
 
 		if modificationRequired {
-			modify, err := util.ModifyCollection(solrCloud.Name, collection.Name, replicationFactor, autoAddReplicas, maxShardsPerNode, collectionConfigName, namespace)
+			modify, err := util.ModifyCollection(apiClient, collection.Name, replicationFactor, autoAddReplicas, maxShardsPerNode, collectionConfigName)
 
 			if err != nil {
-				return nil, false, err
+				return false, err
 			}
 
 			r.Log.Info("Modified Solr collection", "SolrCloud", collection.Spec.SolrCloud, "namespace", collection.Namespace, "Collection Name", collection.Name, "Modified", modify)
@@ -169,18 +176,16 @@ func reconcileSolrCollection(r *SolrCollectionReconciler, collection *solrv1beta
 
 		// Request the creation of collection by calling solr
 		collection.Status.InProgressCreation = true
-		create, err := util.CreateCollection(solrCloud.Name, collection.Name, numShards, replicationFactor, autoAddReplicas, maxShardsPerNode, routerName, routerField, shards, collectionConfigName, namespace)
+		create, err := util.CreateCollection(apiClient, collection.Name, numShards, replicationFactor, autoAddReplicas, maxShardsPerNode, routerName, routerField, shards, collectionConfigName)
 		if err != nil {
 			collection.Status.InProgressCreation = false
-			return nil, false, err
+			return false, err
 		}
 		collection.Status.Created = create
 		collection.Status.InProgressCreation = false
 	}
 
-	err = r.Get(context.TODO(), types.NamespacedName{Namespace: collection.Namespace, Name: collection.Spec.SolrCloud}, solrCloud)
-
-	return solrCloud, collection.Status.Created, err
+	return collection.Status.Created, nil
 }
 
 func (r *SolrCollectionReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -192,6 +197,7 @@ func (r *SolrCollectionReconciler) SetupWithManagerAndReconciler(mgr ctrl.Manage
 		For(&solrv1beta1.SolrCollection{})
 
 	r.scheme = mgr.GetScheme()
+	r.recorder = mgr.GetEventRecorderFor("solrcollection-controller")
 
 	return ctrlBuilder.Complete(reconciler)
 }
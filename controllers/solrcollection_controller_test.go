@@ -18,15 +18,25 @@ package controllers
 
 import (
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
+	"github.com/bloomberg/solr-operator/controllers/util"
 	"github.com/onsi/gomega"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"testing"
+	"time"
 )
 
 var _ reconcile.Reconciler = &SolrCollectionReconciler{}
@@ -78,3 +88,165 @@ func TestCollectionReconcile(t *testing.T) {
 	defer testClient.Delete(context.TODO(), instance)
 	g.Eventually(requests, timeout).Should(gomega.Receive(gomega.Equal(expectedCollectionRequest)))
 }
+
+func TestCollectionAndAliasAuthenticate(t *testing.T) {
+	// Every call to a secured cloud must authenticate as the operator
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, _ := req.BasicAuth()
+		if username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		action := req.URL.Query().Get("action")
+		actions = append(actions, action)
+		switch action {
+		case "LIST":
+			w.Write([]byte(`{"responseHeader":{"status":0},"collections":["t"]}`))
+		case "LISTALIASES":
+			w.Write([]byte(`{"responseHeader":{"status":0},"aliases":{}}`))
+		default:
+			w.Write([]byte(`{"responseHeader":{"status":0}}`))
+		}
+	}))
+	defer server.Close()
+
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-secure", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrSecurity: &solr.SolrSecurityOptions{BasicAuthSecret: "foo-creds"},
+		},
+		Status: solr.SolrCloudStatus{InternalCommonAddress: server.URL},
+	}
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-creds", Namespace: "default"},
+		Type:       corev1.SecretTypeBasicAuth,
+		Data:       map[string][]byte{corev1.BasicAuthUsernameKey: []byte("admin"), corev1.BasicAuthPasswordKey: []byte("secret")},
+	}
+	collection := &solr.SolrCollection{
+		ObjectMeta: metav1.ObjectMeta{Name: "t", Namespace: "default"},
+		Spec:       solr.SolrCollectionSpec{SolrCloud: solrCloud.Name, Collection: "t", CollectionConfigName: "_default"},
+	}
+	alias := &solr.SolrCollectionAlias{
+		ObjectMeta: metav1.ObjectMeta{Name: "t-alias", Namespace: "default"},
+		Spec:       solr.SolrCollectionAliasSpec{SolrCloud: solrCloud.Name, AliasType: "standard", Collections: []string{"t"}},
+	}
+	fakeClient := fake.NewFakeClientWithScheme(scheme.Scheme, solrCloud, credentials, collection, alias)
+
+	collectionReconciler := &SolrCollectionReconciler{
+		Client:   fakeClient,
+		Log:      ctrl.Log.WithName("test"),
+		recorder: record.NewFakeRecorder(10),
+	}
+	_, err := collectionReconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: collection.Name, Namespace: collection.Namespace}})
+	assert.NoError(t, err)
+	assert.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Name: collection.Name, Namespace: collection.Namespace}, collection))
+	assert.True(t, collection.Status.Created, "The collection should be created in the secured cloud")
+
+	aliasReconciler := &SolrCollectionAliasReconciler{
+		Client:   fakeClient,
+		Log:      ctrl.Log.WithName("test"),
+		recorder: record.NewFakeRecorder(10),
+	}
+	_, err = aliasReconciler.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Name: alias.Name, Namespace: alias.Namespace}})
+	assert.NoError(t, err)
+	assert.NoError(t, fakeClient.Get(context.TODO(), types.NamespacedName{Name: alias.Name, Namespace: alias.Namespace}, alias))
+	assert.True(t, alias.Status.Created, "The alias should be created in the secured cloud")
+
+	assert.Equal(t, []string{"CREATE", "LISTALIASES", "CREATEALIAS"}, actions, "Every call should reach Solr with the credentials of the operator")
+}
+
+func TestSolrApiClientRetries(t *testing.T) {
+	var requests int
+	var responseCodes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, _ := req.BasicAuth()
+		assert.Equal(t, "admin", username, "The basic auth username should be sent")
+		assert.Equal(t, "secret", password, "The basic auth password should be sent")
+		assert.Equal(t, "/solr/admin/collections", req.URL.Path, "Wrong Solr API path")
+		assert.Equal(t, "json", req.URL.Query().Get("wt"), "The response should be requested in JSON")
+
+		code := responseCodes[requests]
+		requests++
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			w.Write([]byte(`{"responseHeader":{"status":0},"requestId":"foo"}`))
+		} else {
+			w.Write([]byte(`{"error":{"msg":"Solr is overloaded"}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := util.NewSolrApiClient(server.URL)
+	client.BasicAuth = &util.SolrBasicAuth{Username: "admin", Password: "secret"}
+	client.InitialBackoff = time.Millisecond
+	client.MaxBackoff = 2 * time.Millisecond
+
+	// Unavailable responses are retried within the call
+	requests = 0
+	responseCodes = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}
+	resp := &util.SolrAsyncResponse{}
+	assert.NoError(t, client.CallCollectionsApi(url.Values{"action": []string{"REQUESTSTATUS"}}, resp))
+	assert.Equal(t, 3, requests, "The call should succeed on the last retry")
+	assert.Equal(t, "foo", resp.RequestId, "The response should be decoded")
+
+	// Once all retries fail, the call should be tried again later
+	requests = 0
+	responseCodes = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusInternalServerError}
+	err := client.CallCollectionsApi(url.Values{}, &util.SolrAsyncResponse{})
+	assert.Equal(t, 3, requests, "The number of requests should be bounded by the retries")
+	retryAfter, retryLater := util.IsSolrApiRetryLater(err)
+	assert.True(t, retryLater, "An unavailable Solr should be retried later")
+	assert.Equal(t, util.DefaultSolrApiRetryAfter, retryAfter, "Wrong retry delay")
+	assert.Contains(t, err.Error(), "Solr is overloaded", "The error should contain the response of Solr")
+
+	// Rejected requests are not retried
+	requests = 0
+	responseCodes = []int{http.StatusBadRequest}
+	err = client.CallCollectionsApi(url.Values{}, &util.SolrAsyncResponse{})
+	assert.Equal(t, 1, requests, "A rejected request should not be retried")
+	_, retryLater = util.IsSolrApiRetryLater(err)
+	assert.False(t, retryLater, "A rejected request should not be retried later")
+	assert.True(t, util.IsSolrApiError(err), "The error should be recognized as a Solr API error")
+
+	// The params of the caller are not changed, and may be omitted
+	requests = 0
+	responseCodes = []int{http.StatusOK, http.StatusOK}
+	params := url.Values{"action": []string{"REQUESTSTATUS"}}
+	assert.NoError(t, client.CallCollectionsApi(params, &util.SolrAsyncResponse{}))
+	assert.Equal(t, url.Values{"action": []string{"REQUESTSTATUS"}}, params, "The params of the caller should not be changed")
+	assert.NoError(t, client.CallCollectionsApi(nil, &util.SolrAsyncResponse{}), "A call without params should be sent")
+
+	// Connection errors are retried later
+	server.Close()
+	err = client.CallCollectionsApi(url.Values{}, &util.SolrAsyncResponse{})
+	_, retryLater = util.IsSolrApiRetryLater(err)
+	assert.True(t, retryLater, "An unreachable Solr should be retried later")
+}
+
+func TestSolrApiErrorResult(t *testing.T) {
+	collection := &solr.SolrCollection{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+	recorder := record.NewFakeRecorder(10)
+
+	// Errors of an unavailable Solr requeue the request, instead of failing the reconcile
+	result, err := solrApiErrorResult(recorder, collection, &util.SolrApiRetryLaterError{RetryAfter: time.Minute, Err: &util.SolrApiError{StatusCode: 503, Body: "overloaded"}})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, result.RequeueAfter, "The request should be requeued after the retry delay")
+	if assert.Len(t, recorder.Events, 1, "An event should be recorded") {
+		event := <-recorder.Events
+		assert.Contains(t, event, "SolrApiUnavailable")
+		assert.Contains(t, event, "overloaded", "The event should contain the response of Solr")
+	}
+
+	// Requests rejected by Solr fail the reconcile
+	_, err = solrApiErrorResult(recorder, collection, &util.SolrApiError{StatusCode: 400, Body: "bad request"})
+	assert.Error(t, err)
+	if assert.Len(t, recorder.Events, 1, "An event should be recorded") {
+		assert.Contains(t, <-recorder.Events, "SolrApiError")
+	}
+
+	// Other errors are returned as is
+	_, err = solrApiErrorResult(recorder, collection, apierrors.NewNotFound(solr.GroupVersion.WithResource("solrclouds").GroupResource(), "foo"))
+	assert.True(t, apierrors.IsNotFound(err), "The error should be returned")
+	assert.Len(t, recorder.Events, 0, "No event should be recorded")
+}
@@ -27,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -37,8 +38,9 @@ import (
 // SolrCollectionAliasReconciler reconciles a SolrCollectionAlias object
 type SolrCollectionAliasReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrcollectionaliases,verbs=get;list;watch;create;update;patch;delete
@@ -67,7 +69,18 @@ func (r *SolrCollectionAliasReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 	oldStatus := alias.Status.DeepCopy()
 	requeueOrNot := reconcile.Result{Requeue: true, RequeueAfter: time.Second * 5}
 
-	aliasCreationStatus := reconcileSolrCollectionAlias(r, alias, alias.Spec.SolrCloud, alias.Name, alias.Spec.AliasType, alias.Spec.Collections, alias.Namespace)
+	// Get the SolrCloud of the alias, and a client for its API
+	solrCloud := &solrv1beta1.SolrCloud{}
+	if err = r.Get(context.TODO(), types.NamespacedName{Namespace: alias.Namespace, Name: alias.Spec.SolrCloud}, solrCloud); err != nil {
+		r.Log.Error(err, "Error while fetching the SolrCloud of the alias")
+		return reconcile.Result{}, err
+	}
+	apiClient, err := solrApiClientForCloud(r.Client, solrCloud)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	aliasCreationStatus := reconcileSolrCollectionAlias(r, alias, apiClient, alias.Name, alias.Spec.AliasType, alias.Spec.Collections)
 
 	if err != nil {
 		r.Log.Error(err, "Error while creating SolrCloud alias")
@@ -83,17 +96,14 @@ func (r *SolrCollectionAliasReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 			}
 		}
 	} else {
-		// The object is being deleted, get associated SolrCloud
-		solrCloud := &solrv1beta1.SolrCloud{}
-		err = r.Get(context.TODO(), types.NamespacedName{Namespace: alias.Namespace, Name: alias.Spec.SolrCloud}, solrCloud)
-
-		if util.ContainsString(alias.ObjectMeta.Finalizers, aliasFinalizer) && solrCloud != nil && aliasCreationStatus {
+		// The object is being deleted
+		if util.ContainsString(alias.ObjectMeta.Finalizers, aliasFinalizer) && aliasCreationStatus {
 			r.Log.Info("Deleting Solr collection alias", "cloud", alias.Spec.SolrCloud, "namespace", alias.Namespace, "Collection Name", alias.Name)
 			// our finalizer is present, along with the associated SolrCloud and alias lets delete alias
-			delete, err := util.DeleteCollectionAlias(alias.Spec.SolrCloud, alias.Name, alias.Namespace)
+			delete, err := util.DeleteCollectionAlias(apiClient, alias.Name)
 			if err != nil {
 				r.Log.Error(err, "Failed to delete Solr collection")
-				return reconcile.Result{}, err
+				return solrApiErrorResult(r.recorder, alias, err)
 			}
 
 			r.Log.Info("Deleted Solr collection", "cloud", alias.Spec.SolrCloud, "namespace", alias.Namespace, "Alias", alias.Name, "Deleted", delete)
@@ -125,13 +135,13 @@ func (r *SolrCollectionAliasReconciler) Reconcile(req ctrl.Request) (ctrl.Result
 	return requeueOrNot, nil
 }
 
-func reconcileSolrCollectionAlias(r *SolrCollectionAliasReconciler, alias *solrv1beta1.SolrCollectionAlias, solrCloudName string, aliasName string, aliasType string, collections []string, namespace string) (aliasCreationStatus bool) {
-	success, aliasCollections := util.CurrentCollectionAliasDetails(solrCloudName, aliasName, namespace)
+func reconcileSolrCollectionAlias(r *SolrCollectionAliasReconciler, alias *solrv1beta1.SolrCollectionAlias, apiClient *util.SolrApiClient, aliasName string, aliasType string, collections []string) (aliasCreationStatus bool) {
+	success, aliasCollections := util.CurrentCollectionAliasDetails(apiClient, aliasName)
 
 	// If not created, or if alias status differs from spec requirements create alias
 	if !success || !reflect.DeepEqual(alias.Status.Collections, aliasCollections) {
 		r.Log.Info("Applying collection alias", "alias", alias)
-		err := util.CreateCollectionAlias(solrCloudName, aliasName, aliasType, collections, namespace)
+		err := util.CreateCollectionAlias(apiClient, aliasName, aliasType, collections)
 		if err == nil {
 			alias.Status.Created = true
 			alias.Status.Collections = collections
@@ -143,6 +153,7 @@ func reconcileSolrCollectionAlias(r *SolrCollectionAliasReconciler, alias *solrv
 }
 
 func (r *SolrCollectionAliasReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("solrcollectionalias-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&solrv1beta1.SolrCollectionAlias{}).
 		Complete(r)
@@ -18,25 +18,43 @@ package controllers
 
 import (
 	"context"
+	"crypto/md5"
+	"fmt"
 	solrv1beta1 "github.com/bloomberg/solr-operator/api/v1beta1"
 	"github.com/bloomberg/solr-operator/controllers/util"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
 )
 
 // SolrPrometheusExporterReconciler reconciles a SolrPrometheusExporter object
 type SolrPrometheusExporterReconciler struct {
 	client.Client
-	Log    logr.Logger
-	scheme *runtime.Scheme
+	Log      logr.Logger
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+}
+
+var useServiceMonitorCRD bool
+
+func UseServiceMonitorCRD(useCRD bool) {
+	useServiceMonitorCRD = useCRD
 }
 
 // +kubebuilder:rbac:groups=,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
@@ -45,6 +63,12 @@ type SolrPrometheusExporterReconciler struct {
 // +kubebuilder:rbac:groups=,resources=services/status,verbs=get
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=get
+// +kubebuilder:rbac:groups=,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=extensions,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=extensions,resources=ingresses/status,verbs=get
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses/status,verbs=get
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds,verbs=get;list;watch
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrclouds/status,verbs=get
 // +kubebuilder:rbac:groups=solr.bloomberg.com,resources=solrprometheusexporters,verbs=get;list;watch;create;update;patch;delete
@@ -76,9 +100,18 @@ func (r *SolrPrometheusExporterReconciler) Reconcile(req ctrl.Request) (ctrl.Res
 		return ctrl.Result{Requeue: true}, nil
 	}
 
-	if prometheusExporter.Spec.Config != "" {
+	// Reject ambiguous metrics config options, before creating any resources
+	if err := prometheusExporter.ValidateMetricsConfig(); err != nil {
+		return ctrl.Result{}, errors.NewBadRequest(err.Error())
+	}
+
+	configXmlMd5 := ""
+	if prometheusExporter.UsesMetricsConfigMap() {
 		// Generate ConfigMap
 		configMap := util.GenerateMetricsConfigMap(prometheusExporter)
+
+		// Hash the config the operator intends to use, so that changes to it will restart the exporter
+		configXmlMd5 = fmt.Sprintf("%x", md5.Sum([]byte(configMap.Data[util.PrometheusExporterConfigMapKey])))
 		if err := controllerutil.SetControllerReference(prometheusExporter, configMap, r.scheme); err != nil {
 			return ctrl.Result{}, err
 		}
@@ -120,13 +153,40 @@ func (r *SolrPrometheusExporterReconciler) Reconcile(req ctrl.Request) (ctrl.Res
 		return ctrl.Result{}, err
 	}
 
+	// Features that require an API which the Kubernetes cluster does not serve are skipped, instead of failing the reconcile
+	apiReason, apiMessage := unavailableExporterAPIProblem(prometheusExporter)
+
+	// Generate the Ingress for the Metrics Service, if the exporter should be addressable outside of the kube cluster.
+	// An Ingress is only looked up for removal if the exporter was addressable before, which its external address records.
+	if useIngressAPI && (prometheusExporter.Spec.External != nil || prometheusExporter.Status.ExternalAddress != "") {
+		if err = reconcileMetricsIngress(r, prometheusExporter); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Generate the ServiceMonitor for the Metrics Service, if requested and the ServiceMonitor CRD is installed
+	if useServiceMonitorCRD {
+		if err = reconcileMetricsServiceMonitor(r, prometheusExporter); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Get the SolrCloud that the exporter references, if it is running in the kubernetes cluster
+	var solrCloud *solrv1beta1.SolrCloud
+	if solrCloud, err = getReferencedSolrCloud(r, prometheusExporter); err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
 	// Get the ZkConnectionString to connect to
 	solrConnectionInfo := util.SolrConnectionInfo{}
-	if solrConnectionInfo, err = getSolrConnectionInfo(r, prometheusExporter); err != nil {
+	if solrConnectionInfo, err = getSolrConnectionInfo(prometheusExporter, solrCloud); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	deploy := util.GenerateSolrPrometheusExporterDeployment(prometheusExporter, solrConnectionInfo)
+	// Use the image of the referenced SolrCloud, if one is not provided
+	prometheusExporter.Spec.Image = prometheusExporter.ImageForSolrCloud(solrCloud)
+
+	deploy := util.GenerateSolrPrometheusExporterDeployment(prometheusExporter, solrConnectionInfo, configXmlMd5)
 	if err := controllerutil.SetControllerReference(prometheusExporter, deploy, r.scheme); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -137,17 +197,33 @@ func (r *SolrPrometheusExporterReconciler) Reconcile(req ctrl.Request) (ctrl.Res
 		r.Log.Info("Creating PrometheusExporter Deployment", "namespace", deploy.Namespace, "name", deploy.Name)
 		err = r.Create(context.TODO(), deploy)
 	} else if err == nil {
-		if util.CopyDeploymentFields(deploy, foundDeploy) {
+		suspensionChanged := util.CopyExporterReplicas(prometheusExporter, deploy, foundDeploy)
+		if util.CopyDeploymentFields(deploy, foundDeploy) || suspensionChanged {
 			r.Log.Info("Updating PrometheusExporter Deployment", "namespace", deploy.Namespace, "name", deploy.Name)
 			err = r.Update(context.TODO(), foundDeploy)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 		}
-		ready := foundDeploy.Status.ReadyReplicas > 0
+		newStatus := prometheusExporter.Status.DeepCopy()
+		newStatus.Ready = foundDeploy.Status.ReadyReplicas > 0 && !prometheusExporter.Spec.Suspend
+		newStatus.ExternalAddress = util.MetricsExternalAddress(prometheusExporter)
+		if apiReason != "" {
+			if condition := newStatus.GetCondition(solrv1beta1.ExporterRequiredAPIUnavailable); condition == nil || condition.Status != corev1.ConditionTrue || condition.Message != apiMessage {
+				r.recorder.Event(prometheusExporter, corev1.EventTypeWarning, string(solrv1beta1.ExporterRequiredAPIUnavailable), apiMessage)
+			}
+			newStatus.SetCondition(solrv1beta1.ExporterRequiredAPIUnavailable, corev1.ConditionTrue, apiReason, apiMessage)
+		} else if newStatus.GetCondition(solrv1beta1.ExporterRequiredAPIUnavailable) != nil {
+			newStatus.SetCondition(solrv1beta1.ExporterRequiredAPIUnavailable, corev1.ConditionFalse, "APIsAvailable", "All APIs required by the exporter are available")
+		}
+		if prometheusExporter.Spec.Suspend {
+			newStatus.SetCondition(solrv1beta1.ExporterSuspended, corev1.ConditionTrue, "Suspended", "The exporter Deployment has been scaled to zero replicas")
+		} else if newStatus.GetCondition(solrv1beta1.ExporterSuspended) != nil {
+			newStatus.SetCondition(solrv1beta1.ExporterSuspended, corev1.ConditionFalse, "Resumed", "The exporter Deployment has been scaled back to its previous replicas")
+		}
 
-		if ready != prometheusExporter.Status.Ready {
-			prometheusExporter.Status.Ready = ready
+		if !reflect.DeepEqual(prometheusExporter.Status, *newStatus) {
+			prometheusExporter.Status = *newStatus
 			r.Log.Info("Updating status for solr-prometheus-exporter", "namespace", prometheusExporter.Namespace, "name", prometheusExporter.Name)
 			err = r.Status().Update(context.TODO(), prometheusExporter)
 		}
@@ -155,26 +231,191 @@ func (r *SolrPrometheusExporterReconciler) Reconcile(req ctrl.Request) (ctrl.Res
 	return ctrl.Result{}, err
 }
 
-func getSolrConnectionInfo(r *SolrPrometheusExporterReconciler, prometheusExporter *solrv1beta1.SolrPrometheusExporter) (solrConnectionInfo util.SolrConnectionInfo, err error) {
+// reconcileMetricsIngress creates or updates the Ingress of the metrics Service when the exporter is addressable outside of the kube cluster, and deletes it otherwise.
+// The Ingress is managed in the legacy API version if the cluster does not serve the current one.
+func reconcileMetricsIngress(r *SolrPrometheusExporterReconciler, prometheusExporter *solrv1beta1.SolrPrometheusExporter) (err error) {
+	var foundIngress ingressObject = &netv1.Ingress{}
+	if useLegacyIngressAPI {
+		foundIngress = &extv1.Ingress{}
+	}
+	err = r.Get(context.TODO(), types.NamespacedName{Name: prometheusExporter.MetricsIngressName(), Namespace: prometheusExporter.Namespace}, foundIngress)
+
+	if prometheusExporter.Spec.External != nil {
+		generatedIngress := util.GenerateMetricsIngress(prometheusExporter)
+		var ingress ingressObject = generatedIngress
+		copyIngressFields := func() bool { return util.CopyMetricsIngressFields(generatedIngress, foundIngress.(*netv1.Ingress)) }
+		if useLegacyIngressAPI {
+			legacyIngress := util.LegacyIngress(generatedIngress)
+			ingress = legacyIngress
+			copyIngressFields = func() bool { return util.CopyLegacyIngressFields(legacyIngress, foundIngress.(*extv1.Ingress)) }
+		}
+		if err := controllerutil.SetControllerReference(prometheusExporter, ingress, r.scheme); err != nil {
+			return err
+		}
+
+		if err != nil && errors.IsNotFound(err) {
+			r.Log.Info("Creating PrometheusExporter Ingress", "namespace", ingress.GetNamespace(), "name", ingress.GetName())
+			err = r.Create(context.TODO(), ingress)
+		} else if err == nil && copyIngressFields() {
+			// Update the found Ingress and write the result back if there are any changes
+			r.Log.Info("Updating PrometheusExporter Ingress", "namespace", ingress.GetNamespace(), "name", ingress.GetName())
+			err = r.Update(context.TODO(), foundIngress)
+		}
+	} else if err == nil && metav1.IsControlledBy(foundIngress, prometheusExporter) {
+		// The Ingress is no longer needed
+		r.Log.Info("Deleting PrometheusExporter Ingress", "namespace", foundIngress.GetNamespace(), "name", foundIngress.GetName())
+		err = r.Delete(context.TODO(), foundIngress)
+	} else if errors.IsNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// reconcileMetricsServiceMonitor creates or updates the ServiceMonitor of the metrics Service when it is enabled, and deletes it otherwise.
+func reconcileMetricsServiceMonitor(r *SolrPrometheusExporterReconciler, prometheusExporter *solrv1beta1.SolrPrometheusExporter) (err error) {
+	foundServiceMonitor := &unstructured.Unstructured{}
+	foundServiceMonitor.SetGroupVersionKind(util.ServiceMonitorGVK)
+	err = r.Get(context.TODO(), types.NamespacedName{Name: prometheusExporter.MetricsServiceMonitorName(), Namespace: prometheusExporter.Namespace}, foundServiceMonitor)
+
+	if prometheusExporter.Spec.ServiceMonitor != nil && prometheusExporter.Spec.ServiceMonitor.Enabled {
+		serviceMonitor := util.GenerateSolrMetricsServiceMonitor(prometheusExporter)
+		if err := controllerutil.SetControllerReference(prometheusExporter, serviceMonitor, r.scheme); err != nil {
+			return err
+		}
+
+		if err != nil && errors.IsNotFound(err) {
+			r.Log.Info("Creating PrometheusExporter ServiceMonitor", "namespace", serviceMonitor.GetNamespace(), "name", serviceMonitor.GetName())
+			err = r.Create(context.TODO(), serviceMonitor)
+		} else if err == nil && util.CopyServiceMonitorFields(serviceMonitor, foundServiceMonitor) {
+			// Update the found ServiceMonitor and write the result back if there are any changes
+			r.Log.Info("Updating PrometheusExporter ServiceMonitor", "namespace", serviceMonitor.GetNamespace(), "name", serviceMonitor.GetName())
+			err = r.Update(context.TODO(), foundServiceMonitor)
+		}
+	} else if err == nil && metav1.IsControlledBy(foundServiceMonitor, prometheusExporter) {
+		// The ServiceMonitor is no longer needed
+		r.Log.Info("Deleting PrometheusExporter ServiceMonitor", "namespace", foundServiceMonitor.GetNamespace(), "name", foundServiceMonitor.GetName())
+		err = r.Delete(context.TODO(), foundServiceMonitor)
+	} else if errors.IsNotFound(err) {
+		err = nil
+	}
+	return err
+}
+
+// unavailableExporterAPIProblem returns the reason and message explaining which features of the exporter require an API that the Kubernetes cluster does not serve, if there are any
+func unavailableExporterAPIProblem(prometheusExporter *solrv1beta1.SolrPrometheusExporter) (reason string, message string) {
+	var reasons, messages []string
+	if prometheusExporter.Spec.External != nil && !useIngressAPI {
+		reasons = append(reasons, "IngressUnavailable")
+		messages = append(messages, fmt.Sprintf("Addressing the exporter through an Ingress requires the %s or %s %s API, which the Kubernetes cluster does not serve", util.IngressGroupVersion, util.LegacyIngressGroupVersion, util.IngressKind))
+	}
+	if prometheusExporter.Spec.ServiceMonitor != nil && prometheusExporter.Spec.ServiceMonitor.Enabled && !useServiceMonitorCRD {
+		reasons = append(reasons, "ServiceMonitorUnavailable")
+		messages = append(messages, "Cannot create a ServiceMonitor, as the ServiceMonitor CRD is not installed in the Kubernetes cluster")
+	}
+	return strings.Join(reasons, ","), strings.Join(messages, ". ")
+}
+
+// getReferencedSolrCloud returns the SolrCloud that the prometheusExporter references by name, if any.
+func getReferencedSolrCloud(r *SolrPrometheusExporterReconciler, prometheusExporter *solrv1beta1.SolrPrometheusExporter) (solrCloud *solrv1beta1.SolrCloud, err error) {
+	name, namespace := prometheusExporter.Spec.SolrReference.ReferencedSolrCloud()
+	if name == "" {
+		return nil, nil
+	}
+	solrCloud = &solrv1beta1.SolrCloud{}
+	if err = r.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, solrCloud); err != nil {
+		return nil, err
+	}
+	return solrCloud, nil
+}
+
+func getSolrConnectionInfo(prometheusExporter *solrv1beta1.SolrPrometheusExporter, solrCloud *solrv1beta1.SolrCloud) (solrConnectionInfo util.SolrConnectionInfo, err error) {
 	solrConnectionInfo = util.SolrConnectionInfo{}
 
+	// Connect with the keystore and truststore of the referenced SolrCloud, if no TLS options are provided.
+	// Solr is called over https when the exporter connects with TLS, or the referenced SolrCloud uses TLS.
+	prometheusExporter.Spec.SolrReference.SolrTLS = prometheusExporter.SolrTLSForSolrCloud(solrCloud)
+	urlScheme := "http"
+	if prometheusExporter.Spec.SolrReference.SolrTLS != nil || (solrCloud != nil && solrCloud.UrlScheme() == "https") {
+		urlScheme = "https"
+	}
+
 	if prometheusExporter.Spec.SolrReference.Standalone != nil {
-		solrConnectionInfo.StandaloneAddress = prometheusExporter.Spec.SolrReference.Standalone.Address
+		standaloneReference := prometheusExporter.Spec.SolrReference.Standalone
+		solrConnectionInfo.StandaloneAddress = standaloneReference.Address
+		if solrConnectionInfo.StandaloneAddress == "" && standaloneReference.Name != "" {
+			if solrCloud == nil {
+				return solrConnectionInfo, errors.NewNotFound(solrv1beta1.GroupVersion.WithResource("solrclouds").GroupResource(), standaloneReference.Name)
+			}
+			if !solrCloud.IsStandalone() {
+				return solrConnectionInfo, errors.NewBadRequest(fmt.Sprintf("SolrCloud %s/%s is not running in Standalone mode", solrCloud.Namespace, solrCloud.Name))
+			}
+			if solrCloud.Status.InternalCommonAddress == "" {
+				return solrConnectionInfo, fmt.Errorf("SolrCloud %s/%s does not have an address yet", solrCloud.Namespace, solrCloud.Name)
+			}
+			solrConnectionInfo.StandaloneAddress = solrCloud.Status.InternalCommonAddress + "/solr"
+		}
+
+		solrConnectionInfo.StandaloneAddress = addressWithUrlScheme(solrConnectionInfo.StandaloneAddress, urlScheme)
 	}
+	// The exporter reads the addresses of the Solr nodes of a cloud from ZooKeeper, where their scheme is given by the urlScheme cluster property.
+	// The Solr Operator sets it to https for SolrClouds using TLS, before their nodes start.
 	if prometheusExporter.Spec.SolrReference.Cloud != nil {
-		if prometheusExporter.Spec.SolrReference.Cloud.ZookeeperConnectionInfo != nil {
-			solrConnectionInfo.CloudZkConnnectionString = prometheusExporter.Spec.SolrReference.Cloud.ZookeeperConnectionInfo.ZkConnectionString()
-		} else if prometheusExporter.Spec.SolrReference.Cloud.Name != "" {
-			solrCloud := &solrv1beta1.SolrCloud{}
-			err = r.Get(context.TODO(), types.NamespacedName{Name: prometheusExporter.Spec.SolrReference.Cloud.Name, Namespace: prometheusExporter.Spec.SolrReference.Cloud.Namespace}, solrCloud)
-			if err == nil {
-				solrConnectionInfo.CloudZkConnnectionString = solrCloud.Status.ZookeeperConnectionInfo.ZkConnectionString()
+		cloudReference := prometheusExporter.Spec.SolrReference.Cloud
+		if cloudReference.ZookeeperConnectionInfo != nil {
+			solrConnectionInfo.CloudZkConnnectionString = cloudReference.ZookeeperConnectionInfo.ZkConnectionString()
+			solrConnectionInfo.CloudZkACL = cloudReference.ZookeeperConnectionInfo.ReaderACL()
+			solrConnectionInfo.CloudZkTLS = cloudReference.ZookeeperConnectionInfo.TLS
+		} else if cloudReference.Name != "" {
+			if solrCloud == nil {
+				return solrConnectionInfo, errors.NewNotFound(solrv1beta1.GroupVersion.WithResource("solrclouds").GroupResource(), cloudReference.Name)
+			}
+			if solrCloud.IsStandalone() {
+				return solrConnectionInfo, errors.NewBadRequest(fmt.Sprintf("SolrCloud %s/%s is running in Standalone mode, and must be referenced as a standalone Solr", solrCloud.Namespace, solrCloud.Name))
 			}
+			solrConnectionInfo.CloudZkConnnectionString = solrCloud.Status.ZookeeperConnectionInfo.ZkConnectionString()
+			solrConnectionInfo.CloudZkACL = solrCloud.Status.ZookeeperConnectionInfo.ReaderACL()
+			solrConnectionInfo.CloudZkTLS = solrCloud.Status.ZookeeperConnectionInfo.TLS
 		}
 	}
 	return solrConnectionInfo, err
 }
 
+// addressWithUrlScheme returns the address of Solr with the given scheme, which is added if the address does not have one.
+// An http address is switched to https, but an https address is never downgraded.
+func addressWithUrlScheme(address string, urlScheme string) string {
+	if !strings.Contains(address, "://") {
+		return urlScheme + "://" + address
+	}
+	if urlScheme == "https" && strings.HasPrefix(address, "http://") {
+		return "https://" + strings.TrimPrefix(address, "http://")
+	}
+	return address
+}
+
+// solrCloudToExporterRequests maps a SolrCloud to reconcile requests for all SolrPrometheusExporters that reference it
+func (r *SolrPrometheusExporterReconciler) solrCloudToExporterRequests(obj handler.MapObject) []reconcile.Request {
+	exporterList := &solrv1beta1.SolrPrometheusExporterList{}
+	if err := r.List(context.TODO(), exporterList); err != nil {
+		r.Log.Error(err, "Unable to list SolrPrometheusExporters for SolrCloud", "namespace", obj.Meta.GetNamespace(), "name", obj.Meta.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, exporter := range exporterList.Items {
+		cloudName, cloudNamespace := exporter.Spec.SolrReference.ReferencedSolrCloud()
+		if cloudName != obj.Meta.GetName() {
+			continue
+		}
+		// The namespace of the cloud reference defaults to the namespace of the exporter
+		if cloudNamespace == "" {
+			cloudNamespace = exporter.Namespace
+		}
+		if cloudNamespace == obj.Meta.GetNamespace() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: exporter.Name, Namespace: exporter.Namespace}})
+		}
+	}
+	return requests
+}
+
 func (r *SolrPrometheusExporterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return r.SetupWithManagerAndReconciler(mgr, r)
 }
@@ -184,8 +425,24 @@ func (r *SolrPrometheusExporterReconciler) SetupWithManagerAndReconciler(mgr ctr
 		For(&solrv1beta1.SolrPrometheusExporter{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Service{}).
-		Owns(&appsv1.Deployment{})
+		Owns(&appsv1.Deployment{}).
+		Watches(&source.Kind{Type: &solrv1beta1.SolrCloud{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.solrCloudToExporterRequests),
+		})
+
+	if useIngressAPI && useLegacyIngressAPI {
+		ctrlBuilder = ctrlBuilder.Owns(&extv1.Ingress{})
+	} else if useIngressAPI {
+		ctrlBuilder = ctrlBuilder.Owns(&netv1.Ingress{})
+	}
+
+	if useServiceMonitorCRD {
+		serviceMonitor := &unstructured.Unstructured{}
+		serviceMonitor.SetGroupVersionKind(util.ServiceMonitorGVK)
+		ctrlBuilder = ctrlBuilder.Owns(serviceMonitor)
+	}
 
 	r.scheme = mgr.GetScheme()
+	r.recorder = mgr.GetEventRecorderFor("solrprometheusexporter-controller")
 	return ctrlBuilder.Complete(reconciler)
 }
@@ -17,6 +17,11 @@ limitations under the License.
 package controllers
 
 import (
+	"crypto/md5"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
@@ -24,10 +29,19 @@ import (
 	"github.com/onsi/gomega"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	extv1 "k8s.io/api/extensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -188,7 +202,8 @@ func TestMetricsReconcileWithExporterConfig(t *testing.T) {
 	testMapsEqual(t, "deployment labels", util.MergeLabelsOrAnnotations(expectedDeploymentLabels, testDeploymentLabels), deployment.Labels)
 	testMapsEqual(t, "deployment annotations", testDeploymentAnnotations, deployment.Annotations)
 	testMapsEqual(t, "pod labels", util.MergeLabelsOrAnnotations(expectedDeploymentLabels, testPodLabels), deployment.Spec.Template.ObjectMeta.Labels)
-	testMapsEqual(t, "pod annotations", testPodAnnotations, deployment.Spec.Template.ObjectMeta.Annotations)
+	expectedPodAnnotations := util.MergeLabelsOrAnnotations(testPodAnnotations, map[string]string{util.PrometheusExporterConfigXmlMd5Annotation: fmt.Sprintf("%x", md5.Sum([]byte(testExporterConfig)))})
+	testMapsEqual(t, "pod annotations", expectedPodAnnotations, deployment.Spec.Template.ObjectMeta.Annotations)
 
 	// Test tolerations and node selectors
 	testMapsEqual(t, "pod node selectors", testNodeSelectors, deployment.Spec.Template.Spec.NodeSelector)
@@ -210,3 +225,1181 @@ func TestMetricsReconcileWithExporterConfig(t *testing.T) {
 	testMapsEqual(t, "service annotations", util.MergeLabelsOrAnnotations(expectedServiceAnnotations, testMetricsServiceAnnotations), service.Annotations)
 	assert.EqualValues(t, "solr-metrics", service.Spec.Ports[0].Name, "Wrong port name on common Service")
 }
+
+func TestMetricsReconcileWithSolrTLS(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{
+				Standalone: &solr.StandaloneSolrReference{
+					Address: "http://test-solr:8983/solr",
+				},
+				SolrTLS: &solr.SolrTLSOptions{
+					TrustStoreSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "solr-tls"},
+						Key:                  "truststore.p12",
+					},
+					TrustStorePasswordSecret: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "solr-tls"},
+						Key:                  "truststore-password",
+					},
+				},
+			},
+		},
+	}
+
+	// Setup the Manager and Controller.  Wrap the Controller Reconcile function so it writes each request to a
+	// channel when it is finished.
+	mgr, err := manager.New(testCfg, manager.Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	testClient = mgr.GetClient()
+
+	solrPrometheusExporterReconciler := &SolrPrometheusExporterReconciler{
+		Client: testClient,
+		Log:    ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+	}
+	newRec, requests := SetupTestReconcile(solrPrometheusExporterReconciler)
+	g.Expect(solrPrometheusExporterReconciler.SetupWithManagerAndReconciler(mgr, newRec)).NotTo(gomega.HaveOccurred())
+
+	stopMgr, mgrStopped := StartTestManager(mgr, g)
+
+	defer func() {
+		close(stopMgr)
+		mgrStopped.Wait()
+	}()
+
+	cleanupTest(g, instance.Namespace)
+
+	// Create the SolrPrometheusExporter object and expect the Reconcile and Deployment to be created
+	err = testClient.Create(context.TODO(), instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer testClient.Delete(context.TODO(), instance)
+	g.Eventually(requests, timeout).Should(gomega.Receive(gomega.Equal(expectedMetricsRequest)))
+
+	deployment := expectDeployment(t, g, requests, expectedMetricsRequest, metricsDKey, "")
+
+	// TLS Checks
+	assert.Equal(t, 1, len(deployment.Spec.Template.Spec.Volumes), "Pod has wrong number of volumes")
+	assert.Equal(t, "solr-tls", deployment.Spec.Template.Spec.Volumes[0].Secret.SecretName, "Truststore secret not loaded into pod properly.")
+	assert.Equal(t, util.SolrTLSTrustStorePath, deployment.Spec.Template.Spec.Containers[0].VolumeMounts[0].MountPath, "Truststore not mounted into container properly.")
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "https://test-solr:8983/solr", "Standalone address does not use https")
+	expectedEnvVars := map[string]string{
+		"SOLR_SSL_TRUST_STORE_PASSWORD": "",
+		"JAVA_OPTS":                     "-Djavax.net.ssl.trustStore=/var/solr/tls/truststore/truststore.p12 -Djavax.net.ssl.trustStorePassword=$(SOLR_SSL_TRUST_STORE_PASSWORD)",
+	}
+	testPodEnvVariables(t, expectedEnvVars, deployment.Spec.Template.Spec.Containers[0].Env)
+}
+
+func TestMetricsServiceMonitorGeneration(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			ServiceMonitor: &solr.ServiceMonitorOptions{
+				Enabled:       true,
+				Interval:      "30s",
+				ScrapeTimeout: "10s",
+				Labels:        map[string]string{"release": "prometheus"},
+			},
+		},
+	}
+
+	serviceMonitor := util.GenerateSolrMetricsServiceMonitor(instance)
+	assert.Equal(t, util.ServiceMonitorGVK, serviceMonitor.GroupVersionKind(), "Wrong GroupVersionKind for the ServiceMonitor")
+	assert.Equal(t, metricsSKey.Name, serviceMonitor.GetName(), "Wrong name for the ServiceMonitor")
+	testMapsEqual(t, "serviceMonitor labels", util.MergeLabelsOrAnnotations(instance.SharedLabels(), map[string]string{"release": "prometheus"}), serviceMonitor.GetLabels())
+
+	// The ServiceMonitor must select the metrics Service
+	service := util.GenerateSolrMetricsService(instance)
+	selector, _, _ := unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+	for k, v := range selector {
+		assert.Equal(t, v, service.Labels[k], "ServiceMonitor selector does not match the metrics Service label '%s'", k)
+	}
+
+	endpoints, _, _ := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+	assert.Equal(t, 1, len(endpoints), "Wrong number of ServiceMonitor endpoints")
+	endpoint := endpoints[0].(map[string]interface{})
+	assert.Equal(t, "solr-metrics", endpoint["port"], "Wrong port for the ServiceMonitor endpoint")
+	assert.Equal(t, "30s", endpoint["interval"], "Wrong interval for the ServiceMonitor endpoint")
+	assert.Equal(t, "10s", endpoint["scrapeTimeout"], "Wrong scrapeTimeout for the ServiceMonitor endpoint")
+
+	// Regenerating the ServiceMonitor should not require an update
+	assert.False(t, util.CopyServiceMonitorFields(util.GenerateSolrMetricsServiceMonitor(instance), serviceMonitor), "Unchanged ServiceMonitor should not require an update")
+}
+
+func TestMetricsDeploymentJavaOptions(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			JavaMemory: "1g",
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				PodOptions: &solr.PodOptions{
+					EnvVariables: []corev1.EnvVar{{Name: "JAVA_OPTS", Value: "-XX:+UseG1GC"}},
+				},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	expectedEnvVars := map[string]string{
+		"JAVA_HEAP": "1g",
+		"JAVA_OPTS": "-XX:+UseG1GC",
+	}
+	assert.Equal(t, len(expectedEnvVars), len(deployment.Spec.Template.Spec.Containers[0].Env), "Wrong number of env variables in exporter container")
+	testPodEnvVariables(t, expectedEnvVars, deployment.Spec.Template.Spec.Containers[0].Env)
+
+	// User provided env vars should override the ones set by the operator
+	instance.Spec.CustomKubeOptions.PodOptions.EnvVariables = append(instance.Spec.CustomKubeOptions.PodOptions.EnvVariables, corev1.EnvVar{Name: "JAVA_HEAP", Value: "2g"})
+	deployment = util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	expectedEnvVars["JAVA_HEAP"] = "2g"
+	assert.Equal(t, len(expectedEnvVars), len(deployment.Spec.Template.Spec.Containers[0].Env), "Wrong number of env variables in exporter container")
+	testPodEnvVariables(t, expectedEnvVars, deployment.Spec.Template.Spec.Containers[0].Env)
+}
+
+func TestMetricsServiceCustomPort(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				ServiceOptions: &solr.ServiceOptions{
+					Annotations: map[string]string{"prometheus.io/port": "1234", "mesh.example.com/inject": "true"},
+					Port:        9090,
+				},
+			},
+		},
+	}
+
+	service := util.GenerateSolrMetricsService(instance)
+	assert.EqualValues(t, 9090, service.Spec.Ports[0].Port, "Wrong port on metrics Service")
+	assert.EqualValues(t, util.SolrMetricsPort, service.Spec.Ports[0].TargetPort.IntVal, "Wrong targetPort on metrics Service")
+	assert.Equal(t, "9090", service.Annotations["prometheus.io/port"], "The prometheus.io/port annotation must match the Service port")
+	assert.Equal(t, "true", service.Annotations["mesh.example.com/inject"], "Custom annotation not added to the metrics Service")
+
+	// Annotations added to the Service outside of the operator should not be removed
+	foundService := service.DeepCopy()
+	foundService.Annotations["external.example.com/annotation"] = "value"
+	assert.False(t, util.CopyServiceFields(util.GenerateSolrMetricsService(instance), foundService), "Unchanged metrics Service should not require an update")
+	assert.Equal(t, "value", foundService.Annotations["external.example.com/annotation"], "Externally added annotation was removed from the metrics Service")
+
+	// Session affinity should be applied to the metrics Service
+	instance.Spec.CustomKubeOptions.ServiceOptions.SessionAffinity = corev1.ServiceAffinityClientIP
+	service = util.GenerateSolrMetricsService(instance)
+	assert.Equal(t, corev1.ServiceAffinityClientIP, service.Spec.SessionAffinity, "Wrong sessionAffinity for the metrics Service")
+	assert.True(t, util.CopyServiceFields(service, foundService), "Changed sessionAffinity should require an update")
+	assert.Equal(t, corev1.ServiceAffinityClientIP, foundService.Spec.SessionAffinity, "SessionAffinity not updated")
+}
+
+func TestMetricsDeploymentPodOptions(t *testing.T) {
+	sidecar := corev1.Container{Name: "sidecar", Image: "busybox:1.28.0-glibc"}
+	initContainer := corev1.Container{Name: "init", Image: "busybox:1.28.0-glibc", Command: []string{"echo", "init"}}
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				PodOptions: &solr.PodOptions{
+					Annotations:        testPodAnnotations,
+					Labels:             testPodLabels,
+					EnvVariables:       extraVars,
+					Tolerations:        testTolerationsPromExporter,
+					NodeSelector:       testNodeSelectors,
+					ServiceAccountName: "test-service-account",
+					PriorityClassName:  "test-priority-class",
+					SchedulerName:      "test-scheduler",
+					SidecarContainers:  []corev1.Container{sidecar},
+					InitContainers:     []corev1.Container{initContainer},
+				},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	podSpec := deployment.Spec.Template.Spec
+	assert.Equal(t, "test-service-account", podSpec.ServiceAccountName, "Wrong serviceAccountName for the exporter pod")
+	assert.Equal(t, "test-priority-class", podSpec.PriorityClassName, "Wrong priorityClassName for the exporter pod")
+	assert.Equal(t, "test-scheduler", podSpec.SchedulerName, "Wrong schedulerName for the exporter pod")
+	assert.Nil(t, podSpec.RuntimeClassName, "No runtimeClassName should be set by default")
+	assert.Equal(t, 2, len(podSpec.Containers), "Sidecar container not added to the exporter pod")
+	assert.Equal(t, "solr-prometheus-exporter", podSpec.Containers[0].Name, "The exporter container must be the first container")
+	assert.Equal(t, sidecar, podSpec.Containers[1], "Sidecar container not added to the exporter pod properly")
+	assert.Equal(t, []corev1.Container{initContainer}, podSpec.InitContainers, "Init containers not added to the exporter pod properly")
+	assert.Equal(t, extraVars, podSpec.Containers[0].Env, "Extra Env Vars are not the same as the ones provided in podOptions")
+	testMapsEqual(t, "pod node selectors", testNodeSelectors, podSpec.NodeSelector)
+	testPodTolerations(t, testTolerationsPromExporter, podSpec.Tolerations)
+	testMapsEqual(t, "pod annotations", testPodAnnotations, deployment.Spec.Template.Annotations)
+	for k, v := range testPodLabels {
+		assert.Equal(t, v, deployment.Spec.Template.Labels[k], "Pod label '%s' not added to the exporter pod", k)
+	}
+
+	// Changing the pod options must be picked up by the copy logic
+	foundDeployment := deployment.DeepCopy()
+	instance.Spec.CustomKubeOptions.PodOptions.PriorityClassName = "other-priority-class"
+	runtimeClassName := "kata"
+	instance.Spec.CustomKubeOptions.PodOptions.RuntimeClassName = &runtimeClassName
+	instance.Spec.CustomKubeOptions.PodOptions.InitContainers = nil
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed podOptions should require an update")
+	assert.Equal(t, "other-priority-class", foundDeployment.Spec.Template.Spec.PriorityClassName, "PriorityClassName not updated")
+	assert.Equal(t, &runtimeClassName, foundDeployment.Spec.Template.Spec.RuntimeClassName, "RuntimeClassName not updated")
+	assert.Empty(t, foundDeployment.Spec.Template.Spec.InitContainers, "InitContainers not updated")
+
+	// Additional pull secrets are added to the one of the exporter image
+	instance.Spec.Image.ImagePullSecret = "exporter-registry"
+	instance.Spec.CustomKubeOptions.PodOptions.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "sidecar-registry"}}
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed pull secrets should require an update")
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "exporter-registry"}, {Name: "sidecar-registry"}}, foundDeployment.Spec.Template.Spec.ImagePullSecrets, "ImagePullSecrets not updated")
+
+	// EnvFrom sources are added to the exporter container
+	envFrom := []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "exporter-credentials"}}}}
+	instance.Spec.CustomKubeOptions.PodOptions.EnvFrom = envFrom
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed envFrom should require an update")
+	assert.Equal(t, envFrom, foundDeployment.Spec.Template.Spec.Containers[0].EnvFrom, "EnvFrom not updated")
+
+	// Lifecycle hooks are set on the exporter container
+	lifecycle := &corev1.Lifecycle{PreStop: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"/opt/catalog/deregister.sh"}}}}
+	instance.Spec.CustomKubeOptions.PodOptions.Lifecycle = lifecycle
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed lifecycle should require an update")
+	assert.Equal(t, lifecycle, foundDeployment.Spec.Template.Spec.Containers[0].Lifecycle, "Lifecycle not updated")
+}
+
+func TestMetricsReconcileConfigChange(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			Config: testExporterConfig,
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				PodOptions: &solr.PodOptions{
+					Annotations: testPodAnnotations,
+				},
+			},
+		},
+	}
+
+	// Setup the Manager and Controller.  Wrap the Controller Reconcile function so it writes each request to a
+	// channel when it is finished.
+	mgr, err := manager.New(testCfg, manager.Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	testClient = mgr.GetClient()
+
+	solrPrometheusExporterReconciler := &SolrPrometheusExporterReconciler{
+		Client: testClient,
+		Log:    ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+	}
+	newRec, requests := SetupTestReconcile(solrPrometheusExporterReconciler)
+	g.Expect(solrPrometheusExporterReconciler.SetupWithManagerAndReconciler(mgr, newRec)).NotTo(gomega.HaveOccurred())
+
+	stopMgr, mgrStopped := StartTestManager(mgr, g)
+
+	defer func() {
+		close(stopMgr)
+		mgrStopped.Wait()
+	}()
+
+	cleanupTest(g, instance.Namespace)
+
+	// Create the SolrPrometheusExporter object and expect the Reconcile and Deployment to be created
+	err = testClient.Create(context.TODO(), instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer testClient.Delete(context.TODO(), instance)
+	g.Eventually(requests, timeout).Should(gomega.Receive(gomega.Equal(expectedMetricsRequest)))
+
+	configMap := expectConfigMap(t, g, requests, expectedMetricsRequest, metricsCMKey, map[string]string{util.PrometheusExporterConfigMapKey: testExporterConfig})
+	deployment := expectDeployment(t, g, requests, expectedMetricsRequest, metricsDKey, configMap.Name)
+	originalConfigXmlMd5 := deployment.Spec.Template.Annotations[util.PrometheusExporterConfigXmlMd5Annotation]
+	assert.Equal(t, fmt.Sprintf("%x", md5.Sum([]byte(testExporterConfig))), originalConfigXmlMd5, "Wrong config hash on the exporter pod template")
+
+	// Change the config and expect the pod template to change with it
+	newConfig := testExporterConfig + " Now updated."
+	g.Expect(testClient.Get(context.TODO(), expectedMetricsRequest.NamespacedName, instance)).NotTo(gomega.HaveOccurred())
+	instance.Spec.Config = newConfig
+	g.Expect(testClient.Update(context.TODO(), instance)).NotTo(gomega.HaveOccurred())
+	g.Eventually(requests, timeout).Should(gomega.Receive(gomega.Equal(expectedMetricsRequest)))
+
+	expectConfigMap(t, g, requests, expectedMetricsRequest, metricsCMKey, map[string]string{util.PrometheusExporterConfigMapKey: newConfig})
+	g.Eventually(func() (string, error) {
+		err := testClient.Get(context.TODO(), metricsDKey, deployment)
+		return deployment.Spec.Template.Annotations[util.PrometheusExporterConfigXmlMd5Annotation], err
+	}, timeout).Should(gomega.Equal(fmt.Sprintf("%x", md5.Sum([]byte(newConfig)))))
+	for k, v := range testPodAnnotations {
+		assert.Equal(t, v, deployment.Spec.Template.Annotations[k], "Pod annotation '%s' removed from the exporter pod", k)
+	}
+
+	// The hash should not be added when no custom config is used
+	instance.Spec.Config = ""
+	assert.NotContains(t, util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "").Spec.Template.Annotations, util.PrometheusExporterConfigXmlMd5Annotation, "No config hash should be set without a custom config")
+}
+
+func TestMetricsDeploymentRestrictedSecurityContext(t *testing.T) {
+	runAsNonRoot := true
+	runAsUser := int64(8983)
+	allowPrivilegeEscalation := false
+	podSecurityContext := &corev1.PodSecurityContext{
+		RunAsNonRoot:   &runAsNonRoot,
+		RunAsUser:      &runAsUser,
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	containerSecurityContext := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+	}
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec:       solr.SolrPrometheusExporterSpec{},
+	}
+	instance.WithDefaults()
+
+	// Without any podOptions, the default fsGroup is used
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	assert.NotNil(t, deployment.Spec.Template.Spec.SecurityContext.FSGroup, "The default fsGroup should be set when no podSecurityContext is provided")
+	assert.Nil(t, deployment.Spec.Template.Spec.Containers[0].SecurityContext, "No container securityContext should be set by default")
+
+	instance.Spec.CustomKubeOptions.PodOptions = &solr.PodOptions{
+		PodSecurityContext:       podSecurityContext,
+		ContainerSecurityContext: containerSecurityContext,
+		ServiceAccountName:       "restricted-service-account",
+	}
+	deployment = util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	assert.Equal(t, podSecurityContext, deployment.Spec.Template.Spec.SecurityContext, "The provided podSecurityContext was not used for the exporter pod")
+	assert.Nil(t, deployment.Spec.Template.Spec.SecurityContext.FSGroup, "The default fsGroup should not be added to a provided podSecurityContext")
+	assert.Equal(t, containerSecurityContext, deployment.Spec.Template.Spec.Containers[0].SecurityContext, "The provided containerSecurityContext was not used for the exporter container")
+	assert.Equal(t, "restricted-service-account", deployment.Spec.Template.Spec.ServiceAccountName, "Wrong serviceAccountName for the exporter pod")
+
+	// The restricted spec must round-trip without the operator changing it
+	foundDeployment := deployment.DeepCopy()
+	assert.False(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "An unchanged restricted spec should not require an update")
+	assert.Nil(t, foundDeployment.Spec.Template.Spec.SecurityContext.FSGroup, "The default fsGroup was re-added to the exporter pod")
+
+	// Changing the container securityContext must be picked up by the copy logic
+	readOnlyRootFilesystem := true
+	instance.Spec.CustomKubeOptions.PodOptions.ContainerSecurityContext = &corev1.SecurityContext{ReadOnlyRootFilesystem: &readOnlyRootFilesystem}
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed containerSecurityContext should require an update")
+	assert.Equal(t, instance.Spec.CustomKubeOptions.PodOptions.ContainerSecurityContext, foundDeployment.Spec.Template.Spec.Containers[0].SecurityContext, "Container securityContext not updated")
+}
+
+func TestMetricsDeploymentDNSOptions(t *testing.T) {
+	ndots := "1"
+	dnsConfig := &corev1.PodDNSConfig{
+		Searches: []string{"zk.other-cluster.example.com"},
+		Options:  []corev1.PodDNSConfigOption{{Name: "ndots", Value: &ndots}},
+	}
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				PodOptions: &solr.PodOptions{},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	assert.Empty(t, deployment.Spec.Template.Spec.DNSPolicy, "No dnsPolicy should be set by default")
+	assert.Nil(t, deployment.Spec.Template.Spec.DNSConfig, "No dnsConfig should be set by default")
+
+	instance.Spec.CustomKubeOptions.PodOptions.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	instance.Spec.CustomKubeOptions.PodOptions.DNSConfig = dnsConfig
+	foundDeployment := deployment.DeepCopy()
+	deployment = util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	assert.Equal(t, corev1.DNSClusterFirstWithHostNet, deployment.Spec.Template.Spec.DNSPolicy, "Wrong dnsPolicy for the exporter pod")
+	assert.Equal(t, dnsConfig, deployment.Spec.Template.Spec.DNSConfig, "Wrong dnsConfig for the exporter pod")
+
+	// Changing the DNS options must be picked up by the copy logic
+	assert.True(t, util.CopyDeploymentFields(deployment, foundDeployment), "Changed DNS options should require an update")
+	assert.Equal(t, corev1.DNSClusterFirstWithHostNet, foundDeployment.Spec.Template.Spec.DNSPolicy, "DNSPolicy not updated")
+	assert.Equal(t, dnsConfig, foundDeployment.Spec.Template.Spec.DNSConfig, "DNSConfig not updated")
+}
+
+func TestMetricsDeploymentHostAliases(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				PodOptions: &solr.PodOptions{},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	foundDeployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	assert.Empty(t, foundDeployment.Spec.Template.Spec.HostAliases, "No hostAliases should be set by default")
+
+	// Host aliases are split into one hostname each, so that they are generated deterministically
+	instance.Spec.CustomKubeOptions.PodOptions.HostAliases = []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"solr-2.onprem", "solr-1.onprem"}}}
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed host aliases should require an update")
+	assert.Equal(t, []corev1.HostAlias{
+		{IP: "10.0.0.1", Hostnames: []string{"solr-1.onprem"}},
+		{IP: "10.0.0.1", Hostnames: []string{"solr-2.onprem"}},
+	}, foundDeployment.Spec.Template.Spec.HostAliases, "HostAliases not updated")
+	assert.False(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Unchanged host aliases should not require an update")
+}
+
+func TestMetricsDeploymentOptions(t *testing.T) {
+	maxUnavailable := intstr.FromInt(1)
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				DeploymentOptions: &solr.DeploymentOptions{
+					Annotations: testDeploymentAnnotations,
+					Labels:      testDeploymentLabels,
+					Strategy:    &appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+				},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	assert.Equal(t, appsv1.RecreateDeploymentStrategyType, deployment.Spec.Strategy.Type, "Wrong deployment strategy for the exporter")
+	assert.Nil(t, deployment.Spec.Strategy.RollingUpdate, "No rollingUpdate options should be set for the Recreate strategy")
+	testMapsEqual(t, "deployment annotations", testDeploymentAnnotations, deployment.Annotations)
+	for k, v := range testDeploymentLabels {
+		assert.Equal(t, v, deployment.Labels[k], "Deployment label '%s' not added to the exporter deployment", k)
+	}
+
+	// Annotations added to the deployment externally must not be removed
+	foundDeployment := deployment.DeepCopy()
+	foundDeployment.Annotations["external"] = "annotation"
+	assert.False(t, util.CopyDeploymentFields(deployment, foundDeployment), "No update should be required for an unchanged deployment")
+	assert.Equal(t, "annotation", foundDeployment.Annotations["external"], "Externally added annotation removed from the deployment")
+
+	// A deployment without a strategy should not overwrite the strategy defaulted by Kubernetes
+	instance.Spec.CustomKubeOptions.DeploymentOptions.Strategy = nil
+	assert.False(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "An unset strategy should not require an update")
+	assert.Equal(t, appsv1.RecreateDeploymentStrategyType, foundDeployment.Spec.Strategy.Type, "Deployment strategy should not be changed when unset")
+
+	// Changing the strategy must be picked up by the copy logic, ignoring options defaulted by Kubernetes
+	instance.Spec.CustomKubeOptions.DeploymentOptions.Strategy = &appsv1.DeploymentStrategy{
+		Type:          appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable},
+	}
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Changed strategy should require an update")
+	assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, foundDeployment.Spec.Strategy.Type, "Deployment strategy not updated")
+	defaultedSurge := intstr.FromString("25%")
+	foundDeployment.Spec.Strategy.RollingUpdate.MaxSurge = &defaultedSurge
+	assert.False(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, ""), foundDeployment), "Defaulted rollingUpdate options should not require an update")
+}
+
+func TestMetricsDeploymentProbes(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+	}
+	instance.WithDefaults()
+
+	defaultHandler := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Scheme: corev1.URISchemeHTTP,
+			Path:   "/metrics",
+			Port:   intstr.FromInt(util.SolrMetricsPort),
+		},
+	}
+
+	// Default probes
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	testPodProbe(t, &corev1.Probe{
+		InitialDelaySeconds: 20,
+		TimeoutSeconds:      1,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+		PeriodSeconds:       10,
+		Handler:             defaultHandler,
+	}, deployment.Spec.Template.Spec.Containers[0].LivenessProbe)
+	testPodProbe(t, &corev1.Probe{
+		InitialDelaySeconds: 15,
+		TimeoutSeconds:      1,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+		PeriodSeconds:       5,
+		Handler:             defaultHandler,
+	}, deployment.Spec.Template.Spec.Containers[0].ReadinessProbe)
+	assert.Nil(t, deployment.Spec.Template.Spec.Containers[0].StartupProbe, "No startup probe should be set by default")
+
+	// Partial probes are merged over the defaults
+	instance.Spec.CustomKubeOptions.PodOptions = &solr.PodOptions{
+		LivenessProbe:  &corev1.Probe{TimeoutSeconds: 5},
+		ReadinessProbe: &corev1.Probe{PeriodSeconds: 30, FailureThreshold: 10},
+		StartupProbe:   testProbeStartup,
+	}
+	deployment = util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{}, "")
+	testPodProbe(t, &corev1.Probe{
+		InitialDelaySeconds: 20,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+		PeriodSeconds:       10,
+		Handler:             defaultHandler,
+	}, deployment.Spec.Template.Spec.Containers[0].LivenessProbe)
+	testPodProbe(t, &corev1.Probe{
+		InitialDelaySeconds: 15,
+		TimeoutSeconds:      1,
+		SuccessThreshold:    1,
+		FailureThreshold:    10,
+		PeriodSeconds:       30,
+		Handler:             defaultHandler,
+	}, deployment.Spec.Template.Spec.Containers[0].ReadinessProbe)
+	testPodProbe(t, testProbeStartup, deployment.Spec.Template.Spec.Containers[0].StartupProbe)
+}
+
+func TestMetricsReconcileWithSolrCloudImage(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-cloud", Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrImage: &solr.ContainerImage{
+				Repository: "test-repo",
+				Tag:        "8.6.0",
+			},
+		},
+	}
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{
+				Cloud: &solr.SolrCloudReference{
+					Name: solrCloud.Name,
+				},
+			},
+		},
+	}
+
+	// Setup the Manager and Controller.  Wrap the Controller Reconcile function so it writes each request to a
+	// channel when it is finished.
+	mgr, err := manager.New(testCfg, manager.Options{})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	testClient = mgr.GetClient()
+
+	solrPrometheusExporterReconciler := &SolrPrometheusExporterReconciler{
+		Client: testClient,
+		Log:    ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+	}
+	newRec, requests := SetupTestReconcile(solrPrometheusExporterReconciler)
+	g.Expect(solrPrometheusExporterReconciler.SetupWithManagerAndReconciler(mgr, newRec)).NotTo(gomega.HaveOccurred())
+
+	stopMgr, mgrStopped := StartTestManager(mgr, g)
+
+	defer func() {
+		close(stopMgr)
+		mgrStopped.Wait()
+	}()
+
+	cleanupTest(g, instance.Namespace)
+
+	g.Expect(testClient.Create(context.TODO(), solrCloud)).NotTo(gomega.HaveOccurred())
+	defer testClient.Delete(context.TODO(), solrCloud)
+
+	// Create the SolrPrometheusExporter object and expect the Reconcile and Deployment to be created
+	err = testClient.Create(context.TODO(), instance)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	defer testClient.Delete(context.TODO(), instance)
+	g.Eventually(requests, timeout).Should(gomega.Receive(gomega.Equal(expectedMetricsRequest)))
+
+	// The image is not defaulted in the spec, since it is taken from the SolrCloud
+	foundInstance := &solr.SolrPrometheusExporter{}
+	g.Expect(testClient.Get(context.TODO(), expectedMetricsRequest.NamespacedName, foundInstance)).NotTo(gomega.HaveOccurred())
+	assert.Nil(t, foundInstance.Spec.Image, "The exporter image should not be defaulted when it references a SolrCloud")
+
+	deployment := expectDeployment(t, g, requests, expectedMetricsRequest, metricsDKey, "")
+	assert.Equal(t, "test-repo:8.6.0", deployment.Spec.Template.Spec.Containers[0].Image, "The exporter should use the image of the referenced SolrCloud")
+}
+
+func TestMetricsSolrTLSFromSolrCloud(t *testing.T) {
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-cloud", Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrCloudSpec{
+			SolrTLS: &solr.SolrServerTLSOptions{
+				PKCS12Secret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls"},
+					Key:                  "keystore.p12",
+				},
+				KeyStorePasswordSecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "foo-tls-password"},
+					Key:                  "password",
+				},
+			},
+		},
+	}
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{
+				Cloud: &solr.SolrCloudReference{Name: solrCloud.Name},
+			},
+		},
+	}
+
+	// The truststore of the SolrCloud is used, without a client certificate unless the SolrCloud asks for one
+	tlsOptions := instance.SolrTLSForSolrCloud(solrCloud)
+	if assert.NotNil(t, tlsOptions, "The exporter should use the TLS settings of the SolrCloud") {
+		assert.Equal(t, "foo-tls", tlsOptions.TrustStoreSecret.Name, "The keystore of the SolrCloud should be the truststore")
+		assert.Equal(t, "foo-tls-password", tlsOptions.TrustStorePasswordSecret.Name, "Wrong truststore password")
+		assert.Nil(t, tlsOptions.KeyStoreSecret, "No client certificate should be presented without client authentication")
+	}
+
+	// With client authentication, the keystore of the SolrCloud is presented as the client certificate
+	solrCloud.Spec.SolrTLS.NeedClientAuth = true
+	instance.Spec.SolrReference.SolrTLS = instance.SolrTLSForSolrCloud(solrCloud)
+	instance.Spec.Image = instance.ImageForSolrCloud(solrCloud)
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{CloudZkConnnectionString: "host:2181/"}, "")
+	testPodEnvVariables(t, map[string]string{
+		"JAVA_OPTS": "-Djavax.net.ssl.trustStore=/var/solr/tls/truststore/keystore.p12 -Djavax.net.ssl.trustStorePassword=$(SOLR_SSL_TRUST_STORE_PASSWORD) " +
+			"-Djavax.net.ssl.keyStore=/var/solr/tls/keystore/keystore.p12 -Djavax.net.ssl.keyStorePassword=$(SOLR_SSL_KEY_STORE_PASSWORD)",
+	}, deployment.Spec.Template.Spec.Containers[0].Env)
+
+	// The provided TLS settings of the exporter take precedence
+	provided := &solr.SolrTLSOptions{TrustStoreSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "exporter-trust"}, Key: "truststore.p12"}}
+	instance.Spec.SolrReference.SolrTLS = provided
+	assert.Equal(t, provided, instance.SolrTLSForSolrCloud(solrCloud), "The provided TLS settings should be used")
+
+	// The Secrets of a SolrCloud in another namespace, or built within its pods, cannot be used
+	instance.Spec.SolrReference.SolrTLS = nil
+	solrCloud.Namespace = "other"
+	assert.Nil(t, instance.SolrTLSForSolrCloud(solrCloud), "The Secrets of a SolrCloud in another namespace should not be used")
+	solrCloud.Namespace = instance.Namespace
+	solrCloud.Spec.SolrTLS = &solr.SolrServerTLSOptions{CertManager: &solr.SolrCertManagerOptions{IssuerRef: solr.SolrCertManagerIssuerRef{Name: "ca-issuer"}}}
+	assert.Nil(t, instance.SolrTLSForSolrCloud(solrCloud), "The keystore built from a cert-manager Certificate should not be used")
+}
+
+func TestMetricsZkACL(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{
+				Cloud: &solr.SolrCloudReference{
+					ZookeeperConnectionInfo: &solr.ZookeeperConnectionInfo{
+						InternalConnectionString: "host:2181",
+						AllACL:                   &solr.ZookeeperACL{SecretRef: "zk-acl"},
+					},
+				},
+				SolrTLS: &solr.SolrTLSOptions{
+					TrustStoreSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "solr-tls"}, Key: "truststore.p12"},
+				},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	// The exporter reads the znodes with the acl when there is no readOnlyAcl, next to its TLS settings
+	solrConnectionInfo, err := getSolrConnectionInfo(instance, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "zk-acl", solrConnectionInfo.CloudZkACL.SecretRef, "The exporter should read the znodes with the acl")
+	instance.Spec.Image = instance.ImageForSolrCloud(nil)
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, solrConnectionInfo, "")
+	testPodEnvVariables(t, map[string]string{
+		"SOLR_ZK_CREDS_AND_ACLS": "-DzkCredentialsProvider=" + util.ZkCredentialsProvider + " -DzkDigestUsername=$(ZK_READ_ACL_USERNAME) -DzkDigestPassword=$(ZK_READ_ACL_PASSWORD)",
+		"JAVA_OPTS":              "-Djavax.net.ssl.trustStore=/var/solr/tls/truststore/truststore.p12 $(SOLR_ZK_CREDS_AND_ACLS)",
+	}, deployment.Spec.Template.Spec.Containers[0].Env)
+
+	// The readOnlyAcl is preferred
+	instance.Spec.SolrReference.Cloud.ZookeeperConnectionInfo.ReadOnlyACL = &solr.ZookeeperACL{SecretRef: "zk-read-acl"}
+	solrConnectionInfo, err = getSolrConnectionInfo(instance, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "zk-read-acl", solrConnectionInfo.CloudZkACL.SecretRef, "The exporter should read the znodes with the readOnlyAcl")
+}
+
+func TestMetricsZkTLS(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{
+				Cloud: &solr.SolrCloudReference{
+					ZookeeperConnectionInfo: &solr.ZookeeperConnectionInfo{
+						InternalConnectionString: "host:2181",
+						TLS: &solr.ZookeeperTLSOptions{
+							SecureClientPort: 2281,
+							TrustStoreSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "zk-tls"}, Key: "truststore.pem"},
+						},
+					},
+				},
+			},
+		},
+	}
+	instance.WithDefaults()
+
+	solrConnectionInfo, err := getSolrConnectionInfo(instance, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "host:2281/", solrConnectionInfo.CloudZkConnnectionString, "The exporter should connect to the secure client port")
+	instance.Spec.Image = instance.ImageForSolrCloud(nil)
+	podSpec := util.GenerateSolrPrometheusExporterDeployment(instance, solrConnectionInfo, "").Spec.Template.Spec
+	testPodEnvVariables(t, map[string]string{
+		"SOLR_ZK_CREDS_AND_ACLS": "-Dzookeeper.client.secure=true -Dzookeeper.clientCnxnSocket=" + util.ZkClientCnxnSocket +
+			" -Dzookeeper.ssl.trustStore.location=" + util.ZkTLSTrustStorePath + "/truststore.pem",
+		"JAVA_OPTS": "$(SOLR_ZK_CREDS_AND_ACLS)",
+	}, podSpec.Containers[0].Env)
+	assert.Contains(t, podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{Name: util.ZkTLSTrustStoreVolume, MountPath: util.ZkTLSTrustStorePath, ReadOnly: true}, "The truststore should be mounted in the exporter")
+	assert.Contains(t, podSpec.Volumes, corev1.Volume{Name: util.ZkTLSTrustStoreVolume, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "zk-tls"}}}, "The truststore volume should be added to the exporter")
+}
+
+func TestMetricsSolrCloudToExporterMapping(t *testing.T) {
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-cloud", Namespace: "default"},
+		Status: solr.SolrCloudStatus{
+			ZookeeperConnectionInfo: solr.ZookeeperConnectionInfo{
+				InternalConnectionString: "zk-new:2181",
+				ChRoot:                   "/solr",
+			},
+		},
+	}
+	dependentExporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "dependent", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Cloud: &solr.SolrCloudReference{Name: solrCloud.Name}},
+		},
+	}
+	otherNamespaceExporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-namespace", Namespace: "other"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Cloud: &solr.SolrCloudReference{Name: solrCloud.Name}},
+		},
+	}
+	otherCloudExporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-cloud", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Cloud: &solr.SolrCloudReference{Name: "other", Namespace: "default"}},
+		},
+	}
+	standaloneExporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Standalone: &solr.StandaloneSolrReference{Address: "http://solr:8983/solr"}},
+		},
+	}
+
+	reconciler := &SolrPrometheusExporterReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, dependentExporter, otherNamespaceExporter, otherCloudExporter, standaloneExporter),
+		Log:    ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+	}
+
+	// An update to the SolrCloud should only trigger the reconcile of the dependent exporter
+	requests := reconciler.solrCloudToExporterRequests(handler.MapObject{Meta: solrCloud, Object: solrCloud})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: dependentExporter.Name, Namespace: dependentExporter.Namespace}}}, requests, "Wrong exporters reconciled for SolrCloud update")
+
+	// The exporter deployment must use the current ZK connection string of the SolrCloud
+	dependentExporter.WithDefaults()
+	dependentExporter.Spec.Image = dependentExporter.ImageForSolrCloud(solrCloud)
+	solrConnectionInfo, err := getSolrConnectionInfo(dependentExporter, solrCloud)
+	assert.NoError(t, err)
+	deployment := util.GenerateSolrPrometheusExporterDeployment(dependentExporter, solrConnectionInfo, "")
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "zk-new:2181/solr", "Exporter does not use the current ZK connection string of the SolrCloud")
+	assert.Equal(t, "zk-new:2181/solr", deployment.Annotations[util.SolrZKConnectionStringAnnotation], "Exporter deployment does not record the ZK connection string")
+
+	// Changing the ZK connection string must roll the exporter deployment
+	solrCloud.Status.ZookeeperConnectionInfo.InternalConnectionString = "zk-newer:2181"
+	solrConnectionInfo, _ = getSolrConnectionInfo(dependentExporter, solrCloud)
+	assert.True(t, util.CopyDeploymentFields(util.GenerateSolrPrometheusExporterDeployment(dependentExporter, solrConnectionInfo, ""), deployment), "Changed ZK connection string should require an update")
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "zk-newer:2181/solr", "Exporter deployment not updated with the new ZK connection string")
+}
+
+func TestMetricsStandaloneSolrCloudReference(t *testing.T) {
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-standalone", Namespace: "default"},
+		Spec:       solr.SolrCloudSpec{SolrMode: solr.StandaloneMode},
+		Status:     solr.SolrCloudStatus{InternalCommonAddress: "http://foo-met-standalone-solrcloud-common.default"},
+	}
+	exporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "standalone-by-name", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Standalone: &solr.StandaloneSolrReference{Name: solrCloud.Name}},
+		},
+	}
+	exporter.WithDefaults()
+	assert.Equal(t, "default", exporter.Spec.SolrReference.Standalone.Namespace, "The namespace of the standalone reference was not defaulted")
+
+	reconciler := &SolrPrometheusExporterReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme.Scheme, exporter),
+		Log:    ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+	}
+	requests := reconciler.solrCloudToExporterRequests(handler.MapObject{Meta: solrCloud, Object: solrCloud})
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Name: exporter.Name, Namespace: exporter.Namespace}}}, requests, "Exporter referencing a standalone SolrCloud not reconciled for SolrCloud update")
+
+	solrConnectionInfo, err := getSolrConnectionInfo(exporter, solrCloud)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://foo-met-standalone-solrcloud-common.default/solr", solrConnectionInfo.StandaloneAddress, "Standalone address not defaulted from the SolrCloud")
+
+	// An explicit address takes precedence
+	exporter.Spec.SolrReference.Standalone.Address = "http://solr:8983/solr"
+	solrConnectionInfo, err = getSolrConnectionInfo(exporter, solrCloud)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://solr:8983/solr", solrConnectionInfo.StandaloneAddress, "Provided standalone address not used")
+
+	// A SolrCloud in cloud mode cannot be referenced as a standalone Solr, and vice versa
+	exporter.Spec.SolrReference.Standalone.Address = ""
+	solrCloud.Spec.SolrMode = solr.CloudMode
+	_, err = getSolrConnectionInfo(exporter, solrCloud)
+	assert.Error(t, err, "A SolrCloud in cloud mode should not be usable as a standalone reference")
+	solrCloud.Spec.SolrMode = solr.StandaloneMode
+	exporter.Spec.SolrReference = solr.SolrReference{Cloud: &solr.SolrCloudReference{Name: solrCloud.Name, Namespace: "default"}}
+	_, err = getSolrConnectionInfo(exporter, solrCloud)
+	assert.Error(t, err, "A standalone SolrCloud should not be usable as a cloud reference")
+}
+
+func TestMetricsSolrUrlScheme(t *testing.T) {
+	solrCloud := &solr.SolrCloud{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-scheme", Namespace: "default"},
+		Spec: solr.SolrCloudSpec{
+			SolrMode: solr.StandaloneMode,
+			SolrTLS: &solr.SolrServerTLSOptions{
+				PKCS12Secret:           &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "solr-tls"}, Key: "keystore.p12"},
+				KeyStorePasswordSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "solr-tls"}, Key: "password"},
+			},
+		},
+		// The status of the cloud can lag behind enabling TLS
+		Status: solr.SolrCloudStatus{InternalCommonAddress: "http://foo-met-scheme-solrcloud-common.default"},
+	}
+	exporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "scheme-by-name", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Standalone: &solr.StandaloneSolrReference{Name: solrCloud.Name}},
+		},
+	}
+	exporter.WithDefaults()
+
+	// The TLS options are defaulted from the referenced SolrCloud, and its address uses https
+	solrConnectionInfo, err := getSolrConnectionInfo(exporter, solrCloud)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://foo-met-scheme-solrcloud-common.default/solr", solrConnectionInfo.StandaloneAddress, "The address of a SolrCloud using TLS should use https")
+	assert.NotNil(t, exporter.Spec.SolrReference.SolrTLS, "The TLS options should be defaulted from the SolrCloud")
+	assert.Equal(t, *solrCloud.Spec.SolrTLS.PKCS12Secret, *exporter.Spec.SolrReference.SolrTLS.TrustStoreSecret, "The keystore of the SolrCloud should be used as the truststore")
+
+	// An address without a scheme is given one, and an https address is never downgraded
+	exporter.Spec.SolrReference.SolrTLS = nil
+	exporter.Spec.SolrReference.Standalone.Address = "solr:8983/solr"
+	solrConnectionInfo, err = getSolrConnectionInfo(exporter, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://solr:8983/solr", solrConnectionInfo.StandaloneAddress, "An address without a scheme should be given one")
+	exporter.Spec.SolrReference.Standalone.Address = "https://solr:8983/solr"
+	solrConnectionInfo, err = getSolrConnectionInfo(exporter, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://solr:8983/solr", solrConnectionInfo.StandaloneAddress, "An https address should not be downgraded")
+}
+
+func TestMetricsIngressGeneration(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			External: &solr.ExporterExternalAddressability{
+				DomainName: testDomain,
+			},
+			CustomKubeOptions: solr.CustomExporterKubeOptions{
+				ServiceOptions: &solr.ServiceOptions{
+					Port: 9090,
+				},
+				IngressOptions: &solr.IngressOptions{
+					Annotations: testIngressAnnotations,
+					Labels:      testIngressLabels,
+				},
+			},
+		},
+	}
+
+	ingress := util.GenerateMetricsIngress(instance)
+	assert.Equal(t, "foo-met-solr-metrics", ingress.Name, "Wrong name for the metrics Ingress")
+	testMapsEqual(t, "ingress annotations", testIngressAnnotations, ingress.Annotations)
+	testMapsEqual(t, "ingress labels", util.MergeLabelsOrAnnotations(instance.SharedLabels(), testIngressLabels), ingress.Labels)
+	assert.Equal(t, 1, len(ingress.Spec.Rules), "Wrong number of rules for the metrics Ingress")
+	assert.Equal(t, "default-foo-met-solr-metrics."+testDomain, ingress.Spec.Rules[0].Host, "Wrong host for the metrics Ingress")
+	backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+	assert.Equal(t, metricsSKey.Name, backend.Name, "The metrics Ingress must point to the metrics Service")
+	assert.EqualValues(t, 9090, backend.Port.Number, "The metrics Ingress must use the port of the metrics Service")
+	assert.Empty(t, ingress.Spec.TLS, "No TLS should be configured for the metrics Ingress without a TLS secret")
+	assert.Equal(t, "http://default-foo-met-solr-metrics."+testDomain+"/metrics", util.MetricsExternalAddress(instance), "Wrong external address for the exporter")
+
+	// Using a TLS secret
+	instance.Spec.External.IngressTLSSecret = "metrics-tls"
+	ingress = util.GenerateMetricsIngress(instance)
+	assert.Equal(t, 1, len(ingress.Spec.TLS), "TLS not configured for the metrics Ingress")
+	assert.Equal(t, "metrics-tls", ingress.Spec.TLS[0].SecretName, "Wrong TLS secret for the metrics Ingress")
+	assert.Equal(t, []string{ingress.Spec.Rules[0].Host}, ingress.Spec.TLS[0].Hosts, "Wrong TLS hosts for the metrics Ingress")
+	assert.Equal(t, "https://default-foo-met-solr-metrics."+testDomain+"/metrics", util.MetricsExternalAddress(instance), "Wrong external address for the exporter")
+
+	// No external address without an Ingress
+	instance.Spec.External = nil
+	assert.Empty(t, util.MetricsExternalAddress(instance), "There should be no external address for the exporter without spec.external")
+}
+
+func TestMetricsIngressReconcile(t *testing.T) {
+	defer UseIngressAPI(true)
+	defer UseLegacyIngressAPI(false)
+
+	exporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-ingress", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Standalone: &solr.StandaloneSolrReference{Address: "http://solr:8983/solr"}},
+			External:      &solr.ExporterExternalAddressability{DomainName: testDomain},
+		},
+	}
+	exporter.WithDefaults()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: exporter.Name, Namespace: exporter.Namespace}}
+	ingressKey := types.NamespacedName{Name: exporter.MetricsIngressName(), Namespace: exporter.Namespace}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &SolrPrometheusExporterReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, exporter),
+		Log:      ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+		scheme:   scheme.Scheme,
+		recorder: recorder,
+	}
+	reconcileExporter := func() *solr.SolrPrometheusExporter {
+		_, err := reconciler.Reconcile(request)
+		assert.NoError(t, err)
+		foundExporter := &solr.SolrPrometheusExporter{}
+		assert.NoError(t, reconciler.Get(context.TODO(), request.NamespacedName, foundExporter))
+		return foundExporter
+	}
+
+	// Without an Ingress API, the missing API is reported once instead of failing every reconcile
+	UseIngressAPI(false)
+	reconcileExporter()
+	foundExporter := reconcileExporter()
+	reconcileExporter()
+	condition := foundExporter.Status.GetCondition(solr.ExporterRequiredAPIUnavailable)
+	assert.NotNil(t, condition, "The exporter should report the missing Ingress API")
+	assert.Equal(t, corev1.ConditionTrue, condition.Status, "The exporter should report the missing Ingress API")
+	assert.Equal(t, "IngressUnavailable", condition.Reason, "Wrong reason for the missing Ingress API")
+	assert.Len(t, recorder.Events, 1, "The missing Ingress API should only be recorded once")
+
+	// The legacy Ingress API is used by clusters that do not serve the current one
+	UseIngressAPI(true)
+	UseLegacyIngressAPI(true)
+	foundExporter = reconcileExporter()
+	legacyIngress := &extv1.Ingress{}
+	assert.NoError(t, reconciler.Get(context.TODO(), ingressKey, legacyIngress), "The exporter should have a legacy Ingress")
+	assert.True(t, metav1.IsControlledBy(legacyIngress, foundExporter), "The legacy Ingress should be owned by the exporter")
+	assert.Equal(t, corev1.ConditionFalse, foundExporter.Status.GetCondition(solr.ExporterRequiredAPIUnavailable).Status, "The Ingress API is available")
+	assert.NotEmpty(t, foundExporter.Status.ExternalAddress, "The exporter should have an external address")
+
+	// The Ingress is removed once the exporter is no longer addressable
+	foundExporter.Spec.External = nil
+	assert.NoError(t, reconciler.Update(context.TODO(), foundExporter))
+	foundExporter = reconcileExporter()
+	assert.True(t, apierrors.IsNotFound(reconciler.Get(context.TODO(), ingressKey, &extv1.Ingress{})), "The legacy Ingress should be deleted")
+	assert.Empty(t, foundExporter.Status.ExternalAddress, "The exporter should no longer have an external address")
+}
+
+func TestMetricsServiceMonitorReconcile(t *testing.T) {
+	defer UseServiceMonitorCRD(false)
+
+	exporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-sm", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference:  solr.SolrReference{Standalone: &solr.StandaloneSolrReference{Address: "http://solr:8983/solr"}},
+			ServiceMonitor: &solr.ServiceMonitorOptions{Enabled: true},
+		},
+	}
+	exporter.WithDefaults()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: exporter.Name, Namespace: exporter.Namespace}}
+	serviceMonitorKey := types.NamespacedName{Name: exporter.MetricsServiceMonitorName(), Namespace: exporter.Namespace}
+
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &SolrPrometheusExporterReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, exporter),
+		Log:      ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+		scheme:   scheme.Scheme,
+		recorder: recorder,
+	}
+	reconcileExporter := func() *solr.SolrPrometheusExporter {
+		_, err := reconciler.Reconcile(request)
+		assert.NoError(t, err)
+		foundExporter := &solr.SolrPrometheusExporter{}
+		assert.NoError(t, reconciler.Get(context.TODO(), request.NamespacedName, foundExporter))
+		return foundExporter
+	}
+	getServiceMonitor := func() (*unstructured.Unstructured, error) {
+		serviceMonitor := &unstructured.Unstructured{}
+		serviceMonitor.SetGroupVersionKind(util.ServiceMonitorGVK)
+		return serviceMonitor, reconciler.Get(context.TODO(), serviceMonitorKey, serviceMonitor)
+	}
+
+	// Without the ServiceMonitor CRD, the missing API is reported once instead of on every reconcile
+	UseServiceMonitorCRD(false)
+	reconcileExporter()
+	foundExporter := reconcileExporter()
+	reconcileExporter()
+	condition := foundExporter.Status.GetCondition(solr.ExporterRequiredAPIUnavailable)
+	assert.NotNil(t, condition, "The exporter should report the missing ServiceMonitor CRD")
+	assert.Equal(t, corev1.ConditionTrue, condition.Status, "The exporter should report the missing ServiceMonitor CRD")
+	assert.Equal(t, "ServiceMonitorUnavailable", condition.Reason, "Wrong reason for the missing ServiceMonitor CRD")
+	assert.Len(t, recorder.Events, 1, "The missing ServiceMonitor CRD should only be recorded once")
+
+	// The ServiceMonitor is created once the CRD is available
+	UseServiceMonitorCRD(true)
+	foundExporter = reconcileExporter()
+	serviceMonitor, err := getServiceMonitor()
+	assert.NoError(t, err, "The exporter should have a ServiceMonitor")
+	assert.True(t, metav1.IsControlledBy(serviceMonitor, foundExporter), "The ServiceMonitor should be owned by the exporter")
+	assert.Equal(t, corev1.ConditionFalse, foundExporter.Status.GetCondition(solr.ExporterRequiredAPIUnavailable).Status, "The ServiceMonitor CRD is available")
+
+	// The ServiceMonitor is removed once it is disabled
+	foundExporter.Spec.ServiceMonitor.Enabled = false
+	assert.NoError(t, reconciler.Update(context.TODO(), foundExporter))
+	reconcileExporter()
+	_, err = getServiceMonitor()
+	assert.True(t, apierrors.IsNotFound(err), "The ServiceMonitor should be deleted once it is disabled")
+
+	// A ServiceMonitor that is not owned by the exporter is left alone
+	serviceMonitor = util.GenerateSolrMetricsServiceMonitor(foundExporter)
+	assert.NoError(t, reconciler.Create(context.TODO(), serviceMonitor))
+	foundExporter = reconcileExporter()
+	foundExporter.Spec.ServiceMonitor = nil
+	assert.NoError(t, reconciler.Update(context.TODO(), foundExporter))
+	reconcileExporter()
+	_, err = getServiceMonitor()
+	assert.NoError(t, err, "A ServiceMonitor not owned by the exporter should not be deleted")
+}
+
+func TestMetricsCollectionsConfig(t *testing.T) {
+	instance := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: expectedMetricsRequest.Name, Namespace: expectedMetricsRequest.Namespace},
+		Spec: solr.SolrPrometheusExporterSpec{
+			MetricsCollections: []string{"products", "orders"},
+		},
+	}
+	instance.WithDefaults()
+	assert.NoError(t, instance.ValidateMetricsConfig())
+	assert.True(t, instance.UsesMetricsConfigMap(), "A config should be generated for the metricsCollections")
+
+	// Collection-level queries are only run for the requested collections, and core metrics are not scraped by default
+	configXml := util.GenerateMetricsConfigMap(instance).Data[util.PrometheusExporterConfigMapKey]
+	assertWellFormedXml(t, configXml)
+	assert.Equal(t, []string{"products", "orders", "products", "orders"}, queriedCollections(configXml), "Wrong collections queried by the exporter")
+	assert.Contains(t, configXml, `<str name="group">jvm,jetty,node</str>`, "The node and JVM metrics should always be scraped")
+	assert.NotContains(t, configXml, `<str name="group">core</str>`, "The core metrics should not be scraped without scrapeCores")
+	assert.Equal(t, 1, strings.Count(configXml, "solr_collections_live_nodes"), "The live nodes should only be reported once")
+
+	// Core metrics are filtered to the requested collections
+	instance.Spec.ScrapeCores = true
+	configXml = util.GenerateMetricsConfigMap(instance).Data[util.PrometheusExporterConfigMapKey]
+	assertWellFormedXml(t, configXml)
+	assert.Contains(t, configXml, `<str name="group">core</str>`, "The core metrics should be scraped with scrapeCores")
+	assert.Contains(t, configXml, `select($parts[2] == "products" or $parts[2] == "orders")`, "The core metrics should be filtered to the requested collections")
+
+	// The generated config is mounted into the exporter
+	deployment := util.GenerateSolrPrometheusExporterDeployment(instance, util.SolrConnectionInfo{CloudZkConnnectionString: "host:2181"}, "")
+	assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "/opt/solr-exporter/solr-prometheus-exporter.xml", "The exporter should use the generated config")
+
+	// Ambiguous or invalid options are rejected
+	instance.Spec.Config = testExporterConfig
+	assert.Error(t, instance.ValidateMetricsConfig(), "metricsCollections cannot be used with a custom config")
+	instance.Spec.MetricsCollections = nil
+	assert.Error(t, instance.ValidateMetricsConfig(), "scrapeCores cannot be used with a custom config")
+	instance.Spec.Config = ""
+	assert.Error(t, instance.ValidateMetricsConfig(), "scrapeCores requires metricsCollections")
+	instance.Spec.ScrapeCores = false
+	instance.Spec.MetricsCollections = []string{`bad" or true`}
+	assert.Error(t, instance.ValidateMetricsConfig(), "Invalid collection names should be rejected")
+
+	// Without any option, the default config of the exporter is used
+	instance.Spec.MetricsCollections = nil
+	assert.NoError(t, instance.ValidateMetricsConfig())
+	assert.False(t, instance.UsesMetricsConfigMap(), "The default config of the exporter should be used")
+}
+
+// queriedCollections returns the collections that the queries of the exporter config are restricted to, in order
+func queriedCollections(configXml string) (collections []string) {
+	decoder := xml.NewDecoder(strings.NewReader(configXml))
+	inCollection := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return collections
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			inCollection = element.Name.Local == "str" && len(element.Attr) == 1 && element.Attr[0].Value == "collection"
+		case xml.CharData:
+			if inCollection {
+				collections = append(collections, string(element))
+			}
+		case xml.EndElement:
+			inCollection = false
+		}
+	}
+}
+
+func assertWellFormedXml(t *testing.T, configXml string) {
+	decoder := xml.NewDecoder(strings.NewReader(configXml))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return
+		} else if !assert.NoError(t, err, "The generated config is not well-formed XML") {
+			return
+		}
+	}
+}
+
+func TestMetricsSuspend(t *testing.T) {
+	exporter := &solr.SolrPrometheusExporter{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-met-suspend", Namespace: "default"},
+		Spec: solr.SolrPrometheusExporterSpec{
+			SolrReference: solr.SolrReference{Standalone: &solr.StandaloneSolrReference{Address: "http://solr:8983/solr"}},
+		},
+	}
+	exporter.WithDefaults()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: exporter.Name, Namespace: exporter.Namespace}}
+	deploymentKey := types.NamespacedName{Name: exporter.MetricsDeploymentName(), Namespace: exporter.Namespace}
+
+	reconciler := &SolrPrometheusExporterReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, exporter),
+		Log:      ctrl.Log.WithName("controllers").WithName("SolrPrometheusExporter"),
+		scheme:   scheme.Scheme,
+		recorder: record.NewFakeRecorder(10),
+	}
+	reconcileExporter := func() (*solr.SolrPrometheusExporter, *appsv1.Deployment) {
+		_, err := reconciler.Reconcile(request)
+		assert.NoError(t, err)
+		foundExporter := &solr.SolrPrometheusExporter{}
+		assert.NoError(t, reconciler.Get(context.TODO(), request.NamespacedName, foundExporter))
+		foundDeployment := &appsv1.Deployment{}
+		assert.NoError(t, reconciler.Get(context.TODO(), deploymentKey, foundDeployment))
+		return foundExporter, foundDeployment
+	}
+	scaleDeployment := func(deployment *appsv1.Deployment, replicas int32) {
+		deployment.Spec.Replicas = &replicas
+		deployment.Status.ReadyReplicas = replicas
+		assert.NoError(t, reconciler.Update(context.TODO(), deployment))
+	}
+
+	// Mark the Deployment, so that a recreated Deployment would be noticed
+	_, deployment := reconcileExporter()
+	assert.EqualValues(t, 1, *deployment.Spec.Replicas, "The exporter should start with a single replica")
+	deployment.UID = "exporter-deployment"
+	scaleDeployment(deployment, 3)
+
+	// The operator does not override manual scaling
+	foundExporter, deployment := reconcileExporter()
+	assert.EqualValues(t, 3, *deployment.Spec.Replicas, "The operator should not override the replicas of the exporter")
+	assert.True(t, foundExporter.Status.Ready, "The exporter should be ready")
+
+	// Suspending scales the Deployment to zero, but keeps the Service
+	foundExporter.Spec.Suspend = true
+	assert.NoError(t, reconciler.Update(context.TODO(), foundExporter))
+	foundExporter, deployment = reconcileExporter()
+	assert.EqualValues(t, 0, *deployment.Spec.Replicas, "A suspended exporter should be scaled to zero")
+	assert.Equal(t, "3", deployment.Annotations[util.ExporterReplicasBeforeSuspendAnnotation], "The replicas before suspending were not recorded")
+	assert.False(t, foundExporter.Status.Ready, "A suspended exporter should not be ready")
+	assert.True(t, foundExporter.Status.IsConditionTrue(solr.ExporterSuspended), "The exporter should have a Suspended condition")
+	assert.NoError(t, reconciler.Get(context.TODO(), types.NamespacedName{Name: exporter.MetricsServiceName(), Namespace: exporter.Namespace}, &corev1.Service{}), "The Service should be kept while suspended")
+
+	// The operator does not fight scaling while suspended
+	scaleDeployment(deployment, 2)
+	_, deployment = reconcileExporter()
+	assert.EqualValues(t, 2, *deployment.Spec.Replicas, "The operator should not fight scaling while the exporter is suspended")
+	assert.Equal(t, "3", deployment.Annotations[util.ExporterReplicasBeforeSuspendAnnotation], "The replicas before suspending should not change while suspended")
+
+	// Resuming restores the previous replicas on the same Deployment
+	scaleDeployment(deployment, 0)
+	foundExporter.Spec.Suspend = false
+	assert.NoError(t, reconciler.Update(context.TODO(), foundExporter))
+	foundExporter, deployment = reconcileExporter()
+	assert.EqualValues(t, "exporter-deployment", deployment.UID, "The Deployment should not be recreated")
+	assert.EqualValues(t, 3, *deployment.Spec.Replicas, "The replicas before suspending were not restored")
+	assert.NotContains(t, deployment.Annotations, util.ExporterReplicasBeforeSuspendAnnotation, "The replicas before suspending should be removed once resumed")
+	condition := foundExporter.Status.GetCondition(solr.ExporterSuspended)
+	assert.Equal(t, corev1.ConditionFalse, condition.Status, "The Suspended condition should be false once resumed")
+
+	// An exporter that is created suspended is resumed with a single replica
+	foundExporter.Spec.Suspend = true
+	deployment = util.GenerateSolrPrometheusExporterDeployment(foundExporter, util.SolrConnectionInfo{}, "")
+	assert.EqualValues(t, 0, *deployment.Spec.Replicas, "A suspended exporter should be created without replicas")
+	assert.Equal(t, "1", deployment.Annotations[util.ExporterReplicasBeforeSuspendAnnotation], "A suspended exporter should be resumed with a single replica")
+}
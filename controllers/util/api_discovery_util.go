@@ -0,0 +1,55 @@
+/*
+Copyright 2019 Bloomberg Finance LP.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	zk "github.com/pravega/zookeeper-operator/pkg/apis/zookeeper/v1beta1"
+	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/discovery"
+)
+
+const (
+	IngressKind          = "Ingress"
+	ZookeeperClusterKind = "ZookeeperCluster"
+)
+
+var (
+	// IngressGroupVersion is the group version of the Ingress API used by the operator
+	IngressGroupVersion = netv1.SchemeGroupVersion.String()
+
+	// LegacyIngressGroupVersion is the group version of the Ingress API used on Kubernetes clusters that do not serve the IngressGroupVersion
+	LegacyIngressGroupVersion = extv1.SchemeGroupVersion.String()
+
+	// ZookeeperClusterGroupVersion is the group version of the zookeeper-operator ZookeeperCluster CRD used by the operator
+	ZookeeperClusterGroupVersion = zk.SchemeGroupVersion.String()
+)
+
+// IsAPIResourceAvailable uses the discovery client to determine whether the Kubernetes API server serves the given kind
+// in the given group version, either as a built-in API or through an installed CRD.
+func IsAPIResourceAvailable(discoveryClient discovery.DiscoveryInterface, groupVersion string, kind string) bool {
+	resources, err := discoveryClient.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil || resources == nil {
+		return false
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
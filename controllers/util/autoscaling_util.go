@@ -0,0 +1,95 @@
+/*
+Copyright 2019 Bloomberg Finance LP.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+
+	solr "github.com/bloomberg/solr-operator/api/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	SolrAutoscalingApiPath = "/solr/admin/autoscaling"
+)
+
+// SolrAutoscalingConfigResponse is the autoscaling configuration stored in Solr
+type SolrAutoscalingConfigResponse struct {
+	ResponseHeader SolrCollectionResponseHeader `json:"responseHeader"`
+
+	// +optional
+	ClusterPolicy []interface{} `json:"cluster-policy"`
+
+	// +optional
+	ClusterPreferences []interface{} `json:"cluster-preferences"`
+}
+
+// AutoscalingCommandsForCloud returns the commands of the autoscaling API that set the policy and preferences of the SolrCloud spec,
+// for the lists whose values in Solr differ from the spec
+func AutoscalingCommandsForCloud(apiClient *SolrApiClient, options *solr.SolrAutoscalingOptions) (commands map[string]interface{}, err error) {
+	current := &SolrAutoscalingConfigResponse{}
+	if err = apiClient.Get(SolrAutoscalingApiPath, url.Values{}, current); err != nil {
+		return nil, err
+	}
+
+	commands = map[string]interface{}{}
+	if options.ClusterPolicy != nil {
+		var desired []interface{}
+		if desired, err = decodeAutoscalingRules(options.ClusterPolicy); err != nil {
+			return nil, err
+		}
+		if !autoscalingRulesEqual(desired, current.ClusterPolicy) {
+			commands["set-cluster-policy"] = desired
+		}
+	}
+	if options.ClusterPreferences != nil {
+		var desired []interface{}
+		if desired, err = decodeAutoscalingRules(options.ClusterPreferences); err != nil {
+			return nil, err
+		}
+		if !autoscalingRulesEqual(desired, current.ClusterPreferences) {
+			commands["set-cluster-preferences"] = desired
+		}
+	}
+	return commands, nil
+}
+
+// SetAutoscalingConfig sends the given commands to the autoscaling API of Solr
+func SetAutoscalingConfig(apiClient *SolrApiClient, commands map[string]interface{}) (err error) {
+	return apiClient.Post(SolrAutoscalingApiPath, url.Values{}, commands, &SolrAsyncResponse{})
+}
+
+// decodeAutoscalingRules decodes the given rules into generic JSON values, so that they can be compared with the rules returned by Solr
+func decodeAutoscalingRules(rules []runtime.RawExtension) (decoded []interface{}, err error) {
+	decoded = make([]interface{}, len(rules))
+	for i, rule := range rules {
+		if err = json.Unmarshal(rule.Raw, &decoded[i]); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// autoscalingRulesEqual returns whether the desired rules are the same as the rules stored in Solr, where no rules can be returned as null
+func autoscalingRulesEqual(desired []interface{}, current []interface{}) bool {
+	if len(desired) == 0 && len(current) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(desired, current)
+}
@@ -293,7 +293,7 @@ func GeneratePersistenceOptions(solrBackup *solr.SolrBackup) (image solr.Contain
 	return image, envVars, command, volume, volumeMount, numRetries
 }
 
-func StartBackupForCollection(cloud string, collection string, backupName string, namespace string) (success bool, err error) {
+func StartBackupForCollection(apiClient *SolrApiClient, collection string, backupName string) (success bool, err error) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "BACKUP")
 	queryParams.Add("collection", collection)
@@ -303,29 +303,31 @@ func StartBackupForCollection(cloud string, collection string, backupName string
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to start collection backup", "namespace", namespace, "cloud", cloud, "collection", collection, "backup", backupName)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to start collection backup", "url", apiClient.BaseUrl, "collection", collection, "backup", backupName)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
 			success = true
 		}
 	} else {
-		log.Error(err, "Error starting collection backup", "namespace", namespace, "cloud", cloud, "collection", collection, "backup", backupName)
+		log.Error(err, "Error starting collection backup", "url", apiClient.BaseUrl, "collection", collection, "backup", backupName)
 	}
 
 	return success, err
 }
 
-func CheckBackupForCollection(cloud string, collection string, backupName string, namespace string) (finished bool, success bool, asyncStatus string, err error) {
+// CheckBackupForCollection checks on the async backup of a collection.
+// If the backup failed, the error that Solr gives for the failure is returned as the message.
+func CheckBackupForCollection(apiClient *SolrApiClient, collection string, backupName string) (finished bool, success bool, asyncStatus string, message string, err error) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "REQUESTSTATUS")
 	queryParams.Add("requestid", AsyncIdForCollectionBackup(collection, backupName))
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to check on collection backup", "namespace", namespace, "cloud", cloud, "collection", collection, "backup", backupName)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to check on collection backup", "url", apiClient.BaseUrl, "collection", collection, "backup", backupName)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
@@ -337,26 +339,30 @@ func CheckBackupForCollection(cloud string, collection string, backupName string
 			if resp.Status.AsyncState == "failed" {
 				finished = true
 				success = false
+				message = resp.Exception.Message
+				if message == "" {
+					message = resp.Status.Message
+				}
 			}
 		}
 	} else {
-		log.Error(err, "Error checking on collection backup", "namespace", namespace, "cloud", cloud, "collection", collection, "backup", backupName)
+		log.Error(err, "Error checking on collection backup", "url", apiClient.BaseUrl, "collection", collection, "backup", backupName)
 	}
 
-	return finished, success, asyncStatus, err
+	return finished, success, asyncStatus, message, err
 }
 
-func DeleteAsyncInfoForBackup(cloud string, collection string, backupName string, namespace string) (err error) {
+func DeleteAsyncInfoForBackup(apiClient *SolrApiClient, collection string, backupName string) (err error) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "DELETESTATUS")
 	queryParams.Add("requestid", AsyncIdForCollectionBackup(collection, backupName))
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to delete async info for backup command.", "namespace", namespace, "cloud", cloud, "collection", collection, "backup", backupName)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to delete async info for backup command.", "url", apiClient.BaseUrl, "collection", collection, "backup", backupName)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 	if err != nil {
-		log.Error(err, "Error deleting async data for collection backup", "namespace", namespace, "cloud", cloud, "collection", collection, "backup", backupName)
+		log.Error(err, "Error deleting async data for collection backup", "url", apiClient.BaseUrl, "collection", collection, "backup", backupName)
 	}
 
 	return err
@@ -370,6 +376,16 @@ type SolrAsyncResponse struct {
 
 	// +optional
 	Status SolrAsyncStatus `json:"status"`
+
+	// The exception that caused a failed async request
+	// +optional
+	Exception SolrAsyncException `json:"exception"`
+}
+
+type SolrAsyncException struct {
+	Message string `json:"msg"`
+
+	ResponseCode int `json:"rspCode"`
 }
 
 type SolrResponseHeader struct {
@@ -385,15 +401,45 @@ type SolrAsyncStatus struct {
 	Message string `json:"msg"`
 }
 
+// DeleteCollectionBackupDirectory removes the data of a failed collection backup, so that the backup can be retried
+func DeleteCollectionBackupDirectory(solrCloud *solr.SolrCloud, backup string, collection string, config *rest.Config) (err error) {
+	collectionPath := BackupPath(backup) + "/" + collection
+	nodeNames := solrCloud.GetAllSolrNodeNames()
+	if solrCloud.Spec.BackupRestoreVolume != nil && len(nodeNames) > 1 {
+		nodeNames = nodeNames[:1]
+	}
+	for _, nodeName := range nodeNames {
+		if err = RunExecForPod(
+			nodeName,
+			solrCloud.Namespace,
+			[]string{"/bin/bash", "-c", "rm -rf " + collectionPath},
+			*config,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func EnsureDirectoryForBackup(solrCloud *solr.SolrCloud, backup string, config *rest.Config) (err error) {
 	backupPath := BackupPath(backup)
+	// A shared volume only needs the directory created once, but every node has its own per-node volume
+	nodeNames := solrCloud.GetAllSolrNodeNames()
+	if solrCloud.Spec.BackupRestoreVolume != nil && len(nodeNames) > 1 {
+		nodeNames = nodeNames[:1]
+	}
 	// Create an empty directory for the backup
-	return RunExecForPod(
-		solrCloud.GetAllSolrNodeNames()[0],
-		solrCloud.Namespace,
-		[]string{"/bin/bash", "-c", "rm -rf " + backupPath + " && mkdir -p " + backupPath},
-		*config,
-	)
+	for _, nodeName := range nodeNames {
+		if err = RunExecForPod(
+			nodeName,
+			solrCloud.Namespace,
+			[]string{"/bin/bash", "-c", "rm -rf " + backupPath + " && mkdir -p " + backupPath},
+			*config,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func RunExecForPod(podName string, namespace string, command []string, config rest.Config) (err error) {
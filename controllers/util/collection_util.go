@@ -17,6 +17,7 @@ limitations under the License.
 package util
 
 import (
+	"fmt"
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
 	"net/url"
 	"strconv"
@@ -24,7 +25,7 @@ import (
 )
 
 // CreateCollection to request collection creation on SolrCloud
-func CreateCollection(cloud string, collection string, numShards int64, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, routerName solr.CollectionRouterName, routerField string, shards string, collectionConfigName string, namespace string) (success bool, err error) {
+func CreateCollection(apiClient *SolrApiClient, collection string, numShards int64, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, routerName solr.CollectionRouterName, routerField string, shards string, collectionConfigName string) (success bool, err error) {
 	queryParams := url.Values{}
 	replicationFactorParameter := strconv.FormatInt(replicationFactor, 10)
 	numShardsParameter := strconv.FormatInt(numShards, 10)
@@ -52,22 +53,22 @@ func CreateCollection(cloud string, collection string, numShards int64, replicat
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to create collection", "namespace", namespace, "cloud", cloud, "collection", collection)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to create collection", "url", apiClient.BaseUrl, "collection", collection)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
 			success = true
 		}
 	} else {
-		log.Error(err, "Error creating collection", "namespace", namespace, "cloud", cloud, "collection", collection)
+		log.Error(err, "Error creating collection", "url", apiClient.BaseUrl, "collection", collection)
 	}
 
 	return success, err
 }
 
 // CreateCollectionAlias to request the creation of an alias to one or more collections
-func CreateCollectionAlias(cloud string, alias string, aliasType string, collections []string, namespace string) (err error) {
+func CreateCollectionAlias(apiClient *SolrApiClient, alias string, aliasType string, collections []string) (err error) {
 	queryParams := url.Values{}
 	collectionsArray := strings.Join(collections, ",")
 	queryParams.Add("action", "CREATEALIAS")
@@ -76,15 +77,15 @@ func CreateCollectionAlias(cloud string, alias string, aliasType string, collect
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to create alias", "namespace", namespace, "cloud", cloud, "alias", alias, "to collections", collectionsArray)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to create alias", "url", apiClient.BaseUrl, "alias", alias, "to collections", collectionsArray)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
 			log.Info("Created alias", "alias", alias, "to collection", collectionsArray)
 		}
 	} else {
-		log.Error(err, "Error creating alias", "namespace", namespace, "cloud", cloud, "alias", alias, "to collections", collectionsArray)
+		log.Error(err, "Error creating alias", "url", apiClient.BaseUrl, "alias", alias, "to collections", collectionsArray)
 	}
 
 	return err
@@ -92,51 +93,51 @@ func CreateCollectionAlias(cloud string, alias string, aliasType string, collect
 }
 
 // DeleteCollection to request collection deletion on SolrCloud
-func DeleteCollection(cloud string, collection string, namespace string) (success bool, err error) {
+func DeleteCollection(apiClient *SolrApiClient, collection string) (success bool, err error) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "DELETE")
 	queryParams.Add("name", collection)
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to delete collection", "namespace", namespace, "cloud", cloud, "collection", collection)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to delete collection", "url", apiClient.BaseUrl, "collection", collection)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
 			success = true
 		}
 	} else {
-		log.Error(err, "Error deleting collection", "namespace", namespace, "cloud", cloud, "collection", collection)
+		log.Error(err, "Error deleting collection", "url", apiClient.BaseUrl, "collection", collection)
 	}
 
 	return success, err
 }
 
 // DeleteCollectionAlias removes an alias
-func DeleteCollectionAlias(cloud string, alias string, namespace string) (success bool, err error) {
+func DeleteCollectionAlias(apiClient *SolrApiClient, alias string) (success bool, err error) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "DELETEALIAS")
 	queryParams.Add("name", alias)
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to delete collection alias", "namespace", namespace, "cloud", cloud, "alias", alias)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to delete collection alias", "url", apiClient.BaseUrl, "alias", alias)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
 			success = true
 		}
 	} else {
-		log.Error(err, "Error deleting collection alias", "namespace", namespace, "cloud", cloud, "alias", alias)
+		log.Error(err, "Error deleting collection alias", "url", apiClient.BaseUrl, "alias", alias)
 	}
 
 	return success, err
 }
 
 // ModifyCollection to request collection modification on SolrCloud.
-func ModifyCollection(cloud string, collection string, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, collectionConfigName string, namespace string) (success bool, err error) {
+func ModifyCollection(apiClient *SolrApiClient, collection string, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, collectionConfigName string) (success bool, err error) {
 	queryParams := url.Values{}
 	replicationFactorParameter := strconv.FormatInt(replicationFactor, 10)
 	maxShardsPerNodeParameter := strconv.FormatInt(maxShardsPerNode, 10)
@@ -149,22 +150,76 @@ func ModifyCollection(cloud string, collection string, replicationFactor int64,
 
 	resp := &SolrAsyncResponse{}
 
-	log.Info("Calling to modify collection", "namespace", namespace, "cloud", cloud, "collection", collection)
-	err = CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to modify collection", "url", apiClient.BaseUrl, "collection", collection)
+	err = apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if resp.ResponseHeader.Status == 0 {
 			success = true
 		}
 	} else {
-		log.Error(err, "Error modifying collection", "namespace", namespace, "cloud", cloud, "collection")
+		log.Error(err, "Error modifying collection", "url", apiClient.BaseUrl, "collection")
 	}
 
 	return success, err
 }
 
+// GetLiveNodesFromApi fetches the names of the live nodes in the cluster state, as seen by the Solr node(s) that the client calls
+func GetLiveNodesFromApi(apiClient *SolrApiClient) (liveNodes []string, err error) {
+	queryParams := url.Values{}
+	queryParams.Add("action", "CLUSTERSTATUS")
+
+	resp := &SolrClusterStatusResponse{}
+
+	if err = apiClient.CallCollectionsApi(queryParams, resp); err == nil {
+		if resp.Cluster.LiveNodes == nil {
+			err = fmt.Errorf("no live_nodes found in the CLUSTERSTATUS response from %s", apiClient.BaseUrl)
+		}
+		liveNodes = resp.Cluster.LiveNodes
+	}
+
+	return liveNodes, err
+}
+
+// GetClusterProperties fetches the cluster properties of the SolrCloud from its CLUSTERSTATUS.
+// Nested properties are flattened into dotted names, e.g. "defaults.collection.numShards".
+func GetClusterProperties(apiClient *SolrApiClient) (properties map[string]string, err error) {
+	queryParams := url.Values{}
+	queryParams.Add("action", "CLUSTERSTATUS")
+
+	resp := &SolrClusterStatusResponse{}
+
+	if err = apiClient.CallCollectionsApi(queryParams, resp); err == nil {
+		properties = map[string]string{}
+		flattenClusterProperties("", resp.Cluster.Properties, properties)
+	}
+
+	return properties, err
+}
+
+// SetClusterProperty sets the value of a single cluster property through the CLUSTERPROP API
+func SetClusterProperty(apiClient *SolrApiClient, name string, value string) (err error) {
+	queryParams := url.Values{}
+	queryParams.Add("action", "CLUSTERPROP")
+	queryParams.Add("name", name)
+	queryParams.Add("val", value)
+
+	return apiClient.CallCollectionsApi(queryParams, &SolrAsyncResponse{})
+}
+
+// flattenClusterProperties adds the given properties to the flattened properties, prefixing nested property names with the names of their parents
+func flattenClusterProperties(prefix string, properties map[string]interface{}, flattened map[string]string) {
+	for name, value := range properties {
+		if nested, isMap := value.(map[string]interface{}); isMap {
+			flattenClusterProperties(prefix+name+".", nested, flattened)
+		} else {
+			flattened[prefix+name] = fmt.Sprint(value)
+		}
+	}
+}
+
 // CheckIfCollectionModificationRequired to check if the collection's modifiable parameters have changed in spec and need to be updated
-func CheckIfCollectionModificationRequired(cloud string, collection string, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, collectionConfigName string, namespace string) (success bool, err error) {
+func CheckIfCollectionModificationRequired(apiClient *SolrApiClient, collection string, replicationFactor int64, autoAddReplicas bool, maxShardsPerNode int64, collectionConfigName string) (success bool, err error) {
 	queryParams := url.Values{}
 	replicationFactorParameter := strconv.FormatInt(replicationFactor, 10)
 	maxShardsPerNodeParameter := strconv.FormatInt(maxShardsPerNode, 10)
@@ -175,7 +230,7 @@ func CheckIfCollectionModificationRequired(cloud string, collection string, repl
 
 	resp := &SolrClusterStatusResponse{}
 
-	err = CallCollectionsApi(cloud, namespace, queryParams, &resp)
+	err = apiClient.CallCollectionsApi(queryParams, &resp)
 
 	if collectionResp, ok := resp.Cluster.Collections[collection].(map[string]interface{}); ok {
 		// Check modifiable collection parameters
@@ -198,41 +253,41 @@ func CheckIfCollectionModificationRequired(cloud string, collection string, repl
 			success = true
 		}
 	} else {
-		log.Error(err, "Error calling collection API status", "namespace", namespace, "cloud", cloud, "collection", collection)
+		log.Error(err, "Error calling collection API status", "url", apiClient.BaseUrl, "collection", collection)
 	}
 
 	return success, err
 }
 
 // CheckIfCollectionExists to request if collection exists in list of collection
-func CheckIfCollectionExists(cloud string, collection string, namespace string) (success bool) {
+func CheckIfCollectionExists(apiClient *SolrApiClient, collection string) (success bool) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "LIST")
 
 	resp := &SolrCollectionsListResponse{}
 
-	log.Info("Calling to list collections", "namespace", namespace, "cloud", cloud, "collection", collection)
-	err := CallCollectionsApi(cloud, namespace, queryParams, resp)
+	log.Info("Calling to list collections", "url", apiClient.BaseUrl, "collection", collection)
+	err := apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		if containsCollection(resp.Collections, collection) {
 			success = true
 		}
 	} else {
-		log.Error(err, "Error listing collections", "namespace", namespace, "cloud", cloud, "collection")
+		log.Error(err, "Error listing collections", "url", apiClient.BaseUrl, "collection")
 	}
 
 	return success
 }
 
 // CurrentCollectionAliasDetails will return a success if details found for an alias and comma separated string of associated collections
-func CurrentCollectionAliasDetails(cloud string, alias string, namespace string) (success bool, collections string) {
+func CurrentCollectionAliasDetails(apiClient *SolrApiClient, alias string) (success bool, collections string) {
 	queryParams := url.Values{}
 	queryParams.Add("action", "LISTALIASES")
 
 	resp := &SolrCollectionAliasDetailsResponse{}
 
-	err := CallCollectionsApi(cloud, namespace, queryParams, resp)
+	err := apiClient.CallCollectionsApi(queryParams, resp)
 
 	if err == nil {
 		success, collections := containsAlias(resp.Aliases, alias)
@@ -283,6 +338,11 @@ type SolrClusterStatusResponse struct {
 
 type SolrClusterStatusCluster struct {
 	Collections map[string]interface{} `json:"collections"`
+
+	LiveNodes []string `json:"live_nodes"`
+
+	// +optional
+	Properties map[string]interface{} `json:"properties"`
 }
 
 // ContainsString helper function to test string contains
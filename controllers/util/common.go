@@ -17,8 +17,13 @@ limitations under the License.
 package util
 
 import (
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"reflect"
+	"sort"
 )
 
 // CopyLabelsAndAnnotations copies the labels and annotations from one object to another.
@@ -68,6 +73,27 @@ func MergeLabelsOrAnnotations(base, additional map[string]string) map[string]str
 	return merged
 }
 
+// MergeEnvVars merges the additional environment variables into the base environment variables.
+// If a variable exists in both lists, the value from additional will replace the base value in its original position.
+func MergeEnvVars(base, additional []corev1.EnvVar) []corev1.EnvVar {
+	merged := make([]corev1.EnvVar, len(base), len(base)+len(additional))
+	copy(merged, base)
+	for _, envVar := range additional {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == envVar.Name {
+				merged[i] = envVar
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, envVar)
+		}
+	}
+	return merged
+}
+
 // DeepEqualWithNils returns a deepEquals call that treats nil and zero-length maps, arrays and slices as the same.
 func DeepEqualWithNils(x, y interface{}) bool {
 	if (x == nil) != (y == nil) {
@@ -85,3 +111,133 @@ func DeepEqualWithNils(x, y interface{}) bool {
 	}
 	return reflect.DeepEqual(x, y)
 }
+
+// MergeHostAliases merges the given hostname to IP mapping with the additional host aliases.
+// If a hostname exists in both, the IP from hostNameIPs is used and duplicate hostnames are collapsed.
+// The returned host aliases contain a single hostname each, and are sorted by hostname so that the result is deterministic.
+func MergeHostAliases(hostNameIPs map[string]string, additional []corev1.HostAlias) []corev1.HostAlias {
+	merged := make(map[string]string, len(hostNameIPs))
+	for _, hostAlias := range additional {
+		for _, hostName := range hostAlias.Hostnames {
+			merged[hostName] = hostAlias.IP
+		}
+	}
+	for hostName, ip := range hostNameIPs {
+		merged[hostName] = ip
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+
+	hostNames := make([]string, 0, len(merged))
+	for hostName := range merged {
+		hostNames = append(hostNames, hostName)
+	}
+	sort.Strings(hostNames)
+
+	hostAliases := make([]corev1.HostAlias, len(hostNames))
+	for index, hostName := range hostNames {
+		hostAliases[index] = corev1.HostAlias{
+			IP:        merged[hostName],
+			Hostnames: []string{hostName},
+		}
+	}
+	return hostAliases
+}
+
+// MergeImagePullSecrets returns the pull secret of an image followed by the additional pull secrets, without duplicates.
+func MergeImagePullSecrets(imagePullSecret string, additional []corev1.LocalObjectReference) []corev1.LocalObjectReference {
+	var merged []corev1.LocalObjectReference
+	added := map[string]bool{}
+	for _, secret := range append([]corev1.LocalObjectReference{{Name: imagePullSecret}}, additional...) {
+		if secret.Name != "" && !added[secret.Name] {
+			added[secret.Name] = true
+			merged = append(merged, secret)
+		}
+	}
+	return merged
+}
+
+// ApplyPodTemplateOverrides applies the overrides to the pod template as a strategic merge patch.
+// The pod template is not changed if the overrides cannot be applied.
+func ApplyPodTemplateOverrides(template *corev1.PodTemplateSpec, overrides *runtime.RawExtension) error {
+	if overrides == nil || len(overrides.Raw) == 0 {
+		return nil
+	}
+	original, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, overrides.Raw, corev1.PodTemplateSpec{})
+	if err != nil {
+		return err
+	}
+	patchedTemplate := corev1.PodTemplateSpec{}
+	if err = json.Unmarshal(patched, &patchedTemplate); err != nil {
+		return err
+	}
+	*template = patchedTemplate
+	return nil
+}
+
+// PodSpecReferences returns the names of the Secrets and ConfigMaps that must exist for pods with the given spec to start.
+// References that are marked as optional are not included.
+func PodSpecReferences(podSpec *corev1.PodSpec) (secrets []string, configMaps []string) {
+	secretSet := map[string]bool{}
+	configMapSet := map[string]bool{}
+	isRequired := func(optional *bool) bool {
+		return optional == nil || !*optional
+	}
+
+	for _, volume := range podSpec.Volumes {
+		if volume.Secret != nil && isRequired(volume.Secret.Optional) {
+			secretSet[volume.Secret.SecretName] = true
+		}
+		if volume.ConfigMap != nil && isRequired(volume.ConfigMap.Optional) {
+			configMapSet[volume.ConfigMap.Name] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil && isRequired(source.Secret.Optional) {
+					secretSet[source.Secret.Name] = true
+				}
+				if source.ConfigMap != nil && isRequired(source.ConfigMap.Optional) {
+					configMapSet[source.ConfigMap.Name] = true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, container := range containers {
+		for _, envVar := range container.Env {
+			if envVar.ValueFrom == nil {
+				continue
+			}
+			if ref := envVar.ValueFrom.SecretKeyRef; ref != nil && isRequired(ref.Optional) {
+				secretSet[ref.Name] = true
+			}
+			if ref := envVar.ValueFrom.ConfigMapKeyRef; ref != nil && isRequired(ref.Optional) {
+				configMapSet[ref.Name] = true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if ref := envFrom.SecretRef; ref != nil && isRequired(ref.Optional) {
+				secretSet[ref.Name] = true
+			}
+			if ref := envFrom.ConfigMapRef; ref != nil && isRequired(ref.Optional) {
+				configMapSet[ref.Name] = true
+			}
+		}
+	}
+
+	for name := range secretSet {
+		secrets = append(secrets, name)
+	}
+	for name := range configMapSet {
+		configMaps = append(configMaps, name)
+	}
+	sort.Strings(secrets)
+	sort.Strings(configMaps)
+	return secrets, configMaps
+}
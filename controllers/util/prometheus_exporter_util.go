@@ -15,13 +15,17 @@ limitations under the License.
 package util
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	extv1 "k8s.io/api/extensions/v1beta1"
+	netv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -31,21 +35,52 @@ const (
 	ExtSolrMetricsPort  = 80
 
 	DefaultPrometheusExporterEntrypoint = "/opt/solr/contrib/prometheus-exporter/bin/solr-exporter"
+
+	PrometheusExporterConfigMapKey           = "solr-prometheus-exporter.xml"
+	PrometheusExporterConfigXmlMd5Annotation = "solr.apache.org/exporterConfigXmlMd5"
+
+	// The replicas of the exporter Deployment before it was suspended, which are restored when the exporter is resumed
+	ExporterReplicasBeforeSuspendAnnotation = "solr.apache.org/replicasBeforeSuspend"
+
+	ServiceMonitorGroupVersion = "monitoring.coreos.com/v1"
+	ServiceMonitorKind         = "ServiceMonitor"
+
+	SolrTLSTrustStoreVolume = "solr-tls-truststore"
+	SolrTLSKeyStoreVolume   = "solr-tls-keystore"
+	SolrTLSTrustStorePath   = "/var/solr/tls/truststore"
+	SolrTLSKeyStorePath     = "/var/solr/tls/keystore"
 )
 
+// ServiceMonitorGVK is the GroupVersionKind of the prometheus-operator ServiceMonitor
+var ServiceMonitorGVK = schema.FromAPIVersionAndKind(ServiceMonitorGroupVersion, ServiceMonitorKind)
+
 // SolrConnectionInfo defines how to connect to a cloud or standalone solr instance.
 // One, and only one, of Cloud or Standalone must be provided.
 type SolrConnectionInfo struct {
 	CloudZkConnnectionString string
 	StandaloneAddress        string
+
+	// The ZooKeeper ACL that the exporter reads the znodes of the cloud with
+	CloudZkACL *solr.ZookeeperACL
+
+	// The TLS options that the exporter connects to the ZooKeeper of the cloud with
+	CloudZkTLS *solr.ZookeeperTLSOptions
 }
 
 // GenerateSolrPrometheusExporterDeployment returns a new appsv1.Deployment pointer generated for the SolrCloud Prometheus Exporter instance
 // solrPrometheusExporter: SolrPrometheusExporter instance
-func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrPrometheusExporter, solrConnectionInfo SolrConnectionInfo) *appsv1.Deployment {
+// configXmlMd5: the MD5 hash of the exporter config, used to restart the exporter when the config changes
+func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrPrometheusExporter, solrConnectionInfo SolrConnectionInfo, configXmlMd5 string) *appsv1.Deployment {
 	gracePeriodTerm := int64(10)
-	singleReplica := int32(1)
+	replicas := int32(1)
 	fsGroup := int64(SolrMetricsPort)
+	defaultHandler := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Scheme: corev1.URISchemeHTTP,
+			Path:   "/metrics",
+			Port:   intstr.FromInt(SolrMetricsPort),
+		},
+	}
 
 	labels := solrPrometheusExporter.SharedLabelsWith(solrPrometheusExporter.GetLabels())
 	var annotations map[string]string
@@ -57,10 +92,17 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 	podLabels := labels
 	var podAnnotations map[string]string
 
+	// Record the ZK connection string used, so that it is clear which cloud the exporter is connected to
+	if solrConnectionInfo.CloudZkConnnectionString != "" {
+		annotations = map[string]string{
+			SolrZKConnectionStringAnnotation: solrConnectionInfo.CloudZkConnnectionString,
+		}
+	}
+
 	customDeploymentOptions := solrPrometheusExporter.Spec.CustomKubeOptions.DeploymentOptions
 	if nil != customDeploymentOptions {
 		labels = MergeLabelsOrAnnotations(labels, customDeploymentOptions.Labels)
-		annotations = customDeploymentOptions.Annotations
+		annotations = MergeLabelsOrAnnotations(annotations, customDeploymentOptions.Annotations)
 	}
 
 	customPodOptions := solrPrometheusExporter.Spec.CustomKubeOptions.PodOptions
@@ -69,6 +111,13 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 		podAnnotations = customPodOptions.Annotations
 	}
 
+	// Changes to the command line arguments roll the pods on their own, but changes to the config file do not.
+	if configXmlMd5 != "" {
+		podAnnotations = MergeLabelsOrAnnotations(map[string]string{
+			PrometheusExporterConfigXmlMd5Annotation: configXmlMd5,
+		}, podAnnotations)
+	}
+
 	var solrVolumes []corev1.Volume
 	var volumeMounts []corev1.VolumeMount
 	exporterArgs := []string{
@@ -87,8 +136,8 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 		exporterArgs = append(exporterArgs, "-b", solrConnectionInfo.StandaloneAddress)
 	}
 
-	// Only add the config if it is passed in from the user. Otherwise, use the default.
-	if solrPrometheusExporter.Spec.Config != "" {
+	// Only add the config if it is passed in from the user, or generated for the metricsCollections. Otherwise, use the default.
+	if solrPrometheusExporter.UsesMetricsConfigMap() {
 		solrVolumes = []corev1.Volume{{
 			Name: "solr-prometheus-exporter-xml",
 			VolumeSource: corev1.VolumeSource{
@@ -98,8 +147,8 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 					},
 					Items: []corev1.KeyToPath{
 						{
-							Key:  "solr-prometheus-exporter.xml",
-							Path: "solr-prometheus-exporter.xml",
+							Key:  PrometheusExporterConfigMapKey,
+							Path: PrometheusExporterConfigMapKey,
 						},
 					},
 				},
@@ -120,9 +169,44 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 
 	// Add Custom EnvironmentVariables to the solr container
 	var envVars []corev1.EnvVar
+	var envFrom []corev1.EnvFromSource
+
+	// The system properties of the exporter are passed to its JVM through JAVA_OPTS
+	var javaOpts []string
+
+	// Setup the truststore and keystore needed to connect to Solr over TLS
+	if tlsOptions := solrPrometheusExporter.Spec.SolrReference.SolrTLS; tlsOptions != nil {
+		tlsVolumes, tlsVolumeMounts, tlsEnvVars, tlsJavaOpts := generateSolrTLSConfig(tlsOptions)
+		solrVolumes = append(solrVolumes, tlsVolumes...)
+		volumeMounts = append(volumeMounts, tlsVolumeMounts...)
+		envVars = append(envVars, tlsEnvVars...)
+		javaOpts = append(javaOpts, tlsJavaOpts...)
+	}
+
+	// The znodes of a cloud protected by ZooKeeper ACLs can only be read with the digest credentials,
+	// and a ZooKeeper serving TLS can only be connected to with the ZooKeeper TLS settings
+	if solrConnectionInfo.CloudZkConnnectionString != "" {
+		if zkReaderVars := zkReaderEnvVars(solrConnectionInfo.CloudZkACL, solrConnectionInfo.CloudZkTLS); len(zkReaderVars) > 0 {
+			envVars = append(envVars, zkReaderVars...)
+			javaOpts = append(javaOpts, "$("+ZkCredsAndACLsEnvVar+")")
+		}
+		zkVolumes, zkVolumeMounts := zkTLSVolumes(solrConnectionInfo.CloudZkTLS)
+		solrVolumes = append(solrVolumes, zkVolumes...)
+		volumeMounts = append(volumeMounts, zkVolumeMounts...)
+	}
+
+	if len(javaOpts) > 0 {
+		envVars = append(envVars, corev1.EnvVar{Name: "JAVA_OPTS", Value: strings.Join(javaOpts, " ")})
+	}
+
+	if solrPrometheusExporter.Spec.JavaMemory != "" {
+		envVars = append(envVars, corev1.EnvVar{Name: "JAVA_HEAP", Value: solrPrometheusExporter.Spec.JavaMemory})
+	}
+
 	if nil != customPodOptions {
-		// Add environment variables to container
-		envVars = append(envVars, customPodOptions.EnvVariables...)
+		// Add environment variables to container, user provided values take precedence over the operator's
+		envVars = MergeEnvVars(envVars, customPodOptions.EnvVariables)
+		envFrom = customPodOptions.EnvFrom
 
 		// Add Custom Volumes to pod
 		for _, volume := range customPodOptions.Volumes {
@@ -136,6 +220,14 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 		}
 	}
 
+	// A suspended exporter is created without any replicas, and will be scaled to a single replica when resumed
+	if solrPrometheusExporter.Spec.Suspend {
+		annotations = MergeLabelsOrAnnotations(annotations, map[string]string{
+			ExporterReplicasBeforeSuspendAnnotation: strconv.Itoa(int(replicas)),
+		})
+		replicas = 0
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        solrPrometheusExporter.MetricsDeploymentName(),
@@ -147,7 +239,7 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 			Selector: &metav1.LabelSelector{
 				MatchLabels: selectorLabels,
 			},
-			Replicas: &singleReplica,
+			Replicas: &replicas,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      podLabels,
@@ -169,17 +261,23 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 							Command:         []string{entrypoint},
 							Args:            exporterArgs,
 							Env:             envVars,
+							EnvFrom:         envFrom,
 
 							LivenessProbe: &corev1.Probe{
-								InitialDelaySeconds: 20,
-								PeriodSeconds:       10,
-								Handler: corev1.Handler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Scheme: corev1.URISchemeHTTP,
-										Path:   "/metrics",
-										Port:   intstr.FromInt(SolrMetricsPort),
-									},
-								},
+								InitialDelaySeconds: DefaultLivenessProbeInitialDelaySeconds,
+								TimeoutSeconds:      DefaultLivenessProbeTimeoutSeconds,
+								SuccessThreshold:    DefaultLivenessProbeSuccessThreshold,
+								FailureThreshold:    DefaultLivenessProbeFailureThreshold,
+								PeriodSeconds:       DefaultLivenessProbePeriodSeconds,
+								Handler:             defaultHandler,
+							},
+							ReadinessProbe: &corev1.Probe{
+								InitialDelaySeconds: DefaultReadinessProbeInitialDelaySeconds,
+								TimeoutSeconds:      DefaultReadinessProbeTimeoutSeconds,
+								SuccessThreshold:    DefaultReadinessProbeSuccessThreshold,
+								FailureThreshold:    DefaultReadinessProbeFailureThreshold,
+								PeriodSeconds:       DefaultReadinessProbePeriodSeconds,
+								Handler:             defaultHandler,
 							},
 						},
 					},
@@ -188,12 +286,16 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 		},
 	}
 
-	if solrPrometheusExporter.Spec.Image.ImagePullSecret != "" {
-		deployment.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{
-			{Name: solrPrometheusExporter.Spec.Image.ImagePullSecret},
-		}
+	if nil != customDeploymentOptions && nil != customDeploymentOptions.Strategy {
+		deployment.Spec.Strategy = *customDeploymentOptions.Strategy
 	}
 
+	var additionalPullSecrets []corev1.LocalObjectReference
+	if nil != customPodOptions {
+		additionalPullSecrets = customPodOptions.ImagePullSecrets
+	}
+	deployment.Spec.Template.Spec.ImagePullSecrets = MergeImagePullSecrets(solrPrometheusExporter.Spec.Image.ImagePullSecret, additionalPullSecrets)
+
 	// DEPRECATED: Replaced by the options below
 	if solrPrometheusExporter.Spec.PodPolicy.Affinity != nil {
 		deployment.Spec.Template.Spec.Affinity = solrPrometheusExporter.Spec.PodPolicy.Affinity
@@ -217,6 +319,10 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 			deployment.Spec.Template.Spec.SecurityContext = customPodOptions.PodSecurityContext
 		}
 
+		if customPodOptions.ContainerSecurityContext != nil {
+			deployment.Spec.Template.Spec.Containers[0].SecurityContext = customPodOptions.ContainerSecurityContext
+		}
+
 		if customPodOptions.Tolerations != nil {
 			deployment.Spec.Template.Spec.Tolerations = customPodOptions.Tolerations
 		}
@@ -224,11 +330,141 @@ func GenerateSolrPrometheusExporterDeployment(solrPrometheusExporter *solr.SolrP
 		if customPodOptions.NodeSelector != nil {
 			deployment.Spec.Template.Spec.NodeSelector = customPodOptions.NodeSelector
 		}
+
+		if customPodOptions.LivenessProbe != nil {
+			deployment.Spec.Template.Spec.Containers[0].LivenessProbe = fillProbe(*customPodOptions.LivenessProbe, DefaultLivenessProbeInitialDelaySeconds, DefaultLivenessProbeTimeoutSeconds, DefaultLivenessProbeSuccessThreshold, DefaultLivenessProbeFailureThreshold, DefaultLivenessProbePeriodSeconds, &defaultHandler)
+		}
+
+		if customPodOptions.ReadinessProbe != nil {
+			deployment.Spec.Template.Spec.Containers[0].ReadinessProbe = fillProbe(*customPodOptions.ReadinessProbe, DefaultReadinessProbeInitialDelaySeconds, DefaultReadinessProbeTimeoutSeconds, DefaultReadinessProbeSuccessThreshold, DefaultReadinessProbeFailureThreshold, DefaultReadinessProbePeriodSeconds, &defaultHandler)
+		}
+
+		if customPodOptions.StartupProbe != nil {
+			deployment.Spec.Template.Spec.Containers[0].StartupProbe = fillProbe(*customPodOptions.StartupProbe, DefaultStartupProbeInitialDelaySeconds, DefaultStartupProbeTimeoutSeconds, DefaultStartupProbeSuccessThreshold, DefaultStartupProbeFailureThreshold, DefaultStartupProbePeriodSeconds, &defaultHandler)
+		}
+
+		if customPodOptions.Lifecycle != nil {
+			deployment.Spec.Template.Spec.Containers[0].Lifecycle = customPodOptions.Lifecycle
+		}
+
+		if customPodOptions.ServiceAccountName != "" {
+			deployment.Spec.Template.Spec.ServiceAccountName = customPodOptions.ServiceAccountName
+		}
+
+		if customPodOptions.PriorityClassName != "" {
+			deployment.Spec.Template.Spec.PriorityClassName = customPodOptions.PriorityClassName
+		}
+
+		if customPodOptions.SchedulerName != "" {
+			deployment.Spec.Template.Spec.SchedulerName = customPodOptions.SchedulerName
+		}
+
+		if customPodOptions.RuntimeClassName != nil {
+			deployment.Spec.Template.Spec.RuntimeClassName = customPodOptions.RuntimeClassName
+		}
+
+		if customPodOptions.DNSPolicy != "" {
+			deployment.Spec.Template.Spec.DNSPolicy = customPodOptions.DNSPolicy
+		}
+
+		if customPodOptions.DNSConfig != nil {
+			deployment.Spec.Template.Spec.DNSConfig = customPodOptions.DNSConfig
+		}
+
+		if len(customPodOptions.HostAliases) > 0 {
+			deployment.Spec.Template.Spec.HostAliases = MergeHostAliases(nil, customPodOptions.HostAliases)
+		}
+
+		if len(customPodOptions.SidecarContainers) > 0 {
+			deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, customPodOptions.SidecarContainers...)
+		}
+
+		if len(customPodOptions.InitContainers) > 0 {
+			deployment.Spec.Template.Spec.InitContainers = customPodOptions.InitContainers
+		}
 	}
 
 	return deployment
 }
 
+// CopyExporterReplicas sets the replicas of the generated exporter Deployment to those of the existing Deployment.
+// The replicas are only changed when the exporter is suspended or resumed, so that the operator does not fight an HPA or manual scaling.
+// Returns true if the existing Deployment needs to be updated for a change that is not copied by CopyDeploymentFields.
+func CopyExporterReplicas(solrPrometheusExporter *solr.SolrPrometheusExporter, from, to *appsv1.Deployment) (requireUpdate bool) {
+	from.Spec.Replicas = to.Spec.Replicas
+	previousReplicas, suspended := to.Annotations[ExporterReplicasBeforeSuspendAnnotation]
+	if solrPrometheusExporter.Spec.Suspend {
+		if !suspended {
+			replicas := int32(1)
+			if to.Spec.Replicas != nil {
+				replicas = *to.Spec.Replicas
+			}
+			previousReplicas = strconv.Itoa(int(replicas))
+			zeroReplicas := int32(0)
+			from.Spec.Replicas = &zeroReplicas
+		}
+		from.Annotations[ExporterReplicasBeforeSuspendAnnotation] = previousReplicas
+	} else if suspended {
+		replicas := int32(1)
+		if parsed, err := strconv.ParseInt(previousReplicas, 10, 32); err == nil && parsed > 0 {
+			replicas = int32(parsed)
+		}
+		from.Spec.Replicas = &replicas
+		log.Info("Update required because:", "Annotation "+ExporterReplicasBeforeSuspendAnnotation+" removed, restoring replicas", replicas)
+		delete(to.Annotations, ExporterReplicasBeforeSuspendAnnotation)
+		requireUpdate = true
+	}
+	return requireUpdate
+}
+
+// generateSolrTLSConfig returns the volumes, volumeMounts and environment variables needed to use the given TLS options
+// when connecting to Solr, and the system properties with the truststore and keystore settings, which are passed to the JVM through JAVA_OPTS.
+func generateSolrTLSConfig(tlsOptions *solr.SolrTLSOptions) (volumes []corev1.Volume, volumeMounts []corev1.VolumeMount, envVars []corev1.EnvVar, javaOpts []string) {
+	if tlsOptions.TrustStoreSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: SolrTLSTrustStoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tlsOptions.TrustStoreSecret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTLSTrustStoreVolume, MountPath: SolrTLSTrustStorePath, ReadOnly: true})
+		javaOpts = append(javaOpts, "-Djavax.net.ssl.trustStore="+SolrTLSTrustStorePath+"/"+tlsOptions.TrustStoreSecret.Key)
+
+		if tlsOptions.TrustStorePasswordSecret != nil {
+			envVars = append(envVars, corev1.EnvVar{
+				Name:      "SOLR_SSL_TRUST_STORE_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: tlsOptions.TrustStorePasswordSecret},
+			})
+			javaOpts = append(javaOpts, "-Djavax.net.ssl.trustStorePassword=$(SOLR_SSL_TRUST_STORE_PASSWORD)")
+		}
+	}
+
+	if tlsOptions.KeyStoreSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: SolrTLSKeyStoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tlsOptions.KeyStoreSecret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTLSKeyStoreVolume, MountPath: SolrTLSKeyStorePath, ReadOnly: true})
+		javaOpts = append(javaOpts, "-Djavax.net.ssl.keyStore="+SolrTLSKeyStorePath+"/"+tlsOptions.KeyStoreSecret.Key)
+
+		if tlsOptions.KeyStorePasswordSecret != nil {
+			envVars = append(envVars, corev1.EnvVar{
+				Name:      "SOLR_SSL_KEY_STORE_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: tlsOptions.KeyStorePasswordSecret},
+			})
+			javaOpts = append(javaOpts, "-Djavax.net.ssl.keyStorePassword=$(SOLR_SSL_KEY_STORE_PASSWORD)")
+		}
+	}
+
+	return volumes, volumeMounts, envVars, javaOpts
+}
+
 // GenerateMetricsConfigMap returns a new corev1.ConfigMap pointer generated for the Solr Prometheus Exporter instance solr-prometheus-exporter.xml
 // solrPrometheusExporter: SolrPrometheusExporter instance
 func GenerateMetricsConfigMap(solrPrometheusExporter *solr.SolrPrometheusExporter) *corev1.ConfigMap {
@@ -249,12 +485,203 @@ func GenerateMetricsConfigMap(solrPrometheusExporter *solr.SolrPrometheusExporte
 			Annotations: annotations,
 		},
 		Data: map[string]string{
-			"solr-prometheus-exporter.xml": solrPrometheusExporter.Spec.Config,
+			PrometheusExporterConfigMapKey: MetricsConfigXml(solrPrometheusExporter),
 		},
 	}
 	return configMap
 }
 
+// MetricsConfigXml returns the exporter config that the operator provides through the metrics ConfigMap,
+// which is either the custom config of the user or a config generated for the metricsCollections.
+func MetricsConfigXml(solrPrometheusExporter *solr.SolrPrometheusExporter) string {
+	if solrPrometheusExporter.Spec.Config != "" {
+		return solrPrometheusExporter.Spec.Config
+	}
+	if len(solrPrometheusExporter.Spec.MetricsCollections) > 0 {
+		return GenerateFilteredMetricsConfig(solrPrometheusExporter.Spec.MetricsCollections, solrPrometheusExporter.Spec.ScrapeCores)
+	}
+	return ""
+}
+
+// GenerateFilteredMetricsConfig returns an exporter config, derived from the default config of the exporter,
+// that only runs collection-level queries for the given collections, and core-level queries for their cores if scrapeCores is true.
+// The node, JVM and Jetty metrics are always scraped.
+// The collection names must have been validated, as they are used in the XML and jq queries as is.
+func GenerateFilteredMetricsConfig(collections []string, scrapeCores bool) string {
+	config := &strings.Builder{}
+	config.WriteString(`<?xml version="1.0" encoding="UTF-8" ?>
+<config>
+  <rules>
+    <ping>
+`)
+	for _, collection := range collections {
+		fmt.Fprintf(config, metricsConfigPingRequest, collection)
+	}
+	config.WriteString(`    </ping>
+    <metrics>
+`)
+	config.WriteString(metricsConfigNodeRequest)
+	if scrapeCores {
+		collectionMatches := make([]string, len(collections))
+		for i, collection := range collections {
+			collectionMatches[i] = fmt.Sprintf(`$parts[2] == "%s"`, collection)
+		}
+		fmt.Fprintf(config, metricsConfigCoreRequest, strings.Join(collectionMatches, " or "))
+	}
+	config.WriteString(`    </metrics>
+    <collections>
+`)
+	for i, collection := range collections {
+		// The live nodes are the same in every response, so they are only reported once
+		liveNodesQuery := ""
+		if i == 0 {
+			liveNodesQuery = metricsConfigLiveNodesQuery
+		}
+		fmt.Fprintf(config, metricsConfigClusterStatusRequest, collection, liveNodesQuery)
+	}
+	config.WriteString(`    </collections>
+  </rules>
+</config>
+`)
+	return config.String()
+}
+
+const metricsConfigPingRequest = `      <lst name="request">
+        <lst name="query">
+          <str name="collection">%s</str>
+          <str name="path">/admin/ping</str>
+        </lst>
+        <arr name="jsonQueries">
+          <str>
+            . as $object | $object |
+            (if $object.status == "OK" then 1.0 else 0.0 end) as $value |
+            {
+              name         : "solr_ping",
+              type         : "GAUGE",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/ping.html",
+              label_names  : [],
+              label_values : [],
+              value        : $value
+            }
+          </str>
+        </arr>
+      </lst>
+`
+
+const metricsConfigNodeRequest = `      <lst name="request">
+        <lst name="query">
+          <str name="path">/admin/metrics</str>
+          <lst name="params">
+            <str name="group">jvm,jetty,node</str>
+          </lst>
+        </lst>
+        <arr name="jsonQueries">
+          <str>
+            .metrics["solr.jvm"] | to_entries | .[] | select(.value | type == "number") as $object |
+            {
+              name         : "solr_metrics_jvm",
+              type         : "GAUGE",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/metrics-reporting.html",
+              label_names  : ["item"],
+              label_values : [$object.key],
+              value        : $object.value
+            }
+          </str>
+          <str>
+            .metrics["solr.jetty"] | to_entries | .[] | select(.key | endswith("xx-responses")) as $object |
+            ($object.key | split(".")) as $items |
+            ($items[($items | length) - 1] | split("-"))[0] as $status |
+            {
+              name         : "solr_metrics_jetty_response_total",
+              type         : "COUNTER",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/metrics-reporting.html",
+              label_names  : ["status"],
+              label_values : [$status],
+              value        : $object.value.count
+            }
+          </str>
+          <str>
+            .metrics["solr.node"] | to_entries | .[] | select(.value | type == "number") as $object |
+            {
+              name         : "solr_metrics_node",
+              type         : "GAUGE",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/metrics-reporting.html",
+              label_names  : ["item"],
+              label_values : [$object.key],
+              value        : $object.value
+            }
+          </str>
+        </arr>
+      </lst>
+`
+
+const metricsConfigCoreRequest = `      <lst name="request">
+        <lst name="query">
+          <str name="path">/admin/metrics</str>
+          <lst name="params">
+            <str name="group">core</str>
+          </lst>
+        </lst>
+        <arr name="jsonQueries">
+          <str>
+            .metrics | to_entries | .[] | select(.key | startswith("solr.core.")) as $parent |
+            ($parent.key | split(".")) as $parts |
+            select(($parts | length) == 5) | select(%s) |
+            $parent.value | to_entries | .[] | select(.value | type == "number") as $object |
+            {
+              name         : "solr_metrics_core",
+              type         : "GAUGE",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/metrics-reporting.html",
+              label_names  : ["collection", "shard", "replica", "item"],
+              label_values : [$parts[2], $parts[3], $parts[4], $object.key],
+              value        : $object.value
+            }
+          </str>
+        </arr>
+      </lst>
+`
+
+const metricsConfigClusterStatusRequest = `      <lst name="request">
+        <lst name="query">
+          <str name="path">/admin/collections</str>
+          <lst name="params">
+            <str name="action">CLUSTERSTATUS</str>
+            <str name="collection">%s</str>
+          </lst>
+        </lst>
+        <arr name="jsonQueries">%s
+          <str>
+            .cluster.collections | to_entries | .[] | . as $object |
+            $object.key as $collection |
+            $object.value.shards | to_entries | .[] | . as $shard |
+            $shard.value.replicas | to_entries | .[] | . as $replica |
+            (if $replica.value.state == "active" then 1.0 else 0.0 end) as $value |
+            {
+              name         : "solr_collections_replica_state",
+              type         : "GAUGE",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/collections-api.html#clusterstatus",
+              label_names  : ["collection", "shard", "replica", "core", "base_url"],
+              label_values : [$collection, $shard.key, $replica.key, $replica.value.core, $replica.value.base_url],
+              value        : $value
+            }
+          </str>
+        </arr>
+      </lst>
+`
+
+const metricsConfigLiveNodesQuery = `
+          <str>
+            .cluster.live_nodes | length as $value |
+            {
+              name         : "solr_collections_live_nodes",
+              type         : "GAUGE",
+              help         : "See following URL: https://lucene.apache.org/solr/guide/collections-api.html#clusterstatus",
+              label_names  : [],
+              label_values : [],
+              value        : $value
+            }
+          </str>`
+
 // GenerateSolrMetricsService returns a new corev1.Service pointer generated for the SolrCloud Prometheus Exporter deployment
 // Metrics will be collected on this service endpoint, as we don't want to double-tick data if multiple exporters are runnning.
 // solrPrometheusExporter: solrPrometheusExporter instance
@@ -265,17 +692,20 @@ func GenerateSolrMetricsService(solrPrometheusExporter *solr.SolrPrometheusExpor
 	}
 	labels := solrPrometheusExporter.SharedLabelsWith(solrPrometheusExporter.GetLabels())
 	labels["service-type"] = "metrics"
+
+	servicePort := MetricsServicePort(solrPrometheusExporter)
+	customOptions := solrPrometheusExporter.Spec.CustomKubeOptions.ServiceOptions
+
 	annotations := map[string]string{
 		"prometheus.io/scrape": "true",
 		"prometheus.io/scheme": "http",
 		"prometheus.io/path":   "/metrics",
-		"prometheus.io/port":   strconv.Itoa(ExtSolrMetricsPort),
+		"prometheus.io/port":   strconv.Itoa(int(servicePort)),
 	}
 
 	selectorLabels := solrPrometheusExporter.SharedLabels()
 	selectorLabels["technology"] = solr.SolrPrometheusExporterTechnologyLabel
 
-	customOptions := solrPrometheusExporter.Spec.CustomKubeOptions.ServiceOptions
 	if nil != customOptions {
 		labels = MergeLabelsOrAnnotations(labels, customOptions.Labels)
 		annotations = MergeLabelsOrAnnotations(annotations, customOptions.Annotations)
@@ -290,30 +720,153 @@ func GenerateSolrMetricsService(solrPrometheusExporter *solr.SolrPrometheusExpor
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
-				{Name: SolrMetricsPortName, Port: ExtSolrMetricsPort, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(SolrMetricsPort)},
+				{Name: SolrMetricsPortName, Port: servicePort, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(SolrMetricsPort)},
 			},
 			Selector: selectorLabels,
 		},
 	}
+	applyServiceOptions(service, customOptions)
 	return service
 }
 
+// GenerateSolrMetricsServiceMonitor returns a new prometheus-operator ServiceMonitor, as an unstructured object, generated for the
+// Solr Prometheus Exporter metrics Service.
+// The prometheus-operator types are not imported, since the operator must run in clusters where the ServiceMonitor CRD is not installed.
+// solrPrometheusExporter: solrPrometheusExporter instance
+func GenerateSolrMetricsServiceMonitor(solrPrometheusExporter *solr.SolrPrometheusExporter) *unstructured.Unstructured {
+	options := solrPrometheusExporter.Spec.ServiceMonitor
+
+	labels := solrPrometheusExporter.SharedLabelsWith(solrPrometheusExporter.GetLabels())
+	labels = MergeLabelsOrAnnotations(labels, options.Labels)
+
+	selectorLabels := map[string]interface{}{}
+	for k, v := range solrPrometheusExporter.SharedLabels() {
+		selectorLabels[k] = v
+	}
+	selectorLabels["service-type"] = "metrics"
+
+	endpoint := map[string]interface{}{
+		"port":   SolrMetricsPortName,
+		"path":   "/metrics",
+		"scheme": "http",
+	}
+	if options.Interval != "" {
+		endpoint["interval"] = options.Interval
+	}
+	if options.ScrapeTimeout != "" {
+		endpoint["scrapeTimeout"] = options.ScrapeTimeout
+	}
+
+	serviceMonitor := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"matchLabels": selectorLabels,
+				},
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{solrPrometheusExporter.GetNamespace()},
+				},
+				"endpoints": []interface{}{endpoint},
+			},
+		},
+	}
+	serviceMonitor.SetGroupVersionKind(ServiceMonitorGVK)
+	serviceMonitor.SetName(solrPrometheusExporter.MetricsServiceMonitorName())
+	serviceMonitor.SetNamespace(solrPrometheusExporter.GetNamespace())
+	serviceMonitor.SetLabels(labels)
+
+	return serviceMonitor
+}
+
+// CopyServiceMonitorFields copies the owned fields from one ServiceMonitor to another
+// Returns true if the fields copied from don't match to.
+func CopyServiceMonitorFields(from, to *unstructured.Unstructured) bool {
+	requireUpdate := false
+
+	toLabels := to.GetLabels()
+	if toLabels == nil {
+		toLabels = map[string]string{}
+	}
+	for k, v := range from.GetLabels() {
+		if toLabels[k] != v {
+			requireUpdate = true
+			log.Info("Update Label", "label", k, "newValue", v, "oldValue", toLabels[k])
+			toLabels[k] = v
+		}
+	}
+	to.SetLabels(toLabels)
+
+	if !DeepEqualWithNils(to.Object["spec"], from.Object["spec"]) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec changed from", to.Object["spec"], "To:", from.Object["spec"])
+		to.Object["spec"] = from.Object["spec"]
+	}
+
+	return requireUpdate
+}
+
+// GenerateMetricsIngress returns a new Ingress pointer generated for the Solr Prometheus Exporter metrics endpoint
+// solrPrometheusExporter: solrPrometheusExporter instance
+func GenerateMetricsIngress(solrPrometheusExporter *solr.SolrPrometheusExporter) *netv1.Ingress {
+	labels := solrPrometheusExporter.SharedLabelsWith(solrPrometheusExporter.GetLabels())
+	var annotations map[string]string
+	var ingressClassName *string
+
+	customOptions := solrPrometheusExporter.Spec.CustomKubeOptions.IngressOptions
+	if nil != customOptions {
+		labels = MergeLabelsOrAnnotations(labels, customOptions.Labels)
+		annotations = customOptions.Annotations
+		ingressClassName = customOptions.IngressClassName
+	}
+
+	external := solrPrometheusExporter.Spec.External
+	ingressRule := CreateMetricsIngressRule(solrPrometheusExporter, external.DomainName)
+
+	ingress := &netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        solrPrometheusExporter.MetricsIngressName(),
+			Namespace:   solrPrometheusExporter.GetNamespace(),
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: netv1.IngressSpec{
+			IngressClassName: ingressClassName,
+			Rules:            []netv1.IngressRule{ingressRule},
+		},
+	}
+
+	if external.IngressTLSSecret != "" {
+		ingress.Spec.TLS = []netv1.IngressTLS{
+			{
+				Hosts:      []string{ingressRule.Host},
+				SecretName: external.IngressTLSSecret,
+			},
+		}
+	}
+	return ingress
+}
+
 // CreateMetricsIngressRule returns a new Ingress Rule generated for the solr metrics endpoint
-// This is not currently used, as an ingress is not created for the metrics endpoint.
-// solrCloud: SolrCloud instance
-// nodeName: string Name of the node
+// solrPrometheusExporter: solrPrometheusExporter instance
 // ingressBaseDomain: string base domain for the ingress controller
-func CreateMetricsIngressRule(solrPrometheusExporter *solr.SolrPrometheusExporter, ingressBaseDomain string) extv1.IngressRule {
+func CreateMetricsIngressRule(solrPrometheusExporter *solr.SolrPrometheusExporter, ingressBaseDomain string) netv1.IngressRule {
 	externalAddress := solrPrometheusExporter.MetricsIngressUrl(ingressBaseDomain)
-	return extv1.IngressRule{
+	pathType := netv1.PathTypePrefix
+	return netv1.IngressRule{
 		Host: externalAddress,
-		IngressRuleValue: extv1.IngressRuleValue{
-			HTTP: &extv1.HTTPIngressRuleValue{
-				Paths: []extv1.HTTPIngressPath{
+		IngressRuleValue: netv1.IngressRuleValue{
+			HTTP: &netv1.HTTPIngressRuleValue{
+				Paths: []netv1.HTTPIngressPath{
 					{
-						Backend: extv1.IngressBackend{
-							ServiceName: solrPrometheusExporter.MetricsServiceName(),
-							ServicePort: intstr.FromInt(ExtSolrMetricsPort),
+						Path:     "/",
+						PathType: &pathType,
+						Backend: netv1.IngressBackend{
+							Service: &netv1.IngressServiceBackend{
+								Name: solrPrometheusExporter.MetricsServiceName(),
+								Port: netv1.ServiceBackendPort{
+									Number: MetricsServicePort(solrPrometheusExporter),
+								},
+							},
 						},
 					},
 				},
@@ -321,3 +874,52 @@ func CreateMetricsIngressRule(solrPrometheusExporter *solr.SolrPrometheusExporte
 		},
 	}
 }
+
+// MetricsExternalAddress returns the address that the metrics endpoint can be reached at from outside of the Kube cluster
+// solrPrometheusExporter: solrPrometheusExporter instance
+func MetricsExternalAddress(solrPrometheusExporter *solr.SolrPrometheusExporter) string {
+	external := solrPrometheusExporter.Spec.External
+	if external == nil {
+		return ""
+	}
+	scheme := "http"
+	if external.IngressTLSSecret != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + solrPrometheusExporter.MetricsIngressUrl(external.DomainName) + "/metrics"
+}
+
+// CopyMetricsIngressFields copies the owned fields from one Ingress to another
+func CopyMetricsIngressFields(from, to *netv1.Ingress) bool {
+	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
+
+	if !DeepEqualWithNils(to.Spec.Rules, from.Spec.Rules) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Rules changed from", to.Spec.Rules, "To:", from.Spec.Rules)
+		to.Spec.Rules = from.Spec.Rules
+	}
+
+	if !DeepEqualWithNils(to.Spec.TLS, from.Spec.TLS) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.TLS changed from", to.Spec.TLS, "To:", from.Spec.TLS)
+		to.Spec.TLS = from.Spec.TLS
+	}
+
+	if !DeepEqualWithNils(to.Spec.IngressClassName, from.Spec.IngressClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.IngressClassName changed from", to.Spec.IngressClassName, "To:", from.Spec.IngressClassName)
+		to.Spec.IngressClassName = from.Spec.IngressClassName
+	}
+
+	return requireUpdate
+}
+
+// MetricsServicePort returns the port that the metrics Service listens on
+// solrPrometheusExporter: solrPrometheusExporter instance
+func MetricsServicePort(solrPrometheusExporter *solr.SolrPrometheusExporter) int32 {
+	customOptions := solrPrometheusExporter.Spec.CustomKubeOptions.ServiceOptions
+	if nil != customOptions && customOptions.Port > 0 {
+		return customOptions.Port
+	}
+	return ExtSolrMetricsPort
+}
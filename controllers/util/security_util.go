@@ -0,0 +1,226 @@
+/*
+Copyright 2019 Bloomberg Finance LP.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	solr "github.com/bloomberg/solr-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// The user that the operator authenticates to Solr as, when it generates the credentials
+	OperatorBasicAuthUser = "k8s-oper"
+
+	// The users of the generated security.json, besides the operator, whose passwords are stored in the bootstrap Secret under their names
+	AdminBasicAuthUser = "admin"
+	SolrBasicAuthUser  = "solr"
+
+	SecurityJsonFile = "security.json"
+
+	// The environment variables with the credentials of the operator, which the probes authenticate with
+	BasicAuthUserEnvVar     = "SOLR_BASIC_AUTH_USER"
+	BasicAuthPasswordEnvVar = "SOLR_BASIC_AUTH_PASSWORD"
+
+	generatedPasswordLength = 24
+	passwordSaltLength      = 32
+)
+
+// GenerateBasicAuthSecret returns a new Secret of type kubernetes.io/basic-auth with the generated credentials of the operator
+func GenerateBasicAuthSecret(solrCloud *solr.SolrCloud) (*corev1.Secret, error) {
+	password, err := generatePassword()
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      solrCloud.BasicAuthSecretName(),
+			Namespace: solrCloud.GetNamespace(),
+			Labels:    solrCloud.SharedLabelsWith(solrCloud.GetLabels()),
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte(OperatorBasicAuthUser),
+			corev1.BasicAuthPasswordKey: []byte(password),
+		},
+	}, nil
+}
+
+// GenerateSecurityBootstrapSecret returns a new Secret with the security.json to bootstrap into Zookeeper,
+// and the generated passwords of its admin and solr users.
+// The operator is given the admin role with the given credentials.
+func GenerateSecurityBootstrapSecret(solrCloud *solr.SolrCloud, operatorAuth *SolrBasicAuth) (*corev1.Secret, error) {
+	passwords := map[string]string{operatorAuth.Username: operatorAuth.Password}
+	for _, user := range []string{AdminBasicAuthUser, SolrBasicAuthUser} {
+		password, err := generatePassword()
+		if err != nil {
+			return nil, err
+		}
+		passwords[user] = password
+	}
+	userRoles := map[string][]string{
+		operatorAuth.Username: {"admin"},
+		AdminBasicAuthUser:    {"admin"},
+		SolrBasicAuthUser:     {"users"},
+	}
+
+	securityJson, err := GenerateSecurityJson(passwords, userRoles, solrCloud.Spec.SolrSecurity.ProbesRequireAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      solrCloud.SecurityBootstrapSecretName(),
+			Namespace: solrCloud.GetNamespace(),
+			Labels:    solrCloud.SharedLabelsWith(solrCloud.GetLabels()),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			AdminBasicAuthUser: []byte(passwords[AdminBasicAuthUser]),
+			SolrBasicAuthUser:  []byte(passwords[SolrBasicAuthUser]),
+			SecurityJsonFile:   []byte(securityJson),
+		},
+	}, nil
+}
+
+// GenerateSecurityJson returns a security.json that enables the BasicAuthPlugin with the given users,
+// and the RuleBasedAuthorizationPlugin with the given roles of the users.
+// The endpoints used by the probes are left open to unauthenticated requests, unless blockUnknown is set.
+func GenerateSecurityJson(passwords map[string]string, userRoles map[string][]string, blockUnknown bool) (string, error) {
+	credentials := make(map[string]string, len(passwords))
+	for user, password := range passwords {
+		salt := make([]byte, passwordSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		credentials[user] = SolrPasswordHash(password, salt)
+	}
+
+	permissions := []map[string]interface{}{
+		{"name": "k8s-probe-0", "role": nil, "collection": nil, "path": "/admin/info/system"},
+		{"name": "k8s-probe-1", "role": nil, "collection": nil, "path": "/admin/info/health"},
+		{"name": "read", "role": []string{"admin", "users"}},
+		{"name": "update", "role": []string{"admin"}},
+		{"name": "security-read", "role": []string{"admin"}},
+		{"name": "security-edit", "role": []string{"admin"}},
+		{"name": "all", "role": []string{"admin"}},
+	}
+
+	securityJson, err := json.MarshalIndent(map[string]interface{}{
+		"authentication": map[string]interface{}{
+			"class":              "solr.BasicAuthPlugin",
+			"blockUnknown":       blockUnknown,
+			"forwardCredentials": false,
+			"realm":              "Solr Basic Auth",
+			"credentials":        credentials,
+		},
+		"authorization": map[string]interface{}{
+			"class":       "solr.RuleBasedAuthorizationPlugin",
+			"user-role":   userRoles,
+			"permissions": permissions,
+		},
+	}, "", "  ")
+	return string(securityJson), err
+}
+
+// SolrPasswordHash returns the password hash stored in the credentials of Solr's BasicAuthPlugin,
+// which is the double SHA-256 hash of the salted password, followed by the salt, both base64 encoded.
+func SolrPasswordHash(password string, salt []byte) string {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(password))
+	saltedHash := hash.Sum(nil)
+
+	hash.Reset()
+	hash.Write(saltedHash)
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil)) + " " + base64.StdEncoding.EncodeToString(salt)
+}
+
+// SolrBasicAuthFromSecret returns the credentials stored in a Secret of type kubernetes.io/basic-auth
+func SolrBasicAuthFromSecret(secret *corev1.Secret) (*SolrBasicAuth, error) {
+	username := string(secret.Data[corev1.BasicAuthUsernameKey])
+	password := string(secret.Data[corev1.BasicAuthPasswordKey])
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("the Secret %s must provide both a %q and a %q", secret.Name, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+	}
+	return &SolrBasicAuth{Username: username, Password: password}, nil
+}
+
+// basicAuthEnvVars returns the environment variables with the credentials of the operator, read from its basic auth Secret
+func basicAuthEnvVars(solrCloud *solr.SolrCloud) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: BasicAuthUserEnvVar,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: solrCloud.BasicAuthSecretName()},
+					Key:                  corev1.BasicAuthUsernameKey,
+				},
+			},
+		},
+		{
+			Name: BasicAuthPasswordEnvVar,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: solrCloud.BasicAuthSecretName()},
+					Key:                  corev1.BasicAuthPasswordKey,
+				},
+			},
+		},
+	}
+}
+
+// execProbeHandler returns a probe handler that calls the given path of the local Solr node from within the Solr container,
+// with the credentials of the operator if the probes require authentication, and its client certificate if Solr requires one.
+// Kubernetes cannot add credentials or a client certificate to an HTTP probe, so wget is run in the Solr container instead.
+// Like the HTTP probes of Kubernetes, the certificate of Solr is not verified when using https.
+func execProbeHandler(solrCloud *solr.SolrCloud, scheme corev1.URIScheme, solrPodPort int, path string) corev1.Handler {
+	wgetCommand := "wget -q -O /dev/null"
+	if solrCloud.Spec.SolrSecurity != nil && solrCloud.Spec.SolrSecurity.ProbesRequireAuth {
+		wgetCommand += " --user=\"${" + BasicAuthUserEnvVar + "}\" --password=\"${" + BasicAuthPasswordEnvVar + "}\""
+	}
+	if scheme == corev1.URISchemeHTTPS {
+		wgetCommand += " --no-check-certificate"
+	}
+	if solrCloud.RequiresClientAuth() {
+		wgetCommand += " --certificate=" + SolrTLSClientCertPath + "/" + corev1.TLSCertKey + " --private-key=" + SolrTLSClientCertPath + "/" + corev1.TLSPrivateKeyKey
+	}
+	probeUrl := strings.ToLower(string(scheme)) + "://localhost:" + strconv.Itoa(solrPodPort) + path
+	return corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"sh", "-c", wgetCommand + " " + probeUrl},
+		},
+	}
+}
+
+// generatePassword returns a random password of printable characters
+func generatePassword() (string, error) {
+	random := make([]byte, generatedPasswordLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
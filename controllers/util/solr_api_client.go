@@ -0,0 +1,256 @@
+/*
+Copyright 2019 Bloomberg Finance LP.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	solr "github.com/bloomberg/solr-operator/api/v1beta1"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	DefaultSolrApiTimeout        = 10 * time.Second
+	DefaultSolrApiRetries        = 2
+	DefaultSolrApiInitialBackoff = 500 * time.Millisecond
+	DefaultSolrApiMaxBackoff     = 2 * time.Second
+	DefaultSolrApiRetryAfter     = 15 * time.Second
+
+	// MaxSolrApiErrorBodyLength is the maximum number of characters of a Solr error response kept in errors and events
+	MaxSolrApiErrorBodyLength = 512
+)
+
+// SolrBasicAuth holds the credentials used to authenticate to Solr with basic auth
+type SolrBasicAuth struct {
+	Username string
+	Password string
+}
+
+// SolrApiClient calls the HTTP APIs of a SolrCloud.
+// Failed calls that Solr may recover from are retried within the call, with an exponential backoff.
+// If they keep failing, a SolrApiRetryLaterError is returned, so that the reconciler can requeue the request instead of failing.
+type SolrApiClient struct {
+	// The base url of the Solr node(s) to call, e.g. "http://foo-solrcloud-common.default"
+	BaseUrl string
+
+	// Credentials to send with every request, if Solr requires basic auth
+	BasicAuth *SolrBasicAuth
+
+	// TLS configuration used to connect to Solr over https
+	TLSConfig *tls.Config
+
+	// The timeout of a single request
+	Timeout time.Duration
+
+	// The number of times a failed request is retried within a single call
+	Retries int
+
+	// The wait before the first retry, doubled for every retry after that up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// How long the reconciler should wait before trying again, once all retries have failed
+	RetryAfter time.Duration
+}
+
+// NewSolrApiClient returns a client for the Solr node(s) behind the given base url, with the default timeout and backoff settings
+func NewSolrApiClient(baseUrl string) *SolrApiClient {
+	return &SolrApiClient{
+		BaseUrl:        baseUrl,
+		Timeout:        DefaultSolrApiTimeout,
+		Retries:        DefaultSolrApiRetries,
+		InitialBackoff: DefaultSolrApiInitialBackoff,
+		MaxBackoff:     DefaultSolrApiMaxBackoff,
+		RetryAfter:     DefaultSolrApiRetryAfter,
+	}
+}
+
+// NewSolrApiClientForCloud returns a client for the common Service of the given SolrCloud
+func NewSolrApiClientForCloud(solrCloud *solr.SolrCloud) *SolrApiClient {
+	baseUrl := solrCloud.Status.InternalCommonAddress
+	if baseUrl == "" {
+		baseUrl = solrCloud.UrlScheme() + "://" + solrCloud.InternalCommonUrl(true)
+	}
+	return NewSolrApiClient(baseUrl)
+}
+
+// SolrApiError is returned when Solr rejects a request, which will not succeed by retrying it
+type SolrApiError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *SolrApiError) Error() string {
+	return fmt.Sprintf("Received bad response code of %d from solr with response: %s", e.StatusCode, e.Body)
+}
+
+// SolrApiRetryLaterError is returned when a request still fails after all retries, but may succeed later
+type SolrApiRetryLaterError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *SolrApiRetryLaterError) Error() string {
+	return fmt.Sprintf("Solr is unavailable, retry in %s: %v", e.RetryAfter, e.Err)
+}
+
+func (e *SolrApiRetryLaterError) Unwrap() error {
+	return e.Err
+}
+
+// IsSolrApiRetryLater returns whether the given error signals that the Solr API call should be tried again later, and after how long
+func IsSolrApiRetryLater(err error) (retryAfter time.Duration, retryLater bool) {
+	retryLaterErr := &SolrApiRetryLaterError{}
+	if errors.As(err, &retryLaterErr) {
+		return retryLaterErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// IsSolrApiError returns whether the given error was returned by a call to the Solr API
+func IsSolrApiError(err error) bool {
+	var apiErr *SolrApiError
+	var retryLaterErr *SolrApiRetryLaterError
+	return errors.As(err, &apiErr) || errors.As(err, &retryLaterErr)
+}
+
+// CallCollectionsApi calls the collections API and decodes the JSON response into the given response object
+func (c *SolrApiClient) CallCollectionsApi(urlParams url.Values, response interface{}) error {
+	return c.Get("/solr/admin/collections", urlParams, response)
+}
+
+// Get calls the given path of the Solr API, retrying requests that fail because Solr is unavailable,
+// and decodes the JSON response into the given response object
+func (c *SolrApiClient) Get(path string, urlParams url.Values, response interface{}) (err error) {
+	return c.call(http.MethodGet, path, urlParams, nil, response)
+}
+
+// Post sends the given object as a JSON body to the given path of the Solr API, retrying requests that fail because Solr is unavailable,
+// and decodes the JSON response into the given response object
+func (c *SolrApiClient) Post(path string, urlParams url.Values, requestBody interface{}, response interface{}) (err error) {
+	body, err := json.Marshal(requestBody)
+	if err != nil {
+		return err
+	}
+	return c.call(http.MethodPost, path, urlParams, body, response)
+}
+
+// call sends a request with the given method and body to the Solr API, retrying it while Solr is unavailable
+func (c *SolrApiClient) call(method string, path string, urlParams url.Values, requestBody []byte, response interface{}) (err error) {
+	// The defaults are added to a copy, so that the caller's params are left unchanged
+	params := url.Values{}
+	for name, values := range urlParams {
+		params[name] = append([]string(nil), values...)
+	}
+	params.Set("wt", "json")
+	requestUrl := c.BaseUrl + path + "?" + params.Encode()
+	httpClient := c.httpClient()
+
+	var body []byte
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.backoff(attempt))
+		}
+		var retry bool
+		if body, retry, err = c.doRequest(httpClient, method, requestUrl, requestBody); err == nil || !retry {
+			break
+		}
+		log.Info("Solr API request failed", "method", method, "url", requestUrl, "attempt", attempt+1, "error", err.Error())
+	}
+
+	if err != nil {
+		var apiErr *SolrApiError
+		if !errors.As(err, &apiErr) || isRetryableStatusCode(apiErr.StatusCode) {
+			err = &SolrApiRetryLaterError{RetryAfter: c.RetryAfter, Err: err}
+		}
+		return err
+	}
+
+	if err = json.Unmarshal(body, response); err != nil {
+		err = fmt.Errorf("could not parse the response of %s: %v", requestUrl, err)
+	}
+	return err
+}
+
+// doRequest sends a single request to Solr and returns the response body, or an error and whether the request can be retried
+func (c *SolrApiClient) doRequest(httpClient *http.Client, method string, requestUrl string, requestBody []byte) (body []byte, retry bool, err error) {
+	var bodyReader io.Reader
+	if requestBody != nil {
+		bodyReader = bytes.NewReader(requestBody)
+	}
+	req, err := http.NewRequest(method, requestUrl, bodyReader)
+	if err != nil {
+		return nil, false, err
+	}
+	if requestBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.BasicAuth != nil {
+		req.SetBasicAuth(c.BasicAuth.Username, c.BasicAuth.Password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// Connection errors and timeouts
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		errorBody := string(body)
+		if len(errorBody) > MaxSolrApiErrorBodyLength {
+			errorBody = errorBody[:MaxSolrApiErrorBodyLength] + "..."
+		}
+		return nil, isRetryableStatusCode(resp.StatusCode), &SolrApiError{StatusCode: resp.StatusCode, Body: errorBody}
+	}
+	return body, false, nil
+}
+
+func (c *SolrApiClient) httpClient() *http.Client {
+	httpClient := &http.Client{Timeout: c.Timeout}
+	if c.TLSConfig != nil {
+		httpClient.Transport = &http.Transport{TLSClientConfig: c.TLSConfig}
+	}
+	return httpClient
+}
+
+// backoff returns the wait before the given retry, which doubles with every retry up to the maximum backoff
+func (c *SolrApiClient) backoff(attempt int) time.Duration {
+	backoff := c.InitialBackoff
+	for i := 1; i < attempt && backoff < c.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > c.MaxBackoff {
+		backoff = c.MaxBackoff
+	}
+	return backoff
+}
+
+// isRetryableStatusCode returns whether a response with the given status code means that Solr is overloaded or unavailable
+func isRetryableStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
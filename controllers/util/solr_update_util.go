@@ -0,0 +1,98 @@
+/*
+Copyright 2019 Bloomberg Finance LP.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// StatefulSetZkConnectionString returns the ZooKeeper connection string, including the chroot, that the Solr pods of the StatefulSet use
+func StatefulSetZkConnectionString(statefulSet *appsv1.StatefulSet) string {
+	if len(statefulSet.Spec.Template.Spec.Containers) == 0 {
+		return ""
+	}
+	for _, envVar := range statefulSet.Spec.Template.Spec.Containers[0].Env {
+		if envVar.Name == "ZK_HOST" {
+			return envVar.Value
+		}
+	}
+	return ""
+}
+
+// IsZkConnectionChange returns whether the desired StatefulSet connects the Solr pods to a different ZooKeeper than the existing StatefulSet
+func IsZkConnectionChange(desired, existing *appsv1.StatefulSet) bool {
+	existingZk := StatefulSetZkConnectionString(existing)
+	desiredZk := StatefulSetZkConnectionString(desired)
+	return existingZk != "" && desiredZk != "" && existingZk != desiredZk
+}
+
+// StatefulSetPartition returns the ordinal at or above which the pods of the StatefulSet are updated to the current revision
+func StatefulSetPartition(statefulSet *appsv1.StatefulSet) int32 {
+	rollingUpdate := statefulSet.Spec.UpdateStrategy.RollingUpdate
+	if rollingUpdate == nil || rollingUpdate.Partition == nil {
+		return 0
+	}
+	return *rollingUpdate.Partition
+}
+
+// SetStatefulSetPartition only allows the pods with an ordinal at or above the partition to be updated to the current revision
+func SetStatefulSetPartition(statefulSet *appsv1.StatefulSet, partition int32) {
+	statefulSet.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type: appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{
+			Partition: &partition,
+		},
+	}
+}
+
+// statefulSetStrategyMatches compares the update strategies of two StatefulSets, treating a missing partition as 0
+func statefulSetStrategyMatches(desired, existing *appsv1.StatefulSet) bool {
+	return desired.Spec.UpdateStrategy.Type == existing.Spec.UpdateStrategy.Type &&
+		StatefulSetPartition(desired) == StatefulSetPartition(existing)
+}
+
+// IsPodUpdatedAndReady returns whether the pod runs the current revision of the StatefulSet and is ready
+func IsPodUpdatedAndReady(pod *corev1.Pod, statefulSet *appsv1.StatefulSet) bool {
+	if pod.Labels[appsv1.StatefulSetRevisionLabel] != statefulSet.Status.UpdateRevision || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// IsStatefulSetScaleComplete returns whether the StatefulSet runs exactly the number of pods it should
+func IsStatefulSetScaleComplete(statefulSet *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	return statefulSet.Status.ObservedGeneration >= statefulSet.Generation && statefulSet.Status.Replicas == replicas
+}
+
+// IsStatefulSetRolloutComplete returns whether all pods of the StatefulSet run its current revision and are ready, and no pods remain to be removed
+func IsStatefulSetRolloutComplete(statefulSet *appsv1.StatefulSet) bool {
+	status := statefulSet.Status
+	return IsStatefulSetScaleComplete(statefulSet) &&
+		status.UpdatedReplicas == status.Replicas &&
+		status.ReadyReplicas == status.Replicas &&
+		(status.UpdateRevision == "" || status.CurrentRevision == status.UpdateRevision)
+}
@@ -17,11 +17,11 @@ limitations under the License.
 package util
 
 import (
-	"encoding/json"
+	"crypto/md5"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
+	"path"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -30,8 +30,10 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extv1 "k8s.io/api/extensions/v1beta1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	netv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -40,6 +42,48 @@ const (
 	BackupRestoreVolume = "backup-restore"
 
 	SolrZKConnectionStringAnnotation = "solr.apache.org/zkConnectionString"
+	SolrXmlMd5Annotation             = "solr.apache.org/solrXmlMd5"
+	Log4j2XmlMd5Annotation           = "solr.apache.org/log4j2XmlMd5"
+	TLSCertMd5Annotation             = "solr.apache.org/tlsCertMd5"
+	SolrNodeAddressingAnnotation     = "solr.apache.org/nodeAddressing"
+	SolrKubeDomainAnnotation         = "solr.apache.org/kubeDomain"
+	LegacyIngressClassAnnotation     = "kubernetes.io/ingress.class"
+	ExternalDnsHostnameAnnotation    = "external-dns.alpha.kubernetes.io/hostname"
+	ExternalDnsTTLAnnotation         = "external-dns.alpha.kubernetes.io/ttl"
+	NginxBackendProtocolAnnotation   = "nginx.ingress.kubernetes.io/backend-protocol"
+	SolrXmlFile                      = "solr.xml"
+	LogXmlFile                       = "log4j2.xml"
+
+	EndpointsZkConnectionStringKey = "zkConnectionString"
+	EndpointsCommonUrlKey          = "commonUrl"
+	EndpointsExternalCommonUrlKey  = "externalCommonUrl"
+	EndpointsNodeUrlsKey           = "nodeUrls"
+	EndpointsExternalNodeUrlsKey   = "externalNodeUrls"
+
+	IstioGroupVersion       = "networking.istio.io/v1beta1"
+	IstioGatewayKind        = "Gateway"
+	IstioVirtualServiceKind = "VirtualService"
+
+	RouteGroupVersion = "route.openshift.io/v1"
+	RouteKind         = "Route"
+
+	SolrDataVolume         = "data"
+	SolrLogsVolume         = "solr-logs"
+	SolrLogsPath           = "/var/solr/logs"
+	SolrLog4j2ConfigVolume = "log4j2-xml"
+	SolrLog4j2ConfigPath   = "/var/solr/log4j2"
+	SolrGCLogsVolume       = "solr-gc-logs"
+	SolrGCLogsPath         = "/var/solr/gc-logs"
+	SolrTlogVolume         = "tlog"
+	SolrTlogPath           = "/var/solr/tlog"
+
+	SolrPluginLibsVolume       = "solr-plugin-libs"
+	SolrPluginLibsSourceVolume = "solr-plugin-libs-source"
+
+	SolrAdditionalConfigVolume = "solr-additional-config"
+
+	SolrLocaltimeVolume = "localtime"
+	ZoneInfoPath        = "/usr/share/zoneinfo"
 
 	DefaultLivenessProbeInitialDelaySeconds = 20
 	DefaultLivenessProbeTimeoutSeconds      = 1
@@ -65,18 +109,28 @@ const (
 // replicas: the number of replicas for the SolrCloud instance
 // storage: the size of the storage for the SolrCloud instance (e.g. 100Gi)
 // zkConnectionString: the connectionString of the ZK instance to connect to
-func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCloudStatus, hostNameIPs map[string]string) *appsv1.StatefulSet {
+// solrXmlMd5: the MD5 hash of the solr.xml, used to restart the Solr pods when it changes
+func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCloudStatus, hostNameIPs map[string]string, solrXmlMd5 string) *appsv1.StatefulSet {
 	gracePeriodTerm := int64(10)
 	solrPodPort := solrCloud.Spec.SolrAddressability.PodPort
 	fsGroup := int64(solrPodPort)
 	defaultMode := int32(420)
+	probeScheme := corev1.URISchemeHTTP
+	if solrCloud.Spec.SolrTLS != nil {
+		probeScheme = corev1.URISchemeHTTPS
+	}
 	defaultHandler := corev1.Handler{
 		HTTPGet: &corev1.HTTPGetAction{
-			Scheme: corev1.URISchemeHTTP,
+			Scheme: probeScheme,
 			Path:   "/solr/admin/info/system",
 			Port:   intstr.FromInt(solrPodPort),
 		},
 	}
+	// Kubernetes cannot authenticate HTTP probes, so the probes call Solr from within the container when Solr requires authentication or a client certificate
+	probesRequireAuth := solrCloud.Spec.SolrSecurity != nil && solrCloud.Spec.SolrSecurity.ProbesRequireAuth
+	if probesRequireAuth || solrCloud.RequiresClientAuth() {
+		defaultHandler = execProbeHandler(solrCloud, probeScheme, solrPodPort, defaultHandler.HTTPGet.Path)
+	}
 
 	labels := solrCloud.SharedLabelsWith(solrCloud.GetLabels())
 	selectorLabels := solrCloud.SharedLabels()
@@ -86,6 +140,8 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 
 	annotations := map[string]string{
 		SolrZKConnectionStringAnnotation: solrCloudStatus.ZkConnectionString(),
+		SolrNodeAddressingAnnotation:     solrCloud.NodeAddressing(),
+		SolrKubeDomainAnnotation:         solrCloud.Spec.SolrAddressability.KubeDomain,
 	}
 
 	podLabels := labels
@@ -103,6 +159,13 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 		podAnnotations = customPodOptions.Annotations
 	}
 
+	// Changes to the solr.xml are not picked up by running pods, so they need to be restarted
+	if solrXmlMd5 != "" {
+		podAnnotations = MergeLabelsOrAnnotations(map[string]string{
+			SolrXmlMd5Annotation: solrXmlMd5,
+		}, podAnnotations)
+	}
+
 	// Volumes & Mounts
 	solrVolumes := []corev1.Volume{
 		{
@@ -110,12 +173,12 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 			VolumeSource: corev1.VolumeSource{
 				ConfigMap: &corev1.ConfigMapVolumeSource{
 					LocalObjectReference: corev1.LocalObjectReference{
-						Name: solrCloud.ConfigMapName(),
+						Name: solrCloud.SolrXmlConfigMapName(),
 					},
 					Items: []corev1.KeyToPath{
 						{
-							Key:  "solr.xml",
-							Path: "solr.xml",
+							Key:  SolrXmlFile,
+							Path: SolrXmlFile,
 						},
 					},
 					DefaultMode: &defaultMode,
@@ -124,24 +187,127 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 		},
 	}
 
-	solrDataVolumeName := "data"
-	volumeMounts := []corev1.VolumeMount{{Name: solrDataVolumeName, MountPath: "/var/solr/data"}}
+	solrDataVolumeName := SolrDataVolume
+	volumeMounts := []corev1.VolumeMount{{Name: solrDataVolumeName, MountPath: solrCloud.DataMountPath()}}
 	var pvcs []corev1.PersistentVolumeClaim
-	if solrCloud.Spec.DataPvcSpec != nil {
-		pvcs = []corev1.PersistentVolumeClaim{
-			{
-				ObjectMeta: metav1.ObjectMeta{Name: solrDataVolumeName},
-				Spec:       *solrCloud.Spec.DataPvcSpec,
-			},
+	if dataPvcSpec := solrCloud.DataPvcSpec(); dataPvcSpec != nil {
+		dataPvc := corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: solrDataVolumeName},
+			Spec:       *dataPvcSpec,
+		}
+		if solrCloud.Spec.DataPvcSpec == nil {
+			dataPvc.Labels = solrCloud.Spec.DataStorage.Persistent.Labels
+			dataPvc.Annotations = solrCloud.Spec.DataStorage.Persistent.Annotations
 		}
+		pvcs = []corev1.PersistentVolumeClaim{dataPvc}
 	} else {
+		emptyDir := &corev1.EmptyDirVolumeSource{}
+		if solrCloud.Spec.DataStorage != nil && solrCloud.Spec.DataStorage.EmptyDir != nil {
+			emptyDir = solrCloud.Spec.DataStorage.EmptyDir
+		}
 		solrVolumes = append(solrVolumes, corev1.Volume{
 			Name: solrDataVolumeName,
 			VolumeSource: corev1.VolumeSource{
-				EmptyDir: &corev1.EmptyDirVolumeSource{},
+				EmptyDir: emptyDir,
 			},
 		})
 	}
+	// Add the transaction log volume, which Solr is pointed to through the solr.ulog.dir system property
+	if solrCloud.UsesTlogVolume() {
+		if tlogPvcSpec := solrCloud.TlogPvcSpec(); tlogPvcSpec != nil {
+			pvcs = append(pvcs, corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        SolrTlogVolume,
+					Labels:      solrCloud.Spec.DataStorage.Tlog.Persistent.Labels,
+					Annotations: solrCloud.Spec.DataStorage.Tlog.Persistent.Annotations,
+				},
+				Spec: *tlogPvcSpec,
+			})
+		} else {
+			emptyDir := &corev1.EmptyDirVolumeSource{}
+			if solrCloud.Spec.DataStorage.Tlog.EmptyDir != nil {
+				emptyDir = solrCloud.Spec.DataStorage.Tlog.EmptyDir
+			}
+			solrVolumes = append(solrVolumes, corev1.Volume{
+				Name: SolrTlogVolume,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: emptyDir,
+				},
+			})
+		}
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTlogVolume, MountPath: SolrTlogPath})
+	}
+
+	// Add the log volume, and the log4j2 config to only log to the console or the custom log4j2 config
+	if solrLogs := solrCloud.Spec.SolrLogs; solrLogs != nil {
+		if solrLogs.LogToStdout || solrLogs.Log4j2Config != nil {
+			log4j2ConfigMap := corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: solrCloud.ConfigMapName(),
+				},
+				Items: []corev1.KeyToPath{
+					{
+						Key:  LogXmlFile,
+						Path: LogXmlFile,
+					},
+				},
+				DefaultMode: &defaultMode,
+			}
+			if solrLogs.Log4j2Config != nil && solrLogs.Log4j2Config.ConfigMapRef != nil {
+				log4j2ConfigMap.LocalObjectReference = solrLogs.Log4j2Config.ConfigMapRef.LocalObjectReference
+				log4j2ConfigMap.Items[0].Key = solrLogs.Log4j2Config.ConfigMapRef.Key
+				log4j2ConfigMap.Optional = solrLogs.Log4j2Config.ConfigMapRef.Optional
+			}
+			solrVolumes = append(solrVolumes, corev1.Volume{
+				Name: SolrLog4j2ConfigVolume,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &log4j2ConfigMap,
+				},
+			})
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrLog4j2ConfigVolume, MountPath: SolrLog4j2ConfigPath, ReadOnly: true})
+		}
+		if !solrLogs.LogToStdout {
+			if solrLogs.PersistentVolumeClaimSpec != nil {
+				pvcs = append(pvcs, corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{Name: SolrLogsVolume},
+					Spec:       *solrLogs.PersistentVolumeClaimSpec,
+				})
+			} else {
+				emptyDir := &corev1.EmptyDirVolumeSource{}
+				if solrLogs.EmptyDir != nil {
+					emptyDir = solrLogs.EmptyDir
+				}
+				solrVolumes = append(solrVolumes, corev1.Volume{
+					Name: SolrLogsVolume,
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: emptyDir,
+					},
+				})
+			}
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrLogsVolume, MountPath: SolrLogsPath})
+		}
+		if gcLogs := solrLogs.GCLogs; gcLogs != nil {
+			if gcLogs.PersistentVolumeClaimSpec != nil {
+				pvcs = append(pvcs, corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{Name: SolrGCLogsVolume},
+					Spec:       *gcLogs.PersistentVolumeClaimSpec,
+				})
+			} else {
+				emptyDir := &corev1.EmptyDirVolumeSource{}
+				if gcLogs.EmptyDir != nil {
+					emptyDir = gcLogs.EmptyDir
+				}
+				solrVolumes = append(solrVolumes, corev1.Volume{
+					Name: SolrGCLogsVolume,
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: emptyDir,
+					},
+				})
+			}
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrGCLogsVolume, MountPath: SolrGCLogsPath})
+		}
+	}
+
 	// Add backup volumes
 	if solrCloud.Spec.BackupRestoreVolume != nil {
 		solrVolumes = append(solrVolumes, corev1.Volume{
@@ -149,6 +315,13 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 			VolumeSource: *solrCloud.Spec.BackupRestoreVolume,
 		})
 		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: BackupRestoreVolume, MountPath: BaseBackupRestorePath, SubPath: BackupRestoreSubPathForCloud(solrCloud.Name)})
+	} else if solrCloud.Spec.BackupRestorePerNodePvcSpec != nil {
+		// Each node gets its own backup volume, for storage that cannot be shared between pods
+		pvcs = append(pvcs, corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: BackupRestoreVolume},
+			Spec:       *solrCloud.Spec.BackupRestorePerNodePvcSpec,
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: BackupRestoreVolume, MountPath: BaseBackupRestorePath, SubPath: BackupRestoreSubPathForCloud(solrCloud.Name)})
 	}
 
 	if nil != customPodOptions {
@@ -164,43 +337,177 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 		}
 	}
 
-	// Host Aliases
-	hostAliases := make([]corev1.HostAlias, len(hostNameIPs))
-	if len(hostAliases) == 0 {
-		hostAliases = nil
-	} else {
-		hostNames := make([]string, len(hostNameIPs))
-		index := 0
-		for hostName := range hostNameIPs {
-			hostNames[index] = hostName
-			index += 1
+	// Host Aliases, the operator generated aliases take precedence over the user provided aliases
+	var customHostAliases []corev1.HostAlias
+	if nil != customPodOptions {
+		customHostAliases = customPodOptions.HostAliases
+	}
+	hostAliases := MergeHostAliases(hostNameIPs, customHostAliases)
+
+	// if an ingressBaseDomain is provided, the node should be addressable outside of the cluster
+	solrHostName := solrCloud.AdvertisedNodeHost("$(POD_HOSTNAME)")
+	// When using the host network, the Solr nodes are addressed by the IP address of their Kubernetes node, which is the IP address of the pod
+	if solrCloud.Spec.SolrAddressability.HostNetwork {
+		solrHostName = "$(POD_IP)"
+	}
+	solrAdressingPort := solrCloud.AdvertisedNodePort("$(POD_HOSTNAME)")
+
+	// With the NodePort method, each Solr node advertises the nodePort of its own Service, which depends on the ordinal of the pod.
+	// The ordinal is only known in the pod, so the hostPort is computed by the shell before starting Solr through the entrypoint of the image.
+	var solrCommand []string
+	solrArgs := []string{"-DhostPort=" + strconv.Itoa(solrAdressingPort)}
+	if solrCloud.Spec.SolrAddressability.External.AdvertisesNodePorts() {
+		firstNodePort := solrCloud.Spec.SolrAddressability.External.NodePortBase + 1
+		solrCommand = []string{"sh", "-c", fmt.Sprintf("exec docker-entrypoint.sh -DhostPort=$((%d + ${POD_HOSTNAME##*-}))", firstNodePort)}
+		solrArgs = nil
+	}
+
+	// A standalone Solr does not connect to Zookeeper, which Solr infers from the absence of a ZK_HOST
+	var postStart *corev1.Handler
+	var zkEnvVars []corev1.EnvVar
+	var zkVolumes []corev1.Volume
+	var zkVolumeMounts []corev1.VolumeMount
+	var createsZkChroot bool
+	solrOpts := solrCloud.Spec.SolrOpts
+	if jettyOpts := jettySystemProperties(solrCloud.Spec.Jetty); jettyOpts != "" {
+		solrOpts = strings.TrimSpace(jettyOpts + " " + solrOpts)
+	}
+	podPropertyEnvVars, podPropertyOpts := podSystemProperties(solrCloud.Spec.PodSystemProperties)
+	if podPropertyOpts != "" {
+		solrOpts = strings.TrimSpace(podPropertyOpts + " " + solrOpts)
+	}
+	if solrCloud.UsesTlogVolume() {
+		solrOpts = strings.TrimSpace("-Dsolr.ulog.dir=" + SolrTlogPath + " " + solrOpts)
+	}
+	if !solrCloud.IsStandalone() {
+		zkConnectionStr, zkServer, zkChroot := solrCloudStatus.DissectZkInfo()
+
+		// Only have a postStart command to create the chRoot, if it is not '/' (which does not need to be created)
+		createsZkChroot = len(zkChroot) > 1
+		if createsZkChroot {
+			postStart = &corev1.Handler{
+				Exec: &corev1.ExecAction{
+					Command: []string{"sh", "-c", "solr zk ls ${ZK_CHROOT} -z ${ZK_SERVER} || solr zk mkroot ${ZK_CHROOT} -z ${ZK_SERVER}"},
+				},
+			}
 		}
 
-		sort.Strings(hostNames)
+		zkEnvVars = []corev1.EnvVar{
+			{
+				Name:  "ZK_HOST",
+				Value: zkConnectionStr,
+			},
+			{
+				Name:  "ZK_SERVER",
+				Value: zkServer,
+			},
+			{
+				Name:  "ZK_CHROOT",
+				Value: zkChroot,
+			},
+		}
+		if solrCloud.Spec.ZkClientTimeout != nil {
+			zkEnvVars = append(zkEnvVars, corev1.EnvVar{
+				Name:  "ZK_CLIENT_TIMEOUT",
+				Value: strconv.Itoa(int(*solrCloud.Spec.ZkClientTimeout)),
+			})
+		}
+		// The digest credentials protect the znodes of Solr, including the chroot created before Solr starts
+		if zkClientVars := zkClientEnvVars(solrCloudStatus.ZookeeperConnectionInfo); len(zkClientVars) > 0 {
+			zkEnvVars = append(zkEnvVars, zkClientVars...)
+			solrOpts = strings.TrimSpace("$(" + ZkCredsAndACLsEnvVar + ") " + solrOpts)
+		}
+		zkVolumes, zkVolumeMounts = zkTLSVolumes(solrCloudStatus.ZookeeperConnectionInfo.TLS)
+		solrVolumes = append(solrVolumes, zkVolumes...)
+		volumeMounts = append(volumeMounts, zkVolumeMounts...)
+		// The SOLR_OPTS reference the SOLR_ZK_OPTS, so they must be defined first
+		if solrCloud.Spec.SolrZkOpts != "" {
+			zkEnvVars = append(zkEnvVars, corev1.EnvVar{
+				Name:  "SOLR_ZK_OPTS",
+				Value: solrCloud.Spec.SolrZkOpts,
+			})
+			solrOpts = strings.TrimSpace("$(SOLR_ZK_OPTS) " + solrOpts)
+		}
+	}
 
-		for index, hostName := range hostNames {
-			hostAliases[index] = corev1.HostAlias{
-				IP:        hostNameIPs[hostName],
-				Hostnames: []string{hostName},
+	// Mount the additional files from the ConfigMap, but not the files that the operator generates
+	if configMapOptions := solrCloud.Spec.CustomSolrKubeOptions.ConfigMapOptions; configMapOptions != nil {
+		var items []corev1.KeyToPath
+		for key := range configMapOptions.AdditionalData {
+			if !IsReservedConfigMapKey(key) {
+				items = append(items, corev1.KeyToPath{Key: key, Path: key})
 			}
-			index++
+		}
+		if len(items) > 0 {
+			sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+			solrVolumes = append(solrVolumes, corev1.Volume{
+				Name: SolrAdditionalConfigVolume,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: solrCloud.ConfigMapName(),
+						},
+						Items:       items,
+						DefaultMode: &defaultMode,
+					},
+				},
+			})
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrAdditionalConfigVolume, MountPath: configMapOptions.AdditionalDataMountPath, ReadOnly: true})
 		}
 	}
 
-	// if an ingressBaseDomain is provided, the node should be addressable outside of the cluster
-	solrHostName := solrCloud.AdvertisedNodeHost("$(POD_HOSTNAME)")
-	solrAdressingPort := solrCloud.NodePort()
+	// The solr.xml must be placed in the SOLR_HOME, either by an init container or by mounting it there directly
+	initContainers := generateSolrInitContainers(solrCloud, solrDataVolumeName)
+	if len(initContainers) == 0 {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: "solr-xml", MountPath: solrCloud.SolrHome() + "/" + SolrXmlFile, SubPath: SolrXmlFile})
+	}
 
-	zkConnectionStr, zkServer, zkChroot := solrCloudStatus.DissectZkInfo()
+	// The security.json and the urlScheme must be in Zookeeper before Solr starts, so that Solr never accepts unauthenticated requests,
+	// and registers the replicas with the right scheme
+	if (solrCloud.BootstrapsSecurity() || solrCloud.Spec.SolrTLS != nil) && !solrCloud.IsStandalone() {
+		initContainers = append(initContainers, generateZkSetupInitContainer(solrCloud, zkEnvVars, zkVolumeMounts, createsZkChroot))
+	}
 
-	// Only have a postStart command to create the chRoot, if it is not '/' (which does not need to be created)
-	var postStart *corev1.Handler
-	if len(zkChroot) > 1 {
-		postStart = &corev1.Handler{
-			Exec: &corev1.ExecAction{
-				Command: []string{"sh", "-c", "solr zk ls ${ZK_CHROOT} -z ${ZK_SERVER} || solr zk mkroot ${ZK_CHROOT} -z ${ZK_SERVER}"},
+	if solrCloud.Spec.SolrTLS != nil {
+		tlsVolumes, tlsVolumeMounts := solrServerTLSVolumes(solrCloud)
+		solrVolumes = append(solrVolumes, tlsVolumes...)
+		volumeMounts = append(volumeMounts, tlsVolumeMounts...)
+		if solrCloud.UsesCertManager() {
+			initContainers = append(initContainers, generatePKCS12InitContainer(solrCloud))
+		}
+	}
+
+	// Solr loads the plugin jars from the lib directory of the SOLR_HOME
+	if pluginLibs := solrCloud.Spec.PluginLibs; pluginLibs != nil {
+		solrVolumes = append(solrVolumes, corev1.Volume{
+			Name: SolrPluginLibsVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
+		})
+		if pluginLibs.VolumeSource != nil {
+			solrVolumes = append(solrVolumes, corev1.Volume{
+				Name:         SolrPluginLibsSourceVolume,
+				VolumeSource: *pluginLibs.VolumeSource,
+			})
 		}
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrPluginLibsVolume, MountPath: solrCloud.SolrHome() + "/lib", ReadOnly: true})
+		initContainers = append(initContainers, generatePluginLibsInitContainer(solrCloud))
+	}
+
+	// The timezone is read by the operating system from /etc/localtime
+	if solrCloud.Spec.SolrTimezone != "" && solrCloud.Spec.MountLocaltime {
+		hostPathType := corev1.HostPathFile
+		solrVolumes = append(solrVolumes, corev1.Volume{
+			Name: SolrLocaltimeVolume,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: ZoneInfoPath + "/" + solrCloud.Spec.SolrTimezone,
+					Type: &hostPathType,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrLocaltimeVolume, MountPath: "/etc/localtime", ReadOnly: true})
 	}
 
 	// Environment Variables
@@ -211,7 +518,7 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 		},
 		{
 			Name:  "SOLR_HOME",
-			Value: "/var/solr/data",
+			Value: solrCloud.SolrHome(),
 		},
 		{
 			Name:  "SOLR_PORT",
@@ -226,39 +533,106 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 				},
 			},
 		},
-		{
-			Name:  "SOLR_HOST",
-			Value: solrHostName,
-		},
-		{
-			Name:  "ZK_HOST",
-			Value: zkConnectionStr,
-		},
-		{
-			Name:  "ZK_SERVER",
-			Value: zkServer,
-		},
-		{
-			Name:  "ZK_CHROOT",
-			Value: zkChroot,
-		},
+	}
+	// The SOLR_HOST references the POD_IP when using the host network, so it must be defined first
+	if solrCloud.Spec.SolrAddressability.HostNetwork {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: "POD_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath:  "status.podIP",
+					APIVersion: "v1",
+				},
+			},
+		})
+	}
+	envVars = append(envVars, corev1.EnvVar{
+		Name:  "SOLR_HOST",
+		Value: solrHostName,
+	})
+	envVars = append(envVars, zkEnvVars...)
+	if probesRequireAuth {
+		envVars = append(envVars, basicAuthEnvVars(solrCloud)...)
+	}
+	if solrCloud.Spec.SolrTLS != nil {
+		envVars = append(envVars, solrServerTLSEnvVars(solrCloud)...)
+	}
+	// The SOLR_OPTS reference the pod system properties, so they must be defined first
+	envVars = append(envVars, podPropertyEnvVars...)
+	envVars = append(envVars, []corev1.EnvVar{
 		{
 			Name:  "SOLR_LOG_LEVEL",
 			Value: solrCloud.Spec.SolrLogLevel,
 		},
 		{
 			Name:  "SOLR_OPTS",
-			Value: solrCloud.Spec.SolrOpts,
+			Value: solrOpts,
 		},
 		{
 			Name:  "GC_TUNE",
 			Value: solrCloud.Spec.SolrGCTune,
 		},
+	}...)
+
+	if len(solrCloud.Spec.SolrModules) > 0 {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "SOLR_MODULES",
+			Value: strings.Join(solrCloud.Spec.SolrModules, ","),
+		})
+	}
+
+	if solrCloud.Spec.SolrTimezone != "" {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "SOLR_TIMEZONE",
+			Value: solrCloud.Spec.SolrTimezone,
+		})
+	}
+
+	if solrLogs := solrCloud.Spec.SolrLogs; solrLogs != nil {
+		if solrLogs.LogToStdout || solrLogs.Log4j2Config != nil {
+			envVars = append(envVars, corev1.EnvVar{
+				Name:  "LOG4J_PROPS",
+				Value: SolrLog4j2ConfigPath + "/" + LogXmlFile,
+			})
+		}
+		if gcLogs := solrLogs.GCLogs; gcLogs != nil {
+			// A GC_LOG_OPTS with an explicit output is used by Solr as is
+			envVars = append(envVars, corev1.EnvVar{
+				Name:  "GC_LOG_OPTS",
+				Value: GCLogOpts(gcLogs, solrCloud.GCLogsJavaVersion()),
+			})
+		} else if solrLogs.LogToStdout {
+			// An empty GC_LOG_OPTS disables the GC log file
+			envVars = append(envVars, corev1.EnvVar{
+				Name:  "GC_LOG_OPTS",
+				Value: "",
+			})
+		}
+		if !solrLogs.LogToStdout {
+			// Solr writes the GC logs to the SOLR_LOGS_DIR as well
+			envVars = append(envVars, corev1.EnvVar{
+				Name:  "SOLR_LOGS_DIR",
+				Value: SolrLogsPath,
+			})
+		}
+	}
+
+	// Solr is stopped gracefully, after the custom preStop hook has run
+	preStop := &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"solr", "stop", "-p", strconv.Itoa(solrPodPort)},
+		},
+	}
+	if nil != customPodOptions && nil != customPodOptions.Lifecycle {
+		postStart = mergeLifecycleHandlers(postStart, customPodOptions.Lifecycle.PostStart, false)
+		preStop = mergeLifecycleHandlers(preStop, customPodOptions.Lifecycle.PreStop, true)
 	}
 
 	// Add Custom EnvironmentVariables to the solr container
+	var envFrom []corev1.EnvFromSource
 	if nil != customPodOptions {
 		envVars = append(envVars, customPodOptions.EnvVariables...)
+		envFrom = customPodOptions.EnvFrom
 	}
 
 	// Create the Stateful Set
@@ -275,6 +649,9 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 			},
 			ServiceName: solrCloud.HeadlessServiceName(),
 			Replicas:    solrCloud.Spec.Replicas,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: appsv1.RollingUpdateStatefulSetStrategyType,
+			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      podLabels,
@@ -286,28 +663,9 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 					SecurityContext: &corev1.PodSecurityContext{
 						FSGroup: &fsGroup,
 					},
-					Volumes: solrVolumes,
-					InitContainers: []corev1.Container{
-						{
-							Name:                     "cp-solr-xml",
-							Image:                    solrCloud.Spec.BusyBoxImage.ToImageName(),
-							ImagePullPolicy:          solrCloud.Spec.BusyBoxImage.PullPolicy,
-							TerminationMessagePath:   "/dev/termination-log",
-							TerminationMessagePolicy: "File",
-							Command:                  []string{"sh", "-c", "cp /tmp/solr.xml /tmp-config/solr.xml"},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "solr-xml",
-									MountPath: "/tmp",
-								},
-								{
-									Name:      solrDataVolumeName,
-									MountPath: "/tmp-config",
-								},
-							},
-						},
-					},
-					HostAliases: hostAliases,
+					Volumes:        solrVolumes,
+					InitContainers: initContainers,
+					HostAliases:    hostAliases,
 					Containers: []corev1.Container{
 						{
 							Name:            "solrcloud-node",
@@ -337,17 +695,15 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 								Handler:             defaultHandler,
 							},
 							VolumeMounts:             volumeMounts,
-							Args:                     []string{"-DhostPort=" + strconv.Itoa(solrAdressingPort)},
+							Command:                  solrCommand,
+							Args:                     solrArgs,
 							Env:                      envVars,
+							EnvFrom:                  envFrom,
 							TerminationMessagePath:   "/dev/termination-log",
 							TerminationMessagePolicy: "File",
 							Lifecycle: &corev1.Lifecycle{
 								PostStart: postStart,
-								PreStop: &corev1.Handler{
-									Exec: &corev1.ExecAction{
-										Command: []string{"solr", "stop", "-p", strconv.Itoa(solrPodPort)},
-									},
-								},
+								PreStop:   preStop,
 							},
 						},
 					},
@@ -357,12 +713,20 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 		},
 	}
 
-	if solrCloud.Spec.SolrImage.ImagePullSecret != "" {
-		stateful.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{
-			{Name: solrCloud.Spec.SolrImage.ImagePullSecret},
-		}
+	// The hostPort keeps the scheduler from placing two Solr nodes of the cloud, which listen on the same port, onto the same Kubernetes node.
+	// Pods using the host network need the ClusterFirstWithHostNet DNS policy to resolve the services of the cluster, such as ZooKeeper.
+	if solrCloud.Spec.SolrAddressability.HostNetwork {
+		stateful.Spec.Template.Spec.HostNetwork = true
+		stateful.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+		stateful.Spec.Template.Spec.Containers[0].Ports[0].HostPort = int32(solrPodPort)
 	}
 
+	var additionalPullSecrets []corev1.LocalObjectReference
+	if nil != customPodOptions {
+		additionalPullSecrets = customPodOptions.ImagePullSecrets
+	}
+	stateful.Spec.Template.Spec.ImagePullSecrets = MergeImagePullSecrets(solrCloud.Spec.SolrImage.ImagePullSecret, additionalPullSecrets)
+
 	// DEPRECATED: Replaced by the options below
 	if solrCloud.Spec.SolrPod.Affinity != nil {
 		stateful.Spec.Template.Spec.Affinity = solrCloud.Spec.SolrPod.Affinity
@@ -386,6 +750,10 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 			stateful.Spec.Template.Spec.SecurityContext = customPodOptions.PodSecurityContext
 		}
 
+		if customPodOptions.ContainerSecurityContext != nil {
+			stateful.Spec.Template.Spec.Containers[0].SecurityContext = customPodOptions.ContainerSecurityContext
+		}
+
 		if customPodOptions.Tolerations != nil {
 			stateful.Spec.Template.Spec.Tolerations = customPodOptions.Tolerations
 		}
@@ -406,11 +774,326 @@ func GenerateStatefulSet(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCl
 			stateful.Spec.Template.Spec.Containers[0].StartupProbe = fillProbe(*customPodOptions.StartupProbe, DefaultStartupProbeInitialDelaySeconds, DefaultStartupProbeTimeoutSeconds, DefaultStartupProbeSuccessThreshold, DefaultStartupProbeFailureThreshold, DefaultStartupProbePeriodSeconds, &defaultHandler)
 		}
 
+		if customPodOptions.ServiceAccountName != "" {
+			stateful.Spec.Template.Spec.ServiceAccountName = customPodOptions.ServiceAccountName
+		}
+
+		if customPodOptions.PriorityClassName != "" {
+			stateful.Spec.Template.Spec.PriorityClassName = customPodOptions.PriorityClassName
+		}
+
+		if customPodOptions.SchedulerName != "" {
+			stateful.Spec.Template.Spec.SchedulerName = customPodOptions.SchedulerName
+		}
+
+		if customPodOptions.RuntimeClassName != nil {
+			stateful.Spec.Template.Spec.RuntimeClassName = customPodOptions.RuntimeClassName
+		}
+
+		if customPodOptions.DNSPolicy != "" {
+			stateful.Spec.Template.Spec.DNSPolicy = customPodOptions.DNSPolicy
+		}
+
+		if customPodOptions.DNSConfig != nil {
+			stateful.Spec.Template.Spec.DNSConfig = customPodOptions.DNSConfig
+		}
+
+		if len(customPodOptions.SidecarContainers) > 0 {
+			stateful.Spec.Template.Spec.Containers = append(stateful.Spec.Template.Spec.Containers, customPodOptions.SidecarContainers...)
+		}
+
+		// Custom init containers run after the operator generated init containers
+		if len(customPodOptions.InitContainers) > 0 {
+			stateful.Spec.Template.Spec.InitContainers = append(stateful.Spec.Template.Spec.InitContainers, customPodOptions.InitContainers...)
+		}
+	}
+
+	// The pods run under the service account that the operator creates for the cloud
+	if solrCloud.CreatesServiceAccount() {
+		stateful.Spec.Template.Spec.ServiceAccountName = solrCloud.ServiceAccountName()
+	}
+
+	// Local PersistentVolumes can only be bound on the nodes that hold the local disks
+	if nodeSelector := solrCloud.LocalStorageNodeSelector(); len(nodeSelector) > 0 {
+		stateful.Spec.Template.Spec.Affinity = requireNodeLabels(stateful.Spec.Template.Spec.Affinity, nodeSelector)
+	}
+
+	// With the Manual update method, the user decides which pods can be restarted
+	if partition, isManual := solrCloud.ManualUpdatePartition(); isManual {
+		SetStatefulSetPartition(stateful, partition)
+	} else if !solrCloud.IsManagedUpdate() {
+		stateful.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType}
+	}
+
+	// The heap is sized from the memory limit, once the resources of the Solr container are known
+	if percentage := solrCloud.Spec.SolrJavaMemPercentage; percentage != nil {
+		solrContainer := &stateful.Spec.Template.Spec.Containers[0]
+		if memoryLimit := solrContainer.Resources.Limits.Memory(); !memoryLimit.IsZero() {
+			heapMi := memoryLimit.Value() * int64(*percentage) / 100 / (1024 * 1024)
+			for i := range solrContainer.Env {
+				if solrContainer.Env[i].Name == "SOLR_JAVA_MEM" {
+					solrContainer.Env[i].Value = fmt.Sprintf("-Xms%dm -Xmx%dm", heapMi, heapMi)
+				}
+			}
+		}
+	}
+
+	// The overrides are applied last, so that they can change anything in the generated pod template.
+	// Overrides that cannot be applied are rejected by the SolrCloud controller before the StatefulSet is generated.
+	if err := ApplyPodTemplateOverrides(&stateful.Spec.Template, solrCloud.Spec.PodTemplateOverrides); err != nil {
+		log.Error(err, "Could not apply the podTemplateOverrides", "namespace", solrCloud.Namespace, "cloud", solrCloud.Name)
 	}
 
 	return stateful
 }
 
+// ValidateStatefulSetDataPaths returns an error if the Solr data paths of the desired StatefulSet differ from the existing StatefulSet.
+// Changing these paths would orphan the data that Solr has already stored.
+func ValidateStatefulSetDataPaths(desired, existing *appsv1.StatefulSet) error {
+	desiredMountPath, desiredSolrHome := statefulSetDataPaths(desired)
+	existingMountPath, existingSolrHome := statefulSetDataPaths(existing)
+	if desiredMountPath != existingMountPath {
+		return fmt.Errorf("the data mount path of StatefulSet %s cannot be changed from '%s' to '%s'", existing.Name, existingMountPath, desiredMountPath)
+	}
+	if desiredSolrHome != existingSolrHome {
+		return fmt.Errorf("the SOLR_HOME of StatefulSet %s cannot be changed from '%s' to '%s'", existing.Name, existingSolrHome, desiredSolrHome)
+	}
+	return nil
+}
+
+// generateSolrInitContainers returns the operator generated init containers of the Solr pods.
+// The init container copies the solr.xml into the SOLR_HOME, which can be a sub-directory of the data volume.
+func generateSolrInitContainers(solrCloud *solr.SolrCloud, solrDataVolumeName string) []corev1.Container {
+	var initOptions *solr.SolrDataInitContainerOptions
+	if solrCloud.Spec.DataStorage != nil {
+		initOptions = solrCloud.Spec.DataStorage.InitContainer
+	}
+	if initOptions != nil && initOptions.Disabled {
+		return nil
+	}
+
+	copySolrXmlCommand := "cp /tmp/solr.xml /tmp-config/solr.xml"
+	if solrHomeSubPath, _ := solrCloud.SolrHomeDataSubPath(); solrHomeSubPath != "" {
+		solrHomeConfigPath := "/tmp-config/" + solrHomeSubPath
+		copySolrXmlCommand = "mkdir -p " + solrHomeConfigPath + " && cp /tmp/solr.xml " + solrHomeConfigPath + "/solr.xml"
+	}
+
+	image := solrCloud.Spec.BusyBoxImage
+	if initOptions != nil && initOptions.Image != nil {
+		image = initOptions.Image
+	}
+
+	initContainer := corev1.Container{
+		Name:                     "cp-solr-xml",
+		Image:                    image.ToImageName(),
+		ImagePullPolicy:          image.PullPolicy,
+		TerminationMessagePath:   "/dev/termination-log",
+		TerminationMessagePolicy: "File",
+		Command:                  []string{"sh", "-c", copySolrXmlCommand},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "solr-xml",
+				MountPath: "/tmp",
+			},
+			{
+				Name:      solrDataVolumeName,
+				MountPath: "/tmp-config",
+			},
+		},
+	}
+	setInitContainerOptions(solrCloud, &initContainer)
+
+	return []corev1.Container{initContainer}
+}
+
+// setInitContainerOptions sets the resources and security context of spec.dataStorage.initContainer on an init container generated by the operator,
+// so that the pods are admitted in namespaces that require them
+func setInitContainerOptions(solrCloud *solr.SolrCloud, initContainer *corev1.Container) {
+	if solrCloud.Spec.DataStorage != nil && solrCloud.Spec.DataStorage.InitContainer != nil {
+		initContainer.Resources = solrCloud.Spec.DataStorage.InitContainer.Resources
+		initContainer.SecurityContext = solrCloud.Spec.DataStorage.InitContainer.SecurityContext
+	}
+}
+
+// jettySystemProperties returns the system properties that set the given Jetty options
+func jettySystemProperties(jettyOptions *solr.SolrJettyOptions) string {
+	if jettyOptions == nil {
+		return ""
+	}
+	var properties []string
+	addProperty := func(name string, value *int32) {
+		if value != nil {
+			properties = append(properties, fmt.Sprintf("-D%s=%d", name, *value))
+		}
+	}
+	addProperty("solr.jetty.request.header.size", jettyOptions.RequestHeaderSize)
+	addProperty("solr.jetty.threads.min", jettyOptions.MinThreads)
+	addProperty("solr.jetty.threads.max", jettyOptions.MaxThreads)
+	addProperty("solr.jetty.http.idleTimeout", jettyOptions.IdleTimeoutMillis)
+	return strings.Join(properties, " ")
+}
+
+// requireNodeLabels returns a copy of the affinity that only allows the pods to be scheduled onto nodes with the given labels.
+// The labels are added to every required node selector term, since the terms are ORed.
+func requireNodeLabels(affinity *corev1.Affinity, nodeLabels map[string]string) *corev1.Affinity {
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	} else {
+		affinity = affinity.DeepCopy()
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	nodeSelector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(nodeSelector.NodeSelectorTerms) == 0 {
+		nodeSelector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+
+	// Sort the labels, so that the StatefulSet does not change between reconciles
+	labels := make([]string, 0, len(nodeLabels))
+	for label := range nodeLabels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for i := range nodeSelector.NodeSelectorTerms {
+		for _, label := range labels {
+			nodeSelector.NodeSelectorTerms[i].MatchExpressions = append(nodeSelector.NodeSelectorTerms[i].MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      label,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{nodeLabels[label]},
+			})
+		}
+	}
+	return affinity
+}
+
+// GCLogOpts returns the JVM options that write the GC logs to the GC log volume, rotating them with the given options.
+// Java 8 does not support unified logging, so it is given the legacy GC log options instead.
+func GCLogOpts(gcLogs *solr.SolrGCLogsOptions, javaVersion int32) string {
+	if javaVersion < 9 {
+		return fmt.Sprintf("-Xloggc:%s/solr_gc.log -XX:+UseGCLogFileRotation -XX:NumberOfGCLogFiles=%d -XX:GCLogFileSize=%s", SolrGCLogsPath, *gcLogs.FileCount, gcLogs.FileSize)
+	}
+	return fmt.Sprintf("-Xlog:gc*:file=%s/solr_gc.log:time,uptime:filecount=%d,filesize=%s", SolrGCLogsPath, *gcLogs.FileCount, gcLogs.FileSize)
+}
+
+// podSystemProperties returns the environment variables that read the given pod fields through the downward API,
+// and the system properties that pass their values to Solr
+func podSystemProperties(podProperties []solr.SolrPodSystemProperty) (envVars []corev1.EnvVar, properties string) {
+	propertyOpts := make([]string, len(podProperties))
+	for i, podProperty := range podProperties {
+		envVarName := fmt.Sprintf("SOLR_POD_PROPERTY_%d", i)
+		envVars = append(envVars, corev1.EnvVar{
+			Name: envVarName,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath:  podProperty.FieldPath,
+					APIVersion: "v1",
+				},
+			},
+		})
+		propertyOpts[i] = fmt.Sprintf("-D%s=$(%s)", podProperty.Name, envVarName)
+	}
+	return envVars, strings.Join(propertyOpts, " ")
+}
+
+// generatePluginLibsInitContainer returns the init container that downloads and copies the plugin jars into the plugin lib volume
+func generatePluginLibsInitContainer(solrCloud *solr.SolrCloud) corev1.Container {
+	pluginLibs := solrCloud.Spec.PluginLibs
+	volumeMounts := []corev1.VolumeMount{{Name: SolrPluginLibsVolume, MountPath: "/plugin-libs"}}
+
+	var commands []string
+	if pluginLibs.VolumeSource != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrPluginLibsSourceVolume, MountPath: "/plugin-libs-source", ReadOnly: true})
+		commands = append(commands, "cp /plugin-libs-source/*.jar /plugin-libs/")
+	}
+	for i, libUrl := range pluginLibs.URLs {
+		fileName := fmt.Sprintf("plugin-%d.jar", i)
+		if parsedUrl, err := url.Parse(libUrl); err == nil && path.Base(parsedUrl.Path) != "." && path.Base(parsedUrl.Path) != "/" {
+			fileName = path.Base(parsedUrl.Path)
+		}
+		commands = append(commands, shellCommand([]string{"wget", "-q", "-O", "/plugin-libs/" + fileName, libUrl}))
+	}
+
+	image := solrCloud.Spec.BusyBoxImage
+	if pluginLibs.Image != nil {
+		image = pluginLibs.Image
+	}
+
+	return corev1.Container{
+		Name:                     "solr-plugin-libs",
+		Image:                    image.ToImageName(),
+		ImagePullPolicy:          image.PullPolicy,
+		TerminationMessagePath:   "/dev/termination-log",
+		TerminationMessagePolicy: "File",
+		Command:                  []string{"sh", "-c", strings.Join(commands, " && ")},
+		VolumeMounts:             volumeMounts,
+		Resources:                pluginLibs.Resources,
+		SecurityContext:          pluginLibs.SecurityContext,
+	}
+}
+
+// generateZkSetupInitContainer returns the init container that prepares Zookeeper before Solr starts.
+// It creates the chroot, sets the urlScheme cluster property when TLS is enabled,
+// and uploads the generated security.json if Zookeeper does not have one yet.
+func generateZkSetupInitContainer(solrCloud *solr.SolrCloud, zkEnvVars []corev1.EnvVar, zkVolumeMounts []corev1.VolumeMount, createChroot bool) corev1.Container {
+	var commands []string
+	if createChroot {
+		commands = append(commands, "(solr zk ls ${ZK_CHROOT} -z ${ZK_SERVER} || solr zk mkroot ${ZK_CHROOT} -z ${ZK_SERVER})")
+	}
+	if solrCloud.Spec.SolrTLS != nil {
+		commands = append(commands, "/opt/solr/server/scripts/cloud-scripts/zkcli.sh -zkhost ${ZK_HOST} -cmd clusterprop -name urlScheme -val https")
+	}
+
+	envVars := append([]corev1.EnvVar{}, zkEnvVars...)
+	if solrCloud.BootstrapsSecurity() {
+		commands = append(commands, "(solr zk ls /"+SecurityJsonFile+" -z ${ZK_HOST} > /dev/null 2>&1 || "+
+			"(echo \"${SECURITY_JSON}\" > /tmp/"+SecurityJsonFile+" && solr zk cp file:/tmp/"+SecurityJsonFile+" zk:/"+SecurityJsonFile+" -z ${ZK_HOST}))")
+		envVars = append(envVars, corev1.EnvVar{
+			Name: "SECURITY_JSON",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: solrCloud.SecurityBootstrapSecretName()},
+					Key:                  SecurityJsonFile,
+				},
+			},
+		})
+	}
+
+	initContainer := corev1.Container{
+		Name:                     "setup-zk",
+		Image:                    solrCloud.Spec.SolrImage.ToImageName(),
+		ImagePullPolicy:          solrCloud.Spec.SolrImage.PullPolicy,
+		TerminationMessagePath:   "/dev/termination-log",
+		TerminationMessagePolicy: "File",
+		Command:                  []string{"sh", "-c", strings.Join(commands, " && ")},
+		Env:                      envVars,
+		VolumeMounts:             zkVolumeMounts,
+	}
+	setInitContainerOptions(solrCloud, &initContainer)
+	return initContainer
+}
+
+func statefulSetDataPaths(statefulSet *appsv1.StatefulSet) (dataMountPath string, solrHome string) {
+	if len(statefulSet.Spec.Template.Spec.Containers) == 0 {
+		return "", ""
+	}
+	container := statefulSet.Spec.Template.Spec.Containers[0]
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == SolrDataVolume {
+			dataMountPath = mount.MountPath
+		}
+	}
+	for _, envVar := range container.Env {
+		if envVar.Name == "SOLR_HOME" {
+			solrHome = envVar.Value
+		}
+	}
+	return dataMountPath, solrHome
+}
+
 // CopyStatefulSetFields copies the owned fields from one StatefulSet to another
 // Returns true if the fields copied from don't match to.
 func CopyStatefulSetFields(from, to *appsv1.StatefulSet) bool {
@@ -428,13 +1111,20 @@ func CopyStatefulSetFields(from, to *appsv1.StatefulSet) bool {
 		to.Spec.Selector = from.Spec.Selector
 	}
 
+	// The update strategy is defaulted by Kubernetes, so only compare it if one is specified
+	if from.Spec.UpdateStrategy.Type != "" && !statefulSetStrategyMatches(from, to) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.UpdateStrategy changed from", to.Spec.UpdateStrategy, "To:", from.Spec.UpdateStrategy)
+		to.Spec.UpdateStrategy = from.Spec.UpdateStrategy
+	}
+
 	requireVolumeUpdate := false
 	if len(from.Spec.VolumeClaimTemplates) != len(to.Spec.VolumeClaimTemplates) {
 		requireVolumeUpdate = true
 		log.Info("Update required because:", "Spec.VolumeClaimTemplates changed from", to.Spec.VolumeClaimTemplates, "To:", from.Spec.VolumeClaimTemplates)
 	}
 	for i, fromVct := range from.Spec.VolumeClaimTemplates {
-		if !DeepEqualWithNils(to.Spec.VolumeClaimTemplates[i].Spec, fromVct.Spec) {
+		if i < len(to.Spec.VolumeClaimTemplates) && !DeepEqualWithNils(to.Spec.VolumeClaimTemplates[i].Spec, fromVct.Spec) {
 			requireVolumeUpdate = true
 			log.Info("Update required because:", "Spec.VolumeClaimTemplates.Spec changed from", to.Spec.VolumeClaimTemplates[i].Spec, "To:", fromVct.Spec)
 		}
@@ -443,17 +1133,8 @@ func CopyStatefulSetFields(from, to *appsv1.StatefulSet) bool {
 		to.Spec.Template.Labels = from.Spec.Template.Labels
 	}
 
-	if !DeepEqualWithNils(to.Spec.Template.Labels, from.Spec.Template.Labels) {
-		requireUpdate = true
-		log.Info("Update required because:", "Spec.Template.Labels changed from", to.Spec.Template.Labels, "To:", from.Spec.Template.Labels)
-		to.Spec.Template.Labels = from.Spec.Template.Labels
-	}
-
-	if !DeepEqualWithNils(to.Spec.Template.Annotations, from.Spec.Template.Annotations) {
-		requireUpdate = true
-		log.Info("Update required because:", "Spec.Template.Annotations changed from", to.Spec.Template.Annotations, "To:", from.Spec.Template.Annotations)
-		to.Spec.Template.Annotations = from.Spec.Template.Annotations
-	}
+	// Labels and annotations that were added to the pod template outside of the operator, e.g. by "kubectl rollout restart", are kept
+	requireUpdate = CopyLabelsAndAnnotations(&from.Spec.Template.ObjectMeta, &to.Spec.Template.ObjectMeta) || requireUpdate
 
 	if !DeepEqualWithNils(to.Spec.Template.Spec.Containers, from.Spec.Template.Spec.Containers) {
 		requireUpdate = true
@@ -491,91 +1172,339 @@ func CopyStatefulSetFields(from, to *appsv1.StatefulSet) bool {
 		to.Spec.Template.Spec.Containers[0].Resources = from.Spec.Template.Spec.Containers[0].Resources
 	}
 
-	if !DeepEqualWithNils(to.Spec.Template.Spec.Affinity, from.Spec.Template.Spec.Affinity) {
-		requireUpdate = true
-		log.Info("Update required because:", "Spec.Template.Spec.Affinity changed from", to.Spec.Template.Spec.Affinity, "To:", from.Spec.Template.Spec.Affinity)
-		to.Spec.Template.Spec.Affinity = from.Spec.Template.Spec.Affinity
+	if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[0].SecurityContext, from.Spec.Template.Spec.Containers[0].SecurityContext) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.Containers[0].SecurityContext changed from", to.Spec.Template.Spec.Containers[0].SecurityContext, "To:", from.Spec.Template.Spec.Containers[0].SecurityContext)
+		to.Spec.Template.Spec.Containers[0].SecurityContext = from.Spec.Template.Spec.Containers[0].SecurityContext
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.Affinity, from.Spec.Template.Spec.Affinity) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.Affinity changed from", to.Spec.Template.Spec.Affinity, "To:", from.Spec.Template.Spec.Affinity)
+		to.Spec.Template.Spec.Affinity = from.Spec.Template.Spec.Affinity
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.SecurityContext, from.Spec.Template.Spec.SecurityContext) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.SecurityContext changed from", to.Spec.Template.Spec.SecurityContext, "To:", from.Spec.Template.Spec.SecurityContext)
+		to.Spec.Template.Spec.SecurityContext = from.Spec.Template.Spec.SecurityContext
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.NodeSelector, from.Spec.Template.Spec.NodeSelector) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.NodeSelector changed from", to.Spec.Template.Spec.NodeSelector, "To:", from.Spec.Template.Spec.NodeSelector)
+		to.Spec.Template.Spec.NodeSelector = from.Spec.Template.Spec.NodeSelector
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.Tolerations, from.Spec.Template.Spec.Tolerations) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.Tolerations changed from", to.Spec.Template.Spec.Tolerations, "To:", from.Spec.Template.Spec.Tolerations)
+		to.Spec.Template.Spec.Tolerations = from.Spec.Template.Spec.Tolerations
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.ServiceAccountName, from.Spec.Template.Spec.ServiceAccountName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.ServiceAccountName changed from", to.Spec.Template.Spec.ServiceAccountName, "To:", from.Spec.Template.Spec.ServiceAccountName)
+		to.Spec.Template.Spec.ServiceAccountName = from.Spec.Template.Spec.ServiceAccountName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.PriorityClassName, from.Spec.Template.Spec.PriorityClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.PriorityClassName changed from", to.Spec.Template.Spec.PriorityClassName, "To:", from.Spec.Template.Spec.PriorityClassName)
+		to.Spec.Template.Spec.PriorityClassName = from.Spec.Template.Spec.PriorityClassName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.SchedulerName, from.Spec.Template.Spec.SchedulerName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.SchedulerName changed from", to.Spec.Template.Spec.SchedulerName, "To:", from.Spec.Template.Spec.SchedulerName)
+		to.Spec.Template.Spec.SchedulerName = from.Spec.Template.Spec.SchedulerName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.RuntimeClassName, from.Spec.Template.Spec.RuntimeClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.RuntimeClassName changed from", to.Spec.Template.Spec.RuntimeClassName, "To:", from.Spec.Template.Spec.RuntimeClassName)
+		to.Spec.Template.Spec.RuntimeClassName = from.Spec.Template.Spec.RuntimeClassName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.HostNetwork, from.Spec.Template.Spec.HostNetwork) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.HostNetwork changed from", to.Spec.Template.Spec.HostNetwork, "To:", from.Spec.Template.Spec.HostNetwork)
+		to.Spec.Template.Spec.HostNetwork = from.Spec.Template.Spec.HostNetwork
+	}
+
+	// Only copy the DNS policy if one is specified, since the Kubernetes API server will default it otherwise.
+	if from.Spec.Template.Spec.DNSPolicy != "" && !DeepEqualWithNils(to.Spec.Template.Spec.DNSPolicy, from.Spec.Template.Spec.DNSPolicy) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.DNSPolicy changed from", to.Spec.Template.Spec.DNSPolicy, "To:", from.Spec.Template.Spec.DNSPolicy)
+		to.Spec.Template.Spec.DNSPolicy = from.Spec.Template.Spec.DNSPolicy
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.DNSConfig, from.Spec.Template.Spec.DNSConfig) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.DNSConfig changed from", to.Spec.Template.Spec.DNSConfig, "To:", from.Spec.Template.Spec.DNSConfig)
+		to.Spec.Template.Spec.DNSConfig = from.Spec.Template.Spec.DNSConfig
+	}
+
+	return requireUpdate
+}
+
+// DefaultSolrXml is the solr.xml that the operator generates for a SolrCloud, unless one is provided
+const DefaultSolrXml = `<?xml version="1.0" encoding="UTF-8" ?>
+<solr>
+  <solrcloud>
+    <str name="host">${host:}</str>
+    <int name="hostPort">${hostPort:80}</int>
+    <str name="hostContext">${hostContext:solr}</str>
+    <bool name="genericCoreNodeNames">${genericCoreNodeNames:true}</bool>
+    <int name="zkClientTimeout">${zkClientTimeout:30000}</int>
+    <int name="distribUpdateSoTimeout">${distribUpdateSoTimeout:600000}</int>
+    <int name="distribUpdateConnTimeout">${distribUpdateConnTimeout:60000}</int>
+    <str name="zkCredentialsProvider">${zkCredentialsProvider:org.apache.solr.common.cloud.DefaultZkCredentialsProvider}</str>
+    <str name="zkACLProvider">${zkACLProvider:org.apache.solr.common.cloud.DefaultZkACLProvider}</str>
+  </solrcloud>
+  <shardHandlerFactory name="shardHandlerFactory"
+    class="HttpShardHandlerFactory">
+    <int name="socketTimeout">${socketTimeout:600000}</int>
+    <int name="connTimeout">${connTimeout:60000}</int>
+  </shardHandlerFactory>
+</solr>
+`
+
+// StdoutLog4j2Xml is a log4j2 configuration that only logs to the console
+const StdoutLog4j2Xml = `<?xml version="1.0" encoding="UTF-8"?>
+<Configuration>
+  <Appenders>
+    <Console name="STDOUT" target="SYSTEM_OUT">
+      <PatternLayout>
+        <Pattern>
+          %maxLen{%d{yyyy-MM-dd HH:mm:ss.SSS} %-5p (%t) [%X{collection} %X{shard} %X{replica} %X{core}] %c{1.} %m%notEmpty{ =>%ex{short}}}{10240}%n
+        </Pattern>
+      </PatternLayout>
+    </Console>
+  </Appenders>
+  <Loggers>
+    <Logger name="org.apache.hadoop" level="warn"/>
+    <Logger name="org.apache.solr.update.LoggingInfoStream" level="off"/>
+    <Logger name="org.apache.zookeeper" level="warn"/>
+    <Root level="info">
+      <AppenderRef ref="STDOUT"/>
+    </Root>
+  </Loggers>
+</Configuration>
+`
+
+// GenerateConfigMap returns a new corev1.ConfigMap pointer generated for the SolrCloud instance solr.xml
+// solrCloud: SolrCloud instance
+func GenerateConfigMap(solrCloud *solr.SolrCloud) *corev1.ConfigMap {
+	labels := solrCloud.SharedLabelsWith(solrCloud.GetLabels())
+	var annotations map[string]string
+
+	customOptions := solrCloud.Spec.CustomSolrKubeOptions.ConfigMapOptions
+	if nil != customOptions {
+		labels = MergeLabelsOrAnnotations(labels, customOptions.Labels)
+		annotations = MergeLabelsOrAnnotations(annotations, customOptions.Annotations)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        solrCloud.ConfigMapName(),
+			Namespace:   solrCloud.GetNamespace(),
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Data: map[string]string{},
+	}
+
+	// A solr.xml provided by the user is mounted from its own ConfigMap
+	if solrCloud.ProvidedSolrXmlConfigMap() == "" {
+		configMap.Data[SolrXmlFile] = DefaultSolrXml
+	}
+
+	if log4j2Config := solrCloud.CustomLog4j2Config(); log4j2Config != nil {
+		if log4j2Config.Inline != "" {
+			configMap.Data[LogXmlFile] = log4j2Config.Inline
+		}
+	} else if solrCloud.Spec.SolrLogs != nil && solrCloud.Spec.SolrLogs.LogToStdout {
+		configMap.Data[LogXmlFile] = StdoutLog4j2Xml
+	}
+
+	// The files generated by the operator cannot be overridden by the additional data
+	if nil != customOptions {
+		for key, value := range customOptions.AdditionalData {
+			if _, generated := configMap.Data[key]; !generated && !IsReservedConfigMapKey(key) {
+				configMap.Data[key] = value
+			}
+		}
+	}
+
+	return configMap
+}
+
+// IsReservedConfigMapKey returns whether the key is used for a file that the operator generates in the SolrCloud ConfigMap
+func IsReservedConfigMapKey(key string) bool {
+	return key == SolrXmlFile || key == LogXmlFile
+}
+
+// solrXmlHostPortPattern matches a hostPort in the solr.xml that uses the hostPort system property set by the operator
+var solrXmlHostPortPattern = regexp.MustCompile(`name=["']hostPort["']\s*>\s*\$\{hostPort(:[^}]*)?\}`)
+
+// ValidateProvidedSolrXml returns an error if the solr.xml in a ConfigMap provided by the user cannot be used for a SolrCloud.
+// The Solr nodes are addressed through the port that the operator passes to Solr as the hostPort system property, so the solr.xml must use it.
+func ValidateProvidedSolrXml(configMap *corev1.ConfigMap) error {
+	solrXml, hasSolrXml := configMap.Data[SolrXmlFile]
+	if !hasSolrXml {
+		return fmt.Errorf("the provided ConfigMap %s does not contain a %s key", configMap.Name, SolrXmlFile)
+	}
+	if !solrXmlHostPortPattern.MatchString(solrXml) {
+		return fmt.Errorf("the %s in the provided ConfigMap %s must set the hostPort to ${hostPort:80}", SolrXmlFile, configMap.Name)
+	}
+	return nil
+}
+
+// SetLog4j2ConfigMd5 hashes a custom log4j2 configuration into the pod template, so that changes to it will restart the Solr pods.
+// Configurations with a monitorInterval are not hashed, since log4j2 reloads them without a restart.
+func SetLog4j2ConfigMd5(statefulSet *appsv1.StatefulSet, log4j2Xml string) {
+	if log4j2Xml == "" || strings.Contains(log4j2Xml, "monitorInterval") {
+		return
+	}
+	if statefulSet.Spec.Template.Annotations == nil {
+		statefulSet.Spec.Template.Annotations = map[string]string{}
+	}
+	statefulSet.Spec.Template.Annotations[Log4j2XmlMd5Annotation] = fmt.Sprintf("%x", md5.Sum([]byte(log4j2Xml)))
+}
+
+// CopyConfigMapFields copies the owned fields from one ConfigMap to another
+func CopyConfigMapFields(from, to *corev1.ConfigMap) bool {
+	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
+
+	// Don't copy the entire Spec, because we can't overwrite the clusterIp field
+
+	// Merge the data, so that keys added to the ConfigMap by other tools are kept
+	if len(to.Data) == 0 && len(from.Data) > 0 {
+		to.Data = make(map[string]string, len(from.Data))
+	}
+	for k, v := range from.Data {
+		if existing, ok := to.Data[k]; !ok || existing != v {
+			requireUpdate = true
+			log.Info("Update required because:", "Data key", k, "changed")
+			to.Data[k] = v
+		}
+	}
+
+	return requireUpdate
+}
+
+// GenerateEndpointsConfigMap returns a new corev1.ConfigMap pointer generated for the SolrCloud instance,
+// listing the endpoints of the cloud and its nodes found in the given status.
+// solrCloud: SolrCloud instance
+// solrCloudStatus: SolrCloudStatus with the addresses of the cloud and its nodes
+func GenerateEndpointsConfigMap(solrCloud *solr.SolrCloud, solrCloudStatus *solr.SolrCloudStatus) *corev1.ConfigMap {
+	data := map[string]string{
+		EndpointsCommonUrlKey: solrCloudStatus.InternalCommonAddress,
+	}
+	if !solrCloud.IsStandalone() {
+		data[EndpointsZkConnectionStringKey] = solrCloudStatus.ZkConnectionString()
+	}
+	if solrCloudStatus.ExternalCommonAddress != nil {
+		data[EndpointsExternalCommonUrlKey] = *solrCloudStatus.ExternalCommonAddress
 	}
 
-	if !DeepEqualWithNils(to.Spec.Template.Spec.SecurityContext, from.Spec.Template.Spec.SecurityContext) {
-		requireUpdate = true
-		log.Info("Update required because:", "Spec.Template.Spec.SecurityContext changed from", to.Spec.Template.Spec.SecurityContext, "To:", from.Spec.Template.Spec.SecurityContext)
-		to.Spec.Template.Spec.SecurityContext = from.Spec.Template.Spec.SecurityContext
+	var nodeUrls, externalNodeUrls []string
+	for _, nodeStatus := range solrCloudStatus.SolrNodes {
+		nodeUrls = append(nodeUrls, nodeStatus.InternalAddress)
+		if nodeStatus.ExternalAddress != "" {
+			externalNodeUrls = append(externalNodeUrls, nodeStatus.ExternalAddress)
+		}
+	}
+	data[EndpointsNodeUrlsKey] = strings.Join(nodeUrls, "\n")
+	if len(externalNodeUrls) > 0 {
+		data[EndpointsExternalNodeUrlsKey] = strings.Join(externalNodeUrls, "\n")
 	}
 
-	if !DeepEqualWithNils(to.Spec.Template.Spec.NodeSelector, from.Spec.Template.Spec.NodeSelector) {
-		requireUpdate = true
-		log.Info("Update required because:", "Spec.Template.Spec.NodeSelector changed from", to.Spec.Template.Spec.NodeSelector, "To:", from.Spec.Template.Spec.NodeSelector)
-		to.Spec.Template.Spec.NodeSelector = from.Spec.Template.Spec.NodeSelector
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      solrCloud.EndpointsConfigMapName(),
+			Namespace: solrCloud.GetNamespace(),
+			Labels:    solrCloud.SharedLabelsWith(solrCloud.GetLabels()),
+		},
+		Data: data,
 	}
+}
 
-	if !DeepEqualWithNils(to.Spec.Template.Spec.Tolerations, from.Spec.Template.Spec.Tolerations) {
+// CopyEndpointsConfigMapFields copies the owned fields from one endpoints ConfigMap to another.
+// Unlike the Solr ConfigMap, the data is replaced entirely, as every key is generated by the operator.
+func CopyEndpointsConfigMapFields(from, to *corev1.ConfigMap) bool {
+	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
+
+	if !DeepEqualWithNils(to.Data, from.Data) {
 		requireUpdate = true
-		log.Info("Update required because:", "Spec.Template.Spec.Tolerations changed from", to.Spec.Template.Spec.Tolerations, "To:", from.Spec.Template.Spec.Tolerations)
-		to.Spec.Template.Spec.Tolerations = from.Spec.Template.Spec.Tolerations
+		log.Info("Update required because:", "Data changed from", to.Data, "To:", from.Data)
+		to.Data = from.Data
 	}
 
 	return requireUpdate
 }
 
-// GenerateConfigMap returns a new corev1.ConfigMap pointer generated for the SolrCloud instance solr.xml
+// GenerateServiceAccount returns a new corev1.ServiceAccount pointer for the pods of the SolrCloud instance
 // solrCloud: SolrCloud instance
-func GenerateConfigMap(solrCloud *solr.SolrCloud) *corev1.ConfigMap {
+func GenerateServiceAccount(solrCloud *solr.SolrCloud) *corev1.ServiceAccount {
 	labels := solrCloud.SharedLabelsWith(solrCloud.GetLabels())
 	var annotations map[string]string
 
-	customOptions := solrCloud.Spec.CustomSolrKubeOptions.ConfigMapOptions
+	customOptions := solrCloud.Spec.CustomSolrKubeOptions.ServiceAccountOptions
 	if nil != customOptions {
 		labels = MergeLabelsOrAnnotations(labels, customOptions.Labels)
 		annotations = MergeLabelsOrAnnotations(annotations, customOptions.Annotations)
 	}
 
-	configMap := &corev1.ConfigMap{
+	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        solrCloud.ConfigMapName(),
+			Name:        solrCloud.ServiceAccountName(),
 			Namespace:   solrCloud.GetNamespace(),
 			Labels:      labels,
 			Annotations: annotations,
 		},
-		Data: map[string]string{
-			"solr.xml": `<?xml version="1.0" encoding="UTF-8" ?>
-<solr>
-  <solrcloud>
-    <str name="host">${host:}</str>
-    <int name="hostPort">${hostPort:80}</int>
-    <str name="hostContext">${hostContext:solr}</str>
-    <bool name="genericCoreNodeNames">${genericCoreNodeNames:true}</bool>
-    <int name="zkClientTimeout">${zkClientTimeout:30000}</int>
-    <int name="distribUpdateSoTimeout">${distribUpdateSoTimeout:600000}</int>
-    <int name="distribUpdateConnTimeout">${distribUpdateConnTimeout:60000}</int>
-    <str name="zkCredentialsProvider">${zkCredentialsProvider:org.apache.solr.common.cloud.DefaultZkCredentialsProvider}</str>
-    <str name="zkACLProvider">${zkACLProvider:org.apache.solr.common.cloud.DefaultZkACLProvider}</str>
-  </solrcloud>
-  <shardHandlerFactory name="shardHandlerFactory"
-    class="HttpShardHandlerFactory">
-    <int name="socketTimeout">${socketTimeout:600000}</int>
-    <int name="connTimeout">${connTimeout:60000}</int>
-  </shardHandlerFactory>
-</solr>
-`,
-		},
 	}
-
-	return configMap
 }
 
-// CopyConfigMapFields copies the owned fields from one ConfigMap to another
-func CopyConfigMapFields(from, to *corev1.ConfigMap) bool {
-	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
-
-	// Don't copy the entire Spec, because we can't overwrite the clusterIp field
+// CopyServiceAccountFields copies the owned fields from one ServiceAccount to another.
+// The secrets of the ServiceAccount are managed by Kubernetes, and are left alone.
+// Returns true if the fields copied from don't match to.
+func CopyServiceAccountFields(from, to *corev1.ServiceAccount) bool {
+	return CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
+}
 
-	if !DeepEqualWithNils(to.Data, from.Data) {
-		requireUpdate = true
+// mergeLifecycleHandlers returns a handler that runs both the generated and the custom lifecycle handler.
+// A custom handler that runs first does not stop the generated handler from running if it fails, a custom handler that runs last only runs if the generated handler succeeds.
+// Only exec handlers can be run one after the other, if either of the handlers is not an exec handler, the custom handler is used on its own.
+func mergeLifecycleHandlers(generated, custom *corev1.Handler, customFirst bool) *corev1.Handler {
+	if custom == nil {
+		return generated
+	} else if generated == nil || generated.Exec == nil || custom.Exec == nil {
+		return custom
+	}
+	command := shellCommand(generated.Exec.Command) + " && " + shellCommand(custom.Exec.Command)
+	if customFirst {
+		command = shellCommand(custom.Exec.Command) + " ; " + shellCommand(generated.Exec.Command)
+	}
+	return &corev1.Handler{
+		Exec: &corev1.ExecAction{
+			Command: []string{"sh", "-c", command},
+		},
 	}
-	to.Data = from.Data
+}
 
-	return requireUpdate
+// shellCommand returns the command that runs the given exec command in a shell.
+// Commands that are already run through "sh -c" are used as-is, the arguments of other commands are quoted.
+func shellCommand(command []string) string {
+	if len(command) == 3 && command[0] == "sh" && command[1] == "-c" {
+		return "(" + command[2] + ")"
+	}
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
 }
 
 // fillProbe builds the probe logic used for pod liveness, readiness, startup checks
@@ -609,8 +1538,20 @@ func fillProbe(customSolrKubeOptions corev1.Probe, defaultInitialDelaySeconds in
 		probe.PeriodSeconds = customSolrKubeOptions.PeriodSeconds
 	}
 
-	if customSolrKubeOptions.Handler.Exec != nil || customSolrKubeOptions.Handler.HTTPGet != nil {
+	if customSolrKubeOptions.Handler.Exec != nil || customSolrKubeOptions.Handler.TCPSocket != nil {
 		probe.Handler = customSolrKubeOptions.Handler
+	} else if customHttpGet := customSolrKubeOptions.Handler.HTTPGet; customHttpGet != nil {
+		// Only the path of the HTTP check has to be given, the port and scheme of the default check are used otherwise
+		httpGet := customHttpGet.DeepCopy()
+		if defaultHandler.HTTPGet != nil {
+			if httpGet.Port == (intstr.IntOrString{}) {
+				httpGet.Port = defaultHandler.HTTPGet.Port
+			}
+			if httpGet.Scheme == "" {
+				httpGet.Scheme = defaultHandler.HTTPGet.Scheme
+			}
+		}
+		probe.Handler = corev1.Handler{HTTPGet: httpGet}
 	}
 
 	return probe
@@ -630,12 +1571,7 @@ func GenerateCommonService(solrCloud *solr.SolrCloud) *corev1.Service {
 	// Add externalDNS annotation if necessary
 	extOpts := solrCloud.Spec.SolrAddressability.External
 	if extOpts != nil && extOpts.Method == solr.ExternalDNS && !extOpts.HideCommon {
-		annotations = make(map[string]string, 1)
-		urls := []string{solrCloud.ExternalDnsDomain(extOpts.DomainName)}
-		for _, domain := range extOpts.AdditionalDomainNames {
-			urls = append(urls, solrCloud.ExternalDnsDomain(domain))
-		}
-		annotations["external-dns.alpha.kubernetes.io/hostname"] = strings.Join(urls, ",")
+		annotations = externalDnsAnnotations(solrCloud)
 	}
 
 	customOptions := solrCloud.Spec.CustomSolrKubeOptions.CommonServiceOptions
@@ -658,9 +1594,30 @@ func GenerateCommonService(solrCloud *solr.SolrCloud) *corev1.Service {
 			Selector: selectorLabels,
 		},
 	}
+	if extOpts != nil && extOpts.Method == solr.NodePort && !extOpts.HideCommon {
+		service.Spec.Type = corev1.ServiceTypeNodePort
+		service.Spec.Ports[0].NodePort = int32(solrCloud.CommonNodePort())
+	}
+	applyServiceOptions(service, customOptions)
 	return service
 }
 
+// externalDnsAnnotations returns the annotations that have ExternalDNS create the records of a Service under every domain of the SolrCloud
+func externalDnsAnnotations(solrCloud *solr.SolrCloud) map[string]string {
+	extOpts := solrCloud.Spec.SolrAddressability.External
+	urls := []string{solrCloud.ExternalDnsDomain(extOpts.DomainName)}
+	for _, domain := range extOpts.AdditionalDomainNames {
+		urls = append(urls, solrCloud.ExternalDnsDomain(domain))
+	}
+	annotations := map[string]string{
+		ExternalDnsHostnameAnnotation: strings.Join(urls, ","),
+	}
+	if extOpts.ExternalDnsTTL > 0 {
+		annotations[ExternalDnsTTLAnnotation] = strconv.Itoa(extOpts.ExternalDnsTTL)
+	}
+	return annotations
+}
+
 // GenerateHeadlessService returns a new Headless corev1.Service pointer generated for the SolrCloud instance
 // The PublishNotReadyAddresses option is set as true, because we want each pod to be reachable no matter the readiness of the pod.
 // solrCloud: SolrCloud instance
@@ -676,12 +1633,7 @@ func GenerateHeadlessService(solrCloud *solr.SolrCloud) *corev1.Service {
 	// Add externalDNS annotation if necessary
 	extOpts := solrCloud.Spec.SolrAddressability.External
 	if extOpts != nil && extOpts.Method == solr.ExternalDNS && !extOpts.HideNodes {
-		annotations = make(map[string]string, 1)
-		urls := []string{solrCloud.ExternalDnsDomain(extOpts.DomainName)}
-		for _, domain := range extOpts.AdditionalDomainNames {
-			urls = append(urls, solrCloud.ExternalDnsDomain(domain))
-		}
-		annotations["external-dns.alpha.kubernetes.io/hostname"] = strings.Join(urls, ",")
+		annotations = externalDnsAnnotations(solrCloud)
 	}
 
 	customOptions := solrCloud.Spec.CustomSolrKubeOptions.HeadlessServiceOptions
@@ -706,6 +1658,7 @@ func GenerateHeadlessService(solrCloud *solr.SolrCloud) *corev1.Service {
 			PublishNotReadyAddresses: true,
 		},
 	}
+	applyServiceOptions(service, customOptions)
 	return service
 }
 
@@ -744,24 +1697,104 @@ func GenerateNodeService(solrCloud *solr.SolrCloud, nodeName string) *corev1.Ser
 			PublishNotReadyAddresses: true,
 		},
 	}
+	if extOpts := solrCloud.Spec.SolrAddressability.External; extOpts != nil && extOpts.Method == solr.NodePort {
+		service.Spec.Type = corev1.ServiceTypeNodePort
+		service.Spec.Ports[0].NodePort = int32(solrCloud.NodeServiceNodePort(nodeName))
+	}
+	// Every node Service would be exposed on the same nodePort, so the nodePort option is not used for them
+	if nil != customOptions && customOptions.NodePort != 0 {
+		nodeServiceOptions := *customOptions
+		nodeServiceOptions.NodePort = 0
+		customOptions = &nodeServiceOptions
+	}
+	applyServiceOptions(service, customOptions)
 	return service
 }
 
+// applyServiceOptions applies the traffic options from the ServiceOptions to the generated Service.
+// Options that are not valid for the type of the Service are ignored.
+func applyServiceOptions(service *corev1.Service, customOptions *solr.ServiceOptions) {
+	if nil == customOptions {
+		return
+	}
+
+	// The type of a headless Service cannot be changed
+	if customOptions.Type != "" && service.Spec.ClusterIP != corev1.ClusterIPNone {
+		service.Spec.Type = customOptions.Type
+		// A ClusterIP Service cannot have nodePorts, even if the addressability method would expose it on one
+		if customOptions.Type == corev1.ServiceTypeClusterIP {
+			for i := range service.Spec.Ports {
+				service.Spec.Ports[i].NodePort = 0
+			}
+		}
+	}
+	exposed := service.Spec.Type == corev1.ServiceTypeNodePort || service.Spec.Type == corev1.ServiceTypeLoadBalancer
+
+	if customOptions.NodePort != 0 && exposed && len(service.Spec.Ports) == 1 {
+		service.Spec.Ports[0].NodePort = customOptions.NodePort
+	}
+
+	if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+		service.Spec.LoadBalancerIP = customOptions.LoadBalancerIP
+		service.Spec.LoadBalancerSourceRanges = customOptions.LoadBalancerSourceRanges
+	}
+
+	// The externalTrafficPolicy can only be set for Services that are exposed outside of the cluster
+	if customOptions.ExternalTrafficPolicy != "" && exposed {
+		service.Spec.ExternalTrafficPolicy = customOptions.ExternalTrafficPolicy
+	}
+
+	// Session affinity is not used for headless Services
+	if customOptions.SessionAffinity != "" && service.Spec.ClusterIP != corev1.ClusterIPNone {
+		service.Spec.SessionAffinity = customOptions.SessionAffinity
+		if customOptions.SessionAffinity == corev1.ServiceAffinityClientIP {
+			service.Spec.SessionAffinityConfig = customOptions.SessionAffinityConfig
+		}
+	}
+
+	// For headless Services, the IP family selects the addresses of the endpoints
+	service.Spec.IPFamily = customOptions.IPFamily
+}
+
 // CopyServiceFields copies the owned fields from one Service to another
 func CopyServiceFields(from, to *corev1.Service) bool {
+	// Only the operator's labels and annotations are copied, the ones added by other controllers are kept
 	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
 
-	// Don't copy the entire Spec, because we can't overwrite the clusterIp field
+	// Don't copy the entire Spec, because we can't overwrite the clusterIp and ipFamily fields, or fields assigned by other controllers
 
 	if !DeepEqualWithNils(to.Spec.Selector, from.Spec.Selector) {
 		requireUpdate = true
+		log.Info("Update required because:", "Spec.Selector changed from", to.Spec.Selector, "To:", from.Spec.Selector)
 	}
 	to.Spec.Selector = from.Spec.Selector
 
-	if !DeepEqualWithNils(to.Spec.Ports, from.Spec.Ports) {
+	// Only copy the type if one is specified, since the type of a ClusterIP Service may be changed by others, e.g. to expose it through a LoadBalancer
+	if from.Spec.Type != "" && to.Spec.Type != from.Spec.Type {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Type changed from", to.Spec.Type, "To:", from.Spec.Type)
+		to.Spec.Type = from.Spec.Type
+		// The fields assigned for the previous type are not valid for the new one
+		if from.Spec.Type == corev1.ServiceTypeClusterIP {
+			to.Spec.ExternalTrafficPolicy = ""
+			to.Spec.HealthCheckNodePort = 0
+		}
+		if from.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			to.Spec.LoadBalancerIP = ""
+			to.Spec.LoadBalancerSourceRanges = nil
+		}
+	}
+
+	// A Service that is explicitly changed to ClusterIP cannot keep the nodePorts assigned to it
+	ports := from.Spec.Ports
+	if from.Spec.Type != corev1.ServiceTypeClusterIP {
+		ports = servicePortsWithAssignedNodePorts(from.Spec.Ports, to.Spec.Ports)
+	}
+	if !DeepEqualWithNils(to.Spec.Ports, ports) {
 		requireUpdate = true
+		log.Info("Update required because:", "Spec.Ports changed from", to.Spec.Ports, "To:", ports)
 	}
-	to.Spec.Ports = from.Spec.Ports
+	to.Spec.Ports = ports
 
 	if !DeepEqualWithNils(to.Spec.ExternalName, from.Spec.ExternalName) {
 		requireUpdate = true
@@ -773,21 +1806,93 @@ func CopyServiceFields(from, to *corev1.Service) bool {
 	}
 	to.Spec.PublishNotReadyAddresses = from.Spec.PublishNotReadyAddresses
 
+	// Only copy the externalTrafficPolicy if one is specified, since the Kubernetes API server will default it otherwise.
+	if from.Spec.ExternalTrafficPolicy != "" && to.Spec.ExternalTrafficPolicy != from.Spec.ExternalTrafficPolicy {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.ExternalTrafficPolicy changed from", to.Spec.ExternalTrafficPolicy, "To:", from.Spec.ExternalTrafficPolicy)
+		to.Spec.ExternalTrafficPolicy = from.Spec.ExternalTrafficPolicy
+		// The healthCheckNodePort is assigned by Kubernetes, and can only be kept when the policy is Local
+		if from.Spec.ExternalTrafficPolicy != corev1.ServiceExternalTrafficPolicyTypeLocal {
+			to.Spec.HealthCheckNodePort = 0
+		}
+	}
+
+	// The loadBalancerIP and source ranges can be assigned by other controllers, so they are only copied if specified
+	if from.Spec.LoadBalancerIP != "" && to.Spec.LoadBalancerIP != from.Spec.LoadBalancerIP {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.LoadBalancerIP changed from", to.Spec.LoadBalancerIP, "To:", from.Spec.LoadBalancerIP)
+		to.Spec.LoadBalancerIP = from.Spec.LoadBalancerIP
+	}
+	if len(from.Spec.LoadBalancerSourceRanges) > 0 && !DeepEqualWithNils(to.Spec.LoadBalancerSourceRanges, from.Spec.LoadBalancerSourceRanges) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.LoadBalancerSourceRanges changed from", to.Spec.LoadBalancerSourceRanges, "To:", from.Spec.LoadBalancerSourceRanges)
+		to.Spec.LoadBalancerSourceRanges = from.Spec.LoadBalancerSourceRanges
+	}
+
+	// Only copy the sessionAffinity if one is specified, since the Kubernetes API server will default it otherwise.
+	// The same is true for the sessionAffinityConfig when using ClientIP affinity.
+	if from.Spec.SessionAffinity != "" {
+		if to.Spec.SessionAffinity != from.Spec.SessionAffinity {
+			requireUpdate = true
+			log.Info("Update required because:", "Spec.SessionAffinity changed from", to.Spec.SessionAffinity, "To:", from.Spec.SessionAffinity)
+			to.Spec.SessionAffinity = from.Spec.SessionAffinity
+		}
+		if from.Spec.SessionAffinity == corev1.ServiceAffinityNone {
+			if to.Spec.SessionAffinityConfig != nil {
+				requireUpdate = true
+				to.Spec.SessionAffinityConfig = nil
+			}
+		} else if from.Spec.SessionAffinityConfig != nil && !DeepEqualWithNils(to.Spec.SessionAffinityConfig, from.Spec.SessionAffinityConfig) {
+			requireUpdate = true
+			log.Info("Update required because:", "Spec.SessionAffinityConfig changed from", to.Spec.SessionAffinityConfig, "To:", from.Spec.SessionAffinityConfig)
+			to.Spec.SessionAffinityConfig = from.Spec.SessionAffinityConfig
+		}
+	}
+
 	return requireUpdate
 }
 
+// servicePortsWithAssignedNodePorts returns the desired ports of a Service, keeping the nodePorts that Kubernetes assigned to the existing ports.
+// A nodePort is only changed if the desired port requests a specific one.
+func servicePortsWithAssignedNodePorts(desired []corev1.ServicePort, existing []corev1.ServicePort) []corev1.ServicePort {
+	if desired == nil {
+		return nil
+	}
+	ports := make([]corev1.ServicePort, len(desired))
+	for i, port := range desired {
+		ports[i] = port
+		if port.NodePort != 0 {
+			continue
+		}
+		for _, existingPort := range existing {
+			if existingPort.Name == port.Name && existingPort.Port == port.Port {
+				ports[i].NodePort = existingPort.NodePort
+				break
+			}
+		}
+	}
+	return ports
+}
+
 // GenerateIngress returns a new Ingress pointer generated for the entire SolrCloud, pointing to all instances
 // solrCloud: SolrCloud instance
 // nodeStatuses: []SolrNodeStatus the nodeStatuses
 // ingressBaseDomain: string baseDomain of the ingress
-func GenerateIngress(solrCloud *solr.SolrCloud, nodeNames []string, ingressBaseDomain string) (ingress *extv1.Ingress) {
+func GenerateIngress(solrCloud *solr.SolrCloud, nodeNames []string, ingressBaseDomain string) (ingress *netv1.Ingress) {
 	labels := solrCloud.SharedLabelsWith(solrCloud.GetLabels())
 	var annotations map[string]string
+	var ingressClassName *string
 
 	customOptions := solrCloud.Spec.CustomSolrKubeOptions.IngressOptions
 	if nil != customOptions {
 		labels = MergeLabelsOrAnnotations(labels, customOptions.Labels)
 		annotations = MergeLabelsOrAnnotations(annotations, customOptions.Annotations)
+		ingressClassName = customOptions.IngressClassName
+	}
+
+	// The ingress controller must call the Solr nodes over https when they use TLS, unless the custom annotations say otherwise
+	if solrCloud.Spec.SolrTLS != nil {
+		annotations = MergeLabelsOrAnnotations(annotations, map[string]string{NginxBackendProtocolAnnotation: "HTTPS"})
 	}
 
 	extOpts := solrCloud.Spec.SolrAddressability.External
@@ -795,35 +1900,325 @@ func GenerateIngress(solrCloud *solr.SolrCloud, nodeNames []string, ingressBaseD
 	// Create advertised domain name and possible additional domain names
 	rules := CreateSolrIngressRules(solrCloud, nodeNames, append([]string{extOpts.DomainName}, extOpts.AdditionalDomainNames...))
 
-	ingress = &extv1.Ingress{
+	ingress = &netv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        solrCloud.CommonIngressName(),
 			Namespace:   solrCloud.GetNamespace(),
 			Labels:      labels,
 			Annotations: annotations,
 		},
-		Spec: extv1.IngressSpec{
-			Rules: rules,
+		Spec: netv1.IngressSpec{
+			IngressClassName: ingressClassName,
+			Rules:            rules,
 		},
 	}
+
+	if tls := extOpts.IngressTLS; tls != nil {
+		hosts := tls.Hosts
+		if len(hosts) == 0 {
+			for _, rule := range rules {
+				hosts = append(hosts, rule.Host)
+			}
+		}
+		ingress.Spec.TLS = []netv1.IngressTLS{
+			{
+				Hosts:      hosts,
+				SecretName: tls.SecretName,
+			},
+		}
+	}
 	return ingress
 }
 
+// IstioGatewayGVK is the GroupVersionKind of the Istio Gateway
+var IstioGatewayGVK = schema.FromAPIVersionAndKind(IstioGroupVersion, IstioGatewayKind)
+
+// IstioVirtualServiceGVK is the GroupVersionKind of the Istio VirtualService
+var IstioVirtualServiceGVK = schema.FromAPIVersionAndKind(IstioGroupVersion, IstioVirtualServiceKind)
+
+// IngressHosts returns the hostnames that the SolrCloud is exposed under, through either its Ingress or its Istio Gateway
+func IngressHosts(solrCloud *solr.SolrCloud, nodeNames []string) (hosts []string) {
+	extOpts := solrCloud.Spec.SolrAddressability.External
+	for _, rule := range CreateSolrIngressRules(solrCloud, nodeNames, append([]string{extOpts.DomainName}, extOpts.AdditionalDomainNames...)) {
+		hosts = append(hosts, rule.Host)
+	}
+	return hosts
+}
+
+// GenerateIstioGateway returns a new Istio Gateway, as an unstructured object, that exposes the hosts of the SolrCloud through the Istio ingress gateway.
+// The Istio types are not imported, since the operator must run in clusters where Istio is not installed.
+// solrCloud: SolrCloud instance
+// nodeNames: []string the names of the Solr nodes
+func GenerateIstioGateway(solrCloud *solr.SolrCloud, nodeNames []string) *unstructured.Unstructured {
+	extOpts := solrCloud.Spec.SolrAddressability.External
+	hosts := IngressHosts(solrCloud, nodeNames)
+
+	selector := map[string]interface{}{}
+	for k, v := range extOpts.Istio.GatewaySelector {
+		selector[k] = v
+	}
+
+	servers := []interface{}{
+		map[string]interface{}{
+			"port": map[string]interface{}{
+				"number":   int64(80),
+				"name":     "http",
+				"protocol": "HTTP",
+			},
+			"hosts": stringsToInterfaces(hosts),
+		},
+	}
+	if tls := extOpts.IngressTLS; tls != nil {
+		tlsHosts := tls.Hosts
+		if len(tlsHosts) == 0 {
+			tlsHosts = hosts
+		}
+		servers = append(servers, map[string]interface{}{
+			"port": map[string]interface{}{
+				"number":   int64(443),
+				"name":     "https",
+				"protocol": "HTTPS",
+			},
+			"hosts": stringsToInterfaces(tlsHosts),
+			"tls": map[string]interface{}{
+				"mode":           "SIMPLE",
+				"credentialName": tls.SecretName,
+			},
+		})
+	}
+
+	gateway := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": selector,
+				"servers":  servers,
+			},
+		},
+	}
+	gateway.SetGroupVersionKind(IstioGatewayGVK)
+	setIngressResourceMetadata(solrCloud, gateway, solrCloud.CommonIngressName())
+	return gateway
+}
+
+// GenerateIstioVirtualService returns a new Istio VirtualService, as an unstructured object, that routes the hosts of the SolrCloud to its services.
+// The VirtualService is bound to the Gateway generated for the SolrCloud, or to the existing Gateway given in the Istio options.
+// solrCloud: SolrCloud instance
+// nodeNames: []string the names of the Solr nodes
+func GenerateIstioVirtualService(solrCloud *solr.SolrCloud, nodeNames []string) *unstructured.Unstructured {
+	extOpts := solrCloud.Spec.SolrAddressability.External
+
+	gateway := solrCloud.CommonIngressName()
+	if extOpts.Istio.Gateway != "" {
+		gateway = extOpts.Istio.Gateway
+	}
+
+	// Every host is routed to the same service and port as in the rules of an Ingress
+	var hosts, routes []interface{}
+	for _, rule := range CreateSolrIngressRules(solrCloud, nodeNames, append([]string{extOpts.DomainName}, extOpts.AdditionalDomainNames...)) {
+		backend := rule.HTTP.Paths[0].Backend.Service
+		hosts = append(hosts, rule.Host)
+		routes = append(routes, map[string]interface{}{
+			"match": []interface{}{
+				map[string]interface{}{
+					"authority": map[string]interface{}{"exact": rule.Host},
+				},
+			},
+			"route": []interface{}{
+				map[string]interface{}{
+					"destination": map[string]interface{}{
+						"host": backend.Name,
+						"port": map[string]interface{}{"number": int64(backend.Port.Number)},
+					},
+				},
+			},
+		})
+	}
+
+	virtualService := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"hosts":    hosts,
+				"gateways": []interface{}{gateway},
+				"http":     routes,
+			},
+		},
+	}
+	virtualService.SetGroupVersionKind(IstioVirtualServiceGVK)
+	setIngressResourceMetadata(solrCloud, virtualService, solrCloud.CommonIngressName())
+	return virtualService
+}
+
+// RouteGVK is the GroupVersionKind of the OpenShift Route
+var RouteGVK = schema.FromAPIVersionAndKind(RouteGroupVersion, RouteKind)
+
+// GenerateRoutes returns the OpenShift Routes, as unstructured objects, for the common endpoint and each node of the SolrCloud, unless they are hidden.
+// A Route only has a single host, so only the domainName is used and the additionalDomainNames are ignored.
+// The OpenShift types are not imported, since the operator must run in Kubernetes clusters that are not OpenShift.
+// solrCloud: SolrCloud instance
+// nodeNames: []string the names of the Solr nodes
+func GenerateRoutes(solrCloud *solr.SolrCloud, nodeNames []string) (routes []*unstructured.Unstructured) {
+	extOpts := solrCloud.Spec.SolrAddressability.External
+	if !extOpts.HideCommon {
+		routes = append(routes, generateRoute(solrCloud, solrCloud.CommonIngressName(), solrCloud.ExternalCommonUrl(extOpts.DomainName, false), solrCloud.CommonServiceName()))
+	}
+	if !extOpts.HideNodes {
+		for _, nodeName := range nodeNames {
+			routes = append(routes, generateRoute(solrCloud, nodeName, solrCloud.ExternalNodeUrl(nodeName, extOpts.DomainName, false), nodeName))
+		}
+	}
+	return routes
+}
+
+// generateRoute returns an OpenShift Route that sends all requests for the host to the given service
+func generateRoute(solrCloud *solr.SolrCloud, name string, host string, serviceName string) *unstructured.Unstructured {
+	route := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			// The weight and wildcardPolicy are given with the values that OpenShift defaults them to, so that the spec is not updated on every reconcile
+			"spec": map[string]interface{}{
+				"host": host,
+				"to": map[string]interface{}{
+					"kind":   "Service",
+					"name":   serviceName,
+					"weight": int64(100),
+				},
+				"port": map[string]interface{}{
+					"targetPort": SolrClientPortName,
+				},
+				"wildcardPolicy": "None",
+			},
+		},
+	}
+	route.SetGroupVersionKind(RouteGVK)
+	setIngressResourceMetadata(solrCloud, route, name)
+	return route
+}
+
+// setIngressResourceMetadata sets the name, labels and annotations of a resource that replaces the Ingress of the SolrCloud, such as an Istio Gateway or an OpenShift Route.
+// The labels and annotations of the ingressOptions are used for these resources.
+func setIngressResourceMetadata(solrCloud *solr.SolrCloud, resource *unstructured.Unstructured, name string) {
+	labels := solrCloud.SharedLabelsWith(solrCloud.GetLabels())
+	var annotations map[string]string
+	if customOptions := solrCloud.Spec.CustomSolrKubeOptions.IngressOptions; nil != customOptions {
+		labels = MergeLabelsOrAnnotations(labels, customOptions.Labels)
+		annotations = MergeLabelsOrAnnotations(annotations, customOptions.Annotations)
+	}
+	resource.SetName(name)
+	resource.SetNamespace(solrCloud.GetNamespace())
+	resource.SetLabels(labels)
+	resource.SetAnnotations(annotations)
+}
+
+// CopyUnstructuredResourceFields copies the owned fields from one Istio Gateway, Istio VirtualService, OpenShift Route or cert-manager Certificate to another
+// Returns true if the fields copied from don't match to.
+func CopyUnstructuredResourceFields(from, to *unstructured.Unstructured) bool {
+	requireUpdate := false
+
+	toLabels := to.GetLabels()
+	if toLabels == nil {
+		toLabels = map[string]string{}
+	}
+	for k, v := range from.GetLabels() {
+		if toLabels[k] != v {
+			requireUpdate = true
+			log.Info("Update Label", "label", k, "newValue", v, "oldValue", toLabels[k])
+			toLabels[k] = v
+		}
+	}
+	to.SetLabels(toLabels)
+
+	toAnnotations := to.GetAnnotations()
+	if toAnnotations == nil {
+		toAnnotations = map[string]string{}
+	}
+	for k, v := range from.GetAnnotations() {
+		if toAnnotations[k] != v {
+			requireUpdate = true
+			log.Info("Update Annotation", "annotation", k, "newValue", v, "oldValue", toAnnotations[k])
+			toAnnotations[k] = v
+		}
+	}
+	to.SetAnnotations(toAnnotations)
+
+	if !DeepEqualWithNils(to.Object["spec"], from.Object["spec"]) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec changed from", to.Object["spec"], "To:", from.Object["spec"])
+		to.Object["spec"] = from.Object["spec"]
+	}
+
+	return requireUpdate
+}
+
+// stringsToInterfaces converts a string slice to the slice type used by unstructured objects
+func stringsToInterfaces(values []string) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = value
+	}
+	return converted
+}
+
+// LegacyIngress converts an Ingress to the legacy extensions/v1beta1 API, for Kubernetes clusters that do not serve the networking.k8s.io/v1 API.
+// The IngressClass is also given through the "kubernetes.io/ingress.class" annotation, since older clusters drop the ingressClassName.
+func LegacyIngress(ingress *netv1.Ingress) *extv1.Ingress {
+	legacyIngress := &extv1.Ingress{
+		ObjectMeta: *ingress.ObjectMeta.DeepCopy(),
+		Spec: extv1.IngressSpec{
+			IngressClassName: ingress.Spec.IngressClassName,
+		},
+	}
+	for _, tls := range ingress.Spec.TLS {
+		legacyIngress.Spec.TLS = append(legacyIngress.Spec.TLS, extv1.IngressTLS{Hosts: tls.Hosts, SecretName: tls.SecretName})
+	}
+	if ingressClassName := ingress.Spec.IngressClassName; ingressClassName != nil {
+		if _, hasClassAnnotation := legacyIngress.Annotations[LegacyIngressClassAnnotation]; !hasClassAnnotation {
+			legacyIngress.Annotations = MergeLabelsOrAnnotations(legacyIngress.Annotations, map[string]string{LegacyIngressClassAnnotation: *ingressClassName})
+		}
+	}
+	for _, rule := range ingress.Spec.Rules {
+		legacyRule := extv1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			legacyRule.HTTP = &extv1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				legacyPath := extv1.HTTPIngressPath{Path: path.Path}
+				if path.PathType != nil {
+					pathType := extv1.PathType(*path.PathType)
+					legacyPath.PathType = &pathType
+				}
+				if service := path.Backend.Service; service != nil {
+					legacyPath.Backend = extv1.IngressBackend{
+						ServiceName: service.Name,
+						ServicePort: intstr.FromInt(int(service.Port.Number)),
+					}
+				}
+				legacyRule.HTTP.Paths = append(legacyRule.HTTP.Paths, legacyPath)
+			}
+		}
+		legacyIngress.Spec.Rules = append(legacyIngress.Spec.Rules, legacyRule)
+	}
+	return legacyIngress
+}
+
 // CreateSolrIngressRules returns all applicable ingress rules for a cloud.
 // solrCloud: SolrCloud instance
 // nodeNames: the names for each of the solr pods
 // domainName: string Domain for the ingress rule to use
-func CreateSolrIngressRules(solrCloud *solr.SolrCloud, nodeNames []string, domainNames []string) []extv1.IngressRule {
-	var ingressRules []extv1.IngressRule
+func CreateSolrIngressRules(solrCloud *solr.SolrCloud, nodeNames []string, domainNames []string) []netv1.IngressRule {
+	var ingressRules []netv1.IngressRule
 	if !solrCloud.Spec.SolrAddressability.External.HideCommon {
 		for _, domainName := range domainNames {
 			ingressRules = append(ingressRules, CreateCommonIngressRule(solrCloud, domainName))
 		}
 	}
 	if !solrCloud.Spec.SolrAddressability.External.HideNodes {
+		// A nodeHostPattern without the {domain} placeholder renders the same hostname for every domain, which only needs a single rule
+		nodeHosts := map[string]bool{}
 		for _, nodeName := range nodeNames {
 			for _, domainName := range domainNames {
-				ingressRules = append(ingressRules, CreateNodeIngressRule(solrCloud, nodeName, domainName))
+				rule := CreateNodeIngressRule(solrCloud, nodeName, domainName)
+				if !nodeHosts[rule.Host] {
+					nodeHosts[rule.Host] = true
+					ingressRules = append(ingressRules, rule)
+				}
 			}
 		}
 	}
@@ -833,84 +2228,92 @@ func CreateSolrIngressRules(solrCloud *solr.SolrCloud, nodeNames []string, domai
 // CreateCommonIngressRule returns a new Ingress Rule generated for a SolrCloud under the given domainName
 // solrCloud: SolrCloud instance
 // domainName: string Domain for the ingress rule to use
-func CreateCommonIngressRule(solrCloud *solr.SolrCloud, domainName string) (ingressRule extv1.IngressRule) {
-	ingressRule = extv1.IngressRule{
-		Host: solrCloud.ExternalCommonUrl(domainName, false),
-		IngressRuleValue: extv1.IngressRuleValue{
-			HTTP: &extv1.HTTPIngressRuleValue{
-				Paths: []extv1.HTTPIngressPath{
-					{
-						Backend: extv1.IngressBackend{
-							ServiceName: solrCloud.CommonServiceName(),
-							ServicePort: intstr.FromInt(solrCloud.Spec.SolrAddressability.CommonServicePort),
-						},
-					},
-				},
-			},
-		},
-	}
-	return ingressRule
+func CreateCommonIngressRule(solrCloud *solr.SolrCloud, domainName string) (ingressRule netv1.IngressRule) {
+	return createIngressRule(solrCloud.ExternalCommonUrl(domainName, false), solrCloud.CommonServiceName(), solrCloud.Spec.SolrAddressability.CommonServicePort)
 }
 
 // CreateNodeIngressRule returns a new Ingress Rule generated for a specific Solr Node under the given domainName
 // solrCloud: SolrCloud instance
 // nodeName: string Name of the node
 // domainName: string Domain for the ingress rule to use
-func CreateNodeIngressRule(solrCloud *solr.SolrCloud, nodeName string, domainName string) (ingressRule extv1.IngressRule) {
-	ingressRule = extv1.IngressRule{
-		Host: solrCloud.ExternalNodeUrl(nodeName, domainName, false),
-		IngressRuleValue: extv1.IngressRuleValue{
-			HTTP: &extv1.HTTPIngressRuleValue{
-				Paths: []extv1.HTTPIngressPath{
+func CreateNodeIngressRule(solrCloud *solr.SolrCloud, nodeName string, domainName string) (ingressRule netv1.IngressRule) {
+	return createIngressRule(solrCloud.ExternalNodeUrl(nodeName, domainName, false), nodeName, solrCloud.NodePort())
+}
+
+// createIngressRule returns an Ingress Rule that sends all requests for the host to the port of the given service
+func createIngressRule(host string, serviceName string, servicePort int) netv1.IngressRule {
+	pathType := netv1.PathTypePrefix
+	return netv1.IngressRule{
+		Host: host,
+		IngressRuleValue: netv1.IngressRuleValue{
+			HTTP: &netv1.HTTPIngressRuleValue{
+				Paths: []netv1.HTTPIngressPath{
 					{
-						Backend: extv1.IngressBackend{
-							ServiceName: nodeName,
-							ServicePort: intstr.FromInt(solrCloud.NodePort()),
+						Path:     "/",
+						PathType: &pathType,
+						Backend: netv1.IngressBackend{
+							Service: &netv1.IngressServiceBackend{
+								Name: serviceName,
+								Port: netv1.ServiceBackendPort{
+									Number: int32(servicePort),
+								},
+							},
 						},
 					},
 				},
 			},
 		},
 	}
-	return ingressRule
 }
 
 // CopyIngressFields copies the owned fields from one Ingress to another
-func CopyIngressFields(from, to *extv1.Ingress) bool {
+func CopyIngressFields(from, to *netv1.Ingress) bool {
 	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
 
 	// Don't copy the entire Spec, because we can't overwrite the clusterIp field
 
 	if !DeepEqualWithNils(to.Spec.Rules, from.Spec.Rules) {
 		requireUpdate = true
+		log.Info("Update required because:", "Spec.Rules changed from", to.Spec.Rules, "To:", from.Spec.Rules)
+		to.Spec.Rules = from.Spec.Rules
 	}
-	to.Spec.Rules = from.Spec.Rules
 
-	return requireUpdate
-}
+	if !DeepEqualWithNils(to.Spec.TLS, from.Spec.TLS) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.TLS changed from", to.Spec.TLS, "To:", from.Spec.TLS)
+		to.Spec.TLS = from.Spec.TLS
+	}
 
-func CallCollectionsApi(cloud string, namespace string, urlParams url.Values, response interface{}) (err error) {
-	cloudUrl := solr.InternalURLForCloud(cloud, namespace)
+	if !DeepEqualWithNils(to.Spec.IngressClassName, from.Spec.IngressClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.IngressClassName changed from", to.Spec.IngressClassName, "To:", from.Spec.IngressClassName)
+		to.Spec.IngressClassName = from.Spec.IngressClassName
+	}
 
-	urlParams.Set("wt", "json")
+	return requireUpdate
+}
 
-	cloudUrl = cloudUrl + "/solr/admin/collections?" + urlParams.Encode()
+// CopyLegacyIngressFields copies the owned fields from one legacy Ingress to another
+func CopyLegacyIngressFields(from, to *extv1.Ingress) bool {
+	requireUpdate := CopyLabelsAndAnnotations(&from.ObjectMeta, &to.ObjectMeta)
 
-	resp := &http.Response{}
-	if resp, err = http.Get(cloudUrl); err != nil {
-		return err
+	if !DeepEqualWithNils(to.Spec.Rules, from.Spec.Rules) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Rules changed from", to.Spec.Rules, "To:", from.Spec.Rules)
+		to.Spec.Rules = from.Spec.Rules
 	}
 
-	defer resp.Body.Close()
-
-	if err == nil && resp.StatusCode != 200 {
-		b, _ := ioutil.ReadAll(resp.Body)
-		err = errors.NewServiceUnavailable(fmt.Sprintf("Recieved bad response code of %d from solr with response: %s", resp.StatusCode, string(b)))
+	if !DeepEqualWithNils(to.Spec.TLS, from.Spec.TLS) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.TLS changed from", to.Spec.TLS, "To:", from.Spec.TLS)
+		to.Spec.TLS = from.Spec.TLS
 	}
 
-	if err == nil {
-		json.NewDecoder(resp.Body).Decode(&response)
+	if !DeepEqualWithNils(to.Spec.IngressClassName, from.Spec.IngressClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.IngressClassName changed from", to.Spec.IngressClassName, "To:", from.Spec.IngressClassName)
+		to.Spec.IngressClassName = from.Spec.IngressClassName
 	}
 
-	return err
+	return requireUpdate
 }
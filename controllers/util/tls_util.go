@@ -0,0 +1,388 @@
+/*
+Copyright 2019 Bloomberg Finance LP.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	solr "github.com/bloomberg/solr-operator/api/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// The key of a TLS Secret with the PEM encoded CA certificate, as provided by cert-manager
+	TLSCACertKey = "ca.crt"
+
+	CertManagerGroupVersion = "cert-manager.io/v1"
+	CertificateKind         = "Certificate"
+
+	// The Secret issued by cert-manager is mounted in the PKCS12 init container, which writes the keystore and truststore to an emptyDir
+	SolrTLSPemVolume     = "solr-tls-pem"
+	SolrTLSPemPath       = "/var/solr/tls/pem"
+	SolrTLSPKCS12Volume  = "solr-tls-pkcs12"
+	SolrTLSPKCS12Path    = "/var/solr/tls/pkcs12"
+	PKCS12KeyStoreFile   = "keystore.p12"
+	PKCS12TrustStoreFile = "truststore.p12"
+	PKCS12PasswordKey    = "password"
+
+	// The client certificate presented by the probes, when Solr requires client authentication
+	SolrTLSClientCertVolume = "solr-tls-client-cert"
+	SolrTLSClientCertPath   = "/var/solr/tls/client"
+)
+
+// solrServerTLSVolumes returns the volumes of the keystore and truststore of the Solr nodes, and the volumeMounts of the Solr container.
+// A keystore built from a cert-manager Certificate is written to an emptyDir by the PKCS12 init container.
+func solrServerTLSVolumes(solrCloud *solr.SolrCloud) (volumes []corev1.Volume, volumeMounts []corev1.VolumeMount) {
+	tlsOptions := solrCloud.Spec.SolrTLS
+	if solrCloud.UsesCertManager() {
+		volumes = append(volumes, corev1.Volume{
+			Name: SolrTLSPemVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: solrCloud.CertificateName(),
+				},
+			},
+		}, corev1.Volume{
+			Name: SolrTLSPKCS12Volume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTLSPKCS12Volume, MountPath: SolrTLSPKCS12Path, ReadOnly: true})
+	} else {
+		volumes = append(volumes, corev1.Volume{
+			Name: SolrTLSKeyStoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tlsOptions.PKCS12Secret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTLSKeyStoreVolume, MountPath: SolrTLSKeyStorePath, ReadOnly: true})
+	}
+
+	if tlsOptions.TrustStoreSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: SolrTLSTrustStoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tlsOptions.TrustStoreSecret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTLSTrustStoreVolume, MountPath: SolrTLSTrustStorePath, ReadOnly: true})
+	}
+
+	if solrCloud.RequiresClientAuth() {
+		volumes = append(volumes, corev1.Volume{
+			Name: SolrTLSClientCertVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: solrCloud.ClientCertSecretName(),
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: SolrTLSClientCertVolume, MountPath: SolrTLSClientCertPath, ReadOnly: true})
+	}
+
+	return volumes, volumeMounts
+}
+
+// solrServerTLSEnvVars returns the SOLR_SSL_* environment variables, which enable TLS in Solr with the mounted keystore and truststore
+func solrServerTLSEnvVars(solrCloud *solr.SolrCloud) []corev1.EnvVar {
+	tlsOptions := solrCloud.Spec.SolrTLS
+	var keyStorePath, trustStorePath string
+	var keyStorePasswordSecret *corev1.SecretKeySelector
+	if solrCloud.UsesCertManager() {
+		keyStorePath = SolrTLSPKCS12Path + "/" + PKCS12KeyStoreFile
+		trustStorePath = SolrTLSPKCS12Path + "/" + PKCS12TrustStoreFile
+		keyStorePasswordSecret = pkcs12PasswordSecretKey(solrCloud)
+	} else {
+		keyStorePath = SolrTLSKeyStorePath + "/" + tlsOptions.PKCS12Secret.Key
+		trustStorePath = keyStorePath
+		keyStorePasswordSecret = tlsOptions.KeyStorePasswordSecret
+	}
+	if tlsOptions.TrustStoreSecret != nil {
+		trustStorePath = SolrTLSTrustStorePath + "/" + tlsOptions.TrustStoreSecret.Key
+	}
+	trustStorePasswordSecret := keyStorePasswordSecret
+	if tlsOptions.TrustStorePasswordSecret != nil {
+		trustStorePasswordSecret = tlsOptions.TrustStorePasswordSecret
+	}
+
+	return []corev1.EnvVar{
+		{
+			Name:  "SOLR_SSL_ENABLED",
+			Value: "true",
+		},
+		{
+			Name:  "SOLR_SSL_KEY_STORE",
+			Value: keyStorePath,
+		},
+		{
+			Name:      "SOLR_SSL_KEY_STORE_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: keyStorePasswordSecret},
+		},
+		{
+			Name:  "SOLR_SSL_KEY_STORE_TYPE",
+			Value: "PKCS12",
+		},
+		{
+			Name:  "SOLR_SSL_TRUST_STORE",
+			Value: trustStorePath,
+		},
+		{
+			Name:      "SOLR_SSL_TRUST_STORE_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: trustStorePasswordSecret},
+		},
+		{
+			Name:  "SOLR_SSL_TRUST_STORE_TYPE",
+			Value: "PKCS12",
+		},
+		{
+			Name:  "SOLR_SSL_NEED_CLIENT_AUTH",
+			Value: strconv.FormatBool(tlsOptions.NeedClientAuth),
+		},
+		{
+			Name:  "SOLR_SSL_WANT_CLIENT_AUTH",
+			Value: strconv.FormatBool(tlsOptions.WantClientAuth),
+		},
+		{
+			Name:  "SOLR_SSL_CLIENT_HOSTNAME_VERIFICATION",
+			Value: strconv.FormatBool(tlsOptions.VerifyClientHostname),
+		},
+		{
+			Name:  "SOLR_SSL_CHECK_PEER_NAME",
+			Value: strconv.FormatBool(tlsOptions.CheckPeerName == nil || *tlsOptions.CheckPeerName),
+		},
+	}
+}
+
+// pkcs12PasswordSecretKey returns the reference to the generated password of the keystore built from the cert-manager Certificate
+func pkcs12PasswordSecretKey(solrCloud *solr.SolrCloud) *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: solrCloud.PKCS12PasswordSecretName()},
+		Key:                  PKCS12PasswordKey,
+	}
+}
+
+// generatePKCS12InitContainer returns the init container that builds the PKCS12 keystore of Solr from the Secret issued by cert-manager,
+// and the truststore from the CA certificate of the issuer, if the Secret provides one
+func generatePKCS12InitContainer(solrCloud *solr.SolrCloud) corev1.Container {
+	certManagerOptions := solrCloud.Spec.SolrTLS.CertManager
+	keyStore := SolrTLSPKCS12Path + "/" + PKCS12KeyStoreFile
+	trustStore := SolrTLSPKCS12Path + "/" + PKCS12TrustStoreFile
+	caCert := SolrTLSPemPath + "/" + TLSCACertKey
+	commands := []string{
+		"openssl pkcs12 -export -in " + SolrTLSPemPath + "/" + corev1.TLSCertKey + " -inkey " + SolrTLSPemPath + "/" + corev1.TLSPrivateKeyKey +
+			" -name solr -out " + keyStore + " -passout env:PKCS12_PASSWORD",
+		"rm -f " + trustStore,
+		// Without the CA of the issuer, Solr trusts the certificates that its own keystore is signed with, as with a provided keystore
+		"(if [ -s " + caCert + " ]; then keytool -importcert -noprompt -alias ca -file " + caCert + " -keystore " + trustStore + " -storetype PKCS12 -storepass:env PKCS12_PASSWORD; else cp " + keyStore + " " + trustStore + "; fi)",
+	}
+
+	image := solrCloud.Spec.SolrImage
+	if certManagerOptions.Image != nil {
+		image = certManagerOptions.Image
+	}
+
+	initContainer := corev1.Container{
+		Name:                     "solr-tls-pkcs12",
+		Image:                    image.ToImageName(),
+		ImagePullPolicy:          image.PullPolicy,
+		TerminationMessagePath:   "/dev/termination-log",
+		TerminationMessagePolicy: "File",
+		Command:                  []string{"sh", "-c", strings.Join(commands, " && ")},
+		Env: []corev1.EnvVar{
+			{
+				Name:      "PKCS12_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: pkcs12PasswordSecretKey(solrCloud)},
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: SolrTLSPemVolume, MountPath: SolrTLSPemPath, ReadOnly: true},
+			{Name: SolrTLSPKCS12Volume, MountPath: SolrTLSPKCS12Path},
+		},
+	}
+	setInitContainerOptions(solrCloud, &initContainer)
+	return initContainer
+}
+
+// GeneratePKCS12PasswordSecret returns a new Secret with the generated password of the keystore built from the cert-manager Certificate
+func GeneratePKCS12PasswordSecret(solrCloud *solr.SolrCloud) (*corev1.Secret, error) {
+	password, err := generatePassword()
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      solrCloud.PKCS12PasswordSecretName(),
+			Namespace: solrCloud.GetNamespace(),
+			Labels:    solrCloud.SharedLabelsWith(solrCloud.GetLabels()),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			PKCS12PasswordKey: []byte(password),
+		},
+	}, nil
+}
+
+// CertificateGVK is the GroupVersionKind of the cert-manager Certificate
+var CertificateGVK = schema.FromAPIVersionAndKind(CertManagerGroupVersion, CertificateKind)
+
+// GenerateCertificate returns the cert-manager Certificate, as an unstructured object, for the hostnames of the SolrCloud and its nodes.
+// The cert-manager types are not imported, since the operator must run in Kubernetes clusters without cert-manager.
+// solrCloud: SolrCloud instance
+// nodeNames: []string the names of the Solr nodes
+func GenerateCertificate(solrCloud *solr.SolrCloud, nodeNames []string) *unstructured.Unstructured {
+	certManagerOptions := solrCloud.Spec.SolrTLS.CertManager
+	dnsNames, ipAddresses := CertificateHosts(solrCloud, nodeNames)
+
+	spec := map[string]interface{}{
+		"secretName": solrCloud.CertificateName(),
+		"issuerRef": map[string]interface{}{
+			"name":  certManagerOptions.IssuerRef.Name,
+			"kind":  certManagerOptions.IssuerRef.Kind,
+			"group": certManagerOptions.IssuerRef.Group,
+		},
+		// The Solr nodes also use the certificate to authenticate to each other
+		"usages":   stringsToInterfaces([]string{"digital signature", "key encipherment", "server auth", "client auth"}),
+		"dnsNames": stringsToInterfaces(dnsNames),
+	}
+	if len(ipAddresses) > 0 {
+		spec["ipAddresses"] = stringsToInterfaces(ipAddresses)
+	}
+	if certManagerOptions.Duration != nil {
+		spec["duration"] = certManagerOptions.Duration.Duration.String()
+	}
+	if certManagerOptions.RenewBefore != nil {
+		spec["renewBefore"] = certManagerOptions.RenewBefore.Duration.String()
+	}
+
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	certificate.SetName(solrCloud.CertificateName())
+	certificate.SetNamespace(solrCloud.GetNamespace())
+	certificate.SetLabels(solrCloud.SharedLabelsWith(solrCloud.GetLabels()))
+	return certificate
+}
+
+// CertificateHosts returns the DNS names and IP addresses that the certificate of the SolrCloud must be valid for:
+// the internal and external hostnames of the common endpoint and of every node, and the additionalDNSNames.
+func CertificateHosts(solrCloud *solr.SolrCloud, nodeNames []string) (dnsNames []string, ipAddresses []string) {
+	hosts := []string{solrCloud.InternalCommonUrl(false)}
+	for _, nodeName := range nodeNames {
+		hosts = append(hosts, solrCloud.InternalNodeHost(nodeName))
+	}
+	if external := solrCloud.Spec.SolrAddressability.External; external != nil {
+		for _, domainName := range append([]string{external.DomainName}, external.AdditionalDomainNames...) {
+			if !external.HideCommon {
+				hosts = append(hosts, solrCloud.ExternalCommonUrl(domainName, false))
+			}
+			if !external.HideNodes {
+				for _, nodeName := range nodeNames {
+					hosts = append(hosts, solrCloud.ExternalNodeUrl(nodeName, domainName, false))
+				}
+			}
+		}
+	}
+	hosts = append(hosts, solrCloud.Spec.SolrTLS.CertManager.AdditionalDNSNames...)
+
+	found := map[string]bool{}
+	for _, host := range hosts {
+		// IPv6 addresses are wrapped in brackets in the urls of the cloud
+		host = strings.Trim(host, "[]")
+		if host == "" || found[host] {
+			continue
+		}
+		found[host] = true
+		if net.ParseIP(host) != nil {
+			ipAddresses = append(ipAddresses, host)
+		} else {
+			dnsNames = append(dnsNames, host)
+		}
+	}
+	return dnsNames, ipAddresses
+}
+
+// SolrTLSCertSecretKeys returns the keys of the Secrets with the certificates that the Solr nodes load when they start,
+// including the keystore and truststore that they connect to ZooKeeper with.
+// The Solr pods are restarted when their content changes, e.g. when cert-manager renews the certificate.
+func SolrTLSCertSecretKeys(solrCloud *solr.SolrCloud) (secretKeys []corev1.SecretKeySelector) {
+	if tlsOptions := solrCloud.Spec.SolrTLS; tlsOptions != nil {
+		if solrCloud.UsesCertManager() {
+			for _, key := range []string{corev1.TLSCertKey, TLSCACertKey} {
+				secretKeys = append(secretKeys, corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: solrCloud.CertificateName()}, Key: key})
+			}
+		} else {
+			secretKeys = append(secretKeys, *tlsOptions.PKCS12Secret)
+		}
+		if tlsOptions.TrustStoreSecret != nil {
+			secretKeys = append(secretKeys, *tlsOptions.TrustStoreSecret)
+		}
+	}
+	if zkRef := solrCloud.Spec.ZookeeperRef; zkRef != nil && zkRef.ConnectionInfo != nil && zkRef.ConnectionInfo.TLS != nil {
+		for _, secretKey := range []*corev1.SecretKeySelector{zkRef.ConnectionInfo.TLS.KeyStoreSecret, zkRef.ConnectionInfo.TLS.TrustStoreSecret} {
+			if secretKey != nil {
+				secretKeys = append(secretKeys, *secretKey)
+			}
+		}
+	}
+	return secretKeys
+}
+
+// SetTLSCertMd5 hashes the certificates of the Solr nodes into the pod template, so that rotating them will restart the Solr pods.
+func SetTLSCertMd5(statefulSet *appsv1.StatefulSet, tlsCertMd5 string) {
+	if tlsCertMd5 == "" {
+		return
+	}
+	if statefulSet.Spec.Template.Annotations == nil {
+		statefulSet.Spec.Template.Annotations = map[string]string{}
+	}
+	statefulSet.Spec.Template.Annotations[TLSCertMd5Annotation] = tlsCertMd5
+}
+
+// SolrTLSClientConfig returns the TLS configuration the operator calls Solr with.
+// The CA certificate in the "ca.crt" key of the trust Secret is trusted if it is provided, otherwise the system roots are used.
+// The certificate and key of the client certificate Secret, if one is given, are presented to Solr for client authentication.
+func SolrTLSClientConfig(trustSecret *corev1.Secret, clientCertSecret *corev1.Secret) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if caCert, hasCACert := trustSecret.Data[TLSCACertKey]; hasCACert {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("the %q of the Secret %s does not contain a PEM encoded certificate", TLSCACertKey, trustSecret.Name)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if clientCertSecret != nil {
+		clientCert, err := tls.X509KeyPair(clientCertSecret.Data[corev1.TLSCertKey], clientCertSecret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("the Secret %s does not contain a valid %q and %q: %v", clientCertSecret.Name, corev1.TLSCertKey, corev1.TLSPrivateKeyKey, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	return tlsConfig, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package util
 
 import (
+	"fmt"
 	solr "github.com/bloomberg/solr-operator/api/v1beta1"
 	zk "github.com/pravega/zookeeper-operator/pkg/apis/zookeeper/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
@@ -24,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"strconv"
+	"strings"
 )
 
 var log = logf.Log.WithName("controller")
@@ -88,6 +90,29 @@ func GenerateZookeeperCluster(solrCloud *solr.SolrCloud, zkSpec *solr.ZookeeperS
 	return zkCluster
 }
 
+// ZookeeperClusterConnectionInfo returns the information to connect to the given number of members of a ZookeeperCluster.
+// Solr connects to each member through the headless service of the ZookeeperCluster.
+// If a kubeDomain is given, the members are addressed by their fully qualified names within that Kubernetes cluster domain.
+func ZookeeperClusterConnectionInfo(zkCluster *zk.ZookeeperCluster, replicas int32, chRoot string, kubeDomain string) solr.ZookeeperConnectionInfo {
+	external := &zkCluster.Status.ExternalClientEndpoint
+	if "" == *external {
+		external = nil
+	}
+	domainSuffix := ""
+	if kubeDomain != "" {
+		domainSuffix = ".svc." + kubeDomain
+	}
+	internal := make([]string, replicas)
+	for i := range internal {
+		internal[i] = fmt.Sprintf("%s-%d.%s-headless.%s%s:%d", zkCluster.Name, i, zkCluster.Name, zkCluster.Namespace, domainSuffix, zkCluster.ZookeeperPorts().Client)
+	}
+	return solr.ZookeeperConnectionInfo{
+		InternalConnectionString: strings.Join(internal, ","),
+		ExternalConnectionString: external,
+		ChRoot:                   chRoot,
+	}
+}
+
 // CopyZookeeperClusterFields copies the owned fields from one ZookeeperCluster to another
 // Returns true if the fields copied from don't match to.
 func CopyZookeeperClusterFields(from, to *zk.ZookeeperCluster) bool {
@@ -179,6 +204,26 @@ func CopyZookeeperClusterFields(from, to *zk.ZookeeperCluster) bool {
 	return requireUpdate
 }
 
+// deploymentStrategyMatches returns whether the existing strategy matches all of the options set in the desired strategy.
+func deploymentStrategyMatches(desired, existing appsv1.DeploymentStrategy) bool {
+	if desired.Type != existing.Type {
+		return false
+	}
+	if desired.RollingUpdate == nil {
+		return true
+	}
+	if existing.RollingUpdate == nil {
+		return false
+	}
+	if desired.RollingUpdate.MaxSurge != nil && !DeepEqualWithNils(desired.RollingUpdate.MaxSurge, existing.RollingUpdate.MaxSurge) {
+		return false
+	}
+	if desired.RollingUpdate.MaxUnavailable != nil && !DeepEqualWithNils(desired.RollingUpdate.MaxUnavailable, existing.RollingUpdate.MaxUnavailable) {
+		return false
+	}
+	return true
+}
+
 // CopyDeploymentFields copies the owned fields from one Deployment to another
 // Returns true if the fields copied from don't match to.
 func CopyDeploymentFields(from, to *appsv1.Deployment) bool {
@@ -196,6 +241,14 @@ func CopyDeploymentFields(from, to *appsv1.Deployment) bool {
 		to.Spec.Selector = from.Spec.Selector
 	}
 
+	// Only copy the strategy if one is specified, since the Kubernetes API server will default it otherwise.
+	// The same is true for the individual rollingUpdate options.
+	if from.Spec.Strategy.Type != "" && !deploymentStrategyMatches(from.Spec.Strategy, to.Spec.Strategy) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Strategy changed from", to.Spec.Strategy, "To:", from.Spec.Strategy)
+		to.Spec.Strategy = from.Spec.Strategy
+	}
+
 	if !DeepEqualWithNils(to.Spec.Template.Labels, from.Spec.Template.Labels) {
 		requireUpdate = true
 		log.Info("Update required because:", "Spec.Template.Labels changed from", to.Spec.Template.Labels, "To:", from.Spec.Template.Labels)
@@ -255,6 +308,12 @@ func CopyDeploymentFields(from, to *appsv1.Deployment) bool {
 				to.Spec.Template.Spec.Containers[i].Env = from.Spec.Template.Spec.Containers[i].Env
 			}
 
+			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].EnvFrom, from.Spec.Template.Spec.Containers[i].EnvFrom) {
+				requireUpdate = true
+				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].EnvFrom changed from", to.Spec.Template.Spec.Containers[i].EnvFrom, "To:", from.Spec.Template.Spec.Containers[i].EnvFrom)
+				to.Spec.Template.Spec.Containers[i].EnvFrom = from.Spec.Template.Spec.Containers[i].EnvFrom
+			}
+
 			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].Resources, from.Spec.Template.Spec.Containers[i].Resources) {
 				requireUpdate = true
 				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].Resources changed from", to.Spec.Template.Spec.Containers[i].Resources, "To:", from.Spec.Template.Spec.Containers[i].Resources)
@@ -266,6 +325,36 @@ func CopyDeploymentFields(from, to *appsv1.Deployment) bool {
 				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].VolumeMounts changed from", to.Spec.Template.Spec.Containers[i].VolumeMounts, "To:", from.Spec.Template.Spec.Containers[i].VolumeMounts)
 				to.Spec.Template.Spec.Containers[i].VolumeMounts = from.Spec.Template.Spec.Containers[i].VolumeMounts
 			}
+
+			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].LivenessProbe, from.Spec.Template.Spec.Containers[i].LivenessProbe) {
+				requireUpdate = true
+				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].LivenessProbe changed from", to.Spec.Template.Spec.Containers[i].LivenessProbe, "To:", from.Spec.Template.Spec.Containers[i].LivenessProbe)
+				to.Spec.Template.Spec.Containers[i].LivenessProbe = from.Spec.Template.Spec.Containers[i].LivenessProbe
+			}
+
+			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].ReadinessProbe, from.Spec.Template.Spec.Containers[i].ReadinessProbe) {
+				requireUpdate = true
+				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].ReadinessProbe changed from", to.Spec.Template.Spec.Containers[i].ReadinessProbe, "To:", from.Spec.Template.Spec.Containers[i].ReadinessProbe)
+				to.Spec.Template.Spec.Containers[i].ReadinessProbe = from.Spec.Template.Spec.Containers[i].ReadinessProbe
+			}
+
+			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].StartupProbe, from.Spec.Template.Spec.Containers[i].StartupProbe) {
+				requireUpdate = true
+				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].StartupProbe changed from", to.Spec.Template.Spec.Containers[i].StartupProbe, "To:", from.Spec.Template.Spec.Containers[i].StartupProbe)
+				to.Spec.Template.Spec.Containers[i].StartupProbe = from.Spec.Template.Spec.Containers[i].StartupProbe
+			}
+
+			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].Lifecycle, from.Spec.Template.Spec.Containers[i].Lifecycle) {
+				requireUpdate = true
+				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].Lifecycle changed from", to.Spec.Template.Spec.Containers[i].Lifecycle, "To:", from.Spec.Template.Spec.Containers[i].Lifecycle)
+				to.Spec.Template.Spec.Containers[i].Lifecycle = from.Spec.Template.Spec.Containers[i].Lifecycle
+			}
+
+			if !DeepEqualWithNils(to.Spec.Template.Spec.Containers[i].SecurityContext, from.Spec.Template.Spec.Containers[i].SecurityContext) {
+				requireUpdate = true
+				log.Info("Update required because:", "Spec.Template.Spec.Containers["+strconv.Itoa(i)+")].SecurityContext changed from", to.Spec.Template.Spec.Containers[i].SecurityContext, "To:", from.Spec.Template.Spec.Containers[i].SecurityContext)
+				to.Spec.Template.Spec.Containers[i].SecurityContext = from.Spec.Template.Spec.Containers[i].SecurityContext
+			}
 		}
 	}
 
@@ -299,5 +388,197 @@ func CopyDeploymentFields(from, to *appsv1.Deployment) bool {
 		to.Spec.Template.Spec.NodeSelector = from.Spec.Template.Spec.NodeSelector
 	}
 
+	if !DeepEqualWithNils(to.Spec.Template.Spec.InitContainers, from.Spec.Template.Spec.InitContainers) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.InitContainers changed from", to.Spec.Template.Spec.InitContainers, "To:", from.Spec.Template.Spec.InitContainers)
+		to.Spec.Template.Spec.InitContainers = from.Spec.Template.Spec.InitContainers
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.ServiceAccountName, from.Spec.Template.Spec.ServiceAccountName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.ServiceAccountName changed from", to.Spec.Template.Spec.ServiceAccountName, "To:", from.Spec.Template.Spec.ServiceAccountName)
+		to.Spec.Template.Spec.ServiceAccountName = from.Spec.Template.Spec.ServiceAccountName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.PriorityClassName, from.Spec.Template.Spec.PriorityClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.PriorityClassName changed from", to.Spec.Template.Spec.PriorityClassName, "To:", from.Spec.Template.Spec.PriorityClassName)
+		to.Spec.Template.Spec.PriorityClassName = from.Spec.Template.Spec.PriorityClassName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.SchedulerName, from.Spec.Template.Spec.SchedulerName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.SchedulerName changed from", to.Spec.Template.Spec.SchedulerName, "To:", from.Spec.Template.Spec.SchedulerName)
+		to.Spec.Template.Spec.SchedulerName = from.Spec.Template.Spec.SchedulerName
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.RuntimeClassName, from.Spec.Template.Spec.RuntimeClassName) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.RuntimeClassName changed from", to.Spec.Template.Spec.RuntimeClassName, "To:", from.Spec.Template.Spec.RuntimeClassName)
+		to.Spec.Template.Spec.RuntimeClassName = from.Spec.Template.Spec.RuntimeClassName
+	}
+
+	// Only copy the DNS policy if one is specified, since the Kubernetes API server will default it otherwise.
+	if from.Spec.Template.Spec.DNSPolicy != "" && !DeepEqualWithNils(to.Spec.Template.Spec.DNSPolicy, from.Spec.Template.Spec.DNSPolicy) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.DNSPolicy changed from", to.Spec.Template.Spec.DNSPolicy, "To:", from.Spec.Template.Spec.DNSPolicy)
+		to.Spec.Template.Spec.DNSPolicy = from.Spec.Template.Spec.DNSPolicy
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.DNSConfig, from.Spec.Template.Spec.DNSConfig) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.DNSConfig changed from", to.Spec.Template.Spec.DNSConfig, "To:", from.Spec.Template.Spec.DNSConfig)
+		to.Spec.Template.Spec.DNSConfig = from.Spec.Template.Spec.DNSConfig
+	}
+
+	if !DeepEqualWithNils(to.Spec.Template.Spec.HostAliases, from.Spec.Template.Spec.HostAliases) {
+		requireUpdate = true
+		log.Info("Update required because:", "Spec.Template.Spec.HostAliases changed from", to.Spec.Template.Spec.HostAliases, "To:", from.Spec.Template.Spec.HostAliases)
+		to.Spec.Template.Spec.HostAliases = from.Spec.Template.Spec.HostAliases
+	}
+
 	return requireUpdate
 }
+
+const (
+	// The providers of Solr that read the ZooKeeper digest credentials and ACLs from system properties
+	ZkACLProvider         = "org.apache.solr.common.cloud.VMParamsAllAndReadonlyDigestZkACLProvider"
+	ZkCredentialsProvider = "org.apache.solr.common.cloud.VMParamsSingleSetCredentialsDigestZkCredentialsProvider"
+
+	ZkCredsAndACLsEnvVar = "SOLR_ZK_CREDS_AND_ACLS"
+
+	// The ZooKeeper client only supports TLS with its Netty socket
+	ZkClientCnxnSocket = "org.apache.zookeeper.ClientCnxnSocketNetty"
+
+	ZkTLSKeyStoreVolume   = "zk-tls-keystore"
+	ZkTLSKeyStorePath     = "/var/solr/zk-tls/keystore"
+	ZkTLSTrustStoreVolume = "zk-tls-truststore"
+	ZkTLSTrustStorePath   = "/var/solr/zk-tls/truststore"
+)
+
+// zkClientEnvVars returns the environment variables with the ZooKeeper digest credentials and TLS passwords, and the SOLR_ZK_CREDS_AND_ACLS with the system properties that pass them to Solr.
+// Solr, and the "solr zk" and zkcli.sh commands, connect with the credentials of the acl, and protect the znodes that they create with both ACLs.
+// The TLS properties are set in the SOLR_ZK_CREDS_AND_ACLS as well, since it is the only variable that those commands pass to their ZooKeeper client.
+func zkClientEnvVars(zkInfo solr.ZookeeperConnectionInfo) []corev1.EnvVar {
+	var envVars []corev1.EnvVar
+	var zkOpts []string
+	if zkInfo.AllACL != nil {
+		envVars = append(envVars, zkACLCredentialEnvVars("ZK_ALL_ACL", zkInfo.AllACL)...)
+		zkOpts = append(zkOpts, "-DzkACLProvider="+ZkACLProvider, "-DzkCredentialsProvider="+ZkCredentialsProvider,
+			"-DzkDigestUsername=$(ZK_ALL_ACL_USERNAME)", "-DzkDigestPassword=$(ZK_ALL_ACL_PASSWORD)")
+		if zkInfo.ReadOnlyACL != nil {
+			envVars = append(envVars, zkACLCredentialEnvVars("ZK_READ_ACL", zkInfo.ReadOnlyACL)...)
+			zkOpts = append(zkOpts, "-DzkDigestReadonlyUsername=$(ZK_READ_ACL_USERNAME)", "-DzkDigestReadonlyPassword=$(ZK_READ_ACL_PASSWORD)")
+		}
+	}
+	if zkInfo.TLS != nil {
+		tlsEnvVars, tlsOpts := zkTLSSystemProperties(zkInfo.TLS)
+		envVars = append(envVars, tlsEnvVars...)
+		zkOpts = append(zkOpts, tlsOpts...)
+	}
+	if len(zkOpts) == 0 {
+		return nil
+	}
+	return append(envVars, corev1.EnvVar{Name: ZkCredsAndACLsEnvVar, Value: strings.Join(zkOpts, " ")})
+}
+
+// zkReaderEnvVars returns the environment variables with the ZooKeeper digest credentials and TLS passwords that a client only reading the znodes of Solr connects with,
+// and the SOLR_ZK_CREDS_AND_ACLS with the system properties that pass them to the client.
+func zkReaderEnvVars(acl *solr.ZookeeperACL, tls *solr.ZookeeperTLSOptions) []corev1.EnvVar {
+	var envVars []corev1.EnvVar
+	var zkOpts []string
+	if acl != nil {
+		envVars = append(envVars, zkACLCredentialEnvVars("ZK_READ_ACL", acl)...)
+		zkOpts = append(zkOpts, "-DzkCredentialsProvider="+ZkCredentialsProvider, "-DzkDigestUsername=$(ZK_READ_ACL_USERNAME)", "-DzkDigestPassword=$(ZK_READ_ACL_PASSWORD)")
+	}
+	if tls != nil {
+		tlsEnvVars, tlsOpts := zkTLSSystemProperties(tls)
+		envVars = append(envVars, tlsEnvVars...)
+		zkOpts = append(zkOpts, tlsOpts...)
+	}
+	if len(zkOpts) == 0 {
+		return nil
+	}
+	return append(envVars, corev1.EnvVar{Name: ZkCredsAndACLsEnvVar, Value: strings.Join(zkOpts, " ")})
+}
+
+// zkTLSSystemProperties returns the environment variables with the passwords of the ZooKeeper keystore and truststore,
+// and the system properties that have the ZooKeeper client connect over TLS with the mounted stores.
+func zkTLSSystemProperties(tls *solr.ZookeeperTLSOptions) (envVars []corev1.EnvVar, zkOpts []string) {
+	zkOpts = []string{"-Dzookeeper.client.secure=true", "-Dzookeeper.clientCnxnSocket=" + ZkClientCnxnSocket}
+	if tls.KeyStoreSecret != nil {
+		zkOpts = append(zkOpts, "-Dzookeeper.ssl.keyStore.location="+ZkTLSKeyStorePath+"/"+tls.KeyStoreSecret.Key)
+		if tls.KeyStorePasswordSecret != nil {
+			envVars = append(envVars, corev1.EnvVar{
+				Name:      "ZK_TLS_KEY_STORE_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: tls.KeyStorePasswordSecret},
+			})
+			zkOpts = append(zkOpts, "-Dzookeeper.ssl.keyStore.password=$(ZK_TLS_KEY_STORE_PASSWORD)")
+		}
+	}
+	if tls.TrustStoreSecret != nil {
+		zkOpts = append(zkOpts, "-Dzookeeper.ssl.trustStore.location="+ZkTLSTrustStorePath+"/"+tls.TrustStoreSecret.Key)
+		if tls.TrustStorePasswordSecret != nil {
+			envVars = append(envVars, corev1.EnvVar{
+				Name:      "ZK_TLS_TRUST_STORE_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: tls.TrustStorePasswordSecret},
+			})
+			zkOpts = append(zkOpts, "-Dzookeeper.ssl.trustStore.password=$(ZK_TLS_TRUST_STORE_PASSWORD)")
+		}
+	}
+	return envVars, zkOpts
+}
+
+// zkTLSVolumes returns the volumes and mounts of the keystore and truststore that the ZooKeeper client connects with
+func zkTLSVolumes(tls *solr.ZookeeperTLSOptions) (volumes []corev1.Volume, volumeMounts []corev1.VolumeMount) {
+	if tls == nil {
+		return nil, nil
+	}
+	if tls.KeyStoreSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: ZkTLSKeyStoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tls.KeyStoreSecret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: ZkTLSKeyStoreVolume, MountPath: ZkTLSKeyStorePath, ReadOnly: true})
+	}
+	if tls.TrustStoreSecret != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: ZkTLSTrustStoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: tls.TrustStoreSecret.Name,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: ZkTLSTrustStoreVolume, MountPath: ZkTLSTrustStorePath, ReadOnly: true})
+	}
+	return volumes, volumeMounts
+}
+
+// zkACLCredentialEnvVars returns the environment variables with the username and password of the ACL, read from its Secret
+func zkACLCredentialEnvVars(prefix string, acl *solr.ZookeeperACL) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: prefix + "_USERNAME",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: acl.SecretRef},
+					Key:                  acl.UsernameKey,
+				},
+			},
+		},
+		{
+			Name: prefix + "_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: acl.SecretRef},
+					Key:                  acl.PasswordKey,
+				},
+			},
+		},
+	}
+}
@@ -26,8 +26,10 @@ import (
 
 	solrv1beta1 "github.com/bloomberg/solr-operator/api/v1beta1"
 	"github.com/bloomberg/solr-operator/controllers"
+	"github.com/bloomberg/solr-operator/controllers/util"
 	zkv1beta1 "github.com/pravega/zookeeper-operator/pkg/apis"
 	k8sRuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -60,6 +62,7 @@ var (
 
 	// Addressability Options
 	ingressBaseDomain string
+	kubeDomain        string
 )
 
 func init() {
@@ -71,6 +74,7 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 	flag.BoolVar(&useZookeeperCRD, "zk-operator", true, "The operator will not use the zk operator & crd when this flag is set to false.")
 	flag.StringVar(&ingressBaseDomain, "ingress-base-domain", "", "The operator will use this base domain for host matching in an ingress for the cloud.")
+	flag.StringVar(&kubeDomain, "kube-domain", "", "The Kubernetes cluster domain, used to address SolrClouds and their ZooKeeper clusters internally. Only required if the cluster does not use the default \"cluster.local\" domain.")
 	flag.StringVar(&watchNamespaces, "watch-namespaces", "", "The comma-separated list of namespaces to watch. If an empty string (default) is provided, the operator will watch the entire Kubernetes cluster.")
 	flag.Parse()
 }
@@ -118,7 +122,8 @@ func main() {
 		managerWatchCache = (cache.NewCacheFunc)(nil)
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	config := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
 		LeaderElection:     enableLeaderElection,
@@ -130,8 +135,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Only watch and manage the resource types that the Kubernetes cluster serves
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+
 	controllers.SetIngressBaseUrl(ingressBaseDomain)
-	controllers.UseZkCRD(useZookeeperCRD)
+	controllers.SetKubeDomain(kubeDomain)
+	controllers.UseZkCRD(useZookeeperCRD && isZookeeperClusterCRDInstalled(discoveryClient))
+	ingressAPIAvailable, legacyIngressAPI := ingressAPIAvailability(discoveryClient)
+	controllers.UseIngressAPI(ingressAPIAvailable)
+	controllers.UseLegacyIngressAPI(legacyIngressAPI)
+	controllers.UseServiceMonitorCRD(isServiceMonitorCRDInstalled(discoveryClient))
+	controllers.UseIstioAPI(isIstioAPIInstalled(discoveryClient))
+	controllers.UseRouteAPI(isRouteAPIAvailable(discoveryClient))
+	controllers.UseCertManagerAPI(isCertManagerAPIInstalled(discoveryClient))
 
 	if err = (&controllers.SolrCloudReconciler{
 		Client: mgr.GetClient(),
@@ -177,3 +197,67 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// isServiceMonitorCRDInstalled uses the discovery client to determine whether the prometheus-operator ServiceMonitor CRD
+// is installed in the Kubernetes cluster.
+func isServiceMonitorCRDInstalled(discoveryClient discovery.DiscoveryInterface) bool {
+	if !util.IsAPIResourceAvailable(discoveryClient, util.ServiceMonitorGroupVersion, util.ServiceMonitorKind) {
+		setupLog.Info("ServiceMonitor CRD not found, ServiceMonitors will not be created for SolrPrometheusExporters", "groupVersion", util.ServiceMonitorGroupVersion)
+		return false
+	}
+	return true
+}
+
+// isIstioAPIInstalled uses the discovery client to determine whether the Istio Gateway and VirtualService CRDs
+// are installed in the Kubernetes cluster.
+func isIstioAPIInstalled(discoveryClient discovery.DiscoveryInterface) bool {
+	if !util.IsAPIResourceAvailable(discoveryClient, util.IstioGroupVersion, util.IstioGatewayKind) ||
+		!util.IsAPIResourceAvailable(discoveryClient, util.IstioGroupVersion, util.IstioVirtualServiceKind) {
+		setupLog.Info("Istio CRDs not found, SolrClouds cannot use the Istio addressability method", "groupVersion", util.IstioGroupVersion)
+		return false
+	}
+	return true
+}
+
+// isRouteAPIAvailable uses the discovery client to determine whether the Kubernetes cluster serves the OpenShift Route API.
+func isRouteAPIAvailable(discoveryClient discovery.DiscoveryInterface) bool {
+	if !util.IsAPIResourceAvailable(discoveryClient, util.RouteGroupVersion, util.RouteKind) {
+		setupLog.Info("Route API not found, SolrClouds cannot use the Route addressability method", "groupVersion", util.RouteGroupVersion)
+		return false
+	}
+	return true
+}
+
+// isCertManagerAPIInstalled uses the discovery client to determine whether the cert-manager Certificate CRD
+// is installed in the Kubernetes cluster.
+func isCertManagerAPIInstalled(discoveryClient discovery.DiscoveryInterface) bool {
+	if !util.IsAPIResourceAvailable(discoveryClient, util.CertManagerGroupVersion, util.CertificateKind) {
+		setupLog.Info("cert-manager CRDs not found, SolrClouds cannot issue their certificates through cert-manager", "groupVersion", util.CertManagerGroupVersion)
+		return false
+	}
+	return true
+}
+
+// isZookeeperClusterCRDInstalled uses the discovery client to determine whether the zookeeper-operator ZookeeperCluster CRD
+// is installed in the Kubernetes cluster.
+func isZookeeperClusterCRDInstalled(discoveryClient discovery.DiscoveryInterface) bool {
+	if !util.IsAPIResourceAvailable(discoveryClient, util.ZookeeperClusterGroupVersion, util.ZookeeperClusterKind) {
+		setupLog.Info("ZookeeperCluster CRD not found, SolrClouds cannot use provided Zookeeper ensembles", "groupVersion", util.ZookeeperClusterGroupVersion)
+		return false
+	}
+	return true
+}
+
+// ingressAPIAvailability uses the discovery client to determine whether the Kubernetes cluster serves an Ingress API version
+// used by the operator, and whether only the legacy version is served, as on clusters older than v1.19.
+func ingressAPIAvailability(discoveryClient discovery.DiscoveryInterface) (available bool, legacy bool) {
+	if util.IsAPIResourceAvailable(discoveryClient, util.IngressGroupVersion, util.IngressKind) {
+		return true, false
+	}
+	if util.IsAPIResourceAvailable(discoveryClient, util.LegacyIngressGroupVersion, util.IngressKind) {
+		setupLog.Info("Ingress API not found, using the legacy Ingress API", "groupVersion", util.IngressGroupVersion, "legacyGroupVersion", util.LegacyIngressGroupVersion)
+		return true, true
+	}
+	setupLog.Info("Ingress API not found, SolrClouds cannot be addressed through an Ingress", "groupVersion", util.IngressGroupVersion, "legacyGroupVersion", util.LegacyIngressGroupVersion)
+	return false, false
+}